@@ -11,14 +11,19 @@ import (
 	"time"
 
 	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/lavumi/crypto-quant/internal/api"
 	"github.com/lavumi/crypto-quant/internal/api/handler"
 	"github.com/lavumi/crypto-quant/internal/datasource/database"
 	"github.com/lavumi/crypto-quant/internal/datasource/exchange"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/depth"
 	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/perp"
 	"github.com/lavumi/crypto-quant/internal/datasource/market/price"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
 	"github.com/lavumi/crypto-quant/internal/portfolio"
 	"github.com/lavumi/crypto-quant/internal/portfolio/wallet"
+	"github.com/lavumi/crypto-quant/pkg/config"
 
 	_ "github.com/lavumi/crypto-quant/docs"
 )
@@ -51,6 +56,7 @@ USAGE:
 MODES:
   Default      Start API server with web interface
   --collect    Run historical data collector
+  migrate      Show, apply or roll back schema migrations
 
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 API SERVER OPTIONS:
@@ -61,6 +67,8 @@ API SERVER OPTIONS:
 		fmt.Fprintf(os.Stderr, "  --api-key       Binance API key (optional)\n")
 		fmt.Fprintf(os.Stderr, "  --secret-key    Binance secret key (optional)\n")
 		fmt.Fprintf(os.Stderr, "  --testnet       Use Binance testnet (default: false)\n")
+		fmt.Fprintf(os.Stderr, "  --sync          Keep market data current in the background\n")
+		fmt.Fprintf(os.Stderr, "  --config        Config file with the sync and storage sections (default: configs/config.yaml)\n")
 
 		fmt.Fprintf(os.Stderr, `
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
@@ -94,15 +102,28 @@ EXAMPLES:
   # Collect with custom database
   ./server --collect --db ./custom.db --symbol BNBUSDT --days 30
 
+  # Show schema migration status, apply pending, roll back the last one
+  ./server migrate status
+  ./server migrate up
+  ./server migrate down --steps 1
+
 `)
 	}
 
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
 	// Parse command line flags
 	port := flag.String("port", "8080", "API server port")
 	dbPath := flag.String("db", "data/trading.db", "Path to SQLite database file")
 	apiKey := flag.String("api-key", "", "Binance API key (optional for public data)")
 	secretKey := flag.String("secret-key", "", "Binance secret key (optional for public data)")
 	useTestnet := flag.Bool("testnet", false, "Use Binance testnet")
+	enableSync := flag.Bool("sync", false, "Run continuous market data sync inside the API server")
+	configPath := flag.String("config", "configs/config.yaml", "Path to config file (sync and storage settings)")
 
 	// Collector flags
 	collect := flag.Bool("collect", false, "Run data collector instead of API server")
@@ -116,7 +137,7 @@ EXAMPLES:
 
 	// If collect flag is set, run collector instead of API server
 	if *collect {
-		runCollector(*dbPath, *symbol, *interval, *days, *startDate, *endDate)
+		runCollector(*dbPath, *configPath, *symbol, *interval, *days, *startDate, *endDate)
 		return
 	}
 
@@ -125,6 +146,11 @@ EXAMPLES:
 	log.Printf("Database: %s", *dbPath)
 	log.Printf("Testnet: %t", *useTestnet)
 
+	cfg, err := config.LoadOrDefault(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
 	// Initialize database
 	db, err := database.New(*dbPath)
 	if err != nil {
@@ -137,9 +163,12 @@ EXAMPLES:
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
-	// Initialize repositories
-	candleRepo := history.NewCandleRepository(db)
-	tradeRepo := history.NewTradeRepository(db)
+	// Open candle/trade storage (SQLite or PostgreSQL per config)
+	stores, err := history.OpenStores(db, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer stores.Close()
 
 	// Initialize Binance client
 	var binanceExchange *exchange.BinanceExchange
@@ -164,18 +193,39 @@ EXAMPLES:
 	}
 	binanceClient = binance.NewClient(*apiKey, *secretKey)
 
-	// Initialize wallet (virtual trading)
-	initialBalances := map[string]float64{
-		"USDT": 10000.0, // Start with $10,000 USDT
+	// Point Binance requests at another server (e.g. cmd/mockbinance) when configured
+	endpoints := exchange.BinanceEndpoints{
+		BaseURL:   cfg.Exchange.Binance.BaseURL,
+		WsBaseURL: cfg.Exchange.Binance.WsBaseURL,
+	}
+	binanceExchange.SetEndpoints(endpoints)
+	if endpoints.BaseURL != "" {
+		binanceClient.BaseURL = endpoints.BaseURL
+		log.Printf("Binance endpoints: %s %s", endpoints.BaseURL, endpoints.WsBaseURL)
+	}
+
+	// Initialize Binance USDⓈ-M futures client for perpetual market data
+	if *useTestnet {
+		futures.UseTestnet = true
 	}
-	walletManager := wallet.NewManager(initialBalances)
+	futuresClient := futures.NewClient(*apiKey, *secretKey)
 
-	// Initialize portfolio (position tracking)
+	// Initialize wallet and portfolio: the real account with API keys, virtual otherwise
+	realAccount := *apiKey != "" && *secretKey != ""
+	var walletManager *wallet.Manager
+	if realAccount {
+		walletManager = wallet.NewManager(nil)
+	} else {
+		walletManager = wallet.NewManager(cfg.Portfolio.InitialBalances)
+	}
 	portfolioManager := portfolio.NewManager()
 
 	// Initialize services
 	marketService := price.NewService(binanceExchange)
-	dataService := history.NewService(candleRepo, tradeRepo, binanceClient)
+	dataService := history.NewService(db, stores, binanceClient)
+	depthService := depth.NewService(db)
+	perpService := perp.NewService(db, futuresClient)
+	symbolService := symbols.NewService(db, binanceClient)
 	walletService := wallet.NewService(walletManager)
 	portfolioService := portfolio.NewService(portfolioManager, binanceExchange)
 
@@ -185,9 +235,45 @@ EXAMPLES:
 	walletHandler := handler.NewWalletHandler(walletService)
 	portfolioHandler := handler.NewPortfolioHandler(portfolioService)
 	backtestHandler := handler.NewBacktestHandler(dataService)
+	depthHandler := handler.NewDepthHandler(depthService)
+	perpHandler := handler.NewPerpHandler(perpService)
+	symbolHandler := handler.NewSymbolHandler(symbolService)
+
+	// Optional continuous market data sync
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Keep exchange trading rules current and round/validate orders against them
+	symbolService.Start(ctx, symbols.DefaultRefreshInterval)
+	binanceExchange.SetSymbolRules(symbolService)
+
+	// Mirror the real account's balances, orders and fills into the wallet and portfolio
+	var accountSync *portfolio.AccountSync
+	if realAccount {
+		accountSync = portfolio.NewAccountSync(binanceExchange, walletManager, portfolioManager, cfg.Portfolio.QuoteAsset)
+		accountSync.Start(ctx)
+		log.Printf("Account sync: wallet and positions follow the Binance account")
+	}
+
+	var syncer *history.Syncer
+	if *enableSync {
+		syncer = history.NewSyncer(history.SyncConfig{
+			Symbols:      cfg.Sync.Symbols,
+			Intervals:    cfg.Sync.Intervals,
+			Lookback:     time.Duration(cfg.Sync.LookbackDays) * 24 * time.Hour,
+			PollInterval: time.Duration(cfg.Sync.PollIntervalSec) * time.Second,
+			Jitter:       time.Duration(cfg.Sync.JitterSec) * time.Second,
+			UseWebSocket: cfg.Sync.WebSocket,
+			Stream:       binanceExchange,
+		}, history.NewCollector(binanceClient, db, stores.Candles), stores.Candles)
+		if err := syncer.Start(ctx); err != nil {
+			log.Fatalf("Failed to start sync: %v", err)
+		}
+	}
+	syncHandler := handler.NewSyncHandler(syncer)
 
 	// Setup router
-	r := api.SetupRouter(marketHandler, dataHandler, walletHandler, portfolioHandler, backtestHandler)
+	r := api.SetupRouter(marketHandler, dataHandler, walletHandler, portfolioHandler, backtestHandler, syncHandler, depthHandler, perpHandler, symbolHandler)
 
 	// Start server
 	log.Printf("API server starting on port %s", *port)
@@ -211,15 +297,27 @@ EXAMPLES:
 	<-quit
 
 	log.Println("Shutting down server...")
+	cancel()
+	if syncer != nil {
+		syncer.Wait()
+	}
+	if accountSync != nil {
+		accountSync.Wait()
+	}
 }
 
 // runCollector runs the data collector functionality
-func runCollector(dbPath, symbol, interval string, days int, startDate, endDate string) {
+func runCollector(dbPath, configPath, symbol, interval string, days int, startDate, endDate string) {
 	log.Printf("=== Historical Data Collector ===")
 	log.Printf("Symbol: %s", symbol)
 	log.Printf("Interval: %s", interval)
 	log.Printf("Database: %s", dbPath)
 
+	cfg, err := config.LoadOrDefault(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
 	// Initialize database
 	db, err := database.New(dbPath)
 	if err != nil {
@@ -235,9 +333,15 @@ func runCollector(dbPath, symbol, interval string, days int, startDate, endDate
 	// Initialize Binance client (no API key needed for public data)
 	client := binance.NewClient("", "")
 
+	// Open candle storage (SQLite or PostgreSQL per config)
+	stores, err := history.OpenStores(db, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer stores.Close()
+
 	// Initialize collector
-	candleRepo := history.NewCandleRepository(db)
-	col := history.NewCollector(client, candleRepo)
+	col := history.NewCollector(client, db, stores.Candles)
 
 	// Calculate time range
 	var startTime, endTime time.Time
@@ -261,21 +365,83 @@ func runCollector(dbPath, symbol, interval string, days int, startDate, endDate
 		log.Printf("End Date: %s", endDate)
 	} else if days > 0 {
 		// Use days
-		endTime = time.Now()
+		endTime = time.Now().UTC()
 		startTime = endTime.AddDate(0, 0, -days)
 		log.Printf("Days: %d", days)
 	} else {
 		// Default: 30 days
-		endTime = time.Now()
+		endTime = time.Now().UTC()
 		startTime = endTime.AddDate(0, 0, -30)
 		log.Printf("Days: 30 (default)")
 	}
 
 	// Collect historical data
 	ctx := context.Background()
-	if err := col.CollectHistorical(ctx, symbol, interval, startTime, endTime); err != nil {
+	if _, err := col.CollectResumable(ctx, symbol, interval, startTime, endTime); err != nil {
 		log.Fatalf("Failed to collect historical data: %v", err)
 	}
 
 	log.Println("Historical data collection completed successfully!")
 }
+
+// runMigrate runs the migrate subcommand: status, up or down
+func runMigrate(args []string) {
+	action := "status"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbPath := fs.String("db", "data/trading.db", "Path to SQLite database file")
+	to := fs.Int("to", 0, "up: apply migrations up to this version (default: latest)")
+	steps := fs.Int("steps", 1, "down: number of migrations to roll back")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: ./server migrate [status|up|down] [options]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	db, err := database.New(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	switch action {
+	case "status":
+	case "up":
+		target := *to
+		if target == 0 {
+			target = database.LatestVersion()
+		}
+		count, err := db.MigrateTo(target)
+		if err != nil {
+			log.Fatalf("Migration failed after %d applied: %v", count, err)
+		}
+		log.Printf("%d migrations applied", count)
+	case "down":
+		count, err := db.Rollback(*steps)
+		if err != nil {
+			log.Fatalf("Rollback failed after %d reverted: %v", count, err)
+		}
+		log.Printf("%d migrations rolled back", count)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	status, err := db.MigrationStatus()
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+	version, _ := db.SchemaVersion()
+	fmt.Printf("Database: %s (schema version %d of %d)\n", *dbPath, version, database.LatestVersion())
+	for _, m := range status {
+		state := "pending"
+		if m.Applied {
+			state = "applied " + m.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  %3d  %-28s %s\n", m.Version, m.Name, state)
+	}
+}
@@ -7,9 +7,13 @@ import (
 	"time"
 
 	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/lavumi/crypto-quant/internal/quant/backtest"
 	"github.com/lavumi/crypto-quant/internal/datasource/database"
 	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/perp"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
+	"github.com/lavumi/crypto-quant/internal/domain"
 	"github.com/lavumi/crypto-quant/internal/quant/strategy"
 	"github.com/lavumi/crypto-quant/pkg/config"
 )
@@ -22,6 +26,10 @@ func main() {
 	endDate := flag.String("end", "", "End date (YYYY-MM-DD)")
 	balance := flag.Float64("balance", 10000.0, "Initial balance")
 	commission := flag.Float64("commission", 0.001, "Commission rate (default: 0.1%)")
+	gaps := flag.String("gaps", "warn", "Gap handling: warn, refuse or ffill")
+	replayTrades := flag.Bool("replay-trades", false, "Fill signals against stored aggTrades of the next bar (collected first if missing)")
+	applyFunding := flag.Bool("funding", false, "Apply perpetual futures funding payments to the open position (collected first if missing)")
+	applyRules := flag.Bool("rules", true, "Round orders to the symbol's tick/lot size and reject those below exchange minimums")
 
 	// Strategy parameters
 	fastMA := flag.Int("fast", 10, "Fast MA period")
@@ -40,7 +48,7 @@ func main() {
 		}
 	} else {
 		// Default: 3 months ago
-		startTime = time.Now().AddDate(0, -3, 0)
+		startTime = time.Now().UTC().AddDate(0, -3, 0)
 	}
 
 	if *endDate != "" {
@@ -50,7 +58,7 @@ func main() {
 		}
 	} else {
 		// Default: now
-		endTime = time.Now()
+		endTime = time.Now().UTC()
 	}
 
 	// Load configuration
@@ -74,10 +82,13 @@ func main() {
 	// Initialize Binance client
 	binanceClient := binance.NewClient(cfg.Exchange.Binance.APIKey, cfg.Exchange.Binance.SecretKey)
 
-	// Initialize repositories and services
-	candleRepo := history.NewCandleRepository(db)
-	tradeRepo := history.NewTradeRepository(db)
-	historyService := history.NewService(candleRepo, tradeRepo, binanceClient)
+	// Initialize storage and services
+	stores, err := history.OpenStores(db, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer stores.Close()
+	historyService := history.NewService(db, stores, binanceClient)
 
 	ctx := context.Background()
 
@@ -104,6 +115,16 @@ func main() {
 		log.Fatalf("No candles found for the specified period")
 	}
 
+	// Handle gaps in the loaded data
+	gapPolicy, err := history.ParseGapPolicy(*gaps)
+	if err != nil {
+		log.Fatalf("Invalid gap policy: %v", err)
+	}
+	candles, _, err = history.ApplyGapPolicy(candles, *interval, gapPolicy)
+	if err != nil {
+		log.Fatalf("Refusing to backtest: %v", err)
+	}
+
 	log.Printf("Loaded %d candles from %s to %s",
 		len(candles),
 		candles[0].OpenTime.Format("2006-01-02"),
@@ -112,11 +133,69 @@ func main() {
 	// Create strategy
 	strat := strategy.NewMACrossStrategy(*fastMA, *slowMA)
 
+	// Optional intrabar fills from raw trades
+	var tradeFeed backtest.TradeFeed
+	if *replayTrades {
+		stored, err := historyService.GetAggTrades(ctx, *symbol, startTime, endTime, 1)
+		if err != nil {
+			log.Fatalf("Failed to check aggTrades: %v", err)
+		}
+		if len(stored) == 0 {
+			log.Printf("Collecting %s aggTrades for trade replay...", *symbol)
+			if _, err := historyService.CollectAggTrades(ctx, *symbol, startTime, endTime); err != nil {
+				log.Fatalf("Failed to collect aggTrades: %v", err)
+			}
+		}
+		tradeFeed = backtest.TradeFeedFunc(func(ctx context.Context, start, end time.Time) ([]*domain.AggTrade, error) {
+			return historyService.GetAggTrades(ctx, *symbol, start, end, 0)
+		})
+		log.Printf("Trade replay enabled: signals fill on the next bar's trades")
+	}
+
+	// Optional perpetual futures funding
+	var fundingRates []*domain.FundingRate
+	if *applyFunding {
+		perpService := perp.NewService(db, futures.NewClient("", ""))
+		fundingRates, err = perpService.GetFundingRates(ctx, *symbol, startTime, endTime)
+		if err != nil {
+			log.Fatalf("Failed to load funding rates: %v", err)
+		}
+		if len(fundingRates) == 0 {
+			log.Printf("Collecting %s funding rates and mark prices...", *symbol)
+			if _, err := perpService.CollectAll(ctx, *symbol, *interval, startTime, endTime); err != nil {
+				log.Fatalf("Failed to collect funding data: %v", err)
+			}
+		}
+		fundingRates, err = perpService.GetFundingWithMarkPrices(ctx, *symbol, *interval, startTime, endTime)
+		if err != nil {
+			log.Fatalf("Failed to load funding rates: %v", err)
+		}
+		log.Printf("Funding enabled: %d funding events", len(fundingRates))
+	}
+
+	// Exchange trading rules (optional, current rules are applied to the whole period)
+	var symbolInfo *domain.SymbolInfo
+	if *applyRules {
+		symbolInfo, err = symbols.NewService(db, binanceClient).Get(ctx, *symbol)
+		if err != nil {
+			log.Printf("⚠️  Trading rules unavailable, orders are not rounded: %v", err)
+			symbolInfo = nil
+		} else {
+			log.Printf("Trading rules: tick %s, step %s, min notional %.8g",
+				symbolInfo.FormatPrice(symbolInfo.TickSize),
+				symbolInfo.FormatQuantity(symbolInfo.StepSize),
+				symbolInfo.MinNotional)
+		}
+	}
+
 	// Create and run backtest engine
 	engine := backtest.NewEngine(&backtest.Config{
 		InitialBalance: *balance,
 		Commission:     *commission,
 		Strategy:       strat,
+		TradeFeed:      tradeFeed,
+		FundingRates:   fundingRates,
+		SymbolInfo:     symbolInfo,
 	})
 
 	log.Printf("Running backtest with strategy: %s", strat.Name())
@@ -4,29 +4,59 @@ import (
 	"context"
 	"flag"
 	"log"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/datasource/exchange"
 	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/perp"
+	"github.com/lavumi/crypto-quant/pkg/config"
 )
 
 func main() {
 	// Parse command line flags
-	symbol := flag.String("symbol", "BTCUSDT", "Trading pair symbol (e.g., BTCUSDT)")
-	interval := flag.String("interval", "1h", "Candle interval (e.g., 1m, 5m, 1h, 1d)")
+	symbol := flag.String("symbol", "BTCUSDT", "Comma-separated trading pair symbols (e.g., BTCUSDT,ETHUSDT)")
+	interval := flag.String("interval", "1h", "Comma-separated candle intervals (e.g., 1m,5m,1h,1d)")
 	days := flag.Int("days", 0, "Number of days to collect (from today backwards)")
 	startDate := flag.String("start", "", "Start date (YYYY-MM-DD format)")
 	endDate := flag.String("end", "", "End date (YYYY-MM-DD format)")
 	dbPath := flag.String("db", "data/trading.db", "Path to SQLite database file")
+	configPath := flag.String("config", "configs/config.yaml", "Path to config file (storage settings)")
+	gapsOnly := flag.Bool("gaps", false, "Only report missing candle ranges for the period, don't collect")
+	backfill := flag.Bool("backfill", false, "Collect only the missing candle ranges for the period")
+	derive := flag.String("derive", "", "Comma-separated intervals to build from 1m data after collection (e.g., 2h,6h,3d)")
+	workers := flag.Int("workers", 4, "Number of symbol/interval pairs to collect concurrently")
+	listJobs := flag.Bool("jobs", false, "List recent collection jobs and their checkpoints, don't collect")
+	aggTrades := flag.Bool("aggtrades", false, "Collect aggregate trades for the period instead of candles")
+	perpData := flag.Bool("perp", false, "Collect perpetual futures funding rates, mark/index klines and open interest instead of candles")
+	venue := flag.String("exchange", history.BinanceVenue, "Venue to collect candles from (binance, bybit, okx); other venues' candles are stored as <venue>:<symbol>")
+	fixture := flag.String("fixture", "", "Replay REST responses from a recorded fixture file instead of calling the venue")
+	record := flag.String("record", "", "Record REST requests and responses to a fixture file")
 
 	flag.Parse()
 
+	symbols := splitList(*symbol)
+	intervals := splitList(*interval)
+	if len(symbols) == 0 || len(intervals) == 0 {
+		log.Fatalf("At least one symbol and interval is required")
+	}
+
 	log.Printf("=== Historical Data Collector ===")
-	log.Printf("Symbol: %s", *symbol)
-	log.Printf("Interval: %s", *interval)
+	log.Printf("Exchange: %s", *venue)
+	log.Printf("Symbols: %s", strings.Join(symbols, ", "))
+	log.Printf("Intervals: %s", strings.Join(intervals, ", "))
 	log.Printf("Database: %s", *dbPath)
 
+	cfg, err := config.LoadOrDefault(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
 	// Initialize database
 	db, err := database.New(*dbPath)
 	if err != nil {
@@ -39,12 +69,66 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Open candle/trade storage (SQLite or PostgreSQL per config)
+	stores, err := history.OpenStores(db, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer stores.Close()
+
+	// Recorded fixtures stand in for the venue's REST API
+	var httpClient *http.Client
+	var recorder *exchange.FixtureRecorder
+	if *fixture != "" {
+		replayer, err := loadFixtures(*fixture)
+		if err != nil {
+			log.Fatalf("Failed to load fixtures: %v", err)
+		}
+		httpClient = replayer.Client()
+	} else if *record != "" {
+		recorder = exchange.NewFixtureRecorder(nil)
+		httpClient = recorder.Client()
+	}
+
 	// Initialize Binance client (no API key needed for public data)
 	client := binance.NewClient("", "")
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	}
 
 	// Initialize collector
-	candleRepo := history.NewCandleRepository(db)
-	col := history.NewCollector(client, candleRepo)
+	col := history.NewCollector(client, db, stores.Candles)
+	if *venue != history.BinanceVenue {
+		if *aggTrades || *perpData || *backfill {
+			log.Fatalf("-aggtrades, -perp and -backfill are only supported for %s", history.BinanceVenue)
+		}
+
+		venueCfg := exchange.NewVenueConfig(cfg.Exchange.Venues[*venue])
+		venueCfg.HTTPClient = httpClient
+		source, err := exchange.NewVenueExchange(*venue, venueCfg)
+		if err != nil {
+			log.Fatalf("Failed to initialize exchange: %v", err)
+		}
+		defer source.Close()
+
+		col = history.NewSourceCollector(source, db, stores.Candles)
+		for i, sym := range symbols {
+			symbols[i] = history.StorageSymbol(*venue, sym)
+		}
+	}
+
+	if *listJobs {
+		jobs, err := history.NewJobRepository(db).List(context.Background(), "", 50)
+		if err != nil {
+			log.Fatalf("Failed to list collection jobs: %v", err)
+		}
+		for _, j := range jobs {
+			log.Printf("#%d %s %s [%s] %.1f%% | checkpoint %s | %d candles %s",
+				j.ID, j.Symbol, j.Interval, j.Status, j.Progress,
+				j.Checkpoint.UTC().Format("2006-01-02 15:04"), j.CandlesSaved, j.Error)
+		}
+		return
+	}
 
 	// Calculate time range
 	var startTime, endTime time.Time
@@ -68,21 +152,136 @@ func main() {
 		log.Printf("End Date: %s", *endDate)
 	} else if *days > 0 {
 		// Use days
-		endTime = time.Now()
+		endTime = time.Now().UTC()
 		startTime = endTime.AddDate(0, 0, -*days)
 		log.Printf("Days: %d", *days)
 	} else {
 		// Default: 30 days
-		endTime = time.Now()
+		endTime = time.Now().UTC()
 		startTime = endTime.AddDate(0, 0, -30)
 		log.Printf("Days: 30 (default)")
 	}
 
-	// Collect historical data
 	ctx := context.Background()
-	if err := col.CollectHistorical(ctx, *symbol, *interval, startTime, endTime); err != nil {
+
+	// Tick-level aggTrades
+	if *aggTrades {
+		historyService := history.NewService(db, stores, client)
+		for _, sym := range symbols {
+			if _, err := historyService.CollectAggTrades(ctx, sym, startTime, endTime); err != nil {
+				log.Fatalf("Failed to collect %s aggTrades: %v", sym, err)
+			}
+		}
+		return
+	}
+
+	// Perpetual futures funding, mark/index prices and open interest
+	if *perpData {
+		perpService := perp.NewService(db, futures.NewClient("", ""))
+		for _, sym := range symbols {
+			for _, iv := range intervals {
+				result, err := perpService.CollectAll(ctx, sym, iv, startTime, endTime)
+				if err != nil {
+					log.Fatalf("Failed to collect %s perp data: %v", sym, err)
+				}
+				log.Printf("%s %s: %d funding rates, %d mark and %d index klines, %d open interest samples",
+					sym, iv, result.FundingRates, result.MarkKlines, result.IndexKlines, result.OpenInterest)
+			}
+		}
+		return
+	}
+
+	// Gap scanning and backfill
+	if *gapsOnly || *backfill {
+		historyService := history.NewService(db, stores, client)
+
+		for _, sym := range symbols {
+			for _, iv := range intervals {
+				var report *history.GapReport
+				if *backfill {
+					report, err = historyService.BackfillGaps(ctx, sym, iv, startTime, endTime)
+				} else {
+					report, err = historyService.DetectGaps(ctx, sym, iv, startTime, endTime)
+				}
+				if err != nil {
+					log.Fatalf("Failed to scan gaps for %s %s: %v", sym, iv, err)
+				}
+
+				log.Printf("%s %s: found %d of %d candles, %d missing in %d gaps",
+					sym, iv, report.Found, report.Expected, report.Missing, len(report.Gaps))
+				for _, g := range report.Gaps {
+					log.Printf("  %s → %s (%d candles)",
+						g.Start.Format("2006-01-02 15:04"), g.End.Format("2006-01-02 15:04"), g.Missing)
+				}
+			}
+		}
+		return
+	}
+
+	// Collect historical data; all workers share one request weight budget
+	scheduler := history.NewScheduler(col, *workers)
+	if _, err := scheduler.Run(ctx, history.BuildTasks(symbols, intervals, startTime, endTime)); err != nil {
 		log.Fatalf("Failed to collect historical data: %v", err)
 	}
 
 	log.Println("Historical data collection completed successfully!")
+
+	if recorder != nil {
+		if err := saveFixtures(recorder, *record); err != nil {
+			log.Fatalf("Failed to save fixtures: %v", err)
+		}
+		log.Printf("Recorded %d requests to %s", len(recorder.Fixtures()), *record)
+	}
+
+	// Build derived intervals from 1m candles
+	if *derive != "" {
+		if len(intervals) != 1 || intervals[0] != history.BaseInterval {
+			log.Fatalf("-derive requires -interval %s", history.BaseInterval)
+		}
+
+		resampler := history.NewResampler(stores.Candles)
+		for _, sym := range symbols {
+			for _, target := range splitList(*derive) {
+				count, err := resampler.Materialize(ctx, sym, target, startTime, endTime)
+				if err != nil {
+					log.Fatalf("Failed to build %s %s candles: %v", sym, target, err)
+				}
+				log.Printf("Built %d %s %s candles from %s data", count, sym, target, history.BaseInterval)
+			}
+		}
+	}
+}
+
+// loadFixtures reads a fixture file saved with -record
+func loadFixtures(path string) (*exchange.FixtureReplayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return exchange.LoadFixtures(f)
+}
+
+// saveFixtures writes the recorded requests to path
+func saveFixtures(recorder *exchange.FixtureRecorder, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := recorder.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+func main() {
+	// Parse command line flags
+	importMode := flag.Bool("import", false, "Import the candle files given as arguments (.csv, .csv.gz, .zip, .parquet)")
+	exportPath := flag.String("export", "", "Export candles to this file")
+	symbol := flag.String("symbol", "", "Trading pair symbol (import: for files without a symbol column)")
+	interval := flag.String("interval", "1m", "Candle interval (e.g., 1m, 5m, 1h, 1d)")
+	format := flag.String("format", "", "File format: csv or parquet (default: from file name)")
+	columns := flag.String("columns", "", "Import column mapping, e.g. open_time=timestamp,volume=vol or open_time=0")
+	timeUnit := flag.String("time-unit", history.TimeUnitAuto, "Import timestamp unit: s, ms, us, ns or auto")
+	startDate := flag.String("start", "", "Export start date (YYYY-MM-DD format)")
+	endDate := flag.String("end", "", "Export end date (YYYY-MM-DD format)")
+	dbPath := flag.String("db", "data/trading.db", "Path to SQLite database file")
+	configPath := flag.String("config", "configs/config.yaml", "Path to config file (storage settings)")
+
+	flag.Parse()
+
+	if *importMode == (*exportPath != "") {
+		log.Fatalf("Use either -import <files...> or -export <file>")
+	}
+
+	cfg, err := config.LoadOrDefault(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.New(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Run migrations
+	if err := db.Migrate(); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Open candle/trade storage (SQLite or PostgreSQL per config)
+	stores, err := history.OpenStores(db, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer stores.Close()
+
+	ctx := context.Background()
+
+	if *importMode {
+		if flag.NArg() == 0 {
+			log.Fatalf("No files to import")
+		}
+
+		mapping, err := history.ParseColumnMapping(*columns)
+		if err != nil {
+			log.Fatalf("Invalid column mapping: %v", err)
+		}
+
+		importer := history.NewImporter(db, stores.Candles)
+		for _, path := range flag.Args() {
+			result, err := importer.ImportFile(ctx, path, history.ImportOptions{
+				Symbol:   *symbol,
+				Interval: *interval,
+				Format:   *format,
+				Columns:  mapping,
+				TimeUnit: *timeUnit,
+			})
+			if err != nil {
+				log.Fatalf("Failed to import %s: %v", path, err)
+			}
+			log.Printf("✅ %s: %d candles (%s → %s, time unit %s)",
+				path, result.Imported, result.From.Format("2006-01-02 15:04"), result.To.Format("2006-01-02 15:04"), result.TimeUnit)
+		}
+		return
+	}
+
+	// Export
+	if *symbol == "" || *startDate == "" || *endDate == "" {
+		log.Fatalf("-export requires -symbol, -start and -end")
+	}
+	startTime, err := time.Parse("2006-01-02", *startDate)
+	if err != nil {
+		log.Fatalf("Invalid start date format. Use YYYY-MM-DD: %v", err)
+	}
+	endTime, err := time.Parse("2006-01-02", *endDate)
+	if err != nil {
+		log.Fatalf("Invalid end date format. Use YYYY-MM-DD: %v", err)
+	}
+	endTime = endTime.Add(24*time.Hour - time.Second)
+
+	exportFormat := *format
+	if exportFormat == "" {
+		exportFormat = history.FormatCSV
+		if strings.HasSuffix(*exportPath, ".parquet") {
+			exportFormat = history.FormatParquet
+		}
+	}
+
+	f, err := os.Create(*exportPath)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *exportPath, err)
+	}
+	defer f.Close()
+
+	historyService := history.NewService(db, stores, nil)
+	count, err := historyService.ExportCandles(ctx, f, exportFormat, *symbol, *interval, startTime, endTime)
+	if err != nil {
+		log.Fatalf("Failed to export candles: %v", err)
+	}
+
+	log.Printf("✅ Exported %d %s %s candles to %s", count, *symbol, *interval, *exportPath)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/datasource/exchange/binancemock"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "Listen address")
+	dbPath := flag.String("db", "data/trading.db", "Path to SQLite database file to replay candles from")
+	configPath := flag.String("config", "configs/config.yaml", "Path to config file (storage settings)")
+	fixture := flag.String("fixture", "", "JSON candle fixture to replay instead of the database")
+	symbol := flag.String("symbols", "BTCUSDT", "Comma-separated symbols to replay from the database")
+	interval := flag.String("interval", "1m", "Candle interval to replay")
+	startDate := flag.String("start", "", "Start date (YYYY-MM-DD, default: 7 days before end)")
+	endDate := flag.String("end", "", "End date (YYYY-MM-DD, default: today)")
+	warmup := flag.Int("warmup", 500, "Bars already closed at startup (history served by /api/v3/klines)")
+	tick := flag.Duration("tick", time.Second, "Wall time per replayed bar (0 = advance only via POST /mock/advance)")
+	balance := flag.String("balance", "USDT=10000", "Comma-separated initial balances (e.g., USDT=10000,BTC=0.5)")
+	commission := flag.Float64("commission", 0.001, "Fee rate charged on fills")
+	rateLimitEvery := flag.Int("rate-limit-every", 0, "Answer every Nth REST request with 429 (0 = never)")
+	stallEvery := flag.Int("stall-every", 0, "Stall every Nth REST request (0 = never)")
+	stallFor := flag.Duration("stall-for", 30*time.Second, "How long a stalled request hangs")
+	latency := flag.Duration("latency", 0, "Latency added to every REST request")
+	disconnectEvery := flag.Duration("disconnect-every", 0, "Drop every stream connection this often (0 = never)")
+
+	flag.Parse()
+
+	log.Printf("=== Mock Binance Server ===")
+
+	balances, err := parseBalances(*balance)
+	if err != nil {
+		log.Fatalf("Invalid balances: %v", err)
+	}
+
+	var candles []*domain.Candle
+	if *fixture != "" {
+		candles, err = loadFixture(*fixture)
+	} else {
+		candles, err = loadCandles(*configPath, *dbPath, splitList(*symbol), *interval, *startDate, *endDate)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load candles: %v", err)
+	}
+
+	srv, err := binancemock.New(binancemock.Config{
+		Interval:   *interval,
+		Candles:    candles,
+		Warmup:     *warmup,
+		Balances:   balances,
+		Commission: *commission,
+		Faults: binancemock.Faults{
+			RateLimitEvery: *rateLimitEvery,
+			StallEvery:     *stallEvery,
+			StallFor:       *stallFor,
+			Latency:        *latency,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to create mock server: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if *tick > 0 {
+		go func() {
+			srv.Run(ctx, *tick)
+			log.Printf("Replay finished at %s", srv.Now().Format(time.RFC3339))
+		}()
+	}
+	if *disconnectEvery > 0 {
+		go func() {
+			ticker := time.NewTicker(*disconnectEvery)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					log.Printf("⚠️  Dropped %d stream connections", srv.Disconnect())
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	server := &http.Server{Addr: *addr, Handler: srv.Handler()}
+	go func() {
+		<-ctx.Done()
+		srv.Disconnect()
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("✅ Replaying %d candles (%s) from %s", len(candles), *interval, srv.Now().Format(time.RFC3339))
+	log.Printf("REST:    http://localhost%s", *addr)
+	log.Printf("Streams: ws://localhost%s/ws", *addr)
+	log.Printf("Set exchange.binance.base_url and ws_base_url to point the exchange at the mock")
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server error: %v", err)
+	}
+	log.Println("Mock server stopped")
+}
+
+// loadFixture reads candles from a JSON fixture file
+func loadFixture(path string) ([]*domain.Candle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return binancemock.LoadCandles(f)
+}
+
+// loadCandles reads the symbols' candles for the date range from storage
+func loadCandles(configPath, dbPath string, symbols []string, interval, startDate, endDate string) ([]*domain.Candle, error) {
+	cfg, err := config.LoadOrDefault(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	stores, err := history.OpenStores(db, cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+	defer stores.Close()
+
+	end := time.Now().UTC()
+	if endDate != "" {
+		if end, err = time.Parse("2006-01-02", endDate); err != nil {
+			return nil, err
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	start := end.AddDate(0, 0, -7)
+	if startDate != "" {
+		if start, err = time.Parse("2006-01-02", startDate); err != nil {
+			return nil, err
+		}
+	}
+
+	candles := make([]*domain.Candle, 0)
+	for _, symbol := range symbols {
+		series, err := stores.Candles.GetRange(context.Background(), symbol, interval, start, end)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Loaded %d %s %s candles", len(series), symbol, interval)
+		candles = append(candles, series...)
+	}
+	return candles, nil
+}
+
+// parseBalances parses ASSET=AMOUNT pairs
+func parseBalances(s string) (map[string]float64, error) {
+	balances := make(map[string]float64)
+	for _, item := range splitList(s) {
+		asset, amount, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, errors.New("expected ASSET=AMOUNT: " + item)
+		}
+		v, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return nil, err
+		}
+		balances[strings.ToUpper(strings.TrimSpace(asset))] = v
+	}
+	return balances, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/datasource/exchange"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/depth"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+func main() {
+	// Parse command line flags
+	configPath := flag.String("config", "configs/config.yaml", "Path to config file")
+	dbPath := flag.String("db", "data/trading.db", "Path to SQLite database file")
+	symbols := flag.String("symbols", "", "Comma-separated symbols (overrides config)")
+	intervals := flag.String("intervals", "", "Comma-separated intervals (overrides config)")
+	healthAddr := flag.String("health", "", "Address for the health endpoint (e.g., :8081)")
+	aggTrades := flag.Bool("aggtrades", false, "Also record aggregate trades for the symbols (overrides config)")
+	recordDepth := flag.Bool("depth", false, "Also record order book snapshots for the symbols (overrides config)")
+	depthFixtures := flag.String("depth-fixtures", "", "Directory to also write raw depth streams to as replayable fixtures")
+
+	flag.Parse()
+
+	cfg, err := config.LoadOrDefault(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *symbols != "" {
+		cfg.Sync.Symbols = strings.Split(*symbols, ",")
+	}
+	if *intervals != "" {
+		cfg.Sync.Intervals = strings.Split(*intervals, ",")
+	}
+
+	log.Printf("=== Market Data Sync ===")
+	log.Printf("Symbols: %v", cfg.Sync.Symbols)
+	log.Printf("Intervals: %v", cfg.Sync.Intervals)
+	log.Printf("Database: %s", *dbPath)
+
+	// Initialize database
+	db, err := database.New(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Run migrations
+	if err := db.Migrate(); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Open candle/trade storage (SQLite or PostgreSQL per config)
+	stores, err := history.OpenStores(db, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer stores.Close()
+
+	// Initialize Binance client (no API key needed for public data)
+	if cfg.Exchange.Binance.UseTestnet {
+		binance.UseTestnet = true
+	}
+	client := binance.NewClient("", "")
+	if cfg.Exchange.Binance.BaseURL != "" {
+		client.BaseURL = cfg.Exchange.Binance.BaseURL
+	}
+
+	// Closed bars are followed through the exchange's managed kline streams
+	stream := &exchange.BinanceExchange{}
+	stream.SetClient(client)
+	stream.SetEndpoints(exchange.BinanceEndpoints{
+		BaseURL:   cfg.Exchange.Binance.BaseURL,
+		WsBaseURL: cfg.Exchange.Binance.WsBaseURL,
+	})
+	defer stream.Close()
+
+	syncer := history.NewSyncer(history.SyncConfig{
+		Symbols:      cfg.Sync.Symbols,
+		Intervals:    cfg.Sync.Intervals,
+		Lookback:     time.Duration(cfg.Sync.LookbackDays) * 24 * time.Hour,
+		PollInterval: time.Duration(cfg.Sync.PollIntervalSec) * time.Second,
+		Jitter:       time.Duration(cfg.Sync.JitterSec) * time.Second,
+		UseWebSocket: cfg.Sync.WebSocket,
+		Stream:       stream,
+	}, history.NewCollector(client, db, stores.Candles), stores.Candles)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := syncer.Start(ctx); err != nil {
+		log.Fatalf("Failed to start sync: %v", err)
+	}
+
+	// Tick-level trade recording: REST backfill, then the aggTrade WebSocket
+	var wg sync.WaitGroup
+	if *aggTrades || cfg.Sync.AggTrades {
+		aggCollector := history.NewAggTradeCollector(client, stores.AggTrades)
+		for _, symbol := range cfg.Sync.Symbols {
+			wg.Add(1)
+			go func(symbol string) {
+				defer wg.Done()
+				aggCollector.Follow(ctx, symbol, time.Hour)
+			}(symbol)
+		}
+	}
+
+	// Order book recording: local book from the diff-depth stream, periodic top-N snapshots
+	if *recordDepth || cfg.Sync.Depth {
+		depthSource := depth.NewBinanceSource(client)
+		depthRepo := depth.NewSnapshotRepository(db)
+		for _, symbol := range cfg.Sync.Symbols {
+			recorderCfg := depth.RecorderConfig{
+				Symbol:   symbol,
+				Levels:   cfg.Sync.DepthLevels,
+				Interval: time.Duration(cfg.Sync.DepthSnapshotMs) * time.Millisecond,
+			}
+			if *depthFixtures != "" {
+				f, err := os.Create(filepath.Join(*depthFixtures, strings.ToLower(symbol)+"_depth.jsonl"))
+				if err != nil {
+					log.Fatalf("Failed to create depth fixture: %v", err)
+				}
+				defer f.Close()
+				recorderCfg.Fixture = depth.NewFixtureWriter(f)
+			}
+
+			recorder := depth.NewRecorder(recorderCfg, depthSource, depthRepo)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				recorder.Follow(ctx)
+			}()
+		}
+	}
+
+	// Optional health endpoint for process supervisors
+	if *healthAddr != "" {
+		go func() {
+			http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+				health := syncer.Health()
+				w.Header().Set("Content-Type", "application/json")
+				if !health.Healthy {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+				json.NewEncoder(w).Encode(health)
+			})
+			log.Printf("Health endpoint: http://localhost%s/health", *healthAddr)
+			if err := http.ListenAndServe(*healthAddr, nil); err != nil {
+				log.Printf("Health endpoint stopped: %v", err)
+			}
+		}()
+	}
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down sync...")
+	cancel()
+	syncer.Wait()
+	wg.Wait()
+	log.Println("Sync stopped")
+}
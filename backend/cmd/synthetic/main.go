@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/synthetic"
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/internal/quant/backtest"
+	"github.com/lavumi/crypto-quant/internal/quant/strategy"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+func main() {
+	// Path flags
+	symbol := flag.String("symbol", synthetic.DefaultSymbol, "Synthetic symbol (must start with "+synthetic.SymbolPrefix+")")
+	interval := flag.String("interval", "1m", "Candle interval (e.g., 1m, 5m, 1h)")
+	startDate := flag.String("start", "2025-01-01", "Open time of the first bar (YYYY-MM-DD or RFC3339)")
+	bars := flag.Int("bars", 7*24*60, "Number of bars to generate")
+	price := flag.Float64("price", 100, "Starting price")
+	seed := flag.Int64("seed", 1, "Random seed (same seed and flags, same candles)")
+	substeps := flag.Int("substeps", 16, "Model steps per bar (they form each bar's high and low)")
+	volume := flag.Float64("volume", 1000, "Volume of an average bar")
+
+	// Model flags
+	model := flag.String("model", synthetic.ModelGBM, "Price model: gbm, garch, jump or regime")
+	drift := flag.Float64("drift", 0, "Annual drift (e.g., 0.2 = +20%/year)")
+	vol := flag.Float64("vol", 0.6, "Annualized volatility (garch: long-run level)")
+	alpha := flag.Float64("alpha", 0.08, "GARCH reaction to the last move")
+	beta := flag.Float64("beta", 0.9, "GARCH variance persistence (alpha + beta < 1)")
+	jumpRate := flag.Float64("jump-rate", 12, "Jump model: jumps per year")
+	jumpMean := flag.Float64("jump-mean", -0.03, "Jump model: mean log jump size")
+	jumpVol := flag.Float64("jump-vol", 0.05, "Jump model: log jump size volatility")
+	var regimes, shocks specList
+	flag.Var(&regimes, "regime", "Regime model state, repeatable: name,drift=0.8,vol=0.4,duration=30d")
+	flag.Var(&shocks, "shock", "Scenario shock, repeatable: crash,at=+72h,size=-0.4,duration=30m,recovery=0.5 or vacuum,at=+96h,duration=2h,volume=0.1,vol=3")
+
+	// Output flags
+	save := flag.Bool("save", false, "Save the candles to the candle tables")
+	runBacktest := flag.Bool("backtest", false, "Run the MA cross backtest on the candles")
+	dbPath := flag.String("db", "data/trading.db", "Path to SQLite database file")
+	configPath := flag.String("config", "configs/config.yaml", "Path to config file (storage settings)")
+	balance := flag.Float64("balance", 10000.0, "Backtest initial balance")
+	commission := flag.Float64("commission", 0.001, "Backtest commission rate")
+	fastMA := flag.Int("fast", 10, "Backtest fast MA period")
+	slowMA := flag.Int("slow", 30, "Backtest slow MA period")
+
+	flag.Parse()
+
+	if !strings.HasPrefix(*symbol, synthetic.SymbolPrefix) {
+		log.Fatalf("Synthetic symbols must start with %s so they never mix with real data", synthetic.SymbolPrefix)
+	}
+	start, err := parseTime(*startDate)
+	if err != nil {
+		log.Fatalf("Invalid start: %v", err)
+	}
+
+	cfg := synthetic.Config{
+		Symbol:     *symbol,
+		Interval:   *interval,
+		Start:      start,
+		Bars:       *bars,
+		StartPrice: *price,
+		Seed:       *seed,
+		Model: synthetic.ModelConfig{
+			Type:       *model,
+			Drift:      *drift,
+			Volatility: *vol,
+			Alpha:      *alpha,
+			Beta:       *beta,
+			JumpRate:   *jumpRate,
+			JumpMean:   *jumpMean,
+			JumpVol:    *jumpVol,
+		},
+		Substeps:   *substeps,
+		BaseVolume: *volume,
+	}
+	for _, spec := range regimes {
+		regime, err := synthetic.ParseRegime(spec)
+		if err != nil {
+			log.Fatalf("Invalid regime: %v", err)
+		}
+		cfg.Model.Regimes = append(cfg.Model.Regimes, regime)
+	}
+	for _, spec := range shocks {
+		shock, err := synthetic.ParseShock(spec)
+		if err != nil {
+			log.Fatalf("Invalid shock: %v", err)
+		}
+		cfg.Shocks = append(cfg.Shocks, shock)
+	}
+
+	log.Printf("=== Synthetic Market Data Generator ===")
+	log.Printf("Model: %s (seed %d), %d %s bars of %s from %s", *model, *seed, *bars, *interval, *symbol, start.Format(time.RFC3339))
+
+	candles, err := synthetic.Generate(cfg)
+	if err != nil {
+		log.Fatalf("Failed to generate candles: %v", err)
+	}
+	printSummary(candles, *interval)
+
+	ctx := context.Background()
+
+	if *save {
+		appCfg, err := config.LoadOrDefault(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+
+		db, err := database.New(*dbPath)
+		if err != nil {
+			log.Fatalf("Failed to initialize database: %v", err)
+		}
+		defer db.Close()
+
+		if err := db.Migrate(); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+
+		stores, err := history.OpenStores(db, appCfg.Storage)
+		if err != nil {
+			log.Fatalf("Failed to open storage: %v", err)
+		}
+		defer stores.Close()
+
+		if err := stores.Candles.SaveBatch(ctx, candles, *interval); err != nil {
+			log.Fatalf("Failed to save candles: %v", err)
+		}
+		log.Printf("✅ Saved %d candles to candles_%s as %s", len(candles), *interval, *symbol)
+	}
+
+	if *runBacktest {
+		strat := strategy.NewMACrossStrategy(*fastMA, *slowMA)
+		engine := backtest.NewEngine(&backtest.Config{
+			InitialBalance: *balance,
+			Commission:     *commission,
+			Strategy:       strat,
+		})
+
+		log.Printf("Running backtest with strategy: %s", strat.Name())
+		result, err := engine.Run(ctx, candles)
+		if err != nil {
+			log.Fatalf("Backtest failed: %v", err)
+		}
+		result.Print()
+	}
+}
+
+// specList collects a repeatable flag
+type specList []string
+
+func (l *specList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *specList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseTime parses a YYYY-MM-DD date or an RFC3339 time
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not YYYY-MM-DD or RFC3339", s)
+	}
+	return t.UTC(), nil
+}
+
+// printSummary prints the path's return, range, drawdown and realized volatility
+func printSummary(candles []*domain.Candle, interval string) {
+	first, last := candles[0], candles[len(candles)-1]
+	low, high := first.Low, first.High
+	peak, maxDrawdown := first.Open, 0.0
+	var sumSq, volume float64
+	prev := first.Open
+	for _, c := range candles {
+		low = min(low, c.Low)
+		high = max(high, c.High)
+		peak = max(peak, c.High)
+		maxDrawdown = max(maxDrawdown, (peak-c.Low)/peak)
+		r := math.Log(c.Close / prev)
+		sumSq += r * r
+		volume += c.Volume
+		prev = c.Close
+	}
+
+	d, _ := history.ParseInterval(interval)
+	barsPerYear := float64(365*24*time.Hour) / float64(d)
+	realizedVol := math.Sqrt(sumSq / float64(len(candles)) * barsPerYear)
+
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Printf("  %s  %s → %s\n", first.Symbol, first.OpenTime.Format("2006-01-02 15:04"), last.CloseTime.Format("2006-01-02 15:04"))
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("  Open / Close:     %12.4f → %.4f (%+.2f%%)\n", first.Open, last.Close, (last.Close/first.Open-1)*100)
+	fmt.Printf("  Low / High:       %12.4f / %.4f\n", low, high)
+	fmt.Printf("  Max drawdown:     %11.2f%%\n", maxDrawdown*100)
+	fmt.Printf("  Realized vol:     %11.2f%% (annualized)\n", realizedVol*100)
+	fmt.Printf("  Total volume:     %12.2f\n", volume)
+	fmt.Println(strings.Repeat("=", 60))
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
 	"github.com/lavumi/crypto-quant/internal/datasource/exchange"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	symbolinfo "github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
 	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/internal/portfolio/wallet"
+	"github.com/lavumi/crypto-quant/internal/quant/live"
+	"github.com/lavumi/crypto-quant/internal/quant/strategy"
 	"github.com/lavumi/crypto-quant/pkg/config"
 )
 
@@ -28,16 +38,22 @@ func main() {
 
 	// Initialize exchange
 	var ex domain.Exchange
+	var futuresExchange domain.FuturesExchange // Set for futures trading with API keys
+	var virtualExchange *exchange.VirtualExchange
+	var binanceExchange *exchange.BinanceExchange // Set for spot trading on Binance
 	var err2 error
 
 	switch cfg.Exchange.Type {
 	case "virtual":
-		ex = exchange.NewVirtualExchange(cfg.Exchange.InitialPrices)
+		virtualCfg := exchange.NewVirtualConfig(cfg.Exchange.Virtual)
+		virtualCfg.Wallet = wallet.NewManager(cfg.Portfolio.InitialBalances)
+		virtualExchange = exchange.NewVirtualExchange(cfg.Exchange.InitialPrices, virtualCfg)
+		ex = virtualExchange
 		log.Println("Virtual exchange initialized")
 	case "binance":
 		// API keys are optional for public data (price queries)
 		// Only required for private operations (trading, balance checks)
-		ex, err2 = exchange.NewBinanceExchange(
+		binanceExchange, err2 = exchange.NewBinanceExchange(
 			cfg.Exchange.Binance.APIKey,
 			cfg.Exchange.Binance.SecretKey,
 			cfg.Exchange.Binance.UseTestnet,
@@ -45,13 +61,58 @@ func main() {
 		if err2 != nil {
 			log.Fatalf("Failed to initialize Binance exchange: %v", err2)
 		}
+		ex = binanceExchange
+		binanceExchange.SetEndpoints(exchange.BinanceEndpoints{
+			BaseURL:   cfg.Exchange.Binance.BaseURL,
+			WsBaseURL: cfg.Exchange.Binance.WsBaseURL,
+		})
 		if cfg.Exchange.Binance.UseTestnet {
 			log.Println("Binance TESTNET exchange initialized")
 		} else {
 			log.Println("Binance exchange initialized (public data access)")
 		}
+	case "binance_futures":
+		// Futures use the Binance keys and testnet setting
+		var binanceFutures *exchange.BinanceFuturesExchange
+		binanceFutures, err2 = exchange.NewBinanceFuturesExchange(
+			cfg.Exchange.Binance.APIKey,
+			cfg.Exchange.Binance.SecretKey,
+			cfg.Exchange.Binance.UseTestnet,
+		)
+		if err2 != nil {
+			log.Fatalf("Failed to initialize Binance futures exchange: %v", err2)
+		}
+		ex = binanceFutures
+		binanceFutures.SetEndpoints(exchange.BinanceEndpoints{
+			BaseURL:   cfg.Exchange.Futures.BaseURL,
+			WsBaseURL: cfg.Exchange.Futures.WsBaseURL,
+		})
+		binanceFutures.SetSymbolRules(exchange.NewVenueRules(binanceFutures)) // Round to the contract's lot and tick size
+		if cfg.Exchange.Binance.APIKey != "" {
+			futuresExchange = binanceFutures
+			if err := configureFutures(context.Background(), binanceFutures, cfg.Exchange.Futures, cfg.Trading.Symbols); err != nil {
+				log.Fatalf("Failed to configure futures: %v", err)
+			}
+		}
+		if cfg.Exchange.Binance.UseTestnet {
+			log.Println("Binance futures TESTNET exchange initialized")
+		} else {
+			log.Println("Binance futures exchange initialized")
+		}
 	default:
-		log.Fatalf("Unsupported exchange type: %s", cfg.Exchange.Type)
+		// Any other registered venue (bybit, okx), configured under exchange.venues
+		venueCfg := exchange.NewVenueConfig(cfg.Exchange.Venues[cfg.Exchange.Type])
+		ex, err2 = exchange.NewVenueExchange(cfg.Exchange.Type, venueCfg)
+		if err2 != nil {
+			log.Fatalf("Unsupported exchange type: %v", err2)
+		}
+		// Round and validate orders against the venue's instrument rules
+		exchange.UseVenueRules(ex)
+		if venueCfg.Testnet {
+			log.Printf("%s TESTNET exchange initialized", cfg.Exchange.Type)
+		} else {
+			log.Printf("%s exchange initialized", cfg.Exchange.Type)
+		}
 	}
 	defer ex.Close()
 
@@ -59,6 +120,76 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Stored history serves replays and strategy warm-up
+	replaying := virtualExchange != nil && cfg.Exchange.Virtual.Replay.Start != ""
+	var stores *history.Stores
+	if replaying || len(cfg.Trading.Strategies) > 0 {
+		db, err := database.New("data/trading.db")
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+		if err := db.Migrate(); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		stores, err = history.OpenStores(db, cfg.Storage)
+		if err != nil {
+			log.Fatalf("Failed to open storage: %v", err)
+		}
+		defer stores.Close()
+
+		// Round and validate strategy orders against Binance's trading rules
+		if binanceExchange != nil {
+			symbolService := symbolinfo.NewService(db, binance.NewClient(cfg.Exchange.Binance.APIKey, cfg.Exchange.Binance.SecretKey))
+			symbolService.Start(ctx, symbolinfo.DefaultRefreshInterval)
+			binanceExchange.SetSymbolRules(symbolService)
+		}
+	}
+
+	// Replay stored market data through the virtual exchange
+	var replay *exchange.Replay
+	var replayDone <-chan struct{} // Stays nil (never ready) without a replay
+	if replaying {
+		// Strategies warm up while the replay waits at its start
+		replayCfg := cfg.Exchange.Virtual.Replay
+		replayCfg.Paused = replayCfg.Paused || len(cfg.Trading.Strategies) > 0
+		replay, err = startReplay(ctx, virtualExchange, stores, replayCfg, tradedSymbols(cfg.Trading))
+		if err != nil {
+			log.Fatalf("Failed to start replay: %v", err)
+		}
+		replayDone = replay.Done()
+	}
+
+	// Run the configured strategies
+	var runner *live.Runner
+	if len(cfg.Trading.Strategies) > 0 {
+		liveCfg := live.Config{
+			Candles:          stores.Candles,
+			WarmupBars:       cfg.Trading.WarmupBars,
+			PositionLookback: time.Duration(cfg.Trading.PositionLookbackDays) * 24 * time.Hour,
+			Backfill:         virtualExchange == nil || replay != nil, // The virtual exchange only has real klines during a replay
+			ReduceOnly:       cfg.Exchange.Type == "binance_futures",
+		}
+		if replay != nil {
+			liveCfg.WarmupEnd = replay.Status().Time
+		}
+		runner, err = startRunner(ctx, ex, liveCfg, cfg.Trading.Strategies)
+		if err != nil {
+			log.Fatalf("Failed to start strategies: %v", err)
+		}
+	}
+
+	if replay != nil {
+		if !cfg.Exchange.Virtual.Replay.Paused {
+			if runner != nil {
+				// Let the kline streams subscribe before a full speed replay races past them
+				time.Sleep(200 * time.Millisecond)
+			}
+			replay.Resume()
+		}
+		go replayControls(replay, os.Stdin)
+	}
+
 	// Handle shutdown signals
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -72,14 +203,38 @@ func main() {
 
 	// Display prices immediately
 	displayPrices(ctx, ex, cfg.Trading.Symbols)
+	if futuresExchange != nil {
+		displayPositions(ctx, futuresExchange)
+	}
+	if replay != nil {
+		displayReplay(replay)
+	}
+	if runner != nil {
+		displayStrategies(runner)
+	}
 
 	for {
 		select {
 		case <-ticker.C:
 			displayPrices(ctx, ex, cfg.Trading.Symbols)
+			if futuresExchange != nil {
+				displayPositions(ctx, futuresExchange)
+			}
+			if replay != nil {
+				displayReplay(replay)
+			}
+			if runner != nil {
+				displayStrategies(runner)
+			}
+		case <-replayDone:
+			log.Printf("❌ Replay stopped: %v", replay.Err())
+			cancel()
+			stopRunner(runner, cfg.Trading.FlattenOnExit)
+			return
 		case <-sigCh:
 			log.Println("\nShutdown signal received. Closing...")
 			cancel()
+			stopRunner(runner, cfg.Trading.FlattenOnExit)
 			time.Sleep(500 * time.Millisecond)
 			fmt.Println("\nThank you for using Crypto Quant Trading System!")
 			return
@@ -122,11 +277,252 @@ func displayPrices(ctx context.Context, ex domain.Exchange, symbols []string) {
 	// Display in order
 	for _, symbol := range symbols {
 		if price, ok := prices[symbol]; ok {
-			// Extract coin name (e.g., BTC from BTCUSDT)
-			coinName := symbol[:len(symbol)-4]
+			// Extract coin name (e.g., BTC from BTCUSDT, ETH from ETHFDUSD)
+			coinName := symbolinfo.SplitSymbol(symbol)
 			fmt.Printf("  %-10s $%12.2f\n", coinName, price)
 		}
 	}
 
 	fmt.Println(strings.Repeat("=", 80))
 }
+
+// configureFutures applies the configured margin type and leverage to every trading symbol
+func configureFutures(ctx context.Context, fx domain.FuturesExchange, cfg config.FuturesConfig, symbols []string) error {
+	if cfg.MarginType != "" {
+		marginType, err := domain.ParseMarginType(cfg.MarginType)
+		if err != nil {
+			return err
+		}
+		for _, symbol := range symbols {
+			if err := fx.SetMarginType(ctx, symbol, marginType); err != nil {
+				return err
+			}
+		}
+		log.Printf("Margin type: %s", marginType)
+	}
+
+	if cfg.Leverage > 0 {
+		for _, symbol := range symbols {
+			if err := fx.SetLeverage(ctx, symbol, cfg.Leverage); err != nil {
+				return err
+			}
+		}
+		log.Printf("Leverage: %dx", cfg.Leverage)
+	}
+	return nil
+}
+
+func displayPositions(ctx context.Context, fx domain.FuturesExchange) {
+	positions, err := fx.GetPositionRisk(ctx, "")
+	if err != nil {
+		log.Printf("Failed to get positions: %v", err)
+		return
+	}
+	if len(positions) == 0 {
+		fmt.Println("  No open futures positions")
+		return
+	}
+
+	fmt.Printf("  %-10s %-5s %12s %12s %12s %12s %12s %5s %-8s\n",
+		"Symbol", "Side", "Size", "Entry", "Mark", "Liq. Price", "uPnL", "Lev", "Margin")
+	for _, p := range positions {
+		fmt.Printf("  %-10s %-5s %12.4f %12.2f %12.2f %12.2f %12.2f %4dx %-8s\n",
+			p.Symbol, p.Side(), p.Quantity, p.EntryPrice, p.MarkPrice, p.LiquidationPrice,
+			p.UnrealizedPnL, p.Leverage, p.MarginType)
+	}
+	fmt.Println(strings.Repeat("=", 80))
+}
+
+// tradedSymbols returns the trading symbols followed by any other symbols the strategies trade
+func tradedSymbols(cfg config.TradingConfig) []string {
+	symbols := append([]string(nil), cfg.Symbols...)
+	for _, sc := range cfg.Strategies {
+		found := false
+		for _, symbol := range symbols {
+			found = found || symbol == sc.Symbol
+		}
+		if !found {
+			symbols = append(symbols, sc.Symbol)
+		}
+	}
+	return symbols
+}
+
+// startRunner creates the configured strategy instances, warms them up and starts trading
+func startRunner(ctx context.Context, ex domain.Exchange, liveCfg live.Config, strategies []config.StrategyConfig) (*live.Runner, error) {
+	liveEx, ok := ex.(live.Exchange)
+	if !ok {
+		return nil, fmt.Errorf("exchange has no kline stream")
+	}
+	liveCfg.Exchange = liveEx
+
+	for _, sc := range strategies {
+		strat, err := strategy.New(sc.Strategy, sc.Params)
+		if err != nil {
+			return nil, fmt.Errorf("strategy %s: %w", sc.Name, err)
+		}
+		liveCfg.Instances = append(liveCfg.Instances, &live.Instance{
+			Name:       sc.Name,
+			Symbol:     sc.Symbol,
+			Interval:   sc.Interval,
+			Strategy:   strat,
+			Allocation: sc.Allocation,
+		})
+	}
+
+	runner, err := live.NewRunner(liveCfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := runner.Start(ctx); err != nil {
+		return nil, err
+	}
+	return runner, nil
+}
+
+// stopRunner waits for the strategies to stop (their context must be cancelled)
+// and optionally sells what they hold
+func stopRunner(runner *live.Runner, flatten bool) {
+	if runner == nil {
+		return
+	}
+	runner.Wait()
+	if !flatten {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := runner.Flatten(ctx); err != nil {
+		log.Printf("❌ Failed to flatten positions: %v", err)
+		return
+	}
+	log.Println("✅ Strategy positions flattened")
+}
+
+func displayStrategies(runner *live.Runner) {
+	fmt.Printf("  %-28s %-10s %-4s %14s %-17s %-22s %s\n",
+		"Strategy", "Symbol", "Int.", "Position", "Last Bar", "Last Signal", "Open Order")
+	for _, s := range runner.Status() {
+		lastBar := "-"
+		if !s.LastBar.IsZero() {
+			lastBar = s.LastBar.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("  %-28s %-10s %-4s %14.8f %-17s %-22s %s\n",
+			s.Name, s.Symbol, s.Interval, s.Position, lastBar, s.LastSignal, s.Pending)
+	}
+	fmt.Println(strings.Repeat("=", 80))
+}
+
+// startReplay starts replaying stored market data through the virtual exchange
+func startReplay(ctx context.Context, ve *exchange.VirtualExchange, stores *history.Stores, cfg config.ReplayConfig, symbols []string) (*exchange.Replay, error) {
+	start, err := parseReplayTime(cfg.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replay start: %w", err)
+	}
+	end := time.Now().UTC()
+	if cfg.End != "" {
+		if end, err = parseReplayTime(cfg.End); err != nil {
+			return nil, fmt.Errorf("invalid replay end: %w", err)
+		}
+	}
+
+	return ve.Replay(ctx, exchange.ReplayConfig{
+		Symbols:  symbols,
+		Start:    start,
+		End:      end,
+		Source:   cfg.Source,
+		Interval: cfg.Interval,
+		Speed:    cfg.Speed,
+		Paused:   cfg.Paused,
+		Candles:  stores.Candles,
+		Trades:   stores.AggTrades,
+	})
+}
+
+// parseReplayTime parses a YYYY-MM-DD date or an RFC3339 time
+func parseReplayTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not YYYY-MM-DD or RFC3339", s)
+	}
+	return t.UTC(), nil
+}
+
+// replayControls reads replay commands, one per line, until in is closed
+func replayControls(replay *exchange.Replay, in io.Reader) {
+	log.Println("Replay controls: pause | resume | step [bars] | seek <time> | speed <x, 0 = max> | status")
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch fields[0] {
+		case "pause":
+			replay.Pause()
+		case "resume":
+			replay.Resume()
+		case "step":
+			bars := 1
+			if len(fields) > 1 {
+				bars, err = strconv.Atoi(fields[1])
+			}
+			if err == nil {
+				replay.Step(bars)
+			}
+		case "seek":
+			if len(fields) < 2 {
+				err = fmt.Errorf("seek needs a time")
+				break
+			}
+			var t time.Time
+			if t, err = parseReplayTime(fields[1]); err == nil {
+				err = replay.Seek(t)
+			}
+		case "speed":
+			if len(fields) < 2 {
+				err = fmt.Errorf("speed needs a multiplier")
+				break
+			}
+			var speed float64
+			if speed, err = strconv.ParseFloat(strings.TrimSuffix(fields[1], "x"), 64); err == nil {
+				err = replay.SetSpeed(speed)
+			}
+		case "status":
+		default:
+			err = fmt.Errorf("unknown command %q", fields[0])
+		}
+
+		if err != nil {
+			log.Printf("⚠️  Replay: %v", err)
+			continue
+		}
+		displayReplay(replay)
+	}
+}
+
+func displayReplay(replay *exchange.Replay) {
+	status := replay.Status()
+	state := "running"
+	switch {
+	case status.Finished:
+		state = "finished"
+	case status.Paused:
+		state = "paused"
+	}
+	speed := "max"
+	if status.Speed > 0 {
+		speed = strconv.FormatFloat(status.Speed, 'g', -1, 64) + "x"
+	}
+
+	progress := float64(status.Time.Sub(status.Start)) / float64(status.End.Sub(status.Start)) * 100
+	fmt.Printf("  Replay %s (%.1f%%)  speed %s  %s\n",
+		status.Time.Format("2006-01-02 15:04:05"), progress, speed, state)
+}
@@ -21,6 +21,10 @@ func SetupRouter(
 	walletHandler *handler.WalletHandler,
 	portfolioHandler *handler.PortfolioHandler,
 	backtestHandler *handler.BacktestHandler,
+	syncHandler *handler.SyncHandler,
+	depthHandler *handler.DepthHandler,
+	perpHandler *handler.PerpHandler,
+	symbolHandler *handler.SymbolHandler,
 ) *gin.Engine {
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
@@ -63,16 +67,44 @@ func SetupRouter(
 			market.GET("/price/:symbol", marketHandler.GetPrice)
 			market.GET("/prices", marketHandler.GetMultiplePrices)
 			market.GET("/stream/:symbol", marketHandler.StreamPrice)
+			market.GET("/streams", marketHandler.GetStreamStatus)
+			market.GET("/symbols", symbolHandler.ListSymbols)
+			market.POST("/symbols/refresh", symbolHandler.RefreshSymbols)
+			market.GET("/symbols/:symbol", symbolHandler.GetSymbol)
 		}
 
 		// Data routes
 		data := v1.Group("/data")
 		{
 			data.POST("/collect", dataHandler.CollectHistoricalData)
+			data.GET("/jobs", dataHandler.GetCollectionJobs)
+			data.GET("/jobs/:id", dataHandler.GetCollectionJob)
+			data.POST("/jobs/:id/resume", dataHandler.ResumeCollectionJob)
 			data.GET("/candles", dataHandler.GetCandles)
 			data.GET("/candles/latest", dataHandler.GetLatestCandle)
 			data.GET("/trades", dataHandler.GetTradeHistory)
 			data.GET("/validate", dataHandler.ValidateData)
+			data.GET("/gaps", dataHandler.GetGaps)
+			data.POST("/gaps/backfill", dataHandler.BackfillGaps)
+			data.GET("/quality", dataHandler.GetQualitySummary)
+			data.GET("/quality/scan", dataHandler.ScanQuality)
+			data.GET("/quality/quarantine", dataHandler.GetQuarantined)
+			data.GET("/sync/status", syncHandler.GetStatus)
+			data.GET("/sync/health", syncHandler.GetHealth)
+			data.POST("/resample", dataHandler.ResampleCandles)
+			data.GET("/resample/verify", dataHandler.VerifyResample)
+			data.POST("/import", dataHandler.ImportCandles)
+			data.GET("/export", dataHandler.ExportCandles)
+			data.GET("/aggtrades", dataHandler.GetAggTrades)
+			data.POST("/aggtrades/collect", dataHandler.CollectAggTrades)
+			data.GET("/bars", dataHandler.GetBars)
+			data.GET("/depth", depthHandler.GetSnapshots)
+			data.GET("/depth/at", depthHandler.GetSnapshotAt)
+			data.GET("/depth/fill", depthHandler.EstimateFill)
+			data.GET("/perp/funding", perpHandler.GetFundingRates)
+			data.GET("/perp/klines", perpHandler.GetPriceKlines)
+			data.GET("/perp/open-interest", perpHandler.GetOpenInterest)
+			data.POST("/perp/collect", perpHandler.Collect)
 		}
 
 		// Wallet routes
@@ -62,6 +62,9 @@ type BacktestRequest struct {
 
 	// Position size
 	PositionSize float64 `json:"position_size" example:"0.01"`
+
+	// Gap handling: "warn" (default), "refuse" or "ffill"
+	GapPolicy string `json:"gap_policy" example:"warn"`
 }
 
 // BacktestResponse represents a backtest response
@@ -227,6 +230,18 @@ func (h *BacktestHandler) RunBacktest(c *gin.Context) {
 		return
 	}
 
+	// Handle gaps in the loaded data
+	gapPolicy, err := history.ParseGapPolicy(req.GapPolicy)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	candles, _, err = history.ApplyGapPolicy(candles, req.Interval, gapPolicy)
+	if err != nil {
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
 	// Create strategy
 	var strat backtest.Strategy
 	switch req.Strategy {
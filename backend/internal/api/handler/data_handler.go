@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,11 +26,11 @@ func NewDataHandler(dataService *history.Service) *DataHandler {
 
 // CollectHistoricalData godoc
 // @Summary Collect historical data
-// @Description Collect historical candle data from Binance (limited to 90 days via API, use CLI for larger datasets)
+// @Description Start a resumable background job collecting historical candle data from Binance. An unfinished job for the same symbol/interval is resumed from its checkpoint.
 // @Tags data
 // @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
 // @Param interval query string true "Candle interval (e.g., 1h, 1d)"
-// @Param days query int false "Number of days to collect (from today backwards, max 90)"
+// @Param days query int false "Number of days to collect (from today backwards)"
 // @Param start query string false "Start date (YYYY-MM-DD format)"
 // @Param end query string false "End date (YYYY-MM-DD format)"
 // @Success 200 {object} response.Response
@@ -40,9 +44,6 @@ func (h *DataHandler) CollectHistoricalData(c *gin.Context) {
 		return
 	}
 
-	// API limit to prevent timeouts
-	const maxDaysViaAPI = 90
-
 	var startTime, endTime time.Time
 	var err error
 
@@ -65,16 +66,6 @@ func (h *DataHandler) CollectHistoricalData(c *gin.Context) {
 			return
 		}
 		endTime = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 23, 59, 59, 999999999, time.UTC)
-
-		// Check date range limit
-		daysDiff := int(endTime.Sub(startTime).Hours() / 24)
-		if daysDiff > maxDaysViaAPI {
-			response.BadRequestResponse(c,
-				"Date range too large. API is limited to 90 days to prevent timeouts. "+
-					"For larger datasets, please use the CLI: "+
-					"./bin/collector -symbol "+symbol+" -interval "+interval+" -start "+startStr+" -end "+endStr)
-			return
-		}
 	} else {
 		// Use days parameter
 		days := 30
@@ -91,30 +82,113 @@ func (h *DataHandler) CollectHistoricalData(c *gin.Context) {
 			}
 		}
 
-		// Check days limit
-		if days > maxDaysViaAPI {
-			response.BadRequestResponse(c,
-				"Too many days requested. API is limited to 90 days to prevent timeouts. "+
-					"For larger datasets, please use the CLI: "+
-					"./bin/collector -symbol "+symbol+" -interval "+interval+" -days "+c.Query("days"))
+		endTime = time.Now().UTC()
+		startTime = endTime.AddDate(0, 0, -days)
+	}
+
+	// Runs in the background; progress is available from /data/jobs/{id}
+	job, err := h.dataService.SubmitCollectionJob(c.Request.Context(), symbol, interval, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, gin.H{
+		"message": "Historical data collection started",
+		"job":     job,
+	})
+}
+
+// GetCollectionJobs godoc
+// @Summary List collection jobs
+// @Description List recent historical data collection jobs with progress and ETA
+// @Tags data
+// @Param status query string false "Filter by status (queued, running, failed, done)"
+// @Param limit query int false "Maximum jobs to return (default 50)"
+// @Success 200 {object} response.Response
+// @Router /data/jobs [get]
+func (h *DataHandler) GetCollectionJobs(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", history.JobStatusQueued, history.JobStatusRunning, history.JobStatusFailed, history.JobStatusDone:
+	default:
+		response.BadRequestResponse(c, "status must be one of queued, running, failed, done")
+		return
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			response.BadRequestResponse(c, "limit must be a positive integer")
 			return
 		}
+		limit = l
+	}
 
-		endTime = time.Now()
-		startTime = endTime.AddDate(0, 0, -days)
+	jobs, err := h.dataService.ListCollectionJobs(c.Request.Context(), status, limit)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, jobs)
+}
+
+// GetCollectionJob godoc
+// @Summary Get collection job
+// @Description Get the status, progress and ETA of a historical data collection job
+// @Tags data
+// @Param id path int true "Job ID"
+// @Success 200 {object} response.Response
+// @Router /data/jobs/{id} [get]
+func (h *DataHandler) GetCollectionJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, "invalid job id")
+		return
 	}
 
-	if err := h.dataService.CollectHistoricalData(c.Request.Context(), symbol, interval, startTime, endTime); err != nil {
+	job, err := h.dataService.GetCollectionJob(c.Request.Context(), id)
+	if err != nil {
 		response.InternalErrorResponse(c, err.Error())
 		return
 	}
+	if job == nil {
+		response.NotFoundResponse(c, "collection job not found")
+		return
+	}
+
+	response.SuccessResponse(c, job)
+}
+
+// ResumeCollectionJob godoc
+// @Summary Resume collection job
+// @Description Restart a failed or interrupted collection job from its checkpoint
+// @Tags data
+// @Param id path int true "Job ID"
+// @Success 200 {object} response.Response
+// @Router /data/jobs/{id}/resume [post]
+func (h *DataHandler) ResumeCollectionJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, "invalid job id")
+		return
+	}
+
+	job, err := h.dataService.ResumeCollectionJob(c.Request.Context(), id)
+	if err != nil {
+		response.BadRequestResponse(c, err.Error())
+		return
+	}
+	if job == nil {
+		response.NotFoundResponse(c, "collection job not found")
+		return
+	}
 
 	response.SuccessResponse(c, gin.H{
-		"message":    "Historical data collection completed",
-		"symbol":     symbol,
-		"interval":   interval,
-		"start_time": startTime,
-		"end_time":   endTime,
+		"message": "Collection job resumed",
+		"job":     job,
 	})
 }
 
@@ -255,3 +329,501 @@ func (h *DataHandler) ValidateData(c *gin.Context) {
 
 	response.SuccessResponse(c, result)
 }
+
+// ResampleCandles godoc
+// @Summary Build derived candles
+// @Description Aggregate stored 1m candles into a higher interval (e.g. 2h, 6h, 3d, 90m) and store them
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string true "Target interval (e.g., 2h, 6h, 3d)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/resample [post]
+func (h *DataHandler) ResampleCandles(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || interval == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, interval, start, and end are required")
+		return
+	}
+
+	if _, err := history.ParseInterval(interval); err != nil {
+		response.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	count, err := h.dataService.MaterializeCandles(c.Request.Context(), symbol, interval, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, gin.H{
+		"message":    "Derived candles stored",
+		"symbol":     symbol,
+		"interval":   interval,
+		"candles":    count,
+		"start_time": startTime,
+		"end_time":   endTime,
+	})
+}
+
+// VerifyResample godoc
+// @Summary Verify derived candles
+// @Description Compare candles derived from 1m data with the exchange-provided bars stored for an interval
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string true "Interval to check (e.g., 1h, 4h)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Param tolerance query number false "Allowed relative difference (default: 1e-6)"
+// @Success 200 {object} response.Response
+// @Router /data/resample/verify [get]
+func (h *DataHandler) VerifyResample(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || interval == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, interval, start, and end are required")
+		return
+	}
+
+	tolerance := 1e-6
+	if tolStr := c.Query("tolerance"); tolStr != "" {
+		t, err := strconv.ParseFloat(tolStr, 64)
+		if err != nil || t < 0 {
+			response.BadRequestResponse(c, "invalid tolerance")
+			return
+		}
+		tolerance = t
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	result, err := h.dataService.VerifyResampledCandles(c.Request.Context(), symbol, interval, startTime, endTime, tolerance)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, result)
+}
+
+// parseDateRange parses YYYY-MM-DD start/end dates into a full-day UTC range.
+// It writes a bad request response and returns false on failure.
+func parseDateRange(c *gin.Context, startStr, endStr string) (time.Time, time.Time, bool) {
+	startTime, err := time.Parse("2006-01-02", startStr)
+	if err != nil {
+		response.BadRequestResponse(c, "invalid start date format, use YYYY-MM-DD")
+		return time.Time{}, time.Time{}, false
+	}
+	startTime = time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, time.UTC)
+
+	endTime, err := time.Parse("2006-01-02", endStr)
+	if err != nil {
+		response.BadRequestResponse(c, "invalid end date format, use YYYY-MM-DD")
+		return time.Time{}, time.Time{}, false
+	}
+	endTime = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 23, 59, 59, 999999999, time.UTC)
+
+	return startTime, endTime, true
+}
+
+// GetGaps godoc
+// @Summary Detect data gaps
+// @Description List every missing candle range for a symbol/interval in the requested period
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string true "Candle interval (e.g., 1h, 1d)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/gaps [get]
+func (h *DataHandler) GetGaps(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || interval == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, interval, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	report, err := h.dataService.DetectGaps(c.Request.Context(), symbol, interval, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, report)
+}
+
+// BackfillGaps godoc
+// @Summary Backfill data gaps
+// @Description Collect exactly the missing candle ranges from Binance and report what is still missing
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string true "Candle interval (e.g., 1h, 1d)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/gaps/backfill [post]
+func (h *DataHandler) BackfillGaps(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || interval == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, interval, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	report, err := h.dataService.BackfillGaps(c.Request.Context(), symbol, interval, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, report)
+}
+
+// GetQualitySummary godoc
+// @Summary Data quality summary
+// @Description Summarize quarantined candles per symbol/interval and check
+// @Tags data
+// @Param symbol query string false "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string false "Candle interval (e.g., 1h, 1d)"
+// @Success 200 {object} response.Response
+// @Router /data/quality [get]
+func (h *DataHandler) GetQualitySummary(c *gin.Context) {
+	summary, err := h.dataService.GetQualitySummary(c.Request.Context(), c.Query("symbol"), c.Query("interval"))
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, summary)
+}
+
+// ScanQuality godoc
+// @Summary Scan stored data quality
+// @Description Validate stored candles (OHLC consistency, volume, alignment, close time, outliers) without modifying them
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string true "Candle interval (e.g., 1h, 1d)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/quality/scan [get]
+func (h *DataHandler) ScanQuality(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || interval == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, interval, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	report, err := h.dataService.ScanQuality(c.Request.Context(), symbol, interval, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, report)
+}
+
+// GetQuarantined godoc
+// @Summary Get quarantined candles
+// @Description List candles rejected or flagged during collection
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string true "Candle interval (e.g., 1h, 1d)"
+// @Param limit query int false "Maximum rows to return (default: 100)"
+// @Success 200 {object} response.Response
+// @Router /data/quality/quarantine [get]
+func (h *DataHandler) GetQuarantined(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+
+	if symbol == "" || interval == "" {
+		response.BadRequestResponse(c, "symbol and interval are required")
+		return
+	}
+
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			response.BadRequestResponse(c, "invalid limit")
+			return
+		}
+		limit = l
+	}
+
+	issues, err := h.dataService.GetQuarantined(c.Request.Context(), symbol, interval, limit)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, issues)
+}
+
+// ImportCandles godoc
+// @Summary Import candle file
+// @Description Import candles from an uploaded CSV, gzip/zip CSV or Parquet file. Duplicate (symbol, open_time) rows are merged.
+// @Tags data
+// @Accept multipart/form-data
+// @Param file formData file true "Candle file (.csv, .csv.gz, .zip, .parquet)"
+// @Param interval query string true "Target candle interval (e.g., 1m, 1h)"
+// @Param symbol query string false "Symbol for files without a symbol column"
+// @Param format query string false "csv or parquet (default: from file name)"
+// @Param columns query string false "Column mapping, e.g. open_time=timestamp,volume=vol"
+// @Param time_unit query string false "Timestamp unit: s, ms, us, ns or auto (default: auto)"
+// @Success 200 {object} response.Response
+// @Router /data/import [post]
+func (h *DataHandler) ImportCandles(c *gin.Context) {
+	interval := c.Query("interval")
+	if interval == "" {
+		response.BadRequestResponse(c, "interval is required")
+		return
+	}
+
+	columns, err := history.ParseColumnMapping(c.Query("columns"))
+	if err != nil {
+		response.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequestResponse(c, "file is required")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+	defer file.Close()
+
+	result, err := h.dataService.ImportCandles(c.Request.Context(), file, fileHeader.Size, fileHeader.Filename, history.ImportOptions{
+		Symbol:   c.Query("symbol"),
+		Interval: interval,
+		Format:   c.Query("format"),
+		Columns:  columns,
+		TimeUnit: c.DefaultQuery("time_unit", history.TimeUnitAuto),
+	})
+	if err != nil {
+		response.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, result)
+}
+
+// ExportCandles godoc
+// @Summary Export candles
+// @Description Download stored candles as CSV (epoch millisecond times) or Parquet
+// @Tags data
+// @Produce octet-stream
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string true "Candle interval (e.g., 1h, 1d)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Param format query string false "csv or parquet (default: csv)"
+// @Success 200 {file} file
+// @Router /data/export [get]
+func (h *DataHandler) ExportCandles(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+	format := c.DefaultQuery("format", history.FormatCSV)
+
+	if symbol == "" || interval == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, interval, start, and end are required")
+		return
+	}
+	if format != history.FormatCSV && format != history.FormatParquet {
+		response.BadRequestResponse(c, "format must be csv or parquet")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	// Buffer so a failure can still be reported as JSON
+	var buf bytes.Buffer
+	if _, err := h.dataService.ExportCandles(c.Request.Context(), &buf, format, symbol, interval, startTime, endTime); err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	contentType := "text/csv"
+	if format == history.FormatParquet {
+		contentType = "application/vnd.apache.parquet"
+	}
+	filename := fmt.Sprintf("%s_%s_%s_%s.%s", symbol, interval, startStr, endStr, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// GetAggTrades godoc
+// @Summary Get aggregated trades
+// @Description Get stored Binance aggTrades for a symbol in the requested period
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Param limit query int false "Maximum rows to return (default: 1000)"
+// @Success 200 {object} response.Response
+// @Router /data/aggtrades [get]
+func (h *DataHandler) GetAggTrades(c *gin.Context) {
+	symbol := c.Query("symbol")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	limit := 1000
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			response.BadRequestResponse(c, "invalid limit")
+			return
+		}
+		limit = l
+	}
+
+	trades, err := h.dataService.GetAggTrades(c.Request.Context(), symbol, startTime, endTime, limit)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, trades)
+}
+
+// CollectAggTrades godoc
+// @Summary Collect aggregated trades
+// @Description Download Binance aggTrades for a symbol, resuming from the latest stored trade
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/aggtrades/collect [post]
+func (h *DataHandler) CollectAggTrades(c *gin.Context) {
+	symbol := c.Query("symbol")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	count, err := h.dataService.CollectAggTrades(c.Request.Context(), symbol, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, gin.H{
+		"symbol":    symbol,
+		"collected": count,
+	})
+}
+
+// GetBars godoc
+// @Summary Build bars from trades
+// @Description Build time, tick, volume or dollar bars from stored aggTrades
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param type query string true "Bar type: time, tick, volume or dollar"
+// @Param size query string true "Interval for time bars (e.g., 5m), threshold otherwise (e.g., 1000)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/bars [get]
+func (h *DataHandler) GetBars(c *gin.Context) {
+	symbol := c.Query("symbol")
+	barType := c.Query("type")
+	size := c.Query("size")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || barType == "" || size == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, type, size, start, and end are required")
+		return
+	}
+
+	spec, err := history.ParseBarSpec(barType, size)
+	if err != nil {
+		response.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	bars, err := h.dataService.BuildBars(c.Request.Context(), symbol, spec, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, bars)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lavumi/crypto-quant/internal/api/response"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/depth"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// DepthHandler handles recorded order book requests
+type DepthHandler struct {
+	depthService *depth.Service
+}
+
+// NewDepthHandler creates a new depth handler
+func NewDepthHandler(depthService *depth.Service) *DepthHandler {
+	return &DepthHandler{
+		depthService: depthService,
+	}
+}
+
+// GetSnapshots godoc
+// @Summary Get order book snapshots
+// @Description Get recorded top-of-book snapshots with spread, imbalance and microprice
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Param limit query int false "Maximum rows to return (default: 1000)"
+// @Success 200 {object} response.Response
+// @Router /data/depth [get]
+func (h *DepthHandler) GetSnapshots(c *gin.Context) {
+	symbol := c.Query("symbol")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	limit := 1000
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			response.BadRequestResponse(c, "invalid limit")
+			return
+		}
+		limit = l
+	}
+
+	snapshots, err := h.depthService.GetSnapshots(c.Request.Context(), symbol, startTime, endTime, limit)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, snapshots)
+}
+
+// GetSnapshotAt godoc
+// @Summary Get order book at a time
+// @Description Get the latest recorded snapshot at or before a time
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param time query string true "Time (RFC3339)"
+// @Success 200 {object} response.Response
+// @Router /data/depth/at [get]
+func (h *DepthHandler) GetSnapshotAt(c *gin.Context) {
+	symbol := c.Query("symbol")
+	timeStr := c.Query("time")
+
+	if symbol == "" || timeStr == "" {
+		response.BadRequestResponse(c, "symbol and time are required")
+		return
+	}
+
+	t, err := time.Parse(time.RFC3339, timeStr)
+	if err != nil {
+		response.BadRequestResponse(c, "invalid time format, use RFC3339")
+		return
+	}
+
+	snap, err := h.depthService.GetSnapshotAt(c.Request.Context(), symbol, t)
+	if err != nil {
+		response.NotFoundResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, snap)
+}
+
+// EstimateFill godoc
+// @Summary Estimate market order fill
+// @Description Walk the recorded book at a time to estimate average price and slippage of a market order
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param time query string true "Time (RFC3339)"
+// @Param side query string true "BUY or SELL"
+// @Param quantity query number true "Order quantity in base asset"
+// @Success 200 {object} response.Response
+// @Router /data/depth/fill [get]
+func (h *DepthHandler) EstimateFill(c *gin.Context) {
+	symbol := c.Query("symbol")
+	timeStr := c.Query("time")
+	side := domain.OrderSide(strings.ToUpper(c.Query("side")))
+	quantityStr := c.Query("quantity")
+
+	if symbol == "" || timeStr == "" || side == "" || quantityStr == "" {
+		response.BadRequestResponse(c, "symbol, time, side, and quantity are required")
+		return
+	}
+	if side != domain.OrderSideBuy && side != domain.OrderSideSell {
+		response.BadRequestResponse(c, "side must be BUY or SELL")
+		return
+	}
+
+	t, err := time.Parse(time.RFC3339, timeStr)
+	if err != nil {
+		response.BadRequestResponse(c, "invalid time format, use RFC3339")
+		return
+	}
+
+	quantity, err := strconv.ParseFloat(quantityStr, 64)
+	if err != nil || quantity <= 0 {
+		response.BadRequestResponse(c, "invalid quantity")
+		return
+	}
+
+	fill, err := h.depthService.EstimateFill(c.Request.Context(), symbol, t, side, quantity)
+	if err != nil {
+		response.NotFoundResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, fill)
+}
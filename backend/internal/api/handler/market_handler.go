@@ -132,6 +132,16 @@ func (h *MarketHandler) StreamPrice(c *gin.Context) {
 	}
 }
 
+// GetStreamStatus godoc
+// @Summary Get market data stream status
+// @Description Get the WebSocket connection state, subscribed streams, reconnect count and recent connection events
+// @Tags market
+// @Success 200 {object} response.Response
+// @Router /market/streams [get]
+func (h *MarketHandler) GetStreamStatus(c *gin.Context) {
+	response.SuccessResponse(c, h.marketService.GetStreamStatus())
+}
+
 // parseIntParam parses an integer parameter from a string
 func parseIntParam(s string) (int, error) {
 	var result int
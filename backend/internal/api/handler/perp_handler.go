@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lavumi/crypto-quant/internal/api/response"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/perp"
+)
+
+// PerpHandler handles perpetual futures funding, open interest and mark/index price requests
+type PerpHandler struct {
+	perpService *perp.Service
+}
+
+// NewPerpHandler creates a new perp handler
+func NewPerpHandler(perpService *perp.Service) *PerpHandler {
+	return &PerpHandler{
+		perpService: perpService,
+	}
+}
+
+// GetFundingRates godoc
+// @Summary Get funding rate history
+// @Description Get stored perpetual futures funding events for a symbol
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/perp/funding [get]
+func (h *PerpHandler) GetFundingRates(c *gin.Context) {
+	symbol := c.Query("symbol")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	rates, err := h.perpService.GetFundingRates(c.Request.Context(), symbol, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, rates)
+}
+
+// GetPriceKlines godoc
+// @Summary Get mark or index price klines
+// @Description Get stored perpetual futures mark or index price klines for a symbol
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param kind query string true "mark or index"
+// @Param interval query string true "Candle interval (e.g., 1h, 1d)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/perp/klines [get]
+func (h *PerpHandler) GetPriceKlines(c *gin.Context) {
+	symbol := c.Query("symbol")
+	kind := c.Query("kind")
+	interval := c.Query("interval")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || kind == "" || interval == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, kind, interval, start, and end are required")
+		return
+	}
+	if kind != perp.KindMark && kind != perp.KindIndex {
+		response.BadRequestResponse(c, "kind must be mark or index")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	candles, err := h.perpService.GetPriceKlines(c.Request.Context(), kind, symbol, interval, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, candles)
+}
+
+// GetOpenInterest godoc
+// @Summary Get open interest history
+// @Description Get stored perpetual futures open interest samples for a symbol
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param period query string true "Sampling period (5m, 15m, 30m, 1h, 2h, 4h, 6h, 12h, 1d)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/perp/open-interest [get]
+func (h *PerpHandler) GetOpenInterest(c *gin.Context) {
+	symbol := c.Query("symbol")
+	period := c.Query("period")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || period == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, period, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	samples, err := h.perpService.GetOpenInterest(c.Request.Context(), symbol, period, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, samples)
+}
+
+// Collect godoc
+// @Summary Collect perpetual futures data
+// @Description Collect funding rates, mark and index price klines and open interest (last 30 days only) from Binance USDⓈ-M futures
+// @Tags data
+// @Param symbol query string true "Trading symbol (e.g., BTCUSDT)"
+// @Param interval query string true "Kline interval and open interest period (e.g., 1h)"
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Router /data/perp/collect [post]
+func (h *PerpHandler) Collect(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if symbol == "" || interval == "" || startStr == "" || endStr == "" {
+		response.BadRequestResponse(c, "symbol, interval, start, and end are required")
+		return
+	}
+
+	startTime, endTime, ok := parseDateRange(c, startStr, endStr)
+	if !ok {
+		return
+	}
+
+	result, err := h.perpService.CollectAll(c.Request.Context(), symbol, interval, startTime, endTime)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, result)
+}
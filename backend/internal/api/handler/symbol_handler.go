@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lavumi/crypto-quant/internal/api/response"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
+)
+
+// SymbolHandler handles symbol metadata and trading rule requests
+type SymbolHandler struct {
+	symbolService *symbols.Service
+}
+
+// NewSymbolHandler creates a new symbol handler
+func NewSymbolHandler(symbolService *symbols.Service) *SymbolHandler {
+	return &SymbolHandler{
+		symbolService: symbolService,
+	}
+}
+
+// ListSymbols godoc
+// @Summary List symbols
+// @Description List exchange symbols with their trading rules (tradable only unless all=true)
+// @Tags market
+// @Param quote query string false "Quote asset filter (e.g., USDT)"
+// @Param all query bool false "Include symbols that are not trading"
+// @Success 200 {object} response.Response
+// @Router /market/symbols [get]
+func (h *SymbolHandler) ListSymbols(c *gin.Context) {
+	quote := c.Query("quote")
+	tradableOnly := c.Query("all") != "true"
+
+	infos, err := h.symbolService.List(c.Request.Context(), quote, tradableOnly)
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, infos)
+}
+
+// GetSymbol godoc
+// @Summary Get symbol info
+// @Description Get a symbol's assets, status and price/lot size/notional rules
+// @Tags market
+// @Param symbol path string true "Trading symbol (e.g., BTCUSDT)"
+// @Success 200 {object} response.Response
+// @Router /market/symbols/{symbol} [get]
+func (h *SymbolHandler) GetSymbol(c *gin.Context) {
+	symbol := c.Param("symbol")
+
+	info, err := h.symbolService.Get(c.Request.Context(), symbol)
+	if err != nil {
+		response.NotFoundResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, info)
+}
+
+// RefreshSymbols godoc
+// @Summary Refresh symbol info
+// @Description Reload exchangeInfo from Binance and replace the stored trading rules
+// @Tags market
+// @Success 200 {object} response.Response
+// @Router /market/symbols/refresh [post]
+func (h *SymbolHandler) RefreshSymbols(c *gin.Context) {
+	count, err := h.symbolService.Refresh(c.Request.Context())
+	if err != nil {
+		response.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	response.SuccessResponse(c, gin.H{"symbols": count})
+}
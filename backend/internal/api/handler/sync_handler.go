@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lavumi/crypto-quant/internal/api/response"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+)
+
+// SyncHandler handles market data sync status requests
+type SyncHandler struct {
+	syncer *history.Syncer
+}
+
+// NewSyncHandler creates a new sync handler (syncer may be nil when sync is disabled)
+func NewSyncHandler(syncer *history.Syncer) *SyncHandler {
+	return &SyncHandler{
+		syncer: syncer,
+	}
+}
+
+// GetStatus godoc
+// @Summary Get sync status
+// @Description Get per-symbol/interval status of the continuous market data sync
+// @Tags data
+// @Success 200 {object} response.Response
+// @Router /data/sync/status [get]
+func (h *SyncHandler) GetStatus(c *gin.Context) {
+	if h.syncer == nil {
+		response.NotFoundResponse(c, "market data sync is not enabled (start the server with --sync)")
+		return
+	}
+
+	response.SuccessResponse(c, h.syncer.Status())
+}
+
+// GetHealth godoc
+// @Summary Get sync health
+// @Description Report whether every synced symbol/interval is current
+// @Tags data
+// @Success 200 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /data/sync/health [get]
+func (h *SyncHandler) GetHealth(c *gin.Context) {
+	if h.syncer == nil {
+		response.NotFoundResponse(c, "market data sync is not enabled (start the server with --sync)")
+		return
+	}
+
+	health := h.syncer.Health()
+	if !health.Healthy {
+		c.JSON(http.StatusServiceUnavailable, response.Response{
+			Success: false,
+			Data:    health,
+			Error: &response.ErrorInfo{
+				Code:    "UNHEALTHY",
+				Message: "one or more sync targets are behind",
+			},
+		})
+		return
+	}
+
+	response.SuccessResponse(c, health)
+}
@@ -2,9 +2,10 @@ package database
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
@@ -47,82 +48,78 @@ func New(dbPath string) (*DB, error) {
 	return &DB{db}, nil
 }
 
-// Migrate runs database migrations
-func (db *DB) Migrate() error {
-	// Interval-specific candle tables
-	intervals := []string{"1m", "5m", "15m", "30m", "1h", "4h", "1d"}
-
-	var migrations []string
-
-	// Create separate table for each interval
-	for _, interval := range intervals {
-		tableName := fmt.Sprintf("candles_%s", interval)
-		migrations = append(migrations,
-			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				symbol TEXT NOT NULL,
-				open_time INTEGER NOT NULL,
-				close_time INTEGER NOT NULL,
-				open REAL NOT NULL,
-				high REAL NOT NULL,
-				low REAL NOT NULL,
-				close REAL NOT NULL,
-				volume REAL NOT NULL,
-				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-				UNIQUE(symbol, open_time)
-			)`, tableName),
-			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_symbol_time
-				ON %s(symbol, open_time DESC)`, tableName, tableName),
-		)
+// intervalPattern matches interval names usable in candle table names (e.g. 1m, 4h, 1w).
+// Monthly "1M" is excluded: SQLite table names are case-insensitive, so it would collide with 1m.
+var intervalPattern = regexp.MustCompile(`^[1-9][0-9]*[mhdw]$`)
+
+// EnsureCandleTable creates the candle table for an interval if it doesn't exist
+func (db *DB) EnsureCandleTable(interval string) error {
+	if !intervalPattern.MatchString(interval) {
+		return fmt.Errorf("invalid candle interval: %q", interval)
+	}
+	for _, stmt := range candleTableDDL(interval) {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create candle table for %s: %w", interval, err)
+		}
 	}
+	return nil
+}
 
-	// Trades table
-	migrations = append(migrations,
-		`CREATE TABLE IF NOT EXISTS trades (
+// candleTableDDL returns the statements that create the candle table for an interval
+func candleTableDDL(interval string) []string {
+	tableName := fmt.Sprintf("candles_%s", interval)
+	return []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			order_id TEXT NOT NULL,
 			symbol TEXT NOT NULL,
-			side TEXT NOT NULL,
+			open_time INTEGER NOT NULL,
+			close_time INTEGER NOT NULL,
+			open REAL NOT NULL,
+			high REAL NOT NULL,
+			low REAL NOT NULL,
+			close REAL NOT NULL,
+			volume REAL NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(symbol, open_time)
+		)`, tableName),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_symbol_time
+			ON %s(symbol, open_time DESC)`, tableName, tableName),
+	}
+}
+
+// symbolPattern matches symbols usable in aggTrade table names (e.g. BTCUSDT)
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]{2,30}$`)
+
+// AggTradeTableName returns the per-symbol aggTrade table name
+func AggTradeTableName(symbol string) string {
+	return "aggtrades_" + strings.ToLower(symbol)
+}
+
+// EnsureAggTradeTable creates the aggTrade table for a symbol if it doesn't exist.
+// The aggregate trade ID is the rowid, so each trade costs a single b-tree entry plus the time index.
+func (db *DB) EnsureAggTradeTable(symbol string) error {
+	if !symbolPattern.MatchString(symbol) {
+		return fmt.Errorf("invalid symbol: %q", symbol)
+	}
+	tableName := AggTradeTableName(symbol)
+
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY,
+			time INTEGER NOT NULL,
 			price REAL NOT NULL,
 			quantity REAL NOT NULL,
-			fee REAL NOT NULL,
-			fee_asset TEXT NOT NULL,
-			timestamp INTEGER NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_trades_symbol_timestamp
-			ON trades(symbol, timestamp DESC)`,
-	)
-
-	// Backtest results table
-	migrations = append(migrations,
-		`CREATE TABLE IF NOT EXISTS backtest_results (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			strategy_name TEXT NOT NULL,
-			symbol TEXT NOT NULL,
-			start_time INTEGER NOT NULL,
-			end_time INTEGER NOT NULL,
-			initial_balance REAL NOT NULL,
-			final_balance REAL NOT NULL,
-			total_return REAL NOT NULL,
-			sharpe_ratio REAL,
-			max_drawdown REAL,
-			win_rate REAL,
-			total_trades INTEGER NOT NULL,
-			config TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_backtest_strategy
-			ON backtest_results(strategy_name, created_at DESC)`,
-	)
-
-	for i, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("migration %d failed: %w", i, err)
+			first_id INTEGER NOT NULL,
+			last_id INTEGER NOT NULL,
+			buyer_maker INTEGER NOT NULL
+		)`, tableName),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_time ON %s(time)`, tableName, tableName),
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create aggTrade table for %s: %w", symbol, err)
 		}
 	}
-
-	log.Println("Database migrations completed successfully")
 	return nil
 }
 
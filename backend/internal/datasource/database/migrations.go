@@ -0,0 +1,537 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Migration is a versioned schema change with its rollback
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+
+	// UpTx and DownTx run after the statements, for changes that depend on
+	// the current schema such as candle tables created on demand
+	UpTx   func(tx *sql.Tx) error
+	DownTx func(tx *sql.Tx) error
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// defaultCandleIntervals get candle tables at install time; other intervals
+// are created on first use by EnsureCandleTable
+var defaultCandleIntervals = []string{"1m", "5m", "15m", "30m", "1h", "4h", "1d"}
+
+// migrations lists every schema change in version order.
+// Statements are idempotent so databases created before versioning adopt them cleanly.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_candle_tables",
+		Up:      defaultCandleTablesUp(),
+		Down:    defaultCandleTablesDown(),
+	},
+	{
+		Version: 2,
+		Name:    "create_trades",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS trades (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				order_id TEXT NOT NULL,
+				symbol TEXT NOT NULL,
+				side TEXT NOT NULL,
+				price REAL NOT NULL,
+				quantity REAL NOT NULL,
+				fee REAL NOT NULL,
+				fee_asset TEXT NOT NULL,
+				timestamp INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_trades_symbol_timestamp
+				ON trades(symbol, timestamp DESC)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS trades`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_backtest_results",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS backtest_results (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				strategy_name TEXT NOT NULL,
+				symbol TEXT NOT NULL,
+				start_time INTEGER NOT NULL,
+				end_time INTEGER NOT NULL,
+				initial_balance REAL NOT NULL,
+				final_balance REAL NOT NULL,
+				total_return REAL NOT NULL,
+				sharpe_ratio REAL,
+				max_drawdown REAL,
+				win_rate REAL,
+				total_trades INTEGER NOT NULL,
+				config TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_backtest_strategy
+				ON backtest_results(strategy_name, created_at DESC)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS backtest_results`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create_candle_quarantine",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS candle_quarantine (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				symbol TEXT NOT NULL,
+				interval TEXT NOT NULL,
+				open_time INTEGER NOT NULL,
+				close_time INTEGER NOT NULL,
+				open REAL NOT NULL,
+				high REAL NOT NULL,
+				low REAL NOT NULL,
+				close REAL NOT NULL,
+				volume REAL NOT NULL,
+				check_name TEXT NOT NULL,
+				severity TEXT NOT NULL,
+				reason TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_candle_quarantine_symbol
+				ON candle_quarantine(symbol, interval, open_time DESC)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS candle_quarantine`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create_collection_jobs",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS collection_jobs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				symbol TEXT NOT NULL,
+				interval TEXT NOT NULL,
+				start_time INTEGER NOT NULL,
+				end_time INTEGER NOT NULL,
+				checkpoint INTEGER NOT NULL,
+				status TEXT NOT NULL,
+				candles_saved INTEGER NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				run_from INTEGER NOT NULL,
+				started_at INTEGER,
+				created_at INTEGER NOT NULL,
+				updated_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_collection_jobs_symbol
+				ON collection_jobs(symbol, interval, status)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS collection_jobs`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "millisecond_timestamps",
+		UpTx:    timestampsToMillis,
+		DownTx:  timestampsToSeconds,
+	},
+	{
+		Version: 7,
+		Name:    "create_depth_snapshots",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS depth_snapshots (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				symbol TEXT NOT NULL,
+				time INTEGER NOT NULL,
+				last_update_id INTEGER NOT NULL,
+				bids TEXT NOT NULL,
+				asks TEXT NOT NULL,
+				spread REAL NOT NULL,
+				mid_price REAL NOT NULL,
+				imbalance REAL NOT NULL,
+				microprice REAL NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_depth_snapshots_symbol_time
+				ON depth_snapshots(symbol, time)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS depth_snapshots`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create_perp_tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS funding_rates (
+				symbol TEXT NOT NULL,
+				funding_time INTEGER NOT NULL,
+				rate REAL NOT NULL,
+				mark_price REAL NOT NULL DEFAULT 0,
+				PRIMARY KEY (symbol, funding_time)
+			)`,
+			`CREATE TABLE IF NOT EXISTS open_interest (
+				symbol TEXT NOT NULL,
+				period TEXT NOT NULL,
+				time INTEGER NOT NULL,
+				amount REAL NOT NULL,
+				value REAL NOT NULL,
+				PRIMARY KEY (symbol, period, time)
+			)`,
+			`CREATE TABLE IF NOT EXISTS perp_price_klines (
+				kind TEXT NOT NULL,
+				symbol TEXT NOT NULL,
+				interval TEXT NOT NULL,
+				open_time INTEGER NOT NULL,
+				close_time INTEGER NOT NULL,
+				open REAL NOT NULL,
+				high REAL NOT NULL,
+				low REAL NOT NULL,
+				close REAL NOT NULL,
+				PRIMARY KEY (kind, symbol, interval, open_time)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS perp_price_klines`,
+			`DROP TABLE IF EXISTS open_interest`,
+			`DROP TABLE IF EXISTS funding_rates`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create_symbol_info",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS symbol_info (
+				symbol TEXT PRIMARY KEY,
+				base_asset TEXT NOT NULL,
+				quote_asset TEXT NOT NULL,
+				status TEXT NOT NULL,
+				tick_size REAL NOT NULL,
+				min_price REAL NOT NULL,
+				max_price REAL NOT NULL,
+				step_size REAL NOT NULL,
+				min_qty REAL NOT NULL,
+				max_qty REAL NOT NULL,
+				min_notional REAL NOT NULL,
+				permissions TEXT NOT NULL,
+				updated_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_symbol_info_quote
+				ON symbol_info(quote_asset, status)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS symbol_info`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "collection_job_millisecond_timestamps",
+		Up:      jobTimestampsUp(),
+		Down:    jobTimestampsDown(),
+	},
+}
+
+// defaultCandleTablesUp creates the default interval candle tables
+func defaultCandleTablesUp() []string {
+	var stmts []string
+	for _, interval := range defaultCandleIntervals {
+		stmts = append(stmts, candleTableDDL(interval)...)
+	}
+	return stmts
+}
+
+// defaultCandleTablesDown drops the default interval candle tables
+func defaultCandleTablesDown() []string {
+	var stmts []string
+	for _, interval := range defaultCandleIntervals {
+		stmts = append(stmts, fmt.Sprintf(`DROP TABLE IF EXISTS candles_%s`, interval))
+	}
+	return stmts
+}
+
+// millisThreshold separates second timestamps from millisecond ones:
+// 1e11 seconds is the year 5138, while 1e11 milliseconds is 1973
+const millisThreshold = 100000000000
+
+// candleTimeTables returns every candle table plus the quarantine table,
+// which all carry open_time and close_time columns
+func candleTimeTables(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(`SELECT name FROM sqlite_master
+		WHERE type = 'table' AND (name LIKE 'candles\_%' ESCAPE '\' OR name = 'candle_quarantine')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list candle tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// timestampsToMillis converts candle and trade timestamps from Unix seconds to
+// milliseconds. Close times were stored truncated (…:59 instead of …:59.999),
+// so the lost 999ms is restored. Rows already in milliseconds are left alone.
+func timestampsToMillis(tx *sql.Tx) error {
+	tables, err := candleTimeTables(tx)
+	if err != nil {
+		return err
+	}
+
+	stmts := []string{
+		fmt.Sprintf(`UPDATE trades SET timestamp = timestamp * 1000 WHERE timestamp < %d`, millisThreshold),
+	}
+	for _, table := range tables {
+		stmts = append(stmts, fmt.Sprintf(
+			`UPDATE %s SET open_time = open_time * 1000, close_time = close_time * 1000 + 999 WHERE open_time < %d`,
+			table, millisThreshold))
+	}
+	return execAll(tx, stmts)
+}
+
+// timestampsToSeconds reverts timestampsToMillis
+func timestampsToSeconds(tx *sql.Tx) error {
+	tables, err := candleTimeTables(tx)
+	if err != nil {
+		return err
+	}
+
+	stmts := []string{
+		fmt.Sprintf(`UPDATE trades SET timestamp = timestamp / 1000 WHERE timestamp >= %d`, millisThreshold),
+	}
+	for _, table := range tables {
+		stmts = append(stmts, fmt.Sprintf(
+			`UPDATE %s SET open_time = open_time / 1000, close_time = close_time / 1000 WHERE open_time >= %d`,
+			table, millisThreshold))
+	}
+	return execAll(tx, stmts)
+}
+
+// jobTimeColumns lists the collection_jobs columns holding Unix timestamps
+var jobTimeColumns = []string{"start_time", "end_time", "checkpoint", "run_from", "started_at", "created_at", "updated_at"}
+
+// jobTimestampsUp converts collection job timestamps from Unix seconds to
+// milliseconds. Each column is checked on its own so reruns are harmless;
+// ABS keeps unset (zero) times, which are far before 1970, in step.
+func jobTimestampsUp() []string {
+	stmts := make([]string, 0, len(jobTimeColumns))
+	for _, col := range jobTimeColumns {
+		stmts = append(stmts, fmt.Sprintf(
+			`UPDATE collection_jobs SET %s = %s * 1000 WHERE ABS(%s) < %d`, col, col, col, millisThreshold))
+	}
+	return stmts
+}
+
+// jobTimestampsDown reverts jobTimestampsUp
+func jobTimestampsDown() []string {
+	stmts := make([]string, 0, len(jobTimeColumns))
+	for _, col := range jobTimeColumns {
+		stmts = append(stmts, fmt.Sprintf(
+			`UPDATE collection_jobs SET %s = %s / 1000 WHERE ABS(%s) >= %d`, col, col, col, millisThreshold))
+	}
+	return stmts
+}
+
+// execAll executes statements in order within a transaction
+func execAll(tx *sql.Tx, stmts []string) error {
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// Migrations returns every known migration in version order
+func Migrations() []Migration {
+	return migrations
+}
+
+// LatestVersion returns the newest migration version
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// ensureMigrationsTable creates the schema_migrations table
+func (db *DB) ensureMigrationsTable() error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations returns applied versions and when they were applied
+func (db *DB) appliedMigrations() (map[int]time.Time, error) {
+	if err := db.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = time.Unix(appliedAt, 0)
+	}
+	return applied, rows.Err()
+}
+
+// SchemaVersion returns the highest applied migration version (0 if none)
+func (db *DB) SchemaVersion() (int, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// MigrationStatus lists every migration and whether it has been applied
+func (db *DB) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// Migrate applies every pending migration
+func (db *DB) Migrate() error {
+	count, err := db.MigrateTo(LatestVersion())
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		log.Printf("Database migrations completed successfully (%d applied)", count)
+	}
+	return nil
+}
+
+// MigrateTo applies pending migrations up to and including target and returns how many ran
+func (db *DB) MigrateTo(target int) (int, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := db.runMigration(m, m.Up, m.UpTx, true); err != nil {
+			return count, err
+		}
+		log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Rollback reverts the given number of most recently applied migrations
+func (db *DB) Rollback(steps int) (int, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := db.runMigration(m, m.Down, m.DownTx, false); err != nil {
+			return count, err
+		}
+		log.Printf("Rolled back migration %d_%s", m.Version, m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// runMigration executes a migration's statements and records it in one transaction
+func (db *DB) runMigration(m Migration, stmts []string, fn func(tx *sql.Tx) error, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %d_%s statement %d failed: %w", m.Version, m.Name, i, err)
+		}
+	}
+	if fn != nil {
+		if err := fn(tx); err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now().Unix())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+	return nil
+}
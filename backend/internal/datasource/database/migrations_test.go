@@ -0,0 +1,297 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// tableExists reports whether a table exists in the SQLite schema
+func tableExists(t *testing.T, db *DB, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name); err != nil {
+		t.Fatalf("failed to look up table %s: %v", name, err)
+	}
+	return count > 0
+}
+
+// createBaselineSchema creates the tables of a database from before versioned
+// migrations, without a schema_migrations table
+func createBaselineSchema(t *testing.T, db *DB) {
+	t.Helper()
+	for _, m := range migrations[:3] {
+		for _, stmt := range m.Up {
+			if _, err := db.Exec(stmt); err != nil {
+				t.Fatalf("failed to create baseline schema: %v", err)
+			}
+		}
+	}
+}
+
+func TestMigrateFromBaseline(t *testing.T) {
+	db := newTestDB(t)
+	createBaselineSchema(t, db)
+
+	// Rows written by the baseline schema, in Unix seconds
+	if _, err := db.Exec(`INSERT INTO candles_1h (symbol, open_time, close_time, open, high, low, close, volume)
+		VALUES ('BTCUSDT', 1704067200, 1704070799, 100, 101, 99, 100.5, 10)`); err != nil {
+		t.Fatalf("failed to insert candle: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO trades (order_id, symbol, side, price, quantity, fee, fee_asset, timestamp)
+		VALUES ('1', 'BTCUSDT', 'BUY', 100, 1, 0.1, 'USDT', 1704067200)`); err != nil {
+		t.Fatalf("failed to insert trade: %v", err)
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version != LatestVersion() {
+		t.Errorf("schema version = %d, want %d", version, LatestVersion())
+	}
+	for _, table := range []string{"candle_quarantine", "collection_jobs", "depth_snapshots", "funding_rates", "symbol_info"} {
+		if !tableExists(t, db, table) {
+			t.Errorf("table %s missing after migrating", table)
+		}
+	}
+
+	// Existing rows survive and move to milliseconds
+	var openTime, closeTime, tradeTime int64
+	if err := db.QueryRow(`SELECT open_time, close_time FROM candles_1h`).Scan(&openTime, &closeTime); err != nil {
+		t.Fatalf("failed to read candle: %v", err)
+	}
+	if openTime != 1704067200000 || closeTime != 1704070799999 {
+		t.Errorf("candle times = %d-%d, want 1704067200000-1704070799999", openTime, closeTime)
+	}
+	if err := db.QueryRow(`SELECT timestamp FROM trades`).Scan(&tradeTime); err != nil {
+		t.Fatalf("failed to read trade: %v", err)
+	}
+	if tradeTime != 1704067200000 {
+		t.Errorf("trade time = %d, want 1704067200000", tradeTime)
+	}
+}
+
+func TestMigrationBookkeeping(t *testing.T) {
+	db := newTestDB(t)
+
+	if version, err := db.SchemaVersion(); err != nil || version != 0 {
+		t.Fatalf("SchemaVersion of an empty database = %d, %v, want 0", version, err)
+	}
+
+	count, err := db.MigrateTo(3)
+	if err != nil {
+		t.Fatalf("MigrateTo: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("applied %d migrations, want 3", count)
+	}
+
+	status, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if len(status) != len(Migrations()) {
+		t.Fatalf("got %d statuses, want %d", len(status), len(Migrations()))
+	}
+	for _, s := range status {
+		if want := s.Version <= 3; s.Applied != want || (s.AppliedAt != nil) != want {
+			t.Errorf("migration %d_%s applied = %v, want %v", s.Version, s.Name, s.Applied, want)
+		}
+	}
+
+	// Only the pending migrations run, and a second run does nothing
+	count, err = db.MigrateTo(LatestVersion())
+	if err != nil {
+		t.Fatalf("MigrateTo: %v", err)
+	}
+	if want := len(Migrations()) - 3; count != want {
+		t.Errorf("applied %d migrations, want %d", count, want)
+	}
+	if count, err := db.MigrateTo(LatestVersion()); err != nil || count != 0 {
+		t.Errorf("rerun applied %d migrations, %v, want 0", count, err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO candles_1m (symbol, open_time, close_time, open, high, low, close, volume)
+		VALUES ('BTCUSDT', 1704067200000, 1704067259999, 100, 101, 99, 100.5, 10)`); err != nil {
+		t.Fatalf("failed to insert candle: %v", err)
+	}
+
+	// Roll back to before the millisecond conversion; its DownTx restores seconds
+	steps := LatestVersion() - 5
+	count, err := db.Rollback(steps)
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if count != steps {
+		t.Errorf("rolled back %d migrations, want %d", count, steps)
+	}
+	if version, _ := db.SchemaVersion(); version != 5 {
+		t.Errorf("schema version = %d, want 5", version)
+	}
+	if tableExists(t, db, "depth_snapshots") {
+		t.Error("depth_snapshots still exists after rolling back")
+	}
+
+	var openTime, closeTime int64
+	if err := db.QueryRow(`SELECT open_time, close_time FROM candles_1m`).Scan(&openTime, &closeTime); err != nil {
+		t.Fatalf("failed to read candle: %v", err)
+	}
+	if openTime != 1704067200 || closeTime != 1704067259 {
+		t.Errorf("candle times = %d-%d, want 1704067200-1704067259", openTime, closeTime)
+	}
+
+	// Reapplying converts back without touching other columns
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if err := db.QueryRow(`SELECT open_time, close_time FROM candles_1m`).Scan(&openTime, &closeTime); err != nil {
+		t.Fatalf("failed to read candle: %v", err)
+	}
+	if openTime != 1704067200000 || closeTime != 1704067259999 {
+		t.Errorf("candle times = %d-%d, want 1704067200000-1704067259999", openTime, closeTime)
+	}
+
+	// Rolling back everything leaves only the bookkeeping table
+	if _, err := db.Rollback(len(Migrations())); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if tableExists(t, db, "candles_1m") || tableExists(t, db, "trades") {
+		t.Error("tables left after rolling back every migration")
+	}
+	if version, _ := db.SchemaVersion(); version != 0 {
+		t.Errorf("schema version = %d, want 0", version)
+	}
+}
+
+func TestEnsureCandleTable(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.MigrateTo(5); err != nil {
+		t.Fatalf("MigrateTo: %v", err)
+	}
+
+	// Intervals without a default table get one on first use
+	if tableExists(t, db, "candles_3m") {
+		t.Fatal("candles_3m exists before first use")
+	}
+	for i := 0; i < 2; i++ {
+		if err := db.EnsureCandleTable("3m"); err != nil {
+			t.Fatalf("EnsureCandleTable: %v", err)
+		}
+	}
+	if !tableExists(t, db, "candles_3m") {
+		t.Error("candles_3m missing after EnsureCandleTable")
+	}
+
+	for _, interval := range []string{"1M", "0m", "1s", "1m; DROP TABLE trades", ""} {
+		if err := db.EnsureCandleTable(interval); err == nil {
+			t.Errorf("EnsureCandleTable(%q) succeeded, want error", interval)
+		}
+	}
+
+	// Later migrations also convert tables created on demand
+	if _, err := db.Exec(`INSERT INTO candles_3m (symbol, open_time, close_time, open, high, low, close, volume)
+		VALUES ('BTCUSDT', 1704067200, 1704067379, 100, 101, 99, 100.5, 10)`); err != nil {
+		t.Fatalf("failed to insert candle: %v", err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	var openTime int64
+	if err := db.QueryRow(`SELECT open_time FROM candles_3m`).Scan(&openTime); err != nil {
+		t.Fatalf("failed to read candle: %v", err)
+	}
+	if openTime != 1704067200000 {
+		t.Errorf("open time = %d, want 1704067200000", openTime)
+	}
+}
+
+func TestMillisecondTimestampMigrations(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.MigrateTo(5); err != nil {
+		t.Fatalf("MigrateTo: %v", err)
+	}
+
+	// Fixture rows written in Unix seconds before the conversions
+	fixtures := []string{
+		`INSERT INTO candles_1m (symbol, open_time, close_time, open, high, low, close, volume)
+			VALUES ('BTCUSDT', 1704067200, 1704067259, 100, 101, 99, 100.5, 10)`,
+		`INSERT INTO candle_quarantine (symbol, interval, open_time, close_time, open, high, low, close, volume, check_name, severity, reason)
+			VALUES ('BTCUSDT', '1m', 1704067260, 1704067319, 100, 99, 101, 100, 1, 'ohlc', 'error', 'low above high')`,
+		`INSERT INTO trades (order_id, symbol, side, price, quantity, fee, fee_asset, timestamp)
+			VALUES ('1', 'BTCUSDT', 'BUY', 100, 1, 0.1, 'USDT', 1704067230)`,
+		`INSERT INTO collection_jobs (symbol, interval, start_time, end_time, checkpoint, status, run_from, started_at, created_at, updated_at)
+			VALUES ('BTCUSDT', '1m', 1704067200, 1704153600, 1704070800, 'failed', 1704067200, 1704067500, 1704067400, 1704067600)`,
+		`INSERT INTO collection_jobs (symbol, interval, start_time, end_time, checkpoint, status, run_from, started_at, created_at, updated_at)
+			VALUES ('ETHUSDT', '1m', 1704067200, 1704153600, 1704067200, 'queued', -62135596800, NULL, 1704067400, 1704067400)`,
+	}
+	for _, stmt := range fixtures {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to insert fixture: %v", err)
+		}
+	}
+
+	check := func(query string, want ...int64) {
+		t.Helper()
+		got := make([]int64, len(want))
+		dest := make([]interface{}, len(want))
+		for i := range got {
+			dest[i] = &got[i]
+		}
+		if err := db.QueryRow(query).Scan(dest...); err != nil {
+			t.Fatalf("failed to query %q: %v", query, err)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s = %v, want %v", query, got, want)
+				return
+			}
+		}
+	}
+	checkAll := func() {
+		t.Helper()
+		check(`SELECT open_time, close_time FROM candles_1m`, 1704067200000, 1704067259999)
+		check(`SELECT open_time, close_time FROM candle_quarantine`, 1704067260000, 1704067319999)
+		check(`SELECT timestamp FROM trades`, 1704067230000)
+		check(`SELECT start_time, end_time, checkpoint, run_from, started_at, created_at, updated_at FROM collection_jobs WHERE symbol = 'BTCUSDT'`,
+			1704067200000, 1704153600000, 1704070800000, 1704067200000, 1704067500000, 1704067400000, 1704067600000)
+		// An unset run start moves to milliseconds too; a missing started_at stays NULL
+		check(`SELECT run_from, created_at, COUNT(started_at) FROM collection_jobs WHERE symbol = 'ETHUSDT'`,
+			-62135596800000, 1704067400000, 0)
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	checkAll()
+
+	// Rolling back both conversions restores seconds, and reapplying them is exact
+	if _, err := db.Rollback(LatestVersion() - 5); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	check(`SELECT start_time, started_at FROM collection_jobs WHERE symbol = 'BTCUSDT'`, 1704067200, 1704067500)
+	check(`SELECT open_time, close_time FROM candles_1m`, 1704067200, 1704067259)
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	checkAll()
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// PostgresDB wraps a PostgreSQL connection used for market data storage
+type PostgresDB struct {
+	*sqlx.DB
+	Timescale bool // TimescaleDB extension is available
+}
+
+// NewPostgres connects to PostgreSQL and enables TimescaleDB when it is installed
+func NewPostgres(dsn string) (*PostgresDB, error) {
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+
+	pg := &PostgresDB{DB: db}
+
+	// Hypertables are optional: plain PostgreSQL works with the same schema
+	var available bool
+	err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'timescaledb')`).Scan(&available)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to check timescaledb extension: %w", err)
+	}
+	if available {
+		if _, err := db.Exec(`CREATE EXTENSION IF NOT EXISTS timescaledb`); err != nil {
+			log.Printf("⚠️  TimescaleDB is available but could not be enabled: %v", err)
+		} else {
+			pg.Timescale = true
+		}
+	}
+
+	if err := pg.ensureTradesTable(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := pg.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return pg, nil
+}
+
+// postgresMigrations are the schema changes that apply to PostgreSQL market
+// data, keeping the SQLite migration versions
+var postgresMigrations = []Migration{
+	{
+		Version: 6,
+		Name:    "millisecond_timestamps",
+		UpTx:    pgTimestampsToMillis,
+	},
+}
+
+// migrate applies pending PostgreSQL migrations, recording them in schema_migrations
+func (db *PostgresDB) migrate() error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at BIGINT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	for _, m := range postgresMigrations {
+		var applied bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to query schema_migrations: %w", err)
+		}
+		if applied {
+			continue
+		}
+
+		if err := db.runMigration(m); err != nil {
+			return err
+		}
+		log.Printf("Applied PostgreSQL migration %d_%s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// runMigration applies a migration and records it in one transaction
+func (db *PostgresDB) runMigration(m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.UpTx(tx); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+		m.Version, m.Name, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// pgCandleTables returns every candle table in the current schema
+func pgCandleTables(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name LIKE 'candles\_%'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list candle tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// pgTimestampsToMillis converts candle and trade timestamps written in Unix
+// seconds to milliseconds, like timestampsToMillis does for SQLite
+func pgTimestampsToMillis(tx *sql.Tx) error {
+	tables, err := pgCandleTables(tx)
+	if err != nil {
+		return err
+	}
+
+	stmts := []string{
+		fmt.Sprintf(`UPDATE trades SET timestamp = timestamp * 1000 WHERE timestamp < %d`, millisThreshold),
+	}
+	for _, table := range tables {
+		stmts = append(stmts, fmt.Sprintf(
+			`UPDATE %s SET open_time = open_time * 1000, close_time = close_time * 1000 + 999 WHERE open_time < %d`,
+			table, millisThreshold))
+	}
+	return execAll(tx, stmts)
+}
+
+// EnsureCandleTable creates the candle table for an interval if it doesn't exist.
+// With TimescaleDB the table becomes a hypertable on the millisecond open_time
+// with chunks spanning chunk.
+func (db *PostgresDB) EnsureCandleTable(interval string, chunk time.Duration) error {
+	if !intervalPattern.MatchString(interval) {
+		return fmt.Errorf("invalid candle interval: %q", interval)
+	}
+	tableName := fmt.Sprintf("candles_%s", interval)
+
+	// The primary key includes open_time so the table can be partitioned by it
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			symbol TEXT NOT NULL,
+			open_time BIGINT NOT NULL,
+			close_time BIGINT NOT NULL,
+			open DOUBLE PRECISION NOT NULL,
+			high DOUBLE PRECISION NOT NULL,
+			low DOUBLE PRECISION NOT NULL,
+			close DOUBLE PRECISION NOT NULL,
+			volume DOUBLE PRECISION NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (symbol, open_time)
+		)`, tableName),
+	}
+	if db.Timescale {
+		stmts = append(stmts, fmt.Sprintf(
+			`SELECT create_hypertable('%s', 'open_time', chunk_time_interval => %d, if_not_exists => TRUE, migrate_data => TRUE)`,
+			tableName, chunk.Milliseconds()))
+	}
+
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create candle table for %s: %w", interval, err)
+		}
+	}
+	return nil
+}
+
+// EnsureAggTradeTable creates the aggTrade table for a symbol if it doesn't exist.
+// With TimescaleDB it becomes a hypertable on the millisecond trade time with daily chunks.
+func (db *PostgresDB) EnsureAggTradeTable(symbol string) error {
+	if !symbolPattern.MatchString(symbol) {
+		return fmt.Errorf("invalid symbol: %q", symbol)
+	}
+	tableName := AggTradeTableName(symbol)
+
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id BIGINT NOT NULL,
+			time BIGINT NOT NULL,
+			price DOUBLE PRECISION NOT NULL,
+			quantity DOUBLE PRECISION NOT NULL,
+			first_id BIGINT NOT NULL,
+			last_id BIGINT NOT NULL,
+			buyer_maker BOOLEAN NOT NULL,
+			PRIMARY KEY (id, time)
+		)`, tableName),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_time ON %s(time)`, tableName, tableName),
+	}
+	if db.Timescale {
+		stmts = append(stmts, fmt.Sprintf(
+			`SELECT create_hypertable('%s', 'time', chunk_time_interval => %d, if_not_exists => TRUE, migrate_data => TRUE)`,
+			tableName, (24*time.Hour).Milliseconds()))
+	}
+
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create aggTrade table for %s: %w", symbol, err)
+		}
+	}
+	return nil
+}
+
+// ensureTradesTable creates the trades table
+func (db *PostgresDB) ensureTradesTable() error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS trades (
+			id BIGSERIAL PRIMARY KEY,
+			order_id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,
+			price DOUBLE PRECISION NOT NULL,
+			quantity DOUBLE PRECISION NOT NULL,
+			fee DOUBLE PRECISION NOT NULL,
+			fee_asset TEXT NOT NULL,
+			timestamp BIGINT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trades_symbol_timestamp
+			ON trades(symbol, timestamp DESC)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create trades table: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the database connection
+func (db *PostgresDB) Close() error {
+	return db.DB.Close()
+}
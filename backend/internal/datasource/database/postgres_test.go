@@ -0,0 +1,116 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// postgresTestDSN returns the DSN from CQ_TEST_POSTGRES_DSN pointed at a fresh
+// schema that is dropped after the test, or skips the test when it is unset
+func postgresTestDSN(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv("CQ_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("CQ_TEST_POSTGRES_DSN is not set")
+	}
+
+	admin, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open postgres: %v", err)
+	}
+	schema := fmt.Sprintf("cq_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	// Unknown connection parameters are sent to the server as run-time settings
+	switch {
+	case !strings.Contains(dsn, "://"):
+		return dsn + " search_path=" + schema
+	case strings.Contains(dsn, "?"):
+		return dsn + "&search_path=" + schema
+	default:
+		return dsn + "?search_path=" + schema
+	}
+}
+
+func TestPostgresMillisecondTimestamps(t *testing.T) {
+	dsn := postgresTestDSN(t)
+
+	// Tables and rows written in Unix seconds before the conversion
+	raw, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open postgres: %v", err)
+	}
+	defer raw.Close()
+	fixtures := []string{
+		`CREATE TABLE candles_1m (
+			symbol TEXT NOT NULL,
+			open_time BIGINT NOT NULL,
+			close_time BIGINT NOT NULL,
+			open DOUBLE PRECISION NOT NULL,
+			high DOUBLE PRECISION NOT NULL,
+			low DOUBLE PRECISION NOT NULL,
+			close DOUBLE PRECISION NOT NULL,
+			volume DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (symbol, open_time)
+		)`,
+		`INSERT INTO candles_1m VALUES ('BTCUSDT', 1704067200, 1704067259, 100, 101, 99, 100.5, 10)`,
+		`CREATE TABLE trades (
+			id BIGSERIAL PRIMARY KEY,
+			order_id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,
+			price DOUBLE PRECISION NOT NULL,
+			quantity DOUBLE PRECISION NOT NULL,
+			fee DOUBLE PRECISION NOT NULL,
+			fee_asset TEXT NOT NULL,
+			timestamp BIGINT NOT NULL
+		)`,
+		`INSERT INTO trades (order_id, symbol, side, price, quantity, fee, fee_asset, timestamp)
+			VALUES ('1', 'BTCUSDT', 'BUY', 100, 1, 0.1, 'USDT', 1704067230)`,
+	}
+	for _, stmt := range fixtures {
+		if _, err := raw.Exec(stmt); err != nil {
+			t.Fatalf("failed to create fixture: %v", err)
+		}
+	}
+
+	// Connecting converts once; a second connection leaves the rows alone
+	for i := 0; i < 2; i++ {
+		pg, err := NewPostgres(dsn)
+		if err != nil {
+			t.Fatalf("NewPostgres: %v", err)
+		}
+		pg.Close()
+	}
+
+	var openTime, closeTime, tradeTime int64
+	if err := raw.QueryRow(`SELECT open_time, close_time FROM candles_1m`).Scan(&openTime, &closeTime); err != nil {
+		t.Fatalf("failed to read candle: %v", err)
+	}
+	if openTime != 1704067200000 || closeTime != 1704067259999 {
+		t.Errorf("candle times = %d-%d, want 1704067200000-1704067259999", openTime, closeTime)
+	}
+	if err := raw.QueryRow(`SELECT timestamp FROM trades`).Scan(&tradeTime); err != nil {
+		t.Fatalf("failed to read trade: %v", err)
+	}
+	if tradeTime != 1704067230000 {
+		t.Errorf("trade time = %d, want 1704067230000", tradeTime)
+	}
+
+	var version int
+	if err := raw.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil || version != 6 {
+		t.Errorf("recorded version = %d, %v, want 6", version, err)
+	}
+}
@@ -2,22 +2,73 @@ package exchange
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
 	"github.com/lavumi/crypto-quant/internal/domain"
 )
 
+// SymbolRules rounds and validates orders against exchange trading rules
+type SymbolRules interface {
+	// PrepareOrder rounds the order's price and quantity in place and validates it.
+	// refPrice is the expected fill price for market orders.
+	PrepareOrder(ctx context.Context, order *domain.Order, refPrice float64) error
+}
+
+// binanceHistoryWindow is the longest startTime-endTime span allOrders and myTrades accept
+const binanceHistoryWindow = 24 * time.Hour
+
+// binanceMaxHistoryLimit is the largest page allOrders and myTrades return
+const binanceMaxHistoryLimit = 1000
+
+// binanceMaxKlineLimit is the largest page klines returns
+const binanceMaxKlineLimit = 1000
+
+// binanceUnknownOrder is the API error code for an order that does not exist
+const binanceUnknownOrder = -2013
+
+// binanceFees is the base tier spot fee schedule
+var binanceFees = FeeSchedule{Maker: 0.001, Taker: 0.001}
+
+func init() {
+	RegisterVenue(Venue{
+		Name:    "binance",
+		Symbols: SymbolStyleConcat,
+		Fees:    binanceFees,
+		New: func(cfg VenueConfig) (VenueExchange, error) {
+			be, err := NewBinanceExchange(cfg.APIKey, cfg.SecretKey, cfg.Testnet)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.HTTPClient != nil {
+				be.client.HTTPClient = cfg.HTTPClient
+			}
+			be.SetEndpoints(BinanceEndpoints{BaseURL: cfg.BaseURL, WsBaseURL: cfg.WsBaseURL})
+			be.fees = cfg.fees(binanceFees)
+			return be, nil
+		},
+	})
+}
+
 // BinanceExchange implements Exchange interface for Binance
 type BinanceExchange struct {
 	client           *binance.Client
+	endpoints        BinanceEndpoints
+	fees             FeeSchedule
+	rules            SymbolRules
 	mu               sync.RWMutex
 	prices           map[string]float64
 	priceSubscribers map[string][]chan float64
-	wsCleanup        []func()
+	streams          *StreamManager
+	closedC          chan struct{}
 	closed           bool
 }
 
@@ -32,10 +83,12 @@ func NewBinanceExchange(apiKey, secretKey string, useTestnet bool) (*BinanceExch
 
 	be := &BinanceExchange{
 		client:           client,
+		fees:             binanceFees,
 		prices:           make(map[string]float64),
 		priceSubscribers: make(map[string][]chan float64),
-		wsCleanup:        make([]func(), 0),
+		closedC:          make(chan struct{}),
 	}
+	be.streams = NewStreamManager(be.wsCombinedEndpoint, StreamConfig{})
 
 	return be, nil
 }
@@ -49,11 +102,25 @@ func (be *BinanceExchange) SetClient(client *binance.Client) {
 	if be.priceSubscribers == nil {
 		be.priceSubscribers = make(map[string][]chan float64)
 	}
-	if be.wsCleanup == nil {
-		be.wsCleanup = make([]func(), 0)
+	if be.streams == nil {
+		be.closedC = make(chan struct{})
+		be.streams = NewStreamManager(be.wsCombinedEndpoint, StreamConfig{})
+	}
+}
+
+// SetEndpoints points the REST client and streams at other base URLs (e.g. the mock server)
+func (be *BinanceExchange) SetEndpoints(endpoints BinanceEndpoints) {
+	be.endpoints = endpoints
+	if endpoints.BaseURL != "" {
+		be.client.BaseURL = endpoints.BaseURL
 	}
 }
 
+// SetSymbolRules makes PlaceOrder round and validate orders before sending them
+func (be *BinanceExchange) SetSymbolRules(rules SymbolRules) {
+	be.rules = rules
+}
+
 // GetCurrentPrice returns the current market price (always fetches fresh data from API)
 func (be *BinanceExchange) GetCurrentPrice(ctx context.Context, symbol string) (float64, error) {
 	// Always fetch fresh price from API
@@ -93,29 +160,55 @@ func (be *BinanceExchange) GetCandles(ctx context.Context, symbol, interval stri
 
 	candles := make([]*domain.Candle, 0, len(klines))
 	for _, k := range klines {
-		open, _ := strconv.ParseFloat(k.Open, 64)
-		high, _ := strconv.ParseFloat(k.High, 64)
-		low, _ := strconv.ParseFloat(k.Low, 64)
-		closePrice, _ := strconv.ParseFloat(k.Close, 64)
-		volume, _ := strconv.ParseFloat(k.Volume, 64)
-
-		candle := &domain.Candle{
-			Symbol:    symbol,
-			OpenTime:  time.Unix(k.OpenTime/1000, 0),
-			CloseTime: time.Unix(k.CloseTime/1000, 0),
-			Open:      open,
-			High:      high,
-			Low:       low,
-			Close:     closePrice,
-			Volume:    volume,
-		}
-		candles = append(candles, candle)
+		candles = append(candles, candleFromKline(symbol, k))
 	}
 
 	return candles, nil
 }
 
-// PlaceOrder submits a new order
+// GetKlines returns up to limit candles opening in [start, end], oldest first
+func (be *BinanceExchange) GetKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	service := be.client.NewKlinesService().
+		Symbol(symbol).
+		Interval(interval).
+		Limit(min(limit, binanceMaxKlineLimit))
+	if !start.IsZero() {
+		service = service.StartTime(start.UnixMilli())
+	}
+	if !end.IsZero() {
+		service = service.EndTime(end.UnixMilli())
+	}
+
+	klines, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candles: %w", err)
+	}
+
+	candles := make([]*domain.Candle, 0, len(klines))
+	for _, k := range klines {
+		candles = append(candles, candleFromKline(symbol, k))
+	}
+	return candles, nil
+}
+
+// Venue names the venue
+func (be *BinanceExchange) Venue() string {
+	return "binance"
+}
+
+// MaxKlineLimit is the largest page klines returns
+func (be *BinanceExchange) MaxKlineLimit() int {
+	return binanceMaxKlineLimit
+}
+
+// Fees returns the configured fee schedule
+func (be *BinanceExchange) Fees() FeeSchedule {
+	return be.fees
+}
+
+// PlaceOrder submits a new order.
+// An order that already carries a client order ID is looked up first, so a
+// retried submit returns the order Binance already has instead of trading twice.
 func (be *BinanceExchange) PlaceOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
 	be.mu.RLock()
 	if be.closed {
@@ -124,6 +217,36 @@ func (be *BinanceExchange) PlaceOrder(ctx context.Context, order *domain.Order)
 	}
 	be.mu.RUnlock()
 
+	if err := checkOrderType(order); err != nil {
+		return order, err
+	}
+
+	if order.ClientOrderID != "" {
+		existing, err := be.GetOrder(ctx, domain.OrderRef{Symbol: order.Symbol, ClientOrderID: order.ClientOrderID})
+		if err == nil {
+			return existing, nil
+		}
+		if !isUnknownOrder(err) {
+			return nil, fmt.Errorf("failed to check for duplicate order: %w", err)
+		}
+	}
+
+	// Round to tick/step size and check min notional so Binance doesn't reject the order
+	if be.rules != nil {
+		var refPrice float64
+		if order.Type == domain.OrderTypeMarket {
+			price, err := be.GetCurrentPrice(ctx, order.Symbol)
+			if err != nil {
+				return nil, err
+			}
+			refPrice = price
+		}
+		if err := be.rules.PrepareOrder(ctx, order, refPrice); err != nil {
+			order.Status = domain.OrderStatusRejected
+			return order, fmt.Errorf("order violates trading rules: %w", err)
+		}
+	}
+
 	// Create order service
 	var orderService *binance.CreateOrderService
 
@@ -143,145 +266,303 @@ func (be *BinanceExchange) PlaceOrder(ctx context.Context, order *domain.Order)
 	} else {
 		orderService = orderService.
 			Type(binance.OrderTypeLimit).
-			Price(fmt.Sprintf("%.8f", order.Price)).
+			Price(strconv.FormatFloat(order.Price, 'f', -1, 64)).
 			TimeInForce(binance.TimeInForceTypeGTC)
 	}
 
-	// Set quantity
-	orderService = orderService.Quantity(fmt.Sprintf("%.8f", order.Quantity))
+	// Set quantity and client order ID
+	assignClientOrderID(order)
+	orderService = orderService.
+		Quantity(strconv.FormatFloat(order.Quantity, 'f', -1, 64)).
+		NewClientOrderID(order.ClientOrderID)
 
 	// Execute order
 	response, err := orderService.Do(ctx)
 	if err != nil {
+		// The order may have reached Binance even though the response was lost
+		if !common.IsAPIError(err) {
+			if existing, getErr := be.GetOrder(ctx, order.Ref()); getErr == nil {
+				return existing, nil
+			}
+		}
 		order.Status = domain.OrderStatusRejected
 		return order, fmt.Errorf("failed to place order: %w", err)
 	}
 
 	// Map response to our order type
-	order.ID = fmt.Sprintf("%d", response.OrderID)
-	order.CreatedAt = time.Unix(response.TransactTime/1000, 0)
+	order.ID = strconv.FormatInt(response.OrderID, 10)
+	order.ClientOrderID = response.ClientOrderID
+	order.CreatedAt = domain.TimeFromMillis(response.TransactTime)
 	order.UpdatedAt = order.CreatedAt
-
-	switch response.Status {
-	case binance.OrderStatusTypeNew:
-		order.Status = domain.OrderStatusNew
-	case binance.OrderStatusTypeFilled:
-		order.Status = domain.OrderStatusFilled
-		executedQty, _ := strconv.ParseFloat(response.ExecutedQuantity, 64)
-		order.FilledQty = executedQty
-
-		if len(response.Fills) > 0 {
-			totalCost := 0.0
-			totalQty := 0.0
-			for _, fill := range response.Fills {
-				price, _ := strconv.ParseFloat(fill.Price, 64)
-				qty, _ := strconv.ParseFloat(fill.Quantity, 64)
-				totalCost += price * qty
-				totalQty += qty
-			}
-			if totalQty > 0 {
-				order.AvgPrice = totalCost / totalQty
-			}
+	order.Status = orderStatusFromBinance(response.Status)
+	order.FilledQty, _ = strconv.ParseFloat(response.ExecutedQuantity, 64)
+
+	if len(response.Fills) > 0 {
+		totalCost := 0.0
+		totalQty := 0.0
+		for _, fill := range response.Fills {
+			price, _ := strconv.ParseFloat(fill.Price, 64)
+			qty, _ := strconv.ParseFloat(fill.Quantity, 64)
+			totalCost += price * qty
+			totalQty += qty
+		}
+		if totalQty > 0 {
+			order.AvgPrice = totalCost / totalQty
 		}
-		now := time.Now()
-		order.ExecutedAt = &now
-	case binance.OrderStatusTypeCanceled:
-		order.Status = domain.OrderStatusCancelled
-	case binance.OrderStatusTypeRejected:
-		order.Status = domain.OrderStatusRejected
-	default:
-		order.Status = domain.OrderStatusNew
+	}
+	if order.Status == domain.OrderStatusFilled {
+		executedAt := order.UpdatedAt
+		order.ExecutedAt = &executedAt
 	}
 
 	return order, nil
 }
 
-// GetOrder retrieves order information
-func (be *BinanceExchange) GetOrder(ctx context.Context, orderID string) (*domain.Order, error) {
-	_, err := strconv.ParseInt(orderID, 10, 64)
+// GetOrder retrieves an order by exchange ID or client order ID
+func (be *BinanceExchange) GetOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	orderID, err := parseOrderRef(ref)
 	if err != nil {
-		return nil, fmt.Errorf("invalid order ID: %w", err)
+		return nil, err
+	}
+
+	service := be.client.NewGetOrderService().Symbol(ref.Symbol)
+	if orderID != 0 {
+		service = service.OrderID(orderID)
+	} else {
+		service = service.OrigClientOrderID(ref.ClientOrderID)
 	}
 
-	// Note: We need the symbol to query order, but we don't have it here
-	// This is a limitation of the current interface design
-	// In production, you might want to store orders locally or change the interface
-	return nil, fmt.Errorf("GetOrder not fully implemented for Binance - needs symbol")
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order %s: %w", ref, err)
+	}
+	return orderFromBinance(res), nil
 }
 
-// CancelOrder cancels an existing order
-func (be *BinanceExchange) CancelOrder(ctx context.Context, orderID string) error {
-	_, err := strconv.ParseInt(orderID, 10, 64)
+// CancelOrder cancels an open order by exchange ID or client order ID
+func (be *BinanceExchange) CancelOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	orderID, err := parseOrderRef(ref)
 	if err != nil {
-		return fmt.Errorf("invalid order ID: %w", err)
+		return nil, err
 	}
 
-	// Note: We need the symbol to cancel order
-	// This is a limitation of the current interface design
-	return fmt.Errorf("CancelOrder not fully implemented for Binance - needs symbol")
+	service := be.client.NewCancelOrderService().Symbol(ref.Symbol)
+	if orderID != 0 {
+		service = service.OrderID(orderID)
+	} else {
+		service = service.OrigClientOrderID(ref.ClientOrderID)
+	}
+
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel order %s: %w", ref, err)
+	}
+	return orderFromCancel(res), nil
 }
 
-// StreamKlines streams real-time kline/candle data
-func (be *BinanceExchange) StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error {
-	wsHandler := func(event *binance.WsKlineEvent) {
-		k := event.Kline
-		open, _ := strconv.ParseFloat(k.Open, 64)
-		high, _ := strconv.ParseFloat(k.High, 64)
-		low, _ := strconv.ParseFloat(k.Low, 64)
-		closePrice, _ := strconv.ParseFloat(k.Close, 64)
-		volume, _ := strconv.ParseFloat(k.Volume, 64)
-
-		candle := &domain.Candle{
-			Symbol:    symbol,
-			OpenTime:  time.Unix(k.StartTime/1000, 0),
-			CloseTime: time.Unix(k.EndTime/1000, 0),
-			Open:      open,
-			High:      high,
-			Low:       low,
-			Close:     closePrice,
-			Volume:    volume,
-		}
+// GetOpenOrders lists open orders for a symbol (all symbols if empty, which costs more request weight)
+func (be *BinanceExchange) GetOpenOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	service := be.client.NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
 
-		callback(candle)
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders: %w", err)
+	}
+
+	orders := make([]*domain.Order, 0, len(res))
+	for _, o := range res {
+		orders = append(orders, orderFromBinance(o))
 	}
+	return orders, nil
+}
 
-	errHandler := func(err error) {
-		fmt.Printf("WebSocket error for %s klines: %v\n", symbol, err)
+// CancelAllOrders cancels every open order for a symbol
+func (be *BinanceExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
 	}
 
-	doneC, stopC, err := binance.WsKlineServe(symbol, interval, wsHandler, errHandler)
+	// Binance rejects cancelling open orders when there are none
+	open, err := be.GetOpenOrders(ctx, symbol)
 	if err != nil {
-		return fmt.Errorf("failed to start kline WebSocket: %w", err)
+		return nil, err
+	}
+	if len(open) == 0 {
+		return open, nil
 	}
 
-	be.mu.Lock()
-	be.wsCleanup = append(be.wsCleanup, func() {
-		close(stopC)
+	res, err := be.client.NewCancelOpenOrdersService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel open orders: %w", err)
+	}
+
+	orders := make([]*domain.Order, 0, len(res.Orders))
+	for _, o := range res.Orders {
+		orders = append(orders, orderFromCancel(o))
+	}
+	return orders, nil
+}
+
+// GetOrderHistory lists a symbol's orders of any status, querying 24h windows from start to end
+func (be *BinanceExchange) GetOrderHistory(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit = historyLimit(limit)
+
+	orders := make([]*domain.Order, 0)
+	err := forEachHistoryPage(start, end, limit, func(from, to time.Time, pageLimit int) (int, error) {
+		service := be.client.NewListOrdersService().Symbol(symbol).Limit(pageLimit)
+		if !from.IsZero() {
+			service = service.StartTime(from.UnixMilli()).EndTime(to.UnixMilli())
+		}
+
+		res, err := service.Do(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get order history: %w", err)
+		}
+		for _, o := range res {
+			orders = append(orders, orderFromBinance(o))
+		}
+		return len(res), nil
 	})
-	be.mu.Unlock()
+	return orders, err
+}
+
+// GetMyTrades lists a symbol's executions, querying 24h windows from start to end
+func (be *BinanceExchange) GetMyTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit = historyLimit(limit)
+
+	trades := make([]*domain.Trade, 0)
+	err := forEachHistoryPage(start, end, limit, func(from, to time.Time, pageLimit int) (int, error) {
+		service := be.client.NewListTradesService().Symbol(symbol).Limit(pageLimit)
+		if !from.IsZero() {
+			service = service.StartTime(from.UnixMilli()).EndTime(to.UnixMilli())
+		}
+
+		res, err := service.Do(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get trades: %w", err)
+		}
+		for _, t := range res {
+			trades = append(trades, tradeFromBinance(t))
+		}
+		return len(res), nil
+	})
+	return trades, err
+}
+
+// StreamKlines streams real-time kline/candle data until ctx is done or the
+// exchange is closed. The stream manager keeps the connection alive; after a
+// reconnect, bars missed while disconnected are backfilled over REST first.
+func (be *BinanceExchange) StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error {
+	// The handler and backfill both run on the connection goroutine
+	var last time.Time // Open time of the newest bar delivered
+	deliver := func(candle *domain.Candle) {
+		if candle.OpenTime.Before(last) {
+			return
+		}
+		last = candle.OpenTime
+		callback(candle)
+	}
 
-	go func() {
-		<-ctx.Done()
-		close(stopC)
-	}()
+	handler := func(message []byte) {
+		event := new(binance.WsKlineEvent)
+		if err := json.Unmarshal(message, event); err != nil {
+			log.Printf("⚠️  %s kline stream error: %v", symbol, err)
+			return
+		}
+		deliver(candleFromWsKline(symbol, &event.Kline))
+	}
 
-	<-doneC
+	backfill := func() {
+		if last.IsZero() {
+			return
+		}
+		since := last
+		candles, err := be.klinesSince(ctx, symbol, interval, since)
+		if err != nil {
+			log.Printf("⚠️  Failed to backfill %s %s klines: %v", symbol, interval, err)
+			return
+		}
+		for _, candle := range candles {
+			deliver(candle)
+		}
+		log.Printf("Backfilled %d %s %s klines since %s", len(candles), symbol, interval, since.Format(time.RFC3339))
+	}
+
+	stream := fmt.Sprintf("%s@kline_%s", strings.ToLower(symbol), interval)
+	unsubscribe, err := be.streams.Subscribe(stream, handler, backfill)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", stream, err)
+	}
+	defer unsubscribe()
+
+	select {
+	case <-ctx.Done():
+	case <-be.closedC:
+	}
 	return nil
 }
 
+// klinesSince returns the klines opening at or after since, oldest first
+func (be *BinanceExchange) klinesSince(ctx context.Context, symbol, interval string, since time.Time) ([]*domain.Candle, error) {
+	candles := make([]*domain.Candle, 0)
+	for {
+		klines, err := be.client.NewKlinesService().
+			Symbol(symbol).
+			Interval(interval).
+			StartTime(since.UnixMilli()).
+			Limit(binanceMaxKlineLimit).
+			Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get candles: %w", err)
+		}
+		for _, k := range klines {
+			candles = append(candles, candleFromKline(symbol, k))
+		}
+		if len(klines) < binanceMaxKlineLimit {
+			return candles, nil
+		}
+		since = candles[len(candles)-1].OpenTime.Add(time.Millisecond)
+	}
+}
+
+// StreamStatus returns the market data stream connection state and recent state changes
+func (be *BinanceExchange) StreamStatus() *StreamStatus {
+	return be.streams.Status()
+}
+
+// OnStreamEvent registers a listener for market data stream connection state changes
+func (be *BinanceExchange) OnStreamEvent(listener func(StreamEvent)) {
+	be.streams.OnEvent(listener)
+}
+
 // SubscribePrice subscribes to price updates via WebSocket
 func (be *BinanceExchange) SubscribePrice(ctx context.Context, symbol string) (<-chan float64, error) {
 	be.mu.Lock()
 	defer be.mu.Unlock()
 
-	ch := make(chan float64, 100)
-	be.priceSubscribers[symbol] = append(be.priceSubscribers[symbol], ch)
+	if be.closed {
+		return nil, fmt.Errorf("exchange is closed")
+	}
 
-	// Start WebSocket if this is the first subscriber for this symbol
-	if len(be.priceSubscribers[symbol]) == 1 {
-		go be.startPriceWebSocket(symbol)
+	// Start the trade stream for the first subscriber of this symbol
+	if len(be.priceSubscribers[symbol]) == 0 {
+		if err := be.startPriceStream(symbol); err != nil {
+			return nil, err
+		}
 	}
 
+	ch := make(chan float64, 100)
+	be.priceSubscribers[symbol] = append(be.priceSubscribers[symbol], ch)
+
 	// Send current price if available
 	if price, ok := be.prices[symbol]; ok {
 		ch <- price
@@ -290,64 +571,68 @@ func (be *BinanceExchange) SubscribePrice(ctx context.Context, symbol string) (<
 	return ch, nil
 }
 
-// startPriceWebSocket starts a WebSocket connection for price updates
-func (be *BinanceExchange) startPriceWebSocket(symbol string) {
-	wsHandler := func(event *binance.WsTradeEvent) {
+// startPriceStream subscribes to the symbol's trade stream. After a reconnect
+// the price is refreshed over REST, since trades may have been missed.
+func (be *BinanceExchange) startPriceStream(symbol string) error {
+	handler := func(message []byte) {
+		event := new(binance.WsTradeEvent)
+		if err := json.Unmarshal(message, event); err != nil {
+			return
+		}
 		price, err := strconv.ParseFloat(event.Price, 64)
 		if err != nil {
 			return
 		}
+		be.publishPrice(symbol, price)
+	}
 
-		be.mu.Lock()
-		be.prices[symbol] = price
-		subscribers := be.priceSubscribers[symbol]
-		be.mu.Unlock()
-
-		// Notify all subscribers
-		for _, ch := range subscribers {
-			select {
-			case ch <- price:
-			default:
-				// Skip if channel is full
-			}
+	refresh := func() {
+		price, err := be.GetCurrentPrice(context.Background(), symbol)
+		if err != nil {
+			log.Printf("⚠️  Failed to refresh %s price: %v", symbol, err)
+			return
 		}
+		be.publishPrice(symbol, price)
 	}
 
-	errHandler := func(err error) {
-		// Log error but don't crash
-		fmt.Printf("WebSocket error for %s: %v\n", symbol, err)
+	stream := strings.ToLower(symbol) + "@trade"
+	if _, err := be.streams.Subscribe(stream, handler, refresh); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", stream, err)
 	}
+	return nil
+}
 
-	doneC, stopC, err := binance.WsTradeServe(symbol, wsHandler, errHandler)
-	if err != nil {
-		fmt.Printf("Failed to start WebSocket for %s: %v\n", symbol, err)
+// publishPrice records a price and sends it to the symbol's subscribers
+func (be *BinanceExchange) publishPrice(symbol string, price float64) {
+	// Hold the lock while sending so Close can't close the channels mid-send
+	be.mu.Lock()
+	defer be.mu.Unlock()
+
+	if be.closed {
 		return
 	}
+	be.prices[symbol] = price
 
-	be.mu.Lock()
-	be.wsCleanup = append(be.wsCleanup, func() {
-		close(stopC)
-	})
-	be.mu.Unlock()
-
-	<-doneC
+	// Notify all subscribers
+	for _, ch := range be.priceSubscribers[symbol] {
+		select {
+		case ch <- price:
+		default:
+			// Skip if channel is full
+		}
+	}
 }
 
 // Close closes the exchange connection
 func (be *BinanceExchange) Close() error {
 	be.mu.Lock()
-	defer be.mu.Unlock()
-
 	if be.closed {
+		be.mu.Unlock()
 		return nil
 	}
 
 	be.closed = true
-
-	// Close all WebSocket connections
-	for _, cleanup := range be.wsCleanup {
-		cleanup()
-	}
+	close(be.closedC)
 
 	// Close all subscriber channels
 	for _, subscribers := range be.priceSubscribers {
@@ -355,6 +640,175 @@ func (be *BinanceExchange) Close() error {
 			close(ch)
 		}
 	}
+	be.mu.Unlock()
+
+	// Close all WebSocket connections (handlers take be.mu, so not while holding it)
+	be.streams.Close()
+
+	return nil
+}
+
+// forEachHistoryPage calls fetch for each 24h window in [start, end] until limit
+// rows are returned. A zero start makes a single call without a time range.
+func forEachHistoryPage(start, end time.Time, limit int, fetch func(from, to time.Time, pageLimit int) (int, error)) error {
+	if start.IsZero() {
+		_, err := fetch(time.Time{}, time.Time{}, min(limit, binanceMaxHistoryLimit))
+		return err
+	}
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
 
+	fetched := 0
+	for from := start; !from.After(end) && fetched < limit; from = from.Add(binanceHistoryWindow) {
+		to := from.Add(binanceHistoryWindow - time.Millisecond)
+		if to.After(end) {
+			to = end
+		}
+
+		n, err := fetch(from, to, min(limit-fetched, binanceMaxHistoryLimit))
+		if err != nil {
+			return err
+		}
+		fetched += n
+	}
 	return nil
 }
+
+// parseOrderRef validates a reference and returns its numeric exchange ID (0 if only the client ID is set)
+func parseOrderRef(ref domain.OrderRef) (int64, error) {
+	if err := ref.Validate(); err != nil {
+		return 0, err
+	}
+	if ref.OrderID == "" {
+		return 0, nil
+	}
+
+	orderID, err := strconv.ParseInt(ref.OrderID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid order ID: %w", err)
+	}
+	return orderID, nil
+}
+
+// isUnknownOrder reports whether err is Binance's "order does not exist" error
+func isUnknownOrder(err error) bool {
+	var apiErr *common.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == binanceUnknownOrder
+}
+
+// orderStatusFromBinance maps a Binance order status to ours
+func orderStatusFromBinance(status binance.OrderStatusType) domain.OrderStatus {
+	switch status {
+	case binance.OrderStatusTypePartiallyFilled:
+		return domain.OrderStatusPartiallyFilled
+	case binance.OrderStatusTypeFilled:
+		return domain.OrderStatusFilled
+	case binance.OrderStatusTypeCanceled:
+		return domain.OrderStatusCancelled
+	case binance.OrderStatusTypeRejected:
+		return domain.OrderStatusRejected
+	case binance.OrderStatusTypeExpired:
+		return domain.OrderStatusExpired
+	default:
+		return domain.OrderStatusNew
+	}
+}
+
+// orderFromBinance converts a queried Binance order
+func orderFromBinance(o *binance.Order) *domain.Order {
+	order := &domain.Order{
+		ID:            strconv.FormatInt(o.OrderID, 10),
+		ClientOrderID: o.ClientOrderID,
+		Symbol:        o.Symbol,
+		Side:          domain.OrderSide(o.Side),
+		Type:          domain.OrderType(o.Type),
+		Status:        orderStatusFromBinance(o.Status),
+		UpdatedAt:     domain.TimeFromMillis(o.UpdateTime),
+	}
+	if o.Time > 0 {
+		order.CreatedAt = domain.TimeFromMillis(o.Time)
+	}
+	order.Quantity, _ = strconv.ParseFloat(o.OrigQuantity, 64)
+	order.Price, _ = strconv.ParseFloat(o.Price, 64)
+	order.FilledQty, _ = strconv.ParseFloat(o.ExecutedQuantity, 64)
+
+	// cummulativeQuoteQty is the filled notional, so it gives the average fill price
+	if quote, _ := strconv.ParseFloat(o.CummulativeQuoteQuantity, 64); order.FilledQty > 0 && quote > 0 {
+		order.AvgPrice = quote / order.FilledQty
+	}
+	if order.Status == domain.OrderStatusFilled {
+		executedAt := order.UpdatedAt
+		order.ExecutedAt = &executedAt
+	}
+	return order
+}
+
+// orderFromCancel converts a cancel response, whose clientOrderId is the cancel request's own ID
+func orderFromCancel(r *binance.CancelOrderResponse) *domain.Order {
+	return orderFromBinance(&binance.Order{
+		Symbol:                   r.Symbol,
+		OrderID:                  r.OrderID,
+		ClientOrderID:            r.OrigClientOrderID,
+		Price:                    r.Price,
+		OrigQuantity:             r.OrigQuantity,
+		ExecutedQuantity:         r.ExecutedQuantity,
+		CummulativeQuoteQuantity: r.CummulativeQuoteQuantity,
+		Status:                   r.Status,
+		Type:                     r.Type,
+		Side:                     r.Side,
+		UpdateTime:               r.TransactTime,
+	})
+}
+
+// tradeFromBinance converts an account trade
+func tradeFromBinance(t *binance.TradeV3) *domain.Trade {
+	side := domain.OrderSideSell
+	if t.IsBuyer {
+		side = domain.OrderSideBuy
+	}
+
+	trade := &domain.Trade{
+		ID:        strconv.FormatInt(t.ID, 10),
+		OrderID:   strconv.FormatInt(t.OrderID, 10),
+		Symbol:    t.Symbol,
+		Side:      side,
+		FeeAsset:  t.CommissionAsset,
+		IsMaker:   t.IsMaker,
+		Timestamp: domain.TimeFromMillis(t.Time),
+	}
+	trade.Price, _ = strconv.ParseFloat(t.Price, 64)
+	trade.Quantity, _ = strconv.ParseFloat(t.Quantity, 64)
+	trade.Fee, _ = strconv.ParseFloat(t.Commission, 64)
+	return trade
+}
+
+// candleFromKline converts a REST kline
+func candleFromKline(symbol string, k *binance.Kline) *domain.Candle {
+	candle := &domain.Candle{
+		Symbol:    symbol,
+		OpenTime:  domain.TimeFromMillis(k.OpenTime),
+		CloseTime: domain.TimeFromMillis(k.CloseTime),
+	}
+	candle.Open, _ = strconv.ParseFloat(k.Open, 64)
+	candle.High, _ = strconv.ParseFloat(k.High, 64)
+	candle.Low, _ = strconv.ParseFloat(k.Low, 64)
+	candle.Close, _ = strconv.ParseFloat(k.Close, 64)
+	candle.Volume, _ = strconv.ParseFloat(k.Volume, 64)
+	return candle
+}
+
+// candleFromWsKline converts a streamed kline
+func candleFromWsKline(symbol string, k *binance.WsKline) *domain.Candle {
+	candle := &domain.Candle{
+		Symbol:    symbol,
+		OpenTime:  domain.TimeFromMillis(k.StartTime),
+		CloseTime: domain.TimeFromMillis(k.EndTime),
+	}
+	candle.Open, _ = strconv.ParseFloat(k.Open, 64)
+	candle.High, _ = strconv.ParseFloat(k.High, 64)
+	candle.Low, _ = strconv.ParseFloat(k.Low, 64)
+	candle.Close, _ = strconv.ParseFloat(k.Close, 64)
+	candle.Volume, _ = strconv.ParseFloat(k.Volume, 64)
+	return candle
+}
@@ -0,0 +1,294 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// userStreamKeepalive is how often the listenKey is extended (it expires after 60 minutes)
+const userStreamKeepalive = 30 * time.Minute
+
+// userStreamRetryDelay is the pause before reconnecting a dropped user-data stream
+const userStreamRetryDelay = 5 * time.Second
+
+// listenKeyExpired is the user-data event sent when the listenKey is no longer valid
+const listenKeyExpired = "listenKeyExpired"
+
+// executionTypeTrade is the execution report type for a fill
+const executionTypeTrade = "TRADE"
+
+// GetBalances returns the account's non-zero spot balances (requires API keys)
+func (be *BinanceExchange) GetBalances(ctx context.Context) ([]*domain.Balance, error) {
+	account, err := be.client.NewGetAccountService().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	balances := make([]*domain.Balance, 0)
+	for _, b := range account.Balances {
+		balance := balanceFromBinance(b.Asset, b.Free, b.Locked)
+		if balance.Total > 0 {
+			balances = append(balances, balance)
+		}
+	}
+	return balances, nil
+}
+
+// StreamUserData streams balance and order updates from the user-data stream
+// until ctx is done. Each (re)connect first sends a balance snapshot, so updates
+// missed while disconnected are never lost.
+func (be *BinanceExchange) StreamUserData(ctx context.Context, callback func(*domain.AccountEvent)) error {
+	stream := userStream{
+		name: "User data",
+		start: func(ctx context.Context) (string, error) {
+			return be.client.NewStartUserStreamService().Do(ctx)
+		},
+		keepalive: func(ctx context.Context, listenKey string) error {
+			return be.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(ctx)
+		},
+		close: func(ctx context.Context, listenKey string) error {
+			return be.client.NewCloseUserStreamService().ListenKey(listenKey).Do(ctx)
+		},
+		endpoint: be.wsEndpoint,
+		parse: func(message []byte) (*domain.AccountEvent, bool, error) {
+			event, err := parseUserDataEvent(message)
+			if err != nil {
+				return nil, false, err
+			}
+			if string(event.Event) == listenKeyExpired {
+				return nil, true, nil
+			}
+			return accountEventFromBinance(event), false, nil
+		},
+		snapshot: func(ctx context.Context) (*domain.AccountEvent, error) {
+			balances, err := be.GetBalances(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return &domain.AccountEvent{Time: time.Now().UTC(), Snapshot: true, Balances: balances}, nil
+		},
+	}
+	return stream.run(ctx, callback)
+}
+
+// userStream is a Binance listenKey user-data stream (spot or futures)
+type userStream struct {
+	name      string // For logs
+	start     func(ctx context.Context) (string, error)
+	keepalive func(ctx context.Context, listenKey string) error
+	close     func(ctx context.Context, listenKey string) error
+	endpoint  func(listenKey string) string
+
+	// parse converts a message (nil event to skip it) and reports listenKey expiry
+	parse func(message []byte) (event *domain.AccountEvent, expired bool, err error)
+
+	// snapshot returns the full account state, sent after each connect
+	snapshot func(ctx context.Context) (*domain.AccountEvent, error)
+}
+
+// run serves the stream until ctx is done, reconnecting after drops
+func (s userStream) run(ctx context.Context, callback func(*domain.AccountEvent)) error {
+	for {
+		err := s.serve(ctx, callback)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("⚠️  %s stream disconnected: %v (reconnecting in %s)", s.name, err, userStreamRetryDelay)
+
+		select {
+		case <-time.After(userStreamRetryDelay):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// serve runs one listenKey session until it drops, expires or ctx is done
+func (s userStream) serve(ctx context.Context, callback func(*domain.AccountEvent)) error {
+	listenKey, err := s.start(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start user stream: %w", err)
+	}
+	defer s.close(context.Background(), listenKey)
+
+	expired := make(chan struct{})
+	var expireOnce sync.Once
+
+	wsHandler := func(message []byte) {
+		ev, isExpired, err := s.parse(message)
+		if err != nil {
+			log.Printf("⚠️  %s stream error: %v", s.name, err)
+			return
+		}
+		if isExpired {
+			expireOnce.Do(func() { close(expired) })
+			return
+		}
+		if ev != nil {
+			callback(ev)
+		}
+	}
+
+	errHandler := func(err error) {
+		log.Printf("⚠️  %s stream error: %v", s.name, err)
+	}
+
+	doneC, stopC, err := wsServe(s.endpoint(listenKey), wsHandler, errHandler)
+	if err != nil {
+		return fmt.Errorf("failed to connect user stream: %w", err)
+	}
+	stop := func() {
+		close(stopC)
+		<-doneC
+	}
+
+	// Snapshot after connecting so no update falls between the two
+	snapshot, err := s.snapshot(ctx)
+	if err != nil {
+		stop()
+		return err
+	}
+	callback(snapshot)
+	if snapshot.Positions != nil {
+		log.Printf("✅ %s stream connected (%d balances, %d positions)", s.name, len(snapshot.Balances), len(snapshot.Positions))
+	} else {
+		log.Printf("✅ %s stream connected (%d balances)", s.name, len(snapshot.Balances))
+	}
+
+	ticker := time.NewTicker(userStreamKeepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.keepalive(ctx, listenKey); err != nil {
+				stop()
+				return fmt.Errorf("failed to keep user stream alive: %w", err)
+			}
+		case <-expired:
+			stop()
+			return fmt.Errorf("listenKey expired")
+		case <-doneC:
+			return fmt.Errorf("connection closed")
+		case <-ctx.Done():
+			stop()
+			return nil
+		}
+	}
+}
+
+// parseUserDataEvent decodes a user-data stream message. Binance sends keys that
+// differ only in case (i/I, m/M, n/N, t/T) and encoding/json matches them
+// case-insensitively, so the execution report fields that collide are re-read exactly.
+func parseUserDataEvent(message []byte) (*binance.WsUserDataEvent, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(message, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode user data event: %w", err)
+	}
+
+	event := new(binance.WsUserDataEvent)
+	json.Unmarshal(raw["e"], &event.Event)
+	json.Unmarshal(raw["E"], &event.Time)
+
+	switch event.Event {
+	case binance.UserDataEventTypeOutboundAccountPosition:
+		if err := json.Unmarshal(message, &event.AccountUpdate); err != nil {
+			return nil, fmt.Errorf("failed to decode account update: %w", err)
+		}
+	case binance.UserDataEventTypeExecutionReport:
+		u := &event.OrderUpdate
+		if err := json.Unmarshal(message, u); err != nil {
+			return nil, fmt.Errorf("failed to decode execution report: %w", err)
+		}
+		json.Unmarshal(raw["i"], &u.Id)
+		json.Unmarshal(raw["m"], &u.IsMaker)
+		json.Unmarshal(raw["n"], &u.FeeCost)
+		json.Unmarshal(raw["N"], &u.FeeAsset)
+		json.Unmarshal(raw["t"], &u.TradeId)
+		json.Unmarshal(raw["T"], &u.TransactionTime)
+		event.TransactionTime = u.TransactionTime
+	}
+	return event, nil
+}
+
+// accountEventFromBinance converts balance and execution report events (nil for other events).
+// balanceUpdate deltas are skipped: the outboundAccountPosition that follows carries the new balance.
+func accountEventFromBinance(event *binance.WsUserDataEvent) *domain.AccountEvent {
+	switch event.Event {
+	case binance.UserDataEventTypeOutboundAccountPosition:
+		balances := make([]*domain.Balance, 0, len(event.AccountUpdate.WsAccountUpdates))
+		for _, b := range event.AccountUpdate.WsAccountUpdates {
+			balances = append(balances, balanceFromBinance(b.Asset, b.Free, b.Locked))
+		}
+		return &domain.AccountEvent{Time: domain.TimeFromMillis(event.Time), Balances: balances}
+
+	case binance.UserDataEventTypeExecutionReport:
+		order, fill := orderFromExecutionReport(&event.OrderUpdate)
+		return &domain.AccountEvent{Time: domain.TimeFromMillis(event.Time), Order: order, Fill: fill}
+	}
+	return nil
+}
+
+// orderFromExecutionReport converts an execution report into the order's state and, for
+// TRADE executions, the fill it reports
+func orderFromExecutionReport(u *binance.WsOrderUpdate) (*domain.Order, *domain.Trade) {
+	order := &domain.Order{
+		ID:            strconv.FormatInt(u.Id, 10),
+		ClientOrderID: u.ClientOrderId,
+		Symbol:        u.Symbol,
+		Side:          domain.OrderSide(u.Side),
+		Type:          domain.OrderType(u.Type),
+		Status:        orderStatusFromBinance(binance.OrderStatusType(u.Status)),
+		CreatedAt:     domain.TimeFromMillis(u.CreateTime),
+		UpdatedAt:     domain.TimeFromMillis(u.TransactionTime),
+	}
+	// Cancels report the cancelled order's ID in the original client order ID field
+	if u.OrigCustomOrderId != "" {
+		order.ClientOrderID = u.OrigCustomOrderId
+	}
+	order.Quantity, _ = strconv.ParseFloat(u.Volume, 64)
+	order.Price, _ = strconv.ParseFloat(u.Price, 64)
+	order.FilledQty, _ = strconv.ParseFloat(u.FilledVolume, 64)
+	if quote, _ := strconv.ParseFloat(u.FilledQuoteVolume, 64); order.FilledQty > 0 && quote > 0 {
+		order.AvgPrice = quote / order.FilledQty
+	}
+	if order.Status == domain.OrderStatusFilled {
+		executedAt := order.UpdatedAt
+		order.ExecutedAt = &executedAt
+	}
+
+	if u.ExecutionType != executionTypeTrade {
+		return order, nil
+	}
+
+	fill := &domain.Trade{
+		ID:        strconv.FormatInt(u.TradeId, 10),
+		OrderID:   order.ID,
+		Symbol:    u.Symbol,
+		Side:      order.Side,
+		FeeAsset:  u.FeeAsset,
+		IsMaker:   u.IsMaker,
+		Timestamp: order.UpdatedAt,
+	}
+	fill.Price, _ = strconv.ParseFloat(u.LatestPrice, 64)
+	fill.Quantity, _ = strconv.ParseFloat(u.LatestVolume, 64)
+	fill.Fee, _ = strconv.ParseFloat(u.FeeCost, 64)
+	return order, fill
+}
+
+// balanceFromBinance parses an asset's free and locked amounts
+func balanceFromBinance(asset, free, locked string) *domain.Balance {
+	balance := &domain.Balance{Asset: asset}
+	balance.Free, _ = strconv.ParseFloat(free, 64)
+	balance.Locked, _ = strconv.ParseFloat(locked, 64)
+	balance.Total = balance.Free + balance.Locked
+	return balance
+}
@@ -0,0 +1,869 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Binance USDⓈ-M futures default WebSocket base URLs
+const (
+	binanceFuturesWsMainURL    = "wss://fstream.binance.com/ws"
+	binanceFuturesWsTestnetURL = "wss://stream.binancefuture.com/ws"
+)
+
+// binanceFuturesMaxKlineLimit is the largest page futures klines returns
+const binanceFuturesMaxKlineLimit = 1500
+
+// binanceNoMarginTypeChange is the API error code for setting the margin type a symbol already has
+const binanceNoMarginTypeChange = -4046
+
+// binanceFuturesFees is the base tier USDⓈ-M futures fee schedule
+var binanceFuturesFees = FeeSchedule{Maker: 0.0002, Taker: 0.0005}
+
+func init() {
+	RegisterVenue(Venue{
+		Name:    "binance_futures",
+		Symbols: SymbolStyleConcat,
+		Fees:    binanceFuturesFees,
+		New: func(cfg VenueConfig) (VenueExchange, error) {
+			fe, err := NewBinanceFuturesExchange(cfg.APIKey, cfg.SecretKey, cfg.Testnet)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.HTTPClient != nil {
+				fe.client.HTTPClient = cfg.HTTPClient
+			}
+			fe.SetEndpoints(BinanceEndpoints{BaseURL: cfg.BaseURL, WsBaseURL: cfg.WsBaseURL})
+			fe.fees = cfg.fees(binanceFuturesFees)
+			return fe, nil
+		},
+	})
+}
+
+// BinanceFuturesExchange implements FuturesExchange for Binance USDⓈ-M perpetual
+// futures. Positions are expected in one-way mode (a single net position per
+// symbol), which is the account default.
+type BinanceFuturesExchange struct {
+	client    *futures.Client
+	endpoints BinanceEndpoints
+	fees      FeeSchedule
+	rules     SymbolRules
+	streams   *StreamManager
+	mu        sync.RWMutex
+	closedC   chan struct{}
+	closed    bool
+}
+
+// NewBinanceFuturesExchange creates a new Binance futures exchange client
+func NewBinanceFuturesExchange(apiKey, secretKey string, useTestnet bool) (*BinanceFuturesExchange, error) {
+	// Set testnet flag before creating client
+	if useTestnet {
+		futures.UseTestnet = true
+	}
+
+	fe := &BinanceFuturesExchange{
+		client:  futures.NewClient(apiKey, secretKey),
+		fees:    binanceFuturesFees,
+		closedC: make(chan struct{}),
+	}
+	fe.streams = NewStreamManager(fe.wsCombinedEndpoint, StreamConfig{})
+
+	return fe, nil
+}
+
+// SetEndpoints points the REST client and streams at other base URLs
+func (fe *BinanceFuturesExchange) SetEndpoints(endpoints BinanceEndpoints) {
+	fe.endpoints = endpoints
+	if endpoints.BaseURL != "" {
+		fe.client.BaseURL = endpoints.BaseURL
+	}
+}
+
+// SetSymbolRules makes PlaceOrder round and validate orders before sending them
+func (fe *BinanceFuturesExchange) SetSymbolRules(rules SymbolRules) {
+	fe.rules = rules
+}
+
+// Venue names the venue
+func (fe *BinanceFuturesExchange) Venue() string {
+	return "binance_futures"
+}
+
+// MaxKlineLimit is the largest page futures klines returns
+func (fe *BinanceFuturesExchange) MaxKlineLimit() int {
+	return binanceFuturesMaxKlineLimit
+}
+
+// Fees returns the configured fee schedule
+func (fe *BinanceFuturesExchange) Fees() FeeSchedule {
+	return fe.fees
+}
+
+// GetCurrentPrice returns the contract's last traded price
+func (fe *BinanceFuturesExchange) GetCurrentPrice(ctx context.Context, symbol string) (float64, error) {
+	prices, err := fe.client.NewListPricesService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get price: %w", err)
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("no price data for symbol: %s", symbol)
+	}
+
+	price, err := strconv.ParseFloat(prices[0].Price, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse price: %w", err)
+	}
+	return price, nil
+}
+
+// GetSymbolInfo returns a contract's trading rules from exchangeInfo.
+// Quantities follow LOT_SIZE, which also covers limit orders.
+func (fe *BinanceFuturesExchange) GetSymbolInfo(ctx context.Context, symbol string) (*domain.SymbolInfo, error) {
+	res, err := fe.client.NewExchangeInfoService().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exchange info: %w", err)
+	}
+
+	for i := range res.Symbols {
+		sym := &res.Symbols[i]
+		if sym.Symbol != symbol {
+			continue
+		}
+		info := &domain.SymbolInfo{
+			Symbol:      sym.Symbol,
+			BaseAsset:   sym.BaseAsset,
+			QuoteAsset:  sym.QuoteAsset,
+			Status:      sym.Status,
+			Permissions: make([]string, 0),
+			UpdatedAt:   time.Now().UTC(),
+		}
+		if f := sym.PriceFilter(); f != nil {
+			info.TickSize = parseFloat(f.TickSize)
+			info.MinPrice = parseFloat(f.MinPrice)
+			info.MaxPrice = parseFloat(f.MaxPrice)
+		}
+		if f := sym.LotSizeFilter(); f != nil {
+			info.StepSize = parseFloat(f.StepSize)
+			info.MinQty = parseFloat(f.MinQuantity)
+			info.MaxQty = parseFloat(f.MaxQuantity)
+		}
+		if f := sym.MinNotionalFilter(); f != nil {
+			info.MinNotional = parseFloat(f.Notional)
+		}
+		return info, nil
+	}
+	return nil, fmt.Errorf("unknown symbol: %s", symbol)
+}
+
+// GetCandles retrieves the most recent candles
+func (fe *BinanceFuturesExchange) GetCandles(ctx context.Context, symbol, interval string, limit int) ([]*domain.Candle, error) {
+	return fe.GetKlines(ctx, symbol, interval, time.Time{}, time.Time{}, limit)
+}
+
+// GetKlines returns up to limit candles opening in [start, end], oldest first
+func (fe *BinanceFuturesExchange) GetKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	service := fe.client.NewKlinesService().
+		Symbol(symbol).
+		Interval(interval).
+		Limit(min(limit, binanceFuturesMaxKlineLimit))
+	if !start.IsZero() {
+		service = service.StartTime(start.UnixMilli())
+	}
+	if !end.IsZero() {
+		service = service.EndTime(end.UnixMilli())
+	}
+
+	klines, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candles: %w", err)
+	}
+
+	candles := make([]*domain.Candle, 0, len(klines))
+	for _, k := range klines {
+		candles = append(candles, candleFromFuturesKline(symbol, k))
+	}
+	return candles, nil
+}
+
+// SetLeverage sets the symbol's initial leverage
+func (fe *BinanceFuturesExchange) SetLeverage(ctx context.Context, symbol string, leverage int) error {
+	if _, err := fe.client.NewChangeLeverageService().Symbol(symbol).Leverage(leverage).Do(ctx); err != nil {
+		return fmt.Errorf("failed to set %s leverage to %dx: %w", symbol, leverage, err)
+	}
+	return nil
+}
+
+// SetMarginType switches the symbol between isolated and cross margin
+func (fe *BinanceFuturesExchange) SetMarginType(ctx context.Context, symbol string, marginType domain.MarginType) error {
+	err := fe.client.NewChangeMarginTypeService().
+		Symbol(symbol).
+		MarginType(futures.MarginType(marginType)).
+		Do(ctx)
+
+	// Binance rejects a change to the type the symbol already has
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == binanceNoMarginTypeChange {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set %s margin type to %s: %w", symbol, marginType, err)
+	}
+	return nil
+}
+
+// GetPositionRisk returns a symbol's position, or every open position if symbol is empty
+func (fe *BinanceFuturesExchange) GetPositionRisk(ctx context.Context, symbol string) ([]*domain.PositionRisk, error) {
+	service := fe.client.NewGetPositionRiskService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get position risk: %w", err)
+	}
+
+	positions := make([]*domain.PositionRisk, 0, len(res))
+	for _, p := range res {
+		position := positionRiskFromFutures(p)
+		if symbol == "" && position.Quantity == 0 {
+			continue
+		}
+		positions = append(positions, position)
+	}
+	return positions, nil
+}
+
+// ClosePosition closes the symbol's whole position with a reduce-only market order
+func (fe *BinanceFuturesExchange) ClosePosition(ctx context.Context, symbol string) (*domain.Order, error) {
+	return fe.PlaceOrder(ctx, &domain.Order{
+		Symbol:        symbol,
+		Type:          domain.OrderTypeMarket,
+		ClosePosition: true,
+	})
+}
+
+// PlaceOrder submits a new order. A buy opens or adds to a long and a sell to a
+// short; ReduceOnly orders can only shrink the position. ClosePosition orders
+// take their side and quantity from the open position.
+// An order that already carries a client order ID is looked up first, so a
+// retried submit returns the order Binance already has instead of trading twice.
+func (fe *BinanceFuturesExchange) PlaceOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	fe.mu.RLock()
+	if fe.closed {
+		fe.mu.RUnlock()
+		return nil, fmt.Errorf("exchange is closed")
+	}
+	fe.mu.RUnlock()
+
+	if err := checkOrderType(order); err != nil {
+		return order, err
+	}
+
+	if order.ClientOrderID != "" {
+		existing, err := fe.GetOrder(ctx, domain.OrderRef{Symbol: order.Symbol, ClientOrderID: order.ClientOrderID})
+		if err == nil {
+			return existing, nil
+		}
+		if !isUnknownOrder(err) {
+			return nil, fmt.Errorf("failed to check for duplicate order: %w", err)
+		}
+	}
+
+	// Binance's own closePosition flag only works on stop orders, so size the
+	// close from the position instead
+	if order.ClosePosition {
+		if err := fe.sizeClosePosition(ctx, order); err != nil {
+			order.Status = domain.OrderStatusRejected
+			return order, err
+		}
+	}
+
+	// Round to tick/step size and check min notional so Binance doesn't reject the order
+	if err := applySymbolRules(ctx, fe.rules, order, fe.GetCurrentPrice); err != nil {
+		return order, err
+	}
+
+	service := fe.client.NewCreateOrderService().
+		Symbol(order.Symbol).
+		Side(futures.SideType(order.Side)).
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+
+	if order.Type == domain.OrderTypeMarket {
+		service = service.Type(futures.OrderTypeMarket)
+	} else {
+		service = service.
+			Type(futures.OrderTypeLimit).
+			Price(strconv.FormatFloat(order.Price, 'f', -1, 64)).
+			TimeInForce(futures.TimeInForceTypeGTC)
+	}
+	if order.ReduceOnly {
+		service = service.ReduceOnly(true)
+	}
+
+	assignClientOrderID(order)
+	service = service.
+		Quantity(strconv.FormatFloat(order.Quantity, 'f', -1, 64)).
+		NewClientOrderID(order.ClientOrderID)
+
+	response, err := service.Do(ctx)
+	if err != nil {
+		// The order may have reached Binance even though the response was lost
+		if !common.IsAPIError(err) {
+			if existing, getErr := fe.GetOrder(ctx, order.Ref()); getErr == nil {
+				return existing, nil
+			}
+		}
+		order.Status = domain.OrderStatusRejected
+		return order, fmt.Errorf("failed to place order: %w", err)
+	}
+
+	placed := orderFromFutures(&futures.Order{
+		Symbol:           response.Symbol,
+		OrderID:          response.OrderID,
+		ClientOrderID:    response.ClientOrderID,
+		Price:            response.Price,
+		ReduceOnly:       response.ReduceOnly,
+		OrigQuantity:     response.OrigQuantity,
+		ExecutedQuantity: response.ExecutedQuantity,
+		Status:           response.Status,
+		Type:             response.Type,
+		Side:             response.Side,
+		Time:             response.UpdateTime,
+		UpdateTime:       response.UpdateTime,
+		AvgPrice:         response.AvgPrice,
+	})
+	placed.ClosePosition = order.ClosePosition
+	return placed, nil
+}
+
+// sizeClosePosition sets a close-position order's side and quantity to flatten the open position
+func (fe *BinanceFuturesExchange) sizeClosePosition(ctx context.Context, order *domain.Order) error {
+	positions, err := fe.GetPositionRisk(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
+
+	quantity := 0.0
+	for _, p := range positions {
+		quantity += p.Quantity
+	}
+	if quantity == 0 {
+		return fmt.Errorf("no open %s position to close", order.Symbol)
+	}
+
+	side := domain.PositionSideLong
+	if quantity < 0 {
+		side = domain.PositionSideShort
+	}
+	order.Side = side.CloseSide()
+	order.Quantity = math.Abs(quantity)
+	order.ReduceOnly = true
+	return nil
+}
+
+// GetOrder retrieves an order by exchange ID or client order ID
+func (fe *BinanceFuturesExchange) GetOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	orderID, err := parseOrderRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	service := fe.client.NewGetOrderService().Symbol(ref.Symbol)
+	if orderID != 0 {
+		service = service.OrderID(orderID)
+	} else {
+		service = service.OrigClientOrderID(ref.ClientOrderID)
+	}
+
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order %s: %w", ref, err)
+	}
+	return orderFromFutures(res), nil
+}
+
+// CancelOrder cancels an open order by exchange ID or client order ID
+func (fe *BinanceFuturesExchange) CancelOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	orderID, err := parseOrderRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	service := fe.client.NewCancelOrderService().Symbol(ref.Symbol)
+	if orderID != 0 {
+		service = service.OrderID(orderID)
+	} else {
+		service = service.OrigClientOrderID(ref.ClientOrderID)
+	}
+
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel order %s: %w", ref, err)
+	}
+	return orderFromFutures(&futures.Order{
+		Symbol:           res.Symbol,
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Price:            res.Price,
+		ReduceOnly:       res.ReduceOnly,
+		OrigQuantity:     res.OrigQuantity,
+		ExecutedQuantity: res.ExecutedQuantity,
+		CumQuote:         res.CumQuote,
+		Status:           res.Status,
+		Type:             res.Type,
+		Side:             res.Side,
+		UpdateTime:       res.UpdateTime,
+	}), nil
+}
+
+// GetOpenOrders lists open orders for a symbol (all symbols if empty)
+func (fe *BinanceFuturesExchange) GetOpenOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	service := fe.client.NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders: %w", err)
+	}
+
+	orders := make([]*domain.Order, 0, len(res))
+	for _, o := range res {
+		orders = append(orders, orderFromFutures(o))
+	}
+	return orders, nil
+}
+
+// CancelAllOrders cancels every open order for a symbol
+func (fe *BinanceFuturesExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	// The cancel-all response lists no orders, so fetch them first
+	open, err := fe.GetOpenOrders(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if len(open) == 0 {
+		return open, nil
+	}
+
+	if err := fe.client.NewCancelAllOpenOrdersService().Symbol(symbol).Do(ctx); err != nil {
+		return nil, fmt.Errorf("failed to cancel open orders: %w", err)
+	}
+	return markCancelled(open, len(open), func(i int) string { return open[i].ID }), nil
+}
+
+// GetOrderHistory lists a symbol's orders of any status, querying 24h windows from start to end
+func (fe *BinanceFuturesExchange) GetOrderHistory(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit = historyLimit(limit)
+
+	orders := make([]*domain.Order, 0)
+	err := forEachHistoryPage(start, end, limit, func(from, to time.Time, pageLimit int) (int, error) {
+		service := fe.client.NewListOrdersService().Symbol(symbol).Limit(pageLimit)
+		if !from.IsZero() {
+			service = service.StartTime(from.UnixMilli()).EndTime(to.UnixMilli())
+		}
+
+		res, err := service.Do(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get order history: %w", err)
+		}
+		for _, o := range res {
+			orders = append(orders, orderFromFutures(o))
+		}
+		return len(res), nil
+	})
+	return orders, err
+}
+
+// GetMyTrades lists a symbol's executions, querying 24h windows from start to end
+func (fe *BinanceFuturesExchange) GetMyTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit = historyLimit(limit)
+
+	trades := make([]*domain.Trade, 0)
+	err := forEachHistoryPage(start, end, limit, func(from, to time.Time, pageLimit int) (int, error) {
+		service := fe.client.NewListAccountTradeService().Symbol(symbol).Limit(pageLimit)
+		if !from.IsZero() {
+			service = service.StartTime(from.UnixMilli()).EndTime(to.UnixMilli())
+		}
+
+		res, err := service.Do(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get trades: %w", err)
+		}
+		for _, t := range res {
+			trades = append(trades, tradeFromFutures(t))
+		}
+		return len(res), nil
+	})
+	return trades, err
+}
+
+// GetBalances returns the futures wallet's non-zero balances (requires API keys).
+// Free is the cross wallet balance; Locked is the margin held by isolated positions.
+func (fe *BinanceFuturesExchange) GetBalances(ctx context.Context) ([]*domain.Balance, error) {
+	res, err := fe.client.NewGetBalanceService().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+
+	balances := make([]*domain.Balance, 0)
+	for _, b := range res {
+		balance := balanceFromFutures(b.Asset, b.Balance, b.CrossWalletBalance)
+		if balance.Total > 0 {
+			balances = append(balances, balance)
+		}
+	}
+	return balances, nil
+}
+
+// StreamUserData streams balance, position and order updates from the futures
+// user-data stream until ctx is done. Each (re)connect first sends a snapshot of
+// balances and open positions, so updates missed while disconnected are never lost.
+func (fe *BinanceFuturesExchange) StreamUserData(ctx context.Context, callback func(*domain.AccountEvent)) error {
+	stream := userStream{
+		name: "Futures user data",
+		start: func(ctx context.Context) (string, error) {
+			return fe.client.NewStartUserStreamService().Do(ctx)
+		},
+		keepalive: func(ctx context.Context, listenKey string) error {
+			return fe.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(ctx)
+		},
+		close: func(ctx context.Context, listenKey string) error {
+			return fe.client.NewCloseUserStreamService().ListenKey(listenKey).Do(ctx)
+		},
+		endpoint: fe.wsEndpoint,
+		parse: func(message []byte) (*domain.AccountEvent, bool, error) {
+			event := new(futures.WsUserDataEvent)
+			if err := json.Unmarshal(message, event); err != nil {
+				return nil, false, fmt.Errorf("failed to decode user data event: %w", err)
+			}
+			if event.Event == futures.UserDataEventTypeListenKeyExpired {
+				return nil, true, nil
+			}
+			return accountEventFromFutures(event), false, nil
+		},
+		snapshot: fe.accountSnapshot,
+	}
+	return stream.run(ctx, callback)
+}
+
+// accountSnapshot returns the account's balances and open positions
+func (fe *BinanceFuturesExchange) accountSnapshot(ctx context.Context) (*domain.AccountEvent, error) {
+	balances, err := fe.GetBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	risks, err := fe.GetPositionRisk(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]*domain.Position, 0, len(risks))
+	for _, r := range risks {
+		positions = append(positions, &domain.Position{
+			Symbol:        r.Symbol,
+			Quantity:      r.Quantity,
+			AvgEntryPrice: r.EntryPrice,
+			CurrentPrice:  r.MarkPrice,
+			UnrealizedPnL: r.UnrealizedPnL,
+			UpdatedAt:     r.UpdatedAt,
+		})
+	}
+	return &domain.AccountEvent{Time: time.Now().UTC(), Snapshot: true, Balances: balances, Positions: positions}, nil
+}
+
+// StreamKlines streams real-time kline/candle data until ctx is done or the
+// exchange is closed. After a reconnect, bars missed while disconnected are
+// backfilled over REST first.
+func (fe *BinanceFuturesExchange) StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error {
+	// The handler and backfill both run on the connection goroutine
+	var last time.Time // Open time of the newest bar delivered
+	deliver := func(candle *domain.Candle) {
+		if candle.OpenTime.Before(last) {
+			return
+		}
+		last = candle.OpenTime
+		callback(candle)
+	}
+
+	// Futures kline events have the same shape as spot ones
+	handler := func(message []byte) {
+		event := new(binance.WsKlineEvent)
+		if err := json.Unmarshal(message, event); err != nil {
+			log.Printf("⚠️  %s futures kline stream error: %v", symbol, err)
+			return
+		}
+		deliver(candleFromWsKline(symbol, &event.Kline))
+	}
+
+	backfill := func() {
+		if last.IsZero() {
+			return
+		}
+		since := last
+		candles, err := klinesSince(ctx, fe, symbol, interval, since)
+		if err != nil {
+			log.Printf("⚠️  Failed to backfill %s %s futures klines: %v", symbol, interval, err)
+			return
+		}
+		for _, candle := range candles {
+			deliver(candle)
+		}
+		log.Printf("Backfilled %d %s %s futures klines since %s", len(candles), symbol, interval, since.Format(time.RFC3339))
+	}
+
+	stream := fmt.Sprintf("%s@kline_%s", strings.ToLower(symbol), interval)
+	unsubscribe, err := fe.streams.Subscribe(stream, handler, backfill)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", stream, err)
+	}
+	defer unsubscribe()
+
+	select {
+	case <-ctx.Done():
+	case <-fe.closedC:
+	}
+	return nil
+}
+
+// StreamStatus returns the market data stream connection state and recent state changes
+func (fe *BinanceFuturesExchange) StreamStatus() *StreamStatus {
+	return fe.streams.Status()
+}
+
+// Close closes the exchange connection
+func (fe *BinanceFuturesExchange) Close() error {
+	fe.mu.Lock()
+	if fe.closed {
+		fe.mu.Unlock()
+		return nil
+	}
+	fe.closed = true
+	close(fe.closedC)
+	fe.mu.Unlock()
+
+	fe.streams.Close()
+	return nil
+}
+
+// wsEndpoint returns the URL of a stream on the configured or default base URL
+func (fe *BinanceFuturesExchange) wsEndpoint(stream string) string {
+	base := fe.endpoints.WsBaseURL
+	if base == "" {
+		base = binanceFuturesWsMainURL
+		if futures.UseTestnet {
+			base = binanceFuturesWsTestnetURL
+		}
+	}
+	return strings.TrimSuffix(base, "/") + "/" + stream
+}
+
+// wsCombinedEndpoint returns the combined stream URL (<base>/stream?streams=a/b) for the streams
+func (fe *BinanceFuturesExchange) wsCombinedEndpoint(streams []string) string {
+	base := strings.TrimSuffix(fe.wsEndpoint(""), "/")
+	return strings.TrimSuffix(base, "/ws") + "/stream?streams=" + strings.Join(streams, "/")
+}
+
+// accountEventFromFutures converts account and order updates (nil for other events)
+func accountEventFromFutures(event *futures.WsUserDataEvent) *domain.AccountEvent {
+	switch event.Event {
+	case futures.UserDataEventTypeAccountUpdate:
+		update := event.AccountUpdate
+		balances := make([]*domain.Balance, 0, len(update.Balances))
+		for _, b := range update.Balances {
+			balances = append(balances, balanceFromFutures(b.Asset, b.Balance, b.CrossWalletBalance))
+		}
+		positions := make([]*domain.Position, 0, len(update.Positions))
+		for i := range update.Positions {
+			positions = append(positions, positionFromWsFutures(&update.Positions[i], event.TransactionTime))
+		}
+		return &domain.AccountEvent{Time: domain.TimeFromMillis(event.Time), Balances: balances, Positions: positions}
+
+	case futures.UserDataEventTypeOrderTradeUpdate:
+		order, fill := orderFromTradeUpdate(&event.OrderTradeUpdate, event.TransactionTime)
+		return &domain.AccountEvent{Time: domain.TimeFromMillis(event.Time), Order: order, Fill: fill}
+
+	case futures.UserDataEventTypeMarginCall:
+		for _, p := range event.MarginCallPositions {
+			log.Printf("⚠️  Margin call: %s position %s at mark %s (maintenance margin %s)",
+				p.Symbol, p.Amount, p.MarkPrice, p.MaintenanceMarginRequired)
+		}
+	}
+	return nil
+}
+
+// orderFromTradeUpdate converts an order trade update into the order's state and,
+// for TRADE executions, the fill it reports
+func orderFromTradeUpdate(u *futures.WsOrderTradeUpdate, transactionTime int64) (*domain.Order, *domain.Trade) {
+	order := orderFromFutures(&futures.Order{
+		Symbol:           u.Symbol,
+		OrderID:          u.ID,
+		ClientOrderID:    u.ClientOrderID,
+		Price:            u.OriginalPrice,
+		ReduceOnly:       u.IsReduceOnly,
+		OrigQuantity:     u.OriginalQty,
+		ExecutedQuantity: u.AccumulatedFilledQty,
+		Status:           u.Status,
+		Type:             u.Type,
+		Side:             u.Side,
+		UpdateTime:       transactionTime,
+		AvgPrice:         u.AveragePrice,
+		ClosePosition:    u.IsClosingPosition,
+	})
+
+	if u.ExecutionType != futures.OrderExecutionTypeTrade {
+		return order, nil
+	}
+
+	fill := &domain.Trade{
+		ID:        strconv.FormatInt(u.TradeID, 10),
+		OrderID:   order.ID,
+		Symbol:    u.Symbol,
+		Side:      order.Side,
+		FeeAsset:  u.CommissionAsset,
+		IsMaker:   u.IsMaker,
+		Timestamp: domain.TimeFromMillis(u.TradeTime),
+	}
+	fill.Price, _ = strconv.ParseFloat(u.LastFilledPrice, 64)
+	fill.Quantity, _ = strconv.ParseFloat(u.LastFilledQty, 64)
+	fill.Fee, _ = strconv.ParseFloat(u.Commission, 64)
+	fill.RealizedPnL, _ = strconv.ParseFloat(u.RealizedPnL, 64)
+	return order, fill
+}
+
+// orderFromFutures converts a queried futures order
+func orderFromFutures(o *futures.Order) *domain.Order {
+	order := &domain.Order{
+		ID:            strconv.FormatInt(o.OrderID, 10),
+		ClientOrderID: o.ClientOrderID,
+		Symbol:        o.Symbol,
+		Side:          domain.OrderSide(o.Side),
+		Type:          domain.OrderType(o.Type),
+		ReduceOnly:    o.ReduceOnly,
+		ClosePosition: o.ClosePosition,
+		Status:        orderStatusFromBinance(binance.OrderStatusType(o.Status)),
+		UpdatedAt:     domain.TimeFromMillis(o.UpdateTime),
+	}
+	if o.Time > 0 {
+		order.CreatedAt = domain.TimeFromMillis(o.Time)
+	}
+	order.Quantity, _ = strconv.ParseFloat(o.OrigQuantity, 64)
+	order.Price, _ = strconv.ParseFloat(o.Price, 64)
+	order.FilledQty, _ = strconv.ParseFloat(o.ExecutedQuantity, 64)
+	order.AvgPrice, _ = strconv.ParseFloat(o.AvgPrice, 64)
+
+	// Cancel responses carry the filled notional instead of the average price
+	if quote, _ := strconv.ParseFloat(o.CumQuote, 64); order.AvgPrice == 0 && order.FilledQty > 0 && quote > 0 {
+		order.AvgPrice = quote / order.FilledQty
+	}
+	if order.Status == domain.OrderStatusFilled {
+		executedAt := order.UpdatedAt
+		order.ExecutedAt = &executedAt
+	}
+	return order
+}
+
+// tradeFromFutures converts an account trade
+func tradeFromFutures(t *futures.AccountTrade) *domain.Trade {
+	trade := &domain.Trade{
+		ID:        strconv.FormatInt(t.ID, 10),
+		OrderID:   strconv.FormatInt(t.OrderID, 10),
+		Symbol:    t.Symbol,
+		Side:      domain.OrderSide(t.Side),
+		FeeAsset:  t.CommissionAsset,
+		IsMaker:   t.Maker,
+		Timestamp: domain.TimeFromMillis(t.Time),
+	}
+	trade.Price, _ = strconv.ParseFloat(t.Price, 64)
+	trade.Quantity, _ = strconv.ParseFloat(t.Quantity, 64)
+	trade.Fee, _ = strconv.ParseFloat(t.Commission, 64)
+	trade.RealizedPnL, _ = strconv.ParseFloat(t.RealizedPnl, 64)
+	return trade
+}
+
+// positionRiskFromFutures converts a position risk row
+func positionRiskFromFutures(p *futures.PositionRisk) *domain.PositionRisk {
+	risk := &domain.PositionRisk{
+		Symbol:     p.Symbol,
+		MarginType: domain.MarginTypeCross,
+		UpdatedAt:  time.Now().UTC(),
+	}
+	if strings.EqualFold(p.MarginType, "isolated") {
+		risk.MarginType = domain.MarginTypeIsolated
+	}
+	risk.Quantity, _ = strconv.ParseFloat(p.PositionAmt, 64)
+	risk.EntryPrice, _ = strconv.ParseFloat(p.EntryPrice, 64)
+	risk.MarkPrice, _ = strconv.ParseFloat(p.MarkPrice, 64)
+	risk.UnrealizedPnL, _ = strconv.ParseFloat(p.UnRealizedProfit, 64)
+	risk.LiquidationPrice, _ = strconv.ParseFloat(p.LiquidationPrice, 64)
+	risk.Leverage, _ = strconv.Atoi(p.Leverage)
+	risk.IsolatedMargin, _ = strconv.ParseFloat(p.IsolatedMargin, 64)
+	risk.Notional, _ = strconv.ParseFloat(p.Notional, 64)
+	return risk
+}
+
+// positionFromWsFutures converts a position from an account update. The mark
+// price is derived from the unrealized PnL when the event doesn't carry it.
+func positionFromWsFutures(p *futures.WsPosition, transactionTime int64) *domain.Position {
+	position := &domain.Position{
+		Symbol:    p.Symbol,
+		UpdatedAt: domain.TimeFromMillis(transactionTime),
+	}
+	position.Quantity, _ = strconv.ParseFloat(p.Amount, 64)
+	position.AvgEntryPrice, _ = strconv.ParseFloat(p.EntryPrice, 64)
+	position.UnrealizedPnL, _ = strconv.ParseFloat(p.UnrealizedPnL, 64)
+	position.CurrentPrice, _ = strconv.ParseFloat(p.MarkPrice, 64)
+	if position.CurrentPrice == 0 && position.Quantity != 0 {
+		position.CurrentPrice = position.AvgEntryPrice + position.UnrealizedPnL/position.Quantity
+	}
+	return position
+}
+
+// balanceFromFutures parses a futures wallet balance. The cross wallet part is
+// free to margin any position; the rest is held by isolated positions.
+func balanceFromFutures(asset, wallet, crossWallet string) *domain.Balance {
+	balance := &domain.Balance{Asset: asset}
+	balance.Total, _ = strconv.ParseFloat(wallet, 64)
+	balance.Free, _ = strconv.ParseFloat(crossWallet, 64)
+	balance.Locked = max(balance.Total-balance.Free, 0)
+	return balance
+}
+
+// candleFromFuturesKline converts a REST futures kline
+func candleFromFuturesKline(symbol string, k *futures.Kline) *domain.Candle {
+	candle := &domain.Candle{
+		Symbol:    symbol,
+		OpenTime:  domain.TimeFromMillis(k.OpenTime),
+		CloseTime: domain.TimeFromMillis(k.CloseTime),
+	}
+	candle.Open, _ = strconv.ParseFloat(k.Open, 64)
+	candle.High, _ = strconv.ParseFloat(k.High, 64)
+	candle.Low, _ = strconv.ParseFloat(k.Low, 64)
+	candle.Close, _ = strconv.ParseFloat(k.Close, 64)
+	candle.Volume, _ = strconv.ParseFloat(k.Volume, 64)
+	return candle
+}
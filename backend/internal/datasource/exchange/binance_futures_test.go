@@ -0,0 +1,135 @@
+package exchange
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/exchange/binancemock"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+func newMockBinanceFutures(t *testing.T, srv *binancemock.Server) *BinanceFuturesExchange {
+	t.Helper()
+	fe, err := NewBinanceFuturesExchange("key", "secret", false)
+	if err != nil {
+		t.Fatalf("NewBinanceFuturesExchange: %v", err)
+	}
+	fe.SetEndpoints(BinanceEndpoints{BaseURL: srv.URL(), WsBaseURL: srv.WsURL()})
+	t.Cleanup(func() { fe.Close() })
+	return fe
+}
+
+func TestBinanceFuturesSymbolInfo(t *testing.T) {
+	fe := newMockBinanceFutures(t, newMockServer(t))
+
+	info, err := fe.GetSymbolInfo(context.Background(), "BTCUSDT")
+	if err != nil {
+		t.Fatalf("GetSymbolInfo: %v", err)
+	}
+	if info.TickSize != 0.01 || info.StepSize != 0.001 || info.MinNotional != 5 || !info.IsTradable() {
+		t.Errorf("symbol info = %+v", info)
+	}
+	if _, err := fe.GetSymbolInfo(context.Background(), "DOGEUSDT"); err == nil {
+		t.Errorf("GetSymbolInfo of an unknown symbol succeeded")
+	}
+}
+
+func TestBinanceFuturesOrders(t *testing.T) {
+	fe := newMockBinanceFutures(t, newMockServer(t))
+	fe.SetSymbolRules(NewVenueRules(fe))
+	ctx := context.Background()
+
+	// Rounded to the lot and tick size before it is sent
+	limit, err := fe.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-fut-limit",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeLimit,
+		Quantity:      0.1234,
+		Price:         99.504,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if limit.Status != domain.OrderStatusNew || limit.Quantity != 0.123 || limit.Price != 99.5 {
+		t.Errorf("limit order = %s %v @ %v, want NEW 0.123 @ 99.5", limit.Status, limit.Quantity, limit.Price)
+	}
+
+	// Below the minimum notional: rejected without reaching the venue
+	small, err := fe.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-fut-small",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeLimit,
+		Quantity:      0.01,
+		Price:         99.5,
+	})
+	if err == nil || !strings.Contains(err.Error(), "trading rules") || small.Status != domain.OrderStatusRejected {
+		t.Errorf("small order = %v, %v, want a trading rules rejection", small, err)
+	}
+
+	market, err := fe.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-fut-market",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeMarket,
+		Quantity:      0.2,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if market.Status != domain.OrderStatusFilled || market.FilledQty != 0.2 || market.AvgPrice != 100 {
+		t.Errorf("market order = %s %v @ %v, want FILLED 0.2 @ 100", market.Status, market.FilledQty, market.AvgPrice)
+	}
+
+	again, err := fe.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-fut-market",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeMarket,
+		Quantity:      0.2,
+	})
+	if err != nil || again.ID != market.ID {
+		t.Errorf("resubmit = %v, %v, want order %s", again, err, market.ID)
+	}
+
+	cancelled, err := fe.CancelOrder(ctx, limit.Ref())
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if cancelled.Status != domain.OrderStatusCancelled {
+		t.Errorf("status = %s, want CANCELED", cancelled.Status)
+	}
+
+	for _, price := range []float64{95, 96} {
+		if _, err := fe.PlaceOrder(ctx, &domain.Order{
+			Symbol:   "BTCUSDT",
+			Side:     domain.OrderSideBuy,
+			Type:     domain.OrderTypeLimit,
+			Quantity: 0.1,
+			Price:    price,
+		}); err != nil {
+			t.Fatalf("PlaceOrder: %v", err)
+		}
+	}
+	all, err := fe.CancelAllOrders(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("CancelAllOrders: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("cancelled %d orders, want 2", len(all))
+	}
+	if open, _ := fe.GetOpenOrders(ctx, "BTCUSDT"); len(open) != 0 {
+		t.Errorf("%d orders still open", len(open))
+	}
+
+	history, err := fe.GetOrderHistory(ctx, "BTCUSDT", time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("GetOrderHistory: %v", err)
+	}
+	if len(history) != 4 {
+		t.Errorf("history has %d orders, want 4 (the rejected order never reached the venue)", len(history))
+	}
+}
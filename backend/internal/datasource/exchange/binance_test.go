@@ -0,0 +1,319 @@
+package exchange
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/exchange/binancemock"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+var mockStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newMockServer starts a mock Binance replaying flat 1m BTCUSDT bars
+// (open/close 100, range 99-101) with 10 bars already closed
+func newMockServer(t *testing.T) *binancemock.Server {
+	t.Helper()
+	candles := make([]*domain.Candle, 0, 100)
+	for i := 0; i < 100; i++ {
+		open := mockStart.Add(time.Duration(i) * time.Minute)
+		candles = append(candles, &domain.Candle{
+			Symbol:    "BTCUSDT",
+			OpenTime:  open,
+			CloseTime: open.Add(time.Minute - time.Millisecond),
+			Open:      100,
+			High:      101,
+			Low:       99,
+			Close:     100,
+			Volume:    10,
+		})
+	}
+
+	srv, err := binancemock.New(binancemock.Config{
+		Interval: "1m",
+		Candles:  candles,
+		Warmup:   10,
+		StepSize: 0.001,
+	})
+	if err != nil {
+		t.Fatalf("failed to create mock server: %v", err)
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newMockBinance(t *testing.T, srv *binancemock.Server) *BinanceExchange {
+	t.Helper()
+	be, err := NewBinanceExchange("key", "secret", false)
+	if err != nil {
+		t.Fatalf("NewBinanceExchange: %v", err)
+	}
+	be.SetEndpoints(BinanceEndpoints{BaseURL: srv.URL(), WsBaseURL: srv.WsURL()})
+	t.Cleanup(func() { be.Close() })
+	return be
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func findBalance(balances []*domain.Balance, asset string) *domain.Balance {
+	for _, b := range balances {
+		if b.Asset == asset {
+			return b
+		}
+	}
+	return &domain.Balance{Asset: asset}
+}
+
+func TestBinanceKlinesAreClosedBars(t *testing.T) {
+	srv := newMockServer(t)
+	be := newMockBinance(t, srv)
+
+	candles, err := be.GetCandles(context.Background(), "BTCUSDT", "1m", 100)
+	if err != nil {
+		t.Fatalf("GetCandles: %v", err)
+	}
+	if len(candles) != 10 {
+		t.Fatalf("got %d candles, want the 10 closed bars", len(candles))
+	}
+	if !candles[0].OpenTime.Equal(mockStart) || candles[9].OpenTime.Add(time.Minute) != srv.Now() {
+		t.Errorf("candles span %s - %s, server at %s", candles[0].OpenTime, candles[9].OpenTime, srv.Now())
+	}
+}
+
+func TestBinanceStreamKlines(t *testing.T) {
+	srv := newMockServer(t)
+	be := newMockBinance(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	received := make(chan *domain.Candle, 100)
+	go be.StreamKlines(ctx, "BTCUSDT", "1m", func(c *domain.Candle) { received <- c })
+
+	// Close bars until the stream is connected and delivers one
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case c := <-received:
+			if c.Symbol != "BTCUSDT" || c.Close != 100 || !c.CloseTime.Equal(c.OpenTime.Add(time.Minute-time.Millisecond)) {
+				t.Errorf("streamed candle = %+v", c)
+			}
+			return
+		case <-ticker.C:
+			srv.Advance()
+		case <-ctx.Done():
+			t.Fatalf("no kline streamed")
+		}
+	}
+}
+
+func TestBinanceStreamKlinesReconnect(t *testing.T) {
+	srv := newMockServer(t)
+	be := newMockBinance(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	connected := make(chan struct{}, 10)
+	be.OnStreamEvent(func(ev StreamEvent) {
+		if ev.State == StreamStateConnected {
+			connected <- struct{}{}
+		}
+	})
+	received := make(chan *domain.Candle, 100)
+	go be.StreamKlines(ctx, "BTCUSDT", "1m", func(c *domain.Candle) { received <- c })
+
+	// next waits for the next streamed or backfilled bar
+	next := func() *domain.Candle {
+		t.Helper()
+		select {
+		case c := <-received:
+			return c
+		case <-ctx.Done():
+			t.Fatal("no kline streamed")
+			return nil
+		}
+	}
+	waitConnected := func() {
+		t.Helper()
+		select {
+		case <-connected:
+		case <-ctx.Done():
+			t.Fatal("stream did not connect")
+		}
+	}
+
+	waitConnected()
+	srv.Advance()
+	last := next()
+
+	// Bars closed while disconnected are backfilled over REST on reconnect
+	if n := srv.Disconnect(); n != 1 {
+		t.Fatalf("dropped %d connections, want 1", n)
+	}
+	for i := 0; i < 3; i++ {
+		srv.Advance()
+	}
+	waitConnected()
+	for want := last.OpenTime.Add(time.Minute); !want.After(last.OpenTime.Add(3 * time.Minute)); {
+		c := next()
+		if c.OpenTime.Before(want) {
+			continue // The last delivered bar may be backfilled again
+		}
+		if !c.OpenTime.Equal(want) {
+			t.Fatalf("bar at %s after reconnect, want %s", c.OpenTime, want)
+		}
+		want = want.Add(time.Minute)
+	}
+	lastOpen := last.OpenTime.Add(3 * time.Minute)
+
+	// A stalled backfill doesn't keep the reconnected stream from delivering
+	srv.SetFaults(binancemock.Faults{StallEvery: 1, StallFor: 100 * time.Millisecond})
+	srv.Disconnect()
+	srv.Advance()
+	waitConnected()
+	srv.Advance()
+	if c, want := next(), lastOpen.Add(2*time.Minute); !c.OpenTime.Equal(want) {
+		t.Errorf("bar at %s after a failed backfill, want the live bar %s", c.OpenTime, want)
+	}
+
+	if status := be.StreamStatus(); status.Reconnects != 2 || status.State != StreamStateConnected {
+		t.Errorf("stream status = %s with %d reconnects, want connected after 2", status.State, status.Reconnects)
+	}
+}
+
+func TestBinanceMarketOrder(t *testing.T) {
+	srv := newMockServer(t)
+	be := newMockBinance(t, srv)
+	ctx := context.Background()
+
+	order, err := be.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-market",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeMarket,
+		Quantity:      0.5,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.Status != domain.OrderStatusFilled || order.FilledQty != 0.5 || order.AvgPrice != 100 {
+		t.Errorf("order = %s %v @ %v, want FILLED 0.5 @ 100", order.Status, order.FilledQty, order.AvgPrice)
+	}
+
+	balances, err := be.GetBalances(ctx)
+	if err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	if usdt := findBalance(balances, "USDT"); !approx(usdt.Free, 9950) {
+		t.Errorf("USDT free = %v, want 9950", usdt.Free)
+	}
+	if btc := findBalance(balances, "BTC"); btc.Free <= 0.49 || btc.Free > 0.5 {
+		t.Errorf("BTC free = %v, want 0.5 less the fee", btc.Free)
+	}
+
+	trades, err := be.GetMyTrades(ctx, "BTCUSDT", time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("GetMyTrades: %v", err)
+	}
+	if len(trades) != 1 || trades[0].OrderID != order.ID {
+		t.Errorf("trades = %+v, want the order's fill", trades)
+	}
+}
+
+func TestBinanceLimitOrderLifecycle(t *testing.T) {
+	srv := newMockServer(t)
+	be := newMockBinance(t, srv)
+	ctx := context.Background()
+
+	buy := func(clientID string, price float64) *domain.Order {
+		return &domain.Order{
+			ClientOrderID: clientID,
+			Symbol:        "BTCUSDT",
+			Side:          domain.OrderSideBuy,
+			Type:          domain.OrderTypeLimit,
+			Quantity:      1,
+			Price:         price,
+		}
+	}
+
+	resting, err := be.PlaceOrder(ctx, buy("cq-test-rest", 99.5))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if resting.Status != domain.OrderStatusNew {
+		t.Fatalf("status = %s, want NEW", resting.Status)
+	}
+
+	// A resubmitted client order ID returns the existing order instead of trading twice
+	again, err := be.PlaceOrder(ctx, buy("cq-test-rest", 99.5))
+	if err != nil || again.ID != resting.ID {
+		t.Errorf("resubmit = %v, %v, want order %s", again, err, resting.ID)
+	}
+	open, err := be.GetOpenOrders(ctx, "BTCUSDT")
+	if err != nil || len(open) != 1 {
+		t.Fatalf("open orders = %d, %v, want 1", len(open), err)
+	}
+
+	// The next bar trades down to 99 and fills the order at its price
+	srv.Advance()
+	filled, err := be.GetOrder(ctx, resting.Ref())
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if filled.Status != domain.OrderStatusFilled || filled.AvgPrice != 99.5 {
+		t.Errorf("order = %s @ %v, want FILLED @ 99.5", filled.Status, filled.AvgPrice)
+	}
+
+	// An order below the bar range stays open until cancelled
+	low, err := be.PlaceOrder(ctx, buy("cq-test-low", 90))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	srv.Advance()
+	cancelled, err := be.CancelOrder(ctx, low.Ref())
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if cancelled.Status != domain.OrderStatusCancelled {
+		t.Errorf("status = %s, want CANCELED", cancelled.Status)
+	}
+
+	history, err := be.GetOrderHistory(ctx, "BTCUSDT", time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("GetOrderHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Errorf("history has %d orders, want 2", len(history))
+	}
+
+	_, err = be.GetOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT", ClientOrderID: "cq-test-missing"})
+	if err == nil || !isUnknownOrder(err) {
+		t.Errorf("GetOrder of a missing order returned %v, want unknown order", err)
+	}
+}
+
+func TestBinanceOrderRejectedByFilters(t *testing.T) {
+	srv := newMockServer(t)
+	be := newMockBinance(t, srv)
+
+	// Without symbol rules the order goes out unrounded and the venue rejects it
+	order, err := be.PlaceOrder(context.Background(), &domain.Order{
+		ClientOrderID: "cq-test-lot",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeMarket,
+		Quantity:      0.12345,
+	})
+	if err == nil || !strings.Contains(err.Error(), "LOT_SIZE") {
+		t.Errorf("unrounded order returned %v, want a LOT_SIZE rejection", err)
+	}
+	if order.Status != domain.OrderStatusRejected {
+		t.Errorf("status = %s, want REJECTED", order.Status)
+	}
+}
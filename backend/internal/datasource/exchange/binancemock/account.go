@@ -0,0 +1,716 @@
+package binancemock
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Limits of the order and trade list endpoints
+const (
+	defaultListLimit = 500
+	maxListLimit     = 1000
+)
+
+// filterEpsilon tolerates float noise when checking tick and step multiples
+const filterEpsilon = 1e-6
+
+// balance is one asset of the simulated account
+type balance struct {
+	free   float64
+	locked float64
+}
+
+// order is an order of the simulated account
+type order struct {
+	id            int64
+	clientOrderID string
+	symbol        string
+	side          binance.SideType
+	orderType     binance.OrderType
+	timeInForce   binance.TimeInForceType
+	status        binance.OrderStatusType
+	price         float64
+	quantity      float64
+	executed      float64
+	quoteQty      float64
+	time          int64
+	updateTime    int64
+}
+
+// isOpen reports whether the order is resting on the book
+func (o *order) isOpen() bool {
+	return o.status == binance.OrderStatusTypeNew || o.status == binance.OrderStatusTypePartiallyFilled
+}
+
+// account is the simulated spot account. It is guarded by Server.mu.
+type account struct {
+	balances    map[string]*balance
+	commission  float64
+	orders      []*order // Oldest first
+	trades      []*binance.TradeV3
+	nextOrderID int64
+	nextTradeID int64
+}
+
+// newAccount creates an account holding the given free balances
+func newAccount(balances map[string]float64, commission float64) *account {
+	a := &account{
+		balances:   make(map[string]*balance, len(balances)),
+		commission: commission,
+	}
+	for asset, free := range balances {
+		a.balances[asset] = &balance{free: free}
+	}
+	return a
+}
+
+// balance returns an asset's balance, creating it empty
+func (a *account) balance(asset string) *balance {
+	b, ok := a.balances[asset]
+	if !ok {
+		b = &balance{}
+		a.balances[asset] = b
+	}
+	return b
+}
+
+// findOrder looks an order up by exchange ID or, failing that, the latest with the client order ID
+func (a *account) findOrder(symbol string, orderID int64, clientOrderID string) *order {
+	for i := len(a.orders) - 1; i >= 0; i-- {
+		o := a.orders[i]
+		if o.symbol != symbol {
+			continue
+		}
+		if (orderID != 0 && o.id == orderID) || (orderID == 0 && o.clientOrderID == clientOrderID) {
+			return o
+		}
+	}
+	return nil
+}
+
+// splitAssets returns a symbol's base and quote assets
+func splitAssets(symbol string) (string, string) {
+	base := symbols.SplitSymbol(symbol)
+	return base, strings.TrimPrefix(symbol, base)
+}
+
+// handleAccount returns the account's balances
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assets := make([]string, 0, len(s.account.balances))
+	for asset := range s.account.balances {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	commission := formatFloat(s.account.commission)
+	res := binance.Account{
+		CommissionRates: binance.CommissionRates{Maker: commission, Taker: commission, Buyer: "0", Seller: "0"},
+		CanTrade:        true,
+		CanDeposit:      true,
+		UpdateTime:      uint64(s.clock()),
+		AccountType:     "SPOT",
+		Balances:        make([]binance.Balance, 0, len(assets)),
+		Permissions:     []string{"SPOT"},
+	}
+	for _, asset := range assets {
+		b := s.account.balances[asset]
+		res.Balances = append(res.Balances, binance.Balance{
+			Asset:  asset,
+			Free:   formatFloat(b.free),
+			Locked: formatFloat(b.locked),
+		})
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleOrder creates (POST), queries (GET) and cancels (DELETE) an order
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.createOrder(w, r)
+	case http.MethodGet, http.MethodDelete:
+		q := requestParams(r)
+		orderID, _ := strconv.ParseInt(q.Get("orderId"), 10, 64)
+		clientOrderID := q.Get("origClientOrderId")
+		if orderID == 0 && clientOrderID == "" {
+			writeError(w, http.StatusBadRequest, codeBadParam, "Param 'origClientOrderId' or 'orderId' must be sent, but both were empty/null!")
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		o := s.account.findOrder(q.Get("symbol"), orderID, clientOrderID)
+		if r.Method == http.MethodGet {
+			if o == nil {
+				writeError(w, http.StatusBadRequest, codeNoSuchOrder, "Order does not exist.")
+				return
+			}
+			writeJSON(w, http.StatusOK, orderJSON(o))
+			return
+		}
+
+		if o == nil || !o.isOpen() {
+			writeError(w, http.StatusBadRequest, codeCancelRejected, "Unknown order sent.")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.cancel(o))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// createOrder places a MARKET or LIMIT order. MARKET and marketable LIMIT
+// orders fill at the current price; other LIMIT orders rest until a bar reaches them.
+func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, fills, apiErr := s.placeOrder(r)
+	if apiErr != nil {
+		writeJSON(w, http.StatusBadRequest, apiErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, binance.CreateOrderResponse{
+		Symbol:                   o.symbol,
+		OrderID:                  o.id,
+		ClientOrderID:            o.clientOrderID,
+		TransactTime:             o.updateTime,
+		Price:                    formatFloat(o.price),
+		OrigQuantity:             formatFloat(o.quantity),
+		ExecutedQuantity:         formatFloat(o.executed),
+		CummulativeQuoteQuantity: formatFloat(o.quoteQty),
+		Status:                   o.status,
+		TimeInForce:              o.timeInForce,
+		Type:                     o.orderType,
+		Side:                     o.side,
+		Fills:                    fills,
+	})
+}
+
+// placeOrder validates and places the order in a create request, returning
+// the order and its fills or the Binance error to answer with. The caller holds s.mu.
+func (s *Server) placeOrder(r *http.Request) (*order, []*binance.Fill, *apiError) {
+	reject := func(code int64, format string, args ...interface{}) (*order, []*binance.Fill, *apiError) {
+		return nil, nil, &apiError{Code: code, Message: fmt.Sprintf(format, args...)}
+	}
+
+	symbol := r.FormValue("symbol")
+	side := binance.SideType(r.FormValue("side"))
+	orderType := binance.OrderType(r.FormValue("type"))
+	timeInForce := binance.TimeInForceType(r.FormValue("timeInForce"))
+	clientOrderID := r.FormValue("newClientOrderId")
+	quantity, _ := strconv.ParseFloat(r.FormValue("quantity"), 64)
+	quoteOrderQty, _ := strconv.ParseFloat(r.FormValue("quoteOrderQty"), 64)
+	price, _ := strconv.ParseFloat(r.FormValue("price"), 64)
+
+	if side != binance.SideTypeBuy && side != binance.SideTypeSell {
+		return reject(codeBadParam, "Invalid side.")
+	}
+	if orderType != binance.OrderTypeMarket && orderType != binance.OrderTypeLimit {
+		return reject(codeBadOrderType, "Invalid orderType.")
+	}
+	if orderType == binance.OrderTypeLimit && (price <= 0 || timeInForce == "") {
+		return reject(codeBadParam, "Mandatory parameter 'price' or 'timeInForce' was not sent, was empty/null, or malformed.")
+	}
+
+	current, ok := s.price(symbol)
+	if !ok {
+		return reject(codeBadSymbol, "Invalid symbol.")
+	}
+	if orderType == binance.OrderTypeMarket && quantity == 0 && quoteOrderQty > 0 {
+		quantity = math.Floor(quoteOrderQty/current/s.cfg.StepSize) * s.cfg.StepSize
+	}
+	if quantity <= 0 {
+		return reject(codeBadParam, "Mandatory parameter 'quantity' was not sent, was empty/null, or malformed.")
+	}
+
+	if clientOrderID != "" {
+		if o := s.account.findOrder(symbol, 0, clientOrderID); o != nil && o.isOpen() {
+			return reject(codeNewOrderRejected, "Duplicate order sent.")
+		}
+	}
+
+	refPrice := current
+	if orderType == binance.OrderTypeLimit {
+		refPrice = price
+	}
+	if msg := s.checkFilters(orderType, price, quantity, refPrice); msg != "" {
+		return reject(codeFilterFailure, "Filter failure: %s", msg)
+	}
+
+	base, quote := splitAssets(symbol)
+	if (side == binance.SideTypeBuy && s.account.balance(quote).free < quantity*refPrice) ||
+		(side == binance.SideTypeSell && s.account.balance(base).free < quantity) {
+		return reject(codeNewOrderRejected, "Account has insufficient balance for requested action.")
+	}
+
+	s.account.nextOrderID++
+	o := &order{
+		id:            s.account.nextOrderID,
+		clientOrderID: clientOrderID,
+		symbol:        symbol,
+		side:          side,
+		orderType:     orderType,
+		timeInForce:   timeInForce,
+		status:        binance.OrderStatusTypeNew,
+		price:         price,
+		quantity:      quantity,
+		time:          s.clock(),
+		updateTime:    s.clock(),
+	}
+	if o.clientOrderID == "" {
+		o.clientOrderID = fmt.Sprintf("mock%d", o.id)
+	}
+	s.account.orders = append(s.account.orders, o)
+	s.publishExecution(o, "NEW", nil, "")
+
+	fills := make([]*binance.Fill, 0, 1)
+	marketable := orderType == binance.OrderTypeMarket ||
+		(side == binance.SideTypeBuy && price >= current) ||
+		(side == binance.SideTypeSell && price <= current)
+	switch {
+	case marketable:
+		t := s.fill(o, current, false)
+		fills = append(fills, &binance.Fill{
+			TradeID:         t.ID,
+			Price:           t.Price,
+			Quantity:        t.Quantity,
+			Commission:      t.Commission,
+			CommissionAsset: t.CommissionAsset,
+		})
+	case timeInForce == binance.TimeInForceTypeIOC || timeInForce == binance.TimeInForceTypeFOK:
+		o.status = binance.OrderStatusTypeExpired
+		s.publishExecution(o, "EXPIRED", nil, "")
+	default:
+		s.lock(o)
+	}
+
+	return o, fills, nil
+}
+
+// checkFilters applies the PRICE_FILTER, LOT_SIZE and NOTIONAL filters and
+// returns the failing filter's name (empty if the order passes)
+func (s *Server) checkFilters(orderType binance.OrderType, price, quantity, refPrice float64) string {
+	if orderType == binance.OrderTypeLimit && !isMultiple(price, s.cfg.TickSize) {
+		return string(binance.SymbolFilterTypePriceFilter)
+	}
+	if !isMultiple(quantity, s.cfg.StepSize) {
+		return string(binance.SymbolFilterTypeLotSize)
+	}
+	if quantity*refPrice < s.cfg.MinNotional {
+		return string(binance.SymbolFilterTypeNotional)
+	}
+	return ""
+}
+
+// isMultiple reports whether v is a whole multiple of step
+func isMultiple(v, step float64) bool {
+	n := v / step
+	return math.Abs(n-math.Round(n)) < filterEpsilon
+}
+
+// lock reserves a resting order's funds
+func (s *Server) lock(o *order) {
+	asset, amount := s.reserved(o)
+	b := s.account.balance(asset)
+	b.free -= amount
+	b.locked += amount
+	s.publishBalances(asset)
+}
+
+// unlock releases a resting order's funds
+func (s *Server) unlock(o *order) {
+	asset, amount := s.reserved(o)
+	b := s.account.balance(asset)
+	b.free += amount
+	b.locked -= amount
+}
+
+// reserved returns the asset and amount a resting order locks
+func (s *Server) reserved(o *order) (string, float64) {
+	base, quote := splitAssets(o.symbol)
+	if o.side == binance.SideTypeBuy {
+		return quote, (o.quantity - o.executed) * o.price
+	}
+	return base, o.quantity - o.executed
+}
+
+// fill executes the rest of an order at price. Commission is charged in the received asset.
+func (s *Server) fill(o *order, price float64, maker bool) *binance.TradeV3 {
+	if maker {
+		s.unlock(o)
+	}
+
+	base, quote := splitAssets(o.symbol)
+	quantity := o.quantity - o.executed
+	quoteQty := quantity * price
+
+	feeAsset, fee := base, quantity*s.account.commission
+	if o.side == binance.SideTypeBuy {
+		s.account.balance(quote).free -= quoteQty
+		s.account.balance(base).free += quantity - fee
+	} else {
+		feeAsset, fee = quote, quoteQty*s.account.commission
+		s.account.balance(base).free -= quantity
+		s.account.balance(quote).free += quoteQty - fee
+	}
+
+	o.executed += quantity
+	o.quoteQty += quoteQty
+	o.status = binance.OrderStatusTypeFilled
+	o.updateTime = s.clock()
+
+	s.account.nextTradeID++
+	t := &binance.TradeV3{
+		ID:              s.account.nextTradeID,
+		Symbol:          o.symbol,
+		OrderID:         o.id,
+		OrderListId:     -1,
+		Price:           formatFloat(price),
+		Quantity:        formatFloat(quantity),
+		QuoteQuantity:   formatFloat(quoteQty),
+		Commission:      formatFloat(fee),
+		CommissionAsset: feeAsset,
+		Time:            o.updateTime,
+		IsBuyer:         o.side == binance.SideTypeBuy,
+		IsMaker:         maker,
+		IsBestMatch:     true,
+	}
+	s.account.trades = append(s.account.trades, t)
+
+	s.publishExecution(o, "TRADE", t, "")
+	s.publishBalances(base, quote)
+	return t
+}
+
+// cancel cancels an open order and releases its funds
+func (s *Server) cancel(o *order) binance.CancelOrderResponse {
+	s.unlock(o)
+	o.status = binance.OrderStatusTypeCanceled
+	o.updateTime = s.clock()
+
+	cancelID := fmt.Sprintf("mockcancel%d", o.id)
+	s.publishExecution(o, "CANCELED", nil, cancelID)
+	asset, _ := s.reserved(o)
+	s.publishBalances(asset)
+
+	return binance.CancelOrderResponse{
+		Symbol:                   o.symbol,
+		OrigClientOrderID:        o.clientOrderID,
+		OrderID:                  o.id,
+		OrderListID:              -1,
+		ClientOrderID:            cancelID,
+		TransactTime:             o.updateTime,
+		Price:                    formatFloat(o.price),
+		OrigQuantity:             formatFloat(o.quantity),
+		ExecutedQuantity:         formatFloat(o.executed),
+		CummulativeQuoteQuantity: formatFloat(o.quoteQty),
+		Status:                   o.status,
+		TimeInForce:              o.timeInForce,
+		Type:                     o.orderType,
+		Side:                     o.side,
+	}
+}
+
+// matchRestingOrders fills resting limit orders the bar traded through at their limit price
+func (s *Server) matchRestingOrders(bar *domain.Candle) {
+	for _, o := range s.account.orders {
+		if o.symbol != bar.Symbol || !o.isOpen() {
+			continue
+		}
+		if (o.side == binance.SideTypeBuy && bar.Low <= o.price) ||
+			(o.side == binance.SideTypeSell && bar.High >= o.price) {
+			s.fill(o, o.price, true)
+		}
+	}
+}
+
+// handleOpenOrders lists (GET) or cancels (DELETE) a symbol's open orders
+func (s *Server) handleOpenOrders(w http.ResponseWriter, r *http.Request) {
+	symbol := requestParams(r).Get("symbol")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	open := make([]*order, 0)
+	for _, o := range s.account.orders {
+		if o.isOpen() && (symbol == "" || o.symbol == symbol) {
+			open = append(open, o)
+		}
+	}
+
+	if r.Method == http.MethodDelete {
+		if len(open) == 0 {
+			writeError(w, http.StatusBadRequest, codeCancelRejected, "Unknown order sent.")
+			return
+		}
+		res := make([]binance.CancelOrderResponse, 0, len(open))
+		for _, o := range open {
+			res = append(res, s.cancel(o))
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	res := make([]binance.Order, 0, len(open))
+	for _, o := range open {
+		res = append(res, orderJSON(o))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleAllOrders lists a symbol's orders filtered by orderId, startTime and endTime
+func (s *Server) handleAllOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := parseListFilter(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadParam, "%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]binance.Order, 0)
+	for _, o := range s.account.orders {
+		if o.symbol == q.Get("symbol") && filter.match(o.id, o.time) {
+			res = append(res, orderJSON(o))
+		}
+	}
+	writeJSON(w, http.StatusOK, limitTail(filter, len(res), res))
+}
+
+// handleMyTrades lists a symbol's trades filtered by orderId, fromId, startTime and endTime
+func (s *Server) handleMyTrades(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := parseListFilter(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadParam, "%v", err)
+		return
+	}
+	orderID, _ := strconv.ParseInt(q.Get("orderId"), 10, 64)
+	filter.fromID, _ = strconv.ParseInt(q.Get("fromId"), 10, 64)
+	filter.orderID = 0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]*binance.TradeV3, 0)
+	for _, t := range s.account.trades {
+		if t.Symbol != q.Get("symbol") || (orderID != 0 && t.OrderID != orderID) {
+			continue
+		}
+		if filter.match(t.ID, t.Time) {
+			res = append(res, t)
+		}
+	}
+	writeJSON(w, http.StatusOK, limitTail(filter, len(res), res))
+}
+
+// listFilter is the paging of the order and trade list endpoints
+type listFilter struct {
+	orderID   int64 // allOrders: orders with this ID or newer
+	fromID    int64 // myTrades: trades with this ID or newer
+	startTime int64
+	endTime   int64
+	limit     int
+}
+
+// parseListFilter parses the paging parameters
+func parseListFilter(q url.Values) (*listFilter, error) {
+	f := &listFilter{limit: defaultListLimit}
+	f.orderID, _ = strconv.ParseInt(q.Get("orderId"), 10, 64)
+	f.startTime, _ = strconv.ParseInt(q.Get("startTime"), 10, 64)
+	f.endTime, _ = strconv.ParseInt(q.Get("endTime"), 10, 64)
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("Invalid limit.")
+		}
+		f.limit = min(n, maxListLimit)
+	}
+	if f.startTime > 0 && f.endTime > 0 && f.endTime-f.startTime > 24*60*60*1000 {
+		return nil, fmt.Errorf("More than 24 hours between startTime and endTime.")
+	}
+	return f, nil
+}
+
+// match reports whether an item with the ID and time passes the filter
+func (f *listFilter) match(id, time int64) bool {
+	if f.orderID != 0 && id < f.orderID {
+		return false
+	}
+	if f.fromID != 0 && id < f.fromID {
+		return false
+	}
+	if f.startTime > 0 && time < f.startTime {
+		return false
+	}
+	if f.endTime > 0 && time > f.endTime {
+		return false
+	}
+	return true
+}
+
+// limitTail keeps the oldest items after an ID cursor and the newest otherwise, like Binance
+func limitTail[T any](f *listFilter, n int, items []T) []T {
+	if n <= f.limit {
+		return items
+	}
+	if f.orderID != 0 || f.fromID != 0 || f.startTime > 0 {
+		return items[:f.limit]
+	}
+	return items[n-f.limit:]
+}
+
+// handleUserDataStream starts (POST), extends (PUT) and closes (DELETE) a listenKey
+func (s *Server) handleUserDataStream(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method == http.MethodPost {
+		s.listenID++
+		listenKey := fmt.Sprintf("mocklistenkey%d", s.listenID)
+		s.listenKeys[listenKey] = true
+		writeJSON(w, http.StatusOK, map[string]string{"listenKey": listenKey})
+		return
+	}
+
+	listenKey := requestParams(r).Get("listenKey")
+	if !s.listenKeys[listenKey] {
+		writeError(w, http.StatusBadRequest, codeNoListenKey, "This listenKey does not exist.")
+		return
+	}
+	if r.Method == http.MethodDelete {
+		delete(s.listenKeys, listenKey)
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// executionReport is the user-data stream's order update event
+type executionReport struct {
+	Event string `json:"e"`
+	Time  int64  `json:"E"`
+	binance.WsOrderUpdate
+}
+
+// accountPosition is the user-data stream's balance update event
+type accountPosition struct {
+	Event      string `json:"e"`
+	Time       int64  `json:"E"`
+	UpdateTime int64  `json:"u"`
+	binance.WsAccountUpdateList
+}
+
+// publishExecution sends an order update to every user-data stream
+func (s *Server) publishExecution(o *order, executionType string, t *binance.TradeV3, cancelID string) {
+	update := binance.WsOrderUpdate{
+		Symbol:            o.symbol,
+		ClientOrderId:     o.clientOrderID,
+		Side:              string(o.side),
+		Type:              string(o.orderType),
+		TimeInForce:       o.timeInForce,
+		Volume:            formatFloat(o.quantity),
+		Price:             formatFloat(o.price),
+		StopPrice:         formatFloat(0),
+		IceBergVolume:     formatFloat(0),
+		OrderListId:       -1,
+		ExecutionType:     executionType,
+		Status:            string(o.status),
+		RejectReason:      "NONE",
+		Id:                o.id,
+		LatestVolume:      formatFloat(0),
+		FilledVolume:      formatFloat(o.executed),
+		LatestPrice:       formatFloat(0),
+		FeeCost:           formatFloat(0),
+		TransactionTime:   o.updateTime,
+		TradeId:           -1,
+		IsInOrderBook:     o.isOpen(),
+		CreateTime:        o.time,
+		FilledQuoteVolume: formatFloat(o.quoteQty),
+		LatestQuoteVolume: formatFloat(0),
+		QuoteVolume:       formatFloat(0),
+	}
+	if cancelID != "" {
+		update.ClientOrderId = cancelID
+		update.OrigCustomOrderId = o.clientOrderID
+	}
+	if t != nil {
+		update.LatestVolume = t.Quantity
+		update.LatestPrice = t.Price
+		update.FeeAsset = t.CommissionAsset
+		update.FeeCost = t.Commission
+		update.TradeId = t.ID
+		update.IsMaker = t.IsMaker
+		update.LatestQuoteVolume = t.QuoteQuantity
+	}
+
+	s.publishUserData(executionReport{
+		Event:         string(binance.UserDataEventTypeExecutionReport),
+		Time:          o.updateTime,
+		WsOrderUpdate: update,
+	})
+}
+
+// publishBalances sends the assets' balances to every user-data stream
+func (s *Server) publishBalances(assets ...string) {
+	event := accountPosition{
+		Event:      string(binance.UserDataEventTypeOutboundAccountPosition),
+		Time:       s.clock(),
+		UpdateTime: s.clock(),
+	}
+	for _, asset := range assets {
+		b := s.account.balance(asset)
+		event.WsAccountUpdates = append(event.WsAccountUpdates, binance.WsAccountUpdate{
+			Asset:  asset,
+			Free:   formatFloat(b.free),
+			Locked: formatFloat(b.locked),
+		})
+	}
+	s.publishUserData(event)
+}
+
+// publishUserData sends an event to every open listenKey stream
+func (s *Server) publishUserData(event interface{}) {
+	for listenKey := range s.listenKeys {
+		s.hub.publish(listenKey, event)
+	}
+}
+
+// orderJSON converts an order to Binance's order body
+func orderJSON(o *order) binance.Order {
+	return binance.Order{
+		Symbol:                   o.symbol,
+		OrderID:                  o.id,
+		OrderListId:              -1,
+		ClientOrderID:            o.clientOrderID,
+		Price:                    formatFloat(o.price),
+		OrigQuantity:             formatFloat(o.quantity),
+		ExecutedQuantity:         formatFloat(o.executed),
+		CummulativeQuoteQuantity: formatFloat(o.quoteQty),
+		Status:                   o.status,
+		TimeInForce:              o.timeInForce,
+		Type:                     o.orderType,
+		Side:                     o.side,
+		StopPrice:                formatFloat(0),
+		IcebergQuantity:          formatFloat(0),
+		Time:                     o.time,
+		UpdateTime:               o.updateTime,
+		IsWorking:                true,
+		OrigQuoteOrderQuantity:   formatFloat(0),
+	}
+}
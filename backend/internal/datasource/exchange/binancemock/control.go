@@ -0,0 +1,61 @@
+package binancemock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// handleAdvance closes ?bars=N bars (default 1) and returns the new clock
+func (s *Server) handleAdvance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	bars := 1
+	if v := r.URL.Query().Get("bars"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, codeBadParam, "Invalid bars.")
+			return
+		}
+		bars = n
+	}
+
+	advanced := 0
+	for advanced < bars && s.Advance() {
+		advanced++
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"advanced": advanced,
+		"now":      s.Now().UnixMilli(),
+	})
+}
+
+// handleFaults returns (GET) or replaces (PUT/POST) the periodic faults
+func (s *Server) handleFaults(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.faults.get())
+	case http.MethodPut, http.MethodPost:
+		var cfg Faults
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			writeError(w, http.StatusBadRequest, codeBadParam, "Invalid faults: %v", err)
+			return
+		}
+		s.SetFaults(cfg)
+		writeJSON(w, http.StatusOK, cfg)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDisconnect drops every stream connection
+func (s *Server) handleDisconnect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"disconnected": s.Disconnect()})
+}
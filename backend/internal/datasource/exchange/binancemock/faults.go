@@ -0,0 +1,124 @@
+package binancemock
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultStallFor is how long a stalled request hangs when Faults.StallFor is unset
+const defaultStallFor = 30 * time.Second
+
+// Faults are failures injected into REST requests. Streams are dropped with Disconnect.
+type Faults struct {
+	RateLimitEvery int           `json:"rate_limit_every"` // Every Nth REST request is answered 429 (0 = never)
+	StallEvery     int           `json:"stall_every"`      // Every Nth REST request hangs for StallFor, then fails (0 = never)
+	StallFor       time.Duration `json:"stall_for"`        // How long a stalled request hangs (default: 30s)
+	Latency        time.Duration `json:"latency"`          // Added to every REST request
+}
+
+// faults decides which REST requests fail
+type faults struct {
+	mu            sync.Mutex
+	cfg           Faults
+	requests      int
+	rateLimitNext int
+	stallNext     int
+}
+
+// newFaults creates a fault injector
+func newFaults(cfg Faults) *faults {
+	return &faults{cfg: cfg}
+}
+
+// intercept applies latency and answers the request itself if it is chosen to
+// fail. It returns true if the request was handled.
+func (f *faults) intercept(w http.ResponseWriter, r *http.Request) bool {
+	f.mu.Lock()
+	f.requests++
+	cfg := f.cfg
+	rateLimit := f.rateLimitNext > 0 || (cfg.RateLimitEvery > 0 && f.requests%cfg.RateLimitEvery == 0)
+	if f.rateLimitNext > 0 {
+		f.rateLimitNext--
+	}
+	stall := !rateLimit && (f.stallNext > 0 || (cfg.StallEvery > 0 && f.requests%cfg.StallEvery == 0))
+	if stall && f.stallNext > 0 {
+		f.stallNext--
+	}
+	f.mu.Unlock()
+
+	if cfg.Latency > 0 && !sleep(r, cfg.Latency) {
+		return true
+	}
+
+	if rateLimit {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, codeTooManyRequests,
+			"Too many requests; current limit is 6000 request weight per 1 MINUTE.")
+		return true
+	}
+
+	if stall {
+		stallFor := cfg.StallFor
+		if stallFor <= 0 {
+			stallFor = defaultStallFor
+		}
+		if sleep(r, stallFor) {
+			writeError(w, http.StatusServiceUnavailable, codeTimeout,
+				"Timeout waiting for response from backend server. Send status unknown; execution status unknown.")
+		}
+		return true
+	}
+	return false
+}
+
+// set replaces the periodic faults
+func (f *faults) set(cfg Faults) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cfg = cfg
+}
+
+// get returns the periodic faults
+func (f *faults) get() Faults {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cfg
+}
+
+// sleep waits for d or until the client gives up; it returns false in the latter case
+func sleep(r *http.Request, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// SetFaults replaces the periodic faults
+func (s *Server) SetFaults(cfg Faults) {
+	s.faults.set(cfg)
+}
+
+// RateLimitNext answers the next n REST requests with 429
+func (s *Server) RateLimitNext(n int) {
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+	s.faults.rateLimitNext += n
+}
+
+// StallNext makes the next n REST requests hang for the configured StallFor
+func (s *Server) StallNext(n int) {
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+	s.faults.stallNext += n
+}
+
+// Disconnect drops every stream connection and returns how many were dropped
+func (s *Server) Disconnect() int {
+	return s.hub.disconnectAll()
+}
@@ -0,0 +1,19 @@
+package binancemock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// LoadCandles reads a fixture: a JSON array of candles in the API's format
+// ({"symbol","open_time","close_time","open","high","low","close","volume"})
+func LoadCandles(r io.Reader) ([]*domain.Candle, error) {
+	var candles []*domain.Candle
+	if err := json.NewDecoder(r).Decode(&candles); err != nil {
+		return nil, fmt.Errorf("failed to decode candle fixture: %w", err)
+	}
+	return candles, nil
+}
@@ -0,0 +1,232 @@
+package binancemock
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// futuresRoutes registers the USDⓈ-M futures market data and order endpoints.
+// They trade the same simulated account as spot: there are no positions or
+// margin, so a sell needs the base asset and reduceOnly is ignored.
+func (s *Server) futuresRoutes() {
+	s.rest("/fapi/v1/ping", s.handlePing)
+	s.rest("/fapi/v1/time", s.handleTime)
+	s.rest("/fapi/v1/exchangeInfo", s.handleFuturesExchangeInfo)
+	s.rest("/fapi/v1/klines", s.handleKlines)
+	s.rest("/fapi/v1/ticker/price", s.handleTickerPrice)
+
+	s.rest("/fapi/v1/order", s.handleFuturesOrder)
+	s.rest("/fapi/v1/openOrders", s.handleFuturesOpenOrders)
+	s.rest("/fapi/v1/allOpenOrders", s.handleFuturesCancelAll)
+	s.rest("/fapi/v1/allOrders", s.handleFuturesAllOrders)
+}
+
+// handleFuturesExchangeInfo lists every replayed symbol as a TRADING perpetual with the configured filters
+func (s *Server) handleFuturesExchangeInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := futures.ExchangeInfo{
+		Timezone:   "UTC",
+		ServerTime: s.clock(),
+		Symbols:    make([]futures.Symbol, 0, len(s.series)),
+	}
+	for _, symbol := range s.symbols() {
+		base := symbols.SplitSymbol(symbol)
+		quote := strings.TrimPrefix(symbol, base)
+		info.Symbols = append(info.Symbols, futures.Symbol{
+			Symbol:       symbol,
+			Pair:         symbol,
+			ContractType: futures.ContractTypePerpetual,
+			Status:       string(domain.SymbolStatusTrading),
+			BaseAsset:    base,
+			QuoteAsset:   quote,
+			MarginAsset:  quote,
+			OrderType:    []futures.OrderType{futures.OrderTypeLimit, futures.OrderTypeMarket},
+			Filters: []map[string]interface{}{
+				{
+					"filterType": string(futures.SymbolFilterTypePrice),
+					"minPrice":   formatFloat(s.cfg.TickSize),
+					"maxPrice":   "1000000.00000000",
+					"tickSize":   formatFloat(s.cfg.TickSize),
+				},
+				{
+					"filterType": string(futures.SymbolFilterTypeLotSize),
+					"minQty":     formatFloat(s.cfg.StepSize),
+					"maxQty":     "9000.00000000",
+					"stepSize":   formatFloat(s.cfg.StepSize),
+				},
+				{
+					"filterType": string(futures.SymbolFilterTypeMinNotional),
+					"notional":   formatFloat(s.cfg.MinNotional),
+				},
+			},
+		})
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleFuturesOrder creates (POST), queries (GET) and cancels (DELETE) an order
+func (s *Server) handleFuturesOrder(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method == http.MethodPost {
+		o, _, apiErr := s.placeOrder(r)
+		if apiErr != nil {
+			writeJSON(w, http.StatusBadRequest, apiErr)
+			return
+		}
+		res := futuresOrderJSON(o)
+		writeJSON(w, http.StatusOK, futures.CreateOrderResponse{
+			Symbol:           res.Symbol,
+			OrderID:          res.OrderID,
+			ClientOrderID:    res.ClientOrderID,
+			Price:            res.Price,
+			OrigQuantity:     res.OrigQuantity,
+			ExecutedQuantity: res.ExecutedQuantity,
+			CumQuote:         res.CumQuote,
+			Status:           res.Status,
+			TimeInForce:      res.TimeInForce,
+			Type:             res.Type,
+			Side:             res.Side,
+			UpdateTime:       res.UpdateTime,
+			AvgPrice:         res.AvgPrice,
+			PositionSide:     res.PositionSide,
+		})
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := requestParams(r)
+	orderID, _ := strconv.ParseInt(q.Get("orderId"), 10, 64)
+	clientOrderID := q.Get("origClientOrderId")
+	if orderID == 0 && clientOrderID == "" {
+		writeError(w, http.StatusBadRequest, codeBadParam, "Param 'origClientOrderId' or 'orderId' must be sent, but both were empty/null!")
+		return
+	}
+
+	o := s.account.findOrder(q.Get("symbol"), orderID, clientOrderID)
+	if r.Method == http.MethodGet {
+		if o == nil {
+			writeError(w, http.StatusBadRequest, codeNoSuchOrder, "Order does not exist.")
+			return
+		}
+		writeJSON(w, http.StatusOK, futuresOrderJSON(o))
+		return
+	}
+
+	if o == nil || !o.isOpen() {
+		writeError(w, http.StatusBadRequest, codeCancelRejected, "Unknown order sent.")
+		return
+	}
+	s.cancel(o)
+	res := futuresOrderJSON(o)
+	writeJSON(w, http.StatusOK, futures.CancelOrderResponse{
+		Symbol:           res.Symbol,
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Price:            res.Price,
+		OrigQuantity:     res.OrigQuantity,
+		ExecutedQuantity: res.ExecutedQuantity,
+		CumQuote:         res.CumQuote,
+		Status:           res.Status,
+		TimeInForce:      res.TimeInForce,
+		Type:             res.Type,
+		Side:             res.Side,
+		UpdateTime:       res.UpdateTime,
+		PositionSide:     res.PositionSide,
+	})
+}
+
+// handleFuturesOpenOrders lists the open orders of a symbol (all symbols if empty)
+func (s *Server) handleFuturesOpenOrders(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]futures.Order, 0)
+	for _, o := range s.account.orders {
+		if o.isOpen() && (symbol == "" || o.symbol == symbol) {
+			res = append(res, futuresOrderJSON(o))
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleFuturesCancelAll cancels a symbol's open orders and answers like Binance, without listing them
+func (s *Server) handleFuturesCancelAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	symbol := requestParams(r).Get("symbol")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, o := range s.account.orders {
+		if o.isOpen() && o.symbol == symbol {
+			s.cancel(o)
+		}
+	}
+	writeJSON(w, http.StatusOK, apiError{Code: 200, Message: "The operation of cancel all open order is done."})
+}
+
+// handleFuturesAllOrders lists a symbol's orders filtered by orderId, startTime and endTime
+func (s *Server) handleFuturesAllOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := parseListFilter(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadParam, "%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]futures.Order, 0)
+	for _, o := range s.account.orders {
+		if o.symbol == q.Get("symbol") && filter.match(o.id, o.time) {
+			res = append(res, futuresOrderJSON(o))
+		}
+	}
+	writeJSON(w, http.StatusOK, limitTail(filter, len(res), res))
+}
+
+// futuresOrderJSON converts an order to Binance futures' order body
+func futuresOrderJSON(o *order) futures.Order {
+	avgPrice := 0.0
+	if o.executed > 0 {
+		avgPrice = o.quoteQty / o.executed
+	}
+	return futures.Order{
+		Symbol:           o.symbol,
+		OrderID:          o.id,
+		ClientOrderID:    o.clientOrderID,
+		Price:            formatFloat(o.price),
+		OrigQuantity:     formatFloat(o.quantity),
+		ExecutedQuantity: formatFloat(o.executed),
+		CumQuantity:      formatFloat(o.executed),
+		CumQuote:         formatFloat(o.quoteQty),
+		Status:           futures.OrderStatusType(o.status),
+		TimeInForce:      futures.TimeInForceType(o.timeInForce),
+		Type:             futures.OrderType(o.orderType),
+		Side:             futures.SideType(o.side),
+		StopPrice:        formatFloat(0),
+		Time:             o.time,
+		UpdateTime:       o.updateTime,
+		AvgPrice:         formatFloat(avgPrice),
+		OrigType:         string(o.orderType),
+		PositionSide:     futures.PositionSideTypeBoth,
+	}
+}
@@ -0,0 +1,196 @@
+package binancemock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// streamBuffer is how many messages a slow stream client may fall behind before messages are dropped
+const streamBuffer = 256
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// streamConn is one stream client, subscribed to one or more streams
+type streamConn struct {
+	conn     *websocket.Conn
+	streams  map[string]bool
+	combined bool // Wrap messages as {"stream":...,"data":...} like /stream
+	send     chan []byte
+	done     chan struct{}
+	once     sync.Once
+}
+
+// close drops the connection
+func (c *streamConn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// hub fans stream events out to subscribed clients
+type hub struct {
+	mu    sync.Mutex
+	conns map[*streamConn]bool
+}
+
+// newHub creates an empty hub
+func newHub() *hub {
+	return &hub{conns: make(map[*streamConn]bool)}
+}
+
+// publish sends payload to every client subscribed to stream without blocking
+func (h *hub) publish(stream string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.conns {
+		if !c.streams[stream] {
+			continue
+		}
+		message := data
+		if c.combined {
+			message, _ = json.Marshal(map[string]json.RawMessage{
+				"stream": json.RawMessage(`"` + stream + `"`),
+				"data":   data,
+			})
+		}
+		select {
+		case c.send <- message:
+		default:
+		}
+	}
+}
+
+// disconnectAll drops every client and returns how many were dropped
+func (h *hub) disconnectAll() int {
+	h.mu.Lock()
+	conns := make([]*streamConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.conns = make(map[*streamConn]bool)
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		c.close()
+	}
+	return len(conns)
+}
+
+// streamRequest is a live subscription change sent by a client
+type streamRequest struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+	ID     int64    `json:"id"`
+}
+
+// handleRequest applies a SUBSCRIBE or UNSUBSCRIBE request and replies like Binance
+func (h *hub) handleRequest(c *streamConn, message []byte) {
+	var req streamRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	switch req.Method {
+	case "SUBSCRIBE":
+		for _, stream := range req.Params {
+			c.streams[stream] = true
+		}
+	case "UNSUBSCRIBE":
+		for _, stream := range req.Params {
+			delete(c.streams, stream)
+		}
+	}
+	h.mu.Unlock()
+
+	reply, _ := json.Marshal(map[string]interface{}{"result": nil, "id": req.ID})
+	select {
+	case c.send <- reply:
+	default:
+	}
+}
+
+// remove forgets a client
+func (h *hub) remove(c *streamConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, c)
+}
+
+// handleStream upgrades /ws/<stream> and /stream?streams=<a>/<b> connections
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	combined := false
+	if r.URL.Path == "/stream" {
+		names = strings.Split(r.URL.Query().Get("streams"), "/")
+		combined = true
+	} else {
+		names = strings.Split(strings.TrimPrefix(r.URL.Path, "/ws/"), "/")
+	}
+
+	streams := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name != "" {
+			streams[name] = true
+		}
+	}
+	if len(streams) == 0 {
+		http.Error(w, "no stream", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &streamConn{
+		conn:     conn,
+		streams:  streams,
+		combined: combined,
+		send:     make(chan []byte, streamBuffer),
+		done:     make(chan struct{}),
+	}
+	s.hub.mu.Lock()
+	s.hub.conns[c] = true
+	s.hub.mu.Unlock()
+
+	// Reader: answers pings (gorilla's default handler), handles SUBSCRIBE and
+	// UNSUBSCRIBE requests and notices the client leaving
+	go func() {
+		defer c.close()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			s.hub.handleRequest(c, message)
+		}
+	}()
+
+	defer s.hub.remove(c)
+	defer c.close()
+	for {
+		select {
+		case message := <-c.send:
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
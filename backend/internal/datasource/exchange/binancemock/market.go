@@ -0,0 +1,306 @@
+package binancemock
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Kline limits of /api/v3/klines
+const (
+	defaultKlineLimit = 500
+	maxKlineLimit     = 1000
+)
+
+// Advance closes the bar in progress: resting limit orders fill against its
+// range, a closed kline and a trade at its close are published, and the clock
+// moves to the next bar. It returns false once every series is exhausted.
+func (s *Server) Advance() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.now.After(s.end) {
+		return false
+	}
+
+	for _, symbol := range s.symbols() {
+		bar := s.barAt(symbol, s.now)
+		if bar == nil {
+			continue
+		}
+
+		s.matchRestingOrders(bar)
+
+		closeTime := bar.CloseTime
+		if closeTime.IsZero() {
+			closeTime = bar.OpenTime.Add(s.interval - time.Millisecond)
+		}
+		eventTime := closeTime.UnixMilli()
+
+		lower := strings.ToLower(symbol)
+		s.hub.publish(lower+"@kline_"+s.cfg.Interval, binance.WsKlineEvent{
+			Event:  "kline",
+			Time:   eventTime,
+			Symbol: symbol,
+			Kline: binance.WsKline{
+				StartTime:   bar.OpenTime.UnixMilli(),
+				EndTime:     closeTime.UnixMilli(),
+				Symbol:      symbol,
+				Interval:    s.cfg.Interval,
+				Open:        formatFloat(bar.Open),
+				Close:       formatFloat(bar.Close),
+				High:        formatFloat(bar.High),
+				Low:         formatFloat(bar.Low),
+				Volume:      formatFloat(bar.Volume),
+				IsFinal:     true,
+				QuoteVolume: formatFloat(bar.Volume * bar.Close),
+			},
+		})
+
+		s.tradeID++
+		s.hub.publish(lower+"@trade", binance.WsTradeEvent{
+			Event:     "trade",
+			Time:      eventTime,
+			Symbol:    symbol,
+			TradeID:   s.tradeID,
+			Price:     formatFloat(bar.Close),
+			Quantity:  formatFloat(bar.Volume),
+			TradeTime: eventTime,
+		})
+	}
+
+	s.now = s.now.Add(s.interval)
+	return true
+}
+
+// Run advances one bar every period until the series is exhausted or ctx is done
+func (s *Server) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !s.Advance() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// symbols returns the replayed symbols in order
+func (s *Server) symbols() []string {
+	names := make([]string, 0, len(s.series))
+	for symbol := range s.series {
+		names = append(names, symbol)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// barAt returns the symbol's bar opening at t (nil if there is none)
+func (s *Server) barAt(symbol string, t time.Time) *domain.Candle {
+	candles := s.series[symbol]
+	i := sort.Search(len(candles), func(i int) bool { return !candles[i].OpenTime.Before(t) })
+	if i < len(candles) && candles[i].OpenTime.Equal(t) {
+		return candles[i]
+	}
+	return nil
+}
+
+// price returns the symbol's current price: the open of the bar in progress,
+// or the last close once the series has ended
+func (s *Server) price(symbol string) (float64, bool) {
+	candles, ok := s.series[symbol]
+	if !ok {
+		return 0, false
+	}
+	if bar := s.barAt(symbol, s.now); bar != nil {
+		return bar.Open, true
+	}
+
+	i := sort.Search(len(candles), func(i int) bool { return !candles[i].OpenTime.Before(s.now) })
+	if i == 0 {
+		return candles[0].Open, true
+	}
+	return candles[i-1].Close, true
+}
+
+// clock returns the mock time in milliseconds
+func (s *Server) clock() int64 {
+	return s.now.UnixMilli()
+}
+
+func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) handleTime(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]int64{"serverTime": s.clock()})
+}
+
+// handleExchangeInfo lists every replayed symbol as TRADING with the configured filters
+func (s *Server) handleExchangeInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := binance.ExchangeInfo{
+		Timezone:   "UTC",
+		ServerTime: s.clock(),
+		Symbols:    make([]binance.Symbol, 0, len(s.series)),
+	}
+	for _, symbol := range s.symbols() {
+		base := symbols.SplitSymbol(symbol)
+		info.Symbols = append(info.Symbols, binance.Symbol{
+			Symbol:               symbol,
+			Status:               string(domain.SymbolStatusTrading),
+			BaseAsset:            base,
+			QuoteAsset:           strings.TrimPrefix(symbol, base),
+			OrderTypes:           []string{string(binance.OrderTypeLimit), string(binance.OrderTypeMarket)},
+			IsSpotTradingAllowed: true,
+			Permissions:          []string{"SPOT"},
+			Filters: []map[string]interface{}{
+				{
+					"filterType": string(binance.SymbolFilterTypePriceFilter),
+					"minPrice":   formatFloat(s.cfg.TickSize),
+					"maxPrice":   "1000000.00000000",
+					"tickSize":   formatFloat(s.cfg.TickSize),
+				},
+				{
+					"filterType": string(binance.SymbolFilterTypeLotSize),
+					"minQty":     formatFloat(s.cfg.StepSize),
+					"maxQty":     "9000.00000000",
+					"stepSize":   formatFloat(s.cfg.StepSize),
+				},
+				{
+					"filterType":       string(binance.SymbolFilterTypeNotional),
+					"minNotional":      formatFloat(s.cfg.MinNotional),
+					"applyMinToMarket": true,
+					"maxNotional":      "9000000.00000000",
+					"applyMaxToMarket": false,
+					"avgPriceMins":     5,
+				},
+			},
+		})
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleKlines serves closed bars only, in Binance's array format
+func (s *Server) handleKlines(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	symbol := q.Get("symbol")
+	if interval := q.Get("interval"); interval != s.cfg.Interval {
+		writeError(w, http.StatusBadRequest, codeBadInterval, "Invalid interval %q (the mock replays %s).", interval, s.cfg.Interval)
+		return
+	}
+
+	limit := defaultKlineLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, codeBadParam, "Invalid limit.")
+			return
+		}
+		limit = min(n, maxKlineLimit)
+	}
+	startTime, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+	endTime, _ := strconv.ParseInt(q.Get("endTime"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candles, ok := s.series[symbol]
+	if !ok {
+		writeError(w, http.StatusBadRequest, codeBadSymbol, "Invalid symbol.")
+		return
+	}
+
+	closed := make([]*domain.Candle, 0)
+	for _, c := range candles {
+		if !c.OpenTime.Before(s.now) {
+			break
+		}
+		openTime := c.OpenTime.UnixMilli()
+		if (startTime > 0 && openTime < startTime) || (endTime > 0 && openTime > endTime) {
+			continue
+		}
+		closed = append(closed, c)
+	}
+	// Without startTime Binance returns the most recent bars
+	if len(closed) > limit {
+		if startTime > 0 {
+			closed = closed[:limit]
+		} else {
+			closed = closed[len(closed)-limit:]
+		}
+	}
+
+	rows := make([][]interface{}, 0, len(closed))
+	for _, c := range closed {
+		closeTime := c.CloseTime
+		if closeTime.IsZero() {
+			closeTime = c.OpenTime.Add(s.interval - time.Millisecond)
+		}
+		rows = append(rows, []interface{}{
+			c.OpenTime.UnixMilli(),
+			formatFloat(c.Open),
+			formatFloat(c.High),
+			formatFloat(c.Low),
+			formatFloat(c.Close),
+			formatFloat(c.Volume),
+			closeTime.UnixMilli(),
+			formatFloat(c.Volume * c.Close),
+			0,
+			"0",
+			"0",
+			"0",
+		})
+	}
+	writeJSON(w, http.StatusOK, rows)
+}
+
+// tickerPrice is /api/v3/ticker/price's body
+type tickerPrice struct {
+	Symbol string `json:"symbol"`
+	Price  string `json:"price"`
+}
+
+// handleTickerPrice returns one symbol's price, or every symbol's without a symbol parameter
+func (s *Server) handleTickerPrice(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+		price, ok := s.price(symbol)
+		if !ok {
+			writeError(w, http.StatusBadRequest, codeBadSymbol, "Invalid symbol.")
+			return
+		}
+		writeJSON(w, http.StatusOK, tickerPrice{Symbol: symbol, Price: formatFloat(price)})
+		return
+	}
+
+	prices := make([]tickerPrice, 0, len(s.series))
+	for _, symbol := range s.symbols() {
+		price, _ := s.price(symbol)
+		prices = append(prices, tickerPrice{Symbol: symbol, Price: formatFloat(price)})
+	}
+	writeJSON(w, http.StatusOK, prices)
+}
+
+// formatFloat formats a number like Binance's fixed 8-decimal strings
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 8, 64)
+}
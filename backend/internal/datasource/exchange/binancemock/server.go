@@ -0,0 +1,242 @@
+// Package binancemock is a fake Binance spot API for offline integration testing.
+// It replays a candle series as the market, keeps a simulated account that
+// fills orders against it, serves the REST endpoints and WebSocket streams the
+// exchange and collector use, and can inject rate limits, stalls and disconnects.
+// The futures market data and order endpoints are served on the same account.
+package binancemock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Config configures a mock server
+type Config struct {
+	Interval    string             // Interval of Candles (default: 1m)
+	Candles     []*domain.Candle   // Replayed market, any number of symbols
+	Warmup      int                // Bars already closed when the server starts
+	Balances    map[string]float64 // Initial account balances (default: 10000 USDT)
+	Commission  float64            // Fee rate charged on fills (default: 0.001)
+	TickSize    float64            // exchangeInfo PRICE_FILTER for every symbol (default: 0.01)
+	StepSize    float64            // exchangeInfo LOT_SIZE for every symbol (default: 0.00001)
+	MinNotional float64            // exchangeInfo NOTIONAL for every symbol (default: 5)
+	Faults      Faults             // Faults injected from the start
+}
+
+// Server is a mock Binance spot API
+type Server struct {
+	cfg      Config
+	interval time.Duration
+	mux      *http.ServeMux
+	hub      *hub
+	faults   *faults
+	http     *httptest.Server
+
+	mu         sync.Mutex
+	series     map[string][]*domain.Candle // By symbol, oldest first
+	now        time.Time                   // Open time of the bar in progress
+	end        time.Time                   // Open time of the last bar
+	account    *account
+	listenKeys map[string]bool
+	listenID   int64
+	tradeID    int64 // Last market trade published on the trade streams
+}
+
+// New creates a mock server replaying cfg.Candles. Use Start for an httptest
+// server or Handler to serve it yourself.
+func New(cfg Config) (*Server, error) {
+	if cfg.Interval == "" {
+		cfg.Interval = "1m"
+	}
+	if cfg.Balances == nil {
+		cfg.Balances = map[string]float64{"USDT": 10000}
+	}
+	if cfg.Commission == 0 {
+		cfg.Commission = 0.001
+	}
+	if cfg.TickSize == 0 {
+		cfg.TickSize = 0.01
+	}
+	if cfg.StepSize == 0 {
+		cfg.StepSize = 0.00001
+	}
+	if cfg.MinNotional == 0 {
+		cfg.MinNotional = 5
+	}
+
+	interval, err := history.ParseInterval(cfg.Interval)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Candles) == 0 {
+		return nil, fmt.Errorf("no candles to replay")
+	}
+
+	s := &Server{
+		cfg:        cfg,
+		interval:   interval,
+		mux:        http.NewServeMux(),
+		hub:        newHub(),
+		faults:     newFaults(cfg.Faults),
+		series:     make(map[string][]*domain.Candle),
+		account:    newAccount(cfg.Balances, cfg.Commission),
+		listenKeys: make(map[string]bool),
+	}
+
+	for _, c := range cfg.Candles {
+		s.series[c.Symbol] = append(s.series[c.Symbol], c)
+	}
+	for _, candles := range s.series {
+		sort.Slice(candles, func(i, j int) bool { return candles[i].OpenTime.Before(candles[j].OpenTime) })
+		if first := candles[0].OpenTime; s.now.IsZero() || first.Before(s.now) {
+			s.now = first
+		}
+		if last := candles[len(candles)-1].OpenTime; last.After(s.end) {
+			s.end = last
+		}
+	}
+	s.now = s.now.Add(time.Duration(cfg.Warmup) * interval)
+
+	s.routes()
+	return s, nil
+}
+
+// Start serves the mock on a local httptest server
+func (s *Server) Start() {
+	s.http = httptest.NewServer(s.Handler())
+}
+
+// Close stops the httptest server and drops every stream
+func (s *Server) Close() {
+	s.hub.disconnectAll()
+	if s.http != nil {
+		s.http.Close()
+	}
+}
+
+// Handler returns the mock's HTTP handler (REST, streams and /mock control endpoints)
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// URL returns the REST base URL of a started server
+func (s *Server) URL() string {
+	return s.http.URL
+}
+
+// WsURL returns the stream base URL of a started server (streams are at <WsURL>/<stream>)
+func (s *Server) WsURL() string {
+	return "ws" + strings.TrimPrefix(s.http.URL, "http") + "/ws"
+}
+
+// Now returns the open time of the bar in progress
+func (s *Server) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// routes registers the REST, stream and control endpoints
+func (s *Server) routes() {
+	// Public market data
+	s.rest("/api/v3/ping", s.handlePing)
+	s.rest("/api/v3/time", s.handleTime)
+	s.rest("/api/v3/exchangeInfo", s.handleExchangeInfo)
+	s.rest("/api/v3/klines", s.handleKlines)
+	s.rest("/api/v3/ticker/price", s.handleTickerPrice)
+
+	// Account and orders
+	s.rest("/api/v3/account", s.handleAccount)
+	s.rest("/api/v3/order", s.handleOrder)
+	s.rest("/api/v3/openOrders", s.handleOpenOrders)
+	s.rest("/api/v3/allOrders", s.handleAllOrders)
+	s.rest("/api/v3/myTrades", s.handleMyTrades)
+	s.rest("/api/v3/userDataStream", s.handleUserDataStream)
+
+	// USDⓈ-M futures subset
+	s.futuresRoutes()
+
+	// Streams: /ws/<stream> and /stream?streams=<a>/<b>
+	s.mux.HandleFunc("/ws/", s.handleStream)
+	s.mux.HandleFunc("/stream", s.handleStream)
+
+	// Control
+	s.mux.HandleFunc("/mock/advance", s.handleAdvance)
+	s.mux.HandleFunc("/mock/faults", s.handleFaults)
+	s.mux.HandleFunc("/mock/disconnect", s.handleDisconnect)
+}
+
+// rest registers a REST endpoint behind fault injection
+func (s *Server) rest(path string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if s.faults.intercept(w, r) {
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// requestParams returns a request's query and form body parameters. Unlike
+// ParseForm it also reads DELETE bodies, where go-binance sends cancel parameters.
+func requestParams(r *http.Request) url.Values {
+	params := r.URL.Query()
+	if r.Body == nil {
+		return params
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return params
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return params
+	}
+	for key, values := range form {
+		params[key] = append(params[key], values...)
+	}
+	return params
+}
+
+// apiError is Binance's error body
+type apiError struct {
+	Code    int64  `json:"code"`
+	Message string `json:"msg"`
+}
+
+// Binance error codes returned by the mock
+const (
+	codeTimeout          = -1007
+	codeTooManyRequests  = -1003
+	codeFilterFailure    = -1013
+	codeBadOrderType     = -1116
+	codeBadParam         = -1102
+	codeBadInterval      = -1120
+	codeBadSymbol        = -1121
+	codeNewOrderRejected = -2010
+	codeNoListenKey      = -1125
+	codeCancelRejected   = -2011
+	codeNoSuchOrder      = -2013
+)
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a Binance error response
+func writeError(w http.ResponseWriter, status int, code int64, format string, args ...interface{}) {
+	writeJSON(w, status, apiError{Code: code, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,793 @@
+package exchange
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Bybit v5 REST and public spot stream base URLs
+const (
+	bybitMainURL      = "https://api.bybit.com"
+	bybitTestnetURL   = "https://api-testnet.bybit.com"
+	bybitWsMainURL    = "wss://stream.bybit.com/v5/public/spot"
+	bybitWsTestnetURL = "wss://stream-testnet.bybit.com/v5/public/spot"
+)
+
+// Bybit API limits
+const (
+	bybitRecvWindow       = "5000"
+	bybitMaxKlineLimit    = 1000
+	bybitMaxOrderPage     = 50
+	bybitMaxTradePage     = 100
+	bybitHistoryWindow    = 7 * 24 * time.Hour // Longest startTime-endTime span of order and execution history
+	bybitRequestSpacing   = 50 * time.Millisecond
+	bybitPingInterval     = 20 * time.Second
+	bybitCategory         = "spot"
+	bybitMarketUnitBase   = "baseCoin" // Market buy quantity is in the base asset, like ours
+	bybitTimeInForceGTC   = "GTC"
+	bybitStatusTrading    = "Trading"
+	bybitUnknownOrder     = 110001
+	bybitSpotUnknownOrder = 170213
+)
+
+// bybitFees is the base tier spot fee schedule
+var bybitFees = FeeSchedule{Maker: 0.001, Taker: 0.001}
+
+// bybitIntervals maps our interval names to Bybit's
+var bybitIntervals = map[string]venueInterval{
+	"1m":  {"1", time.Minute},
+	"3m":  {"3", 3 * time.Minute},
+	"5m":  {"5", 5 * time.Minute},
+	"15m": {"15", 15 * time.Minute},
+	"30m": {"30", 30 * time.Minute},
+	"1h":  {"60", time.Hour},
+	"2h":  {"120", 2 * time.Hour},
+	"4h":  {"240", 4 * time.Hour},
+	"6h":  {"360", 6 * time.Hour},
+	"12h": {"720", 12 * time.Hour},
+	"1d":  {"D", 24 * time.Hour},
+	"1w":  {"W", 7 * 24 * time.Hour},
+}
+
+func init() {
+	RegisterVenue(Venue{
+		Name:    "bybit",
+		Symbols: SymbolStyleConcat,
+		Fees:    bybitFees,
+		New: func(cfg VenueConfig) (VenueExchange, error) {
+			return NewBybitExchange(cfg)
+		},
+	})
+}
+
+// BybitExchange implements Exchange for Bybit spot (v5 API, unified account)
+type BybitExchange struct {
+	rest      *restClient
+	apiKey    string
+	secretKey string
+	wsURL     string
+	fees      FeeSchedule
+	rules     SymbolRules
+	tap       func([]byte)
+	mu        sync.RWMutex
+	closedC   chan struct{}
+	closed    bool
+}
+
+// NewBybitExchange creates a new Bybit exchange client. API keys are only needed for trading.
+func NewBybitExchange(cfg VenueConfig) (*BybitExchange, error) {
+	baseURL, wsURL := bybitMainURL, bybitWsMainURL
+	if cfg.Testnet {
+		baseURL, wsURL = bybitTestnetURL, bybitWsTestnetURL
+	}
+	if cfg.BaseURL != "" {
+		baseURL = cfg.BaseURL
+	}
+	if cfg.WsBaseURL != "" {
+		wsURL = cfg.WsBaseURL
+	}
+
+	return &BybitExchange{
+		rest:      newRESTClient("bybit", baseURL, cfg.httpClient(), bybitRequestSpacing),
+		apiKey:    cfg.APIKey,
+		secretKey: cfg.SecretKey,
+		wsURL:     wsURL,
+		fees:      cfg.fees(bybitFees),
+		tap:       cfg.StreamTap,
+		closedC:   make(chan struct{}),
+	}, nil
+}
+
+// Venue names the venue
+func (bx *BybitExchange) Venue() string {
+	return "bybit"
+}
+
+// Fees returns the configured fee schedule
+func (bx *BybitExchange) Fees() FeeSchedule {
+	return bx.fees
+}
+
+// SetSymbolRules makes PlaceOrder round and validate orders before sending them
+func (bx *BybitExchange) SetSymbolRules(rules SymbolRules) {
+	bx.rules = rules
+}
+
+// MaxKlineLimit is the largest page /v5/market/kline returns
+func (bx *BybitExchange) MaxKlineLimit() int {
+	return bybitMaxKlineLimit
+}
+
+// bybitResponse is the envelope of every v5 response
+type bybitResponse struct {
+	RetCode int             `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// bybitList is a v5 list result with cursor pagination
+type bybitList[T any] struct {
+	List           []T    `json:"list"`
+	NextPageCursor string `json:"nextPageCursor"`
+}
+
+// request calls the API. GET parameters go in the query and POST parameters
+// in a JSON body; signed requests carry the X-BAPI-* authentication headers.
+func (bx *BybitExchange) request(ctx context.Context, method, path string, params map[string]string, signed bool, result interface{}) error {
+	var query string
+	var body []byte
+	if method == http.MethodGet {
+		query = encodeQuery(params)
+	} else {
+		fields := make(map[string]string, len(params))
+		for key, value := range params {
+			if value != "" {
+				fields[key] = value
+			}
+		}
+		body, _ = json.Marshal(fields)
+	}
+
+	header := http.Header{}
+	if signed {
+		if bx.apiKey == "" || bx.secretKey == "" {
+			return fmt.Errorf("bybit API key and secret are required")
+		}
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		payload := query
+		if body != nil {
+			payload = string(body)
+		}
+		mac := hmac.New(sha256.New, []byte(bx.secretKey))
+		mac.Write([]byte(timestamp + bx.apiKey + bybitRecvWindow + payload))
+
+		header.Set("X-BAPI-API-KEY", bx.apiKey)
+		header.Set("X-BAPI-TIMESTAMP", timestamp)
+		header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+		header.Set("X-BAPI-SIGN", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	status, data, err := bx.rest.do(ctx, method, path, query, body, header)
+	if err != nil {
+		return err
+	}
+
+	var res bybitResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return &VenueAPIError{Venue: "bybit", Status: status, Message: truncate(string(data), 200)}
+	}
+	if status != http.StatusOK || res.RetCode != 0 {
+		return &VenueAPIError{Venue: "bybit", Status: status, Code: strconv.Itoa(res.RetCode), Message: res.RetMsg}
+	}
+	if result != nil {
+		if err := json.Unmarshal(res.Result, result); err != nil {
+			return fmt.Errorf("failed to decode bybit %s response: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// bybitPages follows a list endpoint's cursor until it is exhausted or max items were read
+func bybitPages[T any](ctx context.Context, bx *BybitExchange, path string, params map[string]string, max int) ([]T, error) {
+	items := make([]T, 0)
+	for {
+		var page bybitList[T]
+		if err := bx.request(ctx, http.MethodGet, path, params, true, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page.List...)
+		if page.NextPageCursor == "" || len(page.List) == 0 || len(items) >= max {
+			return items, nil
+		}
+		params["cursor"] = page.NextPageCursor
+	}
+}
+
+// GetCurrentPrice returns the last traded price
+func (bx *BybitExchange) GetCurrentPrice(ctx context.Context, symbol string) (float64, error) {
+	var res bybitList[struct {
+		LastPrice string `json:"lastPrice"`
+	}]
+	err := bx.request(ctx, http.MethodGet, "/v5/market/tickers", map[string]string{
+		"category": bybitCategory,
+		"symbol":   SymbolStyleConcat.ToVenue(symbol),
+	}, false, &res)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get price: %w", err)
+	}
+	if len(res.List) == 0 {
+		return 0, fmt.Errorf("no price data for symbol: %s", symbol)
+	}
+
+	price, err := strconv.ParseFloat(res.List[0].LastPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse price: %w", err)
+	}
+	return price, nil
+}
+
+// bybitInstrument is a spot instrument's trading rules
+type bybitInstrument struct {
+	Symbol        string `json:"symbol"`
+	BaseCoin      string `json:"baseCoin"`
+	QuoteCoin     string `json:"quoteCoin"`
+	Status        string `json:"status"`
+	LotSizeFilter struct {
+		BasePrecision string `json:"basePrecision"`
+		MinOrderQty   string `json:"minOrderQty"`
+		MaxOrderQty   string `json:"maxOrderQty"`
+		MinOrderAmt   string `json:"minOrderAmt"`
+	} `json:"lotSizeFilter"`
+	PriceFilter struct {
+		TickSize string `json:"tickSize"`
+	} `json:"priceFilter"`
+}
+
+// GetSymbolInfo returns a symbol's trading rules
+func (bx *BybitExchange) GetSymbolInfo(ctx context.Context, symbol string) (*domain.SymbolInfo, error) {
+	var res bybitList[bybitInstrument]
+	err := bx.request(ctx, http.MethodGet, "/v5/market/instruments-info", map[string]string{
+		"category": bybitCategory,
+		"symbol":   SymbolStyleConcat.ToVenue(symbol),
+	}, false, &res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instrument info: %w", err)
+	}
+	if len(res.List) == 0 {
+		return nil, fmt.Errorf("unknown symbol: %s", symbol)
+	}
+
+	inst := res.List[0]
+	status := strings.ToUpper(inst.Status)
+	if inst.Status == bybitStatusTrading {
+		status = domain.SymbolStatusTrading
+	}
+	return &domain.SymbolInfo{
+		Symbol:      symbol,
+		BaseAsset:   inst.BaseCoin,
+		QuoteAsset:  inst.QuoteCoin,
+		Status:      status,
+		TickSize:    parseFloat(inst.PriceFilter.TickSize),
+		StepSize:    parseFloat(inst.LotSizeFilter.BasePrecision),
+		MinQty:      parseFloat(inst.LotSizeFilter.MinOrderQty),
+		MaxQty:      parseFloat(inst.LotSizeFilter.MaxOrderQty),
+		MinNotional: parseFloat(inst.LotSizeFilter.MinOrderAmt),
+		Permissions: make([]string, 0),
+		UpdatedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// GetCandles retrieves the most recent candles, oldest first
+func (bx *BybitExchange) GetCandles(ctx context.Context, symbol, interval string, limit int) ([]*domain.Candle, error) {
+	return bx.GetKlines(ctx, symbol, interval, time.Time{}, time.Time{}, limit)
+}
+
+// GetKlines returns up to limit candles opening in [start, end], oldest first.
+// A zero start returns the most recent candles.
+func (bx *BybitExchange) GetKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	iv, err := lookupInterval("bybit", bybitIntervals, interval)
+	if err != nil {
+		return nil, err
+	}
+	limit = min(max(limit, 1), bybitMaxKlineLimit)
+
+	params := map[string]string{
+		"category": bybitCategory,
+		"symbol":   SymbolStyleConcat.ToVenue(symbol),
+		"interval": iv.Code,
+		"limit":    strconv.Itoa(limit),
+	}
+	if !start.IsZero() {
+		end = klineWindow(start, end, iv.Duration, limit)
+		params["start"] = strconv.FormatInt(start.UnixMilli(), 10)
+		params["end"] = strconv.FormatInt(end.UnixMilli(), 10)
+	}
+
+	var res bybitList[[]string]
+	if err := bx.request(ctx, http.MethodGet, "/v5/market/kline", params, false, &res); err != nil {
+		return nil, fmt.Errorf("failed to get candles: %w", err)
+	}
+
+	candles := make([]*domain.Candle, 0, len(res.List))
+	for _, row := range res.List {
+		candle, err := candleFromRow(symbol, row, iv.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse candle: %w", err)
+		}
+		candles = append(candles, candle)
+	}
+	return sortCandles(candles, start, end), nil
+}
+
+// bybitOrder is an order as returned by /v5/order/realtime and /v5/order/history
+type bybitOrder struct {
+	OrderID      string `json:"orderId"`
+	OrderLinkID  string `json:"orderLinkId"`
+	Symbol       string `json:"symbol"`
+	Side         string `json:"side"`
+	OrderType    string `json:"orderType"`
+	Price        string `json:"price"`
+	Qty          string `json:"qty"`
+	AvgPrice     string `json:"avgPrice"`
+	CumExecQty   string `json:"cumExecQty"`
+	CumExecValue string `json:"cumExecValue"`
+	OrderStatus  string `json:"orderStatus"`
+	CreatedTime  string `json:"createdTime"`
+	UpdatedTime  string `json:"updatedTime"`
+}
+
+// bybitOrderID is the result of create, cancel and cancel-all
+type bybitOrderID struct {
+	OrderID     string `json:"orderId"`
+	OrderLinkID string `json:"orderLinkId"`
+}
+
+// PlaceOrder submits a new order.
+// An order that already carries a client order ID is looked up first, so a
+// retried submit returns the order Bybit already has instead of trading twice.
+func (bx *BybitExchange) PlaceOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	bx.mu.RLock()
+	if bx.closed {
+		bx.mu.RUnlock()
+		return nil, fmt.Errorf("exchange is closed")
+	}
+	bx.mu.RUnlock()
+
+	if err := checkOrderType(order); err != nil {
+		return order, err
+	}
+
+	if order.ClientOrderID != "" {
+		existing, err := bx.GetOrder(ctx, domain.OrderRef{Symbol: order.Symbol, ClientOrderID: order.ClientOrderID})
+		if err == nil {
+			return existing, nil
+		}
+		if !bx.isUnknownOrder(err) {
+			return nil, fmt.Errorf("failed to check for duplicate order: %w", err)
+		}
+	}
+
+	// Round to tick size and base precision so Bybit doesn't reject the order
+	if err := applySymbolRules(ctx, bx.rules, order, bx.GetCurrentPrice); err != nil {
+		return order, err
+	}
+
+	assignClientOrderID(order)
+	params := map[string]string{
+		"category":    bybitCategory,
+		"symbol":      SymbolStyleConcat.ToVenue(order.Symbol),
+		"side":        bybitSide(order.Side),
+		"orderType":   "Market",
+		"qty":         formatFloat(order.Quantity),
+		"orderLinkId": order.ClientOrderID,
+	}
+	if order.Type == domain.OrderTypeLimit {
+		params["orderType"] = "Limit"
+		params["price"] = formatFloat(order.Price)
+		params["timeInForce"] = bybitTimeInForceGTC
+	} else {
+		params["marketUnit"] = bybitMarketUnitBase
+	}
+
+	var res bybitOrderID
+	if err := bx.request(ctx, http.MethodPost, "/v5/order/create", params, true, &res); err != nil {
+		// The order may have reached Bybit even though the response was lost
+		var apiErr *VenueAPIError
+		if !errors.As(err, &apiErr) {
+			if existing, getErr := bx.GetOrder(ctx, order.Ref()); getErr == nil {
+				return existing, nil
+			}
+		}
+		order.Status = domain.OrderStatusRejected
+		return order, fmt.Errorf("failed to place order: %w", err)
+	}
+
+	// Creation only acknowledges the order; fetch its state for fills
+	order.ID = res.OrderID
+	placed, err := bx.GetOrder(ctx, order.Ref())
+	if err != nil {
+		log.Printf("⚠️  Bybit order %s placed but not yet queryable: %v", order.Ref(), err)
+		order.Status = domain.OrderStatusNew
+		order.CreatedAt = time.Now().UTC()
+		order.UpdatedAt = order.CreatedAt
+		return order, nil
+	}
+	return placed, nil
+}
+
+// GetOrder retrieves an order by exchange ID or client order ID, open or closed
+func (bx *BybitExchange) GetOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	params, err := bybitOrderParams(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open and recently closed orders are in realtime, older ones only in history
+	for _, path := range []string{"/v5/order/realtime", "/v5/order/history"} {
+		var res bybitList[bybitOrder]
+		if err := bx.request(ctx, http.MethodGet, path, params, true, &res); err != nil {
+			return nil, fmt.Errorf("failed to get order %s: %w", ref, err)
+		}
+		if len(res.List) > 0 {
+			return orderFromBybit(&res.List[0]), nil
+		}
+	}
+	return nil, fmt.Errorf("failed to get order %s: %w", ref, ErrOrderNotFound)
+}
+
+// CancelOrder cancels an open order by exchange ID or client order ID
+func (bx *BybitExchange) CancelOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	params, err := bybitOrderParams(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var res bybitOrderID
+	if err := bx.request(ctx, http.MethodPost, "/v5/order/cancel", params, true, &res); err != nil {
+		return nil, fmt.Errorf("failed to cancel order %s: %w", ref, err)
+	}
+	return bx.GetOrder(ctx, domain.OrderRef{Symbol: ref.Symbol, OrderID: res.OrderID})
+}
+
+// GetOpenOrders lists open orders for a symbol (all symbols if empty)
+func (bx *BybitExchange) GetOpenOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	params := map[string]string{
+		"category": bybitCategory,
+		"openOnly": "0",
+		"limit":    strconv.Itoa(bybitMaxOrderPage),
+	}
+	if symbol != "" {
+		params["symbol"] = SymbolStyleConcat.ToVenue(symbol)
+	}
+
+	res, err := bybitPages[bybitOrder](ctx, bx, "/v5/order/realtime", params, defaultHistoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders: %w", err)
+	}
+
+	orders := make([]*domain.Order, 0, len(res))
+	for i := range res {
+		if order := orderFromBybit(&res[i]); order.IsOpen() {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+// CancelAllOrders cancels every open order for a symbol
+func (bx *BybitExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	open, err := bx.GetOpenOrders(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if len(open) == 0 {
+		return open, nil
+	}
+
+	var res bybitList[bybitOrderID]
+	err = bx.request(ctx, http.MethodPost, "/v5/order/cancel-all", map[string]string{
+		"category": bybitCategory,
+		"symbol":   SymbolStyleConcat.ToVenue(symbol),
+	}, true, &res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel open orders: %w", err)
+	}
+	return markCancelled(open, len(res.List), func(i int) string { return res.List[i].OrderID }), nil
+}
+
+// GetOrderHistory lists a symbol's orders of any status, querying 7 day windows from start to end
+func (bx *BybitExchange) GetOrderHistory(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit = historyLimit(limit)
+
+	orders := make([]*domain.Order, 0)
+	err := historyWindows(start, end, bybitHistoryWindow, limit, func(from, to time.Time) (int, error) {
+		params := bybitRangeParams(symbol, from, to, bybitMaxOrderPage)
+		res, err := bybitPages[bybitOrder](ctx, bx, "/v5/order/history", params, limit)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get order history: %w", err)
+		}
+		for i := range res {
+			orders = append(orders, orderFromBybit(&res[i]))
+		}
+		return len(res), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+	return limitHistory(orders, start, limit), nil
+}
+
+// bybitExecution is an account trade as returned by /v5/execution/list
+type bybitExecution struct {
+	ExecID      string `json:"execId"`
+	OrderID     string `json:"orderId"`
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"`
+	ExecPrice   string `json:"execPrice"`
+	ExecQty     string `json:"execQty"`
+	ExecFee     string `json:"execFee"`
+	FeeCurrency string `json:"feeCurrency"`
+	IsMaker     bool   `json:"isMaker"`
+	ExecTime    string `json:"execTime"`
+}
+
+// GetMyTrades lists a symbol's executions, querying 7 day windows from start to end
+func (bx *BybitExchange) GetMyTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit = historyLimit(limit)
+
+	trades := make([]*domain.Trade, 0)
+	err := historyWindows(start, end, bybitHistoryWindow, limit, func(from, to time.Time) (int, error) {
+		params := bybitRangeParams(symbol, from, to, bybitMaxTradePage)
+		res, err := bybitPages[bybitExecution](ctx, bx, "/v5/execution/list", params, limit)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get trades: %w", err)
+		}
+		for i := range res {
+			trades = append(trades, tradeFromBybit(&res[i]))
+		}
+		return len(res), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Timestamp.Before(trades[j].Timestamp)
+	})
+	return limitHistory(trades, start, limit), nil
+}
+
+// bybitKlineMessage is a kline stream push
+type bybitKlineMessage struct {
+	Topic string `json:"topic"`
+	Data  []struct {
+		Start  int64  `json:"start"`
+		Open   string `json:"open"`
+		High   string `json:"high"`
+		Low    string `json:"low"`
+		Close  string `json:"close"`
+		Volume string `json:"volume"`
+	} `json:"data"`
+}
+
+// StreamKlines streams real-time kline/candle data until ctx is done or the
+// exchange is closed. After a reconnect, bars missed while disconnected are
+// backfilled over REST first.
+func (bx *BybitExchange) StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error {
+	iv, err := lookupInterval("bybit", bybitIntervals, interval)
+	if err != nil {
+		return err
+	}
+	// Stop when the exchange is closed too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-bx.closedC:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	topic := "kline." + iv.Code + "." + SymbolStyleConcat.ToVenue(symbol)
+	subscribe, _ := json.Marshal(map[string]interface{}{"op": "subscribe", "args": []string{topic}})
+
+	stream := venueStream{
+		name:         "bybit " + topic,
+		endpoint:     bx.wsURL,
+		subscribe:    subscribe,
+		ping:         []byte(`{"op":"ping"}`),
+		pingInterval: bybitPingInterval,
+		tap:          bx.tap,
+	}
+
+	// The handler and backfill both run on the connection goroutine
+	var last time.Time // Open time of the newest bar delivered
+	deliver := func(candle *domain.Candle) {
+		if candle.OpenTime.Before(last) {
+			return
+		}
+		last = candle.OpenTime
+		callback(candle)
+	}
+
+	handler := func(message []byte) {
+		var msg bybitKlineMessage
+		if err := json.Unmarshal(message, &msg); err != nil || msg.Topic != topic {
+			return // Subscribe acks and pongs
+		}
+		for _, k := range msg.Data {
+			row := []string{strconv.FormatInt(k.Start, 10), k.Open, k.High, k.Low, k.Close, k.Volume}
+			if candle, err := candleFromRow(symbol, row, iv.Duration); err == nil {
+				deliver(candle)
+			}
+		}
+	}
+
+	backfill := func() {
+		if last.IsZero() {
+			return
+		}
+		since := last
+		candles, err := klinesSince(ctx, bx, symbol, interval, since)
+		if err != nil {
+			log.Printf("⚠️  Failed to backfill %s %s klines: %v", symbol, interval, err)
+			return
+		}
+		for _, candle := range candles {
+			deliver(candle)
+		}
+		log.Printf("Backfilled %d %s %s klines since %s", len(candles), symbol, interval, since.Format(time.RFC3339))
+	}
+
+	return stream.run(ctx, handler, backfill)
+}
+
+// Close stops streams and rejects further orders
+func (bx *BybitExchange) Close() error {
+	bx.mu.Lock()
+	defer bx.mu.Unlock()
+	if !bx.closed {
+		bx.closed = true
+		close(bx.closedC)
+	}
+	return nil
+}
+
+// isUnknownOrder reports whether err means Bybit has no such order
+func (bx *BybitExchange) isUnknownOrder(err error) bool {
+	var apiErr *VenueAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == strconv.Itoa(bybitUnknownOrder) || apiErr.Code == strconv.Itoa(bybitSpotUnknownOrder)
+	}
+	return errors.Is(err, ErrOrderNotFound)
+}
+
+// bybitOrderParams validates a reference and returns its query parameters
+func bybitOrderParams(ref domain.OrderRef) (map[string]string, error) {
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+	params := map[string]string{
+		"category": bybitCategory,
+		"symbol":   SymbolStyleConcat.ToVenue(ref.Symbol),
+	}
+	if ref.OrderID != "" {
+		params["orderId"] = ref.OrderID
+	} else {
+		params["orderLinkId"] = ref.ClientOrderID
+	}
+	return params, nil
+}
+
+// bybitRangeParams returns history query parameters for a symbol and optional time range
+func bybitRangeParams(symbol string, from, to time.Time, pageLimit int) map[string]string {
+	params := map[string]string{
+		"category": bybitCategory,
+		"symbol":   SymbolStyleConcat.ToVenue(symbol),
+		"limit":    strconv.Itoa(pageLimit),
+	}
+	if !from.IsZero() {
+		params["startTime"] = strconv.FormatInt(from.UnixMilli(), 10)
+		params["endTime"] = strconv.FormatInt(to.UnixMilli(), 10)
+	}
+	return params
+}
+
+// bybitSide maps our order side to Bybit's
+func bybitSide(side domain.OrderSide) string {
+	if side == domain.OrderSideSell {
+		return "Sell"
+	}
+	return "Buy"
+}
+
+// orderStatusFromBybit maps a Bybit order status to ours
+func orderStatusFromBybit(status string) domain.OrderStatus {
+	switch status {
+	case "PartiallyFilled":
+		return domain.OrderStatusPartiallyFilled
+	case "Filled":
+		return domain.OrderStatusFilled
+	case "Cancelled", "PartiallyFilledCanceled", "Deactivated":
+		return domain.OrderStatusCancelled
+	case "Rejected":
+		return domain.OrderStatusRejected
+	default: // New, Created, Untriggered, Triggered
+		return domain.OrderStatusNew
+	}
+}
+
+// orderFromBybit converts a queried Bybit order
+func orderFromBybit(o *bybitOrder) *domain.Order {
+	order := &domain.Order{
+		ID:            o.OrderID,
+		ClientOrderID: o.OrderLinkID,
+		Symbol:        SymbolStyleConcat.FromVenue(o.Symbol),
+		Side:          domain.OrderSide(strings.ToUpper(o.Side)),
+		Type:          domain.OrderType(strings.ToUpper(o.OrderType)),
+		Quantity:      parseFloat(o.Qty),
+		Price:         parseFloat(o.Price),
+		Status:        orderStatusFromBybit(o.OrderStatus),
+		FilledQty:     parseFloat(o.CumExecQty),
+		AvgPrice:      parseFloat(o.AvgPrice),
+		CreatedAt:     parseMillis(o.CreatedTime),
+		UpdatedAt:     parseMillis(o.UpdatedTime),
+	}
+	if order.Type == domain.OrderTypeMarket {
+		order.Price = 0
+	}
+	// avgPrice is empty on some spot orders; the filled notional gives it too
+	if value := parseFloat(o.CumExecValue); order.AvgPrice == 0 && order.FilledQty > 0 && value > 0 {
+		order.AvgPrice = value / order.FilledQty
+	}
+	if order.Status == domain.OrderStatusFilled {
+		executedAt := order.UpdatedAt
+		order.ExecutedAt = &executedAt
+	}
+	return order
+}
+
+// tradeFromBybit converts an account execution
+func tradeFromBybit(e *bybitExecution) *domain.Trade {
+	return &domain.Trade{
+		ID:        e.ExecID,
+		OrderID:   e.OrderID,
+		Symbol:    SymbolStyleConcat.FromVenue(e.Symbol),
+		Side:      domain.OrderSide(strings.ToUpper(e.Side)),
+		Price:     parseFloat(e.ExecPrice),
+		Quantity:  parseFloat(e.ExecQty),
+		Fee:       parseFloat(e.ExecFee),
+		FeeAsset:  e.FeeCurrency,
+		IsMaker:   e.IsMaker,
+		Timestamp: parseMillis(e.ExecTime),
+	}
+}
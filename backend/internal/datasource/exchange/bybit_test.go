@@ -0,0 +1,283 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// restFixture builds a fixture answering one REST request with 200 and
+// response. params are the query; a non-nil body is sent as JSON.
+func restFixture(method, path string, params map[string]string, body interface{}, response string) Fixture {
+	f := Fixture{Method: method, Path: path, Query: encodeQuery(params), Status: http.StatusOK, Response: response}
+	if body != nil {
+		data, _ := json.Marshal(body)
+		f.Body = string(data)
+	}
+	return f
+}
+
+func millis(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func newFixtureBybit(t *testing.T, fixtures ...Fixture) *BybitExchange {
+	t.Helper()
+	bx, err := NewBybitExchange(VenueConfig{
+		APIKey:     "key",
+		SecretKey:  "secret",
+		HTTPClient: NewFixtureReplayer(fixtures).Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewBybitExchange: %v", err)
+	}
+	t.Cleanup(func() { bx.Close() })
+	return bx
+}
+
+// bybitResult wraps a result in the v5 response envelope
+func bybitResult(result string) string {
+	return `{"retCode":0,"retMsg":"OK","result":` + result + `}`
+}
+
+var bybitInstrumentFixture = restFixture(http.MethodGet, "/v5/market/instruments-info",
+	map[string]string{"category": "spot", "symbol": "BTCUSDT"}, nil,
+	bybitResult(`{"category":"spot","list":[{"symbol":"BTCUSDT","baseCoin":"BTC","quoteCoin":"USDT","status":"Trading",
+		"lotSizeFilter":{"basePrecision":"0.001","minOrderQty":"0.001","maxOrderQty":"100","minOrderAmt":"5"},
+		"priceFilter":{"tickSize":"0.1"}}]}`))
+
+// bybitOrderFixture answers an order query from path with orders
+func bybitOrderFixture(path string, ref map[string]string, orders string) Fixture {
+	params := map[string]string{"category": "spot", "symbol": "BTCUSDT"}
+	for key, value := range ref {
+		params[key] = value
+	}
+	return restFixture(http.MethodGet, path, params, nil, bybitResult(`{"list":[`+orders+`],"nextPageCursor":""}`))
+}
+
+const bybitOpenLimit = `{"orderId":"1001","orderLinkId":"cq-test-limit","symbol":"BTCUSDT","side":"Buy","orderType":"Limit",
+	"price":"99.5","qty":"0.123","avgPrice":"","cumExecQty":"0","cumExecValue":"0","orderStatus":"New",
+	"createdTime":"1704067200000","updatedTime":"1704067200000"}`
+
+func TestBybitSymbolInfo(t *testing.T) {
+	bx := newFixtureBybit(t,
+		bybitInstrumentFixture,
+		restFixture(http.MethodGet, "/v5/market/instruments-info",
+			map[string]string{"category": "spot", "symbol": "NEWUSDT"}, nil,
+			bybitResult(`{"list":[{"symbol":"NEWUSDT","baseCoin":"NEW","quoteCoin":"USDT","status":"PreLaunch"}]}`)),
+		restFixture(http.MethodGet, "/v5/market/instruments-info",
+			map[string]string{"category": "spot", "symbol": "NONEUSDT"}, nil,
+			bybitResult(`{"list":[]}`)),
+	)
+	ctx := context.Background()
+
+	info, err := bx.GetSymbolInfo(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("GetSymbolInfo: %v", err)
+	}
+	if info.Status != domain.SymbolStatusTrading || info.BaseAsset != "BTC" || info.QuoteAsset != "USDT" {
+		t.Errorf("info = %s %s/%s, want TRADING BTC/USDT", info.Status, info.BaseAsset, info.QuoteAsset)
+	}
+	if info.TickSize != 0.1 || info.StepSize != 0.001 || info.MinQty != 0.001 || info.MaxQty != 100 || info.MinNotional != 5 {
+		t.Errorf("rules = tick %v step %v qty %v-%v notional %v, want tick 0.1 step 0.001 qty 0.001-100 notional 5",
+			info.TickSize, info.StepSize, info.MinQty, info.MaxQty, info.MinNotional)
+	}
+
+	info, err = bx.GetSymbolInfo(ctx, "NEWUSDT")
+	if err != nil {
+		t.Fatalf("GetSymbolInfo: %v", err)
+	}
+	if info.Status != "PRELAUNCH" || info.IsTradable() {
+		t.Errorf("status = %s, want untradable PRELAUNCH", info.Status)
+	}
+
+	if _, err := bx.GetSymbolInfo(ctx, "NONEUSDT"); err == nil || !strings.Contains(err.Error(), "unknown symbol") {
+		t.Errorf("GetSymbolInfo(NONEUSDT) error = %v, want unknown symbol", err)
+	}
+}
+
+func TestBybitKlines(t *testing.T) {
+	// Newest first, with a bar before the requested range
+	bx := newFixtureBybit(t, restFixture(http.MethodGet, "/v5/market/kline", map[string]string{
+		"category": "spot",
+		"symbol":   "BTCUSDT",
+		"interval": "1",
+		"limit":    "3",
+		"start":    millis(mockStart),
+		"end":      millis(mockStart.Add(2 * time.Minute)),
+	}, nil, bybitResult(`{"list":[
+		["`+millis(mockStart.Add(2*time.Minute))+`","102","103","101","102.5","7","700"],
+		["`+millis(mockStart.Add(time.Minute))+`","101","102","100","102","6","600"],
+		["`+millis(mockStart)+`","100","101","99","101","5","500"],
+		["`+millis(mockStart.Add(-time.Minute))+`","99","100","98","100","4","400"]]}`)))
+	ctx := context.Background()
+
+	candles, err := bx.GetKlines(ctx, "BTCUSDT", "1m", mockStart, time.Time{}, 3)
+	if err != nil {
+		t.Fatalf("GetKlines: %v", err)
+	}
+	if len(candles) != 3 {
+		t.Fatalf("got %d candles, want 3", len(candles))
+	}
+	for i, c := range candles {
+		open := mockStart.Add(time.Duration(i) * time.Minute)
+		if !c.OpenTime.Equal(open) || !c.CloseTime.Equal(open.Add(time.Minute-time.Millisecond)) {
+			t.Errorf("candle %d = %s-%s, want %s-%s", i, c.OpenTime, c.CloseTime, open, open.Add(time.Minute-time.Millisecond))
+		}
+	}
+	if last := candles[2]; last.Close != 102.5 || last.Volume != 7 {
+		t.Errorf("last candle close %v volume %v, want 102.5 and 7", last.Close, last.Volume)
+	}
+
+	if _, err := bx.GetKlines(ctx, "BTCUSDT", "7m", mockStart, time.Time{}, 3); err == nil {
+		t.Error("GetKlines(7m) succeeded, want unsupported interval error")
+	}
+}
+
+func TestBybitPlaceOrder(t *testing.T) {
+	byLink := map[string]string{"orderLinkId": "cq-test-limit"}
+	bx := newFixtureBybit(t,
+		bybitInstrumentFixture,
+		// The duplicate check finds nothing the first time and the order on resubmit
+		bybitOrderFixture("/v5/order/realtime", byLink, ""),
+		bybitOrderFixture("/v5/order/realtime", byLink, bybitOpenLimit),
+		bybitOrderFixture("/v5/order/history", byLink, ""),
+		restFixture(http.MethodPost, "/v5/order/create", nil, map[string]string{
+			"category":    "spot",
+			"symbol":      "BTCUSDT",
+			"side":        "Buy",
+			"orderType":   "Limit",
+			"qty":         "0.123",
+			"price":       "99.5",
+			"timeInForce": "GTC",
+			"orderLinkId": "cq-test-limit",
+		}, bybitResult(`{"orderId":"1001","orderLinkId":"cq-test-limit"}`)),
+		bybitOrderFixture("/v5/order/realtime", map[string]string{"orderId": "1001"}, bybitOpenLimit),
+	)
+	bx.SetSymbolRules(NewVenueRules(bx))
+	ctx := context.Background()
+
+	// Rounded to the base precision and tick size before it is sent
+	placed, err := bx.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-limit",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeLimit,
+		Quantity:      0.1234,
+		Price:         99.504,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.ID != "1001" || placed.Status != domain.OrderStatusNew || placed.Quantity != 0.123 || placed.Price != 99.5 {
+		t.Errorf("placed = #%s %s %v @ %v, want #1001 NEW 0.123 @ 99.5", placed.ID, placed.Status, placed.Quantity, placed.Price)
+	}
+	if placed.Side != domain.OrderSideBuy || placed.Type != domain.OrderTypeLimit || !placed.CreatedAt.Equal(mockStart) {
+		t.Errorf("placed = %s %s at %s, want BUY LIMIT at %s", placed.Side, placed.Type, placed.CreatedAt, mockStart)
+	}
+
+	// A retried submit returns the order Bybit already has
+	again, err := bx.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-limit",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeLimit,
+		Quantity:      0.1234,
+		Price:         99.504,
+	})
+	if err != nil || again.ID != "1001" {
+		t.Errorf("resubmit = %v, %v, want the existing order 1001", again, err)
+	}
+}
+
+func TestBybitRejectsOrderBelowMinimum(t *testing.T) {
+	bx := newFixtureBybit(t,
+		bybitInstrumentFixture,
+		bybitOrderFixture("/v5/order/realtime", map[string]string{"orderLinkId": "cq-test-small"}, ""),
+		bybitOrderFixture("/v5/order/history", map[string]string{"orderLinkId": "cq-test-small"}, ""),
+	)
+	bx.SetSymbolRules(NewVenueRules(bx))
+
+	// No create fixture: the order must not reach the venue
+	small, err := bx.PlaceOrder(context.Background(), &domain.Order{
+		ClientOrderID: "cq-test-small",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeLimit,
+		Quantity:      0.01,
+		Price:         99.5,
+	})
+	if err == nil || !strings.Contains(err.Error(), "trading rules") || small.Status != domain.OrderStatusRejected {
+		t.Errorf("small order = %v, %v, want a trading rules rejection", small, err)
+	}
+}
+
+func TestBybitGetOrder(t *testing.T) {
+	filled := `{"orderId":"1002","orderLinkId":"cq-test-market","symbol":"BTCUSDT","side":"Sell","orderType":"Market",
+		"price":"0","qty":"0.2","avgPrice":"","cumExecQty":"0.2","cumExecValue":"20.1","orderStatus":"Filled",
+		"createdTime":"1704067200000","updatedTime":"1704067201000"}`
+	bx := newFixtureBybit(t,
+		// Closed orders fall back to the history endpoint
+		bybitOrderFixture("/v5/order/realtime", map[string]string{"orderId": "1002"}, ""),
+		bybitOrderFixture("/v5/order/history", map[string]string{"orderId": "1002"}, filled),
+		bybitOrderFixture("/v5/order/realtime", map[string]string{"orderId": "404"}, ""),
+		bybitOrderFixture("/v5/order/history", map[string]string{"orderId": "404"}, ""),
+		restFixture(http.MethodGet, "/v5/order/realtime", map[string]string{"category": "spot", "symbol": "BTCUSDT", "orderId": "500"}, nil,
+			`{"retCode":170213,"retMsg":"Order does not exist.","result":{}}`),
+	)
+	ctx := context.Background()
+
+	order, err := bx.GetOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT", OrderID: "1002"})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	// avgPrice is empty, so it comes from the filled value
+	if order.Status != domain.OrderStatusFilled || order.Side != domain.OrderSideSell || order.Price != 0 || !approx(order.AvgPrice, 100.5) {
+		t.Errorf("order = %s %s price %v avg %v, want FILLED SELL price 0 avg 100.5", order.Status, order.Side, order.Price, order.AvgPrice)
+	}
+	if order.ExecutedAt == nil || !order.ExecutedAt.Equal(mockStart.Add(time.Second)) {
+		t.Errorf("executed at %v, want %s", order.ExecutedAt, mockStart.Add(time.Second))
+	}
+
+	_, err = bx.GetOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT", OrderID: "404"})
+	if !errors.Is(err, ErrOrderNotFound) || !bx.isUnknownOrder(err) {
+		t.Errorf("GetOrder(404) error = %v, want ErrOrderNotFound", err)
+	}
+
+	_, err = bx.GetOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT", OrderID: "500"})
+	var apiErr *VenueAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "170213" || !bx.isUnknownOrder(err) {
+		t.Errorf("GetOrder(500) error = %v, want unknown order API error 170213", err)
+	}
+
+	if _, err := bx.GetOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT"}); err == nil {
+		t.Error("GetOrder without an ID succeeded, want a reference error")
+	}
+}
+
+func TestBybitCancelOrder(t *testing.T) {
+	cancelled := strings.Replace(bybitOpenLimit, `"orderStatus":"New"`, `"orderStatus":"Cancelled"`, 1)
+	bx := newFixtureBybit(t,
+		restFixture(http.MethodPost, "/v5/order/cancel", nil, map[string]string{
+			"category":    "spot",
+			"symbol":      "BTCUSDT",
+			"orderLinkId": "cq-test-limit",
+		}, bybitResult(`{"orderId":"1001","orderLinkId":"cq-test-limit"}`)),
+		bybitOrderFixture("/v5/order/realtime", map[string]string{"orderId": "1001"}, cancelled),
+	)
+
+	order, err := bx.CancelOrder(context.Background(), domain.OrderRef{Symbol: "BTCUSDT", ClientOrderID: "cq-test-limit"})
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if order.ID != "1001" || order.Status != domain.OrderStatusCancelled {
+		t.Errorf("cancelled = #%s %s, want #1001 CANCELLED", order.ID, order.Status)
+	}
+}
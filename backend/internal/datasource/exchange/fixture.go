@@ -0,0 +1,233 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// volatileParams are query parameters that change on every request and are
+// ignored when matching fixtures
+var volatileParams = map[string]bool{"timestamp": true, "signature": true, "recvWindow": true}
+
+// Fixture is one recorded REST request and its response
+type Fixture struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Query    string `json:"query,omitempty"` // Sorted, without volatile parameters
+	Body     string `json:"body,omitempty"`
+	Status   int    `json:"status"`
+	Response string `json:"response"`
+}
+
+// key identifies the request a fixture answers
+func (f Fixture) key() string {
+	return f.Method + " " + f.Path + "?" + f.Query + " " + f.Body
+}
+
+// fixtureRequest captures the matching fields of a request, restoring its body
+func fixtureRequest(req *http.Request) (Fixture, error) {
+	f := Fixture{Method: req.Method, Path: req.URL.Path, Query: canonicalQuery(req.URL.Query())}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return f, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		f.Body = string(body)
+	}
+	return f, nil
+}
+
+// canonicalQuery encodes a query sorted by key without volatile parameters
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if !volatileParams[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// FixtureRecorder is an http.RoundTripper that records every request it
+// forwards, e.g. to capture fixtures from a live venue or testnet
+type FixtureRecorder struct {
+	next     http.RoundTripper
+	mu       sync.Mutex
+	fixtures []Fixture
+}
+
+// NewFixtureRecorder records requests sent through next (http.DefaultTransport if nil)
+func NewFixtureRecorder(next http.RoundTripper) *FixtureRecorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &FixtureRecorder{next: next}
+}
+
+// RoundTrip forwards the request and records it with its response
+func (r *FixtureRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	f, err := fixtureRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	f.Status = res.StatusCode
+	f.Response = string(body)
+	r.mu.Lock()
+	r.fixtures = append(r.fixtures, f)
+	r.mu.Unlock()
+	return res, nil
+}
+
+// Client returns an HTTP client that records through r
+func (r *FixtureRecorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Fixtures returns the requests recorded so far
+func (r *FixtureRecorder) Fixtures() []Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Fixture(nil), r.fixtures...)
+}
+
+// Save writes the recorded fixtures as JSON
+func (r *FixtureRecorder) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Fixtures())
+}
+
+// FixtureReplayer is an http.RoundTripper that answers requests from recorded
+// fixtures without touching the network. Fixtures for the same request are
+// replayed in recorded order; the last one keeps answering once all were used.
+type FixtureReplayer struct {
+	mu       sync.Mutex
+	fixtures map[string][]Fixture
+	served   map[string]int
+}
+
+// NewFixtureReplayer creates a replayer answering from fixtures
+func NewFixtureReplayer(fixtures []Fixture) *FixtureReplayer {
+	r := &FixtureReplayer{
+		fixtures: make(map[string][]Fixture),
+		served:   make(map[string]int),
+	}
+	for _, f := range fixtures {
+		r.fixtures[f.key()] = append(r.fixtures[f.key()], f)
+	}
+	return r
+}
+
+// LoadFixtures reads fixtures saved by FixtureRecorder.Save
+func LoadFixtures(rd io.Reader) (*FixtureReplayer, error) {
+	var fixtures []Fixture
+	if err := json.NewDecoder(rd).Decode(&fixtures); err != nil {
+		return nil, fmt.Errorf("failed to decode fixtures: %w", err)
+	}
+	return NewFixtureReplayer(fixtures), nil
+}
+
+// RoundTrip answers the request from its fixture, failing if none was recorded
+func (r *FixtureReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	f, err := fixtureRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	key := f.key()
+
+	r.mu.Lock()
+	recorded := r.fixtures[key]
+	n := r.served[key]
+	r.served[key] = n + 1
+	r.mu.Unlock()
+
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("no fixture for %s %s?%s", f.Method, f.Path, f.Query)
+	}
+	match := recorded[min(n, len(recorded)-1)]
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", match.Status, http.StatusText(match.Status)),
+		StatusCode:    match.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(match.Response)),
+		ContentLength: int64(len(match.Response)),
+		Request:       req,
+	}, nil
+}
+
+// Client returns an HTTP client that replays through r
+func (r *FixtureReplayer) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// StreamFixtureHandler serves recorded stream messages: every connection gets
+// all messages once the client sends its subscribe request, then stays open
+// (answering pings with pong) until the client leaves. Serve it with
+// httptest.NewServer and point the connector's WsBaseURL at it; messages can
+// be recorded with VenueConfig.StreamTap.
+func StreamFixtureHandler(messages [][]byte, pong []byte) http.Handler {
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// The subscribe request comes first
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		for _, message := range messages {
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			if pong != nil {
+				if err := conn.WriteMessage(websocket.TextMessage, pong); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,826 @@
+package exchange
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// OKX v5 REST and candle stream base URLs. Demo trading uses the same REST
+// host with a header, but its own stream host.
+const (
+	okxMainURL     = "https://www.okx.com"
+	okxWsMainURL   = "wss://ws.okx.com:8443/ws/v5/business"
+	okxWsDemoURL   = "wss://wspap.okx.com:8443/ws/v5/business"
+	okxDemoHeader  = "x-simulated-trading"
+	okxTimeLayout  = "2006-01-02T15:04:05.000Z"
+	okxInstType    = "SPOT"
+	okxTradeMode   = "cash"
+	okxTargetBase  = "base_ccy" // Market buy size is in the base asset, like ours
+	okxStateLive   = "live"
+	okxUnknownCode = "51603"
+)
+
+// OKX API limits
+const (
+	okxMaxKlineLimit    = 100 // history-candles
+	okxMaxRecentCandles = 300 // candles
+	okxMaxPage          = 100
+	okxMaxCancelBatch   = 20
+	okxMaxClientOrderID = 32
+	okxRecentHistory    = 7 * 24 * time.Hour  // orders-history; older orders are in the archive
+	okxHistoryWindow    = 30 * 24 * time.Hour // Paging span for order and fill history
+	okxRequestSpacing   = 100 * time.Millisecond
+	okxPingInterval     = 25 * time.Second
+)
+
+// okxFees is the base tier spot fee schedule
+var okxFees = FeeSchedule{Maker: 0.0008, Taker: 0.001}
+
+// okxIntervals maps our interval names to OKX bars. Bars of 6h and longer use
+// the UTC-aligned variants so they open at the same times as Binance's.
+var okxIntervals = map[string]venueInterval{
+	"1m":  {"1m", time.Minute},
+	"3m":  {"3m", 3 * time.Minute},
+	"5m":  {"5m", 5 * time.Minute},
+	"15m": {"15m", 15 * time.Minute},
+	"30m": {"30m", 30 * time.Minute},
+	"1h":  {"1H", time.Hour},
+	"2h":  {"2H", 2 * time.Hour},
+	"4h":  {"4H", 4 * time.Hour},
+	"6h":  {"6Hutc", 6 * time.Hour},
+	"12h": {"12Hutc", 12 * time.Hour},
+	"1d":  {"1Dutc", 24 * time.Hour},
+	"1w":  {"1Wutc", 7 * 24 * time.Hour},
+}
+
+func init() {
+	RegisterVenue(Venue{
+		Name:    "okx",
+		Symbols: SymbolStyleDashed,
+		Fees:    okxFees,
+		New: func(cfg VenueConfig) (VenueExchange, error) {
+			return NewOKXExchange(cfg)
+		},
+	})
+}
+
+// OKXExchange implements Exchange for OKX spot (v5 API, cash trade mode)
+type OKXExchange struct {
+	rest       *restClient
+	apiKey     string
+	secretKey  string
+	passphrase string
+	demo       bool
+	wsURL      string
+	fees       FeeSchedule
+	rules      SymbolRules
+	tap        func([]byte)
+	mu         sync.RWMutex
+	closedC    chan struct{}
+	closed     bool
+}
+
+// NewOKXExchange creates a new OKX exchange client. API keys are only needed
+// for trading; Testnet selects demo trading.
+func NewOKXExchange(cfg VenueConfig) (*OKXExchange, error) {
+	baseURL, wsURL := okxMainURL, okxWsMainURL
+	if cfg.Testnet {
+		wsURL = okxWsDemoURL
+	}
+	if cfg.BaseURL != "" {
+		baseURL = cfg.BaseURL
+	}
+	if cfg.WsBaseURL != "" {
+		wsURL = cfg.WsBaseURL
+	}
+
+	return &OKXExchange{
+		rest:       newRESTClient("okx", baseURL, cfg.httpClient(), okxRequestSpacing),
+		apiKey:     cfg.APIKey,
+		secretKey:  cfg.SecretKey,
+		passphrase: cfg.Passphrase,
+		demo:       cfg.Testnet,
+		wsURL:      wsURL,
+		fees:       cfg.fees(okxFees),
+		tap:        cfg.StreamTap,
+		closedC:    make(chan struct{}),
+	}, nil
+}
+
+// Venue names the venue
+func (ox *OKXExchange) Venue() string {
+	return "okx"
+}
+
+// Fees returns the configured fee schedule
+func (ox *OKXExchange) Fees() FeeSchedule {
+	return ox.fees
+}
+
+// SetSymbolRules makes PlaceOrder round and validate orders before sending them
+func (ox *OKXExchange) SetSymbolRules(rules SymbolRules) {
+	ox.rules = rules
+}
+
+// MaxKlineLimit is the largest page /api/v5/market/history-candles returns
+func (ox *OKXExchange) MaxKlineLimit() int {
+	return okxMaxKlineLimit
+}
+
+// okxResponse is the envelope of every v5 response
+type okxResponse struct {
+	Code string          `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// okxResult is the per-item status of order placement and cancellation
+type okxResult struct {
+	OrdID   string `json:"ordId"`
+	ClOrdID string `json:"clOrdId"`
+	SCode   string `json:"sCode"`
+	SMsg    string `json:"sMsg"`
+}
+
+// request calls the API with query parameters and an optional JSON body.
+// Signed requests carry the OK-ACCESS-* authentication headers.
+func (ox *OKXExchange) request(ctx context.Context, method, path string, params map[string]string, body interface{}, signed bool, data interface{}) error {
+	query := encodeQuery(params)
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+
+	header := http.Header{}
+	if ox.demo {
+		header.Set(okxDemoHeader, "1")
+	}
+	if signed {
+		if ox.apiKey == "" || ox.secretKey == "" || ox.passphrase == "" {
+			return fmt.Errorf("okx API key, secret and passphrase are required")
+		}
+		requestPath := path
+		if query != "" {
+			requestPath += "?" + query
+		}
+		timestamp := time.Now().UTC().Format(okxTimeLayout)
+		mac := hmac.New(sha256.New, []byte(ox.secretKey))
+		mac.Write([]byte(timestamp + method + requestPath + string(payload)))
+
+		header.Set("OK-ACCESS-KEY", ox.apiKey)
+		header.Set("OK-ACCESS-SIGN", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+		header.Set("OK-ACCESS-PASSPHRASE", ox.passphrase)
+	}
+
+	status, raw, err := ox.rest.do(ctx, method, path, query, payload, header)
+	if err != nil {
+		return err
+	}
+
+	var res okxResponse
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return &VenueAPIError{Venue: "okx", Status: status, Message: truncate(string(raw), 200)}
+	}
+	if status != http.StatusOK || res.Code != "0" {
+		// Order endpoints put the reason in the item status
+		var items []okxResult
+		if json.Unmarshal(res.Data, &items) == nil && len(items) > 0 && items[0].SCode != "" && items[0].SCode != "0" {
+			return &VenueAPIError{Venue: "okx", Status: status, Code: items[0].SCode, Message: items[0].SMsg}
+		}
+		return &VenueAPIError{Venue: "okx", Status: status, Code: res.Code, Message: res.Msg}
+	}
+	if data != nil {
+		if err := json.Unmarshal(res.Data, data); err != nil {
+			return fmt.Errorf("failed to decode okx %s response: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// okxPages follows a list endpoint's "after" cursor until it is exhausted or
+// max items were read. cursor returns an item's pagination ID.
+func okxPages[T any](ctx context.Context, ox *OKXExchange, path string, params map[string]string, max int, cursor func(T) string) ([]T, error) {
+	items := make([]T, 0)
+	for {
+		var page []T
+		if err := ox.request(ctx, http.MethodGet, path, params, nil, true, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if len(page) < okxMaxPage || len(items) >= max {
+			return items, nil
+		}
+		params["after"] = cursor(page[len(page)-1])
+	}
+}
+
+// GetCurrentPrice returns the last traded price
+func (ox *OKXExchange) GetCurrentPrice(ctx context.Context, symbol string) (float64, error) {
+	var res []struct {
+		Last string `json:"last"`
+	}
+	err := ox.request(ctx, http.MethodGet, "/api/v5/market/ticker", map[string]string{
+		"instId": SymbolStyleDashed.ToVenue(symbol),
+	}, nil, false, &res)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get price: %w", err)
+	}
+	if len(res) == 0 {
+		return 0, fmt.Errorf("no price data for symbol: %s", symbol)
+	}
+
+	price, err := strconv.ParseFloat(res[0].Last, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse price: %w", err)
+	}
+	return price, nil
+}
+
+// okxInstrument is a spot instrument's trading rules
+type okxInstrument struct {
+	InstID   string `json:"instId"`
+	BaseCcy  string `json:"baseCcy"`
+	QuoteCcy string `json:"quoteCcy"`
+	TickSz   string `json:"tickSz"`
+	LotSz    string `json:"lotSz"`
+	MinSz    string `json:"minSz"`
+	MaxLmtSz string `json:"maxLmtSz"`
+	State    string `json:"state"`
+}
+
+// GetSymbolInfo returns a symbol's trading rules
+func (ox *OKXExchange) GetSymbolInfo(ctx context.Context, symbol string) (*domain.SymbolInfo, error) {
+	var res []okxInstrument
+	err := ox.request(ctx, http.MethodGet, "/api/v5/public/instruments", map[string]string{
+		"instType": okxInstType,
+		"instId":   SymbolStyleDashed.ToVenue(symbol),
+	}, nil, false, &res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instrument info: %w", err)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("unknown symbol: %s", symbol)
+	}
+
+	inst := res[0]
+	status := strings.ToUpper(inst.State)
+	if inst.State == okxStateLive {
+		status = domain.SymbolStatusTrading
+	}
+	return &domain.SymbolInfo{
+		Symbol:      symbol,
+		BaseAsset:   inst.BaseCcy,
+		QuoteAsset:  inst.QuoteCcy,
+		Status:      status,
+		TickSize:    parseFloat(inst.TickSz),
+		StepSize:    parseFloat(inst.LotSz),
+		MinQty:      parseFloat(inst.MinSz),
+		MaxQty:      parseFloat(inst.MaxLmtSz),
+		Permissions: make([]string, 0),
+		UpdatedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// GetCandles retrieves the most recent candles, oldest first
+func (ox *OKXExchange) GetCandles(ctx context.Context, symbol, interval string, limit int) ([]*domain.Candle, error) {
+	iv, err := lookupInterval("okx", okxIntervals, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	err = ox.request(ctx, http.MethodGet, "/api/v5/market/candles", map[string]string{
+		"instId": SymbolStyleDashed.ToVenue(symbol),
+		"bar":    iv.Code,
+		"limit":  strconv.Itoa(min(max(limit, 1), okxMaxRecentCandles)),
+	}, nil, false, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candles: %w", err)
+	}
+	return candlesFromOKX(symbol, rows, iv.Duration, time.Time{}, time.Time{})
+}
+
+// GetKlines returns up to limit candles opening in [start, end], oldest first.
+// A zero start returns the most recent candles.
+func (ox *OKXExchange) GetKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	if start.IsZero() {
+		return ox.GetCandles(ctx, symbol, interval, limit)
+	}
+	iv, err := lookupInterval("okx", okxIntervals, interval)
+	if err != nil {
+		return nil, err
+	}
+	limit = min(max(limit, 1), okxMaxKlineLimit)
+	end = klineWindow(start, end, iv.Duration, limit)
+
+	// after and before are exclusive: bars older than after and newer than before
+	var rows [][]string
+	err = ox.request(ctx, http.MethodGet, "/api/v5/market/history-candles", map[string]string{
+		"instId": SymbolStyleDashed.ToVenue(symbol),
+		"bar":    iv.Code,
+		"after":  strconv.FormatInt(end.UnixMilli()+1, 10),
+		"before": strconv.FormatInt(start.UnixMilli()-1, 10),
+		"limit":  strconv.Itoa(limit),
+	}, nil, false, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candles: %w", err)
+	}
+	return candlesFromOKX(symbol, rows, iv.Duration, start, end)
+}
+
+// okxOrder is an order as returned by the order query and list endpoints
+type okxOrder struct {
+	InstID    string `json:"instId"`
+	OrdID     string `json:"ordId"`
+	ClOrdID   string `json:"clOrdId"`
+	Px        string `json:"px"`
+	Sz        string `json:"sz"`
+	Side      string `json:"side"`
+	OrdType   string `json:"ordType"`
+	State     string `json:"state"`
+	AccFillSz string `json:"accFillSz"`
+	AvgPx     string `json:"avgPx"`
+	CTime     string `json:"cTime"`
+	UTime     string `json:"uTime"`
+}
+
+// PlaceOrder submits a new order.
+// An order that already carries a client order ID is looked up first, so a
+// retried submit returns the order OKX already has instead of trading twice.
+// OKX client order IDs are alphanumeric, so ours are stored without dashes.
+func (ox *OKXExchange) PlaceOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	ox.mu.RLock()
+	if ox.closed {
+		ox.mu.RUnlock()
+		return nil, fmt.Errorf("exchange is closed")
+	}
+	ox.mu.RUnlock()
+
+	if err := checkOrderType(order); err != nil {
+		return order, err
+	}
+
+	if order.ClientOrderID != "" {
+		existing, err := ox.GetOrder(ctx, domain.OrderRef{Symbol: order.Symbol, ClientOrderID: order.ClientOrderID})
+		if err == nil {
+			return existing, nil
+		}
+		if !ox.isUnknownOrder(err) {
+			return nil, fmt.Errorf("failed to check for duplicate order: %w", err)
+		}
+	}
+
+	// Round to tick and lot size so OKX doesn't reject the order
+	if err := applySymbolRules(ctx, ox.rules, order, ox.GetCurrentPrice); err != nil {
+		return order, err
+	}
+
+	assignClientOrderID(order)
+	order.ClientOrderID = okxClientOrderID(order.ClientOrderID)
+	body := map[string]string{
+		"instId":  SymbolStyleDashed.ToVenue(order.Symbol),
+		"tdMode":  okxTradeMode,
+		"side":    strings.ToLower(string(order.Side)),
+		"ordType": "market",
+		"sz":      formatFloat(order.Quantity),
+		"clOrdId": order.ClientOrderID,
+	}
+	if order.Type == domain.OrderTypeLimit {
+		body["ordType"] = "limit"
+		body["px"] = formatFloat(order.Price)
+	} else {
+		body["tgtCcy"] = okxTargetBase
+	}
+
+	var res []okxResult
+	if err := ox.request(ctx, http.MethodPost, "/api/v5/trade/order", nil, body, true, &res); err != nil {
+		// The order may have reached OKX even though the response was lost
+		var apiErr *VenueAPIError
+		if !errors.As(err, &apiErr) {
+			if existing, getErr := ox.GetOrder(ctx, order.Ref()); getErr == nil {
+				return existing, nil
+			}
+		}
+		order.Status = domain.OrderStatusRejected
+		return order, fmt.Errorf("failed to place order: %w", err)
+	}
+	if len(res) == 0 {
+		order.Status = domain.OrderStatusRejected
+		return order, fmt.Errorf("failed to place order: empty response")
+	}
+
+	// Placement only acknowledges the order; fetch its state for fills
+	order.ID = res[0].OrdID
+	placed, err := ox.GetOrder(ctx, order.Ref())
+	if err != nil {
+		log.Printf("⚠️  OKX order %s placed but not yet queryable: %v", order.Ref(), err)
+		order.Status = domain.OrderStatusNew
+		order.CreatedAt = time.Now().UTC()
+		order.UpdatedAt = order.CreatedAt
+		return order, nil
+	}
+	return placed, nil
+}
+
+// GetOrder retrieves an order by exchange ID or client order ID, open or closed
+func (ox *OKXExchange) GetOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	params, err := okxOrderParams(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []okxOrder
+	if err := ox.request(ctx, http.MethodGet, "/api/v5/trade/order", params, nil, true, &res); err != nil {
+		return nil, fmt.Errorf("failed to get order %s: %w", ref, err)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("failed to get order %s: %w", ref, ErrOrderNotFound)
+	}
+	return orderFromOKX(&res[0]), nil
+}
+
+// CancelOrder cancels an open order by exchange ID or client order ID
+func (ox *OKXExchange) CancelOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	params, err := okxOrderParams(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []okxResult
+	if err := ox.request(ctx, http.MethodPost, "/api/v5/trade/cancel-order", nil, params, true, &res); err != nil {
+		return nil, fmt.Errorf("failed to cancel order %s: %w", ref, err)
+	}
+	return ox.GetOrder(ctx, ref)
+}
+
+// GetOpenOrders lists open orders for a symbol (all symbols if empty)
+func (ox *OKXExchange) GetOpenOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	params := map[string]string{
+		"instType": okxInstType,
+		"limit":    strconv.Itoa(okxMaxPage),
+	}
+	if symbol != "" {
+		params["instId"] = SymbolStyleDashed.ToVenue(symbol)
+	}
+
+	res, err := okxPages(ctx, ox, "/api/v5/trade/orders-pending", params, defaultHistoryLimit, func(o okxOrder) string { return o.OrdID })
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders: %w", err)
+	}
+
+	orders := make([]*domain.Order, 0, len(res))
+	for i := range res {
+		orders = append(orders, orderFromOKX(&res[i]))
+	}
+	return orders, nil
+}
+
+// CancelAllOrders cancels every open order for a symbol, in batches of 20
+func (ox *OKXExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	open, err := ox.GetOpenOrders(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled := make([]string, 0, len(open))
+	for i := 0; i < len(open); i += okxMaxCancelBatch {
+		batch := open[i:min(i+okxMaxCancelBatch, len(open))]
+		body := make([]map[string]string, 0, len(batch))
+		for _, order := range batch {
+			body = append(body, map[string]string{"instId": SymbolStyleDashed.ToVenue(symbol), "ordId": order.ID})
+		}
+
+		var res []okxResult
+		if err := ox.request(ctx, http.MethodPost, "/api/v5/trade/cancel-batch-orders", nil, body, true, &res); err != nil {
+			return nil, fmt.Errorf("failed to cancel open orders: %w", err)
+		}
+		for _, r := range res {
+			if r.SCode == "0" {
+				cancelled = append(cancelled, r.OrdID)
+			}
+		}
+	}
+	return markCancelled(open, len(cancelled), func(i int) string { return cancelled[i] }), nil
+}
+
+// GetOrderHistory lists a symbol's orders of any status, querying 30 day
+// windows from start to end. Orders older than 7 days come from the archive,
+// which keeps 3 months.
+func (ox *OKXExchange) GetOrderHistory(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit = historyLimit(limit)
+
+	orders := make([]*domain.Order, 0)
+	err := historyWindows(start, end, okxHistoryWindow, limit, func(from, to time.Time) (int, error) {
+		path := "/api/v5/trade/orders-history"
+		if !from.IsZero() && time.Since(from) > okxRecentHistory {
+			path = "/api/v5/trade/orders-history-archive"
+		}
+		params := okxRangeParams(symbol, from, to)
+		res, err := okxPages(ctx, ox, path, params, limit, func(o okxOrder) string { return o.OrdID })
+		if err != nil {
+			return 0, fmt.Errorf("failed to get order history: %w", err)
+		}
+		for i := range res {
+			orders = append(orders, orderFromOKX(&res[i]))
+		}
+		return len(res), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+	return limitHistory(orders, start, limit), nil
+}
+
+// okxFill is an account trade as returned by /api/v5/trade/fills-history
+type okxFill struct {
+	InstID   string `json:"instId"`
+	TradeID  string `json:"tradeId"`
+	OrdID    string `json:"ordId"`
+	BillID   string `json:"billId"`
+	FillPx   string `json:"fillPx"`
+	FillSz   string `json:"fillSz"`
+	Side     string `json:"side"`
+	Fee      string `json:"fee"` // Negative when charged
+	FeeCcy   string `json:"feeCcy"`
+	ExecType string `json:"execType"` // T taker, M maker
+	Ts       string `json:"ts"`
+}
+
+// GetMyTrades lists a symbol's fills (kept 3 months), querying 30 day windows from start to end
+func (ox *OKXExchange) GetMyTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit = historyLimit(limit)
+
+	trades := make([]*domain.Trade, 0)
+	err := historyWindows(start, end, okxHistoryWindow, limit, func(from, to time.Time) (int, error) {
+		params := okxRangeParams(symbol, from, to)
+		res, err := okxPages(ctx, ox, "/api/v5/trade/fills-history", params, limit, func(f okxFill) string { return f.BillID })
+		if err != nil {
+			return 0, fmt.Errorf("failed to get trades: %w", err)
+		}
+		for i := range res {
+			trades = append(trades, tradeFromOKX(&res[i]))
+		}
+		return len(res), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Timestamp.Before(trades[j].Timestamp)
+	})
+	return limitHistory(trades, start, limit), nil
+}
+
+// okxCandleMessage is a candle stream push
+type okxCandleMessage struct {
+	Arg struct {
+		Channel string `json:"channel"`
+		InstID  string `json:"instId"`
+	} `json:"arg"`
+	Event string     `json:"event"`
+	Data  [][]string `json:"data"`
+}
+
+// StreamKlines streams real-time kline/candle data until ctx is done or the
+// exchange is closed. After a reconnect, bars missed while disconnected are
+// backfilled over REST first.
+func (ox *OKXExchange) StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error {
+	iv, err := lookupInterval("okx", okxIntervals, interval)
+	if err != nil {
+		return err
+	}
+
+	// Stop when the exchange is closed too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ox.closedC:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	channel := "candle" + iv.Code
+	instID := SymbolStyleDashed.ToVenue(symbol)
+	subscribe, _ := json.Marshal(map[string]interface{}{
+		"op":   "subscribe",
+		"args": []map[string]string{{"channel": channel, "instId": instID}},
+	})
+
+	stream := venueStream{
+		name:         "okx " + channel + " " + instID,
+		endpoint:     ox.wsURL,
+		subscribe:    subscribe,
+		ping:         []byte("ping"),
+		pingInterval: okxPingInterval,
+		tap:          ox.tap,
+	}
+
+	// The handler and backfill both run on the connection goroutine
+	var last time.Time // Open time of the newest bar delivered
+	deliver := func(candle *domain.Candle) {
+		if candle.OpenTime.Before(last) {
+			return
+		}
+		last = candle.OpenTime
+		callback(candle)
+	}
+
+	handler := func(message []byte) {
+		var msg okxCandleMessage
+		if err := json.Unmarshal(message, &msg); err != nil || msg.Event != "" || msg.Arg.Channel != channel {
+			return // Pongs and subscribe acks
+		}
+		candles, err := candlesFromOKX(symbol, msg.Data, iv.Duration, time.Time{}, time.Time{})
+		if err != nil {
+			log.Printf("⚠️  %s kline stream error: %v", symbol, err)
+			return
+		}
+		for _, candle := range candles {
+			deliver(candle)
+		}
+	}
+
+	backfill := func() {
+		if last.IsZero() {
+			return
+		}
+		since := last
+		candles, err := klinesSince(ctx, ox, symbol, interval, since)
+		if err != nil {
+			log.Printf("⚠️  Failed to backfill %s %s klines: %v", symbol, interval, err)
+			return
+		}
+		for _, candle := range candles {
+			deliver(candle)
+		}
+		log.Printf("Backfilled %d %s %s klines since %s", len(candles), symbol, interval, since.Format(time.RFC3339))
+	}
+
+	return stream.run(ctx, handler, backfill)
+}
+
+// Close stops streams and rejects further orders
+func (ox *OKXExchange) Close() error {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+	if !ox.closed {
+		ox.closed = true
+		close(ox.closedC)
+	}
+	return nil
+}
+
+// isUnknownOrder reports whether err means OKX has no such order
+func (ox *OKXExchange) isUnknownOrder(err error) bool {
+	var apiErr *VenueAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == okxUnknownCode
+	}
+	return errors.Is(err, ErrOrderNotFound)
+}
+
+// okxClientOrderID converts a client order ID to OKX's form: letters and
+// digits only, at most 32 characters. IDs already in that form are unchanged.
+func okxClientOrderID(id string) string {
+	var b strings.Builder
+	for _, r := range id {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	clean := b.String()
+	if len(clean) > okxMaxClientOrderID {
+		clean = clean[:okxMaxClientOrderID]
+	}
+	return clean
+}
+
+// okxOrderParams validates a reference and returns its parameters
+func okxOrderParams(ref domain.OrderRef) (map[string]string, error) {
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+	params := map[string]string{"instId": SymbolStyleDashed.ToVenue(ref.Symbol)}
+	if ref.OrderID != "" {
+		params["ordId"] = ref.OrderID
+	} else {
+		params["clOrdId"] = okxClientOrderID(ref.ClientOrderID)
+	}
+	return params, nil
+}
+
+// okxRangeParams returns history query parameters for a symbol and optional time range
+func okxRangeParams(symbol string, from, to time.Time) map[string]string {
+	params := map[string]string{
+		"instType": okxInstType,
+		"instId":   SymbolStyleDashed.ToVenue(symbol),
+		"limit":    strconv.Itoa(okxMaxPage),
+	}
+	if !from.IsZero() {
+		params["begin"] = strconv.FormatInt(from.UnixMilli(), 10)
+		params["end"] = strconv.FormatInt(to.UnixMilli(), 10)
+	}
+	return params
+}
+
+// candlesFromOKX converts newest-first candle rows to candles, oldest first
+func candlesFromOKX(symbol string, rows [][]string, step time.Duration, start, end time.Time) ([]*domain.Candle, error) {
+	candles := make([]*domain.Candle, 0, len(rows))
+	for _, row := range rows {
+		candle, err := candleFromRow(symbol, row, step)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse candle: %w", err)
+		}
+		candles = append(candles, candle)
+	}
+	return sortCandles(candles, start, end), nil
+}
+
+// orderStatusFromOKX maps an OKX order state to ours
+func orderStatusFromOKX(state string) domain.OrderStatus {
+	switch state {
+	case "partially_filled":
+		return domain.OrderStatusPartiallyFilled
+	case "filled":
+		return domain.OrderStatusFilled
+	case "canceled", "mmp_canceled":
+		return domain.OrderStatusCancelled
+	default: // live
+		return domain.OrderStatusNew
+	}
+}
+
+// orderFromOKX converts a queried OKX order
+func orderFromOKX(o *okxOrder) *domain.Order {
+	order := &domain.Order{
+		ID:            o.OrdID,
+		ClientOrderID: o.ClOrdID,
+		Symbol:        SymbolStyleDashed.FromVenue(o.InstID),
+		Side:          domain.OrderSide(strings.ToUpper(o.Side)),
+		Type:          domain.OrderTypeLimit,
+		Quantity:      parseFloat(o.Sz),
+		Price:         parseFloat(o.Px),
+		Status:        orderStatusFromOKX(o.State),
+		FilledQty:     parseFloat(o.AccFillSz),
+		AvgPrice:      parseFloat(o.AvgPx),
+		CreatedAt:     parseMillis(o.CTime),
+		UpdatedAt:     parseMillis(o.UTime),
+	}
+	if o.OrdType == "market" {
+		order.Type = domain.OrderTypeMarket
+		order.Price = 0
+	}
+	if order.Status == domain.OrderStatusFilled {
+		executedAt := order.UpdatedAt
+		order.ExecutedAt = &executedAt
+	}
+	return order
+}
+
+// tradeFromOKX converts an account fill
+func tradeFromOKX(f *okxFill) *domain.Trade {
+	return &domain.Trade{
+		ID:        f.TradeID,
+		OrderID:   f.OrdID,
+		Symbol:    SymbolStyleDashed.FromVenue(f.InstID),
+		Side:      domain.OrderSide(strings.ToUpper(f.Side)),
+		Price:     parseFloat(f.FillPx),
+		Quantity:  parseFloat(f.FillSz),
+		Fee:       -parseFloat(f.Fee),
+		FeeAsset:  f.FeeCcy,
+		IsMaker:   f.ExecType == "M",
+		Timestamp: parseMillis(f.Ts),
+	}
+}
@@ -0,0 +1,263 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+func newFixtureOKX(t *testing.T, fixtures ...Fixture) *OKXExchange {
+	t.Helper()
+	ox, err := NewOKXExchange(VenueConfig{
+		APIKey:     "key",
+		SecretKey:  "secret",
+		Passphrase: "passphrase",
+		HTTPClient: NewFixtureReplayer(fixtures).Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewOKXExchange: %v", err)
+	}
+	t.Cleanup(func() { ox.Close() })
+	return ox
+}
+
+// okxData wraps data in the v5 response envelope
+func okxData(data string) string {
+	return `{"code":"0","msg":"","data":` + data + `}`
+}
+
+// okxUnknownOrderResponse is OKX's answer for an order it doesn't have
+const okxUnknownOrderResponse = `{"code":"51603","msg":"Order does not exist","data":[]}`
+
+var okxInstrumentFixture = restFixture(http.MethodGet, "/api/v5/public/instruments",
+	map[string]string{"instType": "SPOT", "instId": "BTC-USDT"}, nil,
+	okxData(`[{"instId":"BTC-USDT","baseCcy":"BTC","quoteCcy":"USDT","tickSz":"0.1","lotSz":"0.001","minSz":"0.01","maxLmtSz":"1000","state":"live"}]`))
+
+// okxOrderFixture answers an order query by ordId or clOrdId with response
+func okxOrderFixture(key, value, response string) Fixture {
+	return restFixture(http.MethodGet, "/api/v5/trade/order", map[string]string{"instId": "BTC-USDT", key: value}, nil, response)
+}
+
+const okxOpenLimit = `{"instId":"BTC-USDT","ordId":"2001","clOrdId":"cqtestlimit","px":"99.5","sz":"0.123","side":"buy",
+	"ordType":"limit","state":"live","accFillSz":"0","avgPx":"","cTime":"1704067200000","uTime":"1704067200000"}`
+
+func TestOKXSymbolInfo(t *testing.T) {
+	ox := newFixtureOKX(t,
+		okxInstrumentFixture,
+		restFixture(http.MethodGet, "/api/v5/public/instruments",
+			map[string]string{"instType": "SPOT", "instId": "OLD-USDT"}, nil,
+			okxData(`[{"instId":"OLD-USDT","baseCcy":"OLD","quoteCcy":"USDT","state":"suspend"}]`)),
+	)
+	ctx := context.Background()
+
+	info, err := ox.GetSymbolInfo(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("GetSymbolInfo: %v", err)
+	}
+	if info.Symbol != "BTCUSDT" || info.Status != domain.SymbolStatusTrading || info.BaseAsset != "BTC" || info.QuoteAsset != "USDT" {
+		t.Errorf("info = %s %s %s/%s, want BTCUSDT TRADING BTC/USDT", info.Symbol, info.Status, info.BaseAsset, info.QuoteAsset)
+	}
+	if info.TickSize != 0.1 || info.StepSize != 0.001 || info.MinQty != 0.01 || info.MaxQty != 1000 {
+		t.Errorf("rules = tick %v step %v qty %v-%v, want tick 0.1 step 0.001 qty 0.01-1000",
+			info.TickSize, info.StepSize, info.MinQty, info.MaxQty)
+	}
+
+	info, err = ox.GetSymbolInfo(ctx, "OLDUSDT")
+	if err != nil {
+		t.Fatalf("GetSymbolInfo: %v", err)
+	}
+	if info.Status != "SUSPEND" || info.IsTradable() {
+		t.Errorf("status = %s, want untradable SUSPEND", info.Status)
+	}
+}
+
+func TestOKXKlines(t *testing.T) {
+	rows := okxData(`[
+		["` + millis(mockStart.Add(2*time.Minute)) + `","102","103","101","102.5","7","700","700","1"],
+		["` + millis(mockStart.Add(time.Minute)) + `","101","102","100","102","6","600","600","1"],
+		["` + millis(mockStart) + `","100","101","99","101","5","500","500","1"]]`)
+	// after and before are exclusive, so the range is widened by a millisecond
+	ox := newFixtureOKX(t,
+		restFixture(http.MethodGet, "/api/v5/market/history-candles", map[string]string{
+			"instId": "BTC-USDT",
+			"bar":    "1m",
+			"after":  millis(mockStart.Add(2*time.Minute + time.Millisecond)),
+			"before": millis(mockStart.Add(-time.Millisecond)),
+			"limit":  "3",
+		}, nil, rows),
+		restFixture(http.MethodGet, "/api/v5/market/candles", map[string]string{
+			"instId": "BTC-USDT",
+			"bar":    "1m",
+			"limit":  "3",
+		}, nil, rows),
+	)
+	ctx := context.Background()
+
+	for _, start := range []time.Time{mockStart, {}} {
+		candles, err := ox.GetKlines(ctx, "BTCUSDT", "1m", start, time.Time{}, 3)
+		if err != nil {
+			t.Fatalf("GetKlines(%s): %v", start, err)
+		}
+		if len(candles) != 3 {
+			t.Fatalf("GetKlines(%s) got %d candles, want 3", start, len(candles))
+		}
+		for i, c := range candles {
+			if open := mockStart.Add(time.Duration(i) * time.Minute); !c.OpenTime.Equal(open) || c.Symbol != "BTCUSDT" {
+				t.Errorf("candle %d = %s %s, want BTCUSDT %s", i, c.Symbol, c.OpenTime, open)
+			}
+		}
+		if last := candles[2]; last.Close != 102.5 || last.Volume != 7 {
+			t.Errorf("last candle close %v volume %v, want 102.5 and 7", last.Close, last.Volume)
+		}
+	}
+}
+
+func TestOKXPlaceOrder(t *testing.T) {
+	ox := newFixtureOKX(t,
+		okxInstrumentFixture,
+		// Client order IDs are sent without dashes. The duplicate check finds
+		// nothing the first time and the order on resubmit.
+		okxOrderFixture("clOrdId", "cqtestlimit", okxUnknownOrderResponse),
+		okxOrderFixture("clOrdId", "cqtestlimit", okxData(`[`+okxOpenLimit+`]`)),
+		restFixture(http.MethodPost, "/api/v5/trade/order", nil, map[string]string{
+			"instId":  "BTC-USDT",
+			"tdMode":  "cash",
+			"side":    "buy",
+			"ordType": "limit",
+			"sz":      "0.123",
+			"px":      "99.5",
+			"clOrdId": "cqtestlimit",
+		}, okxData(`[{"ordId":"2001","clOrdId":"cqtestlimit","sCode":"0","sMsg":""}]`)),
+		okxOrderFixture("ordId", "2001", okxData(`[`+okxOpenLimit+`]`)),
+	)
+	ox.SetSymbolRules(NewVenueRules(ox))
+	ctx := context.Background()
+
+	// Rounded to the lot and tick size before it is sent
+	placed, err := ox.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-limit",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeLimit,
+		Quantity:      0.1234,
+		Price:         99.504,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.ID != "2001" || placed.ClientOrderID != "cqtestlimit" || placed.Status != domain.OrderStatusNew {
+		t.Errorf("placed = #%s (%s) %s, want #2001 (cqtestlimit) NEW", placed.ID, placed.ClientOrderID, placed.Status)
+	}
+	if placed.Symbol != "BTCUSDT" || placed.Side != domain.OrderSideBuy || placed.Quantity != 0.123 || placed.Price != 99.5 {
+		t.Errorf("placed = %s %s %v @ %v, want BTCUSDT BUY 0.123 @ 99.5", placed.Symbol, placed.Side, placed.Quantity, placed.Price)
+	}
+
+	// A retried submit returns the order OKX already has
+	again, err := ox.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-limit",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideBuy,
+		Type:          domain.OrderTypeLimit,
+		Quantity:      0.1234,
+		Price:         99.504,
+	})
+	if err != nil || again.ID != "2001" {
+		t.Errorf("resubmit = %v, %v, want the existing order 2001", again, err)
+	}
+}
+
+func TestOKXPlaceOrderRejected(t *testing.T) {
+	ox := newFixtureOKX(t,
+		okxInstrumentFixture,
+		okxOrderFixture("clOrdId", "cqtestmarket", okxUnknownOrderResponse),
+		restFixture(http.MethodGet, "/api/v5/market/ticker", map[string]string{"instId": "BTC-USDT"}, nil,
+			okxData(`[{"instId":"BTC-USDT","last":"100"}]`)),
+		restFixture(http.MethodPost, "/api/v5/trade/order", nil, map[string]string{
+			"instId":  "BTC-USDT",
+			"tdMode":  "cash",
+			"side":    "sell",
+			"ordType": "market",
+			"sz":      "0.5",
+			"tgtCcy":  "base_ccy",
+			"clOrdId": "cqtestmarket",
+		}, `{"code":"1","msg":"Operation failed.","data":[{"ordId":"","clOrdId":"cqtestmarket","sCode":"51008","sMsg":"Order failed. Insufficient balance."}]}`),
+	)
+	ox.SetSymbolRules(NewVenueRules(ox))
+	ctx := context.Background()
+
+	// The item status carries the reason
+	order, err := ox.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-market",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideSell,
+		Type:          domain.OrderTypeMarket,
+		Quantity:      0.5,
+	})
+	var apiErr *VenueAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "51008" || order.Status != domain.OrderStatusRejected {
+		t.Errorf("PlaceOrder = %v, %v, want a rejected order with API error 51008", order, err)
+	}
+
+	// Below the minimum size: rejected without reaching the venue
+	small, err := ox.PlaceOrder(ctx, &domain.Order{
+		ClientOrderID: "cq-test-market",
+		Symbol:        "BTCUSDT",
+		Side:          domain.OrderSideSell,
+		Type:          domain.OrderTypeMarket,
+		Quantity:      0.001,
+	})
+	if err == nil || !strings.Contains(err.Error(), "trading rules") || small.Status != domain.OrderStatusRejected {
+		t.Errorf("small order = %v, %v, want a trading rules rejection", small, err)
+	}
+}
+
+func TestOKXGetAndCancelOrder(t *testing.T) {
+	filled := `{"instId":"BTC-USDT","ordId":"2002","clOrdId":"cqtestmarket","px":"","sz":"0.2","side":"sell",
+		"ordType":"market","state":"filled","accFillSz":"0.2","avgPx":"100.5","cTime":"1704067200000","uTime":"1704067201000"}`
+	cancelled := strings.Replace(okxOpenLimit, `"state":"live"`, `"state":"canceled"`, 1)
+	ox := newFixtureOKX(t,
+		okxOrderFixture("ordId", "2002", okxData(`[`+filled+`]`)),
+		okxOrderFixture("ordId", "404", okxUnknownOrderResponse),
+		okxOrderFixture("ordId", "405", okxData(`[]`)),
+		restFixture(http.MethodPost, "/api/v5/trade/cancel-order", nil, map[string]string{
+			"instId":  "BTC-USDT",
+			"clOrdId": "cqtestlimit",
+		}, okxData(`[{"ordId":"2001","clOrdId":"cqtestlimit","sCode":"0","sMsg":""}]`)),
+		okxOrderFixture("clOrdId", "cqtestlimit", okxData(`[`+cancelled+`]`)),
+	)
+	ctx := context.Background()
+
+	order, err := ox.GetOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT", OrderID: "2002"})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if order.Type != domain.OrderTypeMarket || order.Status != domain.OrderStatusFilled || order.FilledQty != 0.2 || order.AvgPrice != 100.5 {
+		t.Errorf("order = %s %s %v @ %v, want MARKET FILLED 0.2 @ 100.5", order.Type, order.Status, order.FilledQty, order.AvgPrice)
+	}
+	if order.ExecutedAt == nil || !order.ExecutedAt.Equal(mockStart.Add(time.Second)) {
+		t.Errorf("executed at %v, want %s", order.ExecutedAt, mockStart.Add(time.Second))
+	}
+
+	_, err = ox.GetOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT", OrderID: "404"})
+	var apiErr *VenueAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "51603" || !ox.isUnknownOrder(err) {
+		t.Errorf("GetOrder(404) error = %v, want unknown order API error 51603", err)
+	}
+	if _, err := ox.GetOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT", OrderID: "405"}); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("GetOrder(405) error = %v, want ErrOrderNotFound", err)
+	}
+
+	// Our client order ID is converted to OKX's form
+	cancelledOrder, err := ox.CancelOrder(ctx, domain.OrderRef{Symbol: "BTCUSDT", ClientOrderID: "cq-test-limit"})
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if cancelledOrder.ID != "2001" || cancelledOrder.Status != domain.OrderStatusCancelled {
+		t.Errorf("cancelled = #%s %s, want #2001 CANCELLED", cancelledOrder.ID, cancelledOrder.Status)
+	}
+}
@@ -0,0 +1,49 @@
+package exchange
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// defaultHistoryLimit is used when order and trade history queries pass no limit
+const defaultHistoryLimit = 500
+
+// assignClientOrderID gives an order without one a client order ID derived
+// from the order itself, so the same order always gets the same ID.
+func assignClientOrderID(order *domain.Order) {
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = domain.NewClientOrderID(orderKey(order), order)
+	}
+}
+
+// orderKey is the intent key of an order placed without a client order ID: the
+// fields NewClientOrderID doesn't hash, including the exchange order ID when it
+// is assigned before placing (virtual exchange) and the creation time if set
+func orderKey(order *domain.Order) string {
+	var created int64
+	if !order.CreatedAt.IsZero() {
+		created = order.CreatedAt.UnixMilli()
+	}
+	return fmt.Sprintf("%s|%d|%s|%s|%t|%t", order.ID, created,
+		strconv.FormatFloat(order.StopPrice, 'f', -1, 64), order.OCOGroup, order.ReduceOnly, order.ClosePosition)
+}
+
+// historyLimit returns limit, or defaultHistoryLimit if it is not positive
+func historyLimit(limit int) int {
+	if limit <= 0 {
+		return defaultHistoryLimit
+	}
+	return limit
+}
+
+// checkOrderType rejects the order unless it is a market or limit order; stop
+// orders are only simulated by the virtual exchange
+func checkOrderType(order *domain.Order) error {
+	if order.Type != domain.OrderTypeMarket && order.Type != domain.OrderTypeLimit {
+		order.Status = domain.OrderStatusRejected
+		return fmt.Errorf("order type %s is not supported", order.Type)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+func TestAssignClientOrderID(t *testing.T) {
+	base := domain.Order{
+		Symbol:   "BTCUSDT",
+		Side:     domain.OrderSideBuy,
+		Type:     domain.OrderTypeLimit,
+		Quantity: 0.5,
+		Price:    40000,
+	}
+	id := func(order domain.Order) string {
+		assignClientOrderID(&order)
+		return order.ClientOrderID
+	}
+
+	// The same order gets the same ID, on every call
+	first := id(base)
+	if !strings.HasPrefix(first, domain.ClientOrderIDPrefix) || len(first) > 36 {
+		t.Fatalf("client order ID = %q, want %s prefix within 36 characters", first, domain.ClientOrderIDPrefix)
+	}
+	time.Sleep(time.Millisecond)
+	if again := id(base); again != first {
+		t.Errorf("client order ID = %s for the same order, want %s", again, first)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(o *domain.Order)
+	}{
+		{"quantity", func(o *domain.Order) { o.Quantity = 0.6 }},
+		{"price", func(o *domain.Order) { o.Price = 40001 }},
+		{"side", func(o *domain.Order) { o.Side = domain.OrderSideSell }},
+		{"stop price", func(o *domain.Order) { o.StopPrice = 39000 }},
+		{"oco group", func(o *domain.Order) { o.OCOGroup = "oco-1" }},
+		{"reduce only", func(o *domain.Order) { o.ReduceOnly = true }},
+		{"close position", func(o *domain.Order) { o.ClosePosition = true }},
+		{"exchange id", func(o *domain.Order) { o.ID = "7" }},
+		{"creation time", func(o *domain.Order) { o.CreatedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }},
+	}
+	for _, tt := range tests {
+		order := base
+		tt.modify(&order)
+		if got := id(order); got == first {
+			t.Errorf("%s: client order ID unchanged by a different order", tt.name)
+		}
+	}
+
+	// An ID set by the caller is kept
+	order := base
+	order.ClientOrderID = "cq-strategy-1"
+	if got := id(order); got != "cq-strategy-1" {
+		t.Errorf("client order ID = %s, want the caller's cq-strategy-1", got)
+	}
+}
@@ -0,0 +1,523 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Stream manager defaults
+const (
+	streamMinBackoff   = time.Second
+	streamMaxBackoff   = time.Minute
+	streamPingInterval = 30 * time.Second
+	streamPongTimeout  = 10 * time.Second
+	// Binance closes connections after 24h; reconnecting earlier keeps it on our schedule
+	streamMaxLifetime = 23 * time.Hour
+)
+
+// streamMaxStreams is Binance's limit of streams on one connection
+const streamMaxStreams = 1024
+
+// streamEventHistory is how many state changes Status keeps
+const streamEventHistory = 50
+
+// errStreamLifetime ends a connection that reached StreamConfig.MaxLifetime
+var errStreamLifetime = errors.New("connection lifetime reached")
+
+// StreamState is the connection state of a stream manager
+type StreamState string
+
+const (
+	StreamStateIdle         StreamState = "idle"         // No subscriptions, not connected
+	StreamStateConnecting   StreamState = "connecting"   // First connect
+	StreamStateConnected    StreamState = "connected"    // Receiving messages
+	StreamStateReconnecting StreamState = "reconnecting" // Waiting to retry after a drop or failed connect
+	StreamStateClosed       StreamState = "closed"       // Closed for good
+)
+
+// StreamEvent is a connection state change
+type StreamEvent struct {
+	State   StreamState `json:"state"`
+	Streams int         `json:"streams"`            // Subscribed streams at the time
+	Attempt int         `json:"attempt,omitempty"`  // Consecutive failed connects (reconnecting only)
+	RetryAt *time.Time  `json:"retry_at,omitempty"` // When the next connect is tried (reconnecting only)
+	Error   string      `json:"error,omitempty"`
+	Time    time.Time   `json:"time"`
+}
+
+// StreamStatus is a snapshot of a stream manager
+type StreamStatus struct {
+	State       StreamState   `json:"state"`
+	Streams     []string      `json:"streams"`
+	ConnectedAt *time.Time    `json:"connected_at,omitempty"`
+	LastMessage *time.Time    `json:"last_message,omitempty"`
+	Reconnects  int           `json:"reconnects"`
+	Events      []StreamEvent `json:"events"` // Recent state changes, oldest first
+}
+
+// StreamConfig tunes reconnects and health checks. Zero fields use the defaults.
+type StreamConfig struct {
+	MinBackoff   time.Duration // First reconnect delay, doubled per failed attempt (default: 1s)
+	MaxBackoff   time.Duration // Reconnect delay cap (default: 1m)
+	PingInterval time.Duration // How often a ping is sent (default: 30s)
+	PongTimeout  time.Duration // Silence after a ping that counts as a dead connection (default: 10s)
+	MaxLifetime  time.Duration // Reconnect proactively after this long (default: 23h)
+}
+
+// streamSub is one subscriber of a stream
+type streamSub struct {
+	handler     func([]byte)
+	onReconnect func()
+}
+
+// StreamManager multiplexes subscriptions onto one combined WebSocket connection
+// and keeps it alive: dead connections are detected with pings, dropped ones are
+// redialed with exponential backoff, and every state change is reported.
+type StreamManager struct {
+	endpoint func(streams []string) string
+	cfg      StreamConfig
+	writeMu  sync.Mutex // Serializes SUBSCRIBE/UNSUBSCRIBE writes
+
+	mu          sync.Mutex
+	subs        map[string][]*streamSub
+	conn        *websocket.Conn
+	requestID   int64
+	closed      bool
+	stopC       chan struct{} // Stops the running connection loop (nil if not running)
+	doneC       chan struct{}
+	state       StreamState
+	connectedAt time.Time
+	lastMessage time.Time
+	reconnects  int
+	events      []StreamEvent
+	listeners   []func(StreamEvent)
+}
+
+// NewStreamManager creates a stream manager. endpoint returns the combined
+// stream URL for a set of streams.
+func NewStreamManager(endpoint func(streams []string) string, cfg StreamConfig) *StreamManager {
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = streamMinBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = streamMaxBackoff
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = streamPingInterval
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = streamPongTimeout
+	}
+	if cfg.MaxLifetime <= 0 {
+		cfg.MaxLifetime = streamMaxLifetime
+	}
+
+	return &StreamManager{
+		endpoint: endpoint,
+		cfg:      cfg,
+		subs:     make(map[string][]*streamSub),
+		state:    StreamStateIdle,
+		events:   make([]StreamEvent, 0, streamEventHistory),
+	}
+}
+
+// Subscribe delivers a stream's messages to handler until the returned function
+// is called. onReconnect (optional) runs after every reconnect, before new
+// messages are delivered, so subscribers can recover what they missed.
+// handler and onReconnect run on the connection goroutine and must not block for long.
+func (m *StreamManager) Subscribe(stream string, handler func([]byte), onReconnect func()) (func(), error) {
+	sub := &streamSub{handler: handler, onReconnect: onReconnect}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("stream manager is closed")
+	}
+	_, exists := m.subs[stream]
+	if !exists && len(m.subs) >= streamMaxStreams {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("too many streams (max %d)", streamMaxStreams)
+	}
+	m.subs[stream] = append(m.subs[stream], sub)
+
+	conn := m.conn
+	if m.stopC == nil {
+		m.stopC = make(chan struct{})
+		m.doneC = make(chan struct{})
+		go m.run(m.stopC, m.doneC)
+		conn = nil
+	}
+	m.mu.Unlock()
+
+	// A live connection takes the new stream without reconnecting
+	if !exists && conn != nil {
+		m.sendMethod(conn, "SUBSCRIBE", []string{stream})
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { m.unsubscribe(stream, sub) }) }, nil
+}
+
+// unsubscribe removes a subscriber, dropping the stream (and the connection) once unused
+func (m *StreamManager) unsubscribe(stream string, sub *streamSub) {
+	m.mu.Lock()
+	subs := m.subs[stream]
+	for i, s := range subs {
+		if s == sub {
+			subs = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+
+	conn := m.conn
+	if len(subs) > 0 {
+		m.subs[stream] = subs
+		m.mu.Unlock()
+		return
+	}
+	delete(m.subs, stream)
+
+	if len(m.subs) == 0 && m.stopC != nil {
+		close(m.stopC)
+		m.stopC = nil
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	if conn != nil {
+		m.sendMethod(conn, "UNSUBSCRIBE", []string{stream})
+	}
+}
+
+// OnEvent registers a listener for connection state changes
+func (m *StreamManager) OnEvent(listener func(StreamEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
+
+// Status returns the connection state, subscribed streams and recent state changes
+func (m *StreamManager) Status() *StreamStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &StreamStatus{
+		State:      m.state,
+		Streams:    m.streamList(),
+		Reconnects: m.reconnects,
+		Events:     append([]StreamEvent(nil), m.events...),
+	}
+	if m.conn != nil {
+		connectedAt := m.connectedAt
+		status.ConnectedAt = &connectedAt
+	}
+	if !m.lastMessage.IsZero() {
+		lastMessage := m.lastMessage
+		status.LastMessage = &lastMessage
+	}
+	return status
+}
+
+// Close disconnects and rejects further subscriptions
+func (m *StreamManager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	if m.stopC != nil {
+		close(m.stopC)
+		m.stopC = nil
+	}
+	doneC := m.doneC
+	m.mu.Unlock()
+
+	if doneC != nil {
+		<-doneC
+	}
+	m.emit(StreamEvent{State: StreamStateClosed})
+}
+
+// run connects and reconnects until stopC is closed or no streams remain
+func (m *StreamManager) run(stopC, doneC chan struct{}) {
+	defer close(doneC)
+
+	attempt := 0
+	connected := false
+	for {
+		streams := m.streams()
+		if len(streams) == 0 {
+			m.stopped(stopC)
+			return
+		}
+		if !connected && attempt == 0 {
+			m.emit(StreamEvent{State: StreamStateConnecting})
+		}
+
+		conn, err := m.dial(streams)
+		if err == nil {
+			attempt = 0
+			err = m.serve(conn, streams, connected, stopC)
+			connected = true
+		}
+
+		select {
+		case <-stopC:
+			m.stopped(stopC)
+			return
+		default:
+		}
+
+		attempt++
+		wait := m.backoff(attempt)
+		retryAt := time.Now().Add(wait)
+		m.emit(StreamEvent{State: StreamStateReconnecting, Attempt: attempt, RetryAt: &retryAt, Error: err.Error()})
+
+		select {
+		case <-time.After(wait):
+		case <-stopC:
+			m.stopped(stopC)
+			return
+		}
+	}
+}
+
+// stopped reports the idle state after a loop stopped because every stream was unsubscribed
+func (m *StreamManager) stopped(stopC chan struct{}) {
+	m.mu.Lock()
+	closed := m.closed
+	// A new subscription may already have started the next loop
+	restarted := m.stopC != nil
+	m.mu.Unlock()
+
+	if !closed && !restarted {
+		m.emit(StreamEvent{State: StreamStateIdle})
+	}
+}
+
+// dial opens a combined connection for the streams
+func (m *StreamManager) dial(streams []string) (*websocket.Conn, error) {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: wsHandshakeTimeout,
+	}
+	conn, _, err := dialer.Dial(m.endpoint(streams), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	conn.SetReadLimit(wsReadLimit)
+	return conn, nil
+}
+
+// serve reads one connection until it fails, goes silent, reaches its lifetime or stopC is closed
+func (m *StreamManager) serve(conn *websocket.Conn, dialed []string, reconnect bool, stopC chan struct{}) error {
+	m.mu.Lock()
+	m.conn = conn
+	m.connectedAt = time.Now().UTC()
+	if reconnect {
+		m.reconnects++
+	}
+	// Streams subscribed while dialing weren't in the URL
+	missing := make([]string, 0)
+	dialedSet := make(map[string]bool, len(dialed))
+	for _, stream := range dialed {
+		dialedSet[stream] = true
+	}
+	for stream := range m.subs {
+		if !dialedSet[stream] {
+			missing = append(missing, stream)
+		}
+	}
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		if m.conn == conn {
+			m.conn = nil
+		}
+		m.mu.Unlock()
+		conn.Close()
+	}()
+
+	m.emit(StreamEvent{State: StreamStateConnected})
+	if len(missing) > 0 {
+		m.sendMethod(conn, "SUBSCRIBE", missing)
+	}
+	if reconnect {
+		for _, sub := range m.allSubs() {
+			if sub.onReconnect != nil {
+				sub.onReconnect()
+			}
+		}
+	}
+
+	// Any message or pong proves the connection is alive
+	timeout := m.cfg.PingInterval + m.cfg.PongTimeout
+	alive := func() { conn.SetReadDeadline(time.Now().Add(timeout)) }
+	alive()
+	conn.SetPongHandler(func(string) error {
+		alive()
+		return nil
+	})
+	conn.SetPingHandler(func(data string) error {
+		alive()
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	expired := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(m.cfg.PingInterval)
+		defer ticker.Stop()
+		lifetime := time.NewTimer(m.cfg.MaxLifetime)
+		defer lifetime.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(m.cfg.PongTimeout))
+			case <-lifetime.C:
+				close(expired)
+				conn.Close()
+				return
+			case <-stopC:
+				conn.Close()
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-expired:
+				return errStreamLifetime
+			default:
+				return err
+			}
+		}
+		alive()
+		m.dispatch(message)
+	}
+}
+
+// dispatch routes a combined stream message ({"stream":...,"data":...}) to the stream's subscribers
+func (m *StreamManager) dispatch(message []byte) {
+	var envelope struct {
+		Stream string          `json:"stream"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(message, &envelope); err != nil || envelope.Stream == "" {
+		// SUBSCRIBE/UNSUBSCRIBE replies carry no stream
+		return
+	}
+
+	m.mu.Lock()
+	m.lastMessage = time.Now().UTC()
+	subs := append([]*streamSub(nil), m.subs[envelope.Stream]...)
+	m.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.handler(envelope.Data)
+	}
+}
+
+// sendMethod sends a SUBSCRIBE or UNSUBSCRIBE request on a live connection.
+// A failed write is recovered by the reconnect, which dials every current stream.
+func (m *StreamManager) sendMethod(conn *websocket.Conn, method string, streams []string) {
+	m.mu.Lock()
+	m.requestID++
+	id := m.requestID
+	m.mu.Unlock()
+
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	if err := conn.WriteJSON(map[string]interface{}{"method": method, "params": streams, "id": id}); err != nil {
+		log.Printf("⚠️  Stream %s failed: %v", method, err)
+	}
+}
+
+// emit records a state change and notifies listeners
+func (m *StreamManager) emit(ev StreamEvent) {
+	ev.Time = time.Now().UTC()
+
+	m.mu.Lock()
+	ev.Streams = len(m.subs)
+	m.state = ev.State
+	if len(m.events) == streamEventHistory {
+		m.events = append(m.events[:0], m.events[1:]...)
+	}
+	m.events = append(m.events, ev)
+	listeners := append(make([]func(StreamEvent), 0, len(m.listeners)), m.listeners...)
+	m.mu.Unlock()
+
+	switch ev.State {
+	case StreamStateConnected:
+		log.Printf("✅ Stream connected (%d streams)", ev.Streams)
+	case StreamStateReconnecting:
+		log.Printf("⚠️  Stream disconnected: %s (attempt %d, retrying at %s)", ev.Error, ev.Attempt, ev.RetryAt.Format(time.TimeOnly))
+	}
+
+	for _, listener := range listeners {
+		listener(ev)
+	}
+}
+
+// backoff returns the delay before a reconnect attempt: doubling from MinBackoff
+// up to MaxBackoff, plus up to 20% jitter so clients don't reconnect in lockstep
+func (m *StreamManager) backoff(attempt int) time.Duration {
+	d := m.cfg.MinBackoff
+	for i := 1; i < attempt && d < m.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, m.cfg.MaxBackoff)
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+// streams returns the subscribed streams
+func (m *StreamManager) streams() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.streamList()
+}
+
+// streamList returns the subscribed streams in order (m.mu must be held)
+func (m *StreamManager) streamList() []string {
+	streams := make([]string, 0, len(m.subs))
+	for stream := range m.subs {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	return streams
+}
+
+// allSubs returns every subscriber
+func (m *StreamManager) allSubs() []*streamSub {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subs := make([]*streamSub, 0, len(m.subs))
+	for _, stream := range m.streamList() {
+		subs = append(subs, m.subs[stream]...)
+	}
+	return subs
+}
@@ -0,0 +1,361 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/gorilla/websocket"
+	"github.com/lavumi/crypto-quant/internal/datasource/exchange/binancemock"
+)
+
+// mockStreamEndpoint returns the mock's combined stream URL for a set of streams
+func mockStreamEndpoint(srv *binancemock.Server) func(streams []string) string {
+	return func(streams []string) string {
+		return strings.TrimSuffix(srv.WsURL(), "/ws") + "/stream?streams=" + strings.Join(streams, "/")
+	}
+}
+
+// recordEvents returns a channel receiving the manager's state changes
+func recordEvents(m *StreamManager) chan StreamEvent {
+	events := make(chan StreamEvent, 100)
+	m.OnEvent(func(ev StreamEvent) {
+		select {
+		case events <- ev:
+		default:
+		}
+	})
+	return events
+}
+
+// waitState waits for the next event in state, skipping others
+func waitState(t *testing.T, events chan StreamEvent, state StreamState) StreamEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.State == state {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", state)
+			return StreamEvent{}
+		}
+	}
+}
+
+// klineOpenTime returns the open time of a kline stream message
+func klineOpenTime(t *testing.T, data []byte) time.Time {
+	t.Helper()
+	event := new(binance.WsKlineEvent)
+	if err := json.Unmarshal(data, event); err != nil {
+		t.Errorf("bad kline message: %v", err)
+	}
+	return time.UnixMilli(event.Kline.StartTime).UTC()
+}
+
+func TestStreamBackoff(t *testing.T) {
+	m := NewStreamManager(nil, StreamConfig{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second}, // Capped
+		{50, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			// Up to 20% jitter on top
+			if got := m.backoff(tt.attempt); got < tt.base || got > tt.base+tt.base/5 {
+				t.Errorf("backoff(%d) = %s, want %s plus up to 20%%", tt.attempt, got, tt.base)
+				break
+			}
+		}
+	}
+
+	defaults := NewStreamManager(nil, StreamConfig{})
+	if defaults.cfg.MinBackoff != streamMinBackoff || defaults.cfg.MaxBackoff != streamMaxBackoff ||
+		defaults.cfg.PingInterval != streamPingInterval || defaults.cfg.PongTimeout != streamPongTimeout {
+		t.Errorf("default config = %+v", defaults.cfg)
+	}
+}
+
+func TestStreamReconnectBackoff(t *testing.T) {
+	// Nothing listens at the endpoint, so every connect fails
+	dead := httptest.NewServer(http.NotFoundHandler())
+	url := "ws" + strings.TrimPrefix(dead.URL, "http")
+	dead.Close()
+
+	m := NewStreamManager(func([]string) string { return url }, StreamConfig{
+		MinBackoff: 20 * time.Millisecond,
+		MaxBackoff: 80 * time.Millisecond,
+	})
+	events := recordEvents(m)
+	unsubscribe, err := m.Subscribe("btcusdt@trade", func([]byte) {}, nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	waitState(t, events, StreamStateConnecting)
+	bases := []time.Duration{20, 40, 80, 80}
+	for i, base := range bases {
+		base *= time.Millisecond
+		ev := waitState(t, events, StreamStateReconnecting)
+		if ev.Attempt != i+1 || ev.Error == "" || ev.Streams != 1 {
+			t.Errorf("reconnect event %d = %+v, want attempt %d with an error", i, ev, i+1)
+		}
+		// RetryAt is taken just before the event time
+		if wait := ev.RetryAt.Sub(ev.Time); wait > base+base/5 || wait < base-10*time.Millisecond {
+			t.Errorf("attempt %d retries after %s, want about %s", ev.Attempt, wait, base)
+		}
+	}
+	if status := m.Status(); status.State != StreamStateReconnecting || status.Reconnects != 0 || status.ConnectedAt != nil {
+		t.Errorf("status = %+v, want reconnecting without ever connecting", status)
+	}
+
+	// Dropping the last stream stops retrying
+	unsubscribe()
+	waitState(t, events, StreamStateIdle)
+	m.Close()
+	waitState(t, events, StreamStateClosed)
+	if _, err := m.Subscribe("btcusdt@trade", func([]byte) {}, nil); err == nil {
+		t.Error("Subscribe after Close succeeded, want error")
+	}
+}
+
+func TestStreamCombinedSubscriptions(t *testing.T) {
+	srv := newMockServer(t)
+	m := NewStreamManager(mockStreamEndpoint(srv), StreamConfig{})
+	t.Cleanup(m.Close)
+	events := recordEvents(m)
+
+	klines := make(chan time.Time, 100)
+	klines2 := make(chan time.Time, 100)
+	trades := make(chan []byte, 100)
+	unsubKline, err := m.Subscribe("btcusdt@kline_1m", func(data []byte) { klines <- klineOpenTime(t, data) }, nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitState(t, events, StreamStateConnected)
+
+	// Streams added to a live connection are subscribed on it, not redialed
+	unsubKline2, _ := m.Subscribe("btcusdt@kline_1m", func(data []byte) { klines2 <- klineOpenTime(t, data) }, nil)
+	unsubTrade, _ := m.Subscribe("btcusdt@trade", func(data []byte) { trades <- data }, nil)
+
+	// Close bars until the mock has taken the SUBSCRIBE
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for len(trades) == 0 {
+		select {
+		case <-ticker.C:
+			srv.Advance()
+		case <-timeout:
+			t.Fatal("no trade streamed after subscribing")
+		}
+	}
+	if len(klines) == 0 || len(klines2) == 0 {
+		t.Errorf("kline subscribers got %d and %d messages, want both", len(klines), len(klines2))
+	}
+
+	status := m.Status()
+	if status.State != StreamStateConnected || status.Reconnects != 0 || status.LastMessage == nil ||
+		fmt.Sprint(status.Streams) != "[btcusdt@kline_1m btcusdt@trade]" {
+		t.Errorf("status = %s %v, %d reconnects, want both streams on one connection", status.State, status.Streams, status.Reconnects)
+	}
+
+	// An unsubscribed handler gets nothing more, while other subscribers still do
+	unsubTrade()
+	unsubKline2()
+	for len(klines) > 0 {
+		<-klines
+	}
+	for len(trades) > 0 {
+		<-trades
+	}
+	for len(klines2) > 0 {
+		<-klines2
+	}
+	srv.Advance()
+	select {
+	case <-klines:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no kline after unsubscribing the trade stream")
+	}
+	if len(trades) != 0 || len(klines2) != 0 {
+		t.Errorf("unsubscribed handlers got %d trades and %d klines", len(trades), len(klines2))
+	}
+	if streams := m.Status().Streams; fmt.Sprint(streams) != "[btcusdt@kline_1m]" {
+		t.Errorf("streams = %v, want only the kline stream", streams)
+	}
+
+	// The last unsubscribe disconnects
+	unsubKline()
+	unsubKline() // Unsubscribing twice is harmless
+	waitState(t, events, StreamStateIdle)
+	if status := m.Status(); status.ConnectedAt != nil || len(status.Streams) != 0 {
+		t.Errorf("status = %+v, want disconnected without streams", status)
+	}
+}
+
+func TestStreamPingKeepsQuietConnection(t *testing.T) {
+	srv := newMockServer(t)
+	m := NewStreamManager(mockStreamEndpoint(srv), StreamConfig{
+		PingInterval: 30 * time.Millisecond,
+		PongTimeout:  30 * time.Millisecond,
+	})
+	t.Cleanup(m.Close)
+	events := recordEvents(m)
+
+	if _, err := m.Subscribe("btcusdt@kline_1m", func([]byte) {}, nil); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitState(t, events, StreamStateConnected)
+
+	// No messages for many ping intervals: the mock's pongs keep it alive
+	time.Sleep(300 * time.Millisecond)
+	for len(events) > 0 {
+		if ev := <-events; ev.State != StreamStateConnected {
+			t.Errorf("unexpected %s event on a quiet but healthy connection: %s", ev.State, ev.Error)
+		}
+	}
+	if status := m.Status(); status.State != StreamStateConnected || status.Reconnects != 0 {
+		t.Errorf("status = %s with %d reconnects, want connected", status.State, status.Reconnects)
+	}
+}
+
+func TestStreamPongTimeout(t *testing.T) {
+	// The server accepts connections but never reads, so pings go unanswered
+	var mu sync.Mutex
+	var conns []*websocket.Conn
+	upgrader := websocket.Upgrader{}
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		conns = append(conns, conn)
+		mu.Unlock()
+	}))
+	t.Cleanup(func() {
+		mu.Lock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+		dead.Close()
+	})
+
+	m := NewStreamManager(func([]string) string { return "ws" + strings.TrimPrefix(dead.URL, "http") }, StreamConfig{
+		MinBackoff:   20 * time.Millisecond,
+		PingInterval: 30 * time.Millisecond,
+		PongTimeout:  30 * time.Millisecond,
+	})
+	t.Cleanup(m.Close)
+	events := recordEvents(m)
+
+	if _, err := m.Subscribe("btcusdt@kline_1m", func([]byte) {}, nil); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	connected := waitState(t, events, StreamStateConnected)
+	ev := waitState(t, events, StreamStateReconnecting)
+	if !strings.Contains(ev.Error, "timeout") || ev.Attempt != 1 {
+		t.Errorf("reconnect event = %+v, want a read timeout", ev)
+	}
+	if silent := ev.Time.Sub(connected.Time); silent < 60*time.Millisecond {
+		t.Errorf("dropped after %s, want after ping interval + pong timeout", silent)
+	}
+
+	// It is redialed
+	waitState(t, events, StreamStateConnected)
+	if reconnects := m.Status().Reconnects; reconnects < 1 {
+		t.Errorf("reconnects = %d, want at least 1", reconnects)
+	}
+}
+
+func TestStreamReconnectBackfill(t *testing.T) {
+	srv := newMockServer(t)
+	m := NewStreamManager(mockStreamEndpoint(srv), StreamConfig{MinBackoff: 100 * time.Millisecond})
+	t.Cleanup(m.Close)
+	events := recordEvents(m)
+
+	client := binance.NewClient("", "")
+	client.BaseURL = srv.URL()
+
+	// The subscriber backfills klines closed while disconnected over REST. Both
+	// callbacks run on the connection goroutine, so last needs no lock.
+	var last time.Time
+	entries := make(chan string, 100)
+	handler := func(data []byte) {
+		last = klineOpenTime(t, data)
+		entries <- "kline " + last.Format("15:04")
+	}
+	backfill := func() {
+		klines, err := client.NewKlinesService().Symbol("BTCUSDT").Interval("1m").
+			StartTime(last.Add(time.Minute).UnixMilli()).Do(context.Background())
+		if err != nil {
+			entries <- "backfill failed"
+			return
+		}
+		entry := "backfill"
+		for _, k := range klines {
+			last = time.UnixMilli(k.OpenTime).UTC()
+			entry += " " + last.Format("15:04")
+		}
+		entries <- entry
+	}
+	if _, err := m.Subscribe("btcusdt@kline_1m", handler, backfill); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitState(t, events, StreamStateConnected)
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-entries:
+			if got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	// No backfill on the first connect
+	srv.Advance()
+	expect("kline 00:10")
+
+	// Bars closed while disconnected are fetched before the stream resumes
+	srv.Disconnect()
+	srv.Advance()
+	srv.Advance()
+	expect("backfill 00:11 00:12")
+	waitState(t, events, StreamStateConnected)
+	srv.Advance()
+	expect("kline 00:13")
+
+	// A stalled backfill delays the live stream but doesn't lose its messages
+	srv.SetFaults(binancemock.Faults{StallEvery: 1, StallFor: 200 * time.Millisecond})
+	srv.Disconnect()
+	waitState(t, events, StreamStateConnected)
+	srv.Advance()
+	expect("backfill failed")
+	expect("kline 00:14")
+
+	if reconnects := m.Status().Reconnects; reconnects != 2 {
+		t.Errorf("reconnects = %d, want 2", reconnects)
+	}
+}
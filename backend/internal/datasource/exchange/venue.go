@@ -0,0 +1,207 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+// FeeSchedule is a venue's trading fees as fractions of the fill notional
+type FeeSchedule struct {
+	Maker float64 `json:"maker"`
+	Taker float64 `json:"taker"`
+}
+
+// Fee returns the fee charged on a fill of the given notional
+func (f FeeSchedule) Fee(notional float64, maker bool) float64 {
+	if maker {
+		return notional * f.Maker
+	}
+	return notional * f.Taker
+}
+
+// ErrOrderNotFound is returned when a venue has no order matching a reference
+var ErrOrderNotFound = errors.New("order does not exist")
+
+// SymbolStyle is how a venue writes market symbols. We use the BTCUSDT form
+// everywhere else; connectors convert at the API boundary.
+type SymbolStyle string
+
+const (
+	SymbolStyleConcat     SymbolStyle = "concat"      // BTCUSDT (Binance, Bybit)
+	SymbolStyleDashed     SymbolStyle = "dashed"      // BTC-USDT (OKX)
+	SymbolStyleQuoteFirst SymbolStyle = "quote_first" // KRW-BTC (Upbit)
+)
+
+// ToVenue converts a BTCUSDT symbol to the venue's form
+func (s SymbolStyle) ToVenue(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	base := symbols.SplitSymbol(symbol)
+	if base == symbol {
+		return symbol // Unknown quote asset, leave as is
+	}
+	quote := strings.TrimPrefix(symbol, base)
+
+	switch s {
+	case SymbolStyleDashed:
+		return base + "-" + quote
+	case SymbolStyleQuoteFirst:
+		return quote + "-" + base
+	default:
+		return symbol
+	}
+}
+
+// FromVenue converts a venue symbol to the BTCUSDT form
+func (s SymbolStyle) FromVenue(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	first, second, ok := strings.Cut(symbol, "-")
+	if !ok {
+		return symbol
+	}
+
+	switch s {
+	case SymbolStyleQuoteFirst:
+		return second + first
+	default:
+		return first + second
+	}
+}
+
+// VenueConfig configures a connector created through the registry
+type VenueConfig struct {
+	APIKey     string
+	SecretKey  string
+	Passphrase string       // OKX only
+	BaseURL    string       // REST base URL override
+	WsBaseURL  string       // Stream base URL override
+	Testnet    bool         // Testnet (Binance, Bybit) or demo trading (OKX)
+	Fees       *FeeSchedule // Overrides the venue's default fee schedule (e.g. a VIP tier)
+	HTTPClient *http.Client // e.g. a FixtureRecorder or FixtureReplayer client
+
+	// StreamTap (optional) sees every raw stream message, e.g. to record stream fixtures
+	StreamTap func(message []byte)
+}
+
+// NewVenueConfig converts a venue's section of the config file
+func NewVenueConfig(cfg config.VenueConfig) VenueConfig {
+	venueCfg := VenueConfig{
+		APIKey:     cfg.APIKey,
+		SecretKey:  cfg.SecretKey,
+		Passphrase: cfg.Passphrase,
+		BaseURL:    cfg.BaseURL,
+		WsBaseURL:  cfg.WsBaseURL,
+		Testnet:    cfg.UseTestnet,
+	}
+	if cfg.MakerFee != 0 || cfg.TakerFee != 0 {
+		venueCfg.Fees = &FeeSchedule{Maker: cfg.MakerFee, Taker: cfg.TakerFee}
+	}
+	return venueCfg
+}
+
+// fees returns the configured fee override or the venue default
+func (c VenueConfig) fees(defaults FeeSchedule) FeeSchedule {
+	if c.Fees != nil {
+		return *c.Fees
+	}
+	return defaults
+}
+
+// httpClient returns the configured HTTP client or a default one
+func (c VenueConfig) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// VenueExchange is what every registered connector implements: trading, a
+// kline source for the history collector and the venue's fee schedule
+type VenueExchange interface {
+	domain.Exchange
+	domain.KlineSource
+
+	// Fees returns the fee schedule the connector was configured with
+	Fees() FeeSchedule
+
+	// StreamKlines streams a symbol's klines (forming and closed) until ctx is done
+	StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error
+}
+
+// Venue describes a registered exchange connector
+type Venue struct {
+	Name    string
+	Symbols SymbolStyle
+	Fees    FeeSchedule // Default (base tier) fees
+	New     func(cfg VenueConfig) (VenueExchange, error)
+}
+
+var (
+	venuesMu sync.RWMutex
+	venues   = make(map[string]*Venue)
+)
+
+// RegisterVenue makes a connector available by name. Connectors register
+// themselves in init; registering a name twice panics.
+func RegisterVenue(venue Venue) {
+	venuesMu.Lock()
+	defer venuesMu.Unlock()
+
+	name := strings.ToLower(venue.Name)
+	if _, ok := venues[name]; ok {
+		panic("exchange: venue registered twice: " + name)
+	}
+	venue.Name = name
+	venues[name] = &venue
+}
+
+// GetVenue looks up a registered venue by name
+func GetVenue(name string) (*Venue, error) {
+	venuesMu.RLock()
+	defer venuesMu.RUnlock()
+
+	venue, ok := venues[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown exchange %q (registered: %s)", name, strings.Join(venueNamesLocked(), ", "))
+	}
+	return venue, nil
+}
+
+// VenueNames lists the registered venues in name order
+func VenueNames() []string {
+	venuesMu.RLock()
+	defer venuesMu.RUnlock()
+	return venueNamesLocked()
+}
+
+// venueNamesLocked lists venue names; the caller holds venuesMu
+func venueNamesLocked() []string {
+	names := make([]string, 0, len(venues))
+	for name := range venues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewVenueExchange creates a connector for a registered venue
+func NewVenueExchange(name string, cfg VenueConfig) (VenueExchange, error) {
+	venue, err := GetVenue(name)
+	if err != nil {
+		return nil, err
+	}
+	ex, err := venue.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exchange: %w", venue.Name, err)
+	}
+	return ex, nil
+}
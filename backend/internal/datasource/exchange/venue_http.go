@@ -0,0 +1,273 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// venueMaxResponse is the largest REST response body read
+const venueMaxResponse = 16 << 20
+
+// VenueAPIError is an error answered by a venue's REST API
+type VenueAPIError struct {
+	Venue   string
+	Status  int    // HTTP status
+	Code    string // Venue error code
+	Message string
+}
+
+// Error formats the error like the venue reports it
+func (e *VenueAPIError) Error() string {
+	return fmt.Sprintf("%s API error (status %d, code %s): %s", e.Venue, e.Status, e.Code, e.Message)
+}
+
+// restClient sends REST requests to a venue, spaced at least spacing apart so
+// bursts stay under the venue's request rate limit
+type restClient struct {
+	venue   string
+	baseURL string
+	http    *http.Client
+	spacing time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newRESTClient creates a REST client for a venue
+func newRESTClient(venue, baseURL string, httpClient *http.Client, spacing time.Duration) *restClient {
+	return &restClient{
+		venue:   venue,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    httpClient,
+		spacing: spacing,
+	}
+}
+
+// wait blocks until the next request slot or ctx is done
+func (c *restClient) wait(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	slot := c.next
+	if slot.Before(now) {
+		slot = now
+	}
+	c.next = slot.Add(c.spacing)
+	c.mu.Unlock()
+
+	if delay := time.Until(slot); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// do sends a request and returns the response status and body. Non-2xx
+// responses are returned too, since venues put error details in the body.
+func (c *restClient) do(ctx context.Context, method, path, query string, body []byte, header http.Header) (int, []byte, error) {
+	if err := c.wait(ctx); err != nil {
+		return 0, nil, err
+	}
+
+	target := c.baseURL + path
+	if query != "" {
+		target += "?" + query
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s request failed: %w", c.venue, err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, venueMaxResponse))
+	if err != nil {
+		return res.StatusCode, nil, fmt.Errorf("failed to read %s response: %w", c.venue, err)
+	}
+	return res.StatusCode, data, nil
+}
+
+// encodeQuery encodes query parameters sorted by key, the order venues sign them in
+func encodeQuery(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key, value := range params {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, url.QueryEscape(key)+"="+url.QueryEscape(params[key]))
+	}
+	return strings.Join(values, "&")
+}
+
+// venueInterval is one kline interval a venue supports
+type venueInterval struct {
+	Code     string // The venue's name for the interval
+	Duration time.Duration
+}
+
+// lookupInterval maps our interval name (1m, 1h, 1d...) to the venue's
+func lookupInterval(venue string, intervals map[string]venueInterval, interval string) (venueInterval, error) {
+	iv, ok := intervals[interval]
+	if !ok {
+		return venueInterval{}, fmt.Errorf("%s does not support interval %q", venue, interval)
+	}
+	return iv, nil
+}
+
+// klineWindow clamps [start, end] to at most limit bars. Venues answer a kline
+// range with its newest bars, so a wider range would skip the oldest ones.
+func klineWindow(start, end time.Time, step time.Duration, limit int) time.Time {
+	if last := start.Add(step * time.Duration(limit-1)); end.IsZero() || end.After(last) {
+		return last
+	}
+	return end
+}
+
+// sortCandles orders candles oldest first and drops those opening outside [start, end]
+func sortCandles(candles []*domain.Candle, start, end time.Time) []*domain.Candle {
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].OpenTime.Before(candles[j].OpenTime)
+	})
+	kept := candles[:0]
+	for _, candle := range candles {
+		if inHistoryRange(candle.OpenTime, start, end) {
+			kept = append(kept, candle)
+		}
+	}
+	return kept
+}
+
+// historyWindows calls fetch for each window of at most span in [start, end],
+// oldest first, until limit rows were fetched. A zero start makes a single
+// call without a time range.
+func historyWindows(start, end time.Time, span time.Duration, limit int, fetch func(from, to time.Time) (int, error)) error {
+	if start.IsZero() {
+		_, err := fetch(time.Time{}, time.Time{})
+		return err
+	}
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+
+	fetched := 0
+	for from := start; !from.After(end) && fetched < limit; from = from.Add(span) {
+		to := from.Add(span - time.Millisecond)
+		if to.After(end) {
+			to = end
+		}
+
+		n, err := fetch(from, to)
+		if err != nil {
+			return err
+		}
+		fetched += n
+	}
+	return nil
+}
+
+// candleFromRow converts a [openTime, open, high, low, close, volume, ...]
+// kline row of string fields, the layout Bybit and OKX share
+func candleFromRow(symbol string, row []string, step time.Duration) (*domain.Candle, error) {
+	if len(row) < 6 {
+		return nil, fmt.Errorf("kline row has %d fields, want at least 6", len(row))
+	}
+	openTime, err := strconv.ParseInt(row[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kline open time %q: %w", row[0], err)
+	}
+
+	candle := &domain.Candle{
+		Symbol:   symbol,
+		OpenTime: domain.TimeFromMillis(openTime),
+	}
+	candle.CloseTime = candle.OpenTime.Add(step - time.Millisecond)
+	candle.Open, _ = strconv.ParseFloat(row[1], 64)
+	candle.High, _ = strconv.ParseFloat(row[2], 64)
+	candle.Low, _ = strconv.ParseFloat(row[3], 64)
+	candle.Close, _ = strconv.ParseFloat(row[4], 64)
+	candle.Volume, _ = strconv.ParseFloat(row[5], 64)
+	return candle, nil
+}
+
+// parseFloat parses a numeric string field, treating empty as zero
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// parseMillis parses a millisecond timestamp string field (zero time if empty)
+func parseMillis(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ms == 0 {
+		return time.Time{}
+	}
+	return domain.TimeFromMillis(ms)
+}
+
+// formatFloat formats a quantity or price for a request
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// truncate shortens s to at most n bytes for error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// markCancelled returns the open orders whose IDs a cancel-all response
+// listed, marked cancelled
+func markCancelled(open []*domain.Order, n int, cancelledID func(i int) string) []*domain.Order {
+	ids := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		ids[cancelledID(i)] = true
+	}
+
+	now := time.Now().UTC()
+	orders := make([]*domain.Order, 0, n)
+	for _, order := range open {
+		if ids[order.ID] {
+			order.Status = domain.OrderStatusCancelled
+			order.UpdatedAt = now
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
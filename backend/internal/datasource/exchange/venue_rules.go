@@ -0,0 +1,109 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// DefaultVenueRulesTTL is how long venue trading rules are cached
+const DefaultVenueRulesTTL = time.Hour
+
+// SymbolInfoSource looks up a venue's trading rules for one symbol
+type SymbolInfoSource interface {
+	GetSymbolInfo(ctx context.Context, symbol string) (*domain.SymbolInfo, error)
+}
+
+// VenueRules implements SymbolRules from a connector's own instrument info,
+// caching each symbol so orders don't cost an extra request
+type VenueRules struct {
+	source SymbolInfoSource
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	infos map[string]*domain.SymbolInfo
+}
+
+// NewVenueRules creates rules that load symbol info from source
+func NewVenueRules(source SymbolInfoSource) *VenueRules {
+	return &VenueRules{
+		source: source,
+		ttl:    DefaultVenueRulesTTL,
+		infos:  make(map[string]*domain.SymbolInfo),
+	}
+}
+
+// UseVenueRules makes a connector round and validate orders against its own
+// trading rules. It reports false for connectors without instrument info.
+func UseVenueRules(ex domain.Exchange) bool {
+	connector, ok := ex.(interface {
+		SymbolInfoSource
+		SetSymbolRules(rules SymbolRules)
+	})
+	if !ok {
+		return false
+	}
+	connector.SetSymbolRules(NewVenueRules(connector))
+	return true
+}
+
+// Get returns a symbol's info, loading it if it isn't cached or is stale
+func (r *VenueRules) Get(ctx context.Context, symbol string) (*domain.SymbolInfo, error) {
+	r.mu.Lock()
+	info := r.infos[symbol]
+	r.mu.Unlock()
+	if info != nil && time.Since(info.UpdatedAt) < r.ttl {
+		return info, nil
+	}
+
+	loaded, err := r.source.GetSymbolInfo(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s trading rules: %w", symbol, err)
+	}
+	r.mu.Lock()
+	r.infos[symbol] = loaded
+	r.mu.Unlock()
+	return loaded, nil
+}
+
+// PrepareOrder rounds an order's price and quantity to the symbol's rules and
+// validates it. refPrice is the expected fill price for market orders.
+// Orders that only reduce a position are exempt from the minimum notional.
+func (r *VenueRules) PrepareOrder(ctx context.Context, order *domain.Order, refPrice float64) error {
+	info, err := r.Get(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
+	if order.ReduceOnly || order.ClosePosition {
+		relaxed := *info
+		relaxed.MinNotional = 0
+		info = &relaxed
+	}
+	return symbols.ApplyRules(info, order, refPrice)
+}
+
+// applySymbolRules runs PrepareOrder on rules (if set), pricing market orders
+// at the current price. Violating orders are marked rejected.
+func applySymbolRules(ctx context.Context, rules SymbolRules, order *domain.Order, currentPrice func(ctx context.Context, symbol string) (float64, error)) error {
+	if rules == nil {
+		return nil
+	}
+
+	var refPrice float64
+	if order.Type == domain.OrderTypeMarket {
+		price, err := currentPrice(ctx, order.Symbol)
+		if err != nil {
+			return err
+		}
+		refPrice = price
+	}
+	if err := rules.PrepareOrder(ctx, order, refPrice); err != nil {
+		order.Status = domain.OrderStatusRejected
+		return fmt.Errorf("order violates trading rules: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package exchange
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Reconnect backoff for venue streams
+const (
+	venueStreamMinBackoff = time.Second
+	venueStreamMaxBackoff = time.Minute
+)
+
+// venueStream is a single-subscription stream on a venue that uses JSON
+// subscribe requests and application-level pings (Bybit, OKX)
+type venueStream struct {
+	name         string // For logs, e.g. "bybit kline.1.BTCUSDT"
+	endpoint     string
+	subscribe    []byte // Sent after every connect
+	ping         []byte // Sent every pingInterval
+	pingInterval time.Duration
+	tap          func(message []byte)
+}
+
+// run connects, subscribes and calls handler for every message until ctx is
+// done, reconnecting with exponential backoff. onReconnect (optional) is called
+// after each reconnect before any message is handled, e.g. to backfill.
+func (s venueStream) run(ctx context.Context, handler func([]byte), onReconnect func()) error {
+	backoff := venueStreamMinBackoff
+	for attempt := 0; ; attempt++ {
+		connectedAt := time.Now()
+		err := s.serve(ctx, handler, attempt > 0, onReconnect)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// A connection that stayed up for a while starts the backoff over
+		if time.Since(connectedAt) > venueStreamMaxBackoff {
+			backoff = venueStreamMinBackoff
+		}
+		log.Printf("⚠️  %s stream dropped: %v (reconnecting in %v)", s.name, err, backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+		backoff = min(backoff*2, venueStreamMaxBackoff)
+	}
+}
+
+// serve runs one connection until it fails or ctx is done
+func (s venueStream) serve(ctx context.Context, handler func([]byte), reconnect bool, onReconnect func()) error {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: wsHandshakeTimeout,
+	}
+	conn, _, err := dialer.DialContext(ctx, s.endpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetReadLimit(wsReadLimit)
+
+	if err := conn.WriteMessage(websocket.TextMessage, s.subscribe); err != nil {
+		return err
+	}
+	if reconnect && onReconnect != nil {
+		onReconnect()
+	}
+
+	// Writer: pings until the reader stops; also closes the connection on ctx done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(s.pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				conn.SetWriteDeadline(time.Now().Add(s.pingInterval))
+				if err := conn.WriteMessage(websocket.TextMessage, s.ping); err != nil {
+					conn.Close()
+					return
+				}
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		// Any message, pong included, proves the connection alive
+		conn.SetReadDeadline(time.Now().Add(2 * s.pingInterval))
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if s.tap != nil {
+			s.tap(message)
+		}
+		handler(message)
+	}
+}
+
+// klinesSince returns a source's klines opening at or after since, oldest first
+func klinesSince(ctx context.Context, source domain.KlineSource, symbol, interval string, since time.Time) ([]*domain.Candle, error) {
+	candles := make([]*domain.Candle, 0)
+	for {
+		page, err := source.GetKlines(ctx, symbol, interval, since, time.Now().UTC(), source.MaxKlineLimit())
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, page...)
+		if len(page) < source.MaxKlineLimit() {
+			return candles, nil
+		}
+		since = page[len(page)-1].OpenTime.Add(time.Millisecond)
+	}
+}
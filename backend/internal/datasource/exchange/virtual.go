@@ -3,26 +3,102 @@ package exchange
 import (
 	"context"
 	"fmt"
+	"log"
 	"math"
 	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
 	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/internal/portfolio/wallet"
+	"github.com/lavumi/crypto-quant/pkg/config"
 )
 
-// VirtualExchange implements a virtual exchange for testing and simulation
+// virtualEventBuffer is how many order events a slow StreamUserData subscriber may fall behind
+const virtualEventBuffer = 1024
+
+// VirtualConfig configures the virtual exchange's matching simulation
+type VirtualConfig struct {
+	// Wallet (optional) is the paper account: orders are checked against and
+	// reserve its balances, and fills settle into it. Without a wallet,
+	// balances aren't checked.
+	Wallet *wallet.Manager
+
+	Fees    *FeeSchedule  // nil charges Binance's base tier spot fees
+	Book    BookConfig    // Synthetic order book the orders fill against
+	Latency time.Duration // Delay before an order or cancel reaches the matching engine
+}
+
+// NewVirtualConfig converts the virtual exchange section of the config file
+// (the wallet is set by the caller)
+func NewVirtualConfig(cfg config.VirtualConfig) VirtualConfig {
+	virtualCfg := VirtualConfig{
+		Book: BookConfig{
+			Levels:        cfg.BookLevels,
+			Spread:        cfg.BookSpread,
+			LevelStep:     cfg.BookLevelStep,
+			LevelNotional: cfg.BookLevelNotional,
+		},
+		Latency: time.Duration(cfg.LatencyMs) * time.Millisecond,
+	}
+	if cfg.MakerFee != 0 || cfg.TakerFee != 0 {
+		virtualCfg.Fees = &FeeSchedule{Maker: cfg.MakerFee, Taker: cfg.TakerFee}
+	}
+	return virtualCfg
+}
+
+// virtualOrder is an order on the virtual exchange with its matching state
+type virtualOrder struct {
+	order     *domain.Order
+	triggered bool         // Stop orders: the stop price was reached
+	resting   bool         // Limit orders: past the first (taker) pass, so later fills are maker fills
+	reserve   *reservation // Shared by the legs of an OCO group
+}
+
+// reservation is wallet balance locked for open orders
+type reservation struct {
+	asset  string
+	amount float64
+}
+
+// VirtualExchange implements a virtual exchange for testing and simulation.
+// Orders match against a synthetic order book around a random-walk price:
+// market orders and marketable limit orders take liquidity level by level
+// (partially filling when the book runs out), resting limit orders fill as
+// maker once the price trades through them, and stop orders wait for their
+// stop price. Fills pay maker/taker fees in the received asset, like Binance spot.
 type VirtualExchange struct {
 	mu               sync.RWMutex
 	prices           map[string]float64
-	orders           map[string]*domain.Order
+	books            map[string]*syntheticBook
+	orders           map[string]*virtualOrder
+	open             []*virtualOrder   // Open orders, oldest first (price-time priority)
+	clientOrderIDs   map[string]string // client order ID -> order ID
+	trades           []*domain.Trade
+	nextOrderID      int64
+	nextTradeID      int64
+	nextOCOID        int64
 	priceSubscribers map[string][]chan float64
+	eventSubscribers map[chan *domain.AccountEvent]struct{}
+	wallet           *wallet.Manager
+	fees             FeeSchedule
+	book             BookConfig
+	latency          time.Duration
+	klineSubs        map[*klineSubscription]struct{}
+	rules            SymbolRules
+	replay           *Replay   // Set while stored market data drives the prices
+	clock            time.Time // Replay time of the last price point
 	closeCh          chan struct{}
 	closed           bool
 }
 
 // NewVirtualExchange creates a new virtual exchange
-func NewVirtualExchange(initialPrices map[string]float64) *VirtualExchange {
+func NewVirtualExchange(initialPrices map[string]float64, cfg VirtualConfig) *VirtualExchange {
 	if initialPrices == nil {
 		initialPrices = map[string]float64{
 			"BTCUSDT": 45000.0,
@@ -31,11 +107,26 @@ func NewVirtualExchange(initialPrices map[string]float64) *VirtualExchange {
 	}
 
 	ve := &VirtualExchange{
-		prices:           initialPrices,
-		orders:           make(map[string]*domain.Order),
+		prices:           make(map[string]float64, len(initialPrices)),
+		books:            make(map[string]*syntheticBook, len(initialPrices)),
+		orders:           make(map[string]*virtualOrder),
+		clientOrderIDs:   make(map[string]string),
+		trades:           make([]*domain.Trade, 0),
 		priceSubscribers: make(map[string][]chan float64),
+		eventSubscribers: make(map[chan *domain.AccountEvent]struct{}),
+		klineSubs:        make(map[*klineSubscription]struct{}),
+		wallet:           cfg.Wallet,
+		fees:             binanceFees,
+		book:             cfg.Book.withDefaults(),
+		latency:          cfg.Latency,
 		closeCh:          make(chan struct{}),
 	}
+	if cfg.Fees != nil {
+		ve.fees = *cfg.Fees
+	}
+	for symbol, price := range initialPrices {
+		ve.setPriceLocked(symbol, price)
+	}
 
 	// Start price simulation
 	go ve.simulatePriceUpdates()
@@ -51,19 +142,24 @@ func (ve *VirtualExchange) simulatePriceUpdates() {
 	for {
 		select {
 		case <-ticker.C:
-			ve.updatePrices()
-			ve.checkLimitOrders()
+			ve.deliverKlines(ve.updatePrices())
 		case <-ve.closeCh:
 			return
 		}
 	}
 }
 
-// updatePrices updates prices with random walk
-func (ve *VirtualExchange) updatePrices() {
+// updatePrices updates prices with random walk (unless a replay drives them)
+func (ve *VirtualExchange) updatePrices() []klineUpdate {
 	ve.mu.Lock()
 	defer ve.mu.Unlock()
 
+	if ve.replay != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	points := make([]pricePoint, 0, len(ve.prices))
 	for symbol, price := range ve.prices {
 		// Random walk: ±0.5% price change
 		change := (rand.Float64() - 0.5) * 0.01 * price
@@ -74,57 +170,287 @@ func (ve *VirtualExchange) updatePrices() {
 			newPrice = price
 		}
 
-		ve.prices[symbol] = newPrice
+		points = append(points, pricePoint{symbol: symbol, time: now, price: newPrice})
+	}
+	return ve.applyPointsLocked(points)
+}
+
+// applyPointsLocked moves prices to the given points: it refreshes the books,
+// notifies price subscribers, matches open orders and updates the forming
+// klines, which the caller delivers with deliverKlines once it has released ve.mu
+func (ve *VirtualExchange) applyPointsLocked(points []pricePoint) []klineUpdate {
+	updates := make([]klineUpdate, 0)
+	for _, p := range points {
+		if ve.replay != nil {
+			ve.clock = p.time
+		}
+		ve.setPriceLocked(p.symbol, p.price)
 
 		// Notify subscribers
-		if subscribers, ok := ve.priceSubscribers[symbol]; ok {
-			for _, ch := range subscribers {
-				select {
-				case ch <- newPrice:
-				default:
-					// Skip if channel is full
-				}
+		for _, ch := range ve.priceSubscribers[p.symbol] {
+			select {
+			case ch <- p.price:
+			default:
+				// Skip if channel is full
+			}
+		}
+
+		ve.matchOpenLocked(p.symbol)
+		for sub := range ve.klineSubs {
+			if sub.symbol == p.symbol {
+				updates = append(updates, klineUpdate{sub: sub, candle: sub.add(p)})
 			}
 		}
 	}
+	return updates
 }
 
-// checkLimitOrders checks if any limit orders should be filled
-func (ve *VirtualExchange) checkLimitOrders() {
-	ve.mu.Lock()
-	defer ve.mu.Unlock()
+// setPriceLocked sets a symbol's price and lays out a fresh book around it; the caller holds ve.mu
+func (ve *VirtualExchange) setPriceLocked(symbol string, price float64) {
+	ve.prices[symbol] = price
+	ve.books[symbol] = newSyntheticBook(price, ve.book)
+}
 
-	for _, order := range ve.orders {
-		if order.Status != domain.OrderStatusNew || order.Type != domain.OrderTypeLimit {
-			continue
+// matchOpenLocked matches a symbol's open orders (all symbols if empty), oldest
+// first, and drops finished orders from the open list; the caller holds ve.mu
+func (ve *VirtualExchange) matchOpenLocked(symbol string) {
+	// Matching can cancel OCO siblings, so iterate over a copy
+	for _, vo := range append([]*virtualOrder(nil), ve.open...) {
+		if vo.order.IsOpen() && (symbol == "" || vo.order.Symbol == symbol) {
+			ve.match(vo)
 		}
+	}
 
-		currentPrice, ok := ve.prices[order.Symbol]
-		if !ok {
-			continue
+	open := ve.open[:0]
+	for _, vo := range ve.open {
+		if vo.order.IsOpen() {
+			open = append(open, vo)
 		}
+	}
+	ve.open = open
+}
+
+// match triggers and fills an open order against its symbol's book
+func (ve *VirtualExchange) match(vo *virtualOrder) {
+	order := vo.order
+	book := ve.books[order.Symbol]
+
+	if order.Type.IsStop() && !vo.triggered {
+		if !stopReached(order, ve.prices[order.Symbol]) {
+			return
+		}
+		vo.triggered = true
+		ve.cancelSiblings(vo)
+	}
+
+	limit := 0.0
+	if order.Type == domain.OrderTypeLimit || order.Type == domain.OrderTypeStopLimit {
+		limit = order.Price
+	}
+	maker := vo.resting
+	fills := book.walk(order.Side, order.Quantity-order.FilledQty, limit, false)
+	if order.Type == domain.OrderTypeStopMarket && order.Side == domain.OrderSideBuy {
+		fills = ve.fundStopBuy(vo, fills)
+	}
+	book.walk(order.Side, fillsQuantity(fills), limit, true)
+
+	if maker {
+		// The market traded through the resting order's price: one fill at that price
+		if quantity := fillsQuantity(fills); quantity > virtualQtyEpsilon {
+			ve.fill(vo, quantity, order.Price, true)
+		}
+	} else {
+		for _, f := range fills {
+			ve.fill(vo, f.Quantity, f.Price, false)
+		}
+	}
+	if len(fills) > 0 {
+		ve.cancelSiblings(vo)
+	}
+	vo.resting = true
+
+	// Market orders don't rest: whatever the book couldn't fill expires
+	if order.IsOpen() && (order.Type == domain.OrderTypeMarket || order.Type == domain.OrderTypeStopMarket) {
+		ve.setStatus(vo, domain.OrderStatusExpired)
+	}
+	if !order.IsOpen() {
+		ve.release(vo)
+	}
+}
 
-		shouldFill := false
-		if order.Side == domain.OrderSideBuy && currentPrice <= order.Price {
-			shouldFill = true
-		} else if order.Side == domain.OrderSideSell && currentPrice >= order.Price {
-			shouldFill = true
+// fundStopBuy tops up a triggered stop-market buy's reservation (made at the
+// stop price) to the fills' cost, cutting the fills to what the wallet can pay
+func (ve *VirtualExchange) fundStopBuy(vo *virtualOrder, fills []domain.BookLevel) []domain.BookLevel {
+	if ve.wallet == nil {
+		return fills
+	}
+
+	need := fillsNotional(fills) - vo.reserve.amount
+	if need > 0 {
+		free := 0.0
+		if balance, ok := ve.wallet.GetAllBalances()[vo.reserve.asset]; ok {
+			free = balance.Free
+		}
+		extra := min(need, free)
+		if extra > 0 && ve.wallet.Lock(vo.reserve.asset, extra) == nil {
+			vo.reserve.amount += extra
 		}
+	}
 
-		if shouldFill {
-			ve.fillOrder(order, order.Price)
+	// Keep the fills the reservation pays for
+	budget := vo.reserve.amount
+	funded := make([]domain.BookLevel, 0, len(fills))
+	for _, f := range fills {
+		if cost := f.Price * f.Quantity; cost > budget {
+			f.Quantity = budget / f.Price
+			if f.Quantity > virtualQtyEpsilon {
+				funded = append(funded, f)
+			}
+			break
 		}
+		budget -= f.Price * f.Quantity
+		funded = append(funded, f)
 	}
+	return funded
 }
 
-// fillOrder fills an order
-func (ve *VirtualExchange) fillOrder(order *domain.Order, price float64) {
-	now := time.Now()
-	order.Status = domain.OrderStatusFilled
-	order.FilledQty = order.Quantity
-	order.AvgPrice = price
-	order.ExecutedAt = &now
+// fill executes quantity of an order at price, settles it in the wallet and records the trade.
+// Fees are charged in the received asset: base for buys, quote for sells.
+func (ve *VirtualExchange) fill(vo *virtualOrder, quantity, price float64, maker bool) {
+	order := vo.order
+	now := ve.now()
+	base, quote := splitVirtualSymbol(order.Symbol)
+	notional := quantity * price
+	fee := ve.fees.Fee(notional, maker)
+
+	ve.nextTradeID++
+	trade := &domain.Trade{
+		ID:        strconv.FormatInt(ve.nextTradeID, 10),
+		OrderID:   order.ID,
+		Symbol:    order.Symbol,
+		Side:      order.Side,
+		Price:     price,
+		Quantity:  quantity,
+		IsMaker:   maker,
+		Timestamp: now,
+	}
+	if order.Side == domain.OrderSideBuy {
+		trade.Fee, trade.FeeAsset = fee/price, base
+		ve.settle(vo, quote, notional, base, quantity-trade.Fee)
+	} else {
+		trade.Fee, trade.FeeAsset = fee, quote
+		ve.settle(vo, base, quantity, quote, notional-fee)
+	}
+	ve.trades = append(ve.trades, trade)
+
+	order.AvgPrice = (order.AvgPrice*order.FilledQty + price*quantity) / (order.FilledQty + quantity)
+	order.FilledQty += quantity
 	order.UpdatedAt = now
+	if order.Quantity-order.FilledQty <= virtualQtyEpsilon {
+		order.FilledQty = order.Quantity
+		order.Status = domain.OrderStatusFilled
+		order.ExecutedAt = &now
+	} else {
+		order.Status = domain.OrderStatusPartiallyFilled
+	}
+
+	ve.emit(order, trade)
+}
+
+// settle pays for a fill from the order's reservation and credits what it bought
+func (ve *VirtualExchange) settle(vo *virtualOrder, fromAsset string, fromAmount float64, toAsset string, toAmount float64) {
+	if ve.wallet == nil {
+		return
+	}
+
+	// Float noise can leave the reservation a hair short
+	spend := min(fromAmount, vo.reserve.amount)
+	if err := ve.wallet.Transfer(fromAsset, spend, toAsset, toAmount); err != nil {
+		log.Printf("⚠️  Virtual fill settlement failed for order %s: %v", vo.order.Ref(), err)
+		return
+	}
+	vo.reserve.amount -= spend
+}
+
+// release unlocks what is left of a finished order's reservation, unless
+// another open leg of its OCO group still needs it
+func (ve *VirtualExchange) release(vo *virtualOrder) {
+	if ve.wallet == nil || vo.reserve.amount <= 0 {
+		return
+	}
+	for _, other := range ve.open {
+		if other != vo && other.reserve == vo.reserve && other.order.IsOpen() {
+			return
+		}
+	}
+
+	if err := ve.wallet.Unlock(vo.reserve.asset, vo.reserve.amount); err != nil {
+		log.Printf("⚠️  Failed to release reservation of order %s: %v", vo.order.Ref(), err)
+	}
+	vo.reserve.amount = 0
+}
+
+// setStatus ends an open order with status and emits the update
+func (ve *VirtualExchange) setStatus(vo *virtualOrder, status domain.OrderStatus) {
+	vo.order.Status = status
+	vo.order.UpdatedAt = ve.now()
+	ve.emit(vo.order, nil)
+}
+
+// cancelSiblings cancels the other open legs of an order's OCO group
+func (ve *VirtualExchange) cancelSiblings(vo *virtualOrder) {
+	for _, leg := range ve.group(vo) {
+		if leg != vo && leg.order.IsOpen() {
+			ve.setStatus(leg, domain.OrderStatusCancelled)
+		}
+	}
+}
+
+// group returns an order's OCO group (just the order if it has none)
+func (ve *VirtualExchange) group(vo *virtualOrder) []*virtualOrder {
+	if vo.order.OCOGroup == "" {
+		return []*virtualOrder{vo}
+	}
+	legs := make([]*virtualOrder, 0, 2)
+	for _, other := range ve.open {
+		if other.order.OCOGroup == vo.order.OCOGroup {
+			legs = append(legs, other)
+		}
+	}
+	return legs
+}
+
+// emit sends an order update (and its fill, if any) to StreamUserData subscribers
+func (ve *VirtualExchange) emit(order *domain.Order, fill *domain.Trade) {
+	if len(ve.eventSubscribers) == 0 {
+		return
+	}
+
+	orderCopy := *order
+	ev := &domain.AccountEvent{Time: order.UpdatedAt, Order: &orderCopy}
+	if fill != nil {
+		fillCopy := *fill
+		ev.Fill = &fillCopy
+	}
+	if ve.wallet != nil {
+		balances := ve.wallet.GetAllBalances()
+		base, quote := splitVirtualSymbol(order.Symbol)
+		for _, asset := range []string{base, quote} {
+			if b, ok := balances[asset]; ok {
+				ev.Balances = append(ev.Balances, b)
+			} else {
+				ev.Balances = append(ev.Balances, &domain.Balance{Asset: asset})
+			}
+		}
+	}
+
+	for ch := range ve.eventSubscribers {
+		select {
+		case ch <- ev:
+		default:
+			log.Printf("⚠️  Virtual exchange event for order %s dropped: subscriber too slow", order.Ref())
+		}
+	}
 }
 
 // GetCurrentPrice returns the current market price
@@ -140,14 +466,35 @@ func (ve *VirtualExchange) GetCurrentPrice(ctx context.Context, symbol string) (
 	return price, nil
 }
 
-// GetCandles retrieves historical candlestick data
+// GetOrderBook returns the liquidity left in a symbol's synthetic book
+func (ve *VirtualExchange) GetOrderBook(ctx context.Context, symbol string) (*domain.OrderBookSnapshot, error) {
+	ve.mu.RLock()
+	defer ve.mu.RUnlock()
+
+	book, ok := ve.books[symbol]
+	if !ok {
+		return nil, fmt.Errorf("symbol not found: %s", symbol)
+	}
+	return book.snapshot(symbol, ve.now()), nil
+}
+
+// GetCandles retrieves historical candlestick data. During a replay these are
+// the stored bars that closed before the replay time; otherwise they are flat
+// bars around the current price.
 func (ve *VirtualExchange) GetCandles(ctx context.Context, symbol, interval string, limit int) ([]*domain.Candle, error) {
+	ve.mu.RLock()
+	replay := ve.replay
+	ve.mu.RUnlock()
+	if replay != nil {
+		return replay.candles(ctx, symbol, interval, limit)
+	}
+
 	price, err := ve.GetCurrentPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now()
+	now := time.Now().UTC()
 	candles := make([]*domain.Candle, limit)
 	for i := 0; i < limit; i++ {
 		candles[i] = &domain.Candle{
@@ -165,8 +512,29 @@ func (ve *VirtualExchange) GetCandles(ctx context.Context, symbol, interval stri
 	return candles, nil
 }
 
-// PlaceOrder submits a new order
+// Fees returns the fee schedule fills are charged with
+func (ve *VirtualExchange) Fees() FeeSchedule {
+	return ve.fees
+}
+
+// SetSymbolRules makes PlaceOrder round and validate orders like the real exchange
+func (ve *VirtualExchange) SetSymbolRules(rules SymbolRules) {
+	ve.mu.Lock()
+	defer ve.mu.Unlock()
+	ve.rules = rules
+}
+
+// PlaceOrder submits a new order after the configured latency. Resubmitting a
+// client order ID returns the existing order. Orders that need more than the
+// wallet's free balance are rejected; accepted orders reserve it until they finish.
 func (ve *VirtualExchange) PlaceOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	if err := ve.delay(ctx); err != nil {
+		return nil, err
+	}
+	if err := ve.prepareOrder(ctx, order); err != nil {
+		return order, err
+	}
+
 	ve.mu.Lock()
 	defer ve.mu.Unlock()
 
@@ -174,70 +542,492 @@ func (ve *VirtualExchange) PlaceOrder(ctx context.Context, order *domain.Order)
 		return nil, fmt.Errorf("exchange is closed")
 	}
 
-	// Generate order ID if not provided
+	if id, ok := ve.clientOrderIDs[order.ClientOrderID]; ok {
+		existing := *ve.orders[id].order
+		return &existing, nil
+	}
+
+	// Generate order IDs if not provided
 	if order.ID == "" {
-		order.ID = fmt.Sprintf("ORDER_%d", time.Now().UnixNano())
+		ve.nextOrderID++
+		order.ID = strconv.FormatInt(ve.nextOrderID, 10)
 	}
+	assignClientOrderID(order)
 
-	// Validate order
-	if order.Quantity <= 0 {
+	if err := ve.validateOrder(order); err != nil {
 		order.Status = domain.OrderStatusRejected
-		return order, fmt.Errorf("invalid quantity: %f", order.Quantity)
+		return order, err
 	}
 
-	currentPrice, ok := ve.prices[order.Symbol]
-	if !ok {
+	asset, amount := ve.requirement(order)
+	reserve, err := ve.reserve(asset, amount)
+	if err != nil {
 		order.Status = domain.OrderStatusRejected
-		return order, fmt.Errorf("symbol not found: %s", order.Symbol)
+		return order, err
 	}
 
-	now := time.Now()
-	order.Status = domain.OrderStatusNew
-	order.CreatedAt = now
-	order.UpdatedAt = now
+	vo := ve.accept(order, reserve)
+	ve.match(vo)
+
+	*order = *vo.order
+	return order, nil
+}
 
-	// Market orders are filled immediately
-	if order.Type == domain.OrderTypeMarket {
-		ve.fillOrder(order, currentPrice)
+// PlaceOCO places a one-cancels-the-other pair on the same symbol and side: a
+// limit order above (sell) or below (buy) the price and a stop order on the
+// other side of it. When either leg fills or triggers, the other is cancelled;
+// cancelling one leg cancels both. The legs share one reservation.
+func (ve *VirtualExchange) PlaceOCO(ctx context.Context, limitOrder, stopOrder *domain.Order) ([]*domain.Order, error) {
+	if err := ve.delay(ctx); err != nil {
+		return nil, err
+	}
+	for _, order := range []*domain.Order{limitOrder, stopOrder} {
+		if err := ve.prepareOrder(ctx, order); err != nil {
+			return nil, err
+		}
 	}
 
-	// Store order
-	ve.orders[order.ID] = order
+	ve.mu.Lock()
+	defer ve.mu.Unlock()
 
-	return order, nil
+	if ve.closed {
+		return nil, fmt.Errorf("exchange is closed")
+	}
+
+	if id, ok := ve.clientOrderIDs[limitOrder.ClientOrderID]; ok {
+		legs := make([]*domain.Order, 0, 2)
+		for _, leg := range ve.ocoLegs(ve.orders[id]) {
+			order := *leg.order
+			legs = append(legs, &order)
+		}
+		return legs, nil
+	}
+
+	if err := ve.validateOCO(limitOrder, stopOrder); err != nil {
+		return nil, err
+	}
+
+	ve.nextOCOID++
+	group := "oco-" + strconv.FormatInt(ve.nextOCOID, 10)
+	for _, order := range []*domain.Order{limitOrder, stopOrder} {
+		if order.ID == "" {
+			ve.nextOrderID++
+			order.ID = strconv.FormatInt(ve.nextOrderID, 10)
+		}
+		order.OCOGroup = group
+		assignClientOrderID(order)
+	}
+
+	// Both legs sell the same base or spend the same quote, so reserve the larger need once
+	asset, limitNeed := ve.requirement(limitOrder)
+	_, stopNeed := ve.requirement(stopOrder)
+	reserve, err := ve.reserve(asset, max(limitNeed, stopNeed))
+	if err != nil {
+		return nil, err
+	}
+
+	legs := []*virtualOrder{ve.accept(limitOrder, reserve), ve.accept(stopOrder, reserve)}
+	for _, leg := range legs {
+		if leg.order.IsOpen() {
+			ve.match(leg)
+		}
+	}
+
+	*limitOrder = *legs[0].order
+	*stopOrder = *legs[1].order
+	return []*domain.Order{limitOrder, stopOrder}, nil
+}
+
+// prepareOrder applies the symbol rules, if any. Rules may need to load
+// exchange info, so this runs before taking the lock.
+func (ve *VirtualExchange) prepareOrder(ctx context.Context, order *domain.Order) error {
+	ve.mu.RLock()
+	rules := ve.rules
+	refPrice := ve.prices[order.Symbol]
+	ve.mu.RUnlock()
+
+	if rules == nil {
+		return nil
+	}
+	if err := rules.PrepareOrder(ctx, order, refPrice); err != nil {
+		order.Status = domain.OrderStatusRejected
+		return fmt.Errorf("order violates trading rules: %w", err)
+	}
+	return nil
+}
+
+// validateOrder checks an order against its type's required fields and the current price
+func (ve *VirtualExchange) validateOrder(order *domain.Order) error {
+	if order.Quantity <= 0 {
+		return fmt.Errorf("invalid quantity: %f", order.Quantity)
+	}
+
+	price, ok := ve.prices[order.Symbol]
+	if !ok {
+		return fmt.Errorf("symbol not found: %s", order.Symbol)
+	}
+	if _, quote := splitVirtualSymbol(order.Symbol); ve.wallet != nil && quote == "" {
+		return fmt.Errorf("unknown quote asset for symbol: %s", order.Symbol)
+	}
+
+	switch order.Type {
+	case domain.OrderTypeMarket:
+	case domain.OrderTypeLimit:
+		if order.Price <= 0 {
+			return fmt.Errorf("limit order needs a price")
+		}
+	case domain.OrderTypeStopMarket, domain.OrderTypeStopLimit:
+		if order.StopPrice <= 0 {
+			return fmt.Errorf("stop order needs a stop price")
+		}
+		if order.Type == domain.OrderTypeStopLimit && order.Price <= 0 {
+			return fmt.Errorf("stop limit order needs a price")
+		}
+		// Like Binance, refuse stops that would trigger right away
+		if stopReached(order, price) {
+			return fmt.Errorf("stop price %.8f would trigger immediately (price %.8f)", order.StopPrice, price)
+		}
+	default:
+		return fmt.Errorf("order type %s is not supported", order.Type)
+	}
+	return nil
+}
+
+// validateOCO checks that two orders form a valid OCO pair
+func (ve *VirtualExchange) validateOCO(limitOrder, stopOrder *domain.Order) error {
+	if limitOrder.Symbol != stopOrder.Symbol || limitOrder.Side != stopOrder.Side {
+		return fmt.Errorf("OCO legs need the same symbol and side")
+	}
+	if limitOrder.Type != domain.OrderTypeLimit || !stopOrder.Type.IsStop() {
+		return fmt.Errorf("OCO needs a limit order and a stop order")
+	}
+	for _, order := range []*domain.Order{limitOrder, stopOrder} {
+		if err := ve.validateOrder(order); err != nil {
+			return err
+		}
+	}
+
+	price := ve.prices[limitOrder.Symbol]
+	if limitOrder.Side == domain.OrderSideSell && limitOrder.Price <= price {
+		return fmt.Errorf("sell OCO limit price must be above the price (%.8f)", price)
+	}
+	if limitOrder.Side == domain.OrderSideBuy && limitOrder.Price >= price {
+		return fmt.Errorf("buy OCO limit price must be below the price (%.8f)", price)
+	}
+	return nil
+}
+
+// requirement returns the asset and amount an order reserves: the base
+// quantity for sells, the quote cost for buys (at the limit price, the stop
+// price for stop-market orders, or the book's current cost for market orders)
+func (ve *VirtualExchange) requirement(order *domain.Order) (string, float64) {
+	base, quote := splitVirtualSymbol(order.Symbol)
+	if order.Side == domain.OrderSideSell {
+		return base, order.Quantity
+	}
+
+	switch order.Type {
+	case domain.OrderTypeMarket:
+		fills := ve.books[order.Symbol].walk(order.Side, order.Quantity, 0, false)
+		return quote, fillsNotional(fills)
+	case domain.OrderTypeStopMarket:
+		return quote, order.Quantity * order.StopPrice
+	default:
+		return quote, order.Quantity * order.Price
+	}
+}
+
+// reserve locks amount of asset in the wallet (nothing without a wallet)
+func (ve *VirtualExchange) reserve(asset string, amount float64) (*reservation, error) {
+	if ve.wallet == nil {
+		return &reservation{asset: asset}, nil
+	}
+	if err := ve.wallet.Lock(asset, amount); err != nil {
+		return nil, fmt.Errorf("insufficient balance: %w", err)
+	}
+	return &reservation{asset: asset, amount: amount}, nil
+}
+
+// accept stores a validated order as open and emits it
+func (ve *VirtualExchange) accept(order *domain.Order, reserve *reservation) *virtualOrder {
+	now := ve.now()
+	stored := *order
+	stored.Status = domain.OrderStatusNew
+	stored.FilledQty = 0
+	stored.AvgPrice = 0
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+
+	vo := &virtualOrder{order: &stored, reserve: reserve}
+	ve.orders[stored.ID] = vo
+	ve.clientOrderIDs[stored.ClientOrderID] = stored.ID
+	ve.open = append(ve.open, vo)
+	ve.emit(vo.order, nil)
+	return vo
+}
+
+// ocoLegs returns every leg of an order's OCO group, open or not
+func (ve *VirtualExchange) ocoLegs(vo *virtualOrder) []*virtualOrder {
+	if vo.order.OCOGroup == "" {
+		return []*virtualOrder{vo}
+	}
+	legs := make([]*virtualOrder, 0, 2)
+	for _, other := range ve.orders {
+		if other.order.OCOGroup == vo.order.OCOGroup {
+			legs = append(legs, other)
+		}
+	}
+	sort.Slice(legs, func(i, j int) bool { return legs[i].order.CreatedAt.Before(legs[j].order.CreatedAt) })
+	return legs
 }
 
 // GetOrder retrieves order information
-func (ve *VirtualExchange) GetOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+func (ve *VirtualExchange) GetOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
 	ve.mu.RLock()
 	defer ve.mu.RUnlock()
 
-	order, ok := ve.orders[orderID]
-	if !ok {
-		return nil, fmt.Errorf("order not found: %s", orderID)
+	vo, err := ve.findOrder(ref)
+	if err != nil {
+		return nil, err
 	}
 
-	return order, nil
+	result := *vo.order
+	return &result, nil
+}
+
+// CancelOrder cancels an existing order (and the rest of its OCO group) after the configured latency
+func (ve *VirtualExchange) CancelOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	if err := ve.delay(ctx); err != nil {
+		return nil, err
+	}
+
+	ve.mu.Lock()
+	defer ve.mu.Unlock()
+
+	vo, err := ve.findOrder(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if !vo.order.IsOpen() {
+		return nil, fmt.Errorf("cannot cancel order with status: %s", vo.order.Status)
+	}
+
+	legs := ve.group(vo)
+	for _, leg := range legs {
+		ve.setStatus(leg, domain.OrderStatusCancelled)
+	}
+	for _, leg := range legs {
+		ve.release(leg)
+	}
+
+	result := *vo.order
+	return &result, nil
+}
+
+// GetOpenOrders lists open orders for a symbol (all symbols if empty)
+func (ve *VirtualExchange) GetOpenOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	ve.mu.RLock()
+	defer ve.mu.RUnlock()
+
+	return ve.listOrders(func(o *domain.Order) bool {
+		return o.IsOpen() && (symbol == "" || o.Symbol == symbol)
+	}), nil
 }
 
-// CancelOrder cancels an existing order
-func (ve *VirtualExchange) CancelOrder(ctx context.Context, orderID string) error {
+// CancelAllOrders cancels every open order for a symbol
+func (ve *VirtualExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	if err := ve.delay(ctx); err != nil {
+		return nil, err
+	}
+
 	ve.mu.Lock()
 	defer ve.mu.Unlock()
 
-	order, ok := ve.orders[orderID]
-	if !ok {
-		return fmt.Errorf("order not found: %s", orderID)
+	cancelled := make([]*virtualOrder, 0)
+	for _, vo := range ve.open {
+		if vo.order.Symbol == symbol && vo.order.IsOpen() {
+			ve.setStatus(vo, domain.OrderStatusCancelled)
+			cancelled = append(cancelled, vo)
+		}
 	}
 
-	if order.Status != domain.OrderStatusNew {
-		return fmt.Errorf("cannot cancel order with status: %s", order.Status)
+	orders := make([]*domain.Order, 0, len(cancelled))
+	for _, vo := range cancelled {
+		ve.release(vo)
+		result := *vo.order
+		orders = append(orders, &result)
 	}
+	return orders, nil
+}
 
-	order.Status = domain.OrderStatusCancelled
-	order.UpdatedAt = time.Now()
+// GetOrderHistory lists a symbol's orders of any status created in [start, end]
+func (ve *VirtualExchange) GetOrderHistory(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Order, error) {
+	ve.mu.RLock()
+	defer ve.mu.RUnlock()
 
-	return nil
+	orders := ve.listOrders(func(o *domain.Order) bool {
+		return o.Symbol == symbol && inHistoryRange(o.CreatedAt, start, end)
+	})
+	return limitHistory(orders, start, historyLimit(limit)), nil
+}
+
+// GetMyTrades lists a symbol's executions in [start, end]
+func (ve *VirtualExchange) GetMyTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	ve.mu.RLock()
+	defer ve.mu.RUnlock()
+
+	// Trades are appended in execution order
+	trades := make([]*domain.Trade, 0)
+	for _, t := range ve.trades {
+		if t.Symbol == symbol && inHistoryRange(t.Timestamp, start, end) {
+			trade := *t
+			trades = append(trades, &trade)
+		}
+	}
+	return limitHistory(trades, start, historyLimit(limit)), nil
+}
+
+// GetBalances returns the wallet's balances (none without a wallet)
+func (ve *VirtualExchange) GetBalances(ctx context.Context) ([]*domain.Balance, error) {
+	balances := make([]*domain.Balance, 0)
+	if ve.wallet == nil {
+		return balances, nil
+	}
+	for _, b := range ve.wallet.GetAllBalances() {
+		balances = append(balances, b)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Asset < balances[j].Asset })
+	return balances, nil
+}
+
+// StreamUserData streams order updates, fills and the balances they change,
+// like a real venue's user-data stream, until ctx is done or the exchange is
+// closed. The first event is a balance snapshot.
+func (ve *VirtualExchange) StreamUserData(ctx context.Context, callback func(*domain.AccountEvent)) error {
+	ch := make(chan *domain.AccountEvent, virtualEventBuffer)
+
+	// Subscribe and snapshot under the lock so no update falls between the two
+	ve.mu.Lock()
+	if ve.closed {
+		ve.mu.Unlock()
+		return fmt.Errorf("exchange is closed")
+	}
+	ve.eventSubscribers[ch] = struct{}{}
+	balances, _ := ve.GetBalances(ctx)
+	now := ve.now()
+	ve.mu.Unlock()
+
+	defer func() {
+		ve.mu.Lock()
+		delete(ve.eventSubscribers, ch)
+		ve.mu.Unlock()
+	}()
+
+	callback(&domain.AccountEvent{Time: now, Snapshot: true, Balances: balances})
+	for {
+		select {
+		case ev := <-ch:
+			callback(ev)
+		case <-ctx.Done():
+			return nil
+		case <-ve.closeCh:
+			return nil
+		}
+	}
+}
+
+// findOrder looks an order up by exchange ID or client order ID
+func (ve *VirtualExchange) findOrder(ref domain.OrderRef) (*virtualOrder, error) {
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+
+	id := ref.OrderID
+	if id == "" {
+		id = ve.clientOrderIDs[ref.ClientOrderID]
+	}
+
+	vo, ok := ve.orders[id]
+	if !ok || vo.order.Symbol != ref.Symbol {
+		return nil, fmt.Errorf("order not found: %s", ref)
+	}
+	return vo, nil
+}
+
+// listOrders returns copies of the orders matching keep, oldest first
+func (ve *VirtualExchange) listOrders(keep func(*domain.Order) bool) []*domain.Order {
+	orders := make([]*domain.Order, 0)
+	for _, vo := range ve.orders {
+		if keep(vo.order) {
+			result := *vo.order
+			orders = append(orders, &result)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+	return orders
+}
+
+// delay waits out the configured latency, like a request travelling to a real venue
+func (ve *VirtualExchange) delay(ctx context.Context) error {
+	if ve.latency <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(ve.latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ve.closeCh:
+		return fmt.Errorf("exchange is closed")
+	}
+}
+
+// now returns the exchange's current time: the replay time during a replay,
+// the wall clock otherwise. The caller holds ve.mu.
+func (ve *VirtualExchange) now() time.Time {
+	if ve.replay != nil {
+		return ve.clock
+	}
+	return time.Now().UTC()
+}
+
+// stopReached reports whether price has reached a stop order's stop price:
+// buy stops trigger at or above it, sell stops at or below
+func stopReached(order *domain.Order, price float64) bool {
+	if order.Side == domain.OrderSideBuy {
+		return price >= order.StopPrice
+	}
+	return price <= order.StopPrice
+}
+
+// splitVirtualSymbol splits a symbol into base and quote assets (empty quote if unknown)
+func splitVirtualSymbol(symbol string) (base, quote string) {
+	base = symbols.SplitSymbol(symbol)
+	return base, strings.TrimPrefix(symbol, base)
+}
+
+// inHistoryRange reports whether t is in [start, end], where zero bounds are open
+func inHistoryRange(t, start, end time.Time) bool {
+	return (start.IsZero() || !t.Before(start)) && (end.IsZero() || !t.After(end))
+}
+
+// limitHistory keeps the first limit items from start, or the most recent limit items without one
+func limitHistory[T any](items []T, start time.Time, limit int) []T {
+	if len(items) <= limit {
+		return items
+	}
+	if start.IsZero() {
+		return items[len(items)-limit:]
+	}
+	return items[:limit]
 }
 
 // SubscribePrice subscribes to price updates
@@ -258,6 +1048,107 @@ func (ve *VirtualExchange) SubscribePrice(ctx context.Context, symbol string) (<
 	return ch, nil
 }
 
+// pricePoint is a price move of a symbol: a random-walk tick, a replayed trade
+// or a point on a replayed candle's path
+type pricePoint struct {
+	symbol string
+	time   time.Time
+	price  float64
+	volume float64
+}
+
+// klineSubscription builds a StreamKlines subscriber's klines from price points
+type klineSubscription struct {
+	symbol   string
+	interval time.Duration
+	current  *domain.Candle // Forming kline
+
+	mu       sync.Mutex // Held while the callback runs, so none runs after StreamKlines returns
+	callback func(*domain.Candle)
+	done     bool
+}
+
+// add folds a price point into the forming kline, starting a new one when the
+// point falls into another bucket, and returns a copy to deliver
+func (sub *klineSubscription) add(p pricePoint) *domain.Candle {
+	openTime := history.AlignTime(p.time, sub.interval)
+	if sub.current == nil || !sub.current.OpenTime.Equal(openTime) {
+		sub.current = &domain.Candle{
+			Symbol:    sub.symbol,
+			OpenTime:  openTime,
+			CloseTime: openTime.Add(sub.interval - time.Millisecond),
+			Open:      p.price,
+			High:      p.price,
+			Low:       p.price,
+		}
+	}
+
+	c := sub.current
+	c.High = max(c.High, p.price)
+	c.Low = min(c.Low, p.price)
+	c.Close = p.price
+	c.Volume += p.volume
+
+	candle := *c
+	return &candle
+}
+
+// klineUpdate is a kline to deliver to a subscriber
+type klineUpdate struct {
+	sub    *klineSubscription
+	candle *domain.Candle
+}
+
+// deliverKlines runs the subscribers' callbacks; the caller must not hold ve.mu,
+// so callbacks can trade on the exchange
+func (ve *VirtualExchange) deliverKlines(updates []klineUpdate) {
+	for _, u := range updates {
+		u.sub.mu.Lock()
+		if !u.sub.done {
+			u.sub.callback(u.candle)
+		}
+		u.sub.mu.Unlock()
+	}
+}
+
+// StreamKlines streams the forming kline of a symbol after every price move
+// until ctx is done or the exchange is closed, like a venue's kline stream: a
+// bar is closed once a kline with a later open time arrives. During a replay
+// the klines rebuild the stored market at the replay's pace.
+func (ve *VirtualExchange) StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error {
+	d, err := history.ParseInterval(interval)
+	if err != nil {
+		return err
+	}
+
+	sub := &klineSubscription{symbol: symbol, interval: d, callback: callback}
+	ve.mu.Lock()
+	if ve.closed {
+		ve.mu.Unlock()
+		return fmt.Errorf("exchange is closed")
+	}
+	if _, ok := ve.prices[symbol]; !ok {
+		ve.mu.Unlock()
+		return fmt.Errorf("symbol not found: %s", symbol)
+	}
+	ve.klineSubs[sub] = struct{}{}
+	ve.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-ve.closeCh:
+	}
+
+	ve.mu.Lock()
+	delete(ve.klineSubs, sub)
+	ve.mu.Unlock()
+
+	sub.mu.Lock()
+	sub.done = true
+	sub.mu.Unlock()
+	return nil
+}
+
 // Close closes the exchange connection
 func (ve *VirtualExchange) Close() error {
 	ve.mu.Lock()
@@ -280,14 +1171,15 @@ func (ve *VirtualExchange) Close() error {
 	return nil
 }
 
-// SetPrice sets the price for a symbol (for testing purposes)
+// SetPrice sets the price for a symbol (for testing purposes), refreshes its
+// book and matches its open orders against it
 func (ve *VirtualExchange) SetPrice(symbol string, price float64) {
-	ve.mu.Lock()
-	defer ve.mu.Unlock()
-
 	if price <= 0 {
 		return
 	}
 
-	ve.prices[symbol] = math.Round(price*100) / 100
+	ve.mu.Lock()
+	updates := ve.applyPointsLocked([]pricePoint{{symbol: symbol, time: ve.now(), price: math.Round(price*100) / 100}})
+	ve.mu.Unlock()
+	ve.deliverKlines(updates)
 }
@@ -0,0 +1,154 @@
+package exchange
+
+import (
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Default synthetic order book shape
+const (
+	defaultBookLevels        = 10
+	defaultBookSpread        = 0.0002  // 2 bps between best bid and best ask
+	defaultBookLevelStep     = 0.0005  // 5 bps between levels
+	defaultBookLevelNotional = 50000.0 // Quote value per level
+)
+
+// virtualQtyEpsilon ignores float noise left over after partial fills
+const virtualQtyEpsilon = 1e-12
+
+// BookConfig shapes the synthetic order book the virtual exchange matches
+// orders against. Zero fields use the defaults.
+type BookConfig struct {
+	Levels        int     // Price levels per side
+	Spread        float64 // Best ask minus best bid as a fraction of the price
+	LevelStep     float64 // Gap between levels as a fraction of the price
+	LevelNotional float64 // Quote value resting at each level
+}
+
+// withDefaults fills unset fields with the defaults
+func (c BookConfig) withDefaults() BookConfig {
+	if c.Levels <= 0 {
+		c.Levels = defaultBookLevels
+	}
+	if c.Spread <= 0 {
+		c.Spread = defaultBookSpread
+	}
+	if c.LevelStep <= 0 {
+		c.LevelStep = defaultBookLevelStep
+	}
+	if c.LevelNotional <= 0 {
+		c.LevelNotional = defaultBookLevelNotional
+	}
+	return c
+}
+
+// syntheticBook is liquidity laid out around the last price. It is rebuilt on
+// every price update, so liquidity taken by orders only returns with the next tick.
+type syntheticBook struct {
+	bids []domain.BookLevel // Best (highest) first
+	asks []domain.BookLevel // Best (lowest) first
+}
+
+// newSyntheticBook lays out cfg.Levels levels per side around price
+func newSyntheticBook(price float64, cfg BookConfig) *syntheticBook {
+	b := &syntheticBook{
+		bids: make([]domain.BookLevel, cfg.Levels),
+		asks: make([]domain.BookLevel, cfg.Levels),
+	}
+	for i := 0; i < cfg.Levels; i++ {
+		offset := cfg.Spread/2 + float64(i)*cfg.LevelStep
+		ask := price * (1 + offset)
+		bid := price * (1 - offset)
+		b.asks[i] = domain.BookLevel{Price: ask, Quantity: cfg.LevelNotional / ask}
+		b.bids[i] = domain.BookLevel{Price: bid, Quantity: cfg.LevelNotional / bid}
+	}
+	return b
+}
+
+// levels returns the side of the book an order on side trades against
+func (b *syntheticBook) levels(side domain.OrderSide) []domain.BookLevel {
+	if side == domain.OrderSideBuy {
+		return b.asks
+	}
+	return b.bids
+}
+
+// walk returns the fills an order would get for quantity, best price first,
+// stopping at limit (0 for any price). consume removes the liquidity.
+func (b *syntheticBook) walk(side domain.OrderSide, quantity, limit float64, consume bool) []domain.BookLevel {
+	levels := b.levels(side)
+	fills := make([]domain.BookLevel, 0)
+	for i := range levels {
+		if quantity <= virtualQtyEpsilon {
+			break
+		}
+		level := &levels[i]
+		if limit > 0 && ((side == domain.OrderSideBuy && level.Price > limit) || (side == domain.OrderSideSell && level.Price < limit)) {
+			break
+		}
+		if level.Quantity <= virtualQtyEpsilon {
+			continue
+		}
+
+		qty := min(quantity, level.Quantity)
+		fills = append(fills, domain.BookLevel{Price: level.Price, Quantity: qty})
+		quantity -= qty
+		if consume {
+			level.Quantity -= qty
+		}
+	}
+	return fills
+}
+
+// snapshot returns the book's remaining liquidity
+func (b *syntheticBook) snapshot(symbol string, at time.Time) *domain.OrderBookSnapshot {
+	snap := &domain.OrderBookSnapshot{
+		Symbol: symbol,
+		Time:   at,
+		Bids:   make([]domain.BookLevel, 0, len(b.bids)),
+		Asks:   make([]domain.BookLevel, 0, len(b.asks)),
+	}
+	var bidQty, askQty float64
+	for _, level := range b.bids {
+		if level.Quantity > virtualQtyEpsilon {
+			snap.Bids = append(snap.Bids, level)
+			bidQty += level.Quantity
+		}
+	}
+	for _, level := range b.asks {
+		if level.Quantity > virtualQtyEpsilon {
+			snap.Asks = append(snap.Asks, level)
+			askQty += level.Quantity
+		}
+	}
+
+	if len(snap.Bids) > 0 && len(snap.Asks) > 0 {
+		bid, ask := snap.Bids[0], snap.Asks[0]
+		snap.Spread = ask.Price - bid.Price
+		snap.MidPrice = (bid.Price + ask.Price) / 2
+		snap.Microprice = (bid.Price*ask.Quantity + ask.Price*bid.Quantity) / (bid.Quantity + ask.Quantity)
+	}
+	if bidQty+askQty > 0 {
+		snap.Imbalance = (bidQty - askQty) / (bidQty + askQty)
+	}
+	return snap
+}
+
+// fillsQuantity sums the quantity of fills
+func fillsQuantity(fills []domain.BookLevel) float64 {
+	total := 0.0
+	for _, f := range fills {
+		total += f.Quantity
+	}
+	return total
+}
+
+// fillsNotional sums the quote value of fills
+func fillsNotional(fills []domain.BookLevel) float64 {
+	total := 0.0
+	for _, f := range fills {
+		total += f.Price * f.Quantity
+	}
+	return total
+}
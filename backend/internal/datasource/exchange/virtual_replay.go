@@ -0,0 +1,561 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Replay sources
+const (
+	ReplaySourceCandles = "candles" // Stored candles_<interval> bars
+	ReplaySourceTrades  = "trades"  // Stored aggTrades (tick data)
+)
+
+// replayCandlePage is how many bars are loaded per symbol per query
+const replayCandlePage = 1000
+
+// replayTradePage is how many aggTrades are loaded per symbol per query
+const replayTradePage = 5000
+
+// ReplayConfig configures a historical replay of the virtual exchange
+type ReplayConfig struct {
+	Symbols  []string
+	Start    time.Time
+	End      time.Time // Exclusive
+	Source   string    // ReplaySourceCandles (default) or ReplaySourceTrades
+	Interval string    // Candle interval replayed (default 1m); also what Step advances by
+	Speed    float64   // 1 replays in real time, N at N×, 0 as fast as possible
+	Paused   bool      // Start paused, waiting for Resume or Step
+
+	Candles history.CandleStore   // Required for candle replays; also serves GetCandles
+	Trades  history.AggTradeStore // Required for trade replays
+}
+
+// ReplayStatus is a replay's progress and controls
+type ReplayStatus struct {
+	Time     time.Time `json:"time"` // Time of the last replayed price
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Speed    float64   `json:"speed"`
+	Paused   bool      `json:"paused"`
+	Finished bool      `json:"finished"` // Reached the end; Seek to replay again
+}
+
+// Replay drives a virtual exchange from stored market data instead of a random
+// walk: every stored trade, or four points along every stored bar's path
+// (open, low/high, high/low, close), moves the price, refreshes the book,
+// fills resting orders and updates StreamKlines subscribers. The exchange's
+// clock follows the replay, so orders, trades and events carry market time.
+type Replay struct {
+	ve       *VirtualExchange
+	cfg      ReplayConfig
+	interval time.Duration
+
+	mu       sync.Mutex
+	now      time.Time
+	speed    float64
+	paused   bool
+	steps    int        // Bars left to step through while paused
+	seekTo   *time.Time // Pending seek
+	finished bool
+	err      error
+
+	wake chan struct{} // Signals the driver that a control changed
+	done chan struct{} // Closed when the driver stops
+}
+
+// Replay switches the exchange to replaying stored market data from cfg.Start
+// until ctx is done or the exchange is closed. The random walk stops and
+// prices start at each symbol's first stored price.
+func (ve *VirtualExchange) Replay(ctx context.Context, cfg ReplayConfig) (*Replay, error) {
+	if cfg.Source == "" {
+		cfg.Source = ReplaySourceCandles
+	}
+	if cfg.Interval == "" {
+		cfg.Interval = history.BaseInterval
+	}
+	interval, err := history.ParseInterval(cfg.Interval)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(cfg.Symbols) == 0:
+		return nil, fmt.Errorf("replay needs at least one symbol")
+	case !cfg.Start.Before(cfg.End):
+		return nil, fmt.Errorf("replay start %s must be before end %s", cfg.Start.Format(time.RFC3339), cfg.End.Format(time.RFC3339))
+	case cfg.Speed < 0:
+		return nil, fmt.Errorf("invalid replay speed: %g", cfg.Speed)
+	case cfg.Source == ReplaySourceCandles && cfg.Candles == nil:
+		return nil, fmt.Errorf("candle replay needs a candle store")
+	case cfg.Source == ReplaySourceTrades && cfg.Trades == nil:
+		return nil, fmt.Errorf("trade replay needs an aggTrade store")
+	case cfg.Source != ReplaySourceCandles && cfg.Source != ReplaySourceTrades:
+		return nil, fmt.Errorf("unsupported replay source: %q", cfg.Source)
+	}
+
+	r := &Replay{
+		ve:       ve,
+		cfg:      cfg,
+		interval: interval,
+		now:      cfg.Start,
+		speed:    cfg.Speed,
+		paused:   cfg.Paused,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	// Open at each symbol's first stored price
+	feed := r.newFeed(cfg.Start)
+	prices := make(map[string]float64, len(cfg.Symbols))
+	for _, sf := range feed {
+		p, err := sf.peek(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, fmt.Errorf("no stored %s for %s between %s and %s", cfg.Source, sf.symbol,
+				cfg.Start.Format(time.RFC3339), cfg.End.Format(time.RFC3339))
+		}
+		prices[sf.symbol] = p.price
+	}
+
+	ve.mu.Lock()
+	if ve.closed {
+		ve.mu.Unlock()
+		return nil, fmt.Errorf("exchange is closed")
+	}
+	if ve.replay != nil {
+		ve.mu.Unlock()
+		return nil, fmt.Errorf("exchange is already replaying")
+	}
+	ve.replay = r
+	ve.clock = cfg.Start
+	ve.prices = make(map[string]float64, len(prices))
+	ve.books = make(map[string]*syntheticBook, len(prices))
+	for symbol, price := range prices {
+		ve.setPriceLocked(symbol, price)
+	}
+	ve.mu.Unlock()
+
+	log.Printf("✅ Replaying %s %s from %s to %s at %s", cfg.Source, cfg.Symbols,
+		cfg.Start.Format(time.RFC3339), cfg.End.Format(time.RFC3339), formatReplaySpeed(cfg.Speed))
+	go r.run(ctx, feed)
+	return r, nil
+}
+
+// Pause stops the replay after the current price
+func (r *Replay) Pause() {
+	r.mu.Lock()
+	r.paused = true
+	r.steps = 0
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Resume continues a paused replay at its speed
+func (r *Replay) Resume() {
+	r.mu.Lock()
+	r.paused = false
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Step replays the next n bars of the replay interval as fast as possible and
+// pauses again. It pauses a running replay first.
+func (r *Replay) Step(n int) {
+	if n <= 0 {
+		return
+	}
+	r.mu.Lock()
+	r.paused = true
+	r.steps += n
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Seek jumps to t within the replay range and carries on from there. Open
+// orders stay open and match against the new prices, like after a market gap;
+// the wallet is not rewound.
+func (r *Replay) Seek(t time.Time) error {
+	if t.Before(r.cfg.Start) || !t.Before(r.cfg.End) {
+		return fmt.Errorf("seek time %s is outside the replay range", t.Format(time.RFC3339))
+	}
+	r.mu.Lock()
+	r.seekTo = &t
+	r.steps = 0
+	r.mu.Unlock()
+	r.signal()
+	return nil
+}
+
+// SetSpeed changes the replay speed: 1 for real time, N for N×, 0 for as fast as possible
+func (r *Replay) SetSpeed(speed float64) error {
+	if speed < 0 {
+		return fmt.Errorf("invalid replay speed: %g", speed)
+	}
+	r.mu.Lock()
+	r.speed = speed
+	r.mu.Unlock()
+	r.signal()
+	return nil
+}
+
+// Status returns the replay's progress
+func (r *Replay) Status() ReplayStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ReplayStatus{
+		Time:     r.now,
+		Start:    r.cfg.Start,
+		End:      r.cfg.End,
+		Speed:    r.speed,
+		Paused:   r.paused,
+		Finished: r.finished,
+	}
+}
+
+// Done is closed when the replay stops: its context is done, the exchange is
+// closed or loading data failed (see Err). Reaching the end doesn't stop it.
+func (r *Replay) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns the error that stopped the replay, if any
+func (r *Replay) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// signal wakes the driver to pick up a control change
+func (r *Replay) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run feeds prices to the exchange until ctx is done or the exchange is closed
+func (r *Replay) run(ctx context.Context, feed replayFeed) {
+	defer close(r.done)
+
+	var last time.Time     // Replay time of the last paced price
+	var lastWall time.Time // When it was applied
+	for {
+		r.mu.Lock()
+		seekTo := r.seekTo
+		if seekTo != nil {
+			feed = r.newFeed(*seekTo)
+			r.seekTo = nil
+			r.finished = false
+			r.now = *seekTo
+			last = time.Time{}
+		}
+		paused, steps, speed, finished := r.paused, r.steps, r.speed, r.finished
+		r.mu.Unlock()
+
+		if seekTo != nil {
+			r.ve.mu.Lock()
+			r.ve.clock = *seekTo
+			r.ve.mu.Unlock()
+			log.Printf("⏩ Replay seeking to %s", seekTo.Format(time.RFC3339))
+		}
+
+		if finished || (paused && steps == 0) {
+			last = time.Time{}
+			if !r.wait(ctx, nil) {
+				return
+			}
+			continue
+		}
+
+		p, err := feed.peek(ctx)
+		if err != nil {
+			r.fail(ctx, err)
+			return
+		}
+		if p == nil {
+			r.mu.Lock()
+			r.finished = true
+			r.steps = 0
+			r.mu.Unlock()
+			log.Printf("✅ Replay reached %s (seek to replay again)", r.cfg.End.Format(time.RFC3339))
+			continue
+		}
+
+		if paused {
+			// Step: the rest of the bar the next price falls into
+			bar := history.AlignTime(p.time, r.interval)
+			for p != nil && history.AlignTime(p.time, r.interval).Equal(bar) {
+				r.apply(*p)
+				feed.advance()
+				if p, err = feed.peek(ctx); err != nil {
+					r.fail(ctx, err)
+					return
+				}
+			}
+			r.mu.Lock()
+			r.steps = max(r.steps-1, 0)
+			r.mu.Unlock()
+			continue
+		}
+
+		if speed > 0 && !last.IsZero() {
+			gap := time.Duration(float64(p.time.Sub(last)) / speed)
+			if wait := time.Until(lastWall.Add(gap)); wait > 0 {
+				timer := time.NewTimer(wait)
+				ok := r.wait(ctx, timer.C)
+				timer.Stop()
+				if !ok {
+					return
+				}
+				// Woken early by a control change or on time: either way, re-check the controls
+				continue
+			}
+		}
+
+		r.apply(*p)
+		feed.advance()
+		if speed > 0 {
+			if last.IsZero() {
+				lastWall = time.Now()
+			} else {
+				// Keep the schedule rather than drifting by the time spent matching
+				lastWall = lastWall.Add(time.Duration(float64(p.time.Sub(last)) / speed))
+			}
+		}
+		last = p.time
+	}
+}
+
+// wait blocks until a control changes, timer fires (if not nil), or the replay
+// must stop, which it reports as false
+func (r *Replay) wait(ctx context.Context, timer <-chan time.Time) bool {
+	select {
+	case <-r.wake:
+		return true
+	case <-timer:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-r.ve.closeCh:
+		return false
+	}
+}
+
+// fail stops the replay on a data loading error
+func (r *Replay) fail(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	log.Printf("❌ Replay stopped: %v", err)
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+// apply moves the exchange to a replayed price
+func (r *Replay) apply(p pricePoint) {
+	r.ve.mu.Lock()
+	updates := r.ve.applyPointsLocked([]pricePoint{p})
+	r.ve.mu.Unlock()
+	r.ve.deliverKlines(updates)
+
+	r.mu.Lock()
+	r.now = p.time
+	r.mu.Unlock()
+}
+
+// candles returns up to limit stored bars of interval that closed before the replay time
+func (r *Replay) candles(ctx context.Context, symbol, interval string, limit int) ([]*domain.Candle, error) {
+	if r.cfg.Candles == nil {
+		return nil, fmt.Errorf("replay has no candle store")
+	}
+	d, err := history.ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	now := r.now
+	r.mu.Unlock()
+
+	// The bar containing the replay time is still forming
+	end := history.AlignTime(now, d)
+	if !now.Before(end.Add(d - time.Millisecond)) {
+		end = end.Add(d)
+	}
+	candles, err := r.cfg.Candles.GetRange(ctx, symbol, interval, end.Add(-time.Duration(limit)*d), end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s %s candles: %w", symbol, interval, err)
+	}
+	if len(candles) > limit {
+		candles = candles[len(candles)-limit:]
+	}
+	return candles, nil
+}
+
+// newFeed opens the replay's data from start
+func (r *Replay) newFeed(start time.Time) replayFeed {
+	feed := make(replayFeed, 0, len(r.cfg.Symbols))
+	for _, symbol := range r.cfg.Symbols {
+		sf := &replaySymbolFeed{symbol: symbol, from: start}
+		if r.cfg.Source == ReplaySourceTrades {
+			sf.load = r.tradeLoader(symbol)
+		} else {
+			sf.load = r.candleLoader(symbol)
+		}
+		feed = append(feed, sf)
+	}
+	return feed
+}
+
+// candleLoader loads a page of bars from `from` and lays each out as price points
+func (r *Replay) candleLoader(symbol string) replayLoader {
+	page := time.Duration(replayCandlePage) * r.interval
+	return func(ctx context.Context, from time.Time) ([]pricePoint, time.Time, bool, error) {
+		to := from.Add(page)
+		if to.After(r.cfg.End) {
+			to = r.cfg.End
+		}
+		candles, err := r.cfg.Candles.GetRange(ctx, symbol, r.cfg.Interval, from, to)
+		if err != nil {
+			return nil, from, false, fmt.Errorf("failed to load %s %s candles: %w", symbol, r.cfg.Interval, err)
+		}
+
+		points := make([]pricePoint, 0, len(candles)*4)
+		for _, c := range candles {
+			points = append(points, candlePath(c, r.interval)...)
+		}
+		return points, to, !to.Before(r.cfg.End), nil
+	}
+}
+
+// tradeLoader loads a page of aggTrades from `from` as price points
+func (r *Replay) tradeLoader(symbol string) replayLoader {
+	var lastID int64 // Trades sharing the page boundary's millisecond come back again
+	return func(ctx context.Context, from time.Time) ([]pricePoint, time.Time, bool, error) {
+		trades, err := r.cfg.Trades.GetRange(ctx, symbol, from, r.cfg.End, replayTradePage)
+		if err != nil {
+			return nil, from, false, fmt.Errorf("failed to load %s aggTrades: %w", symbol, err)
+		}
+
+		points := make([]pricePoint, 0, len(trades))
+		for _, t := range trades {
+			if lastID != 0 && t.ID <= lastID {
+				continue
+			}
+			points = append(points, pricePoint{symbol: symbol, time: t.Time, price: t.Price, volume: t.Quantity})
+			lastID = t.ID
+		}
+		if len(trades) < replayTradePage {
+			return points, r.cfg.End, true, nil
+		}
+
+		next := trades[len(trades)-1].Time
+		if len(points) == 0 {
+			// A whole page in one millisecond: skip past it
+			next = next.Add(time.Millisecond)
+		}
+		return points, next, false, nil
+	}
+}
+
+// candlePath lays a bar out as four price points spread over its interval:
+// open, the low and high in the order a bullish (low first) or bearish (high
+// first) bar most likely traded them, and close. Each carries a quarter of the volume.
+func candlePath(c *domain.Candle, interval time.Duration) []pricePoint {
+	first, second := c.High, c.Low
+	if c.Close >= c.Open {
+		first, second = c.Low, c.High
+	}
+
+	volume := c.Volume / 4
+	return []pricePoint{
+		{symbol: c.Symbol, time: c.OpenTime, price: c.Open, volume: volume},
+		{symbol: c.Symbol, time: c.OpenTime.Add(interval / 3), price: first, volume: volume},
+		{symbol: c.Symbol, time: c.OpenTime.Add(interval * 2 / 3), price: second, volume: volume},
+		{symbol: c.Symbol, time: c.OpenTime.Add(interval - time.Millisecond), price: c.Close, volume: volume},
+	}
+}
+
+// formatReplaySpeed describes a replay speed for logs
+func formatReplaySpeed(speed float64) string {
+	if speed == 0 {
+		return "full speed"
+	}
+	return fmt.Sprintf("%g×", speed)
+}
+
+// replayLoader loads the next page of a symbol's price points from `from`,
+// returning where the following page starts and whether the data is exhausted
+type replayLoader func(ctx context.Context, from time.Time) (points []pricePoint, next time.Time, done bool, err error)
+
+// replaySymbolFeed pages through one symbol's price points
+type replaySymbolFeed struct {
+	symbol string
+	load   replayLoader
+	from   time.Time
+	page   []pricePoint
+	pos    int
+	done   bool
+}
+
+// peek returns the symbol's next price point without consuming it (nil at the end)
+func (f *replaySymbolFeed) peek(ctx context.Context) (*pricePoint, error) {
+	// Pages can be empty (gaps in the data), so keep loading until points or the end
+	for f.pos >= len(f.page) {
+		if f.done {
+			return nil, nil
+		}
+		points, next, done, err := f.load(ctx, f.from)
+		if err != nil {
+			return nil, err
+		}
+		f.page, f.pos, f.from, f.done = points, 0, next, done
+	}
+	return &f.page[f.pos], nil
+}
+
+// replayFeed merges the symbols' feeds in time order
+type replayFeed []*replaySymbolFeed
+
+// peek returns the earliest next price point across symbols (nil at the end)
+func (feed replayFeed) peek(ctx context.Context) (*pricePoint, error) {
+	next, err := feed.next(ctx)
+	if err != nil || next == nil {
+		return nil, err
+	}
+	return &next.page[next.pos], nil
+}
+
+// advance consumes the point peek returned
+func (feed replayFeed) advance() {
+	// peek has loaded every feed's page, so this can't fail
+	if next, _ := feed.next(context.Background()); next != nil {
+		next.pos++
+	}
+}
+
+// next returns the symbol feed holding the earliest next price point (the
+// first configured symbol on ties)
+func (feed replayFeed) next(ctx context.Context) (*replaySymbolFeed, error) {
+	var earliest *replaySymbolFeed
+	var earliestTime time.Time
+	for _, sf := range feed {
+		p, err := sf.peek(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil && (earliest == nil || p.time.Before(earliestTime)) {
+			earliest, earliestTime = sf, p.time
+		}
+	}
+	return earliest, nil
+}
@@ -0,0 +1,385 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+var replayStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newReplayStores opens SQLite stores holding ten bullish 1m BTCUSDT bars:
+// bar i opens at 100+i, ranges 99+i to 101+i and closes at 100.5+i
+func newReplayStores(t *testing.T) *history.Stores {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	stores, err := history.OpenStores(db, config.StorageConfig{})
+	if err != nil {
+		t.Fatalf("OpenStores: %v", err)
+	}
+
+	candles := make([]*domain.Candle, 0, 10)
+	for i := 0; i < 10; i++ {
+		open := replayStart.Add(time.Duration(i) * time.Minute)
+		price := 100 + float64(i)
+		candles = append(candles, &domain.Candle{
+			Symbol:    "BTCUSDT",
+			OpenTime:  open,
+			CloseTime: open.Add(time.Minute - time.Millisecond),
+			Open:      price,
+			High:      price + 1,
+			Low:       price - 1,
+			Close:     price + 0.5,
+			Volume:    4,
+		})
+	}
+	if err := stores.Candles.EnsureTable("1m"); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	if err := stores.Candles.SaveBatch(context.Background(), candles, "1m"); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+	return stores
+}
+
+// startReplay replays the stored bars on a fresh virtual exchange
+func startReplay(t *testing.T, stores *history.Stores, cfg ReplayConfig) (*VirtualExchange, *Replay) {
+	t.Helper()
+	ve, _ := newTestVirtual(t, map[string]float64{"USDT": 10000})
+	if cfg.Symbols == nil {
+		cfg.Symbols = []string{"BTCUSDT"}
+	}
+	if cfg.Start.IsZero() {
+		cfg.Start, cfg.End = replayStart, replayStart.Add(10*time.Minute)
+	}
+	cfg.Candles, cfg.Trades = stores.Candles, stores.AggTrades
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	r, err := ve.Replay(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	return ve, r
+}
+
+// waitReplay waits until the replay's status satisfies cond
+func waitReplay(t *testing.T, r *Replay, what string, cond func(ReplayStatus) bool) ReplayStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := r.Status()
+		if cond(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replay never %s: %+v", what, status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func replayPrice(t *testing.T, ve *VirtualExchange, symbol string) float64 {
+	t.Helper()
+	price, err := ve.GetCurrentPrice(context.Background(), symbol)
+	if err != nil {
+		t.Fatalf("GetCurrentPrice: %v", err)
+	}
+	return price
+}
+
+// barClose is the replay time after bar i's last price point
+func barClose(i int) time.Time {
+	return replayStart.Add(time.Duration(i+1)*time.Minute - time.Millisecond)
+}
+
+func TestReplayConfigErrors(t *testing.T) {
+	stores := newReplayStores(t)
+	end := replayStart.Add(10 * time.Minute)
+	tests := []struct {
+		name string
+		cfg  ReplayConfig
+	}{
+		{"no symbols", ReplayConfig{Start: replayStart, End: end, Candles: stores.Candles}},
+		{"empty range", ReplayConfig{Symbols: []string{"BTCUSDT"}, Start: end, End: end, Candles: stores.Candles}},
+		{"negative speed", ReplayConfig{Symbols: []string{"BTCUSDT"}, Start: replayStart, End: end, Speed: -1, Candles: stores.Candles}},
+		{"no candle store", ReplayConfig{Symbols: []string{"BTCUSDT"}, Start: replayStart, End: end}},
+		{"no aggTrade store", ReplayConfig{Symbols: []string{"BTCUSDT"}, Start: replayStart, End: end, Source: ReplaySourceTrades}},
+		{"unknown source", ReplayConfig{Symbols: []string{"BTCUSDT"}, Start: replayStart, End: end, Source: "ticks", Candles: stores.Candles}},
+		{"bad interval", ReplayConfig{Symbols: []string{"BTCUSDT"}, Start: replayStart, End: end, Interval: "7x", Candles: stores.Candles}},
+		{"nothing stored", ReplayConfig{Symbols: []string{"ETHUSDT"}, Start: replayStart, End: end, Candles: stores.Candles}},
+		{"nothing in range", ReplayConfig{Symbols: []string{"BTCUSDT"}, Start: end, End: end.Add(time.Hour), Candles: stores.Candles}},
+	}
+	for _, tt := range tests {
+		ve, _ := newTestVirtual(t, nil)
+		if _, err := ve.Replay(context.Background(), tt.cfg); err == nil {
+			t.Errorf("%s: Replay succeeded, want error", tt.name)
+		}
+	}
+
+	// One replay per exchange
+	ve, _ := startReplay(t, stores, ReplayConfig{Paused: true})
+	if _, err := ve.Replay(context.Background(), ReplayConfig{Symbols: []string{"BTCUSDT"}, Start: replayStart, End: end, Candles: stores.Candles}); err == nil {
+		t.Error("second Replay succeeded, want error")
+	}
+}
+
+func TestReplayStepAndPause(t *testing.T) {
+	ve, r := startReplay(t, newReplayStores(t), ReplayConfig{Paused: true})
+
+	// Paused at the first stored price
+	status := r.Status()
+	if !status.Paused || status.Finished || !status.Time.Equal(replayStart) {
+		t.Errorf("status = %+v, want paused at the start", status)
+	}
+	if price := replayPrice(t, ve, "BTCUSDT"); price != 100 {
+		t.Errorf("price = %v, want the first open 100", price)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if now := r.Status().Time; !now.Equal(replayStart) {
+		t.Errorf("paused replay moved to %s", now)
+	}
+
+	// Each step replays one whole bar and pauses again
+	r.Step(1)
+	waitReplay(t, r, "stepped one bar", func(s ReplayStatus) bool { return s.Time.Equal(barClose(0)) })
+	if price := replayPrice(t, ve, "BTCUSDT"); price != 100.5 {
+		t.Errorf("price after one step = %v, want bar 0's close 100.5", price)
+	}
+	r.Step(2)
+	status = waitReplay(t, r, "stepped two bars", func(s ReplayStatus) bool { return s.Time.Equal(barClose(2)) })
+	if !status.Paused || replayPrice(t, ve, "BTCUSDT") != 102.5 {
+		t.Errorf("after stepping = %+v at %v, want paused at bar 2's close 102.5", status, replayPrice(t, ve, "BTCUSDT"))
+	}
+	r.Step(0) // Ignored
+
+	// GetCandles serves the stored bars closed before the replay time
+	candles, err := ve.GetCandles(context.Background(), "BTCUSDT", "1m", 5)
+	if err != nil {
+		t.Fatalf("GetCandles: %v", err)
+	}
+	if len(candles) != 3 || candles[2].Close != 102.5 {
+		t.Errorf("GetCandles = %d bars, want bars 0-2", len(candles))
+	}
+
+	// Resume runs to the end at full speed; Pause takes effect
+	r.Resume()
+	status = waitReplay(t, r, "finished", func(s ReplayStatus) bool { return s.Finished })
+	if !status.Time.Equal(barClose(9)) || replayPrice(t, ve, "BTCUSDT") != 109.5 {
+		t.Errorf("finished at %s, %v, want bar 9's close 109.5", status.Time, replayPrice(t, ve, "BTCUSDT"))
+	}
+	r.Pause()
+	if !r.Status().Paused {
+		t.Error("Pause didn't pause")
+	}
+	select {
+	case <-r.Done():
+		t.Error("replay stopped at the end, want it waiting for a seek")
+	default:
+	}
+}
+
+func TestReplaySeek(t *testing.T) {
+	ve, r := startReplay(t, newReplayStores(t), ReplayConfig{Paused: true})
+
+	for _, at := range []time.Time{replayStart.Add(-time.Minute), replayStart.Add(10 * time.Minute)} {
+		if err := r.Seek(at); err == nil {
+			t.Errorf("Seek(%s) succeeded, want out of range", at.Format(time.TimeOnly))
+		}
+	}
+
+	// Forward into the range, then step from there
+	if err := r.Seek(replayStart.Add(5 * time.Minute)); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	waitReplay(t, r, "seeked", func(s ReplayStatus) bool { return s.Time.Equal(replayStart.Add(5 * time.Minute)) })
+	r.Step(1)
+	waitReplay(t, r, "stepped after seeking", func(s ReplayStatus) bool { return s.Time.Equal(barClose(5)) })
+	if price := replayPrice(t, ve, "BTCUSDT"); price != 105.5 {
+		t.Errorf("price = %v, want bar 5's close 105.5", price)
+	}
+
+	// Seeking after the end replays again
+	r.Resume()
+	waitReplay(t, r, "finished", func(s ReplayStatus) bool { return s.Finished })
+	r.Pause()
+	if err := r.Seek(replayStart.Add(2 * time.Minute)); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	status := waitReplay(t, r, "seeked back", func(s ReplayStatus) bool { return !s.Finished })
+	if !status.Time.Equal(replayStart.Add(2 * time.Minute)) {
+		t.Errorf("seeked to %s, want 00:02", status.Time.Format(time.TimeOnly))
+	}
+	r.Step(1)
+	waitReplay(t, r, "stepped after seeking back", func(s ReplayStatus) bool { return s.Time.Equal(barClose(2)) })
+	if price := replayPrice(t, ve, "BTCUSDT"); price != 102.5 {
+		t.Errorf("price = %v, want bar 2's close 102.5", price)
+	}
+}
+
+func TestReplaySpeed(t *testing.T) {
+	stores := newReplayStores(t)
+
+	// Two bars at 600×: two minutes of market time take about 200ms
+	_, r := startReplay(t, stores, ReplayConfig{End: replayStart.Add(2 * time.Minute), Start: replayStart, Speed: 600})
+	started := time.Now()
+	waitReplay(t, r, "finished", func(s ReplayStatus) bool { return s.Finished })
+	if elapsed := time.Since(started); elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("replay at 600× took %s, want about 200ms", elapsed)
+	}
+
+	// Ten bars in real time would take ten minutes; full speed finishes at once
+	_, r = startReplay(t, stores, ReplayConfig{Speed: 1})
+	time.Sleep(50 * time.Millisecond)
+	if status := r.Status(); status.Finished || status.Time.After(replayStart.Add(time.Second)) {
+		t.Errorf("real time replay at %s after 50ms", status.Time.Format(time.TimeOnly))
+	}
+	if err := r.SetSpeed(-1); err == nil {
+		t.Error("SetSpeed(-1) succeeded, want error")
+	}
+	if err := r.SetSpeed(0); err != nil {
+		t.Fatalf("SetSpeed: %v", err)
+	}
+	status := waitReplay(t, r, "finished at full speed", func(s ReplayStatus) bool { return s.Finished })
+	if status.Speed != 0 {
+		t.Errorf("speed = %v, want 0", status.Speed)
+	}
+}
+
+func TestReplayTrades(t *testing.T) {
+	stores := newReplayStores(t)
+	ctx := context.Background()
+
+	// More than a page of trades, several sharing the page boundary's millisecond
+	var trades []*domain.AggTrade
+	at := replayStart
+	for i := 1; i <= replayTradePage+1000; i++ {
+		if i < replayTradePage-2 || i > replayTradePage+2 {
+			at = at.Add(10 * time.Millisecond)
+		}
+		trades = append(trades, &domain.AggTrade{Symbol: "BTCUSDT", ID: int64(i), Price: 100 + float64(i%100)/100,
+			Quantity: 1, FirstTradeID: int64(i), LastTradeID: int64(i), Time: at})
+	}
+	eth := []*domain.AggTrade{
+		{Symbol: "ETHUSDT", ID: 1, Price: 2000, Quantity: 1, FirstTradeID: 1, LastTradeID: 1, Time: replayStart.Add(5 * time.Millisecond)},
+		{Symbol: "ETHUSDT", ID: 2, Price: 2010, Quantity: 1, FirstTradeID: 2, LastTradeID: 2, Time: replayStart.Add(30 * time.Second)},
+	}
+	for symbol, batch := range map[string][]*domain.AggTrade{"BTCUSDT": trades, "ETHUSDT": eth} {
+		if err := stores.AggTrades.EnsureTable(symbol); err != nil {
+			t.Fatalf("EnsureTable: %v", err)
+		}
+		if err := stores.AggTrades.SaveBatch(ctx, symbol, batch); err != nil {
+			t.Fatalf("SaveBatch: %v", err)
+		}
+	}
+
+	ve, r := startReplay(t, stores, ReplayConfig{
+		Symbols: []string{"BTCUSDT", "ETHUSDT"},
+		Source:  ReplaySourceTrades,
+		Paused:  true,
+	})
+	if btc, eth := replayPrice(t, ve, "BTCUSDT"), replayPrice(t, ve, "ETHUSDT"); btc != 100.01 || eth != 2000 {
+		t.Errorf("opening prices = %v, %v, want each symbol's first trade", btc, eth)
+	}
+
+	// The merged feed yields every trade once, in time order across symbols
+	feed := r.newFeed(replayStart)
+	count := 0
+	var last time.Time
+	for {
+		p, err := feed.peek(ctx)
+		if err != nil {
+			t.Fatalf("peek: %v", err)
+		}
+		if p == nil {
+			break
+		}
+		if p.time.Before(last) {
+			t.Fatalf("point %d at %s before %s", count, p.time, last)
+		}
+		last = p.time
+		count++
+		feed.advance()
+	}
+	if want := len(trades) + len(eth); count != want {
+		t.Errorf("feed yielded %d points, want %d", count, want)
+	}
+
+	// Stepping a trade replay moves through one bar interval of trades
+	r.Step(1)
+	waitReplay(t, r, "stepped", func(s ReplayStatus) bool { return !s.Time.Before(replayStart.Add(59 * time.Second)) })
+	if eth := replayPrice(t, ve, "ETHUSDT"); eth != 2010 {
+		t.Errorf("ETHUSDT = %v after the first minute, want 2010", eth)
+	}
+}
+
+// replayFills replays the stored bars with a resting buy at 99.5 and a stop
+// sell at 101.2 and returns the resulting trades
+func replayFills(t *testing.T, stores *history.Stores) []string {
+	t.Helper()
+	ve, r := startReplay(t, stores, ReplayConfig{Paused: true})
+	ctx := context.Background()
+
+	if _, err := ve.PlaceOrder(ctx, testOrder("buy", domain.OrderSideBuy, domain.OrderTypeLimit, 5, 99.5)); err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	r.Step(2)
+	waitReplay(t, r, "stepped", func(s ReplayStatus) bool { return s.Time.Equal(barClose(1)) })
+	stop := testOrder("stop", domain.OrderSideSell, domain.OrderTypeStopMarket, 5, 0)
+	stop.StopPrice = 101.2
+	if _, err := ve.PlaceOrder(ctx, stop); err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	r.Resume()
+	waitReplay(t, r, "finished", func(s ReplayStatus) bool { return s.Finished })
+
+	trades, err := ve.GetMyTrades(ctx, "BTCUSDT", time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("GetMyTrades: %v", err)
+	}
+	fills := make([]string, 0, len(trades))
+	for _, trade := range trades {
+		fills = append(fills, fmt.Sprintf("%s %s %.8f @ %.8f maker=%v", trade.Timestamp.Format(time.TimeOnly+".000"),
+			trade.Side, trade.Quantity, trade.Price, trade.IsMaker))
+	}
+	return fills
+}
+
+func TestReplayDeterministicFills(t *testing.T) {
+	stores := newReplayStores(t)
+
+	first := replayFills(t, stores)
+	if len(first) == 0 {
+		t.Fatal("no fills during the replay")
+	}
+	// The buy fills at bar 0's low, a third into the bar, in market time
+	if !strings.HasPrefix(first[0], "00:00:20.000 BUY") {
+		t.Errorf("first fill = %s, want the buy at 00:00:20", first[0])
+	}
+	// The stop triggers when bar 2 dips to its low of 101 a third into the bar
+	if len(first) != 2 || !strings.HasPrefix(first[1], "00:02:20.000 SELL") {
+		t.Errorf("fills = %v, want the stop sell at 00:02:20", first)
+	}
+
+	// The same data and orders give the same fills
+	for run := 0; run < 3; run++ {
+		if again := replayFills(t, stores); fmt.Sprint(again) != fmt.Sprint(first) {
+			t.Fatalf("run %d fills = %v, want %v", run, again, first)
+		}
+	}
+}
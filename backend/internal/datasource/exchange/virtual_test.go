@@ -0,0 +1,313 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/internal/portfolio/wallet"
+)
+
+// newTestVirtual creates a fee-free virtual exchange trading BTCUSDT at 100
+// with about 10 BTC on each book level
+func newTestVirtual(t *testing.T, balances map[string]float64) (*VirtualExchange, *wallet.Manager) {
+	t.Helper()
+	w := wallet.NewManager(balances)
+	ve := NewVirtualExchange(map[string]float64{"BTCUSDT": 100}, VirtualConfig{
+		Wallet: w,
+		Fees:   &FeeSchedule{},
+		Book:   BookConfig{LevelNotional: 1000},
+	})
+	t.Cleanup(func() { ve.Close() })
+	return ve, w
+}
+
+func assertBalance(t *testing.T, w *wallet.Manager, asset string, free, locked float64) {
+	t.Helper()
+	b, ok := w.GetAllBalances()[asset]
+	if !ok {
+		b = &domain.Balance{Asset: asset}
+	}
+	if !approx(b.Free, free) || !approx(b.Locked, locked) {
+		t.Errorf("%s balance = %.8f free / %.8f locked, want %.8f / %.8f", asset, b.Free, b.Locked, free, locked)
+	}
+}
+
+func testOrder(clientID string, side domain.OrderSide, orderType domain.OrderType, quantity, price float64) *domain.Order {
+	return &domain.Order{
+		ClientOrderID: clientID,
+		Symbol:        "BTCUSDT",
+		Side:          side,
+		Type:          orderType,
+		Quantity:      quantity,
+		Price:         price,
+	}
+}
+
+func TestVirtualMarketOrderWalksBook(t *testing.T) {
+	ve, w := newTestVirtual(t, map[string]float64{"USDT": 10000})
+	ctx := context.Background()
+
+	order, err := ve.PlaceOrder(ctx, testOrder("buy-1", domain.OrderSideBuy, domain.OrderTypeMarket, 15, 0))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.Status != domain.OrderStatusFilled || order.FilledQty != 15 {
+		t.Fatalf("order = %s filled %v, want FILLED 15", order.Status, order.FilledQty)
+	}
+	// The first level holds ~10 BTC, so the rest fills one level deeper
+	if order.AvgPrice <= 100.01 || order.AvgPrice >= 100.06 {
+		t.Errorf("average price %v not between the first two ask levels", order.AvgPrice)
+	}
+	assertBalance(t, w, "USDT", 10000-15*order.AvgPrice, 0)
+	assertBalance(t, w, "BTC", 15, 0)
+
+	trades, err := ve.GetMyTrades(ctx, "BTCUSDT", time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("GetMyTrades: %v", err)
+	}
+	if len(trades) != 2 || trades[0].IsMaker {
+		t.Errorf("got %d trades (maker=%v), want 2 taker fills", len(trades), len(trades) > 0 && trades[0].IsMaker)
+	}
+}
+
+func TestVirtualMarketOrderExpiresWhenBookRunsOut(t *testing.T) {
+	ve, w := newTestVirtual(t, map[string]float64{"USDT": 100000})
+
+	order, err := ve.PlaceOrder(context.Background(), testOrder("buy-1", domain.OrderSideBuy, domain.OrderTypeMarket, 200, 0))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.Status != domain.OrderStatusExpired {
+		t.Errorf("status = %s, want EXPIRED", order.Status)
+	}
+	if order.FilledQty <= 90 || order.FilledQty >= 100 {
+		t.Errorf("filled %v, want the ~100 BTC on the book", order.FilledQty)
+	}
+	assertBalance(t, w, "USDT", 100000-order.FilledQty*order.AvgPrice, 0)
+}
+
+func TestVirtualLimitOrderRestsAndFillsAsMaker(t *testing.T) {
+	ve, w := newTestVirtual(t, map[string]float64{"USDT": 1000})
+	ctx := context.Background()
+
+	order, err := ve.PlaceOrder(ctx, testOrder("buy-1", domain.OrderSideBuy, domain.OrderTypeLimit, 2, 99))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.Status != domain.OrderStatusNew {
+		t.Fatalf("status = %s, want NEW", order.Status)
+	}
+	assertBalance(t, w, "USDT", 802, 198)
+
+	ve.SetPrice("BTCUSDT", 98.9)
+
+	filled, err := ve.GetOrder(ctx, order.Ref())
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if filled.Status != domain.OrderStatusFilled || filled.AvgPrice != 99 {
+		t.Errorf("order = %s at %v, want FILLED at the limit price", filled.Status, filled.AvgPrice)
+	}
+	trades, _ := ve.GetMyTrades(ctx, "BTCUSDT", time.Time{}, time.Time{}, 0)
+	if len(trades) != 1 || !trades[0].IsMaker {
+		t.Errorf("trades = %+v, want one maker fill", trades)
+	}
+	assertBalance(t, w, "USDT", 802, 0)
+	assertBalance(t, w, "BTC", 2, 0)
+}
+
+func TestVirtualReservations(t *testing.T) {
+	ve, w := newTestVirtual(t, map[string]float64{"USDT": 1000, "BTC": 1})
+	ctx := context.Background()
+
+	if _, err := ve.PlaceOrder(ctx, testOrder("buy-big", domain.OrderSideBuy, domain.OrderTypeLimit, 20, 90)); err == nil {
+		t.Errorf("buy beyond the free balance was accepted")
+	}
+	if _, err := ve.PlaceOrder(ctx, testOrder("sell-big", domain.OrderSideSell, domain.OrderTypeLimit, 2, 110)); err == nil {
+		t.Errorf("sell beyond the free base balance was accepted")
+	}
+
+	buy, err := ve.PlaceOrder(ctx, testOrder("buy-1", domain.OrderSideBuy, domain.OrderTypeLimit, 5, 90))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	assertBalance(t, w, "USDT", 550, 450)
+
+	// The reserved quote can't be spent twice
+	if _, err := ve.PlaceOrder(ctx, testOrder("buy-2", domain.OrderSideBuy, domain.OrderTypeLimit, 6, 95)); err == nil {
+		t.Errorf("second buy spending the reserved balance was accepted")
+	}
+
+	// Resubmitting a client order ID returns the existing order
+	again, err := ve.PlaceOrder(ctx, testOrder("buy-1", domain.OrderSideBuy, domain.OrderTypeLimit, 5, 90))
+	if err != nil || again.ID != buy.ID {
+		t.Errorf("resubmitted order = %v, %v, want the existing order %s", again, err, buy.ID)
+	}
+	assertBalance(t, w, "USDT", 550, 450)
+
+	cancelled, err := ve.CancelOrder(ctx, buy.Ref())
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if cancelled.Status != domain.OrderStatusCancelled {
+		t.Errorf("status = %s, want CANCELED", cancelled.Status)
+	}
+	assertBalance(t, w, "USDT", 1000, 0)
+
+	if _, err := ve.CancelOrder(ctx, buy.Ref()); err == nil {
+		t.Errorf("cancelling a cancelled order succeeded")
+	}
+}
+
+func TestVirtualRejectsImmediateStop(t *testing.T) {
+	ve, w := newTestVirtual(t, map[string]float64{"BTC": 1})
+
+	stop := testOrder("stop-1", domain.OrderSideSell, domain.OrderTypeStopMarket, 1, 0)
+	stop.StopPrice = 101
+	order, err := ve.PlaceOrder(context.Background(), stop)
+	if err == nil || order.Status != domain.OrderStatusRejected {
+		t.Errorf("sell stop above the price = %s, %v, want rejected", order.Status, err)
+	}
+	assertBalance(t, w, "BTC", 1, 0)
+}
+
+// placeSellOCO places a sell OCO for 1 BTC: take profit at 110, stop at 90
+func placeSellOCO(t *testing.T, ve *VirtualExchange) (*domain.Order, *domain.Order) {
+	t.Helper()
+	limit := testOrder("tp-1", domain.OrderSideSell, domain.OrderTypeLimit, 1, 110)
+	stop := testOrder("sl-1", domain.OrderSideSell, domain.OrderTypeStopMarket, 1, 0)
+	stop.StopPrice = 90
+
+	legs, err := ve.PlaceOCO(context.Background(), limit, stop)
+	if err != nil {
+		t.Fatalf("PlaceOCO: %v", err)
+	}
+	if len(legs) != 2 || legs[0].OCOGroup == "" || legs[0].OCOGroup != legs[1].OCOGroup {
+		t.Fatalf("legs = %+v, want two legs of one group", legs)
+	}
+	return legs[0], legs[1]
+}
+
+func TestVirtualOCOLimitFillCancelsStop(t *testing.T) {
+	ve, w := newTestVirtual(t, map[string]float64{"BTC": 1})
+	ctx := context.Background()
+
+	limit, stop := placeSellOCO(t, ve)
+	// Both legs sell the same BTC, so it is reserved once
+	assertBalance(t, w, "BTC", 0, 1)
+
+	ve.SetPrice("BTCUSDT", 111)
+
+	if o, _ := ve.GetOrder(ctx, limit.Ref()); o.Status != domain.OrderStatusFilled || o.AvgPrice != 110 {
+		t.Errorf("limit leg = %s at %v, want FILLED at 110", o.Status, o.AvgPrice)
+	}
+	if o, _ := ve.GetOrder(ctx, stop.Ref()); o.Status != domain.OrderStatusCancelled {
+		t.Errorf("stop leg = %s, want CANCELED", o.Status)
+	}
+	assertBalance(t, w, "BTC", 0, 0)
+	assertBalance(t, w, "USDT", 110, 0)
+}
+
+func TestVirtualOCOStopTriggerCancelsLimit(t *testing.T) {
+	ve, w := newTestVirtual(t, map[string]float64{"BTC": 1})
+	ctx := context.Background()
+
+	limit, stop := placeSellOCO(t, ve)
+	ve.SetPrice("BTCUSDT", 89)
+
+	filled, _ := ve.GetOrder(ctx, stop.Ref())
+	if filled.Status != domain.OrderStatusFilled || filled.AvgPrice >= 89 {
+		t.Errorf("stop leg = %s at %v, want FILLED at the bid below 89", filled.Status, filled.AvgPrice)
+	}
+	if o, _ := ve.GetOrder(ctx, limit.Ref()); o.Status != domain.OrderStatusCancelled {
+		t.Errorf("limit leg = %s, want CANCELED", o.Status)
+	}
+	assertBalance(t, w, "BTC", 0, 0)
+	assertBalance(t, w, "USDT", filled.AvgPrice, 0)
+}
+
+func TestVirtualOCOCancelOneLegCancelsBoth(t *testing.T) {
+	ve, w := newTestVirtual(t, map[string]float64{"BTC": 1})
+	ctx := context.Background()
+
+	limit, stop := placeSellOCO(t, ve)
+	if _, err := ve.CancelOrder(ctx, stop.Ref()); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if o, _ := ve.GetOrder(ctx, limit.Ref()); o.Status != domain.OrderStatusCancelled {
+		t.Errorf("limit leg = %s, want CANCELED", o.Status)
+	}
+	assertBalance(t, w, "BTC", 1, 0)
+
+	open, _ := ve.GetOpenOrders(ctx, "BTCUSDT")
+	if len(open) != 0 {
+		t.Errorf("%d orders still open", len(open))
+	}
+}
+
+// staticSymbolInfo serves fixed trading rules and counts lookups
+type staticSymbolInfo struct {
+	info  domain.SymbolInfo
+	calls int
+	err   error
+}
+
+func (s *staticSymbolInfo) GetSymbolInfo(ctx context.Context, symbol string) (*domain.SymbolInfo, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	info := s.info
+	info.Symbol = symbol
+	info.UpdatedAt = time.Now()
+	return &info, nil
+}
+
+func TestVirtualAppliesVenueRules(t *testing.T) {
+	ve, _ := newTestVirtual(t, map[string]float64{"USDT": 1000, "BTC": 1})
+	ctx := context.Background()
+	source := &staticSymbolInfo{info: domain.SymbolInfo{
+		Status:      domain.SymbolStatusTrading,
+		TickSize:    0.1,
+		StepSize:    0.001,
+		MinNotional: 10,
+	}}
+	ve.SetSymbolRules(NewVenueRules(source))
+
+	order, err := ve.PlaceOrder(ctx, testOrder("buy-1", domain.OrderSideBuy, domain.OrderTypeLimit, 0.123456, 95.04))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.Quantity != 0.123 || order.Price != 95 {
+		t.Errorf("order rounded to %v @ %v, want 0.123 @ 95", order.Quantity, order.Price)
+	}
+
+	small := testOrder("buy-2", domain.OrderSideBuy, domain.OrderTypeLimit, 0.05, 95)
+	if _, err := ve.PlaceOrder(ctx, small); err == nil || !strings.Contains(err.Error(), "trading rules") {
+		t.Errorf("order below the minimum notional returned %v", err)
+	}
+
+	// Reduce-only orders may be below the minimum notional
+	reduce := testOrder("sell-1", domain.OrderSideSell, domain.OrderTypeLimit, 0.05, 105)
+	reduce.ReduceOnly = true
+	if _, err := ve.PlaceOrder(ctx, reduce); err != nil {
+		t.Errorf("reduce-only order below the minimum notional: %v", err)
+	}
+
+	if source.calls != 1 {
+		t.Errorf("symbol info loaded %d times, want once", source.calls)
+	}
+}
+
+func TestVenueRulesLoadError(t *testing.T) {
+	source := &staticSymbolInfo{err: errors.New("boom")}
+	rules := NewVenueRules(source)
+	order := testOrder("buy-1", domain.OrderSideBuy, domain.OrderTypeMarket, 1, 0)
+	if err := rules.PrepareOrder(context.Background(), order, 100); err == nil {
+		t.Errorf("PrepareOrder succeeded without symbol info")
+	}
+}
@@ -0,0 +1,94 @@
+package exchange
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/gorilla/websocket"
+)
+
+// Binance default WebSocket base URLs; streams are served at <base>/<stream>
+const (
+	binanceWsMainURL    = "wss://stream.binance.com:9443/ws"
+	binanceWsTestnetURL = "wss://testnet.binance.vision/ws"
+)
+
+// wsHandshakeTimeout bounds connecting to a stream
+const wsHandshakeTimeout = 45 * time.Second
+
+// wsReadLimit is the largest stream message accepted
+const wsReadLimit = 655350
+
+// BinanceEndpoints overrides the Binance REST and WebSocket base URLs, e.g. to
+// point the exchange at the mock server. Empty fields keep the defaults.
+type BinanceEndpoints struct {
+	BaseURL   string // REST, e.g. http://localhost:9090
+	WsBaseURL string // Streams, e.g. ws://localhost:9090/ws
+}
+
+// wsServe connects to a stream and calls handler for every message until the
+// connection drops (errHandler is called) or stopC is closed. doneC is closed
+// when the connection is gone.
+func wsServe(endpoint string, handler func([]byte), errHandler func(error)) (doneC, stopC chan struct{}, err error) {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: wsHandshakeTimeout,
+	}
+
+	conn, _, err := dialer.Dial(endpoint, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetReadLimit(wsReadLimit)
+
+	doneC = make(chan struct{})
+	stopC = make(chan struct{})
+	go func() {
+		defer close(doneC)
+
+		// ReadMessage blocks, so stopC closes the connection from another goroutine
+		stopped := make(chan struct{})
+		go func() {
+			select {
+			case <-stopC:
+				close(stopped)
+			case <-doneC:
+			}
+			conn.Close()
+		}()
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				select {
+				case <-stopped:
+				default:
+					errHandler(err)
+				}
+				return
+			}
+			handler(message)
+		}
+	}()
+	return doneC, stopC, nil
+}
+
+// wsEndpoint returns the URL of a stream on the configured or default base URL
+func (be *BinanceExchange) wsEndpoint(stream string) string {
+	base := be.endpoints.WsBaseURL
+	if base == "" {
+		base = binanceWsMainURL
+		if binance.UseTestnet {
+			base = binanceWsTestnetURL
+		}
+	}
+	return strings.TrimSuffix(base, "/") + "/" + stream
+}
+
+// wsCombinedEndpoint returns the combined stream URL (<base>/stream?streams=a/b) for the streams
+func (be *BinanceExchange) wsCombinedEndpoint(streams []string) string {
+	base := strings.TrimSuffix(be.wsEndpoint(""), "/")
+	return strings.TrimSuffix(base, "/ws") + "/stream?streams=" + strings.Join(streams, "/")
+}
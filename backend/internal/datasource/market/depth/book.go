@@ -0,0 +1,167 @@
+package depth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// ErrOutOfSync is returned when a diff does not continue the book's update
+// sequence; the book must be rebuilt from a new snapshot
+var ErrOutOfSync = errors.New("order book out of sync")
+
+// Snapshot is a full order book from the REST depth endpoint
+type Snapshot struct {
+	Symbol       string             `json:"symbol"`
+	LastUpdateID int64              `json:"last_update_id"`
+	Bids         []domain.BookLevel `json:"bids"`
+	Asks         []domain.BookLevel `json:"asks"`
+}
+
+// DiffEvent is a diff-depth stream update. A level with zero quantity is removed.
+type DiffEvent struct {
+	Symbol        string             `json:"symbol"`
+	Time          time.Time          `json:"time"`
+	FirstUpdateID int64              `json:"first_update_id"` // U
+	LastUpdateID  int64              `json:"last_update_id"`  // u
+	Bids          []domain.BookLevel `json:"bids"`
+	Asks          []domain.BookLevel `json:"asks"`
+}
+
+// OrderBook is a local order book kept in sync with the diff-depth stream.
+// It is not safe for concurrent use.
+type OrderBook struct {
+	symbol       string
+	bids         map[float64]float64
+	asks         map[float64]float64
+	lastUpdateID int64
+	synced       bool // First diff after the snapshot has been applied
+	updated      time.Time
+}
+
+// NewOrderBook creates an empty order book
+func NewOrderBook(symbol string) *OrderBook {
+	return &OrderBook{
+		symbol: symbol,
+		bids:   make(map[float64]float64),
+		asks:   make(map[float64]float64),
+	}
+}
+
+// Reset replaces the book with a REST snapshot
+func (b *OrderBook) Reset(snap *Snapshot) {
+	b.bids = make(map[float64]float64, len(snap.Bids))
+	b.asks = make(map[float64]float64, len(snap.Asks))
+	applyLevels(b.bids, snap.Bids)
+	applyLevels(b.asks, snap.Asks)
+	b.lastUpdateID = snap.LastUpdateID
+	b.synced = false
+}
+
+// Apply applies a diff following Binance's sequencing rules: diffs the snapshot
+// already covers (u <= lastUpdateId) are skipped, the first applied diff must
+// span lastUpdateId+1, and every later diff must start at the previous u+1.
+// Returns whether the diff was applied, or ErrOutOfSync on a sequence gap.
+func (b *OrderBook) Apply(ev *DiffEvent) (bool, error) {
+	if ev.LastUpdateID <= b.lastUpdateID {
+		return false, nil
+	}
+
+	if b.synced {
+		if ev.FirstUpdateID != b.lastUpdateID+1 {
+			return false, fmt.Errorf("%w: expected update %d, got %d-%d",
+				ErrOutOfSync, b.lastUpdateID+1, ev.FirstUpdateID, ev.LastUpdateID)
+		}
+	} else if ev.FirstUpdateID > b.lastUpdateID+1 {
+		return false, fmt.Errorf("%w: first diff %d-%d does not cover snapshot %d",
+			ErrOutOfSync, ev.FirstUpdateID, ev.LastUpdateID, b.lastUpdateID)
+	}
+
+	applyLevels(b.bids, ev.Bids)
+	applyLevels(b.asks, ev.Asks)
+	b.lastUpdateID = ev.LastUpdateID
+	b.synced = true
+	b.updated = ev.Time
+	return true, nil
+}
+
+// LastUpdateID returns the ID of the last update applied to the book
+func (b *OrderBook) LastUpdateID() int64 {
+	return b.lastUpdateID
+}
+
+// Top returns the best n levels per side with derived metrics (n <= 0 for all levels).
+// The snapshot time is the time of the last applied diff.
+func (b *OrderBook) Top(n int) *domain.OrderBookSnapshot {
+	snap := &domain.OrderBookSnapshot{
+		Symbol:       b.symbol,
+		Time:         b.updated,
+		LastUpdateID: b.lastUpdateID,
+		Bids:         sortedLevels(b.bids, n, true),
+		Asks:         sortedLevels(b.asks, n, false),
+	}
+	ComputeMetrics(snap)
+	return snap
+}
+
+// ComputeMetrics fills in spread, mid price, imbalance and microprice from the
+// snapshot's levels. Metrics stay zero when either side is empty.
+func ComputeMetrics(snap *domain.OrderBookSnapshot) {
+	if len(snap.Bids) == 0 || len(snap.Asks) == 0 {
+		return
+	}
+
+	bestBid, bestAsk := snap.Bids[0], snap.Asks[0]
+	snap.Spread = bestAsk.Price - bestBid.Price
+	snap.MidPrice = (bestBid.Price + bestAsk.Price) / 2
+	if size := bestBid.Quantity + bestAsk.Quantity; size > 0 {
+		snap.Microprice = (bestBid.Price*bestAsk.Quantity + bestAsk.Price*bestBid.Quantity) / size
+	}
+
+	var bidQty, askQty float64
+	for _, l := range snap.Bids {
+		bidQty += l.Quantity
+	}
+	for _, l := range snap.Asks {
+		askQty += l.Quantity
+	}
+	if bidQty+askQty > 0 {
+		snap.Imbalance = (bidQty - askQty) / (bidQty + askQty)
+	}
+}
+
+// applyLevels sets or removes (zero quantity) price levels
+func applyLevels(side map[float64]float64, levels []domain.BookLevel) {
+	for _, l := range levels {
+		if l.Quantity == 0 {
+			delete(side, l.Price)
+		} else {
+			side[l.Price] = l.Quantity
+		}
+	}
+}
+
+// sortedLevels returns the best n levels of a side, best first
+func sortedLevels(side map[float64]float64, n int, descending bool) []domain.BookLevel {
+	prices := make([]float64, 0, len(side))
+	for p := range side {
+		prices = append(prices, p)
+	}
+	if descending {
+		sort.Sort(sort.Reverse(sort.Float64Slice(prices)))
+	} else {
+		sort.Float64s(prices)
+	}
+	if n > 0 && len(prices) > n {
+		prices = prices[:n]
+	}
+
+	levels := make([]domain.BookLevel, len(prices))
+	for i, p := range prices {
+		levels[i] = domain.BookLevel{Price: p, Quantity: side[p]}
+	}
+	return levels
+}
@@ -0,0 +1,121 @@
+package depth
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+func levels(pq ...float64) []domain.BookLevel {
+	result := make([]domain.BookLevel, 0, len(pq)/2)
+	for i := 0; i+1 < len(pq); i += 2 {
+		result = append(result, domain.BookLevel{Price: pq[i], Quantity: pq[i+1]})
+	}
+	return result
+}
+
+func newSyncedBook() *OrderBook {
+	book := NewOrderBook("BTCUSDT")
+	book.Reset(&Snapshot{
+		Symbol:       "BTCUSDT",
+		LastUpdateID: 100,
+		Bids:         levels(99, 1, 98, 2),
+		Asks:         levels(101, 1, 102, 3),
+	})
+	return book
+}
+
+func TestOrderBookSequencing(t *testing.T) {
+	book := newSyncedBook()
+
+	// Diffs the snapshot already covers are skipped
+	if applied, err := book.Apply(&DiffEvent{FirstUpdateID: 90, LastUpdateID: 100}); applied || err != nil {
+		t.Fatalf("stale diff: applied=%v err=%v", applied, err)
+	}
+
+	// The first diff may start before the snapshot as long as it spans lastUpdateId+1
+	if applied, err := book.Apply(&DiffEvent{FirstUpdateID: 95, LastUpdateID: 102, Bids: levels(99, 2)}); !applied || err != nil {
+		t.Fatalf("first diff: applied=%v err=%v", applied, err)
+	}
+	if applied, err := book.Apply(&DiffEvent{FirstUpdateID: 103, LastUpdateID: 105, Asks: levels(101, 0)}); !applied || err != nil {
+		t.Fatalf("next diff: applied=%v err=%v", applied, err)
+	}
+	if book.LastUpdateID() != 105 {
+		t.Errorf("last update = %d, want 105", book.LastUpdateID())
+	}
+
+	// A later diff must continue at the previous u+1
+	_, err := book.Apply(&DiffEvent{FirstUpdateID: 107, LastUpdateID: 108})
+	if !errors.Is(err, ErrOutOfSync) {
+		t.Fatalf("gap diff returned %v, want ErrOutOfSync", err)
+	}
+	if book.LastUpdateID() != 105 {
+		t.Errorf("gap diff changed the book to update %d", book.LastUpdateID())
+	}
+
+	top := book.Top(0)
+	if len(top.Bids) != 2 || top.Bids[0] != (domain.BookLevel{Price: 99, Quantity: 2}) {
+		t.Errorf("bids = %+v", top.Bids)
+	}
+	if len(top.Asks) != 1 || top.Asks[0].Price != 102 {
+		t.Errorf("asks = %+v, want 101 removed", top.Asks)
+	}
+}
+
+func TestOrderBookFirstDiffMustCoverSnapshot(t *testing.T) {
+	book := newSyncedBook()
+	if _, err := book.Apply(&DiffEvent{FirstUpdateID: 102, LastUpdateID: 104}); !errors.Is(err, ErrOutOfSync) {
+		t.Errorf("diff after the snapshot's next update returned %v, want ErrOutOfSync", err)
+	}
+}
+
+func TestTopAndMetrics(t *testing.T) {
+	book := newSyncedBook()
+	top := book.Top(1)
+	if len(top.Bids) != 1 || len(top.Asks) != 1 {
+		t.Fatalf("Top(1) returned %d bids and %d asks", len(top.Bids), len(top.Asks))
+	}
+	if top.Spread != 2 || top.MidPrice != 100 {
+		t.Errorf("spread = %v, mid = %v", top.Spread, top.MidPrice)
+	}
+	// Equal top sizes put the microprice at the mid
+	if top.Microprice != 100 {
+		t.Errorf("microprice = %v, want 100", top.Microprice)
+	}
+	if top.Imbalance != 0 {
+		t.Errorf("imbalance over the top level = %v, want 0", top.Imbalance)
+	}
+
+	all := book.Top(0)
+	if want := (3.0 - 4.0) / 7.0; math.Abs(all.Imbalance-want) > 1e-12 {
+		t.Errorf("imbalance = %v, want %v", all.Imbalance, want)
+	}
+}
+
+func TestEstimateFill(t *testing.T) {
+	snap := newSyncedBook().Top(0)
+
+	fill, err := EstimateFill(snap, domain.OrderSideBuy, 2)
+	if err != nil {
+		t.Fatalf("EstimateFill: %v", err)
+	}
+	if fill.Filled != 2 || fill.AveragePrice != 101.5 || fill.WorstPrice != 102 || fill.LevelsUsed != 2 {
+		t.Errorf("buy fill = %+v", fill)
+	}
+	if fill.SlippageBps != 150 {
+		t.Errorf("slippage = %v bps, want 150", fill.SlippageBps)
+	}
+
+	fill, err = EstimateFill(snap, domain.OrderSideSell, 10)
+	if err != nil {
+		t.Fatalf("EstimateFill: %v", err)
+	}
+	if fill.Filled != 3 {
+		t.Errorf("sell filled %v, want the 3 available", fill.Filled)
+	}
+	if _, err := EstimateFill(snap, domain.OrderSideBuy, 0); err == nil {
+		t.Errorf("zero quantity fill succeeded")
+	}
+}
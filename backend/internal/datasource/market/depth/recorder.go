@@ -0,0 +1,140 @@
+package depth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Recorder defaults
+const (
+	DefaultLevels           = 20
+	DefaultSnapshotInterval = time.Second
+	eventBufferSize         = 1000
+)
+
+// RecorderConfig configures a depth recorder
+type RecorderConfig struct {
+	Symbol   string
+	Levels   int            // Levels per side persisted in each snapshot
+	Interval time.Duration  // Stream time between persisted snapshots
+	Fixture  *FixtureWriter // Optional: also record the raw snapshots and diffs
+}
+
+// Recorder keeps a local order book from a depth source and persists periodic
+// top-N snapshots
+type Recorder struct {
+	cfg    RecorderConfig
+	source Source
+	repo   *SnapshotRepository
+	book   *OrderBook
+	saved  time.Time // Time of the last persisted snapshot
+}
+
+// NewRecorder creates a depth recorder
+func NewRecorder(cfg RecorderConfig, source Source, repo *SnapshotRepository) *Recorder {
+	if cfg.Levels <= 0 {
+		cfg.Levels = DefaultLevels
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultSnapshotInterval
+	}
+	return &Recorder{
+		cfg:    cfg,
+		source: source,
+		repo:   repo,
+		book:   NewOrderBook(cfg.Symbol),
+	}
+}
+
+// Follow records until ctx is done, restarting after stream failures
+func (r *Recorder) Follow(ctx context.Context) {
+	for {
+		if err := r.Run(ctx); err != nil {
+			log.Printf("⚠️  %s depth recorder: %v", r.cfg.Symbol, err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Run records one stream session. The stream is started first and buffered
+// while the REST snapshot loads, then buffered and live diffs are applied in
+// update ID order; a sequence gap triggers a new snapshot. Returns nil when the
+// stream ends cleanly or ctx is done.
+func (r *Recorder) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan *DiffEvent, eventBufferSize)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- r.source.Stream(ctx, r.cfg.Symbol, events)
+		close(events)
+	}()
+
+	if err := r.resync(ctx); err != nil {
+		return err
+	}
+
+	for ev := range events {
+		if r.cfg.Fixture != nil {
+			if err := r.cfg.Fixture.WriteDiff(ev); err != nil {
+				return err
+			}
+		}
+
+		applied, err := r.book.Apply(ev)
+		if errors.Is(err, ErrOutOfSync) {
+			log.Printf("⚠️  %s: %v, resyncing", r.cfg.Symbol, err)
+			if err := r.resync(ctx); err != nil {
+				return err
+			}
+			// The diff may be the first one after the new snapshot
+			if applied, err = r.book.Apply(ev); err != nil && !errors.Is(err, ErrOutOfSync) {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		if applied && ev.Time.Sub(r.saved) >= r.cfg.Interval {
+			if err := r.repo.Save(context.Background(), r.book.Top(r.cfg.Levels)); err != nil {
+				log.Printf("❌ %s: %v", r.cfg.Symbol, err)
+			} else {
+				r.saved = ev.Time
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return <-streamErr
+}
+
+// resync resets the book from a fresh REST snapshot
+func (r *Recorder) resync(ctx context.Context) error {
+	snap, err := r.source.Snapshot(ctx, r.cfg.Symbol)
+	if err != nil {
+		return fmt.Errorf("failed to load depth snapshot: %w", err)
+	}
+	if r.cfg.Fixture != nil {
+		if err := r.cfg.Fixture.WriteSnapshot(snap); err != nil {
+			return err
+		}
+	}
+
+	r.book.Reset(snap)
+	log.Printf("✅ %s order book synced at update %d", r.cfg.Symbol, snap.LastUpdateID)
+	return nil
+}
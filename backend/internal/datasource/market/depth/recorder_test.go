@@ -0,0 +1,94 @@
+package depth
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+)
+
+func TestRecorderReplaysFixture(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	diff := func(i int, first, last int64, bids, asks []float64) *DiffEvent {
+		return &DiffEvent{
+			Symbol:        "BTCUSDT",
+			Time:          start.Add(time.Duration(i) * time.Second),
+			FirstUpdateID: first,
+			LastUpdateID:  last,
+			Bids:          levels(bids...),
+			Asks:          levels(asks...),
+		}
+	}
+
+	// Record a session with a sequence gap that needs a second snapshot
+	var buf bytes.Buffer
+	w := NewFixtureWriter(&buf)
+	records := []func() error{
+		func() error {
+			return w.WriteSnapshot(&Snapshot{Symbol: "BTCUSDT", LastUpdateID: 100, Bids: levels(99, 1, 98, 2), Asks: levels(101, 1, 102, 3)})
+		},
+		func() error { return w.WriteDiff(diff(0, 90, 100, nil, nil)) },
+		func() error { return w.WriteDiff(diff(1, 99, 102, []float64{99, 2}, nil)) },
+		func() error { return w.WriteDiff(diff(2, 103, 105, nil, []float64{101, 0})) },
+		func() error { return w.WriteDiff(diff(3, 110, 112, []float64{97, 5}, nil)) },
+		func() error {
+			return w.WriteSnapshot(&Snapshot{Symbol: "BTCUSDT", LastUpdateID: 111, Bids: levels(97, 1), Asks: levels(103, 1)})
+		},
+		func() error { return w.WriteDiff(diff(4, 113, 113, nil, []float64{103, 2})) },
+	}
+	for _, record := range records {
+		if err := record(); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+
+	source, err := LoadFixture(&buf)
+	if err != nil {
+		t.Fatalf("LoadFixture: %v", err)
+	}
+
+	db, err := database.New(filepath.Join(t.TempDir(), "depth.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	repo := NewSnapshotRepository(db)
+
+	recorder := NewRecorder(RecorderConfig{Symbol: "BTCUSDT", Levels: 5, Interval: time.Second}, source, repo)
+	if err := recorder.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	snaps, err := repo.GetRange(ctx, "BTCUSDT", start, start.Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	wantIDs := []int64{102, 105, 112, 113}
+	if len(snaps) != len(wantIDs) {
+		t.Fatalf("saved %d snapshots, want %d", len(snaps), len(wantIDs))
+	}
+	for i, snap := range snaps {
+		if snap.LastUpdateID != wantIDs[i] {
+			t.Errorf("snapshot %d at update %d, want %d", i, snap.LastUpdateID, wantIDs[i])
+		}
+	}
+
+	// The last snapshot is built on the second REST snapshot, not the stale book
+	last := snaps[len(snaps)-1]
+	if len(last.Bids) != 1 || last.Bids[0].Price != 97 || last.Bids[0].Quantity != 5 {
+		t.Errorf("bids after resync = %+v", last.Bids)
+	}
+	if len(last.Asks) != 1 || last.Asks[0].Price != 103 || last.Asks[0].Quantity != 2 {
+		t.Errorf("asks after resync = %+v", last.Asks)
+	}
+	if last.MidPrice != 100 {
+		t.Errorf("mid price = %v, want 100", last.MidPrice)
+	}
+}
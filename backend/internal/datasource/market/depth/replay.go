@@ -0,0 +1,144 @@
+package depth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// replayPageSize is the number of snapshots loaded per query during replay
+const replayPageSize = 1000
+
+// Replay steps through stored snapshots of a time range in time order.
+// Snapshots are loaded a page at a time, so long ranges are cheap to open.
+type Replay struct {
+	repo    *SnapshotRepository
+	symbol  string
+	end     time.Time
+	next    time.Time // Start of the next page
+	page    []*domain.OrderBookSnapshot
+	pos     int
+	current *domain.OrderBookSnapshot
+	done    bool
+}
+
+// NewReplay creates a replay of the snapshots with start <= time < end
+func NewReplay(repo *SnapshotRepository, symbol string, start, end time.Time) *Replay {
+	return &Replay{
+		repo:   repo,
+		symbol: symbol,
+		end:    end,
+		next:   start,
+	}
+}
+
+// Next advances to the next snapshot. Returns nil at the end of the range.
+func (r *Replay) Next(ctx context.Context) (*domain.OrderBookSnapshot, error) {
+	snap, err := r.peek(ctx)
+	if err != nil || snap == nil {
+		return nil, err
+	}
+	r.pos++
+	r.current = snap
+	return snap, nil
+}
+
+// At returns the book state as of t: the latest snapshot at or before t, or nil
+// if there is none yet. Calls must use non-decreasing times.
+func (r *Replay) At(ctx context.Context, t time.Time) (*domain.OrderBookSnapshot, error) {
+	for {
+		snap, err := r.peek(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if snap == nil || snap.Time.After(t) {
+			return r.current, nil
+		}
+		r.pos++
+		r.current = snap
+	}
+}
+
+// peek returns the next snapshot without consuming it, loading a page if needed
+func (r *Replay) peek(ctx context.Context) (*domain.OrderBookSnapshot, error) {
+	if r.pos < len(r.page) {
+		return r.page[r.pos], nil
+	}
+	if r.done {
+		return nil, nil
+	}
+
+	page, err := r.repo.GetRange(ctx, r.symbol, r.next, r.end, replayPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load depth snapshots: %w", err)
+	}
+	if len(page) < replayPageSize {
+		r.done = true
+	}
+	if len(page) == 0 {
+		return nil, nil
+	}
+
+	// The recorder persists at most one snapshot per interval, so the next
+	// page can start right after the last one
+	r.next = page[len(page)-1].Time.Add(time.Millisecond)
+	r.page = page
+	r.pos = 0
+	return r.page[0], nil
+}
+
+// Fill is the estimated execution of a market order against a book snapshot
+type Fill struct {
+	Side         domain.OrderSide `json:"side"`
+	Requested    float64          `json:"requested"`
+	Filled       float64          `json:"filled"` // Less than requested when the stored levels run out
+	AveragePrice float64          `json:"average_price"`
+	WorstPrice   float64          `json:"worst_price"`
+	SlippageBps  float64          `json:"slippage_bps"` // Average price vs. mid, adverse positive
+	LevelsUsed   int              `json:"levels_used"`
+}
+
+// EstimateFill walks the opposite side of the book to fill a market order of quantity
+func EstimateFill(snap *domain.OrderBookSnapshot, side domain.OrderSide, quantity float64) (*Fill, error) {
+	var levels []domain.BookLevel
+	switch side {
+	case domain.OrderSideBuy:
+		levels = snap.Asks
+	case domain.OrderSideSell:
+		levels = snap.Bids
+	default:
+		return nil, fmt.Errorf("unknown order side: %s", side)
+	}
+	if quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+
+	fill := &Fill{Side: side, Requested: quantity}
+	notional := 0.0
+	for _, l := range levels {
+		if fill.Filled >= quantity {
+			break
+		}
+		take := l.Quantity
+		if remaining := quantity - fill.Filled; take > remaining {
+			take = remaining
+		}
+		fill.Filled += take
+		notional += take * l.Price
+		fill.WorstPrice = l.Price
+		fill.LevelsUsed++
+	}
+
+	if fill.Filled > 0 {
+		fill.AveragePrice = notional / fill.Filled
+		if snap.MidPrice > 0 {
+			fill.SlippageBps = (fill.AveragePrice - snap.MidPrice) / snap.MidPrice * 10000
+			if side == domain.OrderSideSell {
+				fill.SlippageBps = -fill.SlippageBps
+			}
+		}
+	}
+	return fill, nil
+}
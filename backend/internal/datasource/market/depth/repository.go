@@ -0,0 +1,143 @@
+package depth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// snapshotColumns are selected in the order scanSnapshot expects
+var snapshotColumns = []string{"symbol", "time", "last_update_id", "bids", "asks", "spread", "mid_price", "imbalance", "microprice"}
+
+// SnapshotRepository stores top-of-book snapshots in SQLite
+type SnapshotRepository struct {
+	db *database.DB
+}
+
+// NewSnapshotRepository creates a new snapshot repository
+func NewSnapshotRepository(db *database.DB) *SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+// Save stores a snapshot
+func (r *SnapshotRepository) Save(ctx context.Context, snap *domain.OrderBookSnapshot) error {
+	bids, err := json.Marshal(snap.Bids)
+	if err != nil {
+		return fmt.Errorf("failed to encode bids: %w", err)
+	}
+	asks, err := json.Marshal(snap.Asks)
+	if err != nil {
+		return fmt.Errorf("failed to encode asks: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO depth_snapshots (symbol, time, last_update_id, bids, asks, spread, mid_price, imbalance, microprice)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		snap.Symbol,
+		snap.Time.UnixMilli(),
+		snap.LastUpdateID,
+		string(bids),
+		string(asks),
+		snap.Spread,
+		snap.MidPrice,
+		snap.Imbalance,
+		snap.Microprice,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save depth snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetRange retrieves snapshots with start <= time < end in time order (limit <= 0 for all)
+func (r *SnapshotRepository) GetRange(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.OrderBookSnapshot, error) {
+	query := sq.Select(snapshotColumns...).
+		From("depth_snapshots").
+		Where(sq.Eq{"symbol": symbol}).
+		Where(sq.GtOrEq{"time": start.UnixMilli()}).
+		Where(sq.Lt{"time": end.UnixMilli()}).
+		OrderBy("time ASC", "id ASC")
+	if limit > 0 {
+		query = query.Limit(uint64(limit))
+	}
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query depth snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]*domain.OrderBookSnapshot, 0)
+	for rows.Next() {
+		snap, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// GetAt retrieves the latest snapshot at or before t (nil if none)
+func (r *SnapshotRepository) GetAt(ctx context.Context, symbol string, t time.Time) (*domain.OrderBookSnapshot, error) {
+	query := sq.Select(snapshotColumns...).
+		From("depth_snapshots").
+		Where(sq.Eq{"symbol": symbol}).
+		Where(sq.LtOrEq{"time": t.UnixMilli()}).
+		OrderBy("time DESC", "id DESC").
+		Limit(1)
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	snap, err := scanSnapshot(r.db.QueryRowContext(ctx, sqlQuery, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return snap, err
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSnapshot scans a row selected with snapshotColumns
+func scanSnapshot(row rowScanner) (*domain.OrderBookSnapshot, error) {
+	var snap domain.OrderBookSnapshot
+	var ts int64
+	var bids, asks string
+
+	err := row.Scan(&snap.Symbol, &ts, &snap.LastUpdateID, &bids, &asks,
+		&snap.Spread, &snap.MidPrice, &snap.Imbalance, &snap.Microprice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan depth snapshot: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(bids), &snap.Bids); err != nil {
+		return nil, fmt.Errorf("failed to decode bids: %w", err)
+	}
+	if err := json.Unmarshal([]byte(asks), &snap.Asks); err != nil {
+		return nil, fmt.Errorf("failed to decode asks: %w", err)
+	}
+	snap.Time = domain.TimeFromMillis(ts)
+	return &snap, nil
+}
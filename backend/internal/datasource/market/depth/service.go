@@ -0,0 +1,62 @@
+package depth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Service handles stored order book depth
+type Service struct {
+	repo *SnapshotRepository
+}
+
+// NewService creates a new depth service
+func NewService(db *database.DB) *Service {
+	return &Service{
+		repo: NewSnapshotRepository(db),
+	}
+}
+
+// Repository returns the snapshot repository, e.g. for recorders
+func (s *Service) Repository() *SnapshotRepository {
+	return s.repo
+}
+
+// GetSnapshots retrieves stored snapshots within a time range
+func (s *Service) GetSnapshots(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.OrderBookSnapshot, error) {
+	snapshots, err := s.repo.GetRange(ctx, symbol, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get depth snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// GetSnapshotAt retrieves the book state as of t
+func (s *Service) GetSnapshotAt(ctx context.Context, symbol string, t time.Time) (*domain.OrderBookSnapshot, error) {
+	snap, err := s.repo.GetAt(ctx, symbol, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get depth snapshot: %w", err)
+	}
+	if snap == nil {
+		return nil, fmt.Errorf("no depth snapshot for %s at or before %s", symbol, t.Format(time.RFC3339))
+	}
+	return snap, nil
+}
+
+// EstimateFill estimates a market order fill against the book as of t
+func (s *Service) EstimateFill(ctx context.Context, symbol string, t time.Time, side domain.OrderSide, quantity float64) (*Fill, error) {
+	snap, err := s.GetSnapshotAt(ctx, symbol, t)
+	if err != nil {
+		return nil, err
+	}
+	return EstimateFill(snap, side, quantity)
+}
+
+// NewReplay creates a replay of stored snapshots for backtests
+func (s *Service) NewReplay(symbol string, start, end time.Time) *Replay {
+	return NewReplay(s.repo, symbol, start, end)
+}
@@ -0,0 +1,237 @@
+package depth
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"sync"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// snapshotLimit is the number of levels per side requested for REST snapshots
+const snapshotLimit = 1000
+
+// Source provides order book snapshots and the diff-depth stream for a symbol
+type Source interface {
+	// Snapshot fetches a full order book
+	Snapshot(ctx context.Context, symbol string) (*Snapshot, error)
+
+	// Stream sends diffs to events until ctx is done (returns nil), the stream
+	// ends cleanly (returns nil) or it fails (returns the error)
+	Stream(ctx context.Context, symbol string, events chan<- *DiffEvent) error
+}
+
+// BinanceSource reads depth from the Binance REST API and diff-depth WebSocket
+type BinanceSource struct {
+	client  *binance.Client
+	limiter *history.RateLimiter
+}
+
+// NewBinanceSource creates a Binance depth source sharing the client's weight limiter
+func NewBinanceSource(client *binance.Client) *BinanceSource {
+	return &BinanceSource{
+		client:  client,
+		limiter: history.ClientRateLimiter(client),
+	}
+}
+
+// Snapshot fetches the REST order book
+func (s *BinanceSource) Snapshot(ctx context.Context, symbol string) (*Snapshot, error) {
+	if err := s.limiter.Wait(ctx, history.DepthWeight); err != nil {
+		return nil, err
+	}
+
+	res, err := s.client.NewDepthService().Symbol(symbol).Limit(snapshotLimit).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s depth snapshot: %w", symbol, err)
+	}
+
+	return &Snapshot{
+		Symbol:       symbol,
+		LastUpdateID: res.LastUpdateID,
+		Bids:         parseLevels(res.Bids),
+		Asks:         parseLevels(res.Asks),
+	}, nil
+}
+
+// Stream follows the 100ms diff-depth WebSocket
+func (s *BinanceSource) Stream(ctx context.Context, symbol string, events chan<- *DiffEvent) error {
+	wsHandler := func(event *binance.WsDepthEvent) {
+		ev := &DiffEvent{
+			Symbol:        symbol,
+			Time:          domain.TimeFromMillis(event.Time),
+			FirstUpdateID: event.FirstUpdateID,
+			LastUpdateID:  event.LastUpdateID,
+			Bids:          parseLevels(event.Bids),
+			Asks:          parseLevels(event.Asks),
+		}
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	}
+
+	var mu sync.Mutex
+	var streamErr error
+	errHandler := func(err error) {
+		log.Printf("%s depth WebSocket error: %v", symbol, err)
+		mu.Lock()
+		streamErr = err
+		mu.Unlock()
+	}
+
+	doneC, stopC, err := binance.WsDepthServe100Ms(symbol, wsHandler, errHandler)
+	if err != nil {
+		return fmt.Errorf("failed to start depth WebSocket: %w", err)
+	}
+	log.Printf("Streaming %s depth", symbol)
+
+	select {
+	case <-doneC:
+		mu.Lock()
+		defer mu.Unlock()
+		if streamErr == nil {
+			return fmt.Errorf("depth WebSocket closed")
+		}
+		return streamErr
+	case <-ctx.Done():
+		close(stopC)
+		<-doneC
+		return nil
+	}
+}
+
+// parseLevels converts Binance string price levels
+func parseLevels(levels []binance.Bid) []domain.BookLevel {
+	result := make([]domain.BookLevel, 0, len(levels))
+	for _, l := range levels {
+		price, err := strconv.ParseFloat(l.Price, 64)
+		if err != nil {
+			continue
+		}
+		quantity, err := strconv.ParseFloat(l.Quantity, 64)
+		if err != nil {
+			continue
+		}
+		result = append(result, domain.BookLevel{Price: price, Quantity: quantity})
+	}
+	return result
+}
+
+// fixtureRecord is one line of a depth fixture: a snapshot or a diff
+type fixtureRecord struct {
+	Snapshot *Snapshot  `json:"snapshot,omitempty"`
+	Diff     *DiffEvent `json:"diff,omitempty"`
+}
+
+// FixtureWriter records snapshots and diffs as JSON lines for later replay
+type FixtureWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewFixtureWriter creates a fixture writer
+func NewFixtureWriter(w io.Writer) *FixtureWriter {
+	return &FixtureWriter{enc: json.NewEncoder(w)}
+}
+
+// WriteSnapshot records a REST snapshot
+func (w *FixtureWriter) WriteSnapshot(snap *Snapshot) error {
+	return w.write(fixtureRecord{Snapshot: snap})
+}
+
+// WriteDiff records a stream diff
+func (w *FixtureWriter) WriteDiff(ev *DiffEvent) error {
+	return w.write(fixtureRecord{Diff: ev})
+}
+
+// write encodes one record
+func (w *FixtureWriter) write(rec fixtureRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.enc.Encode(rec); err != nil {
+		return fmt.Errorf("failed to write depth fixture: %w", err)
+	}
+	return nil
+}
+
+// FixtureSource replays a recorded fixture instead of connecting to the exchange.
+// Snapshots are served in recorded order; Stream sends the remaining diffs and
+// returns nil at the end of the fixture.
+type FixtureSource struct {
+	mu        sync.Mutex
+	snapshots []*Snapshot
+	diffs     []*DiffEvent
+	nextSnap  int
+	nextDiff  int
+}
+
+// LoadFixture reads a fixture written by FixtureWriter
+func LoadFixture(r io.Reader) (*FixtureSource, error) {
+	src := &FixtureSource{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec fixtureRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("invalid depth fixture line %d: %w", line, err)
+		}
+		switch {
+		case rec.Snapshot != nil:
+			src.snapshots = append(src.snapshots, rec.Snapshot)
+		case rec.Diff != nil:
+			src.diffs = append(src.diffs, rec.Diff)
+		default:
+			return nil, fmt.Errorf("invalid depth fixture line %d: no snapshot or diff", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read depth fixture: %w", err)
+	}
+	return src, nil
+}
+
+// Snapshot returns the next recorded snapshot, or io.EOF when none are left
+func (s *FixtureSource) Snapshot(ctx context.Context, symbol string) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nextSnap >= len(s.snapshots) {
+		return nil, io.EOF
+	}
+	snap := s.snapshots[s.nextSnap]
+	s.nextSnap++
+	return snap, nil
+}
+
+// Stream sends the recorded diffs not yet sent
+func (s *FixtureSource) Stream(ctx context.Context, symbol string, events chan<- *DiffEvent) error {
+	for {
+		s.mu.Lock()
+		if s.nextDiff >= len(s.diffs) {
+			s.mu.Unlock()
+			return nil
+		}
+		ev := s.diffs[s.nextDiff]
+		s.nextDiff++
+		s.mu.Unlock()
+
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+const (
+	aggTradesPageLimit = 1000      // Max trades per GET /api/v3/aggTrades
+	aggTradesWindow    = time.Hour // Max startTime/endTime span Binance accepts
+	aggTradesFlushSize = 500       // Streamed trades buffered before a write
+	aggTradesFlushTime = time.Second
+)
+
+// AggTradeCollector collects Binance aggregate trades via REST and WebSocket
+type AggTradeCollector struct {
+	client  *binance.Client
+	store   AggTradeStore
+	limiter *RateLimiter
+}
+
+// NewAggTradeCollector creates a new aggTrade collector.
+// Collectors sharing a client also share its request weight limiter.
+func NewAggTradeCollector(client *binance.Client, store AggTradeStore) *AggTradeCollector {
+	return &AggTradeCollector{
+		client:  client,
+		store:   store,
+		limiter: ClientRateLimiter(client),
+	}
+}
+
+// Collect fetches and stores every aggTrade in [start, end) and returns how many were saved.
+// If trades from inside the range are already stored it resumes after the latest one.
+func (c *AggTradeCollector) Collect(ctx context.Context, symbol string, start, end time.Time) (int, error) {
+	if err := c.store.EnsureTable(symbol); err != nil {
+		return 0, err
+	}
+
+	fromID := int64(-1)
+	latest, err := c.store.GetLatest(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+	if latest != nil && !latest.Time.Before(start) && latest.Time.Before(end) {
+		fromID = latest.ID + 1
+		log.Printf("Resuming %s aggTrades after #%d (%s)", symbol, latest.ID, latest.Time.Format(time.RFC3339))
+	} else {
+		if fromID, err = c.firstID(ctx, symbol, start, end); err != nil {
+			return 0, err
+		}
+		if fromID < 0 {
+			log.Printf("No %s aggTrades between %s and %s", symbol, start.Format(time.RFC3339), end.Format(time.RFC3339))
+			return 0, nil
+		}
+	}
+
+	total := 0
+	for page := 1; ; page++ {
+		res, err := c.fetch(ctx, c.client.NewAggTradesService().Symbol(symbol).FromID(fromID).Limit(aggTradesPageLimit))
+		if err != nil {
+			return total, err
+		}
+
+		trades := make([]*domain.AggTrade, 0, len(res))
+		done := len(res) < aggTradesPageLimit
+		for _, a := range res {
+			t := aggTradeFromREST(symbol, a)
+			if !t.Time.Before(end) {
+				done = true
+				break
+			}
+			trades = append(trades, t)
+		}
+
+		if len(trades) > 0 {
+			if err := c.store.SaveBatch(ctx, symbol, trades); err != nil {
+				return total, err
+			}
+			total += len(trades)
+			fromID = trades[len(trades)-1].ID + 1
+
+			if page%50 == 0 {
+				last := trades[len(trades)-1].Time
+				progress := float64(last.Sub(start)) / float64(end.Sub(start)) * 100
+				log.Printf("%s aggTrades: %.1f%% | %d saved, at %s", symbol, progress, total, last.Format(time.RFC3339))
+			}
+		}
+
+		if done || len(trades) == 0 {
+			break
+		}
+	}
+
+	log.Printf("✅ Collected %d %s aggTrades", total, symbol)
+	return total, nil
+}
+
+// CollectAggTrades fetches and stores aggTrades for a time range
+func (s *Service) CollectAggTrades(ctx context.Context, symbol string, start, end time.Time) (int, error) {
+	count, err := s.aggCollector.Collect(ctx, symbol, start, end)
+	if err != nil {
+		return count, fmt.Errorf("failed to collect aggTrades: %w", err)
+	}
+	return count, nil
+}
+
+// GetAggTrades retrieves up to limit stored aggTrades for a time range (0 = no limit)
+func (s *Service) GetAggTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.AggTrade, error) {
+	trades, err := s.aggTradeRepo.GetRange(ctx, symbol, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggTrades: %w", err)
+	}
+	return trades, nil
+}
+
+// firstID finds the first aggregate trade ID at or after start, or -1 if there
+// is none before end. Binance only accepts one hour per time-bounded request.
+func (c *AggTradeCollector) firstID(ctx context.Context, symbol string, start, end time.Time) (int64, error) {
+	for window := start; window.Before(end); window = window.Add(aggTradesWindow) {
+		windowEnd := window.Add(aggTradesWindow)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+
+		res, err := c.fetch(ctx, c.client.NewAggTradesService().
+			Symbol(symbol).
+			StartTime(window.UnixMilli()).
+			EndTime(windowEnd.UnixMilli()-1).
+			Limit(1))
+		if err != nil {
+			return -1, err
+		}
+		if len(res) > 0 {
+			return res[0].AggTradeID, nil
+		}
+	}
+	return -1, nil
+}
+
+// fetch runs an aggTrades request within the shared weight budget, retrying transient errors
+func (c *AggTradeCollector) fetch(ctx context.Context, svc *binance.AggTradesService) ([]*binance.AggTrade, error) {
+	const maxRetries = 3
+
+	var lastErr error
+	for retry := 0; retry <= maxRetries; retry++ {
+		if err := c.limiter.Wait(ctx, AggTradesWeight); err != nil {
+			return nil, err
+		}
+
+		res, err := svc.Do(ctx)
+		if err == nil {
+			return res, nil
+		}
+		lastErr = err
+
+		if retry < maxRetries {
+			backoffDelay := time.Duration(1<<uint(retry)) * time.Second
+			log.Printf("API error (attempt %d/%d): %v. Retrying after %v...", retry+1, maxRetries, err, backoffDelay)
+			select {
+			case <-time.After(backoffDelay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return nil, fmt.Errorf("failed to fetch aggTrades after %d retries: %w", maxRetries, lastErr)
+}
+
+// Follow keeps a symbol's aggTrades up to date until ctx is done: it backfills
+// from the latest stored trade via REST, then streams live trades over WebSocket,
+// and repeats after every disconnect. Trades made between the backfill and the
+// subscription are fetched by ID once the stream delivers its first trade.
+func (c *AggTradeCollector) Follow(ctx context.Context, symbol string, lookback time.Duration) error {
+	for {
+		now := time.Now().UTC()
+		if _, err := c.Collect(ctx, symbol, now.Add(-lookback), now); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("⚠️  %s aggTrade backfill failed: %v", symbol, err)
+		}
+
+		if err := c.Stream(ctx, symbol); err != nil {
+			log.Printf("⚠️  %s aggTrade stream: %v", symbol, err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stream stores live aggTrades from the WebSocket until it disconnects or ctx is done.
+// Trades are written in batches of aggTradesFlushSize or every aggTradesFlushTime.
+// When trades are already stored, IDs missing between the latest stored trade
+// and the streamed ones (or between streamed ones) are fetched via REST.
+func (c *AggTradeCollector) Stream(ctx context.Context, symbol string) error {
+	if err := c.store.EnsureTable(symbol); err != nil {
+		return err
+	}
+
+	lastID := int64(-1) // Newest trade ID seen; -1 until there is something to continue from
+	latest, err := c.store.GetLatest(ctx, symbol)
+	if err != nil {
+		return err
+	}
+	if latest != nil {
+		lastID = latest.ID
+	}
+
+	var mu sync.Mutex
+	buffer := make([]*domain.AggTrade, 0, aggTradesFlushSize)
+	var gaps []aggTradeGap
+	gapC := make(chan struct{}, 1)
+
+	flush := func() {
+		mu.Lock()
+		batch := buffer
+		buffer = make([]*domain.AggTrade, 0, aggTradesFlushSize)
+		mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+		// Use a fresh context so the final flush still runs after cancellation
+		if err := c.store.SaveBatch(context.Background(), symbol, batch); err != nil {
+			log.Printf("❌ %s: failed to save %d streamed aggTrades: %v", symbol, len(batch), err)
+		}
+	}
+
+	wsHandler := func(event *binance.WsAggTradeEvent) {
+		price, _ := strconv.ParseFloat(event.Price, 64)
+		quantity, _ := strconv.ParseFloat(event.Quantity, 64)
+
+		mu.Lock()
+		if event.AggTradeID <= lastID {
+			mu.Unlock()
+			return // Already stored
+		}
+		if lastID >= 0 && event.AggTradeID > lastID+1 {
+			gaps = append(gaps, aggTradeGap{from: lastID + 1, to: event.AggTradeID})
+			select {
+			case gapC <- struct{}{}:
+			default:
+			}
+		}
+		lastID = event.AggTradeID
+		buffer = append(buffer, &domain.AggTrade{
+			Symbol:       symbol,
+			ID:           event.AggTradeID,
+			Price:        price,
+			Quantity:     quantity,
+			FirstTradeID: event.FirstBreakdownTradeID,
+			LastTradeID:  event.LastBreakdownTradeID,
+			Time:         domain.TimeFromMillis(event.TradeTime),
+			IsBuyerMaker: event.IsBuyerMaker,
+		})
+		full := len(buffer) >= aggTradesFlushSize
+		mu.Unlock()
+
+		if full {
+			flush()
+		}
+	}
+
+	var streamErr error
+	errHandler := func(err error) {
+		log.Printf("%s aggTrade WebSocket error: %v", symbol, err)
+		mu.Lock()
+		streamErr = err
+		mu.Unlock()
+	}
+
+	doneC, stopC, err := binance.WsAggTradeServe(symbol, wsHandler, errHandler)
+	if err != nil {
+		return fmt.Errorf("failed to start aggTrade WebSocket: %w", err)
+	}
+	log.Printf("Streaming %s aggTrades", symbol)
+
+	ticker := time.NewTicker(aggTradesFlushTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			flush()
+		case <-gapC:
+			mu.Lock()
+			pending := gaps
+			gaps = nil
+			mu.Unlock()
+			for _, gap := range pending {
+				if err := c.fillGap(ctx, symbol, gap); err != nil && ctx.Err() == nil {
+					log.Printf("⚠️  %s: failed to fetch aggTrades #%d-#%d: %v", symbol, gap.from, gap.to-1, err)
+				}
+			}
+		case <-doneC:
+			flush()
+			mu.Lock()
+			defer mu.Unlock()
+			return streamErr
+		case <-ctx.Done():
+			close(stopC)
+			<-doneC
+			flush()
+			return nil
+		}
+	}
+}
+
+// aggTradeGap is a range of missing aggregate trade IDs [from, to)
+type aggTradeGap struct {
+	from, to int64
+}
+
+// fillGap fetches and stores the trades of a gap by ID
+func (c *AggTradeCollector) fillGap(ctx context.Context, symbol string, gap aggTradeGap) error {
+	total := 0
+	for fromID := gap.from; fromID < gap.to; {
+		limit := min(gap.to-fromID, aggTradesPageLimit)
+		res, err := c.fetch(ctx, c.client.NewAggTradesService().Symbol(symbol).FromID(fromID).Limit(int(limit)))
+		if err != nil {
+			return err
+		}
+
+		trades := make([]*domain.AggTrade, 0, len(res))
+		for _, a := range res {
+			if a.AggTradeID >= gap.to {
+				break
+			}
+			trades = append(trades, aggTradeFromREST(symbol, a))
+		}
+		if len(trades) == 0 {
+			break
+		}
+		if err := c.store.SaveBatch(ctx, symbol, trades); err != nil {
+			return err
+		}
+		total += len(trades)
+		fromID = trades[len(trades)-1].ID + 1
+	}
+
+	log.Printf("%s: fetched %d aggTrades missing from the stream (#%d-#%d)", symbol, total, gap.from, gap.to-1)
+	return nil
+}
+
+// aggTradeFromREST converts a REST aggTrade
+func aggTradeFromREST(symbol string, a *binance.AggTrade) *domain.AggTrade {
+	price, _ := strconv.ParseFloat(a.Price, 64)
+	quantity, _ := strconv.ParseFloat(a.Quantity, 64)
+	return &domain.AggTrade{
+		Symbol:       symbol,
+		ID:           a.AggTradeID,
+		Price:        price,
+		Quantity:     quantity,
+		FirstTradeID: a.FirstTradeID,
+		LastTradeID:  a.LastTradeID,
+		Time:         domain.TimeFromMillis(a.Timestamp),
+		IsBuyerMaker: a.IsBuyerMaker,
+	}
+}
@@ -0,0 +1,372 @@
+package history
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+)
+
+// Archive periods published on data.binance.vision
+const (
+	ArchiveMonthly = "monthly"
+	ArchiveDaily   = "daily"
+)
+
+// Checksum verification results
+const (
+	ChecksumOK       = "ok"
+	ChecksumMissing  = "missing"
+	ChecksumMismatch = "mismatch"
+)
+
+// archiveNamePattern matches kline archive names such as BTCUSDT-1m-2024-01.zip
+// and BTCUSDT-1m-2024-01-15.zip
+var archiveNamePattern = regexp.MustCompile(`^([A-Z0-9]+)-(\d+[smhdwM])-(\d{4})-(\d{2})(?:-(\d{2}))?\.zip$`)
+
+// ArchiveFile is a downloaded Binance kline archive
+type ArchiveFile struct {
+	Path     string    `json:"path"`
+	Symbol   string    `json:"symbol"`
+	Interval string    `json:"interval"`
+	Period   string    `json:"period"`
+	Date     time.Time `json:"date"` // First day covered
+}
+
+// ArchiveResult is the outcome of loading one archive
+type ArchiveResult struct {
+	File     string `json:"file"`
+	Symbol   string `json:"symbol"`
+	Interval string `json:"interval"`
+	Period   string `json:"period"`
+	Checksum string `json:"checksum"`
+	TimeUnit string `json:"time_unit,omitempty"`
+	Imported int    `json:"imported"`
+	Rejected int    `json:"rejected"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ArchiveCoverage lists the months without local archives for a symbol/interval.
+// Months with only some daily archives are reported in PartialMonths with their missing days.
+type ArchiveCoverage struct {
+	Symbol        string              `json:"symbol"`
+	Interval      string              `json:"interval"`
+	From          string              `json:"from"` // YYYY-MM
+	To            string              `json:"to"`
+	MissingMonths []string            `json:"missing_months"`
+	PartialMonths map[string][]string `json:"partial_months,omitempty"`
+}
+
+// ArchiveLoadReport summarizes a bulk load
+type ArchiveLoadReport struct {
+	Files    int               `json:"files"`
+	Loaded   int               `json:"loaded"`
+	Failed   int               `json:"failed"`
+	Imported int               `json:"imported"`
+	Elapsed  time.Duration     `json:"elapsed"`
+	Results  []ArchiveResult   `json:"results"`
+	Coverage []ArchiveCoverage `json:"coverage"`
+}
+
+// ArchiveLoadOptions configures a bulk load
+type ArchiveLoadOptions struct {
+	Symbols         []string  // Only load these symbols (empty = all)
+	Intervals       []string  // Only load these intervals (empty = all)
+	RequireChecksum bool      // Skip archives without a .CHECKSUM file
+	From, To        time.Time // Month range for the missing report (zero = span of local files)
+	DryRun          bool      // Verify and report coverage without importing
+}
+
+// ArchiveLoader seeds candle tables from data.binance.vision kline archives
+type ArchiveLoader struct {
+	importer *Importer
+}
+
+// NewArchiveLoader creates a new archive loader
+func NewArchiveLoader(db *database.DB, candleRepo CandleStore) *ArchiveLoader {
+	return &ArchiveLoader{importer: NewImporter(db, candleRepo)}
+}
+
+// ScanArchives finds kline archives under dir (recursively)
+func ScanArchives(dir string) ([]ArchiveFile, error) {
+	archives := make([]ArchiveFile, 0)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		m := archiveNamePattern.FindStringSubmatch(d.Name())
+		if m == nil {
+			return nil
+		}
+
+		archive := ArchiveFile{
+			Path:     path,
+			Symbol:   m[1],
+			Interval: m[2],
+			Period:   ArchiveMonthly,
+		}
+		day := "01"
+		if m[5] != "" {
+			archive.Period = ArchiveDaily
+			day = m[5]
+		}
+		archive.Date, err = time.Parse("2006-01-02", m[3]+"-"+m[4]+"-"+day)
+		if err != nil {
+			return nil // Not a real date
+		}
+
+		archives = append(archives, archive)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		a, b := archives[i], archives[j]
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		if a.Interval != b.Interval {
+			return a.Interval < b.Interval
+		}
+		return a.Date.Before(b.Date)
+	})
+	return archives, nil
+}
+
+// VerifyChecksum checks an archive against its .CHECKSUM file
+// ("<sha256>  <file name>") and returns ChecksumOK, ChecksumMissing or ChecksumMismatch
+func VerifyChecksum(path string) (string, error) {
+	data, err := os.ReadFile(path + ".CHECKSUM")
+	if os.IsNotExist(err) {
+		return ChecksumMissing, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file for %s", filepath.Base(path))
+	}
+	expected := strings.ToLower(fields[0])
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, bufio.NewReader(f)); err != nil {
+		return "", fmt.Errorf("failed to hash archive: %w", err)
+	}
+
+	if hex.EncodeToString(h.Sum(nil)) != expected {
+		return ChecksumMismatch, nil
+	}
+	return ChecksumOK, nil
+}
+
+// Load verifies and imports every matching archive under dir, then reports missing months
+func (l *ArchiveLoader) Load(ctx context.Context, dir string, opts ArchiveLoadOptions) (*ArchiveLoadReport, error) {
+	started := time.Now()
+
+	archives, err := ScanArchives(dir)
+	if err != nil {
+		return nil, err
+	}
+	archives = filterArchives(archives, opts.Symbols, opts.Intervals)
+
+	report := &ArchiveLoadReport{
+		Files:   len(archives),
+		Results: make([]ArchiveResult, 0, len(archives)),
+	}
+	usable := make([]ArchiveFile, 0, len(archives))
+
+	for i, a := range archives {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		result := ArchiveResult{
+			File:     filepath.Base(a.Path),
+			Symbol:   a.Symbol,
+			Interval: a.Interval,
+			Period:   a.Period,
+		}
+
+		result.Checksum, err = VerifyChecksum(a.Path)
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case result.Checksum == ChecksumMismatch:
+			result.Error = "checksum mismatch (re-download the archive)"
+		case result.Checksum == ChecksumMissing && opts.RequireChecksum:
+			result.Error = "no .CHECKSUM file"
+		case !opts.DryRun:
+			// One transaction per archive; the time unit is detected per file
+			// because archives switched from milliseconds to microseconds in 2025
+			imported, importErr := l.importer.ImportFile(ctx, a.Path, ImportOptions{
+				Symbol:    a.Symbol,
+				Interval:  a.Interval,
+				Format:    FormatCSV,
+				TimeUnit:  TimeUnitAuto,
+				BatchSize: -1,
+			})
+			if importErr != nil {
+				result.Error = importErr.Error()
+			} else {
+				result.TimeUnit = imported.TimeUnit
+				result.Imported = imported.Imported
+				result.Rejected = imported.Rejected
+			}
+		}
+
+		if result.Error != "" {
+			report.Failed++
+			log.Printf("❌ [%d/%d] %s: %s", i+1, len(archives), result.File, result.Error)
+		} else {
+			report.Loaded++
+			report.Imported += result.Imported
+			usable = append(usable, a)
+			log.Printf("[%d/%d] %s: %d candles (checksum %s)", i+1, len(archives), result.File, result.Imported, result.Checksum)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	// Corrupt or unreadable archives count as missing
+	report.Coverage = ArchiveCoverageReport(usable, opts.From, opts.To)
+	report.Elapsed = time.Since(started)
+	return report, nil
+}
+
+// filterArchives keeps archives for the given symbols and intervals (empty = all)
+func filterArchives(archives []ArchiveFile, symbols, intervals []string) []ArchiveFile {
+	keep := func(list []string, v string) bool {
+		if len(list) == 0 {
+			return true
+		}
+		for _, item := range list {
+			if strings.EqualFold(item, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	filtered := make([]ArchiveFile, 0, len(archives))
+	for _, a := range archives {
+		if keep(symbols, a.Symbol) && keep(intervals, a.Interval) {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
+}
+
+// ArchiveCoverageReport lists months without a local archive for each symbol/interval.
+// The range defaults to the first and last month with any archive.
+func ArchiveCoverageReport(archives []ArchiveFile, from, to time.Time) []ArchiveCoverage {
+	type key struct{ symbol, interval string }
+	monthly := make(map[key]map[string]bool)
+	daily := make(map[key]map[string]map[int]bool)
+	order := make([]key, 0)
+	spans := make(map[key][2]time.Time)
+
+	for _, a := range archives {
+		k := key{a.Symbol, a.Interval}
+		if _, ok := monthly[k]; !ok {
+			monthly[k] = make(map[string]bool)
+			daily[k] = make(map[string]map[int]bool)
+			order = append(order, k)
+			spans[k] = [2]time.Time{a.Date, a.Date}
+		}
+
+		month := a.Date.Format("2006-01")
+		if a.Period == ArchiveMonthly {
+			monthly[k][month] = true
+		} else {
+			if daily[k][month] == nil {
+				daily[k][month] = make(map[int]bool)
+			}
+			daily[k][month][a.Date.Day()] = true
+		}
+
+		span := spans[k]
+		if a.Date.Before(span[0]) {
+			span[0] = a.Date
+		}
+		if a.Date.After(span[1]) {
+			span[1] = a.Date
+		}
+		spans[k] = span
+	}
+
+	// Only days up to yesterday can have a daily archive
+	lastDay := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+
+	coverage := make([]ArchiveCoverage, 0, len(order))
+	for _, k := range order {
+		start, end := spans[k][0], spans[k][1]
+		if !from.IsZero() {
+			start = from
+		}
+		if !to.IsZero() {
+			end = to
+		}
+		startMonth := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+		endMonth := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		c := ArchiveCoverage{
+			Symbol:        k.symbol,
+			Interval:      k.interval,
+			From:          startMonth.Format("2006-01"),
+			To:            endMonth.Format("2006-01"),
+			MissingMonths: make([]string, 0),
+			PartialMonths: make(map[string][]string),
+		}
+
+		for m := startMonth; !m.After(endMonth); m = m.AddDate(0, 1, 0) {
+			month := m.Format("2006-01")
+			if monthly[k][month] {
+				continue
+			}
+			days := daily[k][month]
+			if len(days) == 0 {
+				c.MissingMonths = append(c.MissingMonths, month)
+				continue
+			}
+
+			missingDays := make([]string, 0)
+			for d := m; d.Month() == m.Month() && !d.After(lastDay); d = d.AddDate(0, 0, 1) {
+				if !days[d.Day()] {
+					missingDays = append(missingDays, d.Format("2006-01-02"))
+				}
+			}
+			if len(missingDays) > 0 {
+				c.PartialMonths[month] = missingDays
+			}
+		}
+
+		coverage = append(coverage, c)
+	}
+
+	return coverage
+}
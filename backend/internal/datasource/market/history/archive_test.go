@@ -0,0 +1,211 @@
+package history
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeArchive zips lines as a CSV named after the archive, optionally with a .CHECKSUM file
+func writeArchive(t *testing.T, dir, name string, lines []string, checksum bool) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(strings.TrimSuffix(name, ".zip") + ".csv")
+	if err != nil {
+		t.Fatalf("failed to create zip entry: %v", err)
+	}
+	if _, err := w.Write([]byte(strings.Join(lines, "\n") + "\n")); err != nil {
+		t.Fatalf("failed to write zip entry: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	if checksum {
+		sum := sha256.Sum256(buf.Bytes())
+		if err := os.WriteFile(path+".CHECKSUM", []byte(hex.EncodeToString(sum[:])+"  "+name+"\n"), 0o644); err != nil {
+			t.Fatalf("failed to write checksum: %v", err)
+		}
+	}
+	return path
+}
+
+// archiveFixture lays out daily BTCUSDT archives for 2024-01-01 to 03 and a
+// monthly ETHUSDT archive whose checksum doesn't match
+func archiveFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	// A duplicated row
+	writeArchive(t, dir, "BTCUSDT-1m-2024-01-01.zip", []string{
+		"1704067200000,100,101,99,100.5,10,1704067259999,1000,5,5,500,0",
+		"1704067260000,100.5,102,100,101,12,1704067319999,1200,6,6,600,0",
+		"1704067260000,100.5,102,100,101,12,1704067319999,1200,6,6,600,0",
+	}, true)
+
+	// Microsecond timestamps, no checksum and a final row cut off mid-write
+	writeArchive(t, dir, "BTCUSDT-1m-2024-01-02.zip", []string{
+		"1704153600000000,101,103,100,102,8,1704153659999999,800,4,4,400,0",
+		"1704153660000000,102,104,101,103,9,1704153719999999,900,4,4,400,0",
+		"1704153720000000,103,10",
+	}, false)
+
+	// A truncated download
+	path := writeArchive(t, dir, "BTCUSDT-1m-2024-01-03.zip", []string{
+		"1704240000000,100,101,99,100.5,10,1704240059999,1000,5,5,500,0",
+	}, false)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if err := os.WriteFile(path, data[:len(data)/2], 0o644); err != nil {
+		t.Fatalf("failed to truncate archive: %v", err)
+	}
+
+	path = writeArchive(t, dir, "ETHUSDT-1m-2024-01.zip", []string{
+		"1704067200000,2000,2010,1990,2005,3,1704067259999,6000,2,2,4000,0",
+	}, true)
+	if err := os.WriteFile(path+".CHECKSUM", []byte(strings.Repeat("0", 64)+"  ETHUSDT-1m-2024-01.zip\n"), 0o644); err != nil {
+		t.Fatalf("failed to write checksum: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "README.txt"), []byte("not an archive"), 0o644); err != nil {
+		t.Fatalf("failed to write readme: %v", err)
+	}
+	return dir
+}
+
+func TestScanArchives(t *testing.T) {
+	archives, err := ScanArchives(archiveFixture(t))
+	if err != nil {
+		t.Fatalf("ScanArchives: %v", err)
+	}
+
+	want := []string{"BTCUSDT 1m daily 2024-01-01", "BTCUSDT 1m daily 2024-01-02", "BTCUSDT 1m daily 2024-01-03", "ETHUSDT 1m monthly 2024-01-01"}
+	if len(archives) != len(want) {
+		t.Fatalf("found %d archives, want %d", len(archives), len(want))
+	}
+	for i, a := range archives {
+		if got := strings.Join([]string{a.Symbol, a.Interval, a.Period, a.Date.Format("2006-01-02")}, " "); got != want[i] {
+			t.Errorf("archive %d = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestArchiveLoaderLoad(t *testing.T) {
+	ctx := context.Background()
+	dir := archiveFixture(t)
+	db := newTestDB(t)
+	repo := NewCandleRepository(db)
+	loader := NewArchiveLoader(db, repo)
+
+	report, err := loader.Load(ctx, dir, ArchiveLoadOptions{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if report.Files != 4 || report.Loaded != 2 || report.Failed != 2 || report.Imported != 4 {
+		t.Errorf("report = %d files, %d loaded, %d failed, %d imported, want 4, 2, 2, 4",
+			report.Files, report.Loaded, report.Failed, report.Imported)
+	}
+
+	results := make(map[string]ArchiveResult)
+	for _, r := range report.Results {
+		results[r.File] = r
+	}
+	if r := results["BTCUSDT-1m-2024-01-01.zip"]; r.Checksum != ChecksumOK || r.Imported != 2 || r.TimeUnit != TimeUnitMillis {
+		t.Errorf("2024-01-01 = %+v, want 2 ms candles with a good checksum", r)
+	}
+	if r := results["BTCUSDT-1m-2024-01-02.zip"]; r.Checksum != ChecksumMissing || r.Imported != 2 || r.TimeUnit != TimeUnitMicros {
+		t.Errorf("2024-01-02 = %+v, want 2 us candles without a checksum", r)
+	}
+	if r := results["BTCUSDT-1m-2024-01-03.zip"]; !strings.Contains(r.Error, "failed to open zip") {
+		t.Errorf("2024-01-03 error = %q, want a zip error", r.Error)
+	}
+	if r := results["ETHUSDT-1m-2024-01.zip"]; r.Checksum != ChecksumMismatch || r.Error == "" || r.Imported != 0 {
+		t.Errorf("ETHUSDT = %+v, want a checksum mismatch", r)
+	}
+
+	stored, err := repo.GetRange(ctx, "BTCUSDT", "1m", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	wantOpens := []int64{1704067200000, 1704067260000, 1704153600000, 1704153660000}
+	if len(stored) != len(wantOpens) {
+		t.Fatalf("stored %d candles, want %d", len(stored), len(wantOpens))
+	}
+	for i, c := range stored {
+		if c.OpenTime.UnixMilli() != wantOpens[i] {
+			t.Errorf("candle %d opens at %d, want %d", i, c.OpenTime.UnixMilli(), wantOpens[i])
+		}
+	}
+	if c := stored[2]; c.Close != 102 || c.CloseTime.UnixMilli() != 1704153659999 {
+		t.Errorf("microsecond candle = %+v, want close 102 at 1704153659999", c)
+	}
+	if eth, err := repo.GetLatest(ctx, "ETHUSDT", "1m"); err != nil || eth != nil {
+		t.Errorf("ETHUSDT latest = %v, %v, want nothing stored", eth, err)
+	}
+
+	// Failed archives count as missing: only two days of January are covered
+	if len(report.Coverage) != 1 {
+		t.Fatalf("coverage for %d symbols, want 1", len(report.Coverage))
+	}
+	cov := report.Coverage[0]
+	if cov.Symbol != "BTCUSDT" || len(cov.MissingMonths) != 1 || cov.MissingMonths[0] != "2024-02" {
+		t.Errorf("coverage = %+v, want 2024-02 missing", cov)
+	}
+	if days := cov.PartialMonths["2024-01"]; len(days) != 29 || days[0] != "2024-01-03" {
+		t.Errorf("missing January days = %v, want 2024-01-03 to 31", days)
+	}
+
+	// Reloading upserts the same rows
+	if _, err := loader.Load(ctx, dir, ArchiveLoadOptions{}); err != nil {
+		t.Fatalf("Load again: %v", err)
+	}
+	again, err := repo.GetRange(ctx, "BTCUSDT", "1m", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	if len(again) != len(stored) {
+		t.Errorf("stored %d candles after reload, want %d", len(again), len(stored))
+	}
+}
+
+func TestArchiveLoaderOptions(t *testing.T) {
+	ctx := context.Background()
+	dir := archiveFixture(t)
+	db := newTestDB(t)
+	repo := NewCandleRepository(db)
+	loader := NewArchiveLoader(db, repo)
+
+	// Only archives with a matching checksum pass, and a dry run imports nothing
+	report, err := loader.Load(ctx, dir, ArchiveLoadOptions{
+		Symbols:         []string{"btcusdt"},
+		RequireChecksum: true,
+		DryRun:          true,
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if report.Files != 3 || report.Loaded != 1 || report.Failed != 2 || report.Imported != 0 {
+		t.Errorf("report = %d files, %d loaded, %d failed, %d imported, want 3, 1, 2, 0",
+			report.Files, report.Loaded, report.Failed, report.Imported)
+	}
+	if latest, err := repo.GetLatest(ctx, "BTCUSDT", "1m"); err == nil && latest != nil {
+		t.Errorf("dry run stored %s", latest.OpenTime)
+	}
+}
@@ -0,0 +1,179 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Bar types that can be built from aggTrades
+const (
+	BarTime   = "time"   // Fixed time buckets
+	BarTick   = "tick"   // Fixed number of trades
+	BarVolume = "volume" // Fixed base asset volume
+	BarDollar = "dollar" // Fixed quote asset (notional) volume
+)
+
+// BarSpec describes how trades are grouped into bars
+type BarSpec struct {
+	Type      string
+	Interval  time.Duration // Time bars
+	Threshold float64       // Trades, base volume or quote volume per bar
+}
+
+// ParseBarSpec parses a bar type and size: an interval such as "5m" for time
+// bars, or a threshold such as "1000" for tick, volume and dollar bars
+func ParseBarSpec(barType, size string) (BarSpec, error) {
+	spec := BarSpec{Type: barType}
+	switch barType {
+	case BarTime:
+		d, err := ParseInterval(size)
+		if err != nil {
+			return spec, err
+		}
+		spec.Interval = d
+	case BarTick, BarVolume, BarDollar:
+		threshold, err := strconv.ParseFloat(size, 64)
+		if err != nil || threshold <= 0 {
+			return spec, fmt.Errorf("invalid %s bar size: %q", barType, size)
+		}
+		spec.Threshold = threshold
+	default:
+		return spec, fmt.Errorf("unknown bar type: %q (use time, tick, volume or dollar)", barType)
+	}
+	return spec, nil
+}
+
+// BarBuilder aggregates a time-ordered trade stream into bars
+type BarBuilder struct {
+	symbol string
+	spec   BarSpec
+	bar    *domain.Candle
+	filled float64 // Trades, volume or notional in the current bar
+}
+
+// NewBarBuilder creates a bar builder for a symbol
+func NewBarBuilder(symbol string, spec BarSpec) (*BarBuilder, error) {
+	switch spec.Type {
+	case BarTime:
+		if spec.Interval <= 0 {
+			return nil, fmt.Errorf("time bars need a positive interval")
+		}
+	case BarTick, BarVolume, BarDollar:
+		if spec.Threshold <= 0 {
+			return nil, fmt.Errorf("%s bars need a positive threshold", spec.Type)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bar type: %q", spec.Type)
+	}
+	return &BarBuilder{symbol: symbol, spec: spec}, nil
+}
+
+// Add adds a trade and returns the bar it completed, if any.
+// A time bar completes when a trade falls into a later bucket (empty buckets
+// produce no bar); threshold bars complete on the trade that reaches the threshold.
+func (b *BarBuilder) Add(t *domain.AggTrade) *domain.Candle {
+	var completed *domain.Candle
+
+	// CloseTime is the bucket's last millisecond, which still belongs to the bar
+	if b.spec.Type == BarTime && b.bar != nil && !t.Time.Before(b.bar.OpenTime.Add(b.spec.Interval)) {
+		completed = b.bar
+		b.bar = nil
+	}
+
+	if b.bar == nil {
+		b.bar = &domain.Candle{
+			Symbol:   b.symbol,
+			OpenTime: t.Time,
+			Open:     t.Price,
+			High:     t.Price,
+			Low:      t.Price,
+		}
+		if b.spec.Type == BarTime {
+			b.bar.OpenTime = AlignTime(t.Time, b.spec.Interval)
+			b.bar.CloseTime = b.bar.OpenTime.Add(b.spec.Interval).Add(-time.Millisecond)
+		}
+		b.filled = 0
+	}
+
+	bar := b.bar
+	if t.Price > bar.High {
+		bar.High = t.Price
+	}
+	if t.Price < bar.Low {
+		bar.Low = t.Price
+	}
+	bar.Close = t.Price
+	bar.Volume += t.Quantity
+
+	switch b.spec.Type {
+	case BarTick:
+		b.filled++
+	case BarVolume:
+		b.filled += t.Quantity
+	case BarDollar:
+		b.filled += t.Price * t.Quantity
+	}
+
+	if b.spec.Type != BarTime {
+		bar.CloseTime = t.Time
+		if b.filled >= b.spec.Threshold {
+			b.bar = nil
+			return bar
+		}
+	}
+
+	return completed
+}
+
+// Flush returns the bar still being built (nil if none) and resets the builder
+func (b *BarBuilder) Flush() *domain.Candle {
+	bar := b.bar
+	b.bar = nil
+	return bar
+}
+
+// BuildBars groups time-ordered trades into bars, dropping the trailing incomplete bar
+func BuildBars(symbol string, trades []*domain.AggTrade, spec BarSpec) ([]*domain.Candle, error) {
+	builder, err := NewBarBuilder(symbol, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := make([]*domain.Candle, 0)
+	for _, t := range trades {
+		if bar := builder.Add(t); bar != nil {
+			bars = append(bars, bar)
+		}
+	}
+	return bars, nil
+}
+
+// BuildBars builds bars of any type from the stored aggTrades of a time range.
+// Only complete bars are returned: threshold bars still filling at end are dropped,
+// and a time bar is kept only if its bucket closes before end.
+func (s *Service) BuildBars(ctx context.Context, symbol string, spec BarSpec, start, end time.Time) ([]*domain.Candle, error) {
+	builder, err := NewBarBuilder(symbol, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := make([]*domain.Candle, 0)
+	err = s.aggTradeRepo.Scan(ctx, symbol, start, end, func(t *domain.AggTrade) error {
+		if bar := builder.Add(t); bar != nil {
+			bars = append(bars, bar)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build bars: %w", err)
+	}
+
+	if last := builder.Flush(); last != nil && spec.Type == BarTime && last.CloseTime.Before(end) {
+		bars = append(bars, last)
+	}
+	return bars, nil
+}
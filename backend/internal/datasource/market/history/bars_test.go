@@ -0,0 +1,123 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+var barsStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func trade(id int64, offset time.Duration, price, qty float64) *domain.AggTrade {
+	return &domain.AggTrade{Symbol: "BTCUSDT", ID: id, Price: price, Quantity: qty, Time: barsStart.Add(offset)}
+}
+
+func TestBuildTimeBars(t *testing.T) {
+	trades := []*domain.AggTrade{
+		trade(1, 0, 100, 1),
+		trade(2, 10*time.Second, 105, 2),
+		trade(3, 30*time.Second, 95, 1),
+		trade(4, time.Minute-time.Millisecond, 101, 1), // Last millisecond of the first bar
+		trade(5, time.Minute, 102, 3),
+		trade(6, 3*time.Minute, 110, 1), // Empty bucket in between produces no bar
+		trade(7, 4*time.Minute, 111, 1),
+	}
+
+	bars, err := BuildBars("BTCUSDT", trades, BarSpec{Type: BarTime, Interval: time.Minute})
+	if err != nil {
+		t.Fatalf("BuildBars: %v", err)
+	}
+	if len(bars) != 3 {
+		t.Fatalf("got %d bars, want 3", len(bars))
+	}
+
+	first := bars[0]
+	if !first.OpenTime.Equal(barsStart) || !first.CloseTime.Equal(barsStart.Add(time.Minute-time.Millisecond)) {
+		t.Errorf("first bar spans %s - %s", first.OpenTime, first.CloseTime)
+	}
+	if first.Open != 100 || first.High != 105 || first.Low != 95 || first.Close != 101 || first.Volume != 5 {
+		t.Errorf("first bar OHLCV = %v %v %v %v %v", first.Open, first.High, first.Low, first.Close, first.Volume)
+	}
+	if bars[1].Volume != 3 || !bars[1].OpenTime.Equal(barsStart.Add(time.Minute)) {
+		t.Errorf("second bar = %+v", bars[1])
+	}
+	if !bars[2].OpenTime.Equal(barsStart.Add(3 * time.Minute)) {
+		t.Errorf("third bar opens at %s, want %s", bars[2].OpenTime, barsStart.Add(3*time.Minute))
+	}
+}
+
+func TestBuildThresholdBars(t *testing.T) {
+	trades := []*domain.AggTrade{
+		trade(1, 0, 100, 1),
+		trade(2, time.Second, 100, 1),
+		trade(3, 2*time.Second, 100, 1),
+		trade(4, 3*time.Second, 100, 2),
+		trade(5, 4*time.Second, 100, 1),
+	}
+
+	tests := []struct {
+		name       string
+		spec       BarSpec
+		want       []float64     // Volume per complete bar
+		firstClose time.Duration // Time of the first bar's last trade
+	}{
+		{"tick", BarSpec{Type: BarTick, Threshold: 2}, []float64{2, 3}, time.Second},
+		{"volume", BarSpec{Type: BarVolume, Threshold: 3}, []float64{3, 3}, 2 * time.Second},
+		{"dollar", BarSpec{Type: BarDollar, Threshold: 250}, []float64{3, 3}, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bars, err := BuildBars("BTCUSDT", trades, tt.spec)
+			if err != nil {
+				t.Fatalf("BuildBars: %v", err)
+			}
+			if len(bars) != len(tt.want) {
+				t.Fatalf("got %d bars, want %d", len(bars), len(tt.want))
+			}
+			for i, bar := range bars {
+				if bar.Volume != tt.want[i] {
+					t.Errorf("bar %d volume = %v, want %v", i, bar.Volume, tt.want[i])
+				}
+			}
+			if !bars[0].CloseTime.Equal(barsStart.Add(tt.firstClose)) {
+				t.Errorf("first bar closes at %s, want the time of its last trade", bars[0].CloseTime)
+			}
+		})
+	}
+}
+
+func TestBarBuilderFlush(t *testing.T) {
+	builder, err := NewBarBuilder("BTCUSDT", BarSpec{Type: BarTick, Threshold: 10})
+	if err != nil {
+		t.Fatalf("NewBarBuilder: %v", err)
+	}
+	if bar := builder.Add(trade(1, 0, 100, 1)); bar != nil {
+		t.Fatalf("bar completed after one trade")
+	}
+	if bar := builder.Flush(); bar == nil || bar.Volume != 1 {
+		t.Fatalf("Flush = %+v, want the partial bar", bar)
+	}
+	if bar := builder.Flush(); bar != nil {
+		t.Errorf("second Flush = %+v, want nil", bar)
+	}
+}
+
+func TestParseBarSpec(t *testing.T) {
+	spec, err := ParseBarSpec(BarTime, "5m")
+	if err != nil || spec.Interval != 5*time.Minute {
+		t.Errorf("ParseBarSpec(time, 5m) = %+v, %v", spec, err)
+	}
+	spec, err = ParseBarSpec(BarDollar, "1000000")
+	if err != nil || spec.Threshold != 1000000 {
+		t.Errorf("ParseBarSpec(dollar, 1000000) = %+v, %v", spec, err)
+	}
+	for _, tc := range [][2]string{{BarTick, "0"}, {BarVolume, "abc"}, {"renko", "10"}} {
+		if _, err := ParseBarSpec(tc[0], tc[1]); err == nil {
+			t.Errorf("ParseBarSpec(%s, %s) succeeded, want error", tc[0], tc[1])
+		}
+	}
+	if _, err := NewBarBuilder("BTCUSDT", BarSpec{Type: BarTime}); err == nil {
+		t.Errorf("NewBarBuilder accepted a time spec without an interval")
+	}
+}
@@ -4,36 +4,100 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"strconv"
 	"time"
 
 	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
 	"github.com/lavumi/crypto-quant/internal/domain"
 )
 
 // Collector collects historical candle data
 type Collector struct {
 	client     *binance.Client
-	candleRepo *CandleRepository
+	source     domain.KlineSource // Other venues; nil collects from client
+	candleRepo CandleStore
+	validator  *Validator
+	limiter    *RateLimiter
+	jobRepo    *JobRepository
 }
 
-// NewCollector creates a new historical data collector
-func NewCollector(client *binance.Client, candleRepo *CandleRepository) *Collector {
+// NewCollector creates a new historical data collector.
+// Collectors sharing a client also share its request weight limiter.
+func NewCollector(client *binance.Client, db *database.DB, candleRepo CandleStore) *Collector {
 	return &Collector{
 		client:     client,
 		candleRepo: candleRepo,
+		validator:  NewValidator(NewQuarantineRepository(db), DefaultQualityOptions()),
+		limiter:    ClientRateLimiter(client),
+		jobRepo:    NewJobRepository(db),
 	}
 }
 
+// NewSourceCollector creates a collector that fetches candles from another
+// venue's kline source. Candles are saved under the symbol given to the
+// collector's methods; see StorageSymbol to keep venues apart. Sources pace
+// their own requests, so the collector has no weight limiter.
+func NewSourceCollector(source domain.KlineSource, db *database.DB, candleRepo CandleStore) *Collector {
+	return &Collector{
+		source:     source,
+		candleRepo: candleRepo,
+		validator:  NewValidator(NewQuarantineRepository(db), DefaultQualityOptions()),
+		jobRepo:    NewJobRepository(db),
+	}
+}
+
+// Venue names the venue the collector fetches from
+func (c *Collector) Venue() string {
+	if c.source != nil {
+		return c.source.Venue()
+	}
+	return BinanceVenue
+}
+
+// ClientRateLimiter returns the limiter installed on the client's transport,
+// installing a new one if the client doesn't have one yet
+func ClientRateLimiter(client *binance.Client) *RateLimiter {
+	if client == nil {
+		return NewRateLimiter(DefaultWeightPerMinute)
+	}
+
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if t, ok := httpClient.Transport.(*rateLimitTransport); ok {
+		return t.limiter
+	}
+
+	// Copy the client rather than modifying a shared one (e.g. http.DefaultClient)
+	limiter := NewRateLimiter(DefaultWeightPerMinute)
+	wrapped := *httpClient
+	wrapped.Transport = limiter.Transport(httpClient.Transport)
+	client.HTTPClient = &wrapped
+	return limiter
+}
+
 // CollectHistorical collects historical candle data for a symbol
 func (c *Collector) CollectHistorical(ctx context.Context, symbol, interval string, startTime, endTime time.Time) error {
+	_, err := c.collect(ctx, symbol, interval, startTime, endTime, nil)
+	return err
+}
+
+// collect fetches and stores candles for the range and returns the total saved.
+// onBatch (optional) is called after each committed batch with the number of
+// candles saved and the time the next batch starts from.
+func (c *Collector) collect(ctx context.Context, symbol, interval string, startTime, endTime time.Time, onBatch func(saved int, next time.Time) error) (int, error) {
 	log.Printf("Collecting historical data for %s (%s) from %s to %s",
 		symbol, interval, startTime.Format("2006-01-02"), endTime.Format("2006-01-02"))
 
-	// Binance allows max 1000 candles per request
-	const maxLimit = 1000
+	// Binance allows max 1000 candles per request; other venues report their own page size
+	maxLimit := 1000
+	if c.source != nil {
+		maxLimit = c.source.MaxKlineLimit()
+	}
 	const maxRetries = 3
-	const baseDelay = 250 * time.Millisecond // More conservative rate limiting
 
 	currentStart := startTime
 	totalCandles := 0
@@ -42,7 +106,7 @@ func (c *Collector) CollectHistorical(ctx context.Context, symbol, interval stri
 	// Calculate estimated total batches for progress tracking
 	intervalDuration := parseInterval(interval)
 	totalDuration := endTime.Sub(startTime)
-	estimatedBatches := int(totalDuration / (intervalDuration * maxLimit))
+	estimatedBatches := int(totalDuration / (intervalDuration * time.Duration(maxLimit)))
 	if estimatedBatches == 0 {
 		estimatedBatches = 1
 	}
@@ -50,7 +114,7 @@ func (c *Collector) CollectHistorical(ctx context.Context, symbol, interval stri
 	for currentStart.Before(endTime) {
 		// Calculate end time for this batch
 		var limit int
-		batchEnd := currentStart.Add(intervalDuration * maxLimit)
+		batchEnd := currentStart.Add(intervalDuration * time.Duration(maxLimit))
 		if batchEnd.After(endTime) {
 			batchEnd = endTime
 			// Calculate actual limit needed
@@ -68,21 +132,17 @@ func (c *Collector) CollectHistorical(ctx context.Context, symbol, interval stri
 		}
 
 		// Fetch klines with retry logic
-		var klines []*binance.Kline
+		var candles []*domain.Candle
 		var err error
 
 		for retry := 0; retry <= maxRetries; retry++ {
-			klines, err = c.client.NewKlinesService().
-				Symbol(symbol).
-				Interval(interval).
-				StartTime(currentStart.UnixMilli()).
-				EndTime(batchEnd.UnixMilli()).
-				Limit(limit).
-				Do(ctx)
-
+			candles, err = c.fetchKlines(ctx, symbol, interval, currentStart, batchEnd, limit)
 			if err == nil {
 				break // Success
 			}
+			if ctx.Err() != nil {
+				return totalCandles, ctx.Err()
+			}
 
 			// Check if it's a rate limit error (429) or other retryable error
 			if retry < maxRetries {
@@ -90,44 +150,35 @@ func (c *Collector) CollectHistorical(ctx context.Context, symbol, interval stri
 				backoffDelay := time.Duration(1<<uint(retry)) * time.Second
 				log.Printf("API error (attempt %d/%d): %v. Retrying after %v...",
 					retry+1, maxRetries, err, backoffDelay)
-				time.Sleep(backoffDelay)
+				select {
+				case <-time.After(backoffDelay):
+				case <-ctx.Done():
+					return totalCandles, ctx.Err()
+				}
 				continue
 			}
 
 			// Max retries exceeded
-			return fmt.Errorf("failed to fetch klines after %d retries: %w", maxRetries, err)
+			return totalCandles, fmt.Errorf("failed to fetch klines after %d retries: %w", maxRetries, err)
 		}
 
-		if len(klines) == 0 {
+		if len(candles) == 0 {
 			log.Printf("No more data available. Stopping collection.")
 			break
 		}
 
-		// Convert to candles
-		candles := make([]*domain.Candle, 0, len(klines))
-		for _, k := range klines {
-			open, _ := strconv.ParseFloat(k.Open, 64)
-			high, _ := strconv.ParseFloat(k.High, 64)
-			low, _ := strconv.ParseFloat(k.Low, 64)
-			close, _ := strconv.ParseFloat(k.Close, 64)
-			volume, _ := strconv.ParseFloat(k.Volume, 64)
-
-			candle := &domain.Candle{
-				Symbol:    symbol,
-				OpenTime:  time.Unix(k.OpenTime/1000, 0),
-				CloseTime: time.Unix(k.CloseTime/1000, 0),
-				Open:      open,
-				High:      high,
-				Low:       low,
-				Close:     close,
-				Volume:    volume,
-			}
-			candles = append(candles, candle)
+		// The next batch starts after the newest fetched bar, even if validation drops it
+		next := candles[len(candles)-1].CloseTime.Add(1 * time.Millisecond)
+
+		// Validate and quarantine bad rows before saving
+		candles, err = c.validator.Filter(ctx, candles, interval)
+		if err != nil {
+			return totalCandles, fmt.Errorf("failed to validate candles: %w", err)
 		}
 
 		// Save to database
 		if err := c.candleRepo.SaveBatch(ctx, candles, interval); err != nil {
-			return fmt.Errorf("failed to save candles: %w", err)
+			return totalCandles, fmt.Errorf("failed to save candles: %w", err)
 		}
 
 		totalCandles += len(candles)
@@ -142,56 +193,80 @@ func (c *Collector) CollectHistorical(ctx context.Context, symbol, interval stri
 			progress, batchCount, len(candles), totalCandles)
 
 		// Move to next batch
-		if len(klines) > 0 {
-			lastCandle := klines[len(klines)-1]
-			currentStart = time.Unix(lastCandle.CloseTime/1000, 0).Add(1 * time.Millisecond)
-		} else {
-			break
-		}
+		currentStart = next
 
-		// Rate limiting - be conservative to avoid 429 errors
-		// Binance limit: 1200 weight/min, klines = 1-2 weight
-		// 250ms = max ~4 req/sec = ~240 req/min (safe margin)
-		time.Sleep(baseDelay)
+		if onBatch != nil {
+			if err := onBatch(len(candles), currentStart); err != nil {
+				return totalCandles, err
+			}
+		}
 	}
 
 	log.Printf("✅ Historical data collection complete: %d total candles collected for %s in %d batches",
 		totalCandles, symbol, batchCount)
-	return nil
+	return totalCandles, nil
+}
+
+// fetchKlines fetches one batch of candles from the venue. Candles are
+// labelled with symbol as given, so namespaced symbols are stored as such.
+func (c *Collector) fetchKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	if c.source != nil {
+		_, market := SplitStorageSymbol(symbol)
+		candles, err := c.source.GetKlines(ctx, market, interval, start, end, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, candle := range candles {
+			candle.Symbol = symbol
+		}
+		return candles, nil
+	}
+
+	// Shared weight budget; also honors global backoff after 429/418
+	if err := c.limiter.Wait(ctx, KlinesWeight); err != nil {
+		return nil, err
+	}
+
+	klines, err := c.client.NewKlinesService().
+		Symbol(symbol).
+		Interval(interval).
+		StartTime(start.UnixMilli()).
+		EndTime(end.UnixMilli()).
+		Limit(limit).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to candles
+	candles := make([]*domain.Candle, 0, len(klines))
+	for _, k := range klines {
+		open, _ := strconv.ParseFloat(k.Open, 64)
+		high, _ := strconv.ParseFloat(k.High, 64)
+		low, _ := strconv.ParseFloat(k.Low, 64)
+		close, _ := strconv.ParseFloat(k.Close, 64)
+		volume, _ := strconv.ParseFloat(k.Volume, 64)
+
+		candle := &domain.Candle{
+			Symbol:    symbol,
+			OpenTime:  domain.TimeFromMillis(k.OpenTime),
+			CloseTime: domain.TimeFromMillis(k.CloseTime),
+			Open:      open,
+			High:      high,
+			Low:       low,
+			Close:     close,
+			Volume:    volume,
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
 }
 
-// parseInterval converts interval string to duration
+// parseInterval converts interval string to duration (defaults to 1 minute)
 func parseInterval(interval string) time.Duration {
-	switch interval {
-	case "1m":
-		return 1 * time.Minute
-	case "3m":
-		return 3 * time.Minute
-	case "5m":
-		return 5 * time.Minute
-	case "15m":
-		return 15 * time.Minute
-	case "30m":
-		return 30 * time.Minute
-	case "1h":
-		return 1 * time.Hour
-	case "2h":
-		return 2 * time.Hour
-	case "4h":
-		return 4 * time.Hour
-	case "6h":
-		return 6 * time.Hour
-	case "8h":
-		return 8 * time.Hour
-	case "12h":
-		return 12 * time.Hour
-	case "1d":
-		return 24 * time.Hour
-	case "3d":
-		return 3 * 24 * time.Hour
-	case "1w":
-		return 7 * 24 * time.Hour
-	default:
+	d, err := ParseInterval(interval)
+	if err != nil {
 		return 1 * time.Minute
 	}
+	return d
 }
@@ -0,0 +1,136 @@
+package history_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/datasource/exchange/binancemock"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+// The collector is tested from outside the package, since binancemock imports history
+
+var collectorStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newCollectorMock starts a mock Binance with the given number of 1m BTCUSDT
+// bars already closed
+func newCollectorMock(t *testing.T, bars int, faults binancemock.Faults) *binancemock.Server {
+	t.Helper()
+	candles := make([]*domain.Candle, 0, bars+10)
+	for i := 0; i < bars+10; i++ {
+		open := collectorStart.Add(time.Duration(i) * time.Minute)
+		price := 100 + float64(i%50)
+		candles = append(candles, &domain.Candle{
+			Symbol:    "BTCUSDT",
+			OpenTime:  open,
+			CloseTime: open.Add(time.Minute - time.Millisecond),
+			Open:      price,
+			High:      price + 1,
+			Low:       price - 1,
+			Close:     price,
+			Volume:    10,
+		})
+	}
+
+	srv, err := binancemock.New(binancemock.Config{Interval: "1m", Candles: candles, Warmup: bars, Faults: faults})
+	if err != nil {
+		t.Fatalf("failed to create mock server: %v", err)
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newMockCollector(t *testing.T, srv *binancemock.Server) (*history.Collector, history.CandleStore) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	stores, err := history.OpenStores(db, config.StorageConfig{})
+	if err != nil {
+		t.Fatalf("OpenStores: %v", err)
+	}
+	t.Cleanup(func() { stores.Close() })
+
+	client := binance.NewClient("", "")
+	client.BaseURL = srv.URL()
+	return history.NewCollector(client, db, stores.Candles), stores.Candles
+}
+
+func TestCollectorAgainstMock(t *testing.T) {
+	srv := newCollectorMock(t, 2500, binancemock.Faults{})
+	collector, store := newMockCollector(t, srv)
+	ctx := context.Background()
+
+	end := collectorStart.Add(2500 * time.Minute)
+	if err := collector.CollectHistorical(ctx, "BTCUSDT", "1m", collectorStart, end); err != nil {
+		t.Fatalf("CollectHistorical: %v", err)
+	}
+
+	candles, err := store.GetRange(ctx, "BTCUSDT", "1m", collectorStart, end)
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	if len(candles) != 2500 {
+		t.Fatalf("stored %d candles, want 2500", len(candles))
+	}
+	for i, c := range candles {
+		if want := collectorStart.Add(time.Duration(i) * time.Minute); !c.OpenTime.Equal(want) || c.Close != 100+float64(i%50) {
+			t.Fatalf("candle %d = %s close %v, want %s close %v", i, c.OpenTime, c.Close, want, 100+float64(i%50))
+		}
+	}
+}
+
+func TestCollectorRetriesFaults(t *testing.T) {
+	// Every third request is rate limited, and the first one stalls
+	srv := newCollectorMock(t, 2500, binancemock.Faults{RateLimitEvery: 3, StallFor: 100 * time.Millisecond})
+	srv.StallNext(1)
+	collector, store := newMockCollector(t, srv)
+	ctx := context.Background()
+
+	started := time.Now()
+	end := collectorStart.Add(2500 * time.Minute)
+	if err := collector.CollectHistorical(ctx, "BTCUSDT", "1m", collectorStart, end); err != nil {
+		t.Fatalf("CollectHistorical: %v", err)
+	}
+
+	// Requests: stall, batch 1, 429, batch 2, batch 3; each failure backs off 1s
+	if elapsed := time.Since(started); elapsed < 2*time.Second {
+		t.Errorf("collection took %s, want at least two 1s retry backoffs", elapsed)
+	}
+	candles, err := store.GetRange(ctx, "BTCUSDT", "1m", collectorStart, end)
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	if len(candles) != 2500 {
+		t.Errorf("stored %d candles, want 2500 despite the faults", len(candles))
+	}
+}
+
+func TestCollectorCancelledDuringBackoff(t *testing.T) {
+	// Every request is rate limited, so the collector only ever backs off
+	srv := newCollectorMock(t, 100, binancemock.Faults{RateLimitEvery: 1})
+	collector, _ := newMockCollector(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err := collector.CollectHistorical(ctx, "BTCUSDT", "1m", collectorStart, collectorStart.Add(100*time.Minute))
+	if err == nil {
+		t.Fatal("CollectHistorical succeeded, want the context error")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("cancelled collection returned after %s, want without waiting out the backoff", elapsed)
+	}
+}
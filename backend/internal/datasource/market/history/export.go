@@ -0,0 +1,102 @@
+package history
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// exportHeader is the CSV column order of exported candles
+var exportHeader = []string{
+	FieldSymbol, FieldOpenTime, FieldCloseTime, FieldOpen, FieldHigh, FieldLow, FieldClose, FieldVolume,
+}
+
+// parquetCandle is the Parquet row layout of exported candles.
+// Times are millisecond timestamps so pandas reads them as datetime64.
+type parquetCandle struct {
+	Symbol    string  `parquet:"symbol,dict"`
+	OpenTime  int64   `parquet:"open_time,timestamp(millisecond)"`
+	CloseTime int64   `parquet:"close_time,timestamp(millisecond)"`
+	Open      float64 `parquet:"open"`
+	High      float64 `parquet:"high"`
+	Low       float64 `parquet:"low"`
+	Close     float64 `parquet:"close"`
+	Volume    float64 `parquet:"volume"`
+}
+
+// ExportCandles writes stored candles for a symbol/interval/range to w as CSV or Parquet.
+// CSV times are epoch milliseconds. It returns the number of candles written.
+func (s *Service) ExportCandles(ctx context.Context, w io.Writer, format, symbol, interval string, start, end time.Time) (int, error) {
+	candles, err := s.candleRepo.GetRange(ctx, symbol, interval, start, end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load candles: %w", err)
+	}
+
+	switch format {
+	case "", FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportHeader); err != nil {
+			return 0, fmt.Errorf("failed to write csv: %w", err)
+		}
+		for _, c := range candles {
+			record := []string{
+				c.Symbol,
+				strconv.FormatInt(c.OpenTime.UnixMilli(), 10),
+				strconv.FormatInt(c.CloseTime.UnixMilli(), 10),
+				strconv.FormatFloat(c.Open, 'f', -1, 64),
+				strconv.FormatFloat(c.High, 'f', -1, 64),
+				strconv.FormatFloat(c.Low, 'f', -1, 64),
+				strconv.FormatFloat(c.Close, 'f', -1, 64),
+				strconv.FormatFloat(c.Volume, 'f', -1, 64),
+			}
+			if err := cw.Write(record); err != nil {
+				return 0, fmt.Errorf("failed to write csv: %w", err)
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return 0, fmt.Errorf("failed to write csv: %w", err)
+		}
+
+	case FormatParquet:
+		rows := make([]parquetCandle, len(candles))
+		for i, c := range candles {
+			rows[i] = parquetCandle{
+				Symbol:    c.Symbol,
+				OpenTime:  c.OpenTime.UnixMilli(),
+				CloseTime: c.CloseTime.UnixMilli(),
+				Open:      c.Open,
+				High:      c.High,
+				Low:       c.Low,
+				Close:     c.Close,
+				Volume:    c.Volume,
+			}
+		}
+		pw := parquet.NewGenericWriter[parquetCandle](w, parquet.Compression(&parquet.Snappy))
+		if _, err := pw.Write(rows); err != nil {
+			return 0, fmt.Errorf("failed to write parquet: %w", err)
+		}
+		if err := pw.Close(); err != nil {
+			return 0, fmt.Errorf("failed to write parquet: %w", err)
+		}
+
+	default:
+		return 0, fmt.Errorf("unsupported format: %s (use csv or parquet)", format)
+	}
+
+	return len(candles), nil
+}
+
+// ImportCandles imports a candle file into the candle tables
+func (s *Service) ImportCandles(ctx context.Context, r io.ReaderAt, size int64, name string, opts ImportOptions) (*ImportResult, error) {
+	result, err := s.importer.Import(ctx, r, size, name, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import candles: %w", err)
+	}
+	return result, nil
+}
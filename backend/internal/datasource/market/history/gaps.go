@@ -0,0 +1,214 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Gap represents a range of missing candles.
+// Start is the open time of the first missing candle and End the open time of the last one.
+type Gap struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Missing int       `json:"missing"`
+}
+
+// GapReport lists all missing candle ranges for a symbol/interval
+type GapReport struct {
+	Symbol   string    `json:"symbol"`
+	Interval string    `json:"interval"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Expected int       `json:"expected"`
+	Found    int       `json:"found"`
+	Missing  int       `json:"missing"`
+	Gaps     []Gap     `json:"gaps"`
+}
+
+// GapPolicy controls how backtests treat gaps in candle data
+type GapPolicy string
+
+const (
+	GapPolicyWarn        GapPolicy = "warn"   // Log gaps and run on the data as-is
+	GapPolicyRefuse      GapPolicy = "refuse" // Fail when any gap is present
+	GapPolicyForwardFill GapPolicy = "ffill"  // Fill gaps with flat candles at the previous close
+)
+
+// ParseGapPolicy parses a gap policy string (empty means warn)
+func ParseGapPolicy(s string) (GapPolicy, error) {
+	switch GapPolicy(s) {
+	case "", GapPolicyWarn:
+		return GapPolicyWarn, nil
+	case GapPolicyRefuse, GapPolicyForwardFill:
+		return GapPolicy(s), nil
+	default:
+		return "", fmt.Errorf("unknown gap policy: %s (use warn, refuse or ffill)", s)
+	}
+}
+
+// FindGaps returns the missing candle ranges between start (inclusive) and end (exclusive).
+// openTimes must be sorted ascending.
+func FindGaps(openTimes []time.Time, start, end time.Time, d time.Duration) []Gap {
+	gaps := make([]Gap, 0)
+
+	// First expected candle is the first bucket boundary at or after start
+	expected := AlignTime(start, d)
+	if expected.Before(start) {
+		expected = expected.Add(d)
+	}
+
+	addGap := func(from, to time.Time) {
+		if !from.Before(to) {
+			return
+		}
+		gaps = append(gaps, Gap{
+			Start:   from,
+			End:     to.Add(-d),
+			Missing: int(to.Sub(from) / d),
+		})
+	}
+
+	for _, t := range openTimes {
+		t = t.UTC()
+		if t.Before(expected) {
+			continue // Duplicate or unaligned candle
+		}
+		if !t.Before(end) {
+			break
+		}
+		addGap(expected, t)
+		expected = t.Add(d)
+	}
+
+	// Trailing gap up to the end of the range
+	last := AlignTime(end, d)
+	if last.Before(end) {
+		last = last.Add(d)
+	}
+	addGap(expected, last)
+
+	return gaps
+}
+
+// DetectGaps scans stored candles and reports every missing range
+func (s *Service) DetectGaps(ctx context.Context, symbol, interval string, start, end time.Time) (*GapReport, error) {
+	d, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// Never report bars that cannot exist yet
+	if now := AlignTime(time.Now(), d); end.After(now) {
+		end = now
+	}
+
+	openTimes, err := s.candleRepo.GetOpenTimes(ctx, symbol, interval, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan candles: %w", err)
+	}
+
+	gaps := FindGaps(openTimes, start, end, d)
+
+	report := &GapReport{
+		Symbol:   symbol,
+		Interval: interval,
+		From:     start,
+		To:       end,
+		Found:    len(openTimes),
+		Gaps:     gaps,
+	}
+	for _, g := range gaps {
+		report.Missing += g.Missing
+	}
+	report.Expected = report.Found + report.Missing
+
+	return report, nil
+}
+
+// BackfillGaps detects gaps and collects exactly those ranges from the exchange
+func (s *Service) BackfillGaps(ctx context.Context, symbol, interval string, start, end time.Time) (*GapReport, error) {
+	report, err := s.DetectGaps(ctx, symbol, interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	d, _ := ParseInterval(interval)
+	for _, g := range report.Gaps {
+		log.Printf("Backfilling %s %s gap: %s to %s (%d candles)",
+			symbol, interval, g.Start.Format(time.RFC3339), g.End.Format(time.RFC3339), g.Missing)
+
+		if err := s.collector.CollectHistorical(ctx, symbol, interval, g.Start, g.End.Add(d)); err != nil {
+			return nil, fmt.Errorf("failed to backfill gap at %s: %w", g.Start.Format(time.RFC3339), err)
+		}
+	}
+
+	// Report what is still missing (e.g. exchange downtime)
+	return s.DetectGaps(ctx, symbol, interval, start, end)
+}
+
+// ApplyGapPolicy checks loaded candles for gaps and handles them according to the policy
+func ApplyGapPolicy(candles []*domain.Candle, interval string, policy GapPolicy) ([]*domain.Candle, []Gap, error) {
+	d, err := ParseInterval(interval)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(candles) == 0 {
+		return candles, nil, nil
+	}
+
+	// Only look between the first and last loaded candle; edges are reported by validation
+	openTimes := make([]time.Time, len(candles))
+	for i, c := range candles {
+		openTimes[i] = c.OpenTime
+	}
+	gaps := FindGaps(openTimes, candles[0].OpenTime, candles[len(candles)-1].OpenTime.Add(d), d)
+	if len(gaps) == 0 {
+		return candles, gaps, nil
+	}
+
+	missing := 0
+	for _, g := range gaps {
+		missing += g.Missing
+	}
+
+	switch policy {
+	case GapPolicyRefuse:
+		return nil, gaps, fmt.Errorf("candle data has %d gaps (%d missing candles), first at %s",
+			len(gaps), missing, gaps[0].Start.Format(time.RFC3339))
+	case GapPolicyForwardFill:
+		log.Printf("Forward-filling %d gaps (%d missing candles)", len(gaps), missing)
+		return forwardFill(candles, d), gaps, nil
+	default:
+		log.Printf("⚠️  Candle data has %d gaps (%d missing candles), first at %s",
+			len(gaps), missing, gaps[0].Start.Format(time.RFC3339))
+		return candles, gaps, nil
+	}
+}
+
+// forwardFill inserts flat zero-volume candles at the previous close for each missing bar
+func forwardFill(candles []*domain.Candle, d time.Duration) []*domain.Candle {
+	filled := make([]*domain.Candle, 0, len(candles))
+	for i, c := range candles {
+		if i > 0 {
+			prev := filled[len(filled)-1]
+			for t := prev.OpenTime.Add(d); t.Before(c.OpenTime); t = t.Add(d) {
+				filled = append(filled, &domain.Candle{
+					Symbol:    prev.Symbol,
+					OpenTime:  t,
+					CloseTime: t.Add(d).Add(-time.Millisecond),
+					Open:      prev.Close,
+					High:      prev.Close,
+					Low:       prev.Close,
+					Close:     prev.Close,
+					Volume:    0,
+				})
+			}
+		}
+		filled = append(filled, c)
+	}
+	return filled
+}
@@ -0,0 +1,104 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+func hours(start time.Time, offsets ...int) []time.Time {
+	times := make([]time.Time, len(offsets))
+	for i, h := range offsets {
+		times[i] = start.Add(time.Duration(h) * time.Hour)
+	}
+	return times
+}
+
+func TestFindGaps(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+
+	tests := []struct {
+		name      string
+		openTimes []time.Time
+		start     time.Time
+		want      []Gap
+	}{
+		{
+			name:      "complete",
+			openTimes: hours(start, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9),
+			start:     start,
+			want:      nil,
+		},
+		{
+			name:      "leading, inner and trailing gaps",
+			openTimes: hours(start, 2, 3, 6, 7),
+			start:     start,
+			want: []Gap{
+				{Start: start, End: start.Add(time.Hour), Missing: 2},
+				{Start: start.Add(4 * time.Hour), End: start.Add(5 * time.Hour), Missing: 2},
+				{Start: start.Add(8 * time.Hour), End: start.Add(9 * time.Hour), Missing: 2},
+			},
+		},
+		{
+			name:      "duplicates and candles outside the range are ignored",
+			openTimes: hours(start, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11),
+			start:     start,
+			want:      nil,
+		},
+		{
+			name:      "unaligned start begins at the next boundary",
+			openTimes: hours(start, 1, 2, 3, 4, 5, 6, 7, 8, 9),
+			start:     start.Add(30 * time.Minute),
+			want:      nil,
+		},
+		{
+			name:  "empty range",
+			start: start,
+			want:  []Gap{{Start: start, End: start.Add(9 * time.Hour), Missing: 10}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindGaps(tt.openTimes, tt.start, end, time.Hour)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d gaps %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) || got[i].Missing != tt.want[i].Missing {
+					t.Errorf("gap %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyGapPolicy(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := []*domain.Candle{
+		{Symbol: "BTCUSDT", OpenTime: start, CloseTime: start.Add(time.Hour - time.Millisecond), Open: 100, High: 101, Low: 99, Close: 100, Volume: 1},
+		{Symbol: "BTCUSDT", OpenTime: start.Add(3 * time.Hour), CloseTime: start.Add(4*time.Hour - time.Millisecond), Open: 105, High: 106, Low: 104, Close: 105, Volume: 1},
+	}
+
+	if _, _, err := ApplyGapPolicy(candles, "1h", GapPolicyRefuse); err == nil {
+		t.Errorf("refuse policy accepted gapped candles")
+	}
+
+	filled, gaps, err := ApplyGapPolicy(candles, "1h", GapPolicyForwardFill)
+	if err != nil {
+		t.Fatalf("ApplyGapPolicy(ffill): %v", err)
+	}
+	if len(gaps) != 1 || gaps[0].Missing != 2 {
+		t.Errorf("gaps = %+v, want one gap of 2", gaps)
+	}
+	if len(filled) != 4 {
+		t.Fatalf("got %d candles after ffill, want 4", len(filled))
+	}
+	for _, c := range filled[1:3] {
+		if c.Open != 100 || c.Close != 100 || c.Volume != 0 {
+			t.Errorf("filled candle at %s = %+v, want a flat bar at the previous close", c.OpenTime, c)
+		}
+	}
+}
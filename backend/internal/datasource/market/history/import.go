@@ -0,0 +1,636 @@
+package history
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/parquet-go/parquet-go"
+)
+
+// File formats for import and export
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// Timestamp units for imported files
+const (
+	TimeUnitAuto        = "auto"
+	TimeUnitSeconds     = "s"
+	TimeUnitMillis      = "ms"
+	TimeUnitMicros      = "us"
+	TimeUnitNanoseconds = "ns"
+)
+
+// Candle fields that can be mapped to source columns
+const (
+	FieldOpenTime  = "open_time"
+	FieldCloseTime = "close_time"
+	FieldOpen      = "open"
+	FieldHigh      = "high"
+	FieldLow       = "low"
+	FieldClose     = "close"
+	FieldVolume    = "volume"
+	FieldSymbol    = "symbol"
+)
+
+// requiredFields must be present in every imported file
+var requiredFields = []string{FieldOpenTime, FieldOpen, FieldHigh, FieldLow, FieldClose, FieldVolume}
+
+// fieldAliases are the header names recognized for each field (lowercase)
+var fieldAliases = map[string][]string{
+	FieldOpenTime:  {"open_time", "opentime", "timestamp", "time", "date", "datetime", "ts", "t"},
+	FieldCloseTime: {"close_time", "closetime"},
+	FieldOpen:      {"open", "o"},
+	FieldHigh:      {"high", "h"},
+	FieldLow:       {"low", "l"},
+	FieldClose:     {"close", "c"},
+	FieldVolume:    {"volume", "vol", "v", "base_volume"},
+	FieldSymbol:    {"symbol", "pair", "ticker"},
+}
+
+// binanceKlineColumns is the column layout of headerless Binance kline dumps
+var binanceKlineColumns = map[string]int{
+	FieldOpenTime:  0,
+	FieldOpen:      1,
+	FieldHigh:      2,
+	FieldLow:       3,
+	FieldClose:     4,
+	FieldVolume:    5,
+	FieldCloseTime: 6,
+}
+
+// ColumnMapping maps candle fields to source column names or zero-based indexes
+type ColumnMapping map[string]string
+
+// ParseColumnMapping parses "field=column,..." (e.g. "open_time=ts,volume=5")
+func ParseColumnMapping(s string) (ColumnMapping, error) {
+	mapping := make(ColumnMapping)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid column mapping %q (use field=column)", pair)
+		}
+		if _, known := fieldAliases[field]; !known {
+			return nil, fmt.Errorf("unknown candle field in column mapping: %s", field)
+		}
+		mapping[field] = strings.TrimSpace(column)
+	}
+	return mapping, nil
+}
+
+// ImportOptions configures a candle file import
+type ImportOptions struct {
+	Symbol   string        // Used when the file has no symbol column
+	Interval string        // Target candles_<interval> table
+	Format   string        // csv or parquet; detected from the file name when empty
+	Columns  ColumnMapping // Overrides header detection for the given fields
+	TimeUnit string        // s, ms, us, ns or auto (by magnitude)
+
+	// BatchSize is the number of candles per insert transaction
+	// (0 = 5000, negative = the whole file in one transaction)
+	BatchSize int
+}
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	File       string    `json:"file"`
+	Symbol     string    `json:"symbol"`
+	Interval   string    `json:"interval"`
+	TimeUnit   string    `json:"time_unit"`
+	Rows       int       `json:"rows"`
+	Imported   int       `json:"imported"`
+	Duplicates int       `json:"duplicates"`
+	Rejected   int       `json:"rejected"`
+	Skipped    int       `json:"skipped"` // Rows that couldn't be parsed
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+}
+
+// Importer loads candle files into the candle tables
+type Importer struct {
+	candleRepo CandleStore
+	validator  *Validator
+}
+
+// NewImporter creates a new candle file importer
+func NewImporter(db *database.DB, candleRepo CandleStore) *Importer {
+	return &Importer{
+		candleRepo: candleRepo,
+		validator:  NewValidator(NewQuarantineRepository(db), DefaultQualityOptions()),
+	}
+}
+
+// ImportFile imports a CSV, gzip/zip CSV or Parquet file
+func (im *Importer) ImportFile(ctx context.Context, path string, opts ImportOptions) (*ImportResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	return im.Import(ctx, f, info.Size(), filepath.Base(path), opts)
+}
+
+// Import imports candles from r; name is used to detect the format and compression
+func (im *Importer) Import(ctx context.Context, r io.ReaderAt, size int64, name string, opts ImportOptions) (*ImportResult, error) {
+	if opts.Interval == "" {
+		return nil, fmt.Errorf("interval is required")
+	}
+	if _, err := ParseInterval(opts.Interval); err != nil {
+		return nil, err
+	}
+	if opts.TimeUnit == "" {
+		opts.TimeUnit = TimeUnitAuto
+	}
+
+	sources, err := openSources(r, size, name, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		File:     name,
+		Symbol:   opts.Symbol,
+		Interval: opts.Interval,
+		TimeUnit: opts.TimeUnit,
+	}
+
+	// Dedup on (symbol, open_time) within the file; the table upserts on the same key
+	bySymbol := make(map[string]map[int64]*domain.Candle)
+	for _, src := range sources {
+		if err := im.readSource(src, opts, result, bySymbol); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+
+	if err := im.candleRepo.EnsureTable(opts.Interval); err != nil {
+		return nil, err
+	}
+
+	for symbol, rows := range bySymbol {
+		candles := make([]*domain.Candle, 0, len(rows))
+		for _, c := range rows {
+			candles = append(candles, c)
+		}
+		sort.Slice(candles, func(i, j int) bool { return candles[i].OpenTime.Before(candles[j].OpenTime) })
+
+		valid, err := im.validator.Filter(ctx, candles, opts.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate candles: %w", err)
+		}
+		result.Rejected += len(candles) - len(valid)
+
+		batchSize := opts.BatchSize
+		if batchSize == 0 {
+			batchSize = 5000
+		} else if batchSize < 0 {
+			batchSize = len(valid)
+		}
+		for i := 0; i < len(valid); i += batchSize {
+			end := i + batchSize
+			if end > len(valid) {
+				end = len(valid)
+			}
+			if err := im.candleRepo.SaveBatch(ctx, valid[i:end], opts.Interval); err != nil {
+				return nil, fmt.Errorf("failed to save %s candles: %w", symbol, err)
+			}
+		}
+		result.Imported += len(valid)
+
+		if len(valid) > 0 {
+			if result.From.IsZero() || valid[0].OpenTime.Before(result.From) {
+				result.From = valid[0].OpenTime
+			}
+			if last := valid[len(valid)-1].OpenTime; last.After(result.To) {
+				result.To = last
+			}
+		}
+	}
+
+	result.Symbol = ""
+	for symbol := range bySymbol {
+		if result.Symbol != "" {
+			result.Symbol = "" // Multi-symbol file
+			break
+		}
+		result.Symbol = symbol
+	}
+
+	log.Printf("Imported %s: %d rows, %d candles saved, %d duplicates, %d rejected, %d unparseable",
+		name, result.Rows, result.Imported, result.Duplicates, result.Rejected, result.Skipped)
+	return result, nil
+}
+
+// readSource maps and parses every record of a source into bySymbol
+func (im *Importer) readSource(src recordSource, opts ImportOptions, result *ImportResult, bySymbol map[string]map[int64]*domain.Candle) error {
+	defer src.Close()
+
+	first, err := src.Next()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// A first row without any numeric cell is a header
+	header := src.Header()
+	if header == nil && !hasNumeric(first) {
+		header = first
+		first = nil
+	}
+
+	columns, err := resolveColumns(header, opts.Columns)
+	if err != nil {
+		return err
+	}
+	if _, ok := columns[FieldSymbol]; !ok && opts.Symbol == "" {
+		return fmt.Errorf("symbol is required when the file has no symbol column")
+	}
+	d, _ := ParseInterval(opts.Interval)
+
+	record := first
+	for {
+		if record != nil {
+			result.Rows++
+			c, err := parseCandleRecord(record, columns, opts, d, &result.TimeUnit)
+			if err != nil {
+				result.Skipped++
+				if result.Skipped <= 5 {
+					log.Printf("Skipping row %d: %v", result.Rows, err)
+				}
+			} else {
+				rows, ok := bySymbol[c.Symbol]
+				if !ok {
+					rows = make(map[int64]*domain.Candle)
+					bySymbol[c.Symbol] = rows
+				}
+				if _, dup := rows[c.OpenTime.UnixMilli()]; dup {
+					result.Duplicates++
+				}
+				rows[c.OpenTime.UnixMilli()] = c
+			}
+		}
+
+		record, err = src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// resolveColumns returns the source column index of each mapped field
+func resolveColumns(header []string, mapping ColumnMapping) (map[string]int, error) {
+	columns := make(map[string]int)
+
+	if header == nil {
+		for field, idx := range binanceKlineColumns {
+			columns[field] = idx
+		}
+	} else {
+		names := make(map[string]int, len(header))
+		for i, h := range header {
+			names[strings.ToLower(strings.TrimSpace(h))] = i
+		}
+		for field, aliases := range fieldAliases {
+			for _, alias := range aliases {
+				if idx, ok := names[alias]; ok {
+					columns[field] = idx
+					break
+				}
+			}
+		}
+	}
+
+	// Explicit mapping wins: a column name, or an index if it is a number
+	for field, column := range mapping {
+		if idx, err := strconv.Atoi(column); err == nil {
+			columns[field] = idx
+			continue
+		}
+		found := false
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), column) {
+				columns[field] = i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column %q mapped to %s not found in header", column, field)
+		}
+	}
+
+	for _, field := range requiredFields {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("no column for %s (set a column mapping)", field)
+		}
+	}
+	return columns, nil
+}
+
+// parseCandleRecord converts one source record to a candle.
+// timeUnit is resolved from the first timestamp when it is auto.
+func parseCandleRecord(record []string, columns map[string]int, opts ImportOptions, d time.Duration, timeUnit *string) (*domain.Candle, error) {
+	field := func(name string) (string, bool) {
+		idx, ok := columns[name]
+		if !ok || idx >= len(record) {
+			return "", false
+		}
+		return strings.TrimSpace(record[idx]), true
+	}
+	number := func(name string) (float64, error) {
+		s, ok := field(name)
+		if !ok {
+			return 0, fmt.Errorf("missing %s", name)
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", name, s)
+		}
+		return v, nil
+	}
+
+	c := &domain.Candle{Symbol: opts.Symbol}
+	if s, ok := field(FieldSymbol); ok && s != "" {
+		c.Symbol = strings.ToUpper(s)
+	}
+
+	s, _ := field(FieldOpenTime)
+	openTime, err := parseTimestamp(s, timeUnit)
+	if err != nil {
+		return nil, err
+	}
+	c.OpenTime = openTime
+
+	if s, ok := field(FieldCloseTime); ok && s != "" {
+		if c.CloseTime, err = parseTimestamp(s, timeUnit); err != nil {
+			return nil, err
+		}
+	} else {
+		c.CloseTime = c.OpenTime.Add(d).Add(-time.Millisecond)
+	}
+
+	if c.Open, err = number(FieldOpen); err != nil {
+		return nil, err
+	}
+	if c.High, err = number(FieldHigh); err != nil {
+		return nil, err
+	}
+	if c.Low, err = number(FieldLow); err != nil {
+		return nil, err
+	}
+	if c.Close, err = number(FieldClose); err != nil {
+		return nil, err
+	}
+	if c.Volume, err = number(FieldVolume); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// parseTimestamp parses a numeric epoch in *unit or a date string.
+// An auto unit is resolved from the magnitude of the first numeric timestamp.
+func parseTimestamp(s string, unit *string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		if *unit == TimeUnitAuto {
+			*unit = DetectTimeUnit(int64(v))
+		}
+		switch *unit {
+		case TimeUnitSeconds:
+			return time.Unix(0, int64(v*1e9)).UTC(), nil
+		case TimeUnitMillis:
+			return time.UnixMilli(int64(v)).UTC(), nil
+		case TimeUnitMicros:
+			return time.UnixMicro(int64(v)).UTC(), nil
+		case TimeUnitNanoseconds:
+			return time.Unix(0, int64(v)).UTC(), nil
+		default:
+			return time.Time{}, fmt.Errorf("unknown time unit: %s", *unit)
+		}
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// DetectTimeUnit guesses the unit of an epoch timestamp from its magnitude
+// (valid for dates between 1973 and 5138)
+func DetectTimeUnit(v int64) string {
+	if v < 0 {
+		v = -v
+	}
+	switch {
+	case v < 1e11:
+		return TimeUnitSeconds
+	case v < 1e14:
+		return TimeUnitMillis
+	case v < 1e17:
+		return TimeUnitMicros
+	default:
+		return TimeUnitNanoseconds
+	}
+}
+
+// hasNumeric reports whether any cell of a record parses as a number
+func hasNumeric(record []string) bool {
+	for _, cell := range record {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(cell), 64); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSource yields string records from a CSV or Parquet file
+type recordSource interface {
+	Header() []string // nil if the header is in the records (CSV)
+	Next() ([]string, error)
+	Close() error
+}
+
+// openSources opens the records of a file, one source per CSV inside a zip
+func openSources(r io.ReaderAt, size int64, name, format string) ([]recordSource, error) {
+	lower := strings.ToLower(name)
+	if format == "" {
+		format = FormatCSV
+		if strings.HasSuffix(lower, ".parquet") || strings.HasSuffix(lower, ".pq") {
+			format = FormatParquet
+		}
+	}
+
+	switch format {
+	case FormatParquet:
+		src, err := newParquetSource(r, size)
+		if err != nil {
+			return nil, err
+		}
+		return []recordSource{src}, nil
+	case FormatCSV:
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (use csv or parquet)", format)
+	}
+
+	stream := io.NewSectionReader(r, 0, size)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		sources := make([]recordSource, 0, len(zr.File))
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() || !strings.HasSuffix(strings.ToLower(zf.Name), ".csv") {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s in zip: %w", zf.Name, err)
+			}
+			sources = append(sources, newCSVSource(rc, rc))
+		}
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("no csv files in %s", name)
+		}
+		return sources, nil
+	case strings.HasSuffix(lower, ".gz"):
+		gz, err := gzip.NewReader(stream)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip: %w", err)
+		}
+		return []recordSource{newCSVSource(gz, gz)}, nil
+	default:
+		return []recordSource{newCSVSource(stream, nil)}, nil
+	}
+}
+
+// csvSource reads records from CSV
+type csvSource struct {
+	reader *csv.Reader
+	closer io.Closer
+}
+
+// newCSVSource creates a CSV record source; closer may be nil
+func newCSVSource(r io.Reader, closer io.Closer) *csvSource {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return &csvSource{reader: reader, closer: closer}
+}
+
+func (s *csvSource) Header() []string { return nil }
+
+func (s *csvSource) Next() ([]string, error) { return s.reader.Read() }
+
+func (s *csvSource) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// parquetSource reads flat Parquet rows as strings
+type parquetSource struct {
+	reader *parquet.Reader
+	header []string
+	rows   []parquet.Row
+	n, pos int
+}
+
+// newParquetSource creates a Parquet record source. The file is opened first,
+// since parquet.NewReader panics on files it can't read.
+func newParquetSource(r io.ReaderAt, size int64) (*parquetSource, error) {
+	file, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet: %w", err)
+	}
+	reader := parquet.NewReader(file)
+	columns := reader.Schema().Columns()
+	header := make([]string, len(columns))
+	for i, path := range columns {
+		header[i] = path[len(path)-1]
+	}
+	return &parquetSource{
+		reader: reader,
+		header: header,
+		rows:   make([]parquet.Row, 1024),
+	}, nil
+}
+
+func (s *parquetSource) Header() []string { return s.header }
+
+func (s *parquetSource) Next() ([]string, error) {
+	if s.pos >= s.n {
+		n, err := s.reader.ReadRows(s.rows)
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		s.n, s.pos = n, 0
+	}
+
+	row := s.rows[s.pos]
+	s.pos++
+
+	record := make([]string, len(s.header))
+	for _, v := range row {
+		if col := v.Column(); col >= 0 && col < len(record) {
+			record[col] = parquetValueString(v)
+		}
+	}
+	return record, nil
+}
+
+func (s *parquetSource) Close() error { return s.reader.Close() }
+
+// parquetValueString formats a Parquet value for parsing
+func parquetValueString(v parquet.Value) string {
+	if v.IsNull() {
+		return ""
+	}
+	switch v.Kind() {
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'g', -1, 32)
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'g', -1, 64)
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func newTestImporter(t *testing.T) (*Importer, *CandleRepository) {
+	t.Helper()
+	db := newTestDB(t)
+	repo := NewCandleRepository(db)
+	return NewImporter(db, repo), repo
+}
+
+func TestImportCSV(t *testing.T) {
+	ctx := context.Background()
+	importer, repo := newTestImporter(t)
+
+	// Header aliases, a duplicate row, an unparseable row and an invalid candle
+	data := strings.Join([]string{
+		"timestamp,o,h,l,c,vol",
+		"1704067200,100,101,99,100.5,10",
+		"1704070800,100.5,102,100,101,12",
+		"1704070800,100.5,102,100,101,12",
+		"1704074400,abc,102,100,101,12",
+		"1704078000,101,100,102,101,5",
+		"1704081600,101,103,100,102,8",
+	}, "\n")
+
+	result, err := importer.Import(ctx, strings.NewReader(data), int64(len(data)), "btc.csv", ImportOptions{Symbol: "BTCUSDT", Interval: "1h"})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if result.TimeUnit != TimeUnitSeconds {
+		t.Errorf("time unit = %s, want %s", result.TimeUnit, TimeUnitSeconds)
+	}
+	if result.Rows != 6 || result.Duplicates != 1 || result.Skipped != 1 || result.Rejected != 1 || result.Imported != 3 {
+		t.Errorf("result = %+v", result)
+	}
+
+	candles, err := repo.GetRange(ctx, "BTCUSDT", "1h", qualityStart, qualityStart.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	if len(candles) != 3 {
+		t.Fatalf("stored %d candles, want 3", len(candles))
+	}
+	if !candles[0].OpenTime.Equal(qualityStart) || candles[0].Close != 100.5 {
+		t.Errorf("first candle = %+v", candles[0])
+	}
+	if want := qualityStart.Add(time.Hour - time.Millisecond); !candles[0].CloseTime.Equal(want) {
+		t.Errorf("close time = %s, want %s", candles[0].CloseTime, want)
+	}
+}
+
+func TestImportBinanceKlineCSV(t *testing.T) {
+	ctx := context.Background()
+	importer, _ := newTestImporter(t)
+
+	// Headerless Binance dump with millisecond times and the symbol from options
+	data := "1704067200000,100,101,99,100.5,10,1704070799999,1000,5,5,500,0\n" +
+		"1704070800000,100.5,102,100,101,12,1704074399999,1200,6,6,600,0\n"
+
+	result, err := importer.Import(ctx, strings.NewReader(data), int64(len(data)), "BTCUSDT-1h-2024-01.csv", ImportOptions{Symbol: "BTCUSDT", Interval: "1h"})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if result.Imported != 2 || result.TimeUnit != TimeUnitMillis {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestImportRequiresSymbol(t *testing.T) {
+	importer, _ := newTestImporter(t)
+	data := "open_time,open,high,low,close,volume\n1704067200000,100,101,99,100,1\n"
+	if _, err := importer.Import(context.Background(), strings.NewReader(data), int64(len(data)), "x.csv", ImportOptions{Interval: "1h"}); err == nil {
+		t.Errorf("Import without a symbol column or option succeeded")
+	}
+}
+
+func TestImportParquet(t *testing.T) {
+	ctx := context.Background()
+	importer, _ := newTestImporter(t)
+
+	var buf bytes.Buffer
+	w := parquet.NewGenericWriter[parquetCandle](&buf)
+	rows := []parquetCandle{
+		{Symbol: "ethusdt", OpenTime: 1704067200000, CloseTime: 1704070799999, Open: 2000, High: 2010, Low: 1990, Close: 2005, Volume: 3},
+		{Symbol: "ethusdt", OpenTime: 1704070800000, CloseTime: 1704074399999, Open: 2005, High: 2020, Low: 2000, Close: 2015, Volume: 4},
+	}
+	if _, err := w.Write(rows); err != nil {
+		t.Fatalf("failed to write parquet: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close parquet writer: %v", err)
+	}
+
+	result, err := importer.Import(ctx, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "eth.parquet", ImportOptions{Interval: "1h"})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if result.Imported != 2 || result.Symbol != "ETHUSDT" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestImportMalformedParquet(t *testing.T) {
+	importer, _ := newTestImporter(t)
+	data := []byte("PAR1 this is not a parquet file")
+
+	_, err := importer.Import(context.Background(), bytes.NewReader(data), int64(len(data)), "bad.parquet", ImportOptions{Symbol: "BTCUSDT", Interval: "1h"})
+	if err == nil || !strings.Contains(err.Error(), "failed to open parquet") {
+		t.Errorf("Import of a malformed parquet file returned %v", err)
+	}
+}
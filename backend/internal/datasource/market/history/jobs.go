@@ -0,0 +1,236 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Collection job statuses
+const (
+	JobStatusQueued  = "queued"
+	JobStatusRunning = "running"
+	JobStatusFailed  = "failed"
+	JobStatusDone    = "done"
+)
+
+// CollectionJob is a persisted collection of one symbol/interval range.
+// Everything before Checkpoint has been committed, so a failed or interrupted
+// job resumes from there instead of from Start.
+type CollectionJob struct {
+	ID           int64      `json:"id"`
+	Symbol       string     `json:"symbol"`
+	Interval     string     `json:"interval"`
+	Start        time.Time  `json:"start"`
+	End          time.Time  `json:"end"`
+	Checkpoint   time.Time  `json:"checkpoint"`
+	Status       string     `json:"status"`
+	CandlesSaved int        `json:"candles_saved"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"` // Start of the current or last run
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Progress     float64    `json:"progress"`              // Percent of the range committed
+	ETASeconds   *int64     `json:"eta_seconds,omitempty"` // Only while running
+
+	// RunFrom is the checkpoint when the current run started; ETA uses the rate since then
+	RunFrom time.Time `json:"-"`
+}
+
+// updateProgress derives Progress and ETA from the checkpoint
+func (j *CollectionJob) updateProgress(now time.Time) {
+	j.ETASeconds = nil
+
+	total := j.End.Sub(j.Start)
+	if total <= 0 || j.Status == JobStatusDone {
+		j.Progress = 100
+		return
+	}
+	j.Progress = float64(j.Checkpoint.Sub(j.Start)) / float64(total) * 100
+	if j.Progress > 100 {
+		j.Progress = 100
+	}
+
+	if j.Status != JobStatusRunning || j.StartedAt == nil {
+		return
+	}
+	covered := j.Checkpoint.Sub(j.RunFrom)
+	elapsed := now.Sub(*j.StartedAt)
+	if covered <= 0 || elapsed <= 0 {
+		return
+	}
+	remaining := j.End.Sub(j.Checkpoint)
+	eta := int64(elapsed.Seconds() * float64(remaining) / float64(covered))
+	j.ETASeconds = &eta
+}
+
+// PrepareJob returns the unfinished job that covers start for a symbol/interval,
+// extending its end if needed, or creates a new queued job for the range
+func (c *Collector) PrepareJob(ctx context.Context, symbol, interval string, start, end time.Time) (*CollectionJob, error) {
+	if _, err := ParseInterval(interval); err != nil {
+		return nil, err
+	}
+
+	job, err := c.jobRepo.FindResumable(ctx, symbol, interval, start)
+	if err != nil {
+		return nil, err
+	}
+	if job != nil {
+		if end.After(job.End) {
+			job.End = end
+			if err := c.jobRepo.Update(ctx, job); err != nil {
+				return nil, err
+			}
+		}
+		log.Printf("Resuming collection job #%d for %s %s from %s",
+			job.ID, symbol, interval, job.Checkpoint.UTC().Format(time.RFC3339))
+		return job, nil
+	}
+
+	job = &CollectionJob{
+		Symbol:     symbol,
+		Interval:   interval,
+		Start:      start,
+		End:        end,
+		Checkpoint: start,
+		RunFrom:    start,
+		Status:     JobStatusQueued,
+	}
+	if err := c.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RunJob collects a job from its checkpoint, committing the checkpoint after
+// every saved batch. onBatch (optional) receives the candles saved per batch.
+func (c *Collector) RunJob(ctx context.Context, job *CollectionJob, onBatch func(saved int)) error {
+	if err := c.candleRepo.EnsureTable(job.Interval); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	job.Status = JobStatusRunning
+	job.Error = ""
+	job.StartedAt = &now
+	job.RunFrom = job.Checkpoint
+	if err := c.jobRepo.Update(ctx, job); err != nil {
+		return err
+	}
+
+	_, err := c.collect(ctx, job.Symbol, job.Interval, job.Checkpoint, job.End, func(saved int, next time.Time) error {
+		job.Checkpoint = next
+		job.CandlesSaved += saved
+		if onBatch != nil {
+			onBatch(saved)
+		}
+		return c.jobRepo.Update(ctx, job)
+	})
+
+	// Record the outcome even if ctx was cancelled
+	if err != nil {
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = JobStatusDone
+		job.Checkpoint = job.End
+	}
+	if updateErr := c.jobRepo.Update(context.Background(), job); updateErr != nil {
+		log.Printf("Failed to record collection job #%d outcome: %v", job.ID, updateErr)
+	}
+	job.updateProgress(time.Now())
+
+	if err != nil {
+		return fmt.Errorf("collection job #%d failed at %s: %w",
+			job.ID, job.Checkpoint.UTC().Format(time.RFC3339), err)
+	}
+	return nil
+}
+
+// CollectResumable collects a range as a persisted job, resuming an earlier
+// unfinished job for the same symbol/interval if there is one
+func (c *Collector) CollectResumable(ctx context.Context, symbol, interval string, start, end time.Time) (*CollectionJob, error) {
+	job, err := c.PrepareJob(ctx, symbol, interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return job, c.RunJob(ctx, job, nil)
+}
+
+// SubmitCollectionJob creates or resumes a collection job and runs it in the background
+func (s *Service) SubmitCollectionJob(ctx context.Context, symbol, interval string, start, end time.Time) (*CollectionJob, error) {
+	job, err := s.collector.PrepareJob(ctx, symbol, interval, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare collection job: %w", err)
+	}
+	return s.startJob(job)
+}
+
+// ResumeCollectionJob restarts an unfinished job from its checkpoint in the background
+func (s *Service) ResumeCollectionJob(ctx context.Context, id int64) (*CollectionJob, error) {
+	job, err := s.jobRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, nil
+	}
+	if job.Status == JobStatusDone {
+		return nil, fmt.Errorf("collection job #%d is already done", id)
+	}
+	return s.startJob(job)
+}
+
+// startJob runs a job in the background unless it is already running in this process
+func (s *Service) startJob(job *CollectionJob) (*CollectionJob, error) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	if s.activeJobs[job.ID] {
+		return s.GetCollectionJob(context.Background(), job.ID)
+	}
+	s.activeJobs[job.ID] = true
+
+	job.Status = JobStatusQueued
+	job.Error = ""
+	if err := s.jobRepo.Update(context.Background(), job); err != nil {
+		delete(s.activeJobs, job.ID)
+		return nil, err
+	}
+	job.updateProgress(time.Now())
+	snapshot := *job
+
+	go func() {
+		defer func() {
+			s.jobsMu.Lock()
+			delete(s.activeJobs, job.ID)
+			s.jobsMu.Unlock()
+		}()
+
+		// Outlives the request that submitted it
+		if err := s.collector.RunJob(context.Background(), job, nil); err != nil {
+			log.Printf("❌ %v", err)
+		}
+	}()
+
+	return &snapshot, nil
+}
+
+// GetCollectionJob returns a collection job by ID, or nil if it doesn't exist
+func (s *Service) GetCollectionJob(ctx context.Context, id int64) (*CollectionJob, error) {
+	job, err := s.jobRepo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection job: %w", err)
+	}
+	return job, nil
+}
+
+// ListCollectionJobs returns recent collection jobs, optionally filtered by status
+func (s *Service) ListCollectionJobs(ctx context.Context, status string, limit int) ([]*CollectionJob, error) {
+	jobs, err := s.jobRepo.List(ctx, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collection jobs: %w", err)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,212 @@
+package history
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestJobCollector(t *testing.T) (*Collector, *fakeKlineSource) {
+	t.Helper()
+	db := newTestDB(t)
+	source := &fakeKlineSource{cutoff: qualityStart.Add(time.Hour)}
+	return NewSourceCollector(source, db, NewCandleRepository(db)), source
+}
+
+func TestJobRepositoryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepository(newTestDB(t))
+
+	started := qualityStart.Add(time.Minute)
+	job := &CollectionJob{
+		Symbol:     "BTCUSDT",
+		Interval:   "1m",
+		Start:      qualityStart,
+		End:        qualityStart.Add(10 * time.Minute),
+		Checkpoint: qualityStart.Add(4 * time.Minute),
+		RunFrom:    qualityStart.Add(2 * time.Minute),
+		StartedAt:  &started,
+		Status:     JobStatusFailed,
+		Error:      "boom",
+	}
+	if err := repo.Create(ctx, job); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if job.ID == 0 {
+		t.Fatal("Create didn't set an ID")
+	}
+
+	got, err := repo.Get(ctx, job.ID)
+	if err != nil || got == nil {
+		t.Fatalf("Get = %v, %v", got, err)
+	}
+	if !got.Start.Equal(job.Start) || !got.End.Equal(job.End) || !got.Checkpoint.Equal(job.Checkpoint) ||
+		!got.RunFrom.Equal(job.RunFrom) || got.StartedAt == nil || !got.StartedAt.Equal(started) {
+		t.Errorf("job times = %+v, want %+v", got, job)
+	}
+	if got.Status != JobStatusFailed || got.Error != "boom" || got.Progress != 40 {
+		t.Errorf("job = %s %q %.0f%%, want failed \"boom\" 40%%", got.Status, got.Error, got.Progress)
+	}
+
+	if missing, err := repo.Get(ctx, job.ID+1); err != nil || missing != nil {
+		t.Errorf("Get(missing) = %v, %v, want nil", missing, err)
+	}
+}
+
+func TestJobRepositoryFindResumable(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepository(newTestDB(t))
+
+	create := func(start, end time.Time, status string) *CollectionJob {
+		job := &CollectionJob{Symbol: "BTCUSDT", Interval: "1m", Start: start, End: end, Checkpoint: start, RunFrom: start, Status: status}
+		if err := repo.Create(ctx, job); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return job
+	}
+	create(qualityStart, qualityStart.Add(time.Hour), JobStatusDone)
+	failed := create(qualityStart, qualityStart.Add(time.Hour), JobStatusFailed)
+
+	tests := []struct {
+		name  string
+		start time.Time
+		want  int64
+	}{
+		{"inside the range", qualityStart.Add(30 * time.Minute), failed.ID},
+		{"at the end", qualityStart.Add(time.Hour), failed.ID},
+		{"before the range", qualityStart.Add(-time.Minute), 0},
+		{"after the range", qualityStart.Add(2 * time.Hour), 0},
+	}
+	for _, tt := range tests {
+		job, err := repo.FindResumable(ctx, "BTCUSDT", "1m", tt.start)
+		if err != nil {
+			t.Fatalf("FindResumable: %v", err)
+		}
+		var got int64
+		if job != nil {
+			got = job.ID
+		}
+		if got != tt.want {
+			t.Errorf("%s: found job %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if jobs, err := repo.List(ctx, JobStatusDone, 10); err != nil || len(jobs) != 1 {
+		t.Errorf("List(done) = %d jobs, %v, want 1", len(jobs), err)
+	}
+}
+
+func TestRunJobStatusTransitions(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestJobCollector(t)
+
+	job, err := c.PrepareJob(ctx, "BTCUSDT", "1m", qualityStart, qualityStart.Add(10*time.Minute))
+	if err != nil {
+		t.Fatalf("PrepareJob: %v", err)
+	}
+	if job.Status != JobStatusQueued || !job.Checkpoint.Equal(qualityStart) {
+		t.Errorf("new job = %s at %s, want queued at the start", job.Status, job.Checkpoint)
+	}
+
+	// Every batch commits the checkpoint of a running job
+	var checkpoints []time.Time
+	err = c.RunJob(ctx, job, func(saved int) {
+		stored, err := c.jobRepo.Get(ctx, job.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if stored.Status != JobStatusRunning {
+			t.Errorf("status during run = %s, want %s", stored.Status, JobStatusRunning)
+		}
+		checkpoints = append(checkpoints, stored.Checkpoint)
+	})
+	if err != nil {
+		t.Fatalf("RunJob: %v", err)
+	}
+
+	// The callback runs before the batch's checkpoint is stored
+	want := []time.Time{qualityStart, qualityStart.Add(3 * time.Minute), qualityStart.Add(6 * time.Minute), qualityStart.Add(9 * time.Minute)}
+	if len(checkpoints) != len(want) {
+		t.Fatalf("got %d batches, want %d", len(checkpoints), len(want))
+	}
+	for i := range want {
+		if !checkpoints[i].Equal(want[i]) {
+			t.Errorf("checkpoint before batch %d = %s, want %s", i+1, checkpoints[i], want[i])
+		}
+	}
+
+	stored, err := c.jobRepo.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != JobStatusDone || stored.CandlesSaved != 10 || stored.Progress != 100 || stored.Error != "" {
+		t.Errorf("finished job = %+v, want done with 10 candles", stored)
+	}
+	if !stored.Checkpoint.Equal(stored.End) {
+		t.Errorf("checkpoint = %s, want the end %s", stored.Checkpoint, stored.End)
+	}
+}
+
+func TestRunJobResumesAfterInterruption(t *testing.T) {
+	c, source := newTestJobCollector(t)
+	start, end := qualityStart, qualityStart.Add(10*time.Minute)
+
+	// Interrupt the run after its first batch
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	job, err := c.PrepareJob(ctx, "BTCUSDT", "1m", start, end)
+	if err != nil {
+		t.Fatalf("PrepareJob: %v", err)
+	}
+	if err := c.RunJob(ctx, job, func(int) { cancel() }); err == nil {
+		t.Fatal("RunJob succeeded, want the interruption to fail it")
+	}
+
+	stored, err := c.jobRepo.Get(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != JobStatusFailed || stored.Error == "" {
+		t.Errorf("interrupted job = %s %q, want failed with an error", stored.Status, stored.Error)
+	}
+	if want := start.Add(3 * time.Minute); !stored.Checkpoint.Equal(want) || stored.CandlesSaved != 3 {
+		t.Errorf("interrupted job at %s with %d candles, want %s with 3", stored.Checkpoint, stored.CandlesSaved, want)
+	}
+
+	// A rerun over a wider range picks up the same job from its checkpoint
+	ctx = context.Background()
+	resumed, err := c.PrepareJob(ctx, "BTCUSDT", "1m", start, end.Add(5*time.Minute))
+	if err != nil {
+		t.Fatalf("PrepareJob: %v", err)
+	}
+	if resumed.ID != job.ID || !resumed.End.Equal(end.Add(5*time.Minute)) {
+		t.Fatalf("resumed job #%d ending %s, want #%d extended to %s", resumed.ID, resumed.End, job.ID, end.Add(5*time.Minute))
+	}
+	requests := len(source.starts)
+	if err := c.RunJob(ctx, resumed, nil); err != nil {
+		t.Fatalf("RunJob: %v", err)
+	}
+	if first := source.starts[requests]; !first.Equal(stored.Checkpoint) {
+		t.Errorf("resumed run requested from %s, want the checkpoint %s", first, stored.Checkpoint)
+	}
+	if !resumed.RunFrom.Equal(stored.Checkpoint) {
+		t.Errorf("run from = %s, want %s", resumed.RunFrom, stored.Checkpoint)
+	}
+
+	final, err := c.jobRepo.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if final.Status != JobStatusDone || final.CandlesSaved != 15 {
+		t.Errorf("final job = %s with %d candles, want done with 15", final.Status, final.CandlesSaved)
+	}
+
+	// A finished job isn't resumed; the next run gets a new one
+	next, err := c.PrepareJob(ctx, "BTCUSDT", "1m", start, end)
+	if err != nil {
+		t.Fatalf("PrepareJob: %v", err)
+	}
+	if next.ID == job.ID || next.Status != JobStatusQueued {
+		t.Errorf("next job = #%d %s, want a new queued job", next.ID, next.Status)
+	}
+}
@@ -0,0 +1,255 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"   // Row is rejected and quarantined
+	SeverityWarning = "warning" // Row is stored but flagged as suspicious
+)
+
+// Quality checks
+const (
+	CheckOHLC       = "ohlc"        // low <= open/close <= high, positive prices
+	CheckVolume     = "volume"      // non-negative volume
+	CheckAlignment  = "alignment"   // open_time on an interval boundary
+	CheckCloseTime  = "close_time"  // close_time = open_time + interval - 1ms
+	CheckOrder      = "order"       // strictly increasing open_time
+	CheckWick       = "wick"        // extreme wick outlier
+	CheckZeroVolume = "zero_volume" // zero-volume streak
+)
+
+// QualityIssue describes a problem found in a candle
+type QualityIssue struct {
+	Symbol   string         `json:"symbol"`
+	Interval string         `json:"interval"`
+	OpenTime time.Time      `json:"open_time"`
+	Check    string         `json:"check"`
+	Reason   string         `json:"reason"`
+	Severity string         `json:"severity"`
+	Candle   *domain.Candle `json:"candle"`
+}
+
+// QualityOptions configures anomaly detection thresholds
+type QualityOptions struct {
+	WickThreshold    float64 // Max wick length relative to the body's far end (e.g. 0.1 = 10%)
+	ZeroVolumeStreak int     // Consecutive zero-volume candles before flagging
+}
+
+// DefaultQualityOptions returns the default anomaly thresholds
+func DefaultQualityOptions() QualityOptions {
+	return QualityOptions{
+		WickThreshold:    0.1,
+		ZeroVolumeStreak: 5,
+	}
+}
+
+// CheckCandles validates a batch of candles for one symbol/interval.
+// It returns the candles that may be stored and every issue found; rows with
+// error-severity issues are excluded from the returned candles.
+func CheckCandles(candles []*domain.Candle, interval string, opts QualityOptions) ([]*domain.Candle, []QualityIssue) {
+	d, err := ParseInterval(interval)
+	if err != nil {
+		d = 0
+	}
+
+	valid := make([]*domain.Candle, 0, len(candles))
+	issues := make([]QualityIssue, 0)
+
+	issue := func(c *domain.Candle, severity, check, reason string) {
+		issues = append(issues, QualityIssue{
+			Symbol:   c.Symbol,
+			Interval: interval,
+			OpenTime: c.OpenTime,
+			Check:    check,
+			Reason:   reason,
+			Severity: severity,
+			Candle:   c,
+		})
+	}
+
+	var prevOpen time.Time
+	zeroStreak := 0
+	var streakStart *domain.Candle
+
+	for _, c := range candles {
+		check, reason := candleError(c, d)
+		if reason == "" && !prevOpen.IsZero() && !c.OpenTime.After(prevOpen) {
+			check = CheckOrder
+			reason = fmt.Sprintf("open_time %s is not after previous %s",
+				c.OpenTime.UTC().Format(time.RFC3339), prevOpen.UTC().Format(time.RFC3339))
+		}
+		if reason != "" {
+			issue(c, SeverityError, check, reason)
+			continue
+		}
+		prevOpen = c.OpenTime
+		valid = append(valid, c)
+
+		// Outliers are stored but flagged
+		if wick := wickRatio(c); opts.WickThreshold > 0 && wick > opts.WickThreshold {
+			issue(c, SeverityWarning, CheckWick, fmt.Sprintf("extreme wick: %.1f%% beyond body", wick*100))
+		}
+
+		if c.Volume == 0 {
+			if zeroStreak == 0 {
+				streakStart = c
+			}
+			zeroStreak++
+			if opts.ZeroVolumeStreak > 0 && zeroStreak == opts.ZeroVolumeStreak {
+				issue(streakStart, SeverityWarning, CheckZeroVolume, fmt.Sprintf("zero-volume streak of at least %d candles", zeroStreak))
+			}
+		} else {
+			zeroStreak = 0
+		}
+	}
+
+	return valid, issues
+}
+
+// candleError returns the failed check and reason if a candle is invalid,
+// or empty strings if it is consistent
+func candleError(c *domain.Candle, d time.Duration) (string, string) {
+	switch {
+	case c.Open <= 0 || c.High <= 0 || c.Low <= 0 || c.Close <= 0:
+		return CheckOHLC, "non-positive price"
+	case c.Low > c.High:
+		return CheckOHLC, fmt.Sprintf("low %.8f above high %.8f", c.Low, c.High)
+	case c.Low > math.Min(c.Open, c.Close):
+		return CheckOHLC, fmt.Sprintf("low %.8f above open/close", c.Low)
+	case c.High < math.Max(c.Open, c.Close):
+		return CheckOHLC, fmt.Sprintf("high %.8f below open/close", c.High)
+	case c.Volume < 0 || math.IsNaN(c.Volume):
+		return CheckVolume, "negative volume"
+	}
+
+	if d > 0 {
+		if !AlignTime(c.OpenTime, d).Equal(c.OpenTime.UTC()) {
+			return CheckAlignment, fmt.Sprintf("open_time %s not aligned to %s", c.OpenTime.UTC().Format(time.RFC3339), d)
+		}
+
+		// close_time should be the last instant of the bar (open + interval - 1ms);
+		// allow sub-second truncation from second-precision storage
+		expected := c.OpenTime.Add(d)
+		if !c.CloseTime.Before(expected) || c.CloseTime.Before(expected.Add(-time.Second)) {
+			return CheckCloseTime, fmt.Sprintf("close_time %s does not match interval", c.CloseTime.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return "", ""
+}
+
+// wickRatio returns the longer wick relative to the body's far end
+func wickRatio(c *domain.Candle) float64 {
+	top := math.Max(c.Open, c.Close)
+	bottom := math.Min(c.Open, c.Close)
+	upper := (c.High - top) / top
+	lower := (bottom - c.Low) / bottom
+	return math.Max(upper, lower)
+}
+
+// Validator checks candles before they are stored and quarantines bad rows
+type Validator struct {
+	quarantineRepo *QuarantineRepository
+	opts           QualityOptions
+}
+
+// NewValidator creates a new candle validator
+func NewValidator(quarantineRepo *QuarantineRepository, opts QualityOptions) *Validator {
+	return &Validator{
+		quarantineRepo: quarantineRepo,
+		opts:           opts,
+	}
+}
+
+// Filter validates candles, quarantines the rejected rows and returns the rows
+// safe to store. Warnings are only logged; ScanQuality reports them.
+func (v *Validator) Filter(ctx context.Context, candles []*domain.Candle, interval string) ([]*domain.Candle, error) {
+	valid, issues := CheckCandles(candles, interval, v.opts)
+	if len(issues) == 0 {
+		return valid, nil
+	}
+
+	rejected := make([]QualityIssue, 0, len(issues))
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			rejected = append(rejected, i)
+		}
+	}
+	if len(rejected) > 0 {
+		if err := v.quarantineRepo.SaveBatch(ctx, rejected); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("Data quality: %d rejected, %d flagged for %s %s",
+		len(candles)-len(valid), len(issues)-len(rejected), candles[0].Symbol, interval)
+
+	return valid, nil
+}
+
+// QualityReport summarizes data quality for a symbol/interval
+type QualityReport struct {
+	Symbol      string           `json:"symbol"`
+	Interval    string           `json:"interval"`
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	Scanned     int              `json:"scanned"`
+	Errors      int              `json:"errors"`
+	Warnings    int              `json:"warnings"`
+	Issues      []QualityIssue   `json:"issues"`
+	Quarantined []QualitySummary `json:"quarantined"`
+}
+
+// ScanQuality validates candles already stored for a symbol/interval without modifying them
+func (s *Service) ScanQuality(ctx context.Context, symbol, interval string, start, end time.Time) (*QualityReport, error) {
+	candles, err := s.candleRepo.GetRange(ctx, symbol, interval, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load candles: %w", err)
+	}
+
+	_, issues := CheckCandles(candles, interval, DefaultQualityOptions())
+
+	quarantined, err := s.quarantineRepo.Summary(ctx, symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &QualityReport{
+		Symbol:      symbol,
+		Interval:    interval,
+		From:        start,
+		To:          end,
+		Scanned:     len(candles),
+		Issues:      issues,
+		Quarantined: quarantined,
+	}
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+
+	return report, nil
+}
+
+// GetQualitySummary returns quarantine counts per symbol/interval/reason.
+// Empty symbol or interval match all.
+func (s *Service) GetQualitySummary(ctx context.Context, symbol, interval string) ([]QualitySummary, error) {
+	return s.quarantineRepo.Summary(ctx, symbol, interval)
+}
+
+// GetQuarantined returns quarantined rows for a symbol/interval
+func (s *Service) GetQuarantined(ctx context.Context, symbol, interval string, limit int) ([]QualityIssue, error) {
+	return s.quarantineRepo.List(ctx, symbol, interval, limit)
+}
@@ -0,0 +1,139 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// newTestDB opens a migrated SQLite database in a temp directory
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+var qualityStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// hourCandle returns a consistent 1h candle i hours after qualityStart
+func hourCandle(i int, price float64) *domain.Candle {
+	open := qualityStart.Add(time.Duration(i) * time.Hour)
+	return &domain.Candle{
+		Symbol:    "BTCUSDT",
+		OpenTime:  open,
+		CloseTime: open.Add(time.Hour - time.Millisecond),
+		Open:      price,
+		High:      price * 1.01,
+		Low:       price * 0.99,
+		Close:     price,
+		Volume:    10,
+	}
+}
+
+func TestCheckCandles(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(c *domain.Candle)
+		check    string
+		severity string
+	}{
+		{"non-positive price", func(c *domain.Candle) { c.Low = 0 }, CheckOHLC, SeverityError},
+		{"low above high", func(c *domain.Candle) { c.Low, c.High = c.High, c.Low }, CheckOHLC, SeverityError},
+		{"close above high", func(c *domain.Candle) { c.Close = c.High * 1.1 }, CheckOHLC, SeverityError},
+		{"negative volume", func(c *domain.Candle) { c.Volume = -1 }, CheckVolume, SeverityError},
+		{"unaligned", func(c *domain.Candle) {
+			c.OpenTime = c.OpenTime.Add(time.Minute)
+			c.CloseTime = c.CloseTime.Add(time.Minute)
+		}, CheckAlignment, SeverityError},
+		{"close time", func(c *domain.Candle) { c.CloseTime = c.OpenTime.Add(time.Hour) }, CheckCloseTime, SeverityError},
+		{"extreme wick", func(c *domain.Candle) { c.High = c.Close * 1.5 }, CheckWick, SeverityWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candles := []*domain.Candle{hourCandle(0, 100), hourCandle(1, 100), hourCandle(2, 100)}
+			tt.mutate(candles[1])
+
+			valid, issues := CheckCandles(candles, "1h", DefaultQualityOptions())
+			if len(issues) != 1 {
+				t.Fatalf("got %d issues %+v, want 1", len(issues), issues)
+			}
+			if issues[0].Check != tt.check || issues[0].Severity != tt.severity {
+				t.Errorf("issue = %s/%s, want %s/%s", issues[0].Check, issues[0].Severity, tt.check, tt.severity)
+			}
+			wantValid := 3
+			if tt.severity == SeverityError {
+				wantValid = 2
+			}
+			if len(valid) != wantValid {
+				t.Errorf("got %d valid candles, want %d", len(valid), wantValid)
+			}
+		})
+	}
+}
+
+func TestCheckCandlesOrderAndZeroVolume(t *testing.T) {
+	candles := []*domain.Candle{hourCandle(0, 100), hourCandle(1, 100), hourCandle(1, 100)}
+	valid, issues := CheckCandles(candles, "1h", DefaultQualityOptions())
+	if len(valid) != 2 || len(issues) != 1 || issues[0].Check != CheckOrder {
+		t.Errorf("duplicate open time: %d valid, issues %+v", len(valid), issues)
+	}
+
+	candles = make([]*domain.Candle, 0, 6)
+	for i := 0; i < 6; i++ {
+		c := hourCandle(i, 100)
+		if i > 0 {
+			c.Volume = 0
+		}
+		candles = append(candles, c)
+	}
+	valid, issues = CheckCandles(candles, "1h", DefaultQualityOptions())
+	if len(valid) != 6 {
+		t.Errorf("got %d valid candles, want 6", len(valid))
+	}
+	if len(issues) != 1 || issues[0].Check != CheckZeroVolume || !issues[0].OpenTime.Equal(candles[1].OpenTime) {
+		t.Errorf("zero-volume streak issues = %+v, want one at the streak start", issues)
+	}
+}
+
+func TestValidatorFilterQuarantinesErrorsOnly(t *testing.T) {
+	ctx := context.Background()
+	quarantine := NewQuarantineRepository(newTestDB(t))
+	validator := NewValidator(quarantine, DefaultQualityOptions())
+
+	bad := hourCandle(1, 100)
+	bad.Volume = -1
+	spike := hourCandle(2, 100)
+	spike.High = 150
+	candles := []*domain.Candle{hourCandle(0, 100), bad, spike}
+
+	valid, err := validator.Filter(ctx, candles, "1h")
+	if err != nil {
+		t.Fatalf("Filter: %v", err)
+	}
+	if len(valid) != 2 || valid[1] != spike {
+		t.Errorf("got %d valid candles, want the good candle and the flagged spike", len(valid))
+	}
+
+	quarantined, err := quarantine.List(ctx, "BTCUSDT", "1h", 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(quarantined) != 1 {
+		t.Fatalf("got %d quarantined rows, want 1", len(quarantined))
+	}
+	if quarantined[0].Check != CheckVolume || !quarantined[0].OpenTime.Equal(bad.OpenTime) {
+		t.Errorf("quarantined %+v, want the negative volume candle", quarantined[0])
+	}
+}
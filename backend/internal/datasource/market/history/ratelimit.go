@@ -0,0 +1,155 @@
+package history
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Binance spot request weight budget and costs
+const (
+	DefaultWeightPerMinute = 6000 // Binance spot REQUEST_WEIGHT limit per minute
+	KlinesWeight           = 2    // GET /api/v3/klines
+	AggTradesWeight        = 4    // GET /api/v3/aggTrades
+	DepthWeight            = 50   // GET /api/v3/depth with limit 501-1000
+	ExchangeInfoWeight     = 20   // GET /api/v3/exchangeInfo
+)
+
+// RateLimiter is a token bucket over Binance request weight shared by all workers.
+// It refills continuously, resyncs from the X-MBX-USED-WEIGHT-1M response header
+// and pauses every caller after 429/418 responses.
+type RateLimiter struct {
+	mu           sync.Mutex
+	capacity     float64
+	tokens       float64
+	refillPerSec float64
+	last         time.Time
+	pausedUntil  time.Time
+	usedWeight   int
+}
+
+// NewRateLimiter creates a limiter that allows weightPerMinute of request weight.
+// A safety margin of 20% is kept free for other clients on the same IP.
+func NewRateLimiter(weightPerMinute int) *RateLimiter {
+	capacity := float64(weightPerMinute) * 0.8
+	return &RateLimiter{
+		capacity:     capacity,
+		tokens:       capacity,
+		refillPerSec: capacity / 60,
+		last:         time.Now(),
+	}
+}
+
+// Wait blocks until weight tokens are available or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context, weight int) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.refill(now)
+
+		var delay time.Duration
+		if now.Before(l.pausedUntil) {
+			delay = l.pausedUntil.Sub(now)
+		} else if l.tokens >= float64(weight) {
+			l.tokens -= float64(weight)
+			l.mu.Unlock()
+			return nil
+		} else {
+			missing := float64(weight) - l.tokens
+			delay = time.Duration(missing / l.refillPerSec * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// refill adds tokens for the time elapsed since the last refill (caller holds the lock)
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	l.tokens += elapsed * l.refillPerSec
+	if l.tokens > l.capacity {
+		l.tokens = l.capacity
+	}
+}
+
+// Observe syncs the bucket with the weight the exchange reports as used this minute
+func (l *RateLimiter) Observe(usedWeight int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	l.usedWeight = usedWeight
+	if remaining := l.capacity - float64(usedWeight); remaining < l.tokens {
+		l.tokens = remaining
+	}
+}
+
+// Backoff pauses all callers for d (e.g. after a 429 or 418 response)
+func (l *RateLimiter) Backoff(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+		l.tokens = 0
+	}
+}
+
+// UsedWeight returns the last used weight reported by the exchange (0 for a nil limiter)
+func (l *RateLimiter) UsedWeight() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.usedWeight
+}
+
+// Transport wraps an HTTP transport so responses feed the limiter
+func (l *RateLimiter) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &rateLimitTransport{base: base, limiter: l}
+}
+
+// rateLimitTransport reads Binance weight headers and rate-limit status codes
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *RateLimiter
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if used, err := strconv.Atoi(resp.Header.Get("X-MBX-USED-WEIGHT-1M")); err == nil {
+		t.limiter.Observe(used)
+	}
+
+	// 429 = rate limited, 418 = IP banned after ignoring 429s
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusTeapot {
+		wait := time.Minute
+		if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && retryAfter > 0 {
+			wait = time.Duration(retryAfter) * time.Second
+		}
+		log.Printf("⚠️  Binance returned %d, pausing all requests for %v", resp.StatusCode, wait)
+		t.limiter.Backoff(wait)
+	}
+
+	return resp, nil
+}
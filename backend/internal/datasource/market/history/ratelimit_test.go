@@ -0,0 +1,178 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+)
+
+// limiterTokens returns the tokens left after refilling to now
+func limiterTokens(l *RateLimiter) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	return l.tokens
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	// 600/min keeps 480 with the safety margin, refilling 8 per second
+	l := NewRateLimiter(600)
+	ctx := context.Background()
+
+	if err := l.Wait(ctx, 400); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if tokens := limiterTokens(l); tokens < 80 || tokens > 81 {
+		t.Errorf("tokens = %v, want about 80", tokens)
+	}
+
+	// Not enough weight left: the caller waits until ctx gives up
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(short, 200); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait = %v, want deadline exceeded", err)
+	}
+
+	// A second of refill covers a small request
+	l.mu.Lock()
+	l.tokens = 0
+	l.last = time.Now().Add(-time.Second)
+	l.mu.Unlock()
+	if err := l.Wait(ctx, 8); err != nil {
+		t.Fatalf("Wait after refill: %v", err)
+	}
+}
+
+func TestRateLimiterObserve(t *testing.T) {
+	l := NewRateLimiter(600)
+
+	// The exchange reports more weight used than the bucket accounted for
+	l.Observe(470)
+	if got := l.UsedWeight(); got != 470 {
+		t.Errorf("used weight = %d, want 470", got)
+	}
+	if tokens := limiterTokens(l); tokens < 10 || tokens > 11 {
+		t.Errorf("tokens = %v, want about 10", tokens)
+	}
+
+	// A lower report never adds tokens beyond what the bucket has
+	l.Observe(0)
+	if tokens := limiterTokens(l); tokens > 11 {
+		t.Errorf("tokens = %v after a lower report, want about 10", tokens)
+	}
+
+	var nilLimiter *RateLimiter
+	if got := nilLimiter.UsedWeight(); got != 0 {
+		t.Errorf("nil limiter used weight = %d, want 0", got)
+	}
+}
+
+func TestRateLimiterBackoff(t *testing.T) {
+	l := NewRateLimiter(600)
+	l.Backoff(time.Hour)
+	// A shorter backoff doesn't cut a longer one short
+	l.Backoff(time.Millisecond)
+
+	l.mu.Lock()
+	paused := time.Until(l.pausedUntil)
+	l.mu.Unlock()
+	if paused < 59*time.Minute {
+		t.Errorf("paused for %v, want about an hour", paused)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Wait(ctx, 1) }()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Wait = %v, want canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait didn't return after cancel")
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		used       string
+		retryAfter string
+		wantUsed   int
+		wantPause  time.Duration
+	}{
+		{name: "used weight", status: http.StatusOK, used: "1200", wantUsed: 1200},
+		{name: "no header", status: http.StatusOK},
+		{name: "429 with retry-after", status: http.StatusTooManyRequests, used: "4800", retryAfter: "30", wantUsed: 4800, wantPause: 30 * time.Second},
+		{name: "418 without retry-after", status: http.StatusTeapot, wantPause: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.used != "" {
+					w.Header().Set("X-MBX-USED-WEIGHT-1M", tt.used)
+				}
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			l := NewRateLimiter(DefaultWeightPerMinute)
+			client := &http.Client{Transport: l.Transport(nil)}
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			resp.Body.Close()
+
+			if got := l.UsedWeight(); got != tt.wantUsed {
+				t.Errorf("used weight = %d, want %d", got, tt.wantUsed)
+			}
+			l.mu.Lock()
+			paused := time.Until(l.pausedUntil)
+			l.mu.Unlock()
+			if tt.wantPause == 0 {
+				if paused > 0 {
+					t.Errorf("paused for %v, want no pause", paused)
+				}
+			} else if paused > tt.wantPause || paused < tt.wantPause-5*time.Second {
+				t.Errorf("paused for %v, want %v", paused, tt.wantPause)
+			}
+		})
+	}
+}
+
+func TestClientRateLimiterShared(t *testing.T) {
+	client := binance.NewClient("", "")
+	l := ClientRateLimiter(client)
+	if again := ClientRateLimiter(client); again != l {
+		t.Error("second call installed another limiter")
+	}
+	if http.DefaultClient.Transport != nil {
+		t.Error("http.DefaultClient was modified")
+	}
+
+	// The installed transport feeds the shared limiter
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", "42")
+	}))
+	defer srv.Close()
+	resp, err := client.HTTPClient.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	if got := l.UsedWeight(); got != 42 {
+		t.Errorf("used weight = %d, want 42", got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -13,7 +15,8 @@ import (
 
 // CandleRepository handles candle data operations
 type CandleRepository struct {
-	db *database.DB
+	db     *database.DB
+	tables sync.Map // Intervals whose table is known to exist
 }
 
 // NewCandleRepository creates a new candle repository
@@ -26,8 +29,36 @@ func getTableName(interval string) string {
 	return fmt.Sprintf("candles_%s", interval)
 }
 
+// HasTable reports whether a candle table exists for the interval
+func (r *CandleRepository) HasTable(ctx context.Context, interval string) (bool, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		getTableName(interval),
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check candle table: %w", err)
+	}
+	return count > 0, nil
+}
+
+// EnsureTable creates the candle table for the interval if needed
+func (r *CandleRepository) EnsureTable(interval string) error {
+	if _, ok := r.tables.Load(interval); ok {
+		return nil
+	}
+	if err := r.db.EnsureCandleTable(interval); err != nil {
+		return err
+	}
+	r.tables.Store(interval, true)
+	return nil
+}
+
 // Save saves a candle to the database
 func (r *CandleRepository) Save(ctx context.Context, candle *domain.Candle, interval string) error {
+	if err := r.EnsureTable(interval); err != nil {
+		return err
+	}
 	tableName := getTableName(interval)
 
 	query := fmt.Sprintf(`
@@ -44,8 +75,8 @@ func (r *CandleRepository) Save(ctx context.Context, candle *domain.Candle, inte
 
 	_, err := r.db.ExecContext(ctx, query,
 		candle.Symbol,
-		candle.OpenTime.Unix(),
-		candle.CloseTime.Unix(),
+		candle.OpenTime.UnixMilli(),
+		candle.CloseTime.UnixMilli(),
 		candle.Open,
 		candle.High,
 		candle.Low,
@@ -62,6 +93,9 @@ func (r *CandleRepository) Save(ctx context.Context, candle *domain.Candle, inte
 
 // SaveBatch saves multiple candles in a transaction
 func (r *CandleRepository) SaveBatch(ctx context.Context, candles []*domain.Candle, interval string) error {
+	if err := r.EnsureTable(interval); err != nil {
+		return err
+	}
 	tableName := getTableName(interval)
 
 	tx, err := r.db.BeginTx(ctx, nil)
@@ -91,8 +125,8 @@ func (r *CandleRepository) SaveBatch(ctx context.Context, candles []*domain.Cand
 	for _, candle := range candles {
 		_, err := stmt.ExecContext(ctx,
 			candle.Symbol,
-			candle.OpenTime.Unix(),
-			candle.CloseTime.Unix(),
+			candle.OpenTime.UnixMilli(),
+			candle.CloseTime.UnixMilli(),
 			candle.Open,
 			candle.High,
 			candle.Low,
@@ -113,14 +147,17 @@ func (r *CandleRepository) SaveBatch(ctx context.Context, candles []*domain.Cand
 
 // GetRange retrieves candles within a time range
 func (r *CandleRepository) GetRange(ctx context.Context, symbol, interval string, start, end time.Time) ([]*domain.Candle, error) {
+	if err := r.EnsureTable(interval); err != nil {
+		return nil, err
+	}
 	tableName := getTableName(interval)
 
 	// Build query with Squirrel
 	query := sq.Select("symbol", "open_time", "close_time", "open", "high", "low", "close", "volume").
 		From(tableName).
 		Where(sq.Eq{"symbol": symbol}).
-		Where(sq.GtOrEq{"open_time": start.Unix()}).
-		Where(sq.Lt{"open_time": end.Unix()}).
+		Where(sq.GtOrEq{"open_time": start.UnixMilli()}).
+		Where(sq.Lt{"open_time": end.UnixMilli()}).
 		OrderBy("open_time ASC")
 
 	sqlQuery, args, err := query.ToSql()
@@ -154,16 +191,56 @@ func (r *CandleRepository) GetRange(ctx context.Context, symbol, interval string
 			return nil, fmt.Errorf("failed to scan candle: %w", err)
 		}
 
-		candle.OpenTime = time.Unix(openTime, 0)
-		candle.CloseTime = time.Unix(closeTime, 0)
+		candle.OpenTime = domain.TimeFromMillis(openTime)
+		candle.CloseTime = domain.TimeFromMillis(closeTime)
 		candles = append(candles, &candle)
 	}
 
 	return candles, nil
 }
 
+// GetOpenTimes retrieves only the open times of candles within a time range
+func (r *CandleRepository) GetOpenTimes(ctx context.Context, symbol, interval string, start, end time.Time) ([]time.Time, error) {
+	if err := r.EnsureTable(interval); err != nil {
+		return nil, err
+	}
+	tableName := getTableName(interval)
+
+	query := sq.Select("open_time").
+		From(tableName).
+		Where(sq.Eq{"symbol": symbol}).
+		Where(sq.GtOrEq{"open_time": start.UnixMilli()}).
+		Where(sq.Lt{"open_time": end.UnixMilli()}).
+		OrderBy("open_time ASC")
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open times: %w", err)
+	}
+	defer rows.Close()
+
+	var openTimes []time.Time
+	for rows.Next() {
+		var openTime int64
+		if err := rows.Scan(&openTime); err != nil {
+			return nil, fmt.Errorf("failed to scan open time: %w", err)
+		}
+		openTimes = append(openTimes, domain.TimeFromMillis(openTime))
+	}
+
+	return openTimes, rows.Err()
+}
+
 // GetFirst retrieves the first (oldest) candle
 func (r *CandleRepository) GetFirst(ctx context.Context, symbol, interval string) (*domain.Candle, error) {
+	if err := r.EnsureTable(interval); err != nil {
+		return nil, err
+	}
 	tableName := getTableName(interval)
 
 	// Build query with Squirrel
@@ -199,14 +276,17 @@ func (r *CandleRepository) GetFirst(ctx context.Context, symbol, interval string
 		return nil, fmt.Errorf("failed to get first candle: %w", err)
 	}
 
-	candle.OpenTime = time.Unix(openTime, 0)
-	candle.CloseTime = time.Unix(closeTime, 0)
+	candle.OpenTime = domain.TimeFromMillis(openTime)
+	candle.CloseTime = domain.TimeFromMillis(closeTime)
 
 	return &candle, nil
 }
 
 // GetLatest retrieves the most recent candle
 func (r *CandleRepository) GetLatest(ctx context.Context, symbol, interval string) (*domain.Candle, error) {
+	if err := r.EnsureTable(interval); err != nil {
+		return nil, err
+	}
 	tableName := getTableName(interval)
 
 	// Build query with Squirrel
@@ -242,8 +322,8 @@ func (r *CandleRepository) GetLatest(ctx context.Context, symbol, interval strin
 		return nil, fmt.Errorf("failed to get latest candle: %w", err)
 	}
 
-	candle.OpenTime = time.Unix(openTime, 0)
-	candle.CloseTime = time.Unix(closeTime, 0)
+	candle.OpenTime = domain.TimeFromMillis(openTime)
+	candle.CloseTime = domain.TimeFromMillis(closeTime)
 
 	return &candle, nil
 }
@@ -273,7 +353,7 @@ func (r *TradeRepository) Save(ctx context.Context, trade *domain.Trade) error {
 		trade.Quantity,
 		trade.Fee,
 		trade.FeeAsset,
-		trade.Timestamp.Unix(),
+		trade.Timestamp.UnixMilli(),
 	)
 
 	if err != nil {
@@ -322,9 +402,508 @@ func (r *TradeRepository) GetBySymbol(ctx context.Context, symbol string) ([]*do
 
 		trade.ID = fmt.Sprintf("%d", id)
 		trade.Side = domain.OrderSide(sideStr)
-		trade.Timestamp = time.Unix(timestamp, 0)
+		trade.Timestamp = domain.TimeFromMillis(timestamp)
 		trades = append(trades, &trade)
 	}
 
 	return trades, nil
 }
+
+// AggTradeRepository stores aggregate trades in per-symbol SQLite tables
+type AggTradeRepository struct {
+	db     *database.DB
+	tables sync.Map // Symbols whose table is known to exist
+}
+
+// NewAggTradeRepository creates a new aggTrade repository
+func NewAggTradeRepository(db *database.DB) *AggTradeRepository {
+	return &AggTradeRepository{db: db}
+}
+
+// EnsureTable creates the aggTrade table for the symbol if needed
+func (r *AggTradeRepository) EnsureTable(symbol string) error {
+	if _, ok := r.tables.Load(symbol); ok {
+		return nil
+	}
+	if err := r.db.EnsureAggTradeTable(symbol); err != nil {
+		return err
+	}
+	r.tables.Store(symbol, true)
+	return nil
+}
+
+// SaveBatch saves trades in a transaction; trades already stored are skipped
+func (r *AggTradeRepository) SaveBatch(ctx context.Context, symbol string, trades []*domain.AggTrade) error {
+	if err := r.EnsureTable(symbol); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT OR IGNORE INTO %s (id, time, price, quantity, first_id, last_id, buyer_maker)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, database.AggTradeTableName(symbol)))
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range trades {
+		_, err := stmt.ExecContext(ctx,
+			t.ID,
+			t.Time.UnixMilli(),
+			t.Price,
+			t.Quantity,
+			t.FirstTradeID,
+			t.LastTradeID,
+			t.IsBuyerMaker,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert aggTrade: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetRange retrieves up to limit trades within a time range (0 = no limit)
+func (r *AggTradeRepository) GetRange(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.AggTrade, error) {
+	trades := make([]*domain.AggTrade, 0)
+	err := r.scan(ctx, symbol, start, end, limit, func(t *domain.AggTrade) error {
+		trades = append(trades, t)
+		return nil
+	})
+	return trades, err
+}
+
+// Scan streams trades within a time range to fn in ID order
+func (r *AggTradeRepository) Scan(ctx context.Context, symbol string, start, end time.Time, fn func(*domain.AggTrade) error) error {
+	return r.scan(ctx, symbol, start, end, 0, fn)
+}
+
+// scan runs a time range query and passes each trade to fn
+func (r *AggTradeRepository) scan(ctx context.Context, symbol string, start, end time.Time, limit int, fn func(*domain.AggTrade) error) error {
+	if err := r.EnsureTable(symbol); err != nil {
+		return err
+	}
+
+	query := sq.Select(aggTradeColumns...).
+		From(database.AggTradeTableName(symbol)).
+		Where(sq.GtOrEq{"time": start.UnixMilli()}).
+		Where(sq.Lt{"time": end.UnixMilli()}).
+		OrderBy("id ASC")
+	if limit > 0 {
+		query = query.Limit(uint64(limit))
+	}
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query aggTrades: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanAggTrade(rows, symbol)
+		if err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetLatest retrieves the trade with the highest aggregate ID
+func (r *AggTradeRepository) GetLatest(ctx context.Context, symbol string) (*domain.AggTrade, error) {
+	if err := r.EnsureTable(symbol); err != nil {
+		return nil, err
+	}
+
+	t, err := scanAggTrade(r.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s ORDER BY id DESC LIMIT 1",
+		strings.Join(aggTradeColumns, ", "), database.AggTradeTableName(symbol),
+	)), symbol)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// aggTradeColumns lists aggTrade columns in scan order
+var aggTradeColumns = []string{"id", "time", "price", "quantity", "first_id", "last_id", "buyer_maker"}
+
+// scanAggTrade scans an aggTradeColumns row
+func scanAggTrade(row rowScanner, symbol string) (*domain.AggTrade, error) {
+	t := domain.AggTrade{Symbol: symbol}
+	var ts int64
+
+	err := row.Scan(&t.ID, &ts, &t.Price, &t.Quantity, &t.FirstTradeID, &t.LastTradeID, &t.IsBuyerMaker)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan aggTrade: %w", err)
+	}
+
+	t.Time = domain.TimeFromMillis(ts)
+	return &t, nil
+}
+
+// QuarantineRepository stores candles rejected or flagged by validation
+type QuarantineRepository struct {
+	db *database.DB
+}
+
+// NewQuarantineRepository creates a new quarantine repository
+func NewQuarantineRepository(db *database.DB) *QuarantineRepository {
+	return &QuarantineRepository{db: db}
+}
+
+// QualitySummary counts quarantined rows per symbol, interval and check
+type QualitySummary struct {
+	Symbol   string    `json:"symbol"`
+	Interval string    `json:"interval"`
+	Check    string    `json:"check"`
+	Severity string    `json:"severity"`
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// SaveBatch stores quality issues in a transaction
+func (r *QuarantineRepository) SaveBatch(ctx context.Context, issues []QualityIssue) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO candle_quarantine (symbol, interval, open_time, close_time, open, high, low, close, volume, check_name, severity, reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, issue := range issues {
+		c := issue.Candle
+		_, err := stmt.ExecContext(ctx,
+			issue.Symbol,
+			issue.Interval,
+			c.OpenTime.UnixMilli(),
+			c.CloseTime.UnixMilli(),
+			c.Open,
+			c.High,
+			c.Low,
+			c.Close,
+			c.Volume,
+			issue.Check,
+			issue.Severity,
+			issue.Reason,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to quarantine candle: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Summary returns quarantine counts grouped by symbol, interval and check.
+// Empty symbol or interval match all.
+func (r *QuarantineRepository) Summary(ctx context.Context, symbol, interval string) ([]QualitySummary, error) {
+	query := sq.Select("symbol", "interval", "check_name", "severity", "COUNT(*)", "MAX(open_time)").
+		From("candle_quarantine").
+		GroupBy("symbol", "interval", "check_name", "severity").
+		OrderBy("symbol", "interval", "check_name")
+	if symbol != "" {
+		query = query.Where(sq.Eq{"symbol": symbol})
+	}
+	if interval != "" {
+		query = query.Where(sq.Eq{"interval": interval})
+	}
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quarantine: %w", err)
+	}
+	defer rows.Close()
+
+	summary := make([]QualitySummary, 0)
+	for rows.Next() {
+		var s QualitySummary
+		var lastSeen int64
+		if err := rows.Scan(&s.Symbol, &s.Interval, &s.Check, &s.Severity, &s.Count, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan quarantine summary: %w", err)
+		}
+		s.LastSeen = domain.TimeFromMillis(lastSeen)
+		summary = append(summary, s)
+	}
+
+	return summary, rows.Err()
+}
+
+// List returns the most recent quarantined rows for a symbol/interval
+func (r *QuarantineRepository) List(ctx context.Context, symbol, interval string, limit int) ([]QualityIssue, error) {
+	query := sq.Select("symbol", "interval", "open_time", "close_time", "open", "high", "low", "close", "volume", "check_name", "severity", "reason").
+		From("candle_quarantine").
+		Where(sq.Eq{"symbol": symbol, "interval": interval}).
+		OrderBy("open_time DESC").
+		Limit(uint64(limit))
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quarantine: %w", err)
+	}
+	defer rows.Close()
+
+	issues := make([]QualityIssue, 0)
+	for rows.Next() {
+		var issue QualityIssue
+		var candle domain.Candle
+		var openTime, closeTime int64
+
+		err := rows.Scan(
+			&issue.Symbol,
+			&issue.Interval,
+			&openTime,
+			&closeTime,
+			&candle.Open,
+			&candle.High,
+			&candle.Low,
+			&candle.Close,
+			&candle.Volume,
+			&issue.Check,
+			&issue.Severity,
+			&issue.Reason,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quarantined candle: %w", err)
+		}
+
+		candle.Symbol = issue.Symbol
+		candle.OpenTime = domain.TimeFromMillis(openTime)
+		candle.CloseTime = domain.TimeFromMillis(closeTime)
+		issue.OpenTime = candle.OpenTime
+		issue.Candle = &candle
+		issues = append(issues, issue)
+	}
+
+	return issues, rows.Err()
+}
+
+// JobRepository persists collection jobs and their checkpoints
+type JobRepository struct {
+	db *database.DB
+}
+
+// NewJobRepository creates a new collection job repository
+func NewJobRepository(db *database.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// jobColumns lists collection_jobs columns in scan order
+var jobColumns = []string{
+	"id", "symbol", "interval", "start_time", "end_time", "checkpoint", "status",
+	"candles_saved", "error", "run_from", "started_at", "created_at", "updated_at",
+}
+
+// Create inserts a new job and sets its ID
+func (r *JobRepository) Create(ctx context.Context, job *CollectionJob) error {
+	now := time.Now()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO collection_jobs (symbol, interval, start_time, end_time, checkpoint, status, candles_saved, error, run_from, started_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		job.Symbol,
+		job.Interval,
+		job.Start.UnixMilli(),
+		job.End.UnixMilli(),
+		job.Checkpoint.UnixMilli(),
+		job.Status,
+		job.CandlesSaved,
+		job.Error,
+		job.RunFrom.UnixMilli(),
+		nullableUnixMilli(job.StartedAt),
+		now.UnixMilli(),
+		now.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create collection job: %w", err)
+	}
+
+	job.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get collection job id: %w", err)
+	}
+	return nil
+}
+
+// Update stores a job's range, checkpoint and status
+func (r *JobRepository) Update(ctx context.Context, job *CollectionJob) error {
+	job.UpdatedAt = time.Now()
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE collection_jobs
+		SET end_time = ?, checkpoint = ?, status = ?, candles_saved = ?, error = ?, run_from = ?, started_at = ?, updated_at = ?
+		WHERE id = ?
+	`,
+		job.End.UnixMilli(),
+		job.Checkpoint.UnixMilli(),
+		job.Status,
+		job.CandlesSaved,
+		job.Error,
+		job.RunFrom.UnixMilli(),
+		nullableUnixMilli(job.StartedAt),
+		job.UpdatedAt.UnixMilli(),
+		job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update collection job: %w", err)
+	}
+	return nil
+}
+
+// Get returns a job by ID, or nil if it doesn't exist
+func (r *JobRepository) Get(ctx context.Context, id int64) (*CollectionJob, error) {
+	jobs, err := r.query(ctx, sq.Select(jobColumns...).
+		From("collection_jobs").
+		Where(sq.Eq{"id": id}))
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return jobs[0], nil
+}
+
+// FindResumable returns the latest unfinished job for a symbol/interval whose
+// range starts at or before start and hasn't ended before it, or nil if none
+func (r *JobRepository) FindResumable(ctx context.Context, symbol, interval string, start time.Time) (*CollectionJob, error) {
+	jobs, err := r.query(ctx, sq.Select(jobColumns...).
+		From("collection_jobs").
+		Where(sq.Eq{"symbol": symbol, "interval": interval}).
+		Where(sq.NotEq{"status": JobStatusDone}).
+		Where(sq.LtOrEq{"start_time": start.UnixMilli()}).
+		Where(sq.GtOrEq{"end_time": start.UnixMilli()}).
+		OrderBy("id DESC").
+		Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return jobs[0], nil
+}
+
+// List returns the most recent jobs, optionally filtered by status
+func (r *JobRepository) List(ctx context.Context, status string, limit int) ([]*CollectionJob, error) {
+	query := sq.Select(jobColumns...).
+		From("collection_jobs").
+		OrderBy("id DESC").
+		Limit(uint64(limit))
+	if status != "" {
+		query = query.Where(sq.Eq{"status": status})
+	}
+	return r.query(ctx, query)
+}
+
+// query runs a job select and scans the rows
+func (r *JobRepository) query(ctx context.Context, query sq.SelectBuilder) ([]*CollectionJob, error) {
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collection jobs: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	jobs := make([]*CollectionJob, 0)
+	for rows.Next() {
+		var job CollectionJob
+		var start, end, checkpoint, runFrom, createdAt, updatedAt int64
+		var startedAt sql.NullInt64
+
+		err := rows.Scan(
+			&job.ID,
+			&job.Symbol,
+			&job.Interval,
+			&start,
+			&end,
+			&checkpoint,
+			&job.Status,
+			&job.CandlesSaved,
+			&job.Error,
+			&runFrom,
+			&startedAt,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection job: %w", err)
+		}
+
+		job.Start = time.UnixMilli(start).UTC()
+		job.End = time.UnixMilli(end).UTC()
+		job.Checkpoint = time.UnixMilli(checkpoint).UTC()
+		job.RunFrom = time.UnixMilli(runFrom).UTC()
+		job.CreatedAt = time.UnixMilli(createdAt).UTC()
+		job.UpdatedAt = time.UnixMilli(updatedAt).UTC()
+		if startedAt.Valid {
+			t := time.UnixMilli(startedAt.Int64).UTC()
+			job.StartedAt = &t
+		}
+		job.updateProgress(now)
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// nullableUnixMilli converts an optional time to a nullable Unix millisecond timestamp
+func nullableUnixMilli(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UnixMilli()
+}
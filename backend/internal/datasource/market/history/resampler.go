@@ -0,0 +1,265 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// BaseInterval is the stored interval that every other interval is derived from
+const BaseInterval = "1m"
+
+// weekOffset shifts weekly buckets so they start on Monday 00:00 UTC like Binance
+// (the Unix epoch fell on a Thursday)
+const weekOffset = 4 * 24 * time.Hour
+
+// ParseInterval parses an interval string such as "1m", "90m", "6h", "3d" or "1w"
+func ParseInterval(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("invalid interval: %q", interval)
+	}
+
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid interval: %q", interval)
+	}
+
+	var unit time.Duration
+	switch interval[len(interval)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid interval unit: %q", interval)
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// AlignTime returns the UTC start of the interval bucket containing t
+func AlignTime(t time.Time, d time.Duration) time.Time {
+	offset := time.Duration(0)
+	if d%(7*24*time.Hour) == 0 {
+		offset = weekOffset
+	}
+
+	ms := t.UnixMilli() - offset.Milliseconds()
+	step := d.Milliseconds()
+	bucket := ms - ((ms%step)+step)%step
+
+	return time.UnixMilli(bucket + offset.Milliseconds()).UTC()
+}
+
+// ResampleCandles aggregates ascending base candles into candles of the target duration.
+// Buckets that are not fully covered by the source candles are dropped when
+// dropIncomplete is true (e.g. the still-open bar at the end of the range).
+func ResampleCandles(candles []*domain.Candle, source, target time.Duration, dropIncomplete bool) ([]*domain.Candle, error) {
+	if source <= 0 || target < source || target%source != 0 {
+		return nil, fmt.Errorf("cannot resample %s candles into %s", source, target)
+	}
+
+	expected := int(target / source)
+	result := make([]*domain.Candle, 0, len(candles)/expected+1)
+
+	var current *domain.Candle
+	count := 0
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if !dropIncomplete || count == expected {
+			result = append(result, current)
+		}
+		current = nil
+		count = 0
+	}
+
+	for _, c := range candles {
+		bucket := AlignTime(c.OpenTime, target)
+
+		if current != nil && !current.OpenTime.Equal(bucket) {
+			flush()
+		}
+
+		if current == nil {
+			current = &domain.Candle{
+				Symbol:    c.Symbol,
+				OpenTime:  bucket,
+				CloseTime: bucket.Add(target).Add(-time.Millisecond),
+				Open:      c.Open,
+				High:      c.High,
+				Low:       c.Low,
+				Close:     c.Close,
+				Volume:    c.Volume,
+			}
+			count = 1
+			continue
+		}
+
+		current.High = math.Max(current.High, c.High)
+		current.Low = math.Min(current.Low, c.Low)
+		current.Close = c.Close
+		current.Volume += c.Volume
+		count++
+	}
+	flush()
+
+	return result, nil
+}
+
+// Resampler builds higher-interval candles from stored 1m candles
+type Resampler struct {
+	candleRepo CandleStore
+}
+
+// NewResampler creates a new resampler
+func NewResampler(candleRepo CandleStore) *Resampler {
+	return &Resampler{
+		candleRepo: candleRepo,
+	}
+}
+
+// Resample derives candles for any interval from stored 1m data.
+// The range is widened to bucket boundaries so the first and last bars are complete.
+func (r *Resampler) Resample(ctx context.Context, symbol, interval string, start, end time.Time) ([]*domain.Candle, error) {
+	target, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	source, _ := ParseInterval(BaseInterval)
+
+	alignedStart := AlignTime(start, target)
+	alignedEnd := AlignTime(end, target)
+	if alignedEnd.Before(end) {
+		alignedEnd = alignedEnd.Add(target)
+	}
+
+	base, err := r.candleRepo.GetRange(ctx, symbol, BaseInterval, alignedStart, alignedEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s candles: %w", BaseInterval, err)
+	}
+
+	return ResampleCandles(base, source, target, true)
+}
+
+// Materialize resamples the range and stores the result in the interval's candle table
+func (r *Resampler) Materialize(ctx context.Context, symbol, interval string, start, end time.Time) (int, error) {
+	if interval == BaseInterval {
+		return 0, fmt.Errorf("%s is the base interval and cannot be derived", BaseInterval)
+	}
+
+	candles, err := r.Resample(ctx, symbol, interval, start, end)
+	if err != nil {
+		return 0, err
+	}
+	if len(candles) == 0 {
+		return 0, nil
+	}
+
+	if err := r.candleRepo.EnsureTable(interval); err != nil {
+		return 0, err
+	}
+	if err := r.candleRepo.SaveBatch(ctx, candles, interval); err != nil {
+		return 0, fmt.Errorf("failed to save resampled candles: %w", err)
+	}
+
+	return len(candles), nil
+}
+
+// ResampleMismatch describes a derived bar that disagrees with the stored exchange bar
+type ResampleMismatch struct {
+	OpenTime time.Time `json:"open_time"`
+	Field    string    `json:"field,omitempty"`
+	Derived  float64   `json:"derived"`
+	Exchange float64   `json:"exchange"`
+	Missing  bool      `json:"missing,omitempty"` // No derived bar (1m data incomplete)
+}
+
+// ResampleCheckResult summarizes a comparison between derived and exchange bars
+type ResampleCheckResult struct {
+	Symbol     string             `json:"symbol"`
+	Interval   string             `json:"interval"`
+	Compared   int                `json:"compared"`
+	Mismatches []ResampleMismatch `json:"mismatches"`
+}
+
+// Verify compares derived bars against the exchange-provided bars stored for the interval.
+// tolerance is the allowed relative difference per field (e.g. 1e-9).
+func (r *Resampler) Verify(ctx context.Context, symbol, interval string, start, end time.Time, tolerance float64) (*ResampleCheckResult, error) {
+	exchangeBars, err := r.candleRepo.GetRange(ctx, symbol, interval, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s candles: %w", interval, err)
+	}
+
+	derived, err := r.Resample(ctx, symbol, interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	byOpen := make(map[int64]*domain.Candle, len(derived))
+	for _, c := range derived {
+		byOpen[c.OpenTime.UnixMilli()] = c
+	}
+
+	result := &ResampleCheckResult{
+		Symbol:     symbol,
+		Interval:   interval,
+		Mismatches: make([]ResampleMismatch, 0),
+	}
+
+	for _, ex := range exchangeBars {
+		d, ok := byOpen[ex.OpenTime.UnixMilli()]
+		if !ok {
+			result.Mismatches = append(result.Mismatches, ResampleMismatch{
+				OpenTime: ex.OpenTime,
+				Missing:  true,
+			})
+			continue
+		}
+		result.Compared++
+
+		fields := []struct {
+			name     string
+			derived  float64
+			exchange float64
+		}{
+			{"open", d.Open, ex.Open},
+			{"high", d.High, ex.High},
+			{"low", d.Low, ex.Low},
+			{"close", d.Close, ex.Close},
+			{"volume", d.Volume, ex.Volume},
+		}
+		for _, f := range fields {
+			if !withinTolerance(f.derived, f.exchange, tolerance) {
+				result.Mismatches = append(result.Mismatches, ResampleMismatch{
+					OpenTime: ex.OpenTime,
+					Field:    f.name,
+					Derived:  f.derived,
+					Exchange: f.exchange,
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// withinTolerance reports whether a and b differ by at most tol relative to their magnitude
+func withinTolerance(a, b, tol float64) bool {
+	diff := math.Abs(a - b)
+	scale := math.Max(math.Abs(a), math.Abs(b))
+	if scale == 0 {
+		return true
+	}
+	return diff/scale <= tol
+}
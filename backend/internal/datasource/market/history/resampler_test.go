@@ -0,0 +1,236 @@
+package history
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// minuteCandle returns a 1m candle i minutes after qualityStart ranging price-1 to price+1
+func minuteCandle(i int, price float64) *domain.Candle {
+	open := qualityStart.Add(time.Duration(i) * time.Minute)
+	return &domain.Candle{
+		Symbol:    "BTCUSDT",
+		OpenTime:  open,
+		CloseTime: open.Add(time.Minute - time.Millisecond),
+		Open:      price,
+		High:      price + 1,
+		Low:       price - 1,
+		Close:     price + 0.5,
+		Volume:    1,
+	}
+}
+
+// minuteCandles returns 1m candles at the given minute offsets, priced 100 + offset
+func minuteCandles(offsets ...int) []*domain.Candle {
+	candles := make([]*domain.Candle, 0, len(offsets))
+	for _, i := range offsets {
+		candles = append(candles, minuteCandle(i, 100+float64(i)))
+	}
+	return candles
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"1m", time.Minute, false},
+		{"90m", 90 * time.Minute, false},
+		{"6h", 6 * time.Hour, false},
+		{"3d", 72 * time.Hour, false},
+		{"1w", 7 * 24 * time.Hour, false},
+		{"m", 0, true},
+		{"0h", 0, true},
+		{"5s", 0, true},
+		{"xh", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseInterval(tt.interval)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseInterval(%q) error = %v, wantErr %v", tt.interval, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseInterval(%q) = %v, want %v", tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestAlignTime(t *testing.T) {
+	at := time.Date(2024, 1, 3, 13, 47, 12, 0, time.UTC) // A Wednesday
+	tests := []struct {
+		d    time.Duration
+		want time.Time
+	}{
+		{5 * time.Minute, time.Date(2024, 1, 3, 13, 45, 0, 0, time.UTC)},
+		{time.Hour, time.Date(2024, 1, 3, 13, 0, 0, 0, time.UTC)},
+		{4 * time.Hour, time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{7 * 24 * time.Hour, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, // Monday
+	}
+
+	for _, tt := range tests {
+		if got := AlignTime(at, tt.d); !got.Equal(tt.want) {
+			t.Errorf("AlignTime(%s, %v) = %s, want %s", at, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestResampleCandles(t *testing.T) {
+	tests := []struct {
+		name           string
+		offsets        []int
+		dropIncomplete bool
+		wantOpens      []int // Bucket open minutes
+	}{
+		{
+			name:           "full buckets",
+			offsets:        []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+			dropIncomplete: true,
+			wantOpens:      []int{0, 5},
+		},
+		{
+			name:           "partial trailing bucket dropped",
+			offsets:        []int{0, 1, 2, 3, 4, 5, 6},
+			dropIncomplete: true,
+			wantOpens:      []int{0},
+		},
+		{
+			name:           "partial trailing bucket kept",
+			offsets:        []int{0, 1, 2, 3, 4, 5, 6},
+			dropIncomplete: false,
+			wantOpens:      []int{0, 5},
+		},
+		{
+			name:           "gap inside a bucket",
+			offsets:        []int{0, 1, 3, 4, 5, 6, 7, 8, 9},
+			dropIncomplete: true,
+			wantOpens:      []int{5},
+		},
+		{
+			name:           "whole bucket missing",
+			offsets:        []int{0, 1, 2, 3, 4, 10, 11, 12, 13, 14},
+			dropIncomplete: true,
+			wantOpens:      []int{0, 10},
+		},
+		{
+			name:           "no candles",
+			dropIncomplete: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResampleCandles(minuteCandles(tt.offsets...), time.Minute, 5*time.Minute, tt.dropIncomplete)
+			if err != nil {
+				t.Fatalf("ResampleCandles: %v", err)
+			}
+			if len(got) != len(tt.wantOpens) {
+				t.Fatalf("got %d candles, want %d", len(got), len(tt.wantOpens))
+			}
+			for i, c := range got {
+				want := qualityStart.Add(time.Duration(tt.wantOpens[i]) * time.Minute)
+				if !c.OpenTime.Equal(want) || !c.CloseTime.Equal(want.Add(5*time.Minute-time.Millisecond)) {
+					t.Errorf("candle %d = %s-%s, want to open at %s", i, c.OpenTime, c.CloseTime, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleCandlesAggregates(t *testing.T) {
+	got, err := ResampleCandles(minuteCandles(0, 1, 2, 3, 4), time.Minute, 5*time.Minute, true)
+	if err != nil {
+		t.Fatalf("ResampleCandles: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d candles, want 1", len(got))
+	}
+
+	// Open of the first bar, close of the last, extremes and summed volume
+	c := got[0]
+	if c.Open != 100 || c.High != 105 || c.Low != 99 || c.Close != 104.5 || c.Volume != 5 {
+		t.Errorf("candle = O %v H %v L %v C %v V %v, want O 100 H 105 L 99 C 104.5 V 5",
+			c.Open, c.High, c.Low, c.Close, c.Volume)
+	}
+}
+
+func TestResampleCandlesRejectsIntervals(t *testing.T) {
+	for _, target := range []time.Duration{30 * time.Second, 90 * time.Second} {
+		if _, err := ResampleCandles(nil, time.Minute, target, true); err == nil {
+			t.Errorf("ResampleCandles(1m -> %v) succeeded, want error", target)
+		}
+	}
+}
+
+func TestResamplerMaterializeAndVerify(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCandleRepository(newTestDB(t))
+	if err := repo.EnsureTable(BaseInterval); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	// Two full 5m buckets and a partial one
+	offsets := make([]int, 0, 12)
+	for i := 0; i < 12; i++ {
+		offsets = append(offsets, i)
+	}
+	if err := repo.SaveBatch(ctx, minuteCandles(offsets...), BaseInterval); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+	r := NewResampler(repo)
+
+	// The range is widened to bucket boundaries
+	n, err := r.Materialize(ctx, "BTCUSDT", "5m", qualityStart.Add(2*time.Minute), qualityStart.Add(12*time.Minute))
+	if err != nil {
+		t.Fatalf("Materialize: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("materialized %d candles, want 2", n)
+	}
+
+	result, err := r.Verify(ctx, "BTCUSDT", "5m", qualityStart, qualityStart.Add(15*time.Minute), 1e-9)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if result.Compared != 2 || len(result.Mismatches) != 0 {
+		t.Errorf("verify = %d compared, %v mismatches, want 2 and none", result.Compared, result.Mismatches)
+	}
+
+	// A stored exchange bar that disagrees, and one without 1m data
+	derived, err := r.Resample(ctx, "BTCUSDT", "5m", qualityStart, qualityStart.Add(5*time.Minute))
+	if err != nil || len(derived) != 1 {
+		t.Fatalf("Resample = %d candles, %v, want 1", len(derived), err)
+	}
+	bad := *derived[0]
+	bad.High = 200
+	missing := *derived[0]
+	missing.OpenTime = qualityStart.Add(time.Hour)
+	missing.CloseTime = missing.OpenTime.Add(5*time.Minute - time.Millisecond)
+	if err := repo.SaveBatch(ctx, []*domain.Candle{&bad, &missing}, "5m"); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+	result, err = r.Verify(ctx, "BTCUSDT", "5m", qualityStart, qualityStart.Add(2*time.Hour), 1e-9)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	var fields []string
+	for _, m := range result.Mismatches {
+		if m.Missing {
+			fields = append(fields, "missing")
+		} else {
+			fields = append(fields, m.Field)
+		}
+	}
+	if len(fields) != 2 || fields[0] != "high" {
+		t.Errorf("mismatches = %v, want [high missing]", fields)
+	}
+
+	if _, err := r.Materialize(ctx, "BTCUSDT", BaseInterval, qualityStart, qualityStart.Add(time.Hour)); err == nil {
+		t.Error("Materialize(1m) succeeded, want base interval error")
+	}
+}
@@ -0,0 +1,182 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// CollectionTask is one symbol/interval range to collect
+type CollectionTask struct {
+	Symbol   string    `json:"symbol"`
+	Interval string    `json:"interval"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// CollectionProgress reports aggregate progress of a scheduled collection
+type CollectionProgress struct {
+	TotalTasks     int           `json:"total_tasks"`
+	CompletedTasks int           `json:"completed_tasks"`
+	FailedTasks    int           `json:"failed_tasks"`
+	RunningTasks   int           `json:"running_tasks"`
+	CandlesSaved   int           `json:"candles_saved"`
+	Elapsed        time.Duration `json:"elapsed"`
+	Errors         []string      `json:"errors,omitempty"`
+}
+
+// Scheduler fans collection tasks out over a worker pool.
+// All workers share the collector's request weight limiter.
+type Scheduler struct {
+	collector *Collector
+	workers   int
+
+	mu       sync.Mutex
+	progress CollectionProgress
+	started  time.Time
+}
+
+// NewScheduler creates a new collection scheduler
+func NewScheduler(collector *Collector, workers int) *Scheduler {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Scheduler{
+		collector: collector,
+		workers:   workers,
+	}
+}
+
+// BuildTasks creates one task per symbol/interval pair over the same range
+func BuildTasks(symbols, intervals []string, start, end time.Time) []CollectionTask {
+	tasks := make([]CollectionTask, 0, len(symbols)*len(intervals))
+	for _, symbol := range symbols {
+		for _, interval := range intervals {
+			tasks = append(tasks, CollectionTask{
+				Symbol:   symbol,
+				Interval: interval,
+				Start:    start,
+				End:      end,
+			})
+		}
+	}
+	return tasks
+}
+
+// Run collects every task and returns the final progress.
+// A failed task doesn't stop the others; an error is returned if any failed.
+func (s *Scheduler) Run(ctx context.Context, tasks []CollectionTask) (*CollectionProgress, error) {
+	s.mu.Lock()
+	s.progress = CollectionProgress{TotalTasks: len(tasks)}
+	s.started = time.Now()
+	s.mu.Unlock()
+
+	for _, t := range tasks {
+		if err := s.collector.candleRepo.EnsureTable(t.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	taskCh := make(chan CollectionTask)
+	var wg sync.WaitGroup
+
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range taskCh {
+				s.runTask(ctx, task)
+			}
+		}()
+	}
+
+	// Periodic aggregate progress
+	done := make(chan struct{})
+	go s.reportProgress(done)
+
+	for _, task := range tasks {
+		select {
+		case taskCh <- task:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(taskCh)
+	wg.Wait()
+	close(done)
+
+	progress := s.Progress()
+	log.Printf("✅ Collection finished: %d/%d tasks, %d failed, %d candles in %v",
+		progress.CompletedTasks, progress.TotalTasks, progress.FailedTasks,
+		progress.CandlesSaved, progress.Elapsed.Round(time.Second))
+
+	if ctx.Err() != nil {
+		return progress, ctx.Err()
+	}
+	if progress.FailedTasks > 0 {
+		return progress, fmt.Errorf("%d of %d collection tasks failed", progress.FailedTasks, progress.TotalTasks)
+	}
+	return progress, nil
+}
+
+// runTask collects one task and records the outcome
+func (s *Scheduler) runTask(ctx context.Context, task CollectionTask) {
+	s.mu.Lock()
+	s.progress.RunningTasks++
+	s.mu.Unlock()
+
+	// Each task is a resumable job, so a rerun continues from its checkpoint
+	job, err := s.collector.PrepareJob(ctx, task.Symbol, task.Interval, task.Start, task.End)
+	if err == nil {
+		err = s.collector.RunJob(ctx, job, func(saved int) {
+			s.mu.Lock()
+			s.progress.CandlesSaved += saved
+			s.mu.Unlock()
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.progress.RunningTasks--
+	if err != nil {
+		s.progress.FailedTasks++
+		s.progress.Errors = append(s.progress.Errors, fmt.Sprintf("%s %s: %v", task.Symbol, task.Interval, err))
+		log.Printf("❌ Collection failed for %s %s: %v", task.Symbol, task.Interval, err)
+		return
+	}
+	s.progress.CompletedTasks++
+}
+
+// reportProgress logs aggregate progress every 10 seconds until done is closed
+func (s *Scheduler) reportProgress(done <-chan struct{}) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p := s.Progress()
+			log.Printf("Overall: %d/%d tasks done (%d running, %d failed) | %d candles | weight used: %d/min",
+				p.CompletedTasks, p.TotalTasks, p.RunningTasks, p.FailedTasks,
+				p.CandlesSaved, s.collector.limiter.UsedWeight())
+		case <-done:
+			return
+		}
+	}
+}
+
+// Progress returns a snapshot of the current progress
+func (s *Scheduler) Progress() *CollectionProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.progress
+	p.Errors = append([]string(nil), s.progress.Errors...)
+	p.Elapsed = time.Since(s.started)
+	return &p
+}
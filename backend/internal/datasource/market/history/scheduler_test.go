@@ -0,0 +1,120 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// slowKlineSource serves fakeKlineSource bars after a delay and records how
+// many requests were in flight at once. With block set, requests wait for ctx.
+type slowKlineSource struct {
+	*fakeKlineSource
+	delay time.Duration
+	block bool
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *slowKlineSource) GetKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	if s.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	time.Sleep(s.delay)
+	return s.fakeKlineSource.GetKlines(ctx, symbol, interval, start, end, limit)
+}
+
+func newTestScheduler(t *testing.T, source *slowKlineSource, workers int) (*Scheduler, *Collector) {
+	t.Helper()
+	db := newTestDB(t)
+	collector := NewSourceCollector(source, db, NewCandleRepository(db))
+	return NewScheduler(collector, workers), collector
+}
+
+func TestBuildTasks(t *testing.T) {
+	end := qualityStart.Add(time.Hour)
+	tasks := BuildTasks([]string{"BTCUSDT", "ETHUSDT"}, []string{"1m", "1h"}, qualityStart, end)
+	want := []string{"BTCUSDT 1m", "BTCUSDT 1h", "ETHUSDT 1m", "ETHUSDT 1h"}
+	if len(tasks) != len(want) {
+		t.Fatalf("got %d tasks, want %d", len(tasks), len(want))
+	}
+	for i, task := range tasks {
+		if got := task.Symbol + " " + task.Interval; got != want[i] || !task.Start.Equal(qualityStart) || !task.End.Equal(end) {
+			t.Errorf("task %d = %+v, want %s over the range", i, task, want[i])
+		}
+	}
+}
+
+func TestSchedulerRunLimitsConcurrency(t *testing.T) {
+	source := &slowKlineSource{
+		fakeKlineSource: &fakeKlineSource{cutoff: qualityStart.Add(time.Hour)},
+		delay:           20 * time.Millisecond,
+	}
+	s, _ := newTestScheduler(t, source, 2)
+	tasks := BuildTasks([]string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT"}, []string{"1m"},
+		qualityStart, qualityStart.Add(10*time.Minute))
+
+	progress, err := s.Run(context.Background(), tasks)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if source.maxInFlight != 2 {
+		t.Errorf("max requests in flight = %d, want 2", source.maxInFlight)
+	}
+	if progress.CompletedTasks != 4 || progress.FailedTasks != 0 || progress.RunningTasks != 0 {
+		t.Errorf("progress = %+v, want 4 completed", progress)
+	}
+	if progress.CandlesSaved != 40 {
+		t.Errorf("candles saved = %d, want 40", progress.CandlesSaved)
+	}
+}
+
+func TestSchedulerRunCancelled(t *testing.T) {
+	source := &slowKlineSource{
+		fakeKlineSource: &fakeKlineSource{cutoff: qualityStart.Add(time.Hour)},
+		block:           true,
+	}
+	s, collector := newTestScheduler(t, source, 1)
+	tasks := BuildTasks([]string{"BTCUSDT", "ETHUSDT"}, []string{"1m"},
+		qualityStart, qualityStart.Add(10*time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	progress, err := s.Run(ctx, tasks)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v, want canceled", err)
+	}
+	// Nothing completes and no worker is left running
+	if progress.FailedTasks == 0 || progress.CompletedTasks != 0 || progress.RunningTasks != 0 {
+		t.Errorf("progress = %+v, want only failed tasks", progress)
+	}
+
+	// The interrupted job is kept for a rerun
+	job, err := collector.jobRepo.FindResumable(context.Background(), "BTCUSDT", "1m", qualityStart)
+	if err != nil {
+		t.Fatalf("FindResumable: %v", err)
+	}
+	if job == nil || job.Status != JobStatusFailed {
+		t.Errorf("job = %+v, want a failed job", job)
+	}
+}
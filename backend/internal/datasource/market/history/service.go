@@ -3,45 +3,107 @@ package history
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
 	"github.com/lavumi/crypto-quant/internal/domain"
 )
 
 // Service handles historical data operations
 type Service struct {
-	candleRepo *CandleRepository
-	tradeRepo  *TradeRepository
-	collector  *Collector
+	candleRepo     CandleStore
+	tradeRepo      TradeStore
+	aggTradeRepo   AggTradeStore
+	quarantineRepo *QuarantineRepository
+	jobRepo        *JobRepository
+	collector      *Collector
+	aggCollector   *AggTradeCollector
+	resampler      *Resampler
+	importer       *Importer
+
+	jobsMu     sync.Mutex
+	activeJobs map[int64]bool // Jobs running in this process
 }
 
-// NewService creates a new data service
-func NewService(candleRepo *CandleRepository, tradeRepo *TradeRepository, binanceClient *binance.Client) *Service {
+// NewService creates a new data service.
+// db holds the bookkeeping tables; market data lives in the configured stores.
+func NewService(db *database.DB, stores *Stores, binanceClient *binance.Client) *Service {
 	return &Service{
-		candleRepo: candleRepo,
-		tradeRepo:  tradeRepo,
-		collector:  NewCollector(binanceClient, candleRepo),
+		candleRepo:     stores.Candles,
+		tradeRepo:      stores.Trades,
+		aggTradeRepo:   stores.AggTrades,
+		quarantineRepo: NewQuarantineRepository(db),
+		jobRepo:        NewJobRepository(db),
+		collector:      NewCollector(binanceClient, db, stores.Candles),
+		aggCollector:   NewAggTradeCollector(binanceClient, stores.AggTrades),
+		resampler:      NewResampler(stores.Candles),
+		importer:       NewImporter(db, stores.Candles),
+		activeJobs:     make(map[int64]bool),
 	}
 }
 
-// CollectHistoricalData collects historical candle data
+// CollectHistoricalData collects historical candle data as a resumable job
 func (s *Service) CollectHistoricalData(ctx context.Context, symbol, interval string, startTime, endTime time.Time) error {
-	if err := s.collector.CollectHistorical(ctx, symbol, interval, startTime, endTime); err != nil {
+	if _, err := s.collector.CollectResumable(ctx, symbol, interval, startTime, endTime); err != nil {
 		return fmt.Errorf("failed to collect historical data: %w", err)
 	}
 	return nil
 }
 
-// GetCandles retrieves candles within a time range
+// GetCandles retrieves candles within a time range.
+// Intervals without stored data are derived on the fly from 1m candles.
 func (s *Service) GetCandles(ctx context.Context, symbol, interval string, start, end time.Time) ([]*domain.Candle, error) {
+	if interval != BaseInterval {
+		hasTable, err := s.candleRepo.HasTable(ctx, interval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get candles: %w", err)
+		}
+		if !hasTable {
+			return s.resampleCandles(ctx, symbol, interval, start, end)
+		}
+	}
+
 	candles, err := s.candleRepo.GetRange(ctx, symbol, interval, start, end)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get candles: %w", err)
 	}
+
+	if len(candles) == 0 && interval != BaseInterval {
+		return s.resampleCandles(ctx, symbol, interval, start, end)
+	}
+
+	return candles, nil
+}
+
+// resampleCandles derives candles from 1m data
+func (s *Service) resampleCandles(ctx context.Context, symbol, interval string, start, end time.Time) ([]*domain.Candle, error) {
+	candles, err := s.resampler.Resample(ctx, symbol, interval, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resample candles: %w", err)
+	}
 	return candles, nil
 }
 
+// MaterializeCandles builds candles for an interval from 1m data and stores them
+func (s *Service) MaterializeCandles(ctx context.Context, symbol, interval string, start, end time.Time) (int, error) {
+	count, err := s.resampler.Materialize(ctx, symbol, interval, start, end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to materialize %s candles: %w", interval, err)
+	}
+	return count, nil
+}
+
+// VerifyResampledCandles compares candles derived from 1m data against stored exchange bars
+func (s *Service) VerifyResampledCandles(ctx context.Context, symbol, interval string, start, end time.Time, tolerance float64) (*ResampleCheckResult, error) {
+	result, err := s.resampler.Verify(ctx, symbol, interval, start, end, tolerance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify resampled candles: %w", err)
+	}
+	return result, nil
+}
+
 // GetLatestCandle retrieves the most recent candle
 func (s *Service) GetLatestCandle(ctx context.Context, symbol, interval string) (*domain.Candle, error) {
 	candle, err := s.candleRepo.GetLatest(ctx, symbol, interval)
@@ -73,65 +135,58 @@ func (s *Service) GetTradeHistory(ctx context.Context, symbol string) ([]*domain
 
 // DataValidationResult represents the result of data validation
 type DataValidationResult struct {
-	HasData       bool       `json:"has_data"`
-	AvailableFrom *time.Time `json:"available_from,omitempty"`
-	AvailableTo   *time.Time `json:"available_to,omitempty"`
-	RequestedFrom time.Time  `json:"requested_from"`
-	RequestedTo   time.Time  `json:"requested_to"`
-	CandleCount   int        `json:"candle_count"`
-	IsComplete    bool       `json:"is_complete"`
-	Message       string     `json:"message"`
+	HasData        bool       `json:"has_data"`
+	AvailableFrom  *time.Time `json:"available_from,omitempty"`
+	AvailableTo    *time.Time `json:"available_to,omitempty"`
+	RequestedFrom  time.Time  `json:"requested_from"`
+	RequestedTo    time.Time  `json:"requested_to"`
+	CandleCount    int        `json:"candle_count"`
+	MissingCandles int        `json:"missing_candles"`
+	Gaps           []Gap      `json:"gaps"`
+	IsComplete     bool       `json:"is_complete"`
+	Message        string     `json:"message"`
 }
 
-// ValidateDataAvailability checks if data is available for the requested period
+// ValidateDataAvailability checks if data is available for the requested period,
+// including holes in the middle of the range
 func (s *Service) ValidateDataAvailability(ctx context.Context, symbol, interval string, startTime, endTime time.Time) (*DataValidationResult, error) {
 	result := &DataValidationResult{
 		RequestedFrom: startTime,
 		RequestedTo:   endTime,
 		HasData:       false,
 		IsComplete:    false,
+		Gaps:          make([]Gap, 0),
 	}
 
-	// Get candles in the requested range
-	candles, err := s.candleRepo.GetRange(ctx, symbol, interval, startTime, endTime)
+	report, err := s.DetectGaps(ctx, symbol, interval, startTime, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check data availability: %w", err)
 	}
 
-	result.CandleCount = len(candles)
+	result.CandleCount = report.Found
+	result.MissingCandles = report.Missing
+	result.Gaps = report.Gaps
 
-	if len(candles) == 0 {
+	if report.Found == 0 {
 		result.Message = fmt.Sprintf("No data available for %s %s in the requested period", symbol, interval)
 		return result, nil
 	}
 
 	result.HasData = true
-	firstCandle := candles[0].OpenTime
-	lastCandle := candles[len(candles)-1].OpenTime
-	result.AvailableFrom = &firstCandle
-	result.AvailableTo = &lastCandle
-
-	// Compare dates only (ignore time component)
-	// Truncate to day boundary for accurate comparison
-	firstCandleDate := time.Date(firstCandle.Year(), firstCandle.Month(), firstCandle.Day(), 0, 0, 0, 0, time.UTC)
-	lastCandleDate := time.Date(lastCandle.Year(), lastCandle.Month(), lastCandle.Day(), 0, 0, 0, 0, time.UTC)
-	requestedStartDate := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, time.UTC)
-	requestedEndDate := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 0, 0, 0, 0, time.UTC)
-
-	// Check if the available data covers the requested period
-	// firstCandleDate should be <= requestedStartDate
-	// lastCandleDate should be >= requestedEndDate
-	if firstCandleDate.After(requestedStartDate) || lastCandleDate.Before(requestedEndDate) {
+	firstCandle, lastCandle, err := s.GetDataRange(ctx, symbol, interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check data availability: %w", err)
+	}
+	result.AvailableFrom = firstCandle
+	result.AvailableTo = lastCandle
+
+	if report.Missing > 0 {
 		result.IsComplete = false
-		result.Message = fmt.Sprintf("Data is incomplete. Available: %s to %s, Requested: %s to %s (Missing: %d candles)",
-			firstCandleDate.Format("2006-01-02"),
-			lastCandleDate.Format("2006-01-02"),
-			requestedStartDate.Format("2006-01-02"),
-			requestedEndDate.Format("2006-01-02"),
-			len(candles))
+		result.Message = fmt.Sprintf("Data is incomplete. Found %d of %d candles (Missing: %d candles in %d gaps)",
+			report.Found, report.Expected, report.Missing, len(report.Gaps))
 	} else {
 		result.IsComplete = true
-		result.Message = fmt.Sprintf("Data is complete with %d candles", len(candles))
+		result.Message = fmt.Sprintf("Data is complete with %d candles", report.Found)
 	}
 
 	return result, nil
@@ -0,0 +1,123 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+// Storage backends
+const (
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+// CandleStore persists OHLCV candles in one table per interval
+type CandleStore interface {
+	// EnsureTable creates the candle table for the interval if needed
+	EnsureTable(interval string) error
+	// HasTable reports whether a candle table exists for the interval
+	HasTable(ctx context.Context, interval string) (bool, error)
+	// Save upserts a single candle
+	Save(ctx context.Context, candle *domain.Candle, interval string) error
+	// SaveBatch upserts candles in one transaction
+	SaveBatch(ctx context.Context, candles []*domain.Candle, interval string) error
+	// GetRange returns candles with start <= open_time < end, oldest first
+	GetRange(ctx context.Context, symbol, interval string, start, end time.Time) ([]*domain.Candle, error)
+	// GetOpenTimes returns only the open times within [start, end)
+	GetOpenTimes(ctx context.Context, symbol, interval string, start, end time.Time) ([]time.Time, error)
+	// GetFirst returns the oldest candle, or nil if there is none
+	GetFirst(ctx context.Context, symbol, interval string) (*domain.Candle, error)
+	// GetLatest returns the newest candle, or nil if there is none
+	GetLatest(ctx context.Context, symbol, interval string) (*domain.Candle, error)
+}
+
+// TradeStore persists executed trades
+type TradeStore interface {
+	// Save stores a trade
+	Save(ctx context.Context, trade *domain.Trade) error
+	// GetBySymbol returns all trades for a symbol, newest first
+	GetBySymbol(ctx context.Context, symbol string) ([]*domain.Trade, error)
+}
+
+// AggTradeStore persists Binance aggregate trades in one table per symbol
+type AggTradeStore interface {
+	// EnsureTable creates the aggTrade table for the symbol if needed
+	EnsureTable(symbol string) error
+	// SaveBatch stores trades in one transaction, ignoring IDs already stored
+	SaveBatch(ctx context.Context, symbol string, trades []*domain.AggTrade) error
+	// GetRange returns up to limit trades with start <= time < end, oldest first (0 = no limit)
+	GetRange(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.AggTrade, error)
+	// Scan streams trades with start <= time < end to fn in ID order without loading them all
+	Scan(ctx context.Context, symbol string, start, end time.Time, fn func(*domain.AggTrade) error) error
+	// GetLatest returns the trade with the highest ID, or nil if there is none
+	GetLatest(ctx context.Context, symbol string) (*domain.AggTrade, error)
+}
+
+var (
+	_ CandleStore = (*CandleRepository)(nil)
+	_ CandleStore = (*PostgresCandleStore)(nil)
+	_ TradeStore  = (*TradeRepository)(nil)
+	_ TradeStore  = (*PostgresTradeStore)(nil)
+
+	_ AggTradeStore = (*AggTradeRepository)(nil)
+	_ AggTradeStore = (*PostgresAggTradeStore)(nil)
+)
+
+// Stores bundles the candle and trade stores of the configured backend
+type Stores struct {
+	Backend   string
+	Candles   CandleStore
+	Trades    TradeStore
+	AggTrades AggTradeStore
+	close     func() error
+}
+
+// OpenStores opens the market data stores selected by cfg.
+// Bookkeeping tables (quarantine, collection jobs, migrations) always stay in db.
+func OpenStores(db *database.DB, cfg config.StorageConfig) (*Stores, error) {
+	switch cfg.Backend {
+	case "", BackendSQLite:
+		return &Stores{
+			Backend:   BackendSQLite,
+			Candles:   NewCandleRepository(db),
+			Trades:    NewTradeRepository(db),
+			AggTrades: NewAggTradeRepository(db),
+			close:     func() error { return nil },
+		}, nil
+
+	case BackendPostgres:
+		if cfg.Postgres.DSN == "" {
+			return nil, fmt.Errorf("storage.postgres.dsn is required for the postgres backend")
+		}
+		pg, err := database.NewPostgres(cfg.Postgres.DSN)
+		if err != nil {
+			return nil, err
+		}
+		if pg.Timescale {
+			log.Printf("✅ Market data storage: PostgreSQL with TimescaleDB hypertables")
+		} else {
+			log.Printf("✅ Market data storage: PostgreSQL (TimescaleDB not available)")
+		}
+		return &Stores{
+			Backend:   BackendPostgres,
+			Candles:   NewPostgresCandleStore(pg),
+			Trades:    NewPostgresTradeStore(pg),
+			AggTrades: NewPostgresAggTradeStore(pg),
+			close:     pg.Close,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %q", cfg.Backend)
+	}
+}
+
+// Close releases the backend connection (the SQLite handle is owned by the caller)
+func (s *Stores) Close() error {
+	return s.close()
+}
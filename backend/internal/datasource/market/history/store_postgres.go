@@ -0,0 +1,497 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// pgBuilder builds queries with PostgreSQL $n placeholders
+var pgBuilder = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// hypertableChunkRows is the target number of rows per symbol in a TimescaleDB chunk
+const hypertableChunkRows = 100000
+
+// PostgresCandleStore stores candles in PostgreSQL, as hypertables when TimescaleDB is enabled
+type PostgresCandleStore struct {
+	db     *database.PostgresDB
+	tables sync.Map // Intervals whose table is known to exist
+}
+
+// NewPostgresCandleStore creates a new PostgreSQL candle store
+func NewPostgresCandleStore(db *database.PostgresDB) *PostgresCandleStore {
+	return &PostgresCandleStore{db: db}
+}
+
+// HasTable reports whether a candle table exists for the interval
+func (r *PostgresCandleStore) HasTable(ctx context.Context, interval string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT to_regclass($1) IS NOT NULL",
+		getTableName(interval),
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check candle table: %w", err)
+	}
+	return exists, nil
+}
+
+// EnsureTable creates the candle table (and hypertable) for the interval if needed
+func (r *PostgresCandleStore) EnsureTable(interval string) error {
+	if _, ok := r.tables.Load(interval); ok {
+		return nil
+	}
+	d, err := ParseInterval(interval)
+	if err != nil {
+		return err
+	}
+
+	// Bound chunks to between a day and a year of data
+	chunk := d * hypertableChunkRows
+	if chunk < 24*time.Hour {
+		chunk = 24 * time.Hour
+	}
+	if chunk > 365*24*time.Hour {
+		chunk = 365 * 24 * time.Hour
+	}
+
+	if err := r.db.EnsureCandleTable(interval, chunk); err != nil {
+		return err
+	}
+	r.tables.Store(interval, true)
+	return nil
+}
+
+// upsertQuery returns the candle upsert statement for a table
+func (r *PostgresCandleStore) upsertQuery(tableName string) string {
+	return fmt.Sprintf(`
+		INSERT INTO %s (symbol, open_time, close_time, open, high, low, close, volume)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (symbol, open_time) DO UPDATE SET
+			close_time = excluded.close_time,
+			open = excluded.open,
+			high = excluded.high,
+			low = excluded.low,
+			close = excluded.close,
+			volume = excluded.volume
+	`, tableName)
+}
+
+// Save saves a candle to the database
+func (r *PostgresCandleStore) Save(ctx context.Context, candle *domain.Candle, interval string) error {
+	if err := r.EnsureTable(interval); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, r.upsertQuery(getTableName(interval)),
+		candle.Symbol,
+		candle.OpenTime.UnixMilli(),
+		candle.CloseTime.UnixMilli(),
+		candle.Open,
+		candle.High,
+		candle.Low,
+		candle.Close,
+		candle.Volume,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save candle: %w", err)
+	}
+
+	return nil
+}
+
+// SaveBatch saves multiple candles in a transaction
+func (r *PostgresCandleStore) SaveBatch(ctx context.Context, candles []*domain.Candle, interval string) error {
+	if err := r.EnsureTable(interval); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, r.upsertQuery(getTableName(interval)))
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, candle := range candles {
+		_, err := stmt.ExecContext(ctx,
+			candle.Symbol,
+			candle.OpenTime.UnixMilli(),
+			candle.CloseTime.UnixMilli(),
+			candle.Open,
+			candle.High,
+			candle.Low,
+			candle.Close,
+			candle.Volume,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert candle: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetRange retrieves candles within a time range
+func (r *PostgresCandleStore) GetRange(ctx context.Context, symbol, interval string, start, end time.Time) ([]*domain.Candle, error) {
+	if err := r.EnsureTable(interval); err != nil {
+		return nil, err
+	}
+
+	sqlQuery, args, err := pgBuilder.Select("symbol", "open_time", "close_time", "open", "high", "low", "close", "volume").
+		From(getTableName(interval)).
+		Where(sq.Eq{"symbol": symbol}).
+		Where(sq.GtOrEq{"open_time": start.UnixMilli()}).
+		Where(sq.Lt{"open_time": end.UnixMilli()}).
+		OrderBy("open_time ASC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candles: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []*domain.Candle
+	for rows.Next() {
+		candle, err := scanCandle(rows)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, candle)
+	}
+
+	return candles, rows.Err()
+}
+
+// GetOpenTimes retrieves only the open times of candles within a time range
+func (r *PostgresCandleStore) GetOpenTimes(ctx context.Context, symbol, interval string, start, end time.Time) ([]time.Time, error) {
+	if err := r.EnsureTable(interval); err != nil {
+		return nil, err
+	}
+
+	sqlQuery, args, err := pgBuilder.Select("open_time").
+		From(getTableName(interval)).
+		Where(sq.Eq{"symbol": symbol}).
+		Where(sq.GtOrEq{"open_time": start.UnixMilli()}).
+		Where(sq.Lt{"open_time": end.UnixMilli()}).
+		OrderBy("open_time ASC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open times: %w", err)
+	}
+	defer rows.Close()
+
+	var openTimes []time.Time
+	for rows.Next() {
+		var openTime int64
+		if err := rows.Scan(&openTime); err != nil {
+			return nil, fmt.Errorf("failed to scan open time: %w", err)
+		}
+		openTimes = append(openTimes, domain.TimeFromMillis(openTime))
+	}
+
+	return openTimes, rows.Err()
+}
+
+// GetFirst retrieves the first (oldest) candle
+func (r *PostgresCandleStore) GetFirst(ctx context.Context, symbol, interval string) (*domain.Candle, error) {
+	return r.getEdge(ctx, symbol, interval, "open_time ASC")
+}
+
+// GetLatest retrieves the most recent candle
+func (r *PostgresCandleStore) GetLatest(ctx context.Context, symbol, interval string) (*domain.Candle, error) {
+	return r.getEdge(ctx, symbol, interval, "open_time DESC")
+}
+
+// getEdge returns the first candle in the given order, or nil if there is none
+func (r *PostgresCandleStore) getEdge(ctx context.Context, symbol, interval, order string) (*domain.Candle, error) {
+	if err := r.EnsureTable(interval); err != nil {
+		return nil, err
+	}
+
+	sqlQuery, args, err := pgBuilder.Select("symbol", "open_time", "close_time", "open", "high", "low", "close", "volume").
+		From(getTableName(interval)).
+		Where(sq.Eq{"symbol": symbol}).
+		OrderBy(order).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	candle, err := scanCandle(r.db.QueryRowContext(ctx, sqlQuery, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return candle, err
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCandle scans a symbol, open_time, close_time, open, high, low, close, volume row
+func scanCandle(row rowScanner) (*domain.Candle, error) {
+	var candle domain.Candle
+	var openTime, closeTime int64
+
+	err := row.Scan(
+		&candle.Symbol,
+		&openTime,
+		&closeTime,
+		&candle.Open,
+		&candle.High,
+		&candle.Low,
+		&candle.Close,
+		&candle.Volume,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan candle: %w", err)
+	}
+
+	candle.OpenTime = domain.TimeFromMillis(openTime)
+	candle.CloseTime = domain.TimeFromMillis(closeTime)
+	return &candle, nil
+}
+
+// PostgresTradeStore stores trades in PostgreSQL
+type PostgresTradeStore struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresTradeStore creates a new PostgreSQL trade store
+func NewPostgresTradeStore(db *database.PostgresDB) *PostgresTradeStore {
+	return &PostgresTradeStore{db: db}
+}
+
+// Save saves a trade to the database
+func (r *PostgresTradeStore) Save(ctx context.Context, trade *domain.Trade) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO trades (order_id, symbol, side, price, quantity, fee, fee_asset, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		trade.OrderID,
+		trade.Symbol,
+		string(trade.Side),
+		trade.Price,
+		trade.Quantity,
+		trade.Fee,
+		trade.FeeAsset,
+		trade.Timestamp.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save trade: %w", err)
+	}
+
+	return nil
+}
+
+// GetBySymbol retrieves all trades for a symbol
+func (r *PostgresTradeStore) GetBySymbol(ctx context.Context, symbol string) ([]*domain.Trade, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, order_id, symbol, side, price, quantity, fee, fee_asset, timestamp
+		FROM trades
+		WHERE symbol = $1
+		ORDER BY timestamp DESC
+	`, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trades: %w", err)
+	}
+	defer rows.Close()
+
+	var trades []*domain.Trade
+	for rows.Next() {
+		var trade domain.Trade
+		var id int64
+		var sideStr string
+		var timestamp int64
+
+		err := rows.Scan(
+			&id,
+			&trade.OrderID,
+			&trade.Symbol,
+			&sideStr,
+			&trade.Price,
+			&trade.Quantity,
+			&trade.Fee,
+			&trade.FeeAsset,
+			&timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trade: %w", err)
+		}
+
+		trade.ID = fmt.Sprintf("%d", id)
+		trade.Side = domain.OrderSide(sideStr)
+		trade.Timestamp = domain.TimeFromMillis(timestamp)
+		trades = append(trades, &trade)
+	}
+
+	return trades, rows.Err()
+}
+
+// PostgresAggTradeStore stores aggregate trades in per-symbol PostgreSQL tables
+type PostgresAggTradeStore struct {
+	db     *database.PostgresDB
+	tables sync.Map // Symbols whose table is known to exist
+}
+
+// NewPostgresAggTradeStore creates a new PostgreSQL aggTrade store
+func NewPostgresAggTradeStore(db *database.PostgresDB) *PostgresAggTradeStore {
+	return &PostgresAggTradeStore{db: db}
+}
+
+// EnsureTable creates the aggTrade table (and hypertable) for the symbol if needed
+func (r *PostgresAggTradeStore) EnsureTable(symbol string) error {
+	if _, ok := r.tables.Load(symbol); ok {
+		return nil
+	}
+	if err := r.db.EnsureAggTradeTable(symbol); err != nil {
+		return err
+	}
+	r.tables.Store(symbol, true)
+	return nil
+}
+
+// SaveBatch saves trades in a transaction; trades already stored are skipped
+func (r *PostgresAggTradeStore) SaveBatch(ctx context.Context, symbol string, trades []*domain.AggTrade) error {
+	if err := r.EnsureTable(symbol); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, time, price, quantity, first_id, last_id, buyer_maker)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT DO NOTHING
+	`, database.AggTradeTableName(symbol)))
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range trades {
+		_, err := stmt.ExecContext(ctx,
+			t.ID,
+			t.Time.UnixMilli(),
+			t.Price,
+			t.Quantity,
+			t.FirstTradeID,
+			t.LastTradeID,
+			t.IsBuyerMaker,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert aggTrade: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetRange retrieves up to limit trades within a time range (0 = no limit)
+func (r *PostgresAggTradeStore) GetRange(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.AggTrade, error) {
+	trades := make([]*domain.AggTrade, 0)
+	err := r.scan(ctx, symbol, start, end, limit, func(t *domain.AggTrade) error {
+		trades = append(trades, t)
+		return nil
+	})
+	return trades, err
+}
+
+// Scan streams trades within a time range to fn in ID order
+func (r *PostgresAggTradeStore) Scan(ctx context.Context, symbol string, start, end time.Time, fn func(*domain.AggTrade) error) error {
+	return r.scan(ctx, symbol, start, end, 0, fn)
+}
+
+// scan runs a time range query and passes each trade to fn
+func (r *PostgresAggTradeStore) scan(ctx context.Context, symbol string, start, end time.Time, limit int, fn func(*domain.AggTrade) error) error {
+	if err := r.EnsureTable(symbol); err != nil {
+		return err
+	}
+
+	query := pgBuilder.Select(aggTradeColumns...).
+		From(database.AggTradeTableName(symbol)).
+		Where(sq.GtOrEq{"time": start.UnixMilli()}).
+		Where(sq.Lt{"time": end.UnixMilli()}).
+		OrderBy("id ASC")
+	if limit > 0 {
+		query = query.Limit(uint64(limit))
+	}
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query aggTrades: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanAggTrade(rows, symbol)
+		if err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetLatest retrieves the trade with the highest aggregate ID
+func (r *PostgresAggTradeStore) GetLatest(ctx context.Context, symbol string) (*domain.AggTrade, error) {
+	if err := r.EnsureTable(symbol); err != nil {
+		return nil, err
+	}
+
+	t, err := scanAggTrade(r.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s ORDER BY id DESC LIMIT 1",
+		strings.Join(aggTradeColumns, ", "), database.AggTradeTableName(symbol),
+	)), symbol)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
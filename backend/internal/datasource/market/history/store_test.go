@@ -0,0 +1,198 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/pkg/config"
+)
+
+// postgresDSNEnv names a PostgreSQL database to run the store tests against as well
+const postgresDSNEnv = "CQ_TEST_POSTGRES_DSN"
+
+// testStores opens the stores of every backend under test: SQLite always,
+// PostgreSQL when CQ_TEST_POSTGRES_DSN is set
+func testStores(t *testing.T) map[string]*Stores {
+	t.Helper()
+	backends := map[string]config.StorageConfig{BackendSQLite: {}}
+	if dsn := os.Getenv(postgresDSNEnv); dsn != "" {
+		backends[BackendPostgres] = config.StorageConfig{Backend: BackendPostgres, Postgres: config.PostgresConfig{DSN: dsn}}
+	}
+
+	stores := make(map[string]*Stores, len(backends))
+	for name, cfg := range backends {
+		s, err := OpenStores(newTestDB(t), cfg)
+		if err != nil {
+			t.Fatalf("OpenStores(%s): %v", name, err)
+		}
+		t.Cleanup(func() { s.Close() })
+		if s.Backend != name {
+			t.Errorf("backend = %s, want %s", s.Backend, name)
+		}
+		stores[name] = s
+	}
+	return stores
+}
+
+// testSymbol returns a symbol no earlier run has written, so a shared
+// PostgreSQL database doesn't leak rows between runs
+func testSymbol() string {
+	return fmt.Sprintf("T%dUSDT", time.Now().UnixNano()%1e12)
+}
+
+func TestOpenStoresErrors(t *testing.T) {
+	db := newTestDB(t)
+	tests := []struct {
+		name string
+		cfg  config.StorageConfig
+	}{
+		{"postgres without dsn", config.StorageConfig{Backend: BackendPostgres}},
+		{"unknown backend", config.StorageConfig{Backend: "mysql"}},
+	}
+	for _, tt := range tests {
+		if _, err := OpenStores(db, tt.cfg); err == nil {
+			t.Errorf("%s: OpenStores succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestCandleStore(t *testing.T) {
+	for name, stores := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := stores.Candles
+			symbol := testSymbol()
+
+			if err := store.EnsureTable("1M"); err == nil {
+				t.Error("EnsureTable(1M) succeeded, want error")
+			}
+			if err := store.EnsureTable("3m"); err != nil {
+				t.Fatalf("EnsureTable: %v", err)
+			}
+			if ok, err := store.HasTable(ctx, "3m"); err != nil || !ok {
+				t.Errorf("HasTable(3m) = %v, %v, want true", ok, err)
+			}
+
+			// Nothing stored yet
+			if c, err := store.GetLatest(ctx, symbol, "3m"); err != nil || c != nil {
+				t.Errorf("GetLatest = %v, %v, want nil", c, err)
+			}
+
+			bar := func(i int, close float64) *domain.Candle {
+				open := qualityStart.Add(time.Duration(i) * 3 * time.Minute)
+				return &domain.Candle{
+					Symbol:    symbol,
+					OpenTime:  open,
+					CloseTime: open.Add(3*time.Minute - time.Millisecond),
+					Open:      100,
+					High:      close + 1,
+					Low:       99,
+					Close:     close,
+					Volume:    float64(i + 1),
+				}
+			}
+			if err := store.SaveBatch(ctx, []*domain.Candle{bar(0, 100), bar(1, 101), bar(2, 102)}, "3m"); err != nil {
+				t.Fatalf("SaveBatch: %v", err)
+			}
+			// Saving an existing bar again replaces it
+			if err := store.Save(ctx, bar(1, 105), "3m"); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if err := store.Save(ctx, bar(3, 103), "3m"); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			tests := []struct {
+				name       string
+				start, end int
+				want       []float64 // Closes
+			}{
+				{"everything", 0, 4, []float64{100, 105, 102, 103}},
+				{"end is exclusive", 1, 3, []float64{105, 102}},
+				{"empty range", 5, 8, nil},
+			}
+			for _, tt := range tests {
+				start := qualityStart.Add(time.Duration(tt.start) * 3 * time.Minute)
+				end := qualityStart.Add(time.Duration(tt.end) * 3 * time.Minute)
+
+				candles, err := store.GetRange(ctx, symbol, "3m", start, end)
+				if err != nil {
+					t.Fatalf("GetRange: %v", err)
+				}
+				var closes []float64
+				for _, c := range candles {
+					closes = append(closes, c.Close)
+				}
+				if fmt.Sprint(closes) != fmt.Sprint(tt.want) {
+					t.Errorf("%s: closes = %v, want %v", tt.name, closes, tt.want)
+				}
+
+				times, err := store.GetOpenTimes(ctx, symbol, "3m", start, end)
+				if err != nil {
+					t.Fatalf("GetOpenTimes: %v", err)
+				}
+				if len(times) != len(tt.want) || (len(times) > 0 && !times[0].Equal(start)) {
+					t.Errorf("%s: open times = %v, want %d from %s", tt.name, times, len(tt.want), start)
+				}
+			}
+
+			// Millisecond close times and every column round-trip
+			first, err := store.GetFirst(ctx, symbol, "3m")
+			if err != nil || first == nil {
+				t.Fatalf("GetFirst = %v, %v", first, err)
+			}
+			if want := bar(0, 100); !first.OpenTime.Equal(want.OpenTime) || !first.CloseTime.Equal(want.CloseTime) || first.Symbol != want.Symbol ||
+				first.Open != want.Open || first.High != want.High || first.Low != want.Low || first.Close != want.Close || first.Volume != want.Volume {
+				t.Errorf("first = %+v, want %+v", first, want)
+			}
+			latest, err := store.GetLatest(ctx, symbol, "3m")
+			if err != nil || latest == nil || latest.Close != 103 {
+				t.Errorf("GetLatest = %v, %v, want close 103", latest, err)
+			}
+		})
+	}
+}
+
+func TestTradeStore(t *testing.T) {
+	for name, stores := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			symbol := testSymbol()
+
+			trades := []*domain.Trade{
+				{OrderID: "1", Symbol: symbol, Side: domain.OrderSideBuy, Price: 100, Quantity: 1, Fee: 0.1, FeeAsset: "USDT", Timestamp: qualityStart.Add(1500 * time.Millisecond)},
+				{OrderID: "2", Symbol: symbol, Side: domain.OrderSideSell, Price: 110, Quantity: 1, Fee: 0.11, FeeAsset: "USDT", Timestamp: qualityStart.Add(time.Hour)},
+				{OrderID: "3", Symbol: "OTHERUSDT", Side: domain.OrderSideBuy, Price: 1, Quantity: 1, FeeAsset: "USDT", Timestamp: qualityStart},
+			}
+			for _, trade := range trades {
+				if err := stores.Trades.Save(ctx, trade); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+			}
+
+			got, err := stores.Trades.GetBySymbol(ctx, symbol)
+			if err != nil {
+				t.Fatalf("GetBySymbol: %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("got %d trades, want 2", len(got))
+			}
+			// Newest first, with millisecond timestamps
+			want := []*domain.Trade{trades[1], trades[0]}
+			for i, trade := range got {
+				if trade.OrderID != want[i].OrderID || trade.Side != want[i].Side || trade.Price != want[i].Price ||
+					trade.Fee != want[i].Fee || !trade.Timestamp.Equal(want[i].Timestamp) {
+					t.Errorf("trade %d = %+v, want %+v", i, trade, want[i])
+				}
+			}
+
+			if none, err := stores.Trades.GetBySymbol(ctx, "NONEUSDT"); err != nil || len(none) != 0 {
+				t.Errorf("GetBySymbol(unknown) = %d trades, %v, want none", len(none), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Sync states
+const (
+	SyncStateStarting   = "starting"
+	SyncStateCatchingUp = "catching_up"
+	SyncStateStreaming  = "streaming"
+	SyncStateIdle       = "idle" // Waiting for the next poll (WebSocket disabled or down)
+	SyncStateError      = "error"
+	SyncStateStopped    = "stopped"
+)
+
+// SyncConfig configures the continuous sync service
+type SyncConfig struct {
+	Symbols      []string
+	Intervals    []string
+	Lookback     time.Duration // How far back to start when a table has no data
+	PollInterval time.Duration // REST catch-up period when not streaming
+	Jitter       time.Duration // Max random delay added to scheduled work
+	UseWebSocket bool          // Follow closed bars from the kline stream
+	Stream       KlineStreamer // Kline stream to follow (e.g. BinanceExchange); streaming needs one
+}
+
+// KlineStreamer streams a symbol's klines (forming and closed) until ctx is done
+type KlineStreamer interface {
+	StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error
+}
+
+// SyncStatus reports the sync state of one symbol/interval
+type SyncStatus struct {
+	Symbol       string     `json:"symbol"`
+	Interval     string     `json:"interval"`
+	State        string     `json:"state"`
+	LastCandle   *time.Time `json:"last_candle,omitempty"`
+	LastSync     *time.Time `json:"last_sync,omitempty"`
+	CandlesSaved int        `json:"candles_saved"`
+	Reconnects   int        `json:"reconnects"`
+	LastError    string     `json:"last_error,omitempty"`
+	Healthy      bool       `json:"healthy"`
+}
+
+// SyncHealth summarizes the health of all sync targets
+type SyncHealth struct {
+	Healthy bool          `json:"healthy"`
+	Running bool          `json:"running"`
+	Targets []*SyncStatus `json:"targets"`
+}
+
+// Syncer keeps a set of symbols and intervals continuously up to date
+type Syncer struct {
+	cfg        SyncConfig
+	collector  *Collector
+	candleRepo CandleStore
+
+	mu      sync.RWMutex
+	status  map[string]*SyncStatus
+	running bool
+	wg      sync.WaitGroup
+}
+
+// NewSyncer creates a new sync service
+func NewSyncer(cfg SyncConfig, collector *Collector, candleRepo CandleStore) *Syncer {
+	if cfg.Lookback == 0 {
+		cfg.Lookback = 7 * 24 * time.Hour
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = time.Minute
+	}
+	if cfg.Jitter == 0 {
+		cfg.Jitter = 5 * time.Second
+	}
+
+	s := &Syncer{
+		cfg:        cfg,
+		collector:  collector,
+		candleRepo: candleRepo,
+		status:     make(map[string]*SyncStatus),
+	}
+	for _, symbol := range cfg.Symbols {
+		for _, interval := range cfg.Intervals {
+			s.status[syncKey(symbol, interval)] = &SyncStatus{
+				Symbol:   symbol,
+				Interval: interval,
+				State:    SyncStateStarting,
+			}
+		}
+	}
+	return s
+}
+
+// syncKey returns the status map key for a symbol/interval
+func syncKey(symbol, interval string) string {
+	return symbol + "@" + interval
+}
+
+// Start launches one sync loop per symbol/interval and returns immediately
+func (s *Syncer) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("syncer already running")
+	}
+	for _, interval := range s.cfg.Intervals {
+		if _, err := ParseInterval(interval); err != nil {
+			return err
+		}
+		if err := s.candleRepo.EnsureTable(interval); err != nil {
+			return err
+		}
+	}
+
+	s.running = true
+	for _, st := range s.status {
+		s.wg.Add(1)
+		go s.run(ctx, st.Symbol, st.Interval)
+	}
+
+	log.Printf("Sync started for %d symbols × %d intervals", len(s.cfg.Symbols), len(s.cfg.Intervals))
+	return nil
+}
+
+// Wait blocks until every sync loop has exited (after ctx is cancelled)
+func (s *Syncer) Wait() {
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+}
+
+// run resumes, catches up and then follows one symbol/interval until ctx is done
+func (s *Syncer) run(ctx context.Context, symbol, interval string) {
+	defer s.wg.Done()
+	defer s.update(symbol, interval, func(st *SyncStatus) { st.State = SyncStateStopped })
+
+	// Stagger startup so targets don't hit the API at once
+	if !s.sleep(ctx, s.jitter(0)) {
+		return
+	}
+
+	for {
+		if err := s.catchUp(ctx, symbol, interval); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Sync %s %s: catch-up failed: %v", symbol, interval, err)
+			s.update(symbol, interval, func(st *SyncStatus) {
+				st.State = SyncStateError
+				st.LastError = err.Error()
+			})
+		} else if s.cfg.UseWebSocket && s.cfg.Stream != nil {
+			// Blocks until the stream drops; missed bars are recovered by the next catch-up
+			s.stream(ctx, symbol, interval)
+			if ctx.Err() != nil {
+				return
+			}
+			s.update(symbol, interval, func(st *SyncStatus) { st.Reconnects++ })
+		}
+
+		s.update(symbol, interval, func(st *SyncStatus) {
+			if st.State != SyncStateError {
+				st.State = SyncStateIdle
+			}
+		})
+
+		if !s.sleep(ctx, s.jitter(s.cfg.PollInterval)) {
+			return
+		}
+	}
+}
+
+// catchUp collects every closed bar after the latest stored candle. Bars
+// that close while it runs are collected by another pass.
+func (s *Syncer) catchUp(ctx context.Context, symbol, interval string) error {
+	d, _ := ParseInterval(interval)
+
+	first, err := s.candleRepo.GetLatest(ctx, symbol, interval)
+	if err != nil {
+		return err
+	}
+
+	latest := first
+	saved := 0
+	for {
+		now := time.Now()
+		from := now.Add(-s.cfg.Lookback)
+		if latest != nil {
+			from = latest.OpenTime.Add(d)
+		}
+		// Only closed bars: stop just before the currently open one
+		to := AlignTime(now, d).Add(-time.Millisecond)
+		if !from.Before(to) {
+			break
+		}
+
+		s.update(symbol, interval, func(st *SyncStatus) { st.State = SyncStateCatchingUp })
+		n, err := s.collector.collect(ctx, symbol, interval, from, to, nil)
+		saved += n
+		if err != nil {
+			s.update(symbol, interval, func(st *SyncStatus) { st.CandlesSaved += saved })
+			return err
+		}
+
+		after, err := s.candleRepo.GetLatest(ctx, symbol, interval)
+		if err != nil {
+			return err
+		}
+		// Stop once a pass adds nothing (e.g. the venue hasn't published the bar yet)
+		advanced := after != nil && (latest == nil || after.OpenTime.After(latest.OpenTime))
+		latest = after
+		if !advanced {
+			break
+		}
+	}
+
+	s.update(symbol, interval, func(st *SyncStatus) {
+		synced := time.Now()
+		st.State = SyncStateIdle
+		st.LastSync = &synced
+		st.LastError = ""
+		st.CandlesSaved += saved
+		if latest != nil {
+			openTime := latest.OpenTime
+			st.LastCandle = &openTime
+		}
+	})
+
+	return nil
+}
+
+// stream follows closed bars from the kline stream until it stops or ctx is
+// done. A bar is closed once a kline with a later open time arrives. Bars that
+// closed between the catch-up and the subscription are collected by another
+// catch-up once the first kline arrives.
+func (s *Syncer) stream(ctx context.Context, symbol, interval string) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	subscribed := make(chan struct{})
+	var once sync.Once
+	go func() {
+		select {
+		case <-subscribed:
+		case <-streamCtx.Done():
+			return
+		}
+		if err := s.catchUp(streamCtx, symbol, interval); err != nil && streamCtx.Err() == nil {
+			log.Printf("Sync %s %s: catch-up after subscribing failed: %v", symbol, interval, err)
+			s.update(symbol, interval, func(st *SyncStatus) { st.LastError = err.Error() })
+		}
+		s.update(symbol, interval, func(st *SyncStatus) { st.State = SyncStateStreaming })
+	}()
+
+	var forming *domain.Candle
+	err := s.cfg.Stream.StreamKlines(streamCtx, symbol, interval, func(candle *domain.Candle) {
+		once.Do(func() { close(subscribed) })
+		if forming != nil && candle.OpenTime.After(forming.OpenTime) {
+			s.saveStreamed(ctx, symbol, interval, forming)
+		}
+		if forming == nil || !candle.OpenTime.Before(forming.OpenTime) {
+			c := *candle
+			c.Symbol = symbol
+			forming = &c
+		}
+	})
+	if err != nil {
+		log.Printf("Sync %s %s: kline stream failed: %v", symbol, interval, err)
+		s.update(symbol, interval, func(st *SyncStatus) { st.LastError = err.Error() })
+	}
+}
+
+// saveStreamed validates and stores a closed bar from the stream
+func (s *Syncer) saveStreamed(ctx context.Context, symbol, interval string, candle *domain.Candle) {
+	candles, err := s.collector.validator.Filter(ctx, []*domain.Candle{candle}, interval)
+	if err == nil {
+		err = s.candleRepo.SaveBatch(ctx, candles, interval)
+	}
+	if err != nil {
+		log.Printf("Sync %s %s: failed to save streamed candle: %v", symbol, interval, err)
+		s.update(symbol, interval, func(st *SyncStatus) { st.LastError = err.Error() })
+		return
+	}
+
+	s.update(symbol, interval, func(st *SyncStatus) {
+		synced := time.Now()
+		openTime := candle.OpenTime
+		st.LastSync = &synced
+		st.LastCandle = &openTime
+		st.CandlesSaved += len(candles)
+	})
+}
+
+// Status returns a snapshot of every target's sync status
+func (s *Syncer) Status() []*SyncStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	result := make([]*SyncStatus, 0, len(s.status))
+	for _, st := range s.status {
+		snapshot := *st
+		snapshot.Healthy = s.isHealthy(st, now)
+		result = append(result, &snapshot)
+	}
+	return result
+}
+
+// Health reports whether every target is current
+func (s *Syncer) Health() *SyncHealth {
+	targets := s.Status()
+
+	s.mu.RLock()
+	running := s.running
+	s.mu.RUnlock()
+
+	health := &SyncHealth{
+		Healthy: running,
+		Running: running,
+		Targets: targets,
+	}
+	for _, st := range targets {
+		if !st.Healthy {
+			health.Healthy = false
+		}
+	}
+	return health
+}
+
+// isHealthy reports whether a target's latest candle is no older than two bars plus the poll period
+func (s *Syncer) isHealthy(st *SyncStatus, now time.Time) bool {
+	if st.LastCandle == nil || st.State == SyncStateError || st.State == SyncStateStopped {
+		return false
+	}
+	d, _ := ParseInterval(st.Interval)
+	return now.Sub(*st.LastCandle) <= 2*d+s.cfg.PollInterval+s.cfg.Jitter
+}
+
+// update applies fn to a target's status under the lock
+func (s *Syncer) update(symbol, interval string, fn func(*SyncStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if st, ok := s.status[syncKey(symbol, interval)]; ok {
+		fn(st)
+	}
+}
+
+// jitter returns d plus a random delay of up to the configured jitter
+func (s *Syncer) jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(s.cfg.Jitter)+1))
+}
+
+// sleep waits for d or until ctx is done; it returns false if ctx was cancelled
+func (s *Syncer) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
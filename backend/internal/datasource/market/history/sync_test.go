@@ -0,0 +1,263 @@
+package history
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// syncBar returns a closed 1m candle opening at open
+func syncBar(open time.Time, close float64) *domain.Candle {
+	return &domain.Candle{
+		Symbol:    "BTCUSDT",
+		OpenTime:  open,
+		CloseTime: open.Add(time.Minute - time.Millisecond),
+		Open:      100,
+		High:      math.Max(close, 100) + 1,
+		Low:       99,
+		Close:     close,
+		Volume:    1,
+	}
+}
+
+// fakeKlineSource serves a 1m bar for every minute before cutoff except skipped ones
+type fakeKlineSource struct {
+	cutoff time.Time
+	skip   map[time.Time]bool
+
+	mu     sync.Mutex
+	starts []time.Time
+}
+
+func (f *fakeKlineSource) Venue() string      { return "fake" }
+func (f *fakeKlineSource) MaxKlineLimit() int { return 3 }
+
+func (f *fakeKlineSource) GetKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	f.mu.Lock()
+	f.starts = append(f.starts, start)
+	f.mu.Unlock()
+
+	open := AlignTime(start, time.Minute)
+	if open.Before(start) {
+		open = open.Add(time.Minute)
+	}
+	var candles []*domain.Candle
+	for ; !open.After(end) && open.Before(f.cutoff) && len(candles) < limit; open = open.Add(time.Minute) {
+		if !f.skip[open] {
+			candles = append(candles, syncBar(open, 100.5))
+		}
+	}
+	return candles, nil
+}
+
+// lastStart returns the start of the most recent request
+func (f *fakeKlineSource) lastStart() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.starts[len(f.starts)-1]
+}
+
+func newTestSyncer(t *testing.T, source *fakeKlineSource, cfg SyncConfig) (*Syncer, *CandleRepository) {
+	t.Helper()
+	db := newTestDB(t)
+	repo := NewCandleRepository(db)
+	if err := repo.EnsureTable("1m"); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	cfg.Symbols = []string{"BTCUSDT"}
+	cfg.Intervals = []string{"1m"}
+	return NewSyncer(cfg, NewSourceCollector(source, db, repo), repo), repo
+}
+
+// storedBars returns every stored 1m bar from the last hour
+func storedBars(t *testing.T, repo *CandleRepository) []*domain.Candle {
+	t.Helper()
+	now := time.Now()
+	candles, err := repo.GetRange(context.Background(), "BTCUSDT", "1m", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	return candles
+}
+
+// syncStatus returns the syncer's only target status
+func syncStatus(t *testing.T, s *Syncer) *SyncStatus {
+	t.Helper()
+	statuses := s.Status()
+	if len(statuses) != 1 {
+		t.Fatalf("got %d statuses, want 1", len(statuses))
+	}
+	return statuses[0]
+}
+
+func TestSyncCatchUpCountsStoredCandles(t *testing.T) {
+	ctx := context.Background()
+	now := AlignTime(time.Now(), time.Minute)
+	// A bar missing from the venue must not be counted
+	source := &fakeKlineSource{
+		cutoff: now.Add(time.Hour),
+		skip:   map[time.Time]bool{now.Add(-3 * time.Minute): true},
+	}
+	s, repo := newTestSyncer(t, source, SyncConfig{Lookback: 10 * time.Minute})
+	if err := repo.SaveBatch(ctx, []*domain.Candle{syncBar(now.Add(-6*time.Minute), 100.5)}, "1m"); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+
+	if err := s.catchUp(ctx, "BTCUSDT", "1m"); err != nil {
+		t.Fatalf("catchUp: %v", err)
+	}
+
+	stored := storedBars(t, repo)
+	st := syncStatus(t, s)
+	if want := len(stored) - 1; st.CandlesSaved != want {
+		t.Errorf("candles saved = %d, want %d", st.CandlesSaved, want)
+	}
+	last := stored[len(stored)-1].OpenTime
+	if st.LastCandle == nil || !st.LastCandle.Equal(last) {
+		t.Errorf("last candle = %v, want %s", st.LastCandle, last)
+	}
+	if st.State != SyncStateIdle || st.LastSync == nil {
+		t.Errorf("status = %+v, want idle with a sync time", st)
+	}
+	for _, c := range stored {
+		if c.OpenTime.Equal(now.Add(-3 * time.Minute)) {
+			t.Errorf("bar %s stored, want it missing", c.OpenTime)
+		}
+	}
+}
+
+func TestSyncCatchUpFromLookback(t *testing.T) {
+	source := &fakeKlineSource{cutoff: time.Now().Add(time.Hour)}
+	s, repo := newTestSyncer(t, source, SyncConfig{Lookback: 5 * time.Minute})
+
+	if err := s.catchUp(context.Background(), "BTCUSDT", "1m"); err != nil {
+		t.Fatalf("catchUp: %v", err)
+	}
+
+	stored := storedBars(t, repo)
+	if len(stored) == 0 {
+		t.Fatal("catch-up stored nothing")
+	}
+	if st := syncStatus(t, s); st.CandlesSaved != len(stored) {
+		t.Errorf("candles saved = %d, want %d", st.CandlesSaved, len(stored))
+	}
+	if oldest := time.Now().Add(-6 * time.Minute); stored[0].OpenTime.Before(oldest) {
+		t.Errorf("first bar %s is older than the lookback", stored[0].OpenTime)
+	}
+}
+
+func TestSyncCatchUpStopsWhenNothingPublished(t *testing.T) {
+	ctx := context.Background()
+	// The venue hasn't published the last two closed bars yet
+	cutoff := AlignTime(time.Now(), time.Minute).Add(-2 * time.Minute)
+	source := &fakeKlineSource{cutoff: cutoff}
+	s, repo := newTestSyncer(t, source, SyncConfig{})
+	if err := repo.SaveBatch(ctx, []*domain.Candle{syncBar(cutoff.Add(-3*time.Minute), 100.5)}, "1m"); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+
+	if err := s.catchUp(ctx, "BTCUSDT", "1m"); err != nil {
+		t.Fatalf("catchUp: %v", err)
+	}
+
+	st := syncStatus(t, s)
+	if st.CandlesSaved != 2 {
+		t.Errorf("candles saved = %d, want 2", st.CandlesSaved)
+	}
+	if want := cutoff.Add(-time.Minute); st.LastCandle == nil || !st.LastCandle.Equal(want) {
+		t.Errorf("last candle = %v, want %s", st.LastCandle, want)
+	}
+	// The second pass asks again from the newest stored bar and stops empty-handed
+	if got := source.lastStart(); !got.Equal(cutoff) {
+		t.Errorf("last request started at %s, want %s", got, cutoff)
+	}
+}
+
+// fakeKlineStreamer sends a forming bar, waits for release, then updates it
+// and opens the next bar, which closes the first
+type fakeKlineStreamer struct {
+	open    time.Time
+	release chan struct{}
+}
+
+func (f *fakeKlineStreamer) StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error {
+	callback(syncBar(f.open, 100.5))
+	select {
+	case <-f.release:
+	case <-ctx.Done():
+		return nil
+	}
+	callback(syncBar(f.open, 102))
+	callback(syncBar(f.open.Add(time.Minute), 100.5))
+	<-ctx.Done()
+	return nil
+}
+
+// waitForStatus polls until cond holds for the target status
+func waitForStatus(t *testing.T, s *Syncer, cond func(*SyncStatus) bool) *SyncStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := syncStatus(t, s)
+		if cond(st) {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for status, last %+v", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSyncStreamHandOff(t *testing.T) {
+	// REST covers every bar before the one the stream is forming
+	open := AlignTime(time.Now(), time.Minute)
+	source := &fakeKlineSource{cutoff: open}
+	streamer := &fakeKlineStreamer{open: open, release: make(chan struct{})}
+	s, repo := newTestSyncer(t, source, SyncConfig{
+		Lookback:     5 * time.Minute,
+		Jitter:       time.Nanosecond,
+		UseWebSocket: true,
+		Stream:       streamer,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// The first kline triggers another catch-up, then the target is streaming
+	waitForStatus(t, s, func(st *SyncStatus) bool { return st.State == SyncStateStreaming })
+	caughtUp := len(storedBars(t, repo))
+	if caughtUp == 0 {
+		t.Fatal("catch-up stored nothing before streaming")
+	}
+
+	// The forming bar is stored once the next bar opens, with its final values
+	close(streamer.release)
+	st := waitForStatus(t, s, func(st *SyncStatus) bool { return st.LastCandle != nil && st.LastCandle.Equal(open) })
+	stored := storedBars(t, repo)
+	if len(stored) != caughtUp+1 {
+		t.Fatalf("stored %d bars, want %d", len(stored), caughtUp+1)
+	}
+	if last := stored[len(stored)-1]; !last.OpenTime.Equal(open) || last.Close != 102 {
+		t.Errorf("streamed bar = %s close %v, want %s close 102", last.OpenTime, last.Close, open)
+	}
+	if st.CandlesSaved != len(stored) {
+		t.Errorf("candles saved = %d, want %d", st.CandlesSaved, len(stored))
+	}
+	if !s.Health().Healthy {
+		t.Errorf("health = %+v, want healthy", s.Health())
+	}
+
+	cancel()
+	s.Wait()
+	if st := syncStatus(t, s); st.State != SyncStateStopped {
+		t.Errorf("state = %s, want %s", st.State, SyncStateStopped)
+	}
+}
@@ -0,0 +1,27 @@
+package history
+
+import "strings"
+
+// BinanceVenue is the venue whose candles are stored under plain symbols
+const BinanceVenue = "binance"
+
+// venueSeparator joins a venue and a symbol in a storage symbol (e.g. bybit:BTCUSDT)
+const venueSeparator = ":"
+
+// StorageSymbol returns the symbol a venue's candles are stored under.
+// Binance candles keep the plain symbol so existing data stays where it is;
+// other venues are prefixed so the same market on two venues doesn't mix.
+func StorageSymbol(venue, symbol string) string {
+	if venue == "" || venue == BinanceVenue {
+		return symbol
+	}
+	return strings.ToLower(venue) + venueSeparator + symbol
+}
+
+// SplitStorageSymbol splits a storage symbol into venue and market symbol
+func SplitStorageSymbol(symbol string) (venue, market string) {
+	if venue, market, ok := strings.Cut(symbol, venueSeparator); ok {
+		return venue, market
+	}
+	return BinanceVenue, symbol
+}
@@ -0,0 +1,277 @@
+package perp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Binance USDⓈ-M futures request limits
+const (
+	futuresWeightPerMinute = 2400 // REQUEST_WEIGHT limit per minute
+	fundingPageLimit       = 1000 // GET /fapi/v1/fundingRate
+	klinesPageLimit        = 1000 // GET /fapi/v1/markPriceKlines, /fapi/v1/indexPriceKlines
+	openInterestPageLimit  = 500  // GET /futures/data/openInterestHist
+	fundingWeight          = 1
+	klinesWeight           = 5 // limit 500-1000
+	openInterestWeight     = 1
+)
+
+// OpenInterestPeriods are the sampling periods Binance keeps open interest history for
+var OpenInterestPeriods = []string{"5m", "15m", "30m", "1h", "2h", "4h", "6h", "12h", "1d"}
+
+// Collector collects perpetual futures market data from Binance USDⓈ-M futures
+type Collector struct {
+	client  *futures.Client
+	repo    *Repository
+	limiter *history.RateLimiter
+}
+
+// NewCollector creates a new perp collector with its own futures weight limiter
+func NewCollector(client *futures.Client, repo *Repository) *Collector {
+	limiter := history.NewRateLimiter(futuresWeightPerMinute)
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	// Copy the client rather than modifying a shared one (e.g. http.DefaultClient)
+	wrapped := *httpClient
+	wrapped.Transport = limiter.Transport(httpClient.Transport)
+	client.HTTPClient = &wrapped
+
+	return &Collector{
+		client:  client,
+		repo:    repo,
+		limiter: limiter,
+	}
+}
+
+// CollectFundingRates fetches and stores funding events in [start, end)
+func (c *Collector) CollectFundingRates(ctx context.Context, symbol string, start, end time.Time) (int, error) {
+	total := 0
+	current := start
+	for current.Before(end) {
+		var res []*futures.FundingRate
+		err := c.withRetry(ctx, fundingWeight, func() error {
+			var err error
+			res, err = c.client.NewFundingRateService().
+				Symbol(symbol).
+				StartTime(current.UnixMilli()).
+				EndTime(end.UnixMilli() - 1).
+				Limit(fundingPageLimit).
+				Do(ctx)
+			return err
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to fetch funding rates: %w", err)
+		}
+		if len(res) == 0 {
+			break
+		}
+
+		rates := make([]*domain.FundingRate, 0, len(res))
+		for _, f := range res {
+			rate, err := strconv.ParseFloat(f.FundingRate, 64)
+			if err != nil {
+				continue
+			}
+			rates = append(rates, &domain.FundingRate{
+				Symbol: symbol,
+				Time:   domain.TimeFromMillis(f.FundingTime),
+				Rate:   rate,
+			})
+		}
+		if err := c.repo.SaveFundingRates(ctx, rates); err != nil {
+			return total, err
+		}
+		total += len(rates)
+
+		current = domain.TimeFromMillis(res[len(res)-1].FundingTime).Add(time.Millisecond)
+		if len(res) < fundingPageLimit {
+			break
+		}
+	}
+
+	log.Printf("✅ Collected %d %s funding rates", total, symbol)
+	return total, nil
+}
+
+// CollectPriceKlines fetches and stores mark or index price klines in [start, end).
+// Index klines are requested by pair, which equals the symbol for USDⓈ-M perpetuals.
+func (c *Collector) CollectPriceKlines(ctx context.Context, kind, symbol, interval string, start, end time.Time) (int, error) {
+	if kind != KindMark && kind != KindIndex {
+		return 0, fmt.Errorf("unknown price kline kind: %q (use mark or index)", kind)
+	}
+	if _, err := history.ParseInterval(interval); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	current := start
+	for current.Before(end) {
+		var res []*futures.Kline
+		err := c.withRetry(ctx, klinesWeight, func() error {
+			var err error
+			if kind == KindMark {
+				res, err = c.client.NewMarkPriceKlinesService().
+					Symbol(symbol).
+					Interval(interval).
+					StartTime(current.UnixMilli()).
+					EndTime(end.UnixMilli() - 1).
+					Limit(klinesPageLimit).
+					Do(ctx)
+			} else {
+				res, err = c.client.NewIndexPriceKlinesService().
+					Pair(symbol).
+					Interval(interval).
+					StartTime(current.UnixMilli()).
+					EndTime(end.UnixMilli() - 1).
+					Limit(klinesPageLimit).
+					Do(ctx)
+			}
+			return err
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to fetch %s price klines: %w", kind, err)
+		}
+		if len(res) == 0 {
+			break
+		}
+
+		candles := make([]*domain.Candle, 0, len(res))
+		for _, k := range res {
+			open, _ := strconv.ParseFloat(k.Open, 64)
+			high, _ := strconv.ParseFloat(k.High, 64)
+			low, _ := strconv.ParseFloat(k.Low, 64)
+			close, _ := strconv.ParseFloat(k.Close, 64)
+			candles = append(candles, &domain.Candle{
+				Symbol:    symbol,
+				OpenTime:  domain.TimeFromMillis(k.OpenTime),
+				CloseTime: domain.TimeFromMillis(k.CloseTime),
+				Open:      open,
+				High:      high,
+				Low:       low,
+				Close:     close,
+			})
+		}
+		if err := c.repo.SavePriceKlines(ctx, kind, interval, candles); err != nil {
+			return total, err
+		}
+		total += len(candles)
+
+		current = domain.TimeFromMillis(res[len(res)-1].CloseTime).Add(time.Millisecond)
+		if len(res) < klinesPageLimit {
+			break
+		}
+	}
+
+	log.Printf("✅ Collected %d %s %s price klines (%s)", total, symbol, kind, interval)
+	return total, nil
+}
+
+// CollectOpenInterest fetches and stores open interest history in [start, end).
+// Binance only serves the most recent 30 days; older parts of the range return nothing.
+func (c *Collector) CollectOpenInterest(ctx context.Context, symbol, period string, start, end time.Time) (int, error) {
+	step, err := history.ParseInterval(period)
+	if err != nil || !isOpenInterestPeriod(period) {
+		return 0, fmt.Errorf("invalid open interest period: %q", period)
+	}
+
+	if earliest := time.Now().UTC().AddDate(0, 0, -30); start.Before(earliest) {
+		log.Printf("⚠️  %s open interest history starts %s; skipping earlier data", symbol, earliest.Format("2006-01-02"))
+		start = history.AlignTime(earliest, step).Add(step)
+	}
+
+	total := 0
+	current := start
+	for current.Before(end) {
+		windowEnd := current.Add(step * openInterestPageLimit)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+
+		var res []*futures.OpenInterestStatistic
+		err := c.withRetry(ctx, openInterestWeight, func() error {
+			var err error
+			res, err = c.client.NewOpenInterestStatisticsService().
+				Symbol(symbol).
+				Period(period).
+				StartTime(current.UnixMilli()).
+				EndTime(windowEnd.UnixMilli() - 1).
+				Limit(openInterestPageLimit).
+				Do(ctx)
+			return err
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to fetch open interest: %w", err)
+		}
+
+		samples := make([]*domain.OpenInterest, 0, len(res))
+		for _, s := range res {
+			amount, _ := strconv.ParseFloat(s.SumOpenInterest, 64)
+			value, _ := strconv.ParseFloat(s.SumOpenInterestValue, 64)
+			samples = append(samples, &domain.OpenInterest{
+				Symbol: symbol,
+				Period: period,
+				Time:   domain.TimeFromMillis(s.Timestamp),
+				Amount: amount,
+				Value:  value,
+			})
+		}
+		if err := c.repo.SaveOpenInterest(ctx, samples); err != nil {
+			return total, err
+		}
+		total += len(samples)
+
+		current = windowEnd
+	}
+
+	log.Printf("✅ Collected %d %s open interest samples (%s)", total, symbol, period)
+	return total, nil
+}
+
+// withRetry waits for request weight and runs fn, retrying with exponential backoff
+func (c *Collector) withRetry(ctx context.Context, weight int, fn func() error) error {
+	const maxRetries = 3
+
+	var lastErr error
+	for retry := 0; retry <= maxRetries; retry++ {
+		if err := c.limiter.Wait(ctx, weight); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if retry < maxRetries {
+			backoffDelay := time.Duration(1<<uint(retry)) * time.Second
+			log.Printf("API error (attempt %d/%d): %v. Retrying after %v...", retry+1, maxRetries, err, backoffDelay)
+			select {
+			case <-time.After(backoffDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return fmt.Errorf("giving up after %d retries: %w", maxRetries, lastErr)
+}
+
+// isOpenInterestPeriod reports whether Binance keeps open interest history for period
+func isOpenInterestPeriod(period string) bool {
+	for _, p := range OpenInterestPeriods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package perp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+)
+
+var fundingStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return NewRepository(db)
+}
+
+// fundingServer serves count funding events every 8 hours from fundingStart,
+// honoring startTime, endTime and limit like /fapi/v1/fundingRate. The event
+// at index bad has an unparseable rate.
+func fundingServer(t *testing.T, count, bad int) (*httptest.Server, *[]int64) {
+	t.Helper()
+	var starts []int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/fundingRate" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		start, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+		end, _ := strconv.ParseInt(q.Get("endTime"), 10, 64)
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		starts = append(starts, start)
+
+		res := make([]*futures.FundingRate, 0)
+		for i := 0; i < count && len(res) < limit; i++ {
+			at := fundingStart.Add(time.Duration(i) * 8 * time.Hour).UnixMilli()
+			if at < start || at > end {
+				continue
+			}
+			rate := strconv.FormatFloat(0.0001*float64(i%3-1), 'f', -1, 64)
+			if i == bad {
+				rate = "n/a"
+			}
+			res = append(res, &futures.FundingRate{Symbol: "BTCUSDT", FundingRate: rate, FundingTime: at, Time: at})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &starts
+}
+
+func TestCollectFundingRates(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	srv, starts := fundingServer(t, 1200, 5)
+
+	client := futures.NewClient("", "")
+	client.BaseURL = srv.URL
+	c := NewCollector(client, repo)
+
+	// 1200 events span two pages; the last event is outside the range
+	end := fundingStart.Add(1199 * 8 * time.Hour)
+	total, err := c.CollectFundingRates(ctx, "BTCUSDT", fundingStart, end)
+	if err != nil {
+		t.Fatalf("CollectFundingRates: %v", err)
+	}
+	if total != 1198 {
+		t.Errorf("collected %d funding rates, want 1198", total)
+	}
+	if len(*starts) != 2 {
+		t.Fatalf("made %d requests, want 2", len(*starts))
+	}
+	if want := fundingStart.Add(999*8*time.Hour).UnixMilli() + 1; (*starts)[1] != want {
+		t.Errorf("second page starts at %d, want %d", (*starts)[1], want)
+	}
+
+	rates, err := repo.GetFundingRates(ctx, "BTCUSDT", fundingStart, end.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 1198 {
+		t.Fatalf("stored %d funding rates, want 1198", len(rates))
+	}
+	if !rates[0].Time.Equal(fundingStart) || rates[0].Rate != -0.0001 || rates[1].Rate != 0 {
+		t.Errorf("first rates = %+v, %+v", rates[0], rates[1])
+	}
+	// The event with a bad rate is skipped
+	if got, want := rates[5].Time, fundingStart.Add(6*8*time.Hour); !got.Equal(want) {
+		t.Errorf("rate 5 at %s, want %s", got, want)
+	}
+
+	// Collecting the range again replaces the same events
+	if _, err := c.CollectFundingRates(ctx, "BTCUSDT", fundingStart, end); err != nil {
+		t.Fatalf("CollectFundingRates: %v", err)
+	}
+	if again, _ := repo.GetFundingRates(ctx, "BTCUSDT", fundingStart, end.Add(time.Hour)); len(again) != 1198 {
+		t.Errorf("stored %d funding rates after recollecting, want 1198", len(again))
+	}
+}
@@ -0,0 +1,228 @@
+package perp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Price kline kinds
+const (
+	KindMark  = "mark"
+	KindIndex = "index"
+)
+
+// Repository stores perpetual futures funding, open interest and mark/index klines
+type Repository struct {
+	db *database.DB
+}
+
+// NewRepository creates a new perp repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// SaveFundingRates stores funding events in a transaction; existing events are replaced
+func (r *Repository) SaveFundingRates(ctx context.Context, rates []*domain.FundingRate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO funding_rates (symbol, funding_time, rate, mark_price)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, f := range rates {
+		if _, err := stmt.ExecContext(ctx, f.Symbol, f.Time.UnixMilli(), f.Rate, f.MarkPrice); err != nil {
+			return fmt.Errorf("failed to insert funding rate: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetFundingRates retrieves funding events with start <= time < end in time order
+func (r *Repository) GetFundingRates(ctx context.Context, symbol string, start, end time.Time) ([]*domain.FundingRate, error) {
+	query := sq.Select("symbol", "funding_time", "rate", "mark_price").
+		From("funding_rates").
+		Where(sq.Eq{"symbol": symbol}).
+		Where(sq.GtOrEq{"funding_time": start.UnixMilli()}).
+		Where(sq.Lt{"funding_time": end.UnixMilli()}).
+		OrderBy("funding_time ASC")
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funding rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := make([]*domain.FundingRate, 0)
+	for rows.Next() {
+		var f domain.FundingRate
+		var ts int64
+		if err := rows.Scan(&f.Symbol, &ts, &f.Rate, &f.MarkPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan funding rate: %w", err)
+		}
+		f.Time = domain.TimeFromMillis(ts)
+		rates = append(rates, &f)
+	}
+
+	return rates, rows.Err()
+}
+
+// SaveOpenInterest stores open interest samples in a transaction; existing samples are replaced
+func (r *Repository) SaveOpenInterest(ctx context.Context, samples []*domain.OpenInterest) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO open_interest (symbol, period, time, amount, value)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, oi := range samples {
+		if _, err := stmt.ExecContext(ctx, oi.Symbol, oi.Period, oi.Time.UnixMilli(), oi.Amount, oi.Value); err != nil {
+			return fmt.Errorf("failed to insert open interest: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetOpenInterest retrieves open interest samples with start <= time < end in time order
+func (r *Repository) GetOpenInterest(ctx context.Context, symbol, period string, start, end time.Time) ([]*domain.OpenInterest, error) {
+	query := sq.Select("symbol", "period", "time", "amount", "value").
+		From("open_interest").
+		Where(sq.Eq{"symbol": symbol, "period": period}).
+		Where(sq.GtOrEq{"time": start.UnixMilli()}).
+		Where(sq.Lt{"time": end.UnixMilli()}).
+		OrderBy("time ASC")
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open interest: %w", err)
+	}
+	defer rows.Close()
+
+	samples := make([]*domain.OpenInterest, 0)
+	for rows.Next() {
+		var oi domain.OpenInterest
+		var ts int64
+		if err := rows.Scan(&oi.Symbol, &oi.Period, &ts, &oi.Amount, &oi.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan open interest: %w", err)
+		}
+		oi.Time = domain.TimeFromMillis(ts)
+		samples = append(samples, &oi)
+	}
+
+	return samples, rows.Err()
+}
+
+// SavePriceKlines stores mark or index price klines in a transaction; existing bars are replaced
+func (r *Repository) SavePriceKlines(ctx context.Context, kind, interval string, candles []*domain.Candle) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO perp_price_klines (kind, symbol, interval, open_time, close_time, open, high, low, close)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, c := range candles {
+		_, err := stmt.ExecContext(ctx,
+			kind,
+			c.Symbol,
+			interval,
+			c.OpenTime.UnixMilli(),
+			c.CloseTime.UnixMilli(),
+			c.Open,
+			c.High,
+			c.Low,
+			c.Close,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert %s price kline: %w", kind, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetPriceKlines retrieves mark or index price klines with start <= open_time <= end
+func (r *Repository) GetPriceKlines(ctx context.Context, kind, symbol, interval string, start, end time.Time) ([]*domain.Candle, error) {
+	query := sq.Select("symbol", "open_time", "close_time", "open", "high", "low", "close").
+		From("perp_price_klines").
+		Where(sq.Eq{"kind": kind, "symbol": symbol, "interval": interval}).
+		Where(sq.GtOrEq{"open_time": start.UnixMilli()}).
+		Where(sq.LtOrEq{"open_time": end.UnixMilli()}).
+		OrderBy("open_time ASC")
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s price klines: %w", kind, err)
+	}
+	defer rows.Close()
+
+	candles := make([]*domain.Candle, 0)
+	for rows.Next() {
+		var c domain.Candle
+		var openTime, closeTime int64
+		if err := rows.Scan(&c.Symbol, &openTime, &closeTime, &c.Open, &c.High, &c.Low, &c.Close); err != nil {
+			return nil, fmt.Errorf("failed to scan %s price kline: %w", kind, err)
+		}
+		c.OpenTime = domain.TimeFromMillis(openTime)
+		c.CloseTime = domain.TimeFromMillis(closeTime)
+		candles = append(candles, &c)
+	}
+
+	return candles, rows.Err()
+}
@@ -0,0 +1,121 @@
+package perp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// Service handles perpetual futures funding, open interest and mark/index prices
+type Service struct {
+	repo      *Repository
+	collector *Collector
+}
+
+// NewService creates a new perp data service
+func NewService(db *database.DB, client *futures.Client) *Service {
+	repo := NewRepository(db)
+	return &Service{
+		repo:      repo,
+		collector: NewCollector(client, repo),
+	}
+}
+
+// CollectResult summarizes a CollectAll run
+type CollectResult struct {
+	Symbol       string `json:"symbol"`
+	FundingRates int    `json:"funding_rates"`
+	MarkKlines   int    `json:"mark_klines"`
+	IndexKlines  int    `json:"index_klines"`
+	OpenInterest int    `json:"open_interest"`
+}
+
+// CollectAll collects funding rates, mark and index klines and open interest for a range.
+// interval is used for the klines and as the open interest period.
+func (s *Service) CollectAll(ctx context.Context, symbol, interval string, start, end time.Time) (*CollectResult, error) {
+	result := &CollectResult{Symbol: symbol}
+	var err error
+
+	if result.FundingRates, err = s.collector.CollectFundingRates(ctx, symbol, start, end); err != nil {
+		return result, fmt.Errorf("failed to collect funding rates: %w", err)
+	}
+	if result.MarkKlines, err = s.collector.CollectPriceKlines(ctx, KindMark, symbol, interval, start, end); err != nil {
+		return result, fmt.Errorf("failed to collect mark price klines: %w", err)
+	}
+	if result.IndexKlines, err = s.collector.CollectPriceKlines(ctx, KindIndex, symbol, interval, start, end); err != nil {
+		return result, fmt.Errorf("failed to collect index price klines: %w", err)
+	}
+	if isOpenInterestPeriod(interval) {
+		if result.OpenInterest, err = s.collector.CollectOpenInterest(ctx, symbol, interval, start, end); err != nil {
+			return result, fmt.Errorf("failed to collect open interest: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// CollectFundingRates collects funding events for a range
+func (s *Service) CollectFundingRates(ctx context.Context, symbol string, start, end time.Time) (int, error) {
+	count, err := s.collector.CollectFundingRates(ctx, symbol, start, end)
+	if err != nil {
+		return count, fmt.Errorf("failed to collect funding rates: %w", err)
+	}
+	return count, nil
+}
+
+// GetFundingRates retrieves stored funding events within a time range
+func (s *Service) GetFundingRates(ctx context.Context, symbol string, start, end time.Time) ([]*domain.FundingRate, error) {
+	rates, err := s.repo.GetFundingRates(ctx, symbol, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get funding rates: %w", err)
+	}
+	return rates, nil
+}
+
+// GetPriceKlines retrieves stored mark or index price klines within a time range
+func (s *Service) GetPriceKlines(ctx context.Context, kind, symbol, interval string, start, end time.Time) ([]*domain.Candle, error) {
+	candles, err := s.repo.GetPriceKlines(ctx, kind, symbol, interval, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s price klines: %w", kind, err)
+	}
+	return candles, nil
+}
+
+// GetOpenInterest retrieves stored open interest samples within a time range
+func (s *Service) GetOpenInterest(ctx context.Context, symbol, period string, start, end time.Time) ([]*domain.OpenInterest, error) {
+	samples, err := s.repo.GetOpenInterest(ctx, symbol, period, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open interest: %w", err)
+	}
+	return samples, nil
+}
+
+// GetFundingWithMarkPrices retrieves funding events with the mark price at each
+// funding time filled in from stored mark klines of interval where available
+func (s *Service) GetFundingWithMarkPrices(ctx context.Context, symbol, interval string, start, end time.Time) ([]*domain.FundingRate, error) {
+	rates, err := s.GetFundingRates(ctx, symbol, start, end)
+	if err != nil || len(rates) == 0 {
+		return rates, err
+	}
+
+	marks, err := s.GetPriceKlines(ctx, KindMark, symbol, interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both are in time order: the mark at funding time is the open of the bar starting there
+	j := 0
+	for _, f := range rates {
+		for j < len(marks) && marks[j].CloseTime.Before(f.Time) {
+			j++
+		}
+		if j < len(marks) && !marks[j].OpenTime.After(f.Time) {
+			f.MarkPrice = marks[j].Open
+		}
+	}
+	return rates, nil
+}
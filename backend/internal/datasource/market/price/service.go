@@ -42,3 +42,8 @@ func (s *Service) GetMultiplePrices(ctx context.Context, symbols []string) (map[
 
 	return prices, nil
 }
+
+// GetStreamStatus returns the state of the real-time market data streams
+func (s *Service) GetStreamStatus() *exchange.StreamStatus {
+	return s.binance.StreamStatus()
+}
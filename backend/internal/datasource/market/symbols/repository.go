@@ -0,0 +1,156 @@
+package symbols
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// symbolColumns are selected in the order scanSymbol expects
+var symbolColumns = []string{"symbol", "base_asset", "quote_asset", "status", "tick_size", "min_price", "max_price",
+	"step_size", "min_qty", "max_qty", "min_notional", "permissions", "updated_at"}
+
+// Repository stores symbol metadata and trading rules in SQLite
+type Repository struct {
+	db *database.DB
+}
+
+// NewRepository creates a new symbol info repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// SaveAll replaces the stored symbols with infos in a transaction
+func (r *Repository) SaveAll(ctx context.Context, infos []*domain.SymbolInfo) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Delisted symbols disappear from exchangeInfo
+	if _, err := tx.ExecContext(ctx, `DELETE FROM symbol_info`); err != nil {
+		return fmt.Errorf("failed to clear symbol info: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO symbol_info (symbol, base_asset, quote_asset, status, tick_size, min_price, max_price,
+			step_size, min_qty, max_qty, min_notional, permissions, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, s := range infos {
+		_, err := stmt.ExecContext(ctx,
+			s.Symbol,
+			s.BaseAsset,
+			s.QuoteAsset,
+			s.Status,
+			s.TickSize,
+			s.MinPrice,
+			s.MaxPrice,
+			s.StepSize,
+			s.MinQty,
+			s.MaxQty,
+			s.MinNotional,
+			strings.Join(s.Permissions, ","),
+			s.UpdatedAt.UnixMilli(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert symbol info: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves one symbol (nil if unknown)
+func (r *Repository) Get(ctx context.Context, symbol string) (*domain.SymbolInfo, error) {
+	sqlQuery, args, err := sq.Select(symbolColumns...).
+		From("symbol_info").
+		Where(sq.Eq{"symbol": symbol}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	info, err := scanSymbol(r.db.QueryRowContext(ctx, sqlQuery, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return info, err
+}
+
+// List retrieves symbols ordered by name. Empty quote matches every quote asset.
+func (r *Repository) List(ctx context.Context, quote string, tradableOnly bool) ([]*domain.SymbolInfo, error) {
+	query := sq.Select(symbolColumns...).
+		From("symbol_info").
+		OrderBy("symbol")
+	if quote != "" {
+		query = query.Where(sq.Eq{"quote_asset": quote})
+	}
+	if tradableOnly {
+		query = query.Where(sq.Eq{"status": domain.SymbolStatusTrading})
+	}
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query symbol info: %w", err)
+	}
+	defer rows.Close()
+
+	infos := make([]*domain.SymbolInfo, 0)
+	for rows.Next() {
+		info, err := scanSymbol(rows)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, rows.Err()
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSymbol scans a row selected with symbolColumns
+func scanSymbol(row rowScanner) (*domain.SymbolInfo, error) {
+	var s domain.SymbolInfo
+	var permissions string
+	var updatedAt int64
+
+	err := row.Scan(&s.Symbol, &s.BaseAsset, &s.QuoteAsset, &s.Status, &s.TickSize, &s.MinPrice, &s.MaxPrice,
+		&s.StepSize, &s.MinQty, &s.MaxQty, &s.MinNotional, &permissions, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan symbol info: %w", err)
+	}
+
+	s.Permissions = make([]string, 0)
+	if permissions != "" {
+		s.Permissions = strings.Split(permissions, ",")
+	}
+	s.UpdatedAt = domain.TimeFromMillis(updatedAt)
+	return &s, nil
+}
@@ -0,0 +1,248 @@
+package symbols
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// DefaultRefreshInterval is how often Start reloads exchangeInfo
+const DefaultRefreshInterval = time.Hour
+
+// knownQuotes are quote assets tried longest first when no symbol info is available
+var knownQuotes = []string{"FDUSD", "USDT", "USDC", "TUSD", "BUSD", "BTC", "ETH", "BNB", "EUR", "TRY", "KRW"}
+
+// Service caches Binance spot symbol metadata and trading rules
+type Service struct {
+	client  *binance.Client
+	repo    *Repository
+	limiter *history.RateLimiter
+
+	mu      sync.RWMutex
+	symbols map[string]*domain.SymbolInfo
+	loaded  time.Time
+}
+
+// NewService creates a new symbol info service.
+// Symbols are served from memory, then SQLite, then a fresh exchangeInfo load.
+func NewService(db *database.DB, client *binance.Client) *Service {
+	return &Service{
+		client:  client,
+		repo:    NewRepository(db),
+		limiter: history.ClientRateLimiter(client),
+		symbols: make(map[string]*domain.SymbolInfo),
+	}
+}
+
+// Refresh loads exchangeInfo and replaces the stored symbols
+func (s *Service) Refresh(ctx context.Context) (int, error) {
+	if err := s.limiter.Wait(ctx, history.ExchangeInfoWeight); err != nil {
+		return 0, err
+	}
+
+	res, err := s.client.NewExchangeInfoService().Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load exchange info: %w", err)
+	}
+
+	now := time.Now().UTC()
+	infos := make([]*domain.SymbolInfo, 0, len(res.Symbols))
+	for i := range res.Symbols {
+		infos = append(infos, symbolFromExchangeInfo(&res.Symbols[i], now))
+	}
+
+	if err := s.repo.SaveAll(ctx, infos); err != nil {
+		return 0, err
+	}
+	s.setCache(infos, now)
+
+	log.Printf("✅ Loaded trading rules for %d symbols", len(infos))
+	return len(infos), nil
+}
+
+// Start refreshes exchangeInfo immediately and then every interval until ctx is done.
+// A failed refresh keeps serving the last stored rules.
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️  Symbol info refresh failed: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Get returns a symbol's info, loading exchangeInfo if it has never been stored
+func (s *Service) Get(ctx context.Context, symbol string) (*domain.SymbolInfo, error) {
+	if info := s.cached(symbol); info != nil {
+		return info, nil
+	}
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	if info := s.cached(symbol); info != nil {
+		return info, nil
+	}
+	return nil, fmt.Errorf("unknown symbol: %s", symbol)
+}
+
+// List returns stored symbols, optionally only tradable ones with a given quote asset
+func (s *Service) List(ctx context.Context, quote string, tradableOnly bool) ([]*domain.SymbolInfo, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	infos, err := s.repo.List(ctx, quote, tradableOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list symbols: %w", err)
+	}
+	return infos, nil
+}
+
+// PrepareOrder rounds an order's price and quantity to the symbol's rules and
+// validates it. refPrice is the expected fill price for market orders.
+func (s *Service) PrepareOrder(ctx context.Context, order *domain.Order, refPrice float64) error {
+	info, err := s.Get(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
+	return ApplyRules(info, order, refPrice)
+}
+
+// BaseAsset returns a symbol's base asset from stored info, falling back to
+// stripping a known quote asset suffix
+func (s *Service) BaseAsset(ctx context.Context, symbol string) string {
+	if info, err := s.Get(ctx, symbol); err == nil {
+		return info.BaseAsset
+	}
+	return SplitSymbol(symbol)
+}
+
+// ApplyRules rounds an order's price and quantity to info's rules and validates it
+func ApplyRules(info *domain.SymbolInfo, order *domain.Order, refPrice float64) error {
+	if !info.IsTradable() {
+		return fmt.Errorf("%s is not trading (status %s)", info.Symbol, info.Status)
+	}
+	order.Quantity = info.RoundQuantity(order.Quantity)
+	price := refPrice
+	if order.Type == domain.OrderTypeLimit {
+		order.Price = info.RoundPrice(order.Price)
+		price = order.Price
+	}
+	return info.Validate(price, order.Quantity)
+}
+
+// SplitSymbol returns the base asset of a symbol by stripping a known quote asset
+// (the symbol itself if none matches)
+func SplitSymbol(symbol string) string {
+	for _, quote := range knownQuotes {
+		if len(symbol) > len(quote) && strings.HasSuffix(symbol, quote) {
+			return strings.TrimSuffix(symbol, quote)
+		}
+	}
+	return symbol
+}
+
+// load fills the memory cache from SQLite, or from exchangeInfo if nothing is stored
+func (s *Service) load(ctx context.Context) error {
+	s.mu.RLock()
+	loaded := !s.loaded.IsZero()
+	s.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	infos, err := s.repo.List(ctx, "", false)
+	if err != nil {
+		return fmt.Errorf("failed to load symbol info: %w", err)
+	}
+	if len(infos) == 0 {
+		_, err := s.Refresh(ctx)
+		return err
+	}
+
+	s.setCache(infos, infos[0].UpdatedAt)
+	return nil
+}
+
+// cached returns a symbol from the memory cache
+func (s *Service) cached(symbol string) *domain.SymbolInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.symbols[symbol]
+}
+
+// setCache replaces the memory cache
+func (s *Service) setCache(infos []*domain.SymbolInfo, loaded time.Time) {
+	symbols := make(map[string]*domain.SymbolInfo, len(infos))
+	for _, info := range infos {
+		symbols[info.Symbol] = info
+	}
+
+	s.mu.Lock()
+	s.symbols = symbols
+	s.loaded = loaded
+	s.mu.Unlock()
+}
+
+// symbolFromExchangeInfo converts an exchangeInfo symbol and its filters
+func symbolFromExchangeInfo(sym *binance.Symbol, now time.Time) *domain.SymbolInfo {
+	info := &domain.SymbolInfo{
+		Symbol:      sym.Symbol,
+		BaseAsset:   sym.BaseAsset,
+		QuoteAsset:  sym.QuoteAsset,
+		Status:      sym.Status,
+		Permissions: sym.Permissions,
+		UpdatedAt:   now,
+	}
+	if info.Permissions == nil {
+		info.Permissions = make([]string, 0)
+	}
+
+	if f := sym.PriceFilter(); f != nil {
+		info.TickSize = parseFloat(f.TickSize)
+		info.MinPrice = parseFloat(f.MinPrice)
+		info.MaxPrice = parseFloat(f.MaxPrice)
+	}
+	if f := sym.LotSizeFilter(); f != nil {
+		info.StepSize = parseFloat(f.StepSize)
+		info.MinQty = parseFloat(f.MinQuantity)
+		info.MaxQty = parseFloat(f.MaxQuantity)
+	}
+	// Binance replaced MIN_NOTIONAL with NOTIONAL on most spot symbols
+	if f := sym.NotionalFilter(); f != nil {
+		info.MinNotional = parseFloat(f.MinNotional)
+	} else if f := sym.MinNotionalFilter(); f != nil {
+		info.MinNotional = parseFloat(f.MinNotional)
+	}
+	return info
+}
+
+// parseFloat parses an exchange number string (0 if invalid)
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
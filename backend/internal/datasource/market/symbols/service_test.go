@@ -0,0 +1,280 @@
+package symbols
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/lavumi/crypto-quant/internal/datasource/database"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// exchangeInfoServer serves /api/v3/exchangeInfo with a replaceable symbol list
+type exchangeInfoServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	symbols  []binance.Symbol
+	requests int
+}
+
+func newExchangeInfoServer(t *testing.T, symbols ...binance.Symbol) *exchangeInfoServer {
+	t.Helper()
+	s := &exchangeInfoServer{symbols: symbols}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/exchangeInfo" {
+			http.NotFound(w, r)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(binance.ExchangeInfo{Timezone: "UTC", Symbols: s.symbols})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *exchangeInfoServer) setSymbols(symbols ...binance.Symbol) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.symbols = symbols
+}
+
+func (s *exchangeInfoServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// exchangeSymbol returns a spot symbol with PRICE_FILTER, LOT_SIZE and the given notional filter
+func exchangeSymbol(symbol, base, quote, status, notionalFilter string) binance.Symbol {
+	return binance.Symbol{
+		Symbol:      symbol,
+		Status:      status,
+		BaseAsset:   base,
+		QuoteAsset:  quote,
+		Permissions: []string{"SPOT", "MARGIN"},
+		Filters: []map[string]interface{}{
+			{"filterType": string(binance.SymbolFilterTypePriceFilter), "minPrice": "0.01000000", "maxPrice": "1000000.00000000", "tickSize": "0.01000000"},
+			{"filterType": string(binance.SymbolFilterTypeLotSize), "minQty": "0.00010000", "maxQty": "9000.00000000", "stepSize": "0.00010000"},
+			{"filterType": notionalFilter, "minNotional": "5.00000000"},
+		},
+	}
+}
+
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func newTestService(t *testing.T, db *database.DB, srv *exchangeInfoServer) *Service {
+	t.Helper()
+	client := binance.NewClient("", "")
+	client.BaseURL = srv.URL
+	return NewService(db, client)
+}
+
+func TestServiceLoadsExchangeInfo(t *testing.T) {
+	ctx := context.Background()
+	srv := newExchangeInfoServer(t,
+		exchangeSymbol("BTCUSDT", "BTC", "USDT", domain.SymbolStatusTrading, string(binance.SymbolFilterTypeNotional)),
+		exchangeSymbol("ETHBTC", "ETH", "BTC", domain.SymbolStatusTrading, string(binance.SymbolFilterTypeMinNotional)),
+		exchangeSymbol("LUNAUSDT", "LUNA", "USDT", "BREAK", string(binance.SymbolFilterTypeNotional)),
+	)
+	svc := newTestService(t, newTestDB(t), srv)
+
+	// The first lookup loads exchangeInfo; later lookups are served from memory
+	info, err := svc.Get(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if info.BaseAsset != "BTC" || info.QuoteAsset != "USDT" || info.TickSize != 0.01 || info.MinPrice != 0.01 ||
+		info.MaxPrice != 1000000 || info.StepSize != 0.0001 || info.MinQty != 0.0001 || info.MaxQty != 9000 ||
+		info.MinNotional != 5 || strings.Join(info.Permissions, ",") != "SPOT,MARGIN" {
+		t.Errorf("BTCUSDT = %+v", info)
+	}
+	// MIN_NOTIONAL is used when a symbol has no NOTIONAL filter
+	if info, err := svc.Get(ctx, "ETHBTC"); err != nil || info.MinNotional != 5 {
+		t.Errorf("Get(ETHBTC) = %+v, %v, want min notional 5", info, err)
+	}
+	if _, err := svc.Get(ctx, "DOGEUSDT"); err == nil {
+		t.Error("Get(DOGEUSDT) succeeded, want unknown symbol")
+	}
+	if n := srv.requestCount(); n != 1 {
+		t.Errorf("exchangeInfo requested %d times, want 1", n)
+	}
+
+	tests := []struct {
+		quote        string
+		tradableOnly bool
+		want         string
+	}{
+		{"", false, "BTCUSDT,ETHBTC,LUNAUSDT"},
+		{"USDT", false, "BTCUSDT,LUNAUSDT"},
+		{"USDT", true, "BTCUSDT"},
+		{"EUR", false, ""},
+	}
+	for _, tt := range tests {
+		infos, err := svc.List(ctx, tt.quote, tt.tradableOnly)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Symbol)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("List(%q, %v) = %s, want %s", tt.quote, tt.tradableOnly, got, tt.want)
+		}
+	}
+}
+
+func TestServiceRefresh(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	srv := newExchangeInfoServer(t,
+		exchangeSymbol("BTCUSDT", "BTC", "USDT", domain.SymbolStatusTrading, string(binance.SymbolFilterTypeNotional)),
+		exchangeSymbol("ETHUSDT", "ETH", "USDT", domain.SymbolStatusTrading, string(binance.SymbolFilterTypeNotional)),
+	)
+	svc := newTestService(t, db, srv)
+	if n, err := svc.Refresh(ctx); err != nil || n != 2 {
+		t.Fatalf("Refresh = %d, %v, want 2", n, err)
+	}
+
+	// ETHUSDT is delisted and BTCUSDT halted: a refresh drops and updates them
+	halted := exchangeSymbol("BTCUSDT", "BTC", "USDT", "HALT", string(binance.SymbolFilterTypeNotional))
+	srv.setSymbols(halted)
+	if n, err := svc.Refresh(ctx); err != nil || n != 1 {
+		t.Fatalf("Refresh = %d, %v, want 1", n, err)
+	}
+	if _, err := svc.Get(ctx, "ETHUSDT"); err == nil {
+		t.Error("Get(ETHUSDT) succeeded after delisting, want unknown symbol")
+	}
+	info, err := svc.Get(ctx, "BTCUSDT")
+	if err != nil || info.IsTradable() {
+		t.Errorf("Get(BTCUSDT) = %+v, %v, want halted", info, err)
+	}
+	order := &domain.Order{Symbol: "BTCUSDT", Type: domain.OrderTypeMarket, Quantity: 1}
+	if err := svc.PrepareOrder(ctx, order, 100); err == nil || !strings.Contains(err.Error(), "not trading") {
+		t.Errorf("PrepareOrder = %v, want not trading", err)
+	}
+
+	// A restarted service serves the stored rules without loading exchangeInfo
+	requests := srv.requestCount()
+	restarted := newTestService(t, db, srv)
+	if info, err := restarted.Get(ctx, "BTCUSDT"); err != nil || info.Status != "HALT" {
+		t.Errorf("Get after restart = %+v, %v, want stored HALT", info, err)
+	}
+	if n := srv.requestCount(); n != requests {
+		t.Errorf("exchangeInfo requested %d times after restart, want %d", n, requests)
+	}
+}
+
+func TestServiceStart(t *testing.T) {
+	srv := newExchangeInfoServer(t,
+		exchangeSymbol("BTCUSDT", "BTC", "USDT", domain.SymbolStatusTrading, string(binance.SymbolFilterTypeNotional)),
+	)
+	svc := newTestService(t, newTestDB(t), srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	svc.Start(ctx, 20*time.Millisecond)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.requestCount() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("exchangeInfo requested %d times, want periodic refreshes", srv.requestCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if svc.cached("BTCUSDT") == nil {
+		t.Error("BTCUSDT not cached after Start")
+	}
+
+	// A listing appears on the next refresh
+	srv.setSymbols(
+		exchangeSymbol("BTCUSDT", "BTC", "USDT", domain.SymbolStatusTrading, string(binance.SymbolFilterTypeNotional)),
+		exchangeSymbol("SOLUSDT", "SOL", "USDT", domain.SymbolStatusTrading, string(binance.SymbolFilterTypeNotional)),
+	)
+	for svc.cached("SOLUSDT") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("SOLUSDT not cached after a refresh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Refreshes stop with the context
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	stopped := srv.requestCount()
+	time.Sleep(100 * time.Millisecond)
+	if n := srv.requestCount(); n != stopped {
+		t.Errorf("exchangeInfo requested %d times after cancel, want %d", n, stopped)
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	info := &domain.SymbolInfo{
+		Symbol:      "BTCUSDT",
+		Status:      domain.SymbolStatusTrading,
+		TickSize:    0.01,
+		StepSize:    0.0001,
+		MinQty:      0.0001,
+		MinNotional: 5,
+	}
+	tests := []struct {
+		name         string
+		order        domain.Order
+		refPrice     float64
+		wantPrice    float64
+		wantQuantity float64
+		wantErr      bool
+	}{
+		{"limit rounds price and quantity", domain.Order{Type: domain.OrderTypeLimit, Price: 100.005, Quantity: 0.12345}, 0, 100.01, 0.1234, false},
+		{"market keeps zero price", domain.Order{Type: domain.OrderTypeMarket, Quantity: 0.12345}, 100, 0, 0.1234, false},
+		{"market checks notional at the reference price", domain.Order{Type: domain.OrderTypeMarket, Quantity: 0.01}, 100, 0, 0.01, true},
+		{"limit rounding to zero", domain.Order{Type: domain.OrderTypeLimit, Price: 100, Quantity: 0.00009}, 0, 100, 0, true},
+	}
+	for _, tt := range tests {
+		order := tt.order
+		err := ApplyRules(info, &order, tt.refPrice)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ApplyRules = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if order.Price != tt.wantPrice || order.Quantity != tt.wantQuantity {
+			t.Errorf("%s: order = %v @ %v, want %v @ %v", tt.name, order.Quantity, order.Price, tt.wantQuantity, tt.wantPrice)
+		}
+	}
+}
+
+func TestSplitSymbol(t *testing.T) {
+	tests := map[string]string{
+		"BTCUSDT":  "BTC",
+		"ETHBTC":   "ETH",
+		"BTCFDUSD": "BTC", // Not BTCFD with quote USD
+		"USDCUSDT": "USDC",
+		"USDT":     "USDT", // Nothing left without the quote
+		"XYZ":      "XYZ",
+	}
+	for symbol, want := range tests {
+		if got := SplitSymbol(symbol); got != want {
+			t.Errorf("SplitSymbol(%s) = %s, want %s", symbol, got, want)
+		}
+	}
+}
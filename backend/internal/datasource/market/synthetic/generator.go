@@ -0,0 +1,131 @@
+package synthetic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// SymbolPrefix marks synthetic symbols, keeping their candles apart from real market data
+const SymbolPrefix = "SYNTH"
+
+// DefaultSymbol is the synthetic symbol used when none is configured
+const DefaultSymbol = SymbolPrefix + "USDT"
+
+// Generator defaults
+const (
+	defaultStartPrice = 100.0
+	defaultSubsteps   = 16
+	defaultBaseVolume = 1000.0 // Base asset volume of an average bar
+)
+
+// volumeNoise is the log-normal dispersion of bar volumes
+const volumeNoise = 0.3
+
+// Config configures a synthetic candle path
+type Config struct {
+	Symbol     string    // Default DefaultSymbol
+	Interval   string    // Candle interval, e.g. 1m or 1h
+	Start      time.Time // Open time of the first bar (aligned to the interval)
+	Bars       int
+	StartPrice float64 // Default 100
+	Seed       int64   // The same seed and config always generate the same candles
+
+	Model  ModelConfig
+	Shocks []Shock
+
+	Substeps   int     // Model steps per bar; their path forms the bar's high and low (default 16)
+	BaseVolume float64 // Volume of an average bar (default 1000)
+}
+
+// Generate produces OHLCV candles from the configured model and shocks. Each
+// bar opens at the previous close and is built from Substeps model steps, so
+// highs and lows come from the path; volume rises with the size of moves
+// relative to the model's volatility, and shocks scale it.
+func Generate(cfg Config) ([]*domain.Candle, error) {
+	interval, err := history.ParseInterval(cfg.Interval)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Bars <= 0 {
+		return nil, fmt.Errorf("bars must be positive")
+	}
+	if cfg.StartPrice < 0 {
+		return nil, fmt.Errorf("start price must be positive")
+	}
+	if cfg.Symbol == "" {
+		cfg.Symbol = DefaultSymbol
+	}
+	if cfg.StartPrice == 0 {
+		cfg.StartPrice = defaultStartPrice
+	}
+	if cfg.Substeps <= 0 {
+		cfg.Substeps = defaultSubsteps
+	}
+	if cfg.BaseVolume <= 0 {
+		cfg.BaseVolume = defaultBaseVolume
+	}
+
+	modelCfg := cfg.Model.withDefaults()
+	if err := modelCfg.validate(); err != nil {
+		return nil, err
+	}
+	start := history.AlignTime(cfg.Start, interval)
+	shocks := make([]Shock, 0, len(cfg.Shocks))
+	for _, s := range cfg.Shocks {
+		s = s.withDefaults(start)
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+		shocks = append(shocks, s)
+	}
+
+	step := interval / time.Duration(cfg.Substeps)
+	dt := years(step)
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	m := newModel(modelCfg, dt)
+	stepVolume := cfg.BaseVolume / float64(cfg.Substeps)
+
+	candles := make([]*domain.Candle, cfg.Bars)
+	price := cfg.StartPrice
+	for i := range candles {
+		openTime := start.Add(time.Duration(i) * interval)
+		c := &domain.Candle{
+			Symbol:    cfg.Symbol,
+			OpenTime:  openTime,
+			CloseTime: openTime.Add(interval - time.Millisecond),
+			Open:      price,
+			High:      price,
+			Low:       price,
+		}
+
+		logPrice := math.Log(price)
+		for j := 0; j < cfg.Substeps; j++ {
+			e := applyShocks(shocks, openTime.Add(time.Duration(j)*step), step)
+			expected := m.volatility() * e.volatility * math.Sqrt(dt)
+			r := m.step(rng, dt, e.volatility) + e.logReturn
+			logPrice += r
+
+			p := math.Exp(logPrice)
+			c.High = max(c.High, p)
+			c.Low = min(c.Low, p)
+
+			// Surprise drives volume: a typical step trades the base volume
+			surprise := 1.0
+			if expected > 0 {
+				surprise = 0.5 + 0.5*math.Abs(r)/expected
+			}
+			noise := math.Exp(volumeNoise*rng.NormFloat64() - volumeNoise*volumeNoise/2)
+			c.Volume += stepVolume * surprise * noise * e.volume
+		}
+
+		price = math.Exp(logPrice)
+		c.Close = price
+		candles[i] = c
+	}
+	return candles, nil
+}
@@ -0,0 +1,258 @@
+package synthetic
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+var synthStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testModels covers every price model
+var testModels = map[string]ModelConfig{
+	ModelGBM:   {Type: ModelGBM, Drift: 0.1},
+	ModelGARCH: {Type: ModelGARCH, Drift: 0.1, Volatility: 0.8},
+	ModelJump:  {Type: ModelJump, JumpRate: 5000, JumpMean: -0.01, JumpVol: 0.02},
+	ModelRegime: {Type: ModelRegime, Regimes: []Regime{
+		{Name: "bull", Drift: 1, Volatility: 0.4, MeanDuration: time.Hour},
+		{Name: "bear", Drift: -1, Volatility: 1.2, MeanDuration: time.Hour},
+	}},
+}
+
+func generate(t *testing.T, cfg Config) []*domain.Candle {
+	t.Helper()
+	if cfg.Interval == "" {
+		cfg.Interval = "1m"
+	}
+	if cfg.Start.IsZero() {
+		cfg.Start = synthStart
+	}
+	candles, err := Generate(cfg)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return candles
+}
+
+// checkCandles verifies the OHLC invariants and that bars follow each other
+func checkCandles(t *testing.T, name string, candles []*domain.Candle, interval time.Duration) {
+	t.Helper()
+	for i, c := range candles {
+		if c.High < max(c.Open, c.Close) || c.Low > min(c.Open, c.Close) || c.Low <= 0 || c.Volume < 0 {
+			t.Fatalf("%s: bar %d = O %v H %v L %v C %v V %v", name, i, c.Open, c.High, c.Low, c.Close, c.Volume)
+		}
+		openTime := synthStart.Add(time.Duration(i) * interval)
+		if !c.OpenTime.Equal(openTime) || !c.CloseTime.Equal(openTime.Add(interval-time.Millisecond)) {
+			t.Fatalf("%s: bar %d spans %s-%s", name, i, c.OpenTime, c.CloseTime)
+		}
+		if i > 0 && c.Open != candles[i-1].Close {
+			t.Fatalf("%s: bar %d opens at %v, want the previous close %v", name, i, c.Open, candles[i-1].Close)
+		}
+	}
+}
+
+func TestGenerateSeeded(t *testing.T) {
+	for name, model := range testModels {
+		cfg := Config{Bars: 500, Seed: 42, Model: model}
+		first := generate(t, cfg)
+		if len(first) != 500 || first[0].Open != defaultStartPrice || first[0].Symbol != DefaultSymbol {
+			t.Fatalf("%s: %d bars from %v for %s", name, len(first), first[0].Open, first[0].Symbol)
+		}
+
+		// The same seed and config generate the same candles
+		if again := generate(t, cfg); !reflect.DeepEqual(again, first) {
+			t.Errorf("%s: seed 42 generated different candles", name)
+		}
+		cfg.Seed = 43
+		if other := generate(t, cfg); other[len(other)-1].Close == first[len(first)-1].Close {
+			t.Errorf("%s: seeds 42 and 43 generated the same path", name)
+		}
+	}
+}
+
+func TestGenerateInvariants(t *testing.T) {
+	intervals := map[string]time.Duration{"1m": time.Minute, "1h": time.Hour}
+	for name, model := range testModels {
+		for interval, d := range intervals {
+			for seed := int64(1); seed <= 5; seed++ {
+				candles := generate(t, Config{
+					Interval: interval,
+					Bars:     300,
+					Seed:     seed,
+					Model:    model,
+					Shocks: []Shock{
+						{Kind: ShockFlashCrash, Offset: 50 * d, Duration: 5 * d, Size: -0.5, Recovery: 0.8},
+						{Kind: ShockLiquidityVacuum, Offset: 100 * d, Duration: 20 * d},
+					},
+					Substeps: int(seed), // A single step still forms a valid bar
+				})
+				checkCandles(t, name+" "+interval, candles, d)
+			}
+		}
+	}
+}
+
+func TestGenerateFlashCrash(t *testing.T) {
+	// Shocks don't draw random numbers, so against the same seed without the
+	// shock the price is off by exactly the crash's move
+	base := generate(t, Config{Bars: 40, Seed: 7})
+	tests := []struct {
+		name  string
+		shock Shock
+		want  func(bar int) float64 // Close relative to the path without the shock
+	}{
+		{
+			"crash and half recovery",
+			Shock{Kind: ShockFlashCrash, Offset: 10 * time.Minute, Duration: 10 * time.Minute, Size: -0.4, Recovery: 0.5},
+			func(bar int) float64 {
+				switch {
+				case bar < 10:
+					return 1
+				case bar < 20:
+					return math.Pow(0.6, float64(bar-9)/10)
+				case bar < 30:
+					return math.Pow(0.6, 1-0.5*float64(bar-19)/10)
+				default:
+					return math.Sqrt(0.6)
+				}
+			},
+		},
+		{
+			"at a fixed time without recovery",
+			Shock{Kind: ShockFlashCrash, At: synthStart.Add(5 * time.Minute), Duration: 2 * time.Minute, Size: -0.2},
+			func(bar int) float64 {
+				switch {
+				case bar < 5:
+					return 1
+				case bar == 5:
+					return math.Sqrt(0.8)
+				default:
+					return 0.8
+				}
+			},
+		},
+		{
+			"shorter than a step",
+			Shock{Kind: ShockFlashCrash, Offset: 3 * time.Minute, Duration: time.Millisecond, Size: 0.5},
+			func(bar int) float64 {
+				if bar < 3 {
+					return 1
+				}
+				return 1.5
+			},
+		},
+	}
+	for _, tt := range tests {
+		candles := generate(t, Config{Bars: 40, Seed: 7, Shocks: []Shock{tt.shock}})
+		checkCandles(t, tt.name, candles, time.Minute)
+		for i, c := range candles {
+			if got, want := c.Close/base[i].Close, tt.want(i); math.Abs(got-want) > 1e-9 {
+				t.Errorf("%s: bar %d close ratio = %v, want %v", tt.name, i, got, want)
+			}
+		}
+	}
+}
+
+func TestGenerateLiquidityVacuum(t *testing.T) {
+	// Bars 200-399 are in the vacuum: a tenth of the volume and three times the volatility
+	candles := generate(t, Config{
+		Bars:   600,
+		Seed:   11,
+		Shocks: []Shock{{Kind: ShockLiquidityVacuum, Offset: 200 * time.Minute, Duration: 200 * time.Minute}},
+	})
+	checkCandles(t, "vacuum", candles, time.Minute)
+
+	stats := func(bars []*domain.Candle) (volume, vol float64) {
+		var sum, sumSq float64
+		for _, c := range bars {
+			volume += c.Volume
+			r := math.Log(c.Close / c.Open)
+			sum += r
+			sumSq += r * r
+		}
+		n := float64(len(bars))
+		return volume / n, math.Sqrt(sumSq/n - (sum/n)*(sum/n))
+	}
+	calmVolume, calmVol := stats(append(candles[:200:200], candles[400:]...))
+	vacuumVolume, vacuumVol := stats(candles[200:400])
+
+	if ratio := vacuumVolume / calmVolume; ratio < 0.08 || ratio > 0.12 {
+		t.Errorf("vacuum volume ratio = %v, want about 0.1", ratio)
+	}
+	if ratio := vacuumVol / calmVol; ratio < 2.5 || ratio > 3.5 {
+		t.Errorf("vacuum volatility ratio = %v, want about 3", ratio)
+	}
+	if calmVolume < 0.8*defaultBaseVolume || calmVolume > 1.5*defaultBaseVolume {
+		t.Errorf("calm volume = %v, want about %v", calmVolume, defaultBaseVolume)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"bad interval", Config{Interval: "7x", Bars: 10}},
+		{"no bars", Config{Interval: "1m"}},
+		{"negative price", Config{Interval: "1m", Bars: 10, StartPrice: -1}},
+		{"negative volatility", Config{Interval: "1m", Bars: 10, Model: ModelConfig{Volatility: -0.1}}},
+		{"explosive GARCH", Config{Interval: "1m", Bars: 10, Model: ModelConfig{Type: ModelGARCH, Alpha: 0.5, Beta: 0.5}}},
+		{"negative jump rate", Config{Interval: "1m", Bars: 10, Model: ModelConfig{Type: ModelJump, JumpRate: -1}}},
+		{"no regimes", Config{Interval: "1m", Bars: 10, Model: ModelConfig{Type: ModelRegime}}},
+		{"regime without duration", Config{Interval: "1m", Bars: 10, Model: ModelConfig{Type: ModelRegime, Regimes: []Regime{{Name: "flat"}}}}},
+		{"unknown model", Config{Interval: "1m", Bars: 10, Model: ModelConfig{Type: "heston"}}},
+		{"crash wiping out the price", Config{Interval: "1m", Bars: 10, Shocks: []Shock{{Kind: ShockFlashCrash, Size: -1, Duration: time.Minute}}}},
+		{"shock without duration", Config{Interval: "1m", Bars: 10, Shocks: []Shock{{Kind: ShockLiquidityVacuum}}}},
+		{"unknown shock", Config{Interval: "1m", Bars: 10, Shocks: []Shock{{Kind: "halt", Duration: time.Minute}}}},
+	}
+	for _, tt := range tests {
+		if _, err := Generate(tt.cfg); err == nil {
+			t.Errorf("%s: Generate succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestParseShock(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Shock
+		wantErr bool
+	}{
+		{"crash,at=2026-03-01T12:00:00Z,size=-0.4,duration=30m,recovery=0.5",
+			Shock{Kind: ShockFlashCrash, At: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Size: -0.4, Duration: 30 * time.Minute, Recovery: 0.5}, false},
+		{"vacuum,at=+3d,duration=2h,volume=0.2,vol=4",
+			Shock{Kind: ShockLiquidityVacuum, Offset: 72 * time.Hour, Duration: 2 * time.Hour, Volume: 0.2, Volatility: 4}, false},
+		{"crash,at=yesterday,size=-0.4,duration=30m", Shock{}, true},
+		{"crash,size=-0.4,duration=30m,recovery=2", Shock{}, true},
+		{"vacuum,duration=1h,depth=3", Shock{}, true},
+		{"vacuum,duration", Shock{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseShock(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseShock(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseShock(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseRegime(t *testing.T) {
+	got, err := ParseRegime("bear,drift=-0.8,vol=0.9,duration=720h")
+	if want := (Regime{Name: "bear", Drift: -0.8, Volatility: 0.9, MeanDuration: 720 * time.Hour}); err != nil || got != want {
+		t.Errorf("ParseRegime = %+v, %v, want %+v", got, err, want)
+	}
+	if got, err := ParseRegime("calm"); err != nil || got.MeanDuration != 30*24*time.Hour {
+		t.Errorf("ParseRegime(calm) = %+v, %v, want a 30 day duration", got, err)
+	}
+	for _, spec := range []string{"", ",vol=1", "bull,vol=high", "bull,skew=1"} {
+		if _, err := ParseRegime(spec); err == nil {
+			t.Errorf("ParseRegime(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package synthetic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// year is the time unit of drift and volatility parameters (crypto trades around the clock)
+const year = 365 * 24 * time.Hour
+
+// Models
+const (
+	ModelGBM    = "gbm"    // Geometric Brownian motion
+	ModelGARCH  = "garch"  // GARCH(1,1) volatility clustering
+	ModelJump   = "jump"   // Merton jump-diffusion
+	ModelRegime = "regime" // Markov regime switching between GBM regimes
+)
+
+// ModelConfig selects a price model and its parameters. Drift and volatility
+// are annualized; unused fields are ignored.
+type ModelConfig struct {
+	Type       string  // ModelGBM (default), ModelGARCH, ModelJump or ModelRegime
+	Drift      float64 // Expected return per year (μ), e.g. 0.1
+	Volatility float64 // Annualized volatility, e.g. 0.6 (GARCH: long-run level)
+
+	// GARCH(1,1): variance reacts to the last shock (Alpha) and persists (Beta); Alpha+Beta < 1
+	Alpha float64
+	Beta  float64
+
+	// Merton jumps: JumpRate jumps per year with normally distributed log sizes
+	JumpRate float64
+	JumpMean float64 // Mean log jump size, e.g. -0.05
+	JumpVol  float64 // Standard deviation of the log jump size
+
+	Regimes []Regime // Regime switching: the path starts in the first regime
+}
+
+// Regime is a market state of the regime switching model
+type Regime struct {
+	Name         string
+	Drift        float64       // Annualized
+	Volatility   float64       // Annualized
+	MeanDuration time.Duration // Expected time before switching to another regime (chosen uniformly)
+}
+
+// withDefaults fills unset parameters
+func (c ModelConfig) withDefaults() ModelConfig {
+	if c.Type == "" {
+		c.Type = ModelGBM
+	}
+	if c.Volatility == 0 {
+		c.Volatility = 0.6
+	}
+	if c.Type == ModelGARCH && c.Alpha == 0 && c.Beta == 0 {
+		c.Alpha, c.Beta = 0.08, 0.9
+	}
+	if c.Type == ModelJump && c.JumpRate == 0 {
+		c.JumpRate, c.JumpMean, c.JumpVol = 12, -0.03, 0.05
+	}
+	return c
+}
+
+// validate checks the parameters of the selected model
+func (c ModelConfig) validate() error {
+	if c.Volatility < 0 {
+		return fmt.Errorf("volatility must not be negative")
+	}
+
+	switch c.Type {
+	case ModelGBM:
+	case ModelGARCH:
+		if c.Alpha < 0 || c.Beta < 0 || c.Alpha+c.Beta >= 1 {
+			return fmt.Errorf("GARCH needs alpha, beta >= 0 and alpha + beta < 1")
+		}
+	case ModelJump:
+		if c.JumpRate < 0 || c.JumpVol < 0 {
+			return fmt.Errorf("jump rate and jump volatility must not be negative")
+		}
+	case ModelRegime:
+		if len(c.Regimes) == 0 {
+			return fmt.Errorf("regime model needs at least one regime")
+		}
+		for _, r := range c.Regimes {
+			if r.Volatility < 0 || r.MeanDuration <= 0 {
+				return fmt.Errorf("regime %q needs a volatility >= 0 and a positive duration", r.Name)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported model: %q", c.Type)
+	}
+	return nil
+}
+
+// model generates log returns step by step. Models keep state (variance, regime),
+// so each path gets a fresh one.
+type model interface {
+	// step returns one step's log return over dt years; scale multiplies the
+	// diffusion volatility (shocks)
+	step(rng *rand.Rand, dt, scale float64) float64
+
+	// volatility is the current annualized diffusion volatility
+	volatility() float64
+}
+
+// newModel creates the configured model
+func newModel(c ModelConfig, dt float64) model {
+	switch c.Type {
+	case ModelGARCH:
+		variance := c.Volatility * c.Volatility * dt
+		return &garch{drift: c.Drift, alpha: c.Alpha, beta: c.Beta, variance: variance,
+			omega: variance * (1 - c.Alpha - c.Beta), dt: dt}
+	case ModelJump:
+		return &jumpDiffusion{gbm: gbm{drift: c.Drift, vol: c.Volatility}, rate: c.JumpRate, mean: c.JumpMean, vol: c.JumpVol}
+	case ModelRegime:
+		return &regimeSwitching{regimes: c.Regimes}
+	default:
+		return &gbm{drift: c.Drift, vol: c.Volatility}
+	}
+}
+
+// gbm is geometric Brownian motion: constant drift and volatility
+type gbm struct {
+	drift, vol float64
+}
+
+func (m *gbm) step(rng *rand.Rand, dt, scale float64) float64 {
+	vol := m.vol * scale
+	return (m.drift-vol*vol/2)*dt + vol*math.Sqrt(dt)*rng.NormFloat64()
+}
+
+func (m *gbm) volatility() float64 {
+	return m.vol
+}
+
+// garch is GBM whose variance follows GARCH(1,1): large moves raise the
+// variance of the following steps, producing volatility clusters
+type garch struct {
+	drift       float64
+	omega       float64 // Per-step variance floor: long-run variance × (1 - alpha - beta)
+	alpha, beta float64
+	variance    float64 // Per-step variance of the next step
+	dt          float64 // Step size the variance is expressed in
+}
+
+func (m *garch) step(rng *rand.Rand, dt, scale float64) float64 {
+	z := rng.NormFloat64()
+	variance := m.variance * scale * scale
+	shock := math.Sqrt(variance) * z
+
+	// The recursion sees the model's own shock: a scaled-up shock feeding back
+	// into the variance would compound every step until the price underflows
+	modelShock := math.Sqrt(m.variance) * z
+	m.variance = m.omega + m.alpha*modelShock*modelShock + m.beta*m.variance
+	return m.drift*dt - variance/2 + shock
+}
+
+func (m *garch) volatility() float64 {
+	return math.Sqrt(m.variance / m.dt)
+}
+
+// jumpDiffusion is Merton's jump-diffusion: GBM plus Poisson jumps with
+// normally distributed log sizes. The drift is compensated, so jumps add risk
+// but don't change the expected price.
+type jumpDiffusion struct {
+	gbm
+	rate, mean, vol float64
+}
+
+func (m *jumpDiffusion) step(rng *rand.Rand, dt, scale float64) float64 {
+	compensation := m.rate * (math.Exp(m.mean+m.vol*m.vol/2) - 1) * dt
+	r := m.gbm.step(rng, dt, scale) - compensation
+	for n := poisson(rng, m.rate*dt); n > 0; n-- {
+		r += m.mean + m.vol*rng.NormFloat64()
+	}
+	return r
+}
+
+// regimeSwitching is GBM whose drift and volatility jump between regimes,
+// leaving each after an exponentially distributed time
+type regimeSwitching struct {
+	regimes []Regime
+	current int
+}
+
+func (m *regimeSwitching) step(rng *rand.Rand, dt, scale float64) float64 {
+	r := m.regimes[m.current]
+	vol := r.Volatility * scale
+	ret := (r.Drift-vol*vol/2)*dt + vol*math.Sqrt(dt)*rng.NormFloat64()
+
+	// Leave with probability dt / mean duration per step
+	if len(m.regimes) > 1 && rng.Float64() < dt/years(r.MeanDuration) {
+		next := rng.Intn(len(m.regimes) - 1)
+		if next >= m.current {
+			next++
+		}
+		m.current = next
+	}
+	return ret
+}
+
+func (m *regimeSwitching) volatility() float64 {
+	return m.regimes[m.current].Volatility
+}
+
+// poisson draws a Poisson count with mean lambda (Knuth's method, fine for the small means of one step)
+func poisson(rng *rand.Rand, lambda float64) int {
+	if lambda <= 0 {
+		return 0
+	}
+	limit := math.Exp(-lambda)
+	n := 0
+	for p := rng.Float64(); p > limit; p *= rng.Float64() {
+		n++
+	}
+	return n
+}
+
+// years converts a duration to years
+func years(d time.Duration) float64 {
+	return float64(d) / float64(year)
+}
+
+// ParseRegime parses a regime spec such as "bear,drift=-0.8,vol=0.9,duration=720h"
+func ParseRegime(spec string) (Regime, error) {
+	name, params, err := parseSpec(spec)
+	if err != nil {
+		return Regime{}, err
+	}
+
+	r := Regime{Name: name, MeanDuration: 30 * 24 * time.Hour}
+	for key, value := range params {
+		switch key {
+		case "drift":
+			r.Drift, err = parseFloat(key, value)
+		case "vol":
+			r.Volatility, err = parseFloat(key, value)
+		case "duration":
+			r.MeanDuration, err = parseDuration(key, value)
+		default:
+			err = fmt.Errorf("unknown regime parameter %q", key)
+		}
+		if err != nil {
+			return Regime{}, err
+		}
+	}
+	return r, nil
+}
+
+// parseSpec splits "name,key=value,..." into the name and parameters
+func parseSpec(spec string) (string, map[string]string, error) {
+	parts := strings.Split(spec, ",")
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		return "", nil, fmt.Errorf("invalid spec %q: missing name", spec)
+	}
+
+	params := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", nil, fmt.Errorf("invalid spec %q: %q is not key=value", spec, part)
+		}
+		params[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return name, params, nil
+}
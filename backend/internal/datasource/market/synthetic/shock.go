@@ -0,0 +1,193 @@
+package synthetic
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shock kinds
+const (
+	ShockFlashCrash      = "crash"  // The price falls by Size over Duration, then retraces part of it
+	ShockLiquidityVacuum = "vacuum" // Volume dries up and moves get violent for Duration
+)
+
+// Default shock multipliers
+const (
+	defaultCrashVolume    = 5.0 // Panic selling
+	defaultVacuumVolume   = 0.1
+	defaultVacuumVolScale = 3.0
+)
+
+// Shock is a scenario event injected into the generated path on top of the model
+type Shock struct {
+	Kind     string        // ShockFlashCrash or ShockLiquidityVacuum
+	At       time.Time     // When the shock starts
+	Offset   time.Duration // Start relative to the path start, used when At is zero
+	Duration time.Duration
+
+	// Flash crash
+	Size         float64       // Total move, e.g. -0.4 for -40%
+	Recovery     float64       // Fraction of the move retraced afterwards (0-1)
+	RecoveryTime time.Duration // Time the retracement takes (default Duration)
+
+	Volatility float64 // Volatility multiplier while the shock lasts (vacuum default 3)
+	Volume     float64 // Volume multiplier while the shock lasts (crash default 5, vacuum 0.1)
+}
+
+// withDefaults fills unset parameters and resolves an offset start against the path start
+func (s Shock) withDefaults(start time.Time) Shock {
+	if s.At.IsZero() {
+		s.At = start.Add(s.Offset)
+	}
+	if s.RecoveryTime == 0 {
+		s.RecoveryTime = s.Duration
+	}
+	if s.Volatility == 0 {
+		s.Volatility = 1
+		if s.Kind == ShockLiquidityVacuum {
+			s.Volatility = defaultVacuumVolScale
+		}
+	}
+	if s.Volume == 0 {
+		s.Volume = defaultCrashVolume
+		if s.Kind == ShockLiquidityVacuum {
+			s.Volume = defaultVacuumVolume
+		}
+	}
+	return s
+}
+
+// validate checks the shock's parameters
+func (s Shock) validate() error {
+	switch s.Kind {
+	case ShockFlashCrash:
+		if s.Size <= -1 || s.Size == 0 {
+			return fmt.Errorf("flash crash needs a size above -1 (e.g. -0.4)")
+		}
+		if s.Recovery < 0 || s.Recovery > 1 {
+			return fmt.Errorf("flash crash recovery must be between 0 and 1")
+		}
+	case ShockLiquidityVacuum:
+	default:
+		return fmt.Errorf("unsupported shock: %q", s.Kind)
+	}
+	if s.Duration <= 0 {
+		return fmt.Errorf("%s shock needs a positive duration", s.Kind)
+	}
+	if s.Volatility < 0 || s.Volume < 0 {
+		return fmt.Errorf("%s shock multipliers must not be negative", s.Kind)
+	}
+	return nil
+}
+
+// effect is what active shocks do to one step
+type effect struct {
+	logReturn  float64 // Added to the model's log return
+	volatility float64 // Volatility multiplier
+	volume     float64 // Volume multiplier
+}
+
+// applyShocks returns the combined effect of the shocks on the step [t, t+step).
+// Shocks shorter than a step still land in full on the step they fall into.
+func applyShocks(shocks []Shock, t time.Time, step time.Duration) effect {
+	e := effect{volatility: 1, volume: 1}
+	stepEnd := t.Add(step)
+	for _, s := range shocks {
+		end := s.At.Add(s.Duration)
+		if share := overlap(t, stepEnd, s.At, end); share > 0 {
+			if s.Kind == ShockFlashCrash {
+				// The move is spread evenly over the crash
+				e.logReturn += math.Log1p(s.Size) * float64(share) / float64(s.Duration)
+			}
+			e.volatility *= s.Volatility
+			e.volume *= s.Volume
+		}
+		if s.Kind == ShockFlashCrash && s.Recovery > 0 {
+			if share := overlap(t, stepEnd, end, end.Add(s.RecoveryTime)); share > 0 {
+				e.logReturn -= math.Log1p(s.Size) * s.Recovery * float64(share) / float64(s.RecoveryTime)
+			}
+		}
+	}
+	return e
+}
+
+// overlap returns how long [a1, a2) and [b1, b2) overlap
+func overlap(a1, a2, b1, b2 time.Time) time.Duration {
+	start, end := a1, a2
+	if b1.After(start) {
+		start = b1
+	}
+	if b2.Before(end) {
+		end = b2
+	}
+	return max(end.Sub(start), 0)
+}
+
+// ParseShock parses a shock spec such as
+//
+//	crash,at=2026-03-01T12:00:00Z,size=-0.4,duration=30m,recovery=0.5
+//	vacuum,at=+72h,duration=2h,volume=0.1,vol=3
+//
+// where at is an RFC3339 time or an offset (+duration) from the path start.
+func ParseShock(spec string) (Shock, error) {
+	kind, params, err := parseSpec(spec)
+	if err != nil {
+		return Shock{}, err
+	}
+
+	s := Shock{Kind: kind}
+	for key, value := range params {
+		switch key {
+		case "at":
+			if offset, ok := strings.CutPrefix(value, "+"); ok {
+				s.Offset, err = parseDuration(key, offset)
+			} else if s.At, err = time.Parse(time.RFC3339, value); err != nil {
+				err = fmt.Errorf("invalid at %q: want RFC3339 or +duration", value)
+			}
+		case "size":
+			s.Size, err = parseFloat(key, value)
+		case "duration":
+			s.Duration, err = parseDuration(key, value)
+		case "recovery":
+			s.Recovery, err = parseFloat(key, value)
+		case "recovery_time":
+			s.RecoveryTime, err = parseDuration(key, value)
+		case "vol":
+			s.Volatility, err = parseFloat(key, value)
+		case "volume":
+			s.Volume, err = parseFloat(key, value)
+		default:
+			err = fmt.Errorf("unknown shock parameter %q", key)
+		}
+		if err != nil {
+			return Shock{}, err
+		}
+	}
+	return s, s.validate()
+}
+
+// parseFloat parses a spec value
+func parseFloat(key, value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return f, nil
+}
+
+// parseDuration parses a spec value, also accepting days (e.g. 30d)
+func parseDuration(key, value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.ParseFloat(days, 64); err == nil {
+			return time.Duration(n * float64(24*time.Hour)), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return d, nil
+}
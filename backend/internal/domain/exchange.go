@@ -1,17 +1,36 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Exchange defines the interface for interacting with exchanges
 type Exchange interface {
-	// PlaceOrder places a new order
+	// PlaceOrder places a new order. Orders without a ClientOrderID get one
+	// assigned, and placing an order whose ClientOrderID already exists returns
+	// the existing order instead of trading twice.
 	PlaceOrder(ctx context.Context, order *Order) (*Order, error)
 
-	// CancelOrder cancels an existing order
-	CancelOrder(ctx context.Context, orderID string) error
+	// CancelOrder cancels an open order and returns its final state
+	CancelOrder(ctx context.Context, ref OrderRef) (*Order, error)
 
 	// GetOrder retrieves order details
-	GetOrder(ctx context.Context, orderID string) (*Order, error)
+	GetOrder(ctx context.Context, ref OrderRef) (*Order, error)
+
+	// GetOpenOrders lists open orders for a symbol (all symbols if empty)
+	GetOpenOrders(ctx context.Context, symbol string) ([]*Order, error)
+
+	// CancelAllOrders cancels every open order for a symbol and returns them
+	CancelAllOrders(ctx context.Context, symbol string) ([]*Order, error)
+
+	// GetOrderHistory lists a symbol's orders of any status created in [start, end],
+	// oldest first. A zero start returns the most recent limit orders.
+	GetOrderHistory(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*Order, error)
+
+	// GetMyTrades lists a symbol's executions in [start, end], oldest first.
+	// A zero start returns the most recent limit trades.
+	GetMyTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*Trade, error)
 
 	// GetCurrentPrice gets the current price for a symbol
 	GetCurrentPrice(ctx context.Context, symbol string) (float64, error)
@@ -22,3 +41,16 @@ type Exchange interface {
 	// Close closes the exchange connection
 	Close() error
 }
+
+// KlineSource fetches historical candles from a venue for the history collector
+type KlineSource interface {
+	// Venue names the venue the candles come from (e.g. "binance", "bybit")
+	Venue() string
+
+	// MaxKlineLimit is the most candles a single GetKlines call returns
+	MaxKlineLimit() int
+
+	// GetKlines returns up to limit closed or forming candles opening in
+	// [start, end], oldest first. symbol uses our BTCUSDT form.
+	GetKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]*Candle, error)
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MarginType is how a futures position's margin is held
+type MarginType string
+
+const (
+	MarginTypeIsolated MarginType = "ISOLATED" // Each position has its own margin; only it can be liquidated
+	MarginTypeCross    MarginType = "CROSSED"  // Positions share the account's margin balance
+)
+
+// ParseMarginType parses "isolated" or "cross" (Binance's "crossed" also works)
+func ParseMarginType(s string) (MarginType, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "ISOLATED":
+		return MarginTypeIsolated, nil
+	case "CROSS", "CROSSED":
+		return MarginTypeCross, nil
+	default:
+		return "", fmt.Errorf("invalid margin type %q (want isolated or cross)", s)
+	}
+}
+
+// PositionSide is the direction of a futures position
+type PositionSide string
+
+const (
+	PositionSideLong  PositionSide = "LONG"
+	PositionSideShort PositionSide = "SHORT"
+)
+
+// OpenSide returns the order side that opens or adds to a position on this side
+func (s PositionSide) OpenSide() OrderSide {
+	if s == PositionSideShort {
+		return OrderSideSell
+	}
+	return OrderSideBuy
+}
+
+// CloseSide returns the order side that reduces or closes a position on this side
+func (s PositionSide) CloseSide() OrderSide {
+	if s == PositionSideShort {
+		return OrderSideBuy
+	}
+	return OrderSideSell
+}
+
+// PositionRisk is a futures position with its leverage, margin and liquidation price
+type PositionRisk struct {
+	Symbol           string     `json:"symbol"`
+	Quantity         float64    `json:"quantity"` // Positive for long, negative for short, 0 if flat
+	EntryPrice       float64    `json:"entry_price"`
+	MarkPrice        float64    `json:"mark_price"`
+	UnrealizedPnL    float64    `json:"unrealized_pnl"`
+	LiquidationPrice float64    `json:"liquidation_price"` // 0 if flat or out of reach
+	Leverage         int        `json:"leverage"`
+	MarginType       MarginType `json:"margin_type"`
+	IsolatedMargin   float64    `json:"isolated_margin"` // Margin held by an isolated position
+	Notional         float64    `json:"notional"`        // Position value at the mark price (negative for short)
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Side returns the position's side (long for a flat position)
+func (p *PositionRisk) Side() PositionSide {
+	if p.Quantity < 0 {
+		return PositionSideShort
+	}
+	return PositionSideLong
+}
+
+// FuturesExchange is an exchange trading leveraged perpetual futures.
+// PlaceOrder opens a long with a buy and a short with a sell; orders marked
+// ReduceOnly or ClosePosition only ever shrink the open position.
+type FuturesExchange interface {
+	Exchange
+
+	// SetLeverage sets the symbol's initial leverage (e.g. 1-125 on Binance)
+	SetLeverage(ctx context.Context, symbol string, leverage int) error
+
+	// SetMarginType switches the symbol between isolated and cross margin.
+	// Setting the type the symbol already has is not an error.
+	SetMarginType(ctx context.Context, symbol string, marginType MarginType) error
+
+	// GetPositionRisk returns a symbol's position, flat or not, or every open
+	// position if symbol is empty
+	GetPositionRisk(ctx context.Context, symbol string) ([]*PositionRisk, error)
+
+	// ClosePosition closes the symbol's whole position with a reduce-only market order
+	ClosePosition(ctx context.Context, symbol string) (*Order, error)
+}
@@ -13,3 +13,57 @@ type Candle struct {
 	Close     float64   `json:"close"`
 	Volume    float64   `json:"volume"`
 }
+
+// AggTrade is an aggregated trade: fills of one taker order at a single price
+type AggTrade struct {
+	Symbol       string    `json:"symbol"`
+	ID           int64     `json:"id"` // Aggregate trade ID
+	Price        float64   `json:"price"`
+	Quantity     float64   `json:"quantity"`
+	FirstTradeID int64     `json:"first_trade_id"`
+	LastTradeID  int64     `json:"last_trade_id"`
+	Time         time.Time `json:"time"`
+	IsBuyerMaker bool      `json:"is_buyer_maker"` // True when the taker sold
+}
+
+// TimeFromMillis converts a Unix millisecond timestamp (as used by exchanges
+// and candle storage) to a UTC time
+func TimeFromMillis(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
+}
+
+// BookLevel represents one price level of an order book
+type BookLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// OrderBookSnapshot represents the top of an order book at a point in time
+type OrderBookSnapshot struct {
+	Symbol       string      `json:"symbol"`
+	Time         time.Time   `json:"time"`
+	LastUpdateID int64       `json:"last_update_id"`
+	Bids         []BookLevel `json:"bids"` // Best (highest) first
+	Asks         []BookLevel `json:"asks"` // Best (lowest) first
+	Spread       float64     `json:"spread"`
+	MidPrice     float64     `json:"mid_price"`
+	Imbalance    float64     `json:"imbalance"`  // (bid qty - ask qty) / (bid qty + ask qty) over the stored levels
+	Microprice   float64     `json:"microprice"` // Top-of-book mid weighted by the opposite side's size
+}
+
+// FundingRate is a perpetual futures funding event
+type FundingRate struct {
+	Symbol    string    `json:"symbol"`
+	Time      time.Time `json:"time"` // Funding timestamp
+	Rate      float64   `json:"rate"` // Positive: longs pay shorts
+	MarkPrice float64   `json:"mark_price,omitempty"`
+}
+
+// OpenInterest is the total open perpetual futures position at a point in time
+type OpenInterest struct {
+	Symbol string    `json:"symbol"`
+	Period string    `json:"period"` // Sampling period, e.g. 5m, 1h
+	Time   time.Time `json:"time"`
+	Amount float64   `json:"amount"` // Base asset
+	Value  float64   `json:"value"`  // Quote asset
+}
@@ -1,6 +1,13 @@
 package domain
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // OrderSide represents buy or sell direction
 type OrderSide string
@@ -14,34 +21,121 @@ const (
 type OrderType string
 
 const (
-	OrderTypeMarket OrderType = "MARKET"
-	OrderTypeLimit  OrderType = "LIMIT"
+	OrderTypeMarket     OrderType = "MARKET"
+	OrderTypeLimit      OrderType = "LIMIT"
+	OrderTypeStopMarket OrderType = "STOP_MARKET" // Becomes a market order once the price reaches StopPrice
+	OrderTypeStopLimit  OrderType = "STOP_LIMIT"  // Becomes a limit order at Price once the price reaches StopPrice
 )
 
+// IsStop reports whether the order type waits for its stop price
+func (t OrderType) IsStop() bool {
+	return t == OrderTypeStopMarket || t == OrderTypeStopLimit
+}
+
 // OrderStatus represents order status
 type OrderStatus string
 
 const (
-	OrderStatusNew       OrderStatus = "NEW"
-	OrderStatusFilled    OrderStatus = "FILLED"
-	OrderStatusCancelled OrderStatus = "CANCELLED"
-	OrderStatusRejected  OrderStatus = "REJECTED"
+	OrderStatusNew             OrderStatus = "NEW"
+	OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+	OrderStatusFilled          OrderStatus = "FILLED"
+	OrderStatusCancelled       OrderStatus = "CANCELLED"
+	OrderStatusRejected        OrderStatus = "REJECTED"
+	OrderStatusExpired         OrderStatus = "EXPIRED"
 )
 
+// ClientOrderIDPrefix marks client order IDs generated by NewClientOrderID
+const ClientOrderIDPrefix = "cq-"
+
 // Order represents a trading order
 type Order struct {
-	ID         string      `json:"id"`
-	Symbol     string      `json:"symbol"`
-	Side       OrderSide   `json:"side"`
-	Type       OrderType   `json:"type"`
-	Quantity   float64     `json:"quantity"`
-	Price      float64     `json:"price"` // 0 for market orders
-	Status     OrderStatus `json:"status"`
-	FilledQty  float64     `json:"filled_qty"`
-	AvgPrice   float64     `json:"avg_price"`
-	CreatedAt  time.Time   `json:"created_at"`
-	UpdatedAt  time.Time   `json:"updated_at"`
-	ExecutedAt *time.Time  `json:"executed_at,omitempty"`
+	ID            string      `json:"id"`              // Assigned by the exchange
+	ClientOrderID string      `json:"client_order_id"` // Assigned by us, see NewClientOrderID
+	Symbol        string      `json:"symbol"`
+	Side          OrderSide   `json:"side"`
+	Type          OrderType   `json:"type"`
+	Quantity      float64     `json:"quantity"`
+	Price         float64     `json:"price"`                    // 0 for market orders
+	StopPrice     float64     `json:"stop_price,omitempty"`     // Trigger price of stop orders
+	OCOGroup      string      `json:"oco_group,omitempty"`      // Orders in the same one-cancels-the-other group
+	ReduceOnly    bool        `json:"reduce_only,omitempty"`    // Futures: only reduces the position, never opens or flips it
+	ClosePosition bool        `json:"close_position,omitempty"` // Futures: closes the whole position; Side and Quantity come from it
+	Status        OrderStatus `json:"status"`
+	FilledQty     float64     `json:"filled_qty"`
+	AvgPrice      float64     `json:"avg_price"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	ExecutedAt    *time.Time  `json:"executed_at,omitempty"`
+}
+
+// Ref returns the reference used to query or cancel the order
+func (o *Order) Ref() OrderRef {
+	return OrderRef{Symbol: o.Symbol, OrderID: o.ID, ClientOrderID: o.ClientOrderID}
+}
+
+// IsOpen reports whether the order is still working on the book
+func (o *Order) IsOpen() bool {
+	return o.Status == OrderStatusNew || o.Status == OrderStatusPartiallyFilled
+}
+
+// OrderRef identifies an order by symbol and either the exchange order ID or
+// the client order ID. The exchange ID wins when both are set.
+type OrderRef struct {
+	Symbol        string `json:"symbol"`
+	OrderID       string `json:"order_id,omitempty"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+}
+
+// Validate checks that the reference names a symbol and an order
+func (r OrderRef) Validate() error {
+	if r.Symbol == "" {
+		return fmt.Errorf("order reference needs a symbol")
+	}
+	if r.OrderID == "" && r.ClientOrderID == "" {
+		return fmt.Errorf("order reference needs an order ID or client order ID")
+	}
+	return nil
+}
+
+// String formats the reference for logs and errors
+func (r OrderRef) String() string {
+	if r.OrderID != "" {
+		return r.Symbol + "#" + r.OrderID
+	}
+	return r.Symbol + "/" + r.ClientOrderID
+}
+
+// NewClientOrderID derives a client order ID from an intent key (e.g. strategy
+// name and signal bar time) and the order's parameters. The same key and order
+// always give the same ID, so a resubmitted order is recognized as a duplicate
+// instead of trading twice. IDs fit Binance's 36 character limit.
+func NewClientOrderID(key string, order *Order) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%s", key, order.Symbol, order.Side, order.Type,
+		strconv.FormatFloat(order.Quantity, 'f', -1, 64),
+		strconv.FormatFloat(order.Price, 'f', -1, 64))
+	return ClientOrderIDPrefix + hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// ClientOrderIDPrefixFor returns the prefix of client order IDs placed for
+// source (e.g. a strategy instance name) by NewSourceClientOrderID, so the
+// source's orders can be picked out of the order history
+func ClientOrderIDPrefixFor(source string) string {
+	h := sha256.Sum256([]byte(source))
+	return ClientOrderIDPrefix + hex.EncodeToString(h[:])[:8]
+}
+
+// NewSourceClientOrderID is NewClientOrderID for an order placed for source;
+// the ID starts with ClientOrderIDPrefixFor(source)
+func NewSourceClientOrderID(source, key string, order *Order) string {
+	id := strings.TrimPrefix(NewClientOrderID(key, order), ClientOrderIDPrefix)
+	return ClientOrderIDPrefixFor(source) + id[:24]
+}
+
+// HasClientOrderIDPrefix reports whether id starts with prefix. Dashes are
+// ignored, since some venues (OKX) only accept alphanumeric IDs.
+func HasClientOrderIDPrefix(id, prefix string) bool {
+	return strings.HasPrefix(strings.ReplaceAll(id, "-", ""), strings.ReplaceAll(prefix, "-", ""))
 }
 
 // Trade represents an executed trade
@@ -54,5 +148,8 @@ type Trade struct {
 	Quantity  float64   `json:"quantity"`
 	Fee       float64   `json:"fee"`
 	FeeAsset  string    `json:"fee_asset"`
+	IsMaker   bool      `json:"is_maker"`
 	Timestamp time.Time `json:"timestamp"`
+
+	RealizedPnL float64 `json:"realized_pnl,omitempty"` // Futures: PnL the fill realized on the position
 }
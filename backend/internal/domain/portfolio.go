@@ -28,3 +28,16 @@ type Portfolio struct {
 	TotalPnL  float64              `json:"total_pnl"`
 	UpdatedAt time.Time            `json:"updated_at"`
 }
+
+// AccountEvent is a live account update from an exchange's user-data stream
+type AccountEvent struct {
+	Time     time.Time  `json:"time"`
+	Snapshot bool       `json:"snapshot"`        // Balances holds every asset (sent on each stream connect)
+	Balances []*Balance `json:"balances"`        // Changed (or, for a snapshot, all) balances
+	Order    *Order     `json:"order,omitempty"` // Order update from an execution report
+	Fill     *Trade     `json:"fill,omitempty"`  // The execution the order update reports, if any
+
+	// Positions (futures accounts only) are the exchange's own position state:
+	// changed positions, or for a snapshot every open one. Nil for spot accounts.
+	Positions []*Position `json:"positions,omitempty"`
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// SymbolStatusTrading is the status of symbols open for trading
+const SymbolStatusTrading = "TRADING"
+
+// SymbolInfo holds a symbol's assets and exchange trading rules
+type SymbolInfo struct {
+	Symbol      string    `json:"symbol"`
+	BaseAsset   string    `json:"base_asset"`
+	QuoteAsset  string    `json:"quote_asset"`
+	Status      string    `json:"status"`
+	TickSize    float64   `json:"tick_size"` // PRICE_FILTER (0 = no rule)
+	MinPrice    float64   `json:"min_price"`
+	MaxPrice    float64   `json:"max_price"`
+	StepSize    float64   `json:"step_size"` // LOT_SIZE (0 = no rule)
+	MinQty      float64   `json:"min_qty"`
+	MaxQty      float64   `json:"max_qty"`
+	MinNotional float64   `json:"min_notional"` // MIN_NOTIONAL or NOTIONAL
+	Permissions []string  `json:"permissions"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsTradable reports whether the symbol is open for trading
+func (s *SymbolInfo) IsTradable() bool {
+	return s.Status == SymbolStatusTrading
+}
+
+// RoundPrice rounds a price to the nearest tick
+func (s *SymbolInfo) RoundPrice(price float64) float64 {
+	if s.TickSize <= 0 {
+		return price
+	}
+	return roundTo(math.Round(price/s.TickSize)*s.TickSize, stepDecimals(s.TickSize))
+}
+
+// RoundQuantity rounds a quantity down to the lot step so it never exceeds the request
+func (s *SymbolInfo) RoundQuantity(quantity float64) float64 {
+	if s.StepSize <= 0 {
+		return quantity
+	}
+	// The epsilon keeps exact multiples (e.g. 0.3 / 0.1) from flooring one step down
+	return roundTo(math.Floor(quantity/s.StepSize+1e-9)*s.StepSize, stepDecimals(s.StepSize))
+}
+
+// FormatPrice formats a price with the tick size's decimals
+func (s *SymbolInfo) FormatPrice(price float64) string {
+	if s.TickSize <= 0 {
+		return strconv.FormatFloat(price, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(price, 'f', stepDecimals(s.TickSize), 64)
+}
+
+// FormatQuantity formats a quantity with the step size's decimals
+func (s *SymbolInfo) FormatQuantity(quantity float64) string {
+	if s.StepSize <= 0 {
+		return strconv.FormatFloat(quantity, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(quantity, 'f', stepDecimals(s.StepSize), 64)
+}
+
+// Validate checks an already rounded order against the price, lot size and
+// notional rules. price is the limit price, or the expected fill price for market orders.
+func (s *SymbolInfo) Validate(price, quantity float64) error {
+	if quantity <= 0 {
+		return fmt.Errorf("%s quantity must be positive after rounding to step %s", s.Symbol, s.FormatQuantity(s.StepSize))
+	}
+	if s.MinQty > 0 && quantity < s.MinQty {
+		return fmt.Errorf("%s quantity %s below minimum %s", s.Symbol, s.FormatQuantity(quantity), s.FormatQuantity(s.MinQty))
+	}
+	if s.MaxQty > 0 && quantity > s.MaxQty {
+		return fmt.Errorf("%s quantity %s above maximum %s", s.Symbol, s.FormatQuantity(quantity), s.FormatQuantity(s.MaxQty))
+	}
+	if price > 0 {
+		if s.MinPrice > 0 && price < s.MinPrice {
+			return fmt.Errorf("%s price %s below minimum %s", s.Symbol, s.FormatPrice(price), s.FormatPrice(s.MinPrice))
+		}
+		if s.MaxPrice > 0 && price > s.MaxPrice {
+			return fmt.Errorf("%s price %s above maximum %s", s.Symbol, s.FormatPrice(price), s.FormatPrice(s.MaxPrice))
+		}
+		if s.MinNotional > 0 && price*quantity < s.MinNotional {
+			return fmt.Errorf("%s order value %.8g below minimum notional %.8g", s.Symbol, price*quantity, s.MinNotional)
+		}
+	}
+	return nil
+}
+
+// stepDecimals returns the number of decimals of a tick or step size (e.g. 0.001 -> 3)
+func stepDecimals(step float64) int {
+	decimals := 0
+	for decimals < 12 && math.Abs(step-math.Round(step)) > 1e-12 {
+		step *= 10
+		decimals++
+	}
+	return decimals
+}
+
+// roundTo rounds to a number of decimals, removing float noise from step arithmetic
+func roundTo(value float64, decimals int) float64 {
+	pow := math.Pow(10, float64(decimals))
+	return math.Round(value*pow) / pow
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoundPrice(t *testing.T) {
+	tests := []struct {
+		tick, price, want float64
+	}{
+		{0.01, 100.126, 100.13},
+		{0.01, 100.124, 100.12},
+		{0.01, 0.1 + 0.2, 0.3}, // Float noise is removed
+		{0.5, 100.3, 100.5},
+		{0.5, 100.2, 100},
+		{10, 12345, 12350}, // Halves round away from zero
+		{0.00000001, 0.123456789, 0.12345679},
+		{0, 100.126, 100.126}, // No rule
+	}
+	for _, tt := range tests {
+		info := &SymbolInfo{TickSize: tt.tick}
+		if got := info.RoundPrice(tt.price); got != tt.want {
+			t.Errorf("RoundPrice(%v) with tick %v = %v, want %v", tt.price, tt.tick, got, tt.want)
+		}
+	}
+}
+
+func TestRoundQuantity(t *testing.T) {
+	tests := []struct {
+		step, quantity, want float64
+	}{
+		{0.1, 0.3, 0.3}, // Exact multiples don't floor a step down
+		{0.1, 0.39, 0.3},
+		{0.001, 1.23456, 1.234},
+		{0.001, 0.0005, 0}, // Below one step
+		{1, 2.9999, 2},
+		{0.00001, 0.1 + 0.2, 0.3},
+		{0, 1.23456, 1.23456}, // No rule
+	}
+	for _, tt := range tests {
+		info := &SymbolInfo{StepSize: tt.step}
+		if got := info.RoundQuantity(tt.quantity); got != tt.want {
+			t.Errorf("RoundQuantity(%v) with step %v = %v, want %v", tt.quantity, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	info := &SymbolInfo{TickSize: 0.01, StepSize: 0.00001}
+	if got := info.FormatPrice(100.5); got != "100.50" {
+		t.Errorf("FormatPrice = %s, want 100.50", got)
+	}
+	if got := info.FormatQuantity(0.5); got != "0.50000" {
+		t.Errorf("FormatQuantity = %s, want 0.50000", got)
+	}
+
+	none := &SymbolInfo{}
+	if got := none.FormatPrice(100.5); got != "100.5" {
+		t.Errorf("FormatPrice without a rule = %s, want 100.5", got)
+	}
+	if got := none.FormatQuantity(0.123456789); got != "0.123456789" {
+		t.Errorf("FormatQuantity without a rule = %s, want 0.123456789", got)
+	}
+
+	steps := map[float64]int{1: 0, 10: 0, 0.5: 1, 0.001: 3, 0.00000001: 8}
+	for step, want := range steps {
+		if got := stepDecimals(step); got != want {
+			t.Errorf("stepDecimals(%v) = %d, want %d", step, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	info := &SymbolInfo{
+		Symbol:      "BTCUSDT",
+		TickSize:    0.01,
+		MinPrice:    0.01,
+		MaxPrice:    1000000,
+		StepSize:    0.001,
+		MinQty:      0.001,
+		MaxQty:      100,
+		MinNotional: 5,
+	}
+	tests := []struct {
+		name            string
+		info            *SymbolInfo
+		price, quantity float64
+		wantErr         string // Empty: valid
+	}{
+		{"valid", info, 100, 0.1, ""},
+		{"exactly min notional", info, 100, 0.05, ""},
+		{"exactly min quantity", info, 10000, 0.001, ""},
+		{"zero quantity", info, 100, 0, "must be positive"},
+		{"below min quantity", info, 10000, 0.0005, "below minimum 0.001"},
+		{"above max quantity", info, 100, 101, "above maximum 100.000"},
+		{"below min price", info, 0.001, 10, "price 0.00 below minimum 0.01"},
+		{"above max price", info, 2000000, 1, "above maximum 1000000.00"},
+		{"below min notional", info, 100, 0.04, "below minimum notional 5"},
+		{"market order skips price rules", info, 0, 0.001, ""},
+		{"no rules", &SymbolInfo{Symbol: "ETHUSDT"}, 1, 0.0001, ""},
+	}
+	for _, tt := range tests {
+		err := tt.info.Validate(tt.price, tt.quantity)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: Validate = %v, want nil", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: Validate = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package portfolio
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/internal/portfolio/wallet"
+)
+
+// quantityEpsilon ignores float noise when comparing positions with balances
+const quantityEpsilon = 1e-9
+
+// AccountSource is a real exchange account with a live user-data stream
+type AccountSource interface {
+	GetCurrentPrice(ctx context.Context, symbol string) (float64, error)
+	StreamUserData(ctx context.Context, callback func(*domain.AccountEvent)) error
+}
+
+// AccountSync mirrors a real exchange account into the wallet and portfolio managers.
+// Spot holdings become positions in <asset><quote> symbols; fills update them
+// with entry prices and realized PnL. Futures accounts report their positions
+// directly, so those are copied as is and fills only add realized PnL.
+type AccountSync struct {
+	source    AccountSource
+	wallet    *wallet.Manager
+	portfolio *Manager
+	quote     string
+	futures   bool // Set once the stream reports positions; only touched by the stream goroutine
+	done      chan struct{}
+}
+
+// NewAccountSync creates a new account sync
+func NewAccountSync(source AccountSource, wallet *wallet.Manager, portfolio *Manager, quote string) *AccountSync {
+	return &AccountSync{
+		source:    source,
+		wallet:    wallet,
+		portfolio: portfolio,
+		quote:     quote,
+		done:      make(chan struct{}),
+	}
+}
+
+// Start streams account updates in the background until ctx is done
+func (s *AccountSync) Start(ctx context.Context) {
+	go func() {
+		defer close(s.done)
+		if err := s.source.StreamUserData(ctx, func(ev *domain.AccountEvent) {
+			s.handle(ctx, ev)
+		}); err != nil {
+			log.Printf("❌ Account sync stopped: %v", err)
+		}
+	}()
+}
+
+// Wait blocks until the account stream has stopped
+func (s *AccountSync) Wait() {
+	<-s.done
+}
+
+// handle applies one user-data stream event
+func (s *AccountSync) handle(ctx context.Context, ev *domain.AccountEvent) {
+	if ev.Positions != nil {
+		s.futures = true
+	}
+
+	if ev.Snapshot {
+		s.wallet.SetBalances(ev.Balances)
+		if s.futures {
+			s.setPositions(ev.Positions, true)
+		} else {
+			s.reconcile(ctx, ev.Balances)
+		}
+		return
+	}
+
+	if len(ev.Balances) > 0 {
+		s.wallet.UpdateBalances(ev.Balances)
+	}
+	if ev.Positions != nil {
+		s.setPositions(ev.Positions, false)
+	}
+	if ev.Fill != nil {
+		if s.futures {
+			// The position itself arrives with the account update that follows
+			s.portfolio.AddRealizedPnL(ev.Fill.Symbol, ev.Fill.RealizedPnL)
+		} else {
+			s.applyFill(ev.Fill)
+		}
+	}
+	if ev.Order != nil {
+		log.Printf("Order %s %s %s %.8f: %s (filled %.8f)",
+			ev.Order.Ref(), ev.Order.Side, ev.Order.Type, ev.Order.Quantity, ev.Order.Status, ev.Order.FilledQty)
+	}
+}
+
+// applyFill updates the symbol's position with an execution.
+// Fees charged in the base asset reduce the position like Binance reduces the balance.
+func (s *AccountSync) applyFill(fill *domain.Trade) {
+	quantity := fill.Quantity
+	if fill.Side == domain.OrderSideSell {
+		quantity = -quantity
+	}
+	if base, ok := s.baseAsset(fill.Symbol); ok && fill.FeeAsset == base {
+		quantity -= fill.Fee
+	}
+
+	s.portfolio.UpdatePosition(fill.Symbol, quantity, fill.Price)
+}
+
+// setPositions copies positions reported by a futures account. A snapshot holds
+// every open position, so positions missing from it were closed.
+func (s *AccountSync) setPositions(positions []*domain.Position, snapshot bool) {
+	reported := make(map[string]bool, len(positions))
+	for _, p := range positions {
+		reported[p.Symbol] = true
+		s.portfolio.SetPosition(p.Symbol, p.Quantity, p.AvgEntryPrice, p.CurrentPrice)
+	}
+	if !snapshot {
+		return
+	}
+
+	for _, symbol := range s.portfolio.symbols() {
+		if !reported[symbol] {
+			s.portfolio.SetPosition(symbol, 0, 0, 0)
+		}
+	}
+}
+
+// reconcile sets positions to the snapshot's holdings, keeping known entry prices.
+// New holdings are entered at the current price since their cost is unknown.
+func (s *AccountSync) reconcile(ctx context.Context, balances []*domain.Balance) {
+	held := make(map[string]float64, len(balances))
+	for _, b := range balances {
+		if b.Asset != s.quote {
+			held[b.Asset+s.quote] = b.Total
+		}
+	}
+
+	// Holdings sold while disconnected
+	for _, symbol := range s.portfolio.symbols() {
+		if _, ok := held[symbol]; !ok {
+			held[symbol] = 0
+		}
+	}
+
+	for symbol, quantity := range held {
+		pos, err := s.portfolio.GetPosition(symbol)
+		if err == nil && math.Abs(pos.Quantity-quantity) < quantityEpsilon {
+			continue
+		}
+		if err != nil && quantity == 0 {
+			continue
+		}
+
+		price, err := s.source.GetCurrentPrice(ctx, symbol)
+		if err != nil {
+			// Assets without a <asset><quote> market (e.g. the quote of another pair) aren't positions
+			continue
+		}
+
+		entry := price
+		if pos != nil && pos.Quantity > 0 && quantity > 0 {
+			entry = pos.AvgEntryPrice
+		}
+		if quantity == 0 {
+			entry = 0
+		}
+		s.portfolio.SetPosition(symbol, quantity, entry, price)
+	}
+}
+
+// baseAsset returns the base asset of a <asset><quote> symbol
+func (s *AccountSync) baseAsset(symbol string) (string, bool) {
+	if !strings.HasSuffix(symbol, s.quote) || len(symbol) == len(s.quote) {
+		return "", false
+	}
+	return strings.TrimSuffix(symbol, s.quote), true
+}
@@ -0,0 +1,191 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/internal/portfolio/wallet"
+)
+
+// fakeAccountSource serves prices from a map and streams scripted events
+type fakeAccountSource struct {
+	mu     sync.Mutex
+	prices map[string]float64
+	events []*domain.AccountEvent
+}
+
+func (f *fakeAccountSource) GetCurrentPrice(ctx context.Context, symbol string) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	price, ok := f.prices[symbol]
+	if !ok {
+		return 0, fmt.Errorf("invalid symbol: %s", symbol)
+	}
+	return price, nil
+}
+
+func (f *fakeAccountSource) StreamUserData(ctx context.Context, callback func(*domain.AccountEvent)) error {
+	for _, ev := range f.events {
+		callback(ev)
+	}
+	return nil
+}
+
+func (f *fakeAccountSource) setPrice(symbol string, price float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prices[symbol] = price
+}
+
+func balance(asset string, total float64) *domain.Balance {
+	return &domain.Balance{Asset: asset, Free: total, Total: total}
+}
+
+func newTestAccountSync(source AccountSource) (*AccountSync, *wallet.Manager, *Manager) {
+	w := wallet.NewManager(nil)
+	p := NewManager()
+	return NewAccountSync(source, w, p, "USDT"), w, p
+}
+
+// checkPosition fails unless symbol's position has the quantity and entry price
+func checkPosition(t *testing.T, p *Manager, symbol string, quantity, entry float64) {
+	t.Helper()
+	pos, err := p.GetPosition(symbol)
+	if quantity == 0 {
+		if err == nil {
+			t.Errorf("%s position = %v, want none", symbol, pos.Quantity)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("GetPosition(%s): %v", symbol, err)
+	}
+	if math.Abs(pos.Quantity-quantity) > 1e-9 || math.Abs(pos.AvgEntryPrice-entry) > 1e-9 {
+		t.Errorf("%s position = %v @ %v, want %v @ %v", symbol, pos.Quantity, pos.AvgEntryPrice, quantity, entry)
+	}
+}
+
+func TestAccountSyncSpot(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeAccountSource{prices: map[string]float64{"BTCUSDT": 40000, "ETHUSDT": 2000}}
+	s, w, p := newTestAccountSync(source)
+
+	// Holdings become positions at the current price; BNB has no BNBUSDT market here
+	s.handle(ctx, &domain.AccountEvent{
+		Snapshot: true,
+		Balances: []*domain.Balance{balance("USDT", 1000), balance("BTC", 0.5), balance("BNB", 2)},
+	})
+	checkPosition(t, p, "BTCUSDT", 0.5, 40000)
+	checkPosition(t, p, "BNBUSDT", 0, 0)
+	checkPosition(t, p, "USDTUSDT", 0, 0)
+	if len(w.GetAllBalances()) != 3 {
+		t.Errorf("wallet holds %d assets, want 3", len(w.GetAllBalances()))
+	}
+
+	// A fee in the base asset shrinks the position; a fee in BNB doesn't
+	s.handle(ctx, &domain.AccountEvent{
+		Balances: []*domain.Balance{balance("ETH", 0.999), balance("USDT", 0)},
+		Fill:     &domain.Trade{Symbol: "ETHUSDT", Side: domain.OrderSideBuy, Price: 2000, Quantity: 1, Fee: 0.001, FeeAsset: "ETH"},
+	})
+	s.handle(ctx, &domain.AccountEvent{
+		Fill: &domain.Trade{Symbol: "BTCUSDT", Side: domain.OrderSideBuy, Price: 44000, Quantity: 0.5, Fee: 0.01, FeeAsset: "BNB"},
+	})
+	checkPosition(t, p, "ETHUSDT", 0.999, 2000)
+	checkPosition(t, p, "BTCUSDT", 1, 42000)
+	if _, err := w.GetBalance("USDT"); err == nil {
+		t.Error("USDT balance kept after it was spent, want dropped")
+	}
+	if b, err := w.GetBalance("ETH"); err != nil || b.Total != 0.999 {
+		t.Errorf("ETH balance = %v, %v, want 0.999", b, err)
+	}
+
+	// Reconnect: BTC grew and ETH was sold while disconnected
+	source.setPrice("BTCUSDT", 41000)
+	s.handle(ctx, &domain.AccountEvent{
+		Snapshot: true,
+		Balances: []*domain.Balance{balance("USDT", 3000), balance("BTC", 1.2), balance("BNB", 2)},
+	})
+	checkPosition(t, p, "BTCUSDT", 1.2, 42000) // Entry price kept
+	checkPosition(t, p, "ETHUSDT", 0, 0)
+	if pos := p.GetAllPositions()["BTCUSDT"]; pos.CurrentPrice != 41000 {
+		t.Errorf("BTCUSDT current price = %v, want 41000", pos.CurrentPrice)
+	}
+	if _, err := w.GetBalance("ETH"); err == nil {
+		t.Error("ETH balance kept after the snapshot, want replaced")
+	}
+
+	// An unchanged snapshot leaves positions alone
+	source.setPrice("BTCUSDT", 50000)
+	s.handle(ctx, &domain.AccountEvent{
+		Snapshot: true,
+		Balances: []*domain.Balance{balance("USDT", 3000), balance("BTC", 1.2)},
+	})
+	if pos := p.GetAllPositions()["BTCUSDT"]; pos.CurrentPrice != 41000 || pos.AvgEntryPrice != 42000 {
+		t.Errorf("BTCUSDT = %+v, want untouched", pos)
+	}
+
+	// Selling realizes PnL against the kept entry price
+	s.handle(ctx, &domain.AccountEvent{
+		Fill: &domain.Trade{Symbol: "BTCUSDT", Side: domain.OrderSideSell, Price: 43000, Quantity: 1.2, Fee: 5, FeeAsset: "USDT"},
+	})
+	checkPosition(t, p, "BTCUSDT", 0, 0)
+	if _, realized := p.CalculateTotalPnL(); math.Abs(realized-1200) > 1e-6 {
+		t.Errorf("realized PnL = %v, want 1200", realized)
+	}
+}
+
+func TestAccountSyncFutures(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeAccountSource{
+		prices: map[string]float64{"BTCUSDT": 39000},
+		events: []*domain.AccountEvent{
+			// Reported positions mark the account as futures, shorts included
+			{
+				Snapshot:  true,
+				Balances:  []*domain.Balance{balance("USDT", 1000)},
+				Positions: []*domain.Position{{Symbol: "BTCUSDT", Quantity: -0.1, AvgEntryPrice: 40000, CurrentPrice: 39000}},
+			},
+			// Fills only book realized PnL; the position arrives separately
+			{
+				Fill: &domain.Trade{Symbol: "BTCUSDT", Side: domain.OrderSideBuy, Price: 39000, Quantity: 0.05, RealizedPnL: 50},
+			},
+			{
+				Balances:  []*domain.Balance{balance("USDT", 1050)},
+				Positions: []*domain.Position{{Symbol: "BTCUSDT", Quantity: -0.05, AvgEntryPrice: 40000, CurrentPrice: 39000}},
+			},
+			{
+				Positions: []*domain.Position{{Symbol: "ETHUSDT", Quantity: 2, AvgEntryPrice: 2000, CurrentPrice: 2000}},
+			},
+			// A reconnect snapshot without BTCUSDT: it was closed while disconnected
+			{
+				Snapshot:  true,
+				Balances:  []*domain.Balance{balance("USDT", 1100)},
+				Positions: []*domain.Position{{Symbol: "ETHUSDT", Quantity: 2, AvgEntryPrice: 2000, CurrentPrice: 2100}},
+			},
+		},
+	}
+	s, w, p := newTestAccountSync(source)
+	s.Start(ctx)
+	s.Wait()
+
+	if !s.futures {
+		t.Fatal("account not detected as futures")
+	}
+	checkPosition(t, p, "BTCUSDT", 0, 0)
+	checkPosition(t, p, "ETHUSDT", 2, 2000)
+
+	positions := p.GetAllPositions()
+	if btc := positions["BTCUSDT"]; btc == nil || btc.RealizedPnL != 50 {
+		t.Errorf("BTCUSDT = %+v, want realized PnL 50 kept", btc)
+	}
+	if eth := positions["ETHUSDT"]; eth.UnrealizedPnL != 200 {
+		t.Errorf("ETHUSDT unrealized PnL = %v, want 200", eth.UnrealizedPnL)
+	}
+	if b, err := w.GetBalance("USDT"); err != nil || b.Total != 1100 {
+		t.Errorf("USDT balance = %v, %v, want 1100", b, err)
+	}
+}
@@ -34,12 +34,55 @@ func (m *Manager) GetPosition(symbol string) (*domain.Position, error) {
 	return pos, nil
 }
 
-// GetAllPositions returns all positions
+// GetAllPositions returns a copy of all positions
 func (m *Manager) GetAllPositions() map[string]*domain.Position {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return m.positions
+	// Copies, since an account sync may update positions while they are read
+	positions := make(map[string]*domain.Position, len(m.positions))
+	for symbol, p := range m.positions {
+		pos := *p
+		positions[symbol] = &pos
+	}
+	return positions
+}
+
+// SetPosition overwrites a position's size and entry price (e.g. when seeding
+// from real account balances), keeping its realized PnL
+func (m *Manager) SetPosition(symbol string, quantity, avgEntryPrice, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pos, ok := m.positions[symbol]
+	if !ok {
+		pos = &domain.Position{Symbol: symbol}
+		m.positions[symbol] = pos
+	}
+
+	pos.Quantity = quantity
+	pos.AvgEntryPrice = avgEntryPrice
+	pos.CurrentPrice = price
+	pos.UnrealizedPnL = (price - avgEntryPrice) * quantity
+	pos.UpdatedAt = time.Now().UTC()
+}
+
+// AddRealizedPnL books PnL realized outside UpdatePosition, e.g. reported by a futures exchange
+func (m *Manager) AddRealizedPnL(symbol string, pnl float64) {
+	if pnl == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pos, ok := m.positions[symbol]
+	if !ok {
+		pos = &domain.Position{Symbol: symbol}
+		m.positions[symbol] = pos
+	}
+	pos.RealizedPnL += pnl
+	pos.UpdatedAt = time.Now().UTC()
 }
 
 // UpdatePosition updates position after trade
@@ -56,7 +99,7 @@ func (m *Manager) UpdatePosition(symbol string, quantity, price float64) {
 			CurrentPrice:  price,
 			UnrealizedPnL: 0,
 			RealizedPnL:   0,
-			UpdatedAt:     time.Now(),
+			UpdatedAt:     time.Now().UTC(),
 		}
 		m.positions[symbol] = pos
 	}
@@ -99,7 +142,7 @@ func (m *Manager) UpdatePosition(symbol string, quantity, price float64) {
 	}
 
 	pos.CurrentPrice = price
-	pos.UpdatedAt = time.Now()
+	pos.UpdatedAt = time.Now().UTC()
 }
 
 // UpdatePrices updates current prices and unrealized PnL for all positions
@@ -115,11 +158,23 @@ func (m *Manager) UpdatePrices(prices map[string]float64) {
 		if price, ok := prices[symbol]; ok {
 			pos.CurrentPrice = price
 			pos.UnrealizedPnL = (price - pos.AvgEntryPrice) * pos.Quantity
-			pos.UpdatedAt = time.Now()
+			pos.UpdatedAt = time.Now().UTC()
 		}
 	}
 }
 
+// symbols returns the symbols of every tracked position
+func (m *Manager) symbols() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	symbols := make([]string, 0, len(m.positions))
+	for symbol := range m.positions {
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
 // CalculateTotalPnL calculates total profit/loss across all positions
 func (m *Manager) CalculateTotalPnL() (unrealized, realized float64) {
 	m.mu.RLock()
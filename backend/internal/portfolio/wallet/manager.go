@@ -43,12 +43,45 @@ func (m *Manager) GetBalance(asset string) (*domain.Balance, error) {
 	return balance, nil
 }
 
-// GetAllBalances returns all balances
+// GetAllBalances returns a copy of all balances
 func (m *Manager) GetAllBalances() map[string]*domain.Balance {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return m.balances
+	// Copies, since an account sync may update balances while they are read
+	balances := make(map[string]*domain.Balance, len(m.balances))
+	for asset, b := range m.balances {
+		balance := *b
+		balances[asset] = &balance
+	}
+	return balances
+}
+
+// SetBalances replaces every balance, e.g. with a snapshot of a real account
+func (m *Manager) SetBalances(balances []*domain.Balance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.balances = make(map[string]*domain.Balance, len(balances))
+	for _, b := range balances {
+		balance := *b
+		m.balances[b.Asset] = &balance
+	}
+}
+
+// UpdateBalances overwrites the given assets' balances, dropping assets that are now empty
+func (m *Manager) UpdateBalances(balances []*domain.Balance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, b := range balances {
+		if b.Total <= 0 {
+			delete(m.balances, b.Asset)
+			continue
+		}
+		balance := *b
+		m.balances[b.Asset] = &balance
+	}
 }
 
 // Lock locks a specific amount of an asset
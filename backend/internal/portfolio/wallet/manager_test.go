@@ -0,0 +1,66 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+func TestSetBalances(t *testing.T) {
+	m := NewManager(map[string]float64{"USDT": 1000, "ETH": 1})
+
+	snapshot := []*domain.Balance{
+		{Asset: "USDT", Free: 400, Locked: 100, Total: 500},
+		{Asset: "BTC", Free: 0.5, Total: 0.5},
+	}
+	m.SetBalances(snapshot)
+
+	// The snapshot replaces everything, and later changes to it don't leak in
+	snapshot[0].Free = 0
+	balances := m.GetAllBalances()
+	if len(balances) != 2 {
+		t.Fatalf("got %d balances, want 2", len(balances))
+	}
+	if _, err := m.GetBalance("ETH"); err == nil {
+		t.Error("ETH balance kept, want replaced by the snapshot")
+	}
+	if b := balances["USDT"]; b.Free != 400 || b.Locked != 100 || b.Total != 500 {
+		t.Errorf("USDT = %+v, want 400 free, 100 locked", b)
+	}
+
+	// GetAllBalances returns copies
+	balances["BTC"].Total = 99
+	if b, _ := m.GetBalance("BTC"); b.Total != 0.5 {
+		t.Errorf("BTC total = %v after editing a copy, want 0.5", b.Total)
+	}
+}
+
+func TestUpdateBalances(t *testing.T) {
+	m := NewManager(map[string]float64{"USDT": 1000, "BTC": 0.5})
+
+	m.UpdateBalances([]*domain.Balance{
+		{Asset: "USDT", Free: 0, Total: 0},               // Spent
+		{Asset: "ETH", Free: 1.5, Locked: 0.5, Total: 2}, // New
+	})
+
+	tests := []struct {
+		asset string
+		total float64 // 0: no balance
+	}{
+		{"USDT", 0},
+		{"BTC", 0.5}, // Not in the update
+		{"ETH", 2},
+	}
+	for _, tt := range tests {
+		b, err := m.GetBalance(tt.asset)
+		switch {
+		case tt.total == 0 && err == nil:
+			t.Errorf("%s balance = %v, want dropped", tt.asset, b.Total)
+		case tt.total != 0 && (err != nil || b.Total != tt.total):
+			t.Errorf("%s balance = %v, %v, want %v", tt.asset, b, err, tt.total)
+		}
+	}
+	if !m.HasSufficientBalance("ETH", 1.5) || m.HasSufficientBalance("ETH", 1.6) {
+		t.Error("ETH free balance is not 1.5")
+	}
+}
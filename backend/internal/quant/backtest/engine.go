@@ -35,12 +35,18 @@ type Engine struct {
 	strategy       Strategy
 	initialBalance float64
 	commission     float64 // Commission rate (e.g., 0.001 for 0.1%)
+	tradeFeed      TradeFeed
+	funding        []*domain.FundingRate
+	symbolInfo     *domain.SymbolInfo
 
 	// State
 	balance  float64
 	position float64 // Current position size
 	trades   []*Trade
 	equity   []EquityPoint
+	pending  *pendingOrder // Signal awaiting a fill during trade replay
+	payments []*FundingPayment
+	nextFund int // Index of the next funding event to apply
 }
 
 // Trade represents a backtesting trade
@@ -53,6 +59,8 @@ type Trade struct {
 	Balance   float64
 	Position  float64
 	Reason    string
+
+	SignalPrice float64 // Close of the bar the signal fired on (equals Price without trade replay)
 }
 
 // EquityPoint represents equity at a point in time
@@ -67,6 +75,18 @@ type Config struct {
 	InitialBalance float64
 	Commission     float64
 	Strategy       Strategy
+
+	// TradeFeed enables trade replay: signals fill against the raw trades of the
+	// following bar instead of at the signal bar's close
+	TradeFeed TradeFeed
+
+	// FundingRates (time ordered) are charged to or paid on the open position at
+	// each funding timestamp, as on a perpetual futures contract
+	FundingRates []*domain.FundingRate
+
+	// SymbolInfo (optional) rounds quantities to the lot step and limit prices to
+	// the tick size, and rejects orders below the exchange minimums
+	SymbolInfo *domain.SymbolInfo
 }
 
 // NewEngine creates a new backtesting engine
@@ -75,10 +95,14 @@ func NewEngine(cfg *Config) *Engine {
 		strategy:       cfg.Strategy,
 		initialBalance: cfg.InitialBalance,
 		commission:     cfg.Commission,
+		tradeFeed:      cfg.TradeFeed,
+		funding:        cfg.FundingRates,
+		symbolInfo:     cfg.SymbolInfo,
 		balance:        cfg.InitialBalance,
 		position:       0,
 		trades:         make([]*Trade, 0),
 		equity:         make([]EquityPoint, 0),
+		payments:       make([]*FundingPayment, 0),
 	}
 }
 
@@ -94,6 +118,20 @@ func (e *Engine) Run(ctx context.Context, candles []*domain.Candle) (*Result, er
 
 	// Process each candle
 	for i, candle := range candles {
+		// Settle funding that fell within this bar on the position held into it
+		e.applyFunding(candle)
+
+		// Fill the previous bar's signal against this bar's trades
+		if e.pending != nil {
+			done, err := e.fillPending(ctx, candle)
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				e.pending = nil
+			}
+		}
+
 		// Generate signal
 		signal, err := e.strategy.OnCandle(ctx, candle)
 		if err != nil {
@@ -101,7 +139,10 @@ func (e *Engine) Run(ctx context.Context, candles []*domain.Candle) (*Result, er
 		}
 
 		// Execute signal if present
-		if signal != nil {
+		if signal != nil && e.tradeFeed != nil {
+			// A newer signal replaces an unfilled limit order
+			e.pending = &pendingOrder{signal: signal, signalPrice: candle.Close}
+		} else if signal != nil {
 			if err := e.executeSignal(candle, signal); err != nil {
 				log.Printf("Failed to execute signal: %v", err)
 			}
@@ -127,7 +168,7 @@ func (e *Engine) Run(ctx context.Context, candles []*domain.Candle) (*Result, er
 
 // executeSignal executes a trading signal
 func (e *Engine) executeSignal(candle *domain.Candle, signal *Signal) error {
-	price := signal.Price
+	price := e.limitPrice(signal)
 	if price == 0 {
 		price = candle.Close // Market order uses close price
 	}
@@ -143,18 +184,43 @@ func (e *Engine) executeSignal(candle *domain.Candle, signal *Signal) error {
 		availableAmount = math.Floor(availableAmount*100) / 100
 		// Account for commission when calculating quantity
 		actualQuantity = availableAmount / (price * (1 + e.commission))
-		return e.executeBuy(candle.OpenTime, price, actualQuantity, signal.Reason)
+		return e.executeBuy(candle.OpenTime, price, actualQuantity, price, signal.Reason)
 	case domain.OrderSideSell:
 		// For sell: use percentage of current position
 		actualQuantity = e.position
-		return e.executeSell(candle.OpenTime, price, actualQuantity, signal.Reason)
+		return e.executeSell(candle.OpenTime, price, actualQuantity, price, signal.Reason)
 	default:
 		return fmt.Errorf("unknown order side: %s", signal.Action)
 	}
 }
 
+// limitPrice returns the signal's limit price rounded to the tick size (0 for market orders)
+func (e *Engine) limitPrice(signal *Signal) float64 {
+	if signal.Price > 0 && e.symbolInfo != nil {
+		return e.symbolInfo.RoundPrice(signal.Price)
+	}
+	return signal.Price
+}
+
+// applyRules rounds a quantity down to the lot step and validates the order
+func (e *Engine) applyRules(price, quantity float64) (float64, error) {
+	if e.symbolInfo == nil {
+		return quantity, nil
+	}
+	quantity = e.symbolInfo.RoundQuantity(quantity)
+	if err := e.symbolInfo.Validate(price, quantity); err != nil {
+		return 0, fmt.Errorf("order rejected: %w", err)
+	}
+	return quantity, nil
+}
+
 // executeBuy executes a buy order
-func (e *Engine) executeBuy(timestamp time.Time, price, quantity float64, reason string) error {
+func (e *Engine) executeBuy(timestamp time.Time, price, quantity, signalPrice float64, reason string) error {
+	quantity, err := e.applyRules(price, quantity)
+	if err != nil {
+		return err
+	}
+
 	cost := price * quantity
 	fee := cost * e.commission
 	totalCost := cost + fee
@@ -175,6 +241,8 @@ func (e *Engine) executeBuy(timestamp time.Time, price, quantity float64, reason
 		Balance:   e.balance,
 		Position:  e.position,
 		Reason:    reason,
+
+		SignalPrice: signalPrice,
 	}
 	e.trades = append(e.trades, trade)
 
@@ -185,7 +253,12 @@ func (e *Engine) executeBuy(timestamp time.Time, price, quantity float64, reason
 }
 
 // executeSell executes a sell order
-func (e *Engine) executeSell(timestamp time.Time, price, quantity float64, reason string) error {
+func (e *Engine) executeSell(timestamp time.Time, price, quantity, signalPrice float64, reason string) error {
+	quantity, err := e.applyRules(price, quantity)
+	if err != nil {
+		return err
+	}
+
 	if quantity > e.position {
 		return fmt.Errorf("insufficient position: need %.8f, have %.8f", quantity, e.position)
 	}
@@ -206,6 +279,8 @@ func (e *Engine) executeSell(timestamp time.Time, price, quantity float64, reaso
 		Balance:   e.balance,
 		Position:  e.position,
 		Reason:    reason,
+
+		SignalPrice: signalPrice,
 	}
 	e.trades = append(e.trades, trade)
 
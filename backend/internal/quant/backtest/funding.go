@@ -0,0 +1,52 @@
+package backtest
+
+import (
+	"log"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// FundingPayment is a funding settlement on the open position
+type FundingPayment struct {
+	Timestamp time.Time
+	Rate      float64
+	Position  float64
+	Price     float64 // Mark price, or the bar's open when no mark price is known
+	Amount    float64 // Positive: paid by the position; negative: received
+}
+
+// applyFunding settles every funding event up to the candle's close time on
+// the position held since the previous bar, including events that fell in a
+// gap between bars. Nothing is held before the first candle; the position is
+// long-only, so a positive rate is paid and a negative rate is received.
+func (e *Engine) applyFunding(candle *domain.Candle) {
+	for e.nextFund < len(e.funding) {
+		f := e.funding[e.nextFund]
+		if f.Time.After(candle.CloseTime) {
+			return
+		}
+		e.nextFund++
+
+		if e.position == 0 {
+			continue
+		}
+
+		price := f.MarkPrice
+		if price == 0 {
+			price = candle.Open
+		}
+		amount := e.position * price * f.Rate
+		e.balance -= amount
+
+		e.payments = append(e.payments, &FundingPayment{
+			Timestamp: f.Time,
+			Rate:      f.Rate,
+			Position:  e.position,
+			Price:     price,
+			Amount:    amount,
+		})
+		log.Printf("FUNDING: rate %.6f%% on %.8f @ %.2f = %.4f - Balance: %.2f",
+			f.Rate*100, e.position, price, -amount, e.balance)
+	}
+}
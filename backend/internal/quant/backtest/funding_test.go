@@ -0,0 +1,115 @@
+package backtest
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+var fundingStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// scriptedStrategy emits the signal scripted for each bar index
+type scriptedStrategy struct {
+	signals map[int]*Signal
+	bar     int
+}
+
+func (s *scriptedStrategy) Initialize(ctx context.Context) error { return nil }
+
+func (s *scriptedStrategy) OnCandle(ctx context.Context, candle *domain.Candle) (*Signal, error) {
+	signal := s.signals[s.bar]
+	s.bar++
+	return signal, nil
+}
+
+func (s *scriptedStrategy) Name() string { return "scripted" }
+
+// hourBar returns the hourly candle h hours after fundingStart, opening at
+// 100+10h and closing at 100
+func hourBar(h int) *domain.Candle {
+	open := fundingStart.Add(time.Duration(h) * time.Hour)
+	return &domain.Candle{
+		Symbol:    "BTCUSDT",
+		OpenTime:  open,
+		CloseTime: open.Add(time.Hour - time.Millisecond),
+		Open:      100 + 10*float64(h),
+		High:      200,
+		Low:       50,
+		Close:     100,
+		Volume:    1,
+	}
+}
+
+func TestFundingSettlement(t *testing.T) {
+	at := func(d time.Duration) time.Time { return fundingStart.Add(d) }
+	rates := []*domain.FundingRate{
+		{Time: at(0), Rate: 0.001},                                 // Before the first buy: nothing held
+		{Time: at(time.Hour), Rate: 0.001},                         // On bar 1's open
+		{Time: at(90 * time.Minute), Rate: 0.0001, MarkPrice: 105}, // Inside bar 1, at the mark price
+		{Time: at(3*time.Hour - time.Millisecond), Rate: 0.001},    // On bar 2's close time
+		{Time: at(3 * time.Hour), Rate: -0.0005},                   // In the gap, settled at bar 4's open
+		{Time: at(5 * time.Hour), Rate: 0.001},                     // After the sell: nothing held
+		{Time: at(9 * time.Hour), Rate: 0.001},                     // After the last bar
+	}
+	// No bar at hour 3
+	candles := []*domain.Candle{hourBar(0), hourBar(1), hourBar(2), hourBar(4), hourBar(5)}
+
+	engine := NewEngine(&Config{
+		InitialBalance: 1001, // Buys exactly 10 at 100
+		Strategy: &scriptedStrategy{signals: map[int]*Signal{
+			0: {Action: domain.OrderSideBuy, Quantity: 1},
+			3: {Action: domain.OrderSideSell, Quantity: 1},
+		}},
+		FundingRates: rates,
+	})
+	result, err := engine.Run(context.Background(), candles)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []FundingPayment{
+		{Timestamp: at(time.Hour), Rate: 0.001, Position: 10, Price: 110, Amount: 1.1},
+		{Timestamp: at(90 * time.Minute), Rate: 0.0001, Position: 10, Price: 105, Amount: 0.105},
+		{Timestamp: at(3*time.Hour - time.Millisecond), Rate: 0.001, Position: 10, Price: 120, Amount: 1.2},
+		{Timestamp: at(3 * time.Hour), Rate: -0.0005, Position: 10, Price: 140, Amount: -0.7},
+	}
+	if len(result.FundingPayments) != len(want) {
+		t.Fatalf("got %d funding payments, want %d", len(result.FundingPayments), len(want))
+	}
+	for i, p := range result.FundingPayments {
+		w := want[i]
+		if !p.Timestamp.Equal(w.Timestamp) || p.Rate != w.Rate || !approx(p.Position, w.Position) ||
+			p.Price != w.Price || !approx(p.Amount, w.Amount) {
+			t.Errorf("payment %d = %+v, want %+v", i, *p, w)
+		}
+	}
+
+	if !approx(result.TotalFunding, 1.705) {
+		t.Errorf("total funding = %v, want 1.705", result.TotalFunding)
+	}
+	// 1001 - 1000 for the buy - 1.705 funding + 1000 for the sell
+	if !approx(result.FinalEquity, 999.295) {
+		t.Errorf("final equity = %v, want 999.295", result.FinalEquity)
+	}
+}
+
+func TestFundingWithoutRates(t *testing.T) {
+	engine := NewEngine(&Config{
+		InitialBalance: 1001,
+		Strategy:       &scriptedStrategy{signals: map[int]*Signal{0: {Action: domain.OrderSideBuy, Quantity: 1}}},
+	})
+	result, err := engine.Run(context.Background(), []*domain.Candle{hourBar(0), hourBar(1)})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(result.FundingPayments) != 0 || result.TotalFunding != 0 {
+		t.Errorf("funding = %d payments, %v total, want none", len(result.FundingPayments), result.TotalFunding)
+	}
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
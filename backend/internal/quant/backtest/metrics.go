@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math"
 	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
 )
 
 // Result holds backtest results and performance metrics
@@ -24,14 +26,22 @@ type Result struct {
 	MaxDrawdown    float64
 	MaxDrawdownPct float64
 
+	// Execution quality: mean fill price deviation from the signal bar's close,
+	// in basis points (positive = paid more on buys / received less on sells)
+	AvgSlippageBps float64
+
+	// Net funding paid on the position (negative when funding was received)
+	TotalFunding float64
+
 	// Time metrics
 	StartTime time.Time
 	EndTime   time.Time
 	Duration  time.Duration
 
 	// Detailed data
-	Trades      []*Trade
-	EquityCurve []EquityPoint
+	Trades          []*Trade
+	EquityCurve     []EquityPoint
+	FundingPayments []*FundingPayment
 }
 
 // calculateResult computes backtest results and metrics
@@ -56,6 +66,8 @@ func (e *Engine) calculateResult() *Result {
 		EndTime:        e.equity[len(e.equity)-1].Timestamp,
 		Trades:         e.trades,
 		EquityCurve:    e.equity,
+
+		FundingPayments: e.payments,
 	}
 
 	result.Duration = result.EndTime.Sub(result.StartTime)
@@ -69,6 +81,13 @@ func (e *Engine) calculateResult() *Result {
 	// Calculate Maximum Drawdown
 	result.MaxDrawdown, result.MaxDrawdownPct = result.calculateMaxDrawdown()
 
+	// Calculate slippage against signal prices
+	result.AvgSlippageBps = result.calculateSlippage()
+
+	for _, p := range e.payments {
+		result.TotalFunding += p.Amount
+	}
+
 	return result
 }
 
@@ -190,6 +209,26 @@ func (r *Result) calculateMaxDrawdown() (float64, float64) {
 	return maxDrawdown, maxDrawdownPct
 }
 
+// calculateSlippage returns the mean signed slippage in basis points
+func (r *Result) calculateSlippage() float64 {
+	if len(r.Trades) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, t := range r.Trades {
+		if t.SignalPrice == 0 {
+			continue
+		}
+		slippage := (t.Price - t.SignalPrice) / t.SignalPrice * 10000
+		if t.Side == domain.OrderSideSell {
+			slippage = -slippage
+		}
+		total += slippage
+	}
+	return total / float64(len(r.Trades))
+}
+
 // Print prints the backtest results
 func (r *Result) Print() {
 	println("\n========== Backtest Results ==========")
@@ -202,10 +241,14 @@ func (r *Result) Print() {
 	println("  Initial Balance:", formatMoney(r.InitialBalance))
 	println("  Final Equity:   ", formatMoney(r.FinalEquity))
 	println("  Total Return:   ", formatPercent(r.TotalReturn))
+	if len(r.FundingPayments) > 0 {
+		println("  Funding Paid:   ", formatMoney(r.TotalFunding), "over", len(r.FundingPayments), "payments")
+	}
 	println()
 	println("Risk Metrics:")
 	println("  Sharpe Ratio:   ", formatFloat(r.SharpeRatio, 2))
 	println("  Max Drawdown:   ", formatMoney(r.MaxDrawdown), formatPercent(r.MaxDrawdownPct))
+	println("  Avg Slippage:   ", formatFloat(r.AvgSlippageBps, 2), "bps")
 	println()
 	println("Trade Statistics:")
 	println("  Total Trades:   ", r.TotalTrades)
@@ -0,0 +1,128 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+)
+
+// TradeFeed supplies raw trades for intrabar fill simulation
+type TradeFeed interface {
+	// Trades returns the trades with start <= time < end in time order
+	Trades(ctx context.Context, start, end time.Time) ([]*domain.AggTrade, error)
+}
+
+// TradeFeedFunc adapts a function to TradeFeed
+type TradeFeedFunc func(ctx context.Context, start, end time.Time) ([]*domain.AggTrade, error)
+
+// Trades calls f
+func (f TradeFeedFunc) Trades(ctx context.Context, start, end time.Time) ([]*domain.AggTrade, error) {
+	return f(ctx, start, end)
+}
+
+// pendingOrder is a signal waiting to be filled against the next bar's trades
+type pendingOrder struct {
+	signal      *Signal
+	signalPrice float64 // Close of the bar the signal fired on
+}
+
+// fillPending fills the pending order against the trades of candle.
+// Market orders walk the trade tape until their quantity is filled (the
+// remainder, if the bar runs out of volume, fills at the last trade price).
+// Limit orders fill at their limit price on the first trade that crosses it
+// and otherwise stay pending. Returns whether the order is done.
+func (e *Engine) fillPending(ctx context.Context, candle *domain.Candle) (bool, error) {
+	order := e.pending
+	trades, err := e.tradeFeed.Trades(ctx, candle.OpenTime, candle.CloseTime.Add(time.Millisecond))
+	if err != nil {
+		return false, fmt.Errorf("failed to load trades for %s: %w", candle.OpenTime.Format(time.RFC3339), err)
+	}
+
+	// No tape for this bar: fall back to the bar itself
+	if len(trades) == 0 {
+		trades = []*domain.AggTrade{{Time: candle.OpenTime, Price: candle.Open, Quantity: math.Inf(1)}}
+	}
+
+	if order.signal.Price > 0 {
+		return e.fillLimit(order, trades)
+	}
+	return true, e.fillMarket(order, trades)
+}
+
+// fillMarket fills a market order at the volume-weighted price of the trades it consumes
+func (e *Engine) fillMarket(order *pendingOrder, trades []*domain.AggTrade) error {
+	signal := order.signal
+	reference := trades[0].Price
+
+	var quantity float64
+	switch signal.Action {
+	case domain.OrderSideBuy:
+		availableAmount := math.Floor((e.balance*signal.Quantity-1)*100) / 100
+		quantity = availableAmount / (reference * (1 + e.commission))
+	case domain.OrderSideSell:
+		quantity = e.position
+	default:
+		return fmt.Errorf("unknown order side: %s", signal.Action)
+	}
+	if quantity <= 0 {
+		return nil
+	}
+
+	remaining := quantity
+	notional := 0.0
+	last := trades[0]
+	for _, t := range trades {
+		take := math.Min(remaining, t.Quantity)
+		notional += take * t.Price
+		remaining -= take
+		last = t
+		if remaining <= 0 {
+			break
+		}
+	}
+	if remaining > 0 {
+		notional += remaining * last.Price
+	}
+	price := notional / quantity
+
+	// A buy sized at the first trade price can exceed the balance after slippage
+	if signal.Action == domain.OrderSideBuy && quantity*price*(1+e.commission) > e.balance {
+		quantity = math.Floor(e.balance/(price*(1+e.commission))*1e8) / 1e8
+	}
+
+	if signal.Action == domain.OrderSideBuy {
+		return e.executeBuy(last.Time, price, quantity, order.signalPrice, signal.Reason)
+	}
+	return e.executeSell(last.Time, price, quantity, order.signalPrice, signal.Reason)
+}
+
+// fillLimit fills a limit order on the first trade at or through its price
+func (e *Engine) fillLimit(order *pendingOrder, trades []*domain.AggTrade) (bool, error) {
+	signal := order.signal
+	price := e.limitPrice(signal)
+	for _, t := range trades {
+		crossed := (signal.Action == domain.OrderSideBuy && t.Price <= price) ||
+			(signal.Action == domain.OrderSideSell && t.Price >= price)
+		if !crossed {
+			continue
+		}
+
+		switch signal.Action {
+		case domain.OrderSideBuy:
+			availableAmount := math.Floor((e.balance*signal.Quantity-1)*100) / 100
+			quantity := availableAmount / (price * (1 + e.commission))
+			return true, e.executeBuy(t.Time, price, quantity, order.signalPrice, signal.Reason)
+		case domain.OrderSideSell:
+			return true, e.executeSell(t.Time, price, e.position, order.signalPrice, signal.Reason)
+		default:
+			return true, fmt.Errorf("unknown order side: %s", signal.Action)
+		}
+	}
+
+	log.Printf("Limit %s @ %.2f not reached, still pending", signal.Action, price)
+	return false, nil
+}
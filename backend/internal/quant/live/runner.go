@@ -0,0 +1,648 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/datasource/market/history"
+	symbolinfo "github.com/lavumi/crypto-quant/internal/datasource/market/symbols"
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/internal/quant/backtest"
+)
+
+// feeReserve keeps part of a buy's budget back for fees and slippage
+const feeReserve = 0.002
+
+// barBuffer is how many closed bars may queue up while a strategy is busy trading
+const barBuffer = 64
+
+// Exchange is a venue the runner trades on: orders plus a kline stream
+type Exchange interface {
+	domain.Exchange
+
+	// StreamKlines streams a symbol's klines (forming and closed) until ctx is done
+	StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error
+}
+
+// BalanceSource reports account balances. Buys spend a share of the free quote
+// balance when the exchange implements it.
+type BalanceSource interface {
+	GetBalances(ctx context.Context) ([]*domain.Balance, error)
+}
+
+// Instance is a strategy trading one symbol on one interval
+type Instance struct {
+	Name       string // Used in logs and client order IDs; must be unique
+	Symbol     string
+	Interval   string
+	Strategy   backtest.Strategy
+	Allocation float64 // Most quote asset one buy may spend (0 = no limit)
+}
+
+// Config configures a live runner
+type Config struct {
+	Exchange  Exchange
+	Candles   history.CandleStore // Stored history the strategies warm up on (optional)
+	Instances []*Instance
+
+	WarmupBars int       // Closed bars fed to each strategy before trading (default 200)
+	WarmupEnd  time.Time // Bars closing after it are not history yet (default now; replays pass their start)
+
+	// PositionLookback is how far back Start looks through the order history
+	// for the instances' own orders to restore their positions (default 7 days)
+	PositionLookback time.Duration
+
+	// Backfill fetches bars missing from stored history (and from stream gaps)
+	// from the exchange. Leave it off for exchanges without real klines.
+	Backfill bool
+
+	// ReduceOnly sends sells as reduce-only orders (futures), so closing a long
+	// never opens a short
+	ReduceOnly bool
+}
+
+// Runner runs strategies on live klines. Like the backtest engine, a BUY
+// spends the signal's share of the available quote balance and a SELL closes
+// the instance's position; each instance only sells what it bought itself.
+// Orders carry a client order ID prefix per instance, so a restarted runner
+// restores positions from the exchange's order history.
+type Runner struct {
+	cfg       Config
+	instances []*instance
+	wg        sync.WaitGroup
+}
+
+// instance is an Instance's trading state. The worker goroutine is the only
+// writer; mu guards the fields Status reads.
+type instance struct {
+	*Instance
+	interval    time.Duration
+	base, quote string
+	idPrefix    string // Client order ID prefix of the instance's orders
+	bars        chan *domain.Candle
+	warmedUntil time.Time // Open time of the last warm-up bar
+
+	mu         sync.Mutex
+	position   float64       // Base asset bought by this instance and not sold yet
+	pending    *domain.Order // Open order whose fills are still being tracked
+	lastBar    time.Time     // Open time of the newest bar fed to the strategy
+	lastSignal string
+}
+
+// Status is a snapshot of an instance's state
+type Status struct {
+	Name       string
+	Strategy   string
+	Symbol     string
+	Interval   string
+	Position   float64
+	Pending    string // Open order reference, if any
+	LastBar    time.Time
+	LastSignal string
+}
+
+// NewRunner creates a new live runner
+func NewRunner(cfg Config) (*Runner, error) {
+	if cfg.Exchange == nil {
+		return nil, fmt.Errorf("runner needs an exchange")
+	}
+	if cfg.WarmupBars <= 0 {
+		cfg.WarmupBars = 200
+	}
+	if cfg.PositionLookback <= 0 {
+		cfg.PositionLookback = 7 * 24 * time.Hour
+	}
+
+	r := &Runner{cfg: cfg}
+	names := make(map[string]bool, len(cfg.Instances))
+	for _, inst := range cfg.Instances {
+		if inst.Strategy == nil || inst.Symbol == "" {
+			return nil, fmt.Errorf("instance %q needs a strategy and a symbol", inst.Name)
+		}
+		if names[inst.Name] {
+			return nil, fmt.Errorf("duplicate instance name: %q", inst.Name)
+		}
+		names[inst.Name] = true
+
+		interval, err := history.ParseInterval(inst.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("instance %q: %w", inst.Name, err)
+		}
+		base := symbolinfo.SplitSymbol(inst.Symbol)
+		r.instances = append(r.instances, &instance{
+			Instance: inst,
+			interval: interval,
+			base:     base,
+			quote:    strings.TrimPrefix(inst.Symbol, base),
+			idPrefix: domain.ClientOrderIDPrefixFor(inst.Name),
+			bars:     make(chan *domain.Candle, barBuffer),
+		})
+	}
+	return r, nil
+}
+
+// Start restores every instance's position, warms its strategy up on stored
+// history, then trades closed bars from the kline streams in the background
+// until ctx is done
+func (r *Runner) Start(ctx context.Context) error {
+	for _, in := range r.instances {
+		if err := r.restore(ctx, in); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", in.Name, err)
+		}
+		if err := r.warmUp(ctx, in); err != nil {
+			return fmt.Errorf("failed to warm up %s: %w", in.Name, err)
+		}
+	}
+
+	for _, in := range r.instances {
+		r.wg.Add(2)
+		go func(in *instance) {
+			defer r.wg.Done()
+			r.stream(ctx, in)
+		}(in)
+		go func(in *instance) {
+			defer r.wg.Done()
+			r.work(ctx, in)
+		}(in)
+		log.Printf("✅ Strategy %s trading %s %s", in.Name, in.Symbol, in.Interval)
+	}
+	return nil
+}
+
+// Wait blocks until the streams and workers have stopped
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Status returns every instance's state
+func (r *Runner) Status() []Status {
+	statuses := make([]Status, 0, len(r.instances))
+	for _, in := range r.instances {
+		in.mu.Lock()
+		s := Status{
+			Name:       in.Name,
+			Strategy:   in.Strategy.Name(),
+			Symbol:     in.Symbol,
+			Interval:   in.Interval,
+			Position:   in.position,
+			LastBar:    in.lastBar,
+			LastSignal: in.lastSignal,
+		}
+		if in.pending != nil {
+			s.Pending = in.pending.Ref().String()
+		}
+		in.mu.Unlock()
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// Flatten cancels the instances' open orders and sells their positions at
+// market. Call it after Wait, once no bars are being traded.
+func (r *Runner) Flatten(ctx context.Context) error {
+	var errs []error
+	for _, in := range r.instances {
+		if err := r.cancelPending(ctx, in); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", in.Name, err))
+		}
+		if in.position <= 0 {
+			continue
+		}
+
+		log.Printf("Flattening %s: selling %.8f %s", in.Name, in.position, in.base)
+		key := in.Name + "|flatten|" + time.Now().UTC().Format(time.RFC3339)
+		if err := r.sell(ctx, in, domain.OrderTypeMarket, 0, key); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", in.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// restore seeds the instance's position from its own orders in the order
+// history, capped by what the account still holds, and resumes tracking an
+// order left open by the last run. Positions older than the lookback are not found.
+func (r *Runner) restore(ctx context.Context, in *instance) error {
+	end := time.Now().UTC()
+	orders, err := r.cfg.Exchange.GetOrderHistory(ctx, in.Symbol, end.Add(-r.cfg.PositionLookback), end, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load order history: %w", err)
+	}
+
+	var position float64
+	var open *domain.Order
+	for _, order := range orders {
+		if !domain.HasClientOrderIDPrefix(order.ClientOrderID, in.idPrefix) {
+			continue
+		}
+		if order.Side == domain.OrderSideBuy {
+			position += order.FilledQty
+		} else {
+			position = max(position-order.FilledQty, 0)
+		}
+		if order.IsOpen() {
+			open = order
+		}
+	}
+	if held, ok := r.held(ctx, in); ok && held < position {
+		position = held
+	}
+
+	in.mu.Lock()
+	in.position = position
+	in.pending = open
+	in.mu.Unlock()
+	if position > 0 || open != nil {
+		log.Printf("%s restored a %.8f %s position from the order history", in.Name, position, in.base)
+	}
+	if open != nil {
+		log.Printf("%s resumed tracking open order %s", in.Name, open.Ref())
+	}
+	return nil
+}
+
+// held returns how much of the base asset the account holds: the long
+// position on futures, or the base balance (free and locked) on spot
+func (r *Runner) held(ctx context.Context, in *instance) (float64, bool) {
+	if r.cfg.ReduceOnly {
+		futures, ok := r.cfg.Exchange.(domain.FuturesExchange)
+		if !ok {
+			return 0, false
+		}
+		positions, err := futures.GetPositionRisk(ctx, in.Symbol)
+		if err != nil {
+			log.Printf("⚠️  Failed to get %s position: %v", in.Symbol, err)
+			return 0, false
+		}
+		var long float64
+		for _, p := range positions {
+			long += max(p.Quantity, 0)
+		}
+		return long, true
+	}
+
+	balance, ok := r.balance(ctx, in.base)
+	if !ok {
+		return 0, false
+	}
+	return balance.Free + balance.Locked, true
+}
+
+// warmUp feeds the most recent closed bars to the strategy, discarding its signals
+func (r *Runner) warmUp(ctx context.Context, in *instance) error {
+	end := r.cfg.WarmupEnd
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	start := end.Add(-time.Duration(r.cfg.WarmupBars) * in.interval)
+
+	var bars []*domain.Candle
+	if r.cfg.Candles != nil {
+		stored, err := r.cfg.Candles.GetRange(ctx, in.Symbol, in.Interval, start, end)
+		if err != nil {
+			return fmt.Errorf("failed to load history: %w", err)
+		}
+		bars = stored
+	}
+
+	// Top up from the exchange when stored history stops short (e.g. no syncer running)
+	if r.cfg.Backfill && (len(bars) == 0 || bars[len(bars)-1].OpenTime.Add(2*in.interval).Before(end)) {
+		recent, err := r.cfg.Exchange.GetCandles(ctx, in.Symbol, in.Interval, r.cfg.WarmupBars+1)
+		if err != nil {
+			log.Printf("⚠️  %s: failed to fetch recent candles: %v", in.Name, err)
+		} else {
+			bars = appendNewer(bars, recent)
+		}
+	}
+	bars = closedBefore(bars, in.interval, end)
+	if len(bars) > r.cfg.WarmupBars {
+		bars = bars[len(bars)-r.cfg.WarmupBars:]
+	}
+
+	if err := in.Strategy.Initialize(ctx); err != nil {
+		return fmt.Errorf("failed to initialize strategy: %w", err)
+	}
+	for _, bar := range bars {
+		if _, err := in.Strategy.OnCandle(ctx, bar); err != nil {
+			return fmt.Errorf("strategy error on warm-up bar %s: %w", bar.OpenTime.Format(time.RFC3339), err)
+		}
+	}
+
+	if len(bars) > 0 {
+		in.warmedUntil = bars[len(bars)-1].OpenTime
+		in.lastBar = in.warmedUntil
+	}
+	if len(bars) < r.cfg.WarmupBars {
+		log.Printf("⚠️  %s warmed up on %d of %d bars", in.Name, len(bars), r.cfg.WarmupBars)
+	} else {
+		log.Printf("%s warmed up on %d bars up to %s", in.Name, len(bars), in.warmedUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// stream forwards closed bars to the worker. Streams deliver the forming bar
+// on every update; a bar is closed once a kline with a later open time arrives.
+func (r *Runner) stream(ctx context.Context, in *instance) {
+	var forming *domain.Candle
+	err := r.cfg.Exchange.StreamKlines(ctx, in.Symbol, in.Interval, func(candle *domain.Candle) {
+		if forming != nil && candle.OpenTime.Before(forming.OpenTime) {
+			return
+		}
+		if forming != nil && candle.OpenTime.After(forming.OpenTime) && forming.OpenTime.After(in.warmedUntil) {
+			select {
+			case in.bars <- forming:
+			case <-ctx.Done():
+			}
+		}
+		c := *candle
+		forming = &c
+	})
+	if err != nil {
+		log.Printf("❌ %s kline stream stopped: %v", in.Name, err)
+	}
+}
+
+// work trades the closed bars until ctx is done
+func (r *Runner) work(ctx context.Context, in *instance) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case bar := <-in.bars:
+			r.onBar(ctx, in, bar)
+		}
+	}
+}
+
+// onBar feeds a closed bar to the strategy and trades its signal
+func (r *Runner) onBar(ctx context.Context, in *instance, bar *domain.Candle) {
+	if !in.lastBar.IsZero() && !bar.OpenTime.After(in.lastBar) {
+		return
+	}
+	if !in.lastBar.IsZero() && bar.OpenTime.After(in.lastBar.Add(in.interval)) {
+		r.catchUp(ctx, in, bar.OpenTime)
+	}
+	if err := r.syncPending(ctx, in); err != nil {
+		log.Printf("⚠️  %s: %v", in.Name, err)
+	}
+
+	signal, err := in.Strategy.OnCandle(ctx, bar)
+	in.mu.Lock()
+	in.lastBar = bar.OpenTime
+	in.mu.Unlock()
+	if err != nil {
+		log.Printf("❌ %s strategy error on %s: %v", in.Name, bar.OpenTime.Format(time.RFC3339), err)
+		return
+	}
+	if signal == nil {
+		return
+	}
+
+	log.Printf("%s %s signal on %s bar @ %.2f: %s", in.Name, signal.Action, bar.OpenTime.Format(time.RFC3339), bar.Close, signal.Reason)
+	in.mu.Lock()
+	in.lastSignal = fmt.Sprintf("%s %s", signal.Action, bar.OpenTime.Format("2006-01-02 15:04"))
+	in.mu.Unlock()
+
+	if err := r.execute(ctx, in, bar, signal); err != nil {
+		log.Printf("❌ %s: %v", in.Name, err)
+	}
+}
+
+// catchUp feeds bars the stream skipped (e.g. while reconnecting) to the
+// strategy without trading them, since their signals are stale
+func (r *Runner) catchUp(ctx context.Context, in *instance, next time.Time) {
+	missed := int(next.Sub(in.lastBar)/in.interval) - 1
+	if !r.cfg.Backfill {
+		log.Printf("⚠️  %s skipped %d bars before %s", in.Name, missed, next.Format(time.RFC3339))
+		return
+	}
+
+	recent, err := r.cfg.Exchange.GetCandles(ctx, in.Symbol, in.Interval, missed+2)
+	if err != nil {
+		log.Printf("⚠️  %s: failed to fetch %d missed bars: %v", in.Name, missed, err)
+		return
+	}
+	fed := 0
+	for _, bar := range recent {
+		if !bar.OpenTime.After(in.lastBar) || !bar.OpenTime.Before(next) {
+			continue
+		}
+		if _, err := in.Strategy.OnCandle(ctx, bar); err != nil {
+			log.Printf("❌ %s strategy error on %s: %v", in.Name, bar.OpenTime.Format(time.RFC3339), err)
+		}
+		in.mu.Lock()
+		in.lastBar = bar.OpenTime
+		in.mu.Unlock()
+		fed++
+	}
+	log.Printf("⚠️  %s caught up on %d of %d missed bars without trading", in.Name, fed, missed)
+}
+
+// execute turns a signal into an order. A new signal replaces an unfilled order.
+func (r *Runner) execute(ctx context.Context, in *instance, bar *domain.Candle, signal *backtest.Signal) error {
+	if err := r.cancelPending(ctx, in); err != nil {
+		return err
+	}
+
+	orderType := domain.OrderTypeMarket
+	if signal.Price > 0 {
+		orderType = domain.OrderTypeLimit
+	}
+	key := in.Name + "|" + bar.OpenTime.Format(time.RFC3339)
+
+	switch signal.Action {
+	case domain.OrderSideBuy:
+		budget, err := r.buyBudget(ctx, in)
+		if err != nil {
+			return err
+		}
+		price := signal.Price
+		if price == 0 {
+			price = bar.Close
+		}
+		quantity := budget * min(signal.Quantity, 1) / (price * (1 + feeReserve))
+		if quantity <= 0 {
+			return fmt.Errorf("no %s available to buy %s", in.quote, in.Symbol)
+		}
+		return r.place(ctx, in, &domain.Order{
+			Symbol:   in.Symbol,
+			Side:     domain.OrderSideBuy,
+			Type:     orderType,
+			Quantity: quantity,
+			Price:    signal.Price,
+		}, key)
+	case domain.OrderSideSell:
+		return r.sell(ctx, in, orderType, signal.Price, key)
+	default:
+		return fmt.Errorf("unknown order side: %s", signal.Action)
+	}
+}
+
+// sell closes the instance's position
+func (r *Runner) sell(ctx context.Context, in *instance, orderType domain.OrderType, price float64, key string) error {
+	quantity := in.position
+	if !r.cfg.ReduceOnly {
+		// Fees charged in the base asset leave less than was bought
+		if free, ok := r.freeBalance(ctx, in.base); ok && free < quantity {
+			quantity = free
+			in.mu.Lock()
+			in.position = free
+			in.mu.Unlock()
+		}
+	}
+	if quantity <= 0 {
+		log.Printf("%s has no %s position to sell", in.Name, in.base)
+		return nil
+	}
+
+	return r.place(ctx, in, &domain.Order{
+		Symbol:     in.Symbol,
+		Side:       domain.OrderSideSell,
+		Type:       orderType,
+		Quantity:   quantity,
+		Price:      price,
+		ReduceOnly: r.cfg.ReduceOnly,
+	}, key)
+}
+
+// place submits an order under a client order ID derived from key, so a retry
+// of the same signal never trades twice
+func (r *Runner) place(ctx context.Context, in *instance, order *domain.Order, key string) error {
+	order.ClientOrderID = domain.NewSourceClientOrderID(in.Name, key, order)
+	placed, err := r.cfg.Exchange.PlaceOrder(ctx, order)
+	if err != nil {
+		return fmt.Errorf("failed to place %s %s order: %w", order.Side, order.Symbol, err)
+	}
+	r.track(in, placed)
+	return nil
+}
+
+// buyBudget returns the quote asset a buy may spend: the free balance, capped
+// by the allocation
+func (r *Runner) buyBudget(ctx context.Context, in *instance) (float64, error) {
+	free, ok := r.freeBalance(ctx, in.quote)
+	switch {
+	case ok && in.Allocation > 0:
+		return min(free, in.Allocation), nil
+	case ok:
+		return free, nil
+	case in.Allocation > 0:
+		return in.Allocation, nil
+	default:
+		return 0, fmt.Errorf("no %s balance available: set an allocation for %s", in.quote, in.Name)
+	}
+}
+
+// freeBalance returns an asset's free balance, if the exchange reports balances
+func (r *Runner) freeBalance(ctx context.Context, asset string) (float64, bool) {
+	balance, ok := r.balance(ctx, asset)
+	if !ok {
+		return 0, false
+	}
+	return balance.Free, true
+}
+
+// balance returns an asset's balance (zero if the account has none), if the
+// exchange reports balances
+func (r *Runner) balance(ctx context.Context, asset string) (domain.Balance, bool) {
+	source, ok := r.cfg.Exchange.(BalanceSource)
+	if !ok || asset == "" {
+		return domain.Balance{}, false
+	}
+	balances, err := source.GetBalances(ctx)
+	if err != nil {
+		log.Printf("⚠️  Failed to get balances: %v", err)
+		return domain.Balance{}, false
+	}
+	for _, b := range balances {
+		if b.Asset == asset {
+			return *b, true
+		}
+	}
+	return domain.Balance{Asset: asset}, true
+}
+
+// syncPending refreshes the open order's fills
+func (r *Runner) syncPending(ctx context.Context, in *instance) error {
+	if in.pending == nil {
+		return nil
+	}
+	order, err := r.cfg.Exchange.GetOrder(ctx, in.pending.Ref())
+	if err != nil {
+		return fmt.Errorf("failed to get order %s: %w", in.pending.Ref(), err)
+	}
+	r.track(in, order)
+	return nil
+}
+
+// cancelPending cancels the open order, keeping whatever it filled
+func (r *Runner) cancelPending(ctx context.Context, in *instance) error {
+	if in.pending == nil {
+		return nil
+	}
+	order, err := r.cfg.Exchange.CancelOrder(ctx, in.pending.Ref())
+	if err != nil {
+		// It may have filled in the meantime
+		if syncErr := r.syncPending(ctx, in); syncErr == nil && in.pending == nil {
+			return nil
+		}
+		return fmt.Errorf("failed to cancel order %s: %w", in.pending.Ref(), err)
+	}
+	r.track(in, order)
+	return nil
+}
+
+// track applies an order's new fills to the position and keeps the order
+// pending while it is open
+func (r *Runner) track(in *instance, order *domain.Order) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	var prev float64
+	if in.pending != nil && in.pending.Ref() == order.Ref() {
+		prev = in.pending.FilledQty
+	}
+	if filled := order.FilledQty - prev; filled > 0 {
+		if order.Side == domain.OrderSideBuy {
+			in.position += filled
+		} else {
+			in.position = max(in.position-filled, 0)
+		}
+		log.Printf("✅ %s %s %.8f %s @ %.2f (position %.8f)",
+			in.Name, order.Side, filled, in.base, order.AvgPrice, in.position)
+	}
+
+	in.pending = nil
+	if order.IsOpen() {
+		in.pending = order
+	} else if order.Status != domain.OrderStatusFilled {
+		log.Printf("⚠️  %s order %s %s", in.Name, order.Ref(), order.Status)
+	}
+}
+
+// appendNewer appends the candles opening after the last one in bars
+func appendNewer(bars, candles []*domain.Candle) []*domain.Candle {
+	var last time.Time
+	if len(bars) > 0 {
+		last = bars[len(bars)-1].OpenTime
+	}
+	for _, c := range candles {
+		if c.OpenTime.After(last) {
+			bars = append(bars, c)
+			last = c.OpenTime
+		}
+	}
+	return bars
+}
+
+// closedBefore drops the bars still forming at end
+func closedBefore(bars []*domain.Candle, interval time.Duration, end time.Time) []*domain.Candle {
+	for len(bars) > 0 && bars[len(bars)-1].OpenTime.Add(interval).After(end) {
+		bars = bars[:len(bars)-1]
+	}
+	return bars
+}
@@ -0,0 +1,477 @@
+package live
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/domain"
+	"github.com/lavumi/crypto-quant/internal/quant/backtest"
+)
+
+var runnerStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// at returns the open time of the i-th 1m bar
+func at(i int) time.Time {
+	return runnerStart.Add(time.Duration(i) * time.Minute)
+}
+
+func bar(i int, price float64) *domain.Candle {
+	return &domain.Candle{
+		Symbol:    "BTCUSDT",
+		OpenTime:  at(i),
+		CloseTime: at(i + 1).Add(-time.Millisecond),
+		Open:      price,
+		High:      price,
+		Low:       price,
+		Close:     price,
+		Volume:    1,
+	}
+}
+
+func bars(from, to int) []*domain.Candle {
+	candles := make([]*domain.Candle, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		candles = append(candles, bar(i, 100))
+	}
+	return candles
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// fakeExchange fills market orders at price, rests limit orders until fill
+// is called and streams the klines sent on klines. Orders are returned as
+// copies, like a venue answering each request.
+type fakeExchange struct {
+	klines chan *domain.Candle
+	placed chan *domain.Order
+
+	mu       sync.Mutex
+	price    float64
+	candles  []*domain.Candle
+	balances map[string]float64
+	history  []*domain.Order // Orders of earlier runs
+	orders   []*domain.Order
+}
+
+func newFakeExchange(balances map[string]float64) *fakeExchange {
+	return &fakeExchange{
+		klines:   make(chan *domain.Candle, 16),
+		placed:   make(chan *domain.Order, 16),
+		price:    100,
+		candles:  bars(0, 4),
+		balances: balances,
+	}
+}
+
+func (f *fakeExchange) find(ref domain.OrderRef) *domain.Order {
+	for _, o := range append(f.history, f.orders...) {
+		if (ref.OrderID != "" && o.ID == ref.OrderID) || (ref.OrderID == "" && o.ClientOrderID == ref.ClientOrderID) {
+			return o
+		}
+	}
+	return nil
+}
+
+// fillLocked fills qty of an order at price; the caller holds f.mu
+func (f *fakeExchange) fillLocked(o *domain.Order, qty, price float64) {
+	o.AvgPrice = (o.AvgPrice*o.FilledQty + price*qty) / (o.FilledQty + qty)
+	o.FilledQty += qty
+	o.Status = domain.OrderStatusPartiallyFilled
+	if o.FilledQty >= o.Quantity {
+		o.Status = domain.OrderStatusFilled
+	}
+	if o.Side == domain.OrderSideBuy {
+		f.balances["BTC"] += qty
+		f.balances["USDT"] -= qty * price
+	} else {
+		f.balances["BTC"] -= qty
+		f.balances["USDT"] += qty * price
+	}
+}
+
+// fill fills qty of a resting limit order at its price
+func (f *fakeExchange) fill(id string, qty float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o := f.find(domain.OrderRef{OrderID: id})
+	f.fillLocked(o, qty, o.Price)
+}
+
+func (f *fakeExchange) setCandles(candles []*domain.Candle) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.candles = candles
+}
+
+func (f *fakeExchange) placedOrders() []domain.Order {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	orders := make([]domain.Order, 0, len(f.orders))
+	for _, o := range f.orders {
+		orders = append(orders, *o)
+	}
+	return orders
+}
+
+func (f *fakeExchange) PlaceOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if existing := f.find(domain.OrderRef{ClientOrderID: order.ClientOrderID}); existing != nil {
+		o := *existing
+		return &o, nil
+	}
+
+	o := *order
+	o.ID = fmt.Sprint(len(f.orders) + 1)
+	o.Status = domain.OrderStatusNew
+	if o.Type == domain.OrderTypeMarket {
+		f.fillLocked(&o, o.Quantity, f.price)
+	}
+	f.orders = append(f.orders, &o)
+	placed := o
+	f.placed <- &placed
+	result := o
+	return &result, nil
+}
+
+func (f *fakeExchange) CancelOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o := f.find(ref)
+	if o == nil {
+		return nil, fmt.Errorf("order %s does not exist", ref)
+	}
+	if o.IsOpen() {
+		o.Status = domain.OrderStatusCancelled
+	}
+	result := *o
+	return &result, nil
+}
+
+func (f *fakeExchange) GetOrder(ctx context.Context, ref domain.OrderRef) (*domain.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o := f.find(ref)
+	if o == nil {
+		return nil, fmt.Errorf("order %s does not exist", ref)
+	}
+	result := *o
+	return &result, nil
+}
+
+func (f *fakeExchange) GetOpenOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	return nil, nil
+}
+
+func (f *fakeExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*domain.Order, error) {
+	return nil, nil
+}
+
+func (f *fakeExchange) GetOrderHistory(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	orders := make([]*domain.Order, 0, len(f.history)+len(f.orders))
+	for _, o := range append(f.history, f.orders...) {
+		result := *o
+		orders = append(orders, &result)
+	}
+	return orders, nil
+}
+
+func (f *fakeExchange) GetMyTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	return nil, nil
+}
+
+func (f *fakeExchange) GetCurrentPrice(ctx context.Context, symbol string) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.price, nil
+}
+
+func (f *fakeExchange) GetCandles(ctx context.Context, symbol, interval string, limit int) ([]*domain.Candle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.candles[max(len(f.candles)-limit, 0):], nil
+}
+
+func (f *fakeExchange) GetBalances(ctx context.Context) ([]*domain.Balance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	balances := make([]*domain.Balance, 0, len(f.balances))
+	for asset, free := range f.balances {
+		balances = append(balances, &domain.Balance{Asset: asset, Free: free, Total: free})
+	}
+	return balances, nil
+}
+
+func (f *fakeExchange) StreamKlines(ctx context.Context, symbol, interval string, callback func(*domain.Candle)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case candle := <-f.klines:
+			callback(candle)
+		}
+	}
+}
+
+func (f *fakeExchange) Close() error {
+	return nil
+}
+
+// scriptedStrategy emits the signals scripted per bar open time and reports
+// every bar it sees on seen
+type scriptedStrategy struct {
+	signals map[time.Time]*backtest.Signal
+	seen    chan *domain.Candle
+}
+
+func newScriptedStrategy(signals map[time.Time]*backtest.Signal) *scriptedStrategy {
+	return &scriptedStrategy{signals: signals, seen: make(chan *domain.Candle, 64)}
+}
+
+func (s *scriptedStrategy) Initialize(ctx context.Context) error {
+	return nil
+}
+
+func (s *scriptedStrategy) OnCandle(ctx context.Context, candle *domain.Candle) (*backtest.Signal, error) {
+	s.seen <- candle
+	return s.signals[candle.OpenTime], nil
+}
+
+func (s *scriptedStrategy) Name() string {
+	return "scripted"
+}
+
+// waitFor reads the bars the strategy saw up to the one opening at open
+func (s *scriptedStrategy) waitFor(t *testing.T, open time.Time) []*domain.Candle {
+	t.Helper()
+	var seen []*domain.Candle
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case candle := <-s.seen:
+			seen = append(seen, candle)
+			if candle.OpenTime.Equal(open) {
+				return seen
+			}
+		case <-timeout:
+			t.Fatalf("strategy never saw the %s bar (saw %d bars)", open.Format(time.RFC3339), len(seen))
+		}
+	}
+}
+
+// startRunner starts a runner warmed up on bars 0-4 from the exchange
+func startRunner(t *testing.T, ex *fakeExchange, strategy *scriptedStrategy, allocation float64) (*Runner, context.CancelFunc) {
+	t.Helper()
+	r, err := NewRunner(Config{
+		Exchange: ex,
+		Instances: []*Instance{{
+			Name:       "test",
+			Symbol:     "BTCUSDT",
+			Interval:   "1m",
+			Strategy:   strategy,
+			Allocation: allocation,
+		}},
+		WarmupBars: 5,
+		WarmupEnd:  at(5),
+		Backfill:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := r.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		r.Wait()
+	})
+	return r, cancel
+}
+
+// openTimes returns the bar indexes of candles
+func openTimes(candles []*domain.Candle) []int {
+	indexes := make([]int, 0, len(candles))
+	for _, c := range candles {
+		indexes = append(indexes, int(c.OpenTime.Sub(runnerStart)/time.Minute))
+	}
+	return indexes
+}
+
+func TestRunnerTradesClosedBars(t *testing.T) {
+	ex := newFakeExchange(map[string]float64{"USDT": 1000})
+	strategy := newScriptedStrategy(map[time.Time]*backtest.Signal{
+		at(2): {Action: domain.OrderSideBuy, Quantity: 1}, // Warm-up signals are not traded
+		at(5): {Action: domain.OrderSideBuy, Quantity: 1},
+		at(6): {Action: domain.OrderSideSell, Quantity: 1},
+	})
+	ex.price = 101
+	r, cancel := startRunner(t, ex, strategy, 0)
+
+	if got := openTimes(strategy.waitFor(t, at(4))); fmt.Sprint(got) != "[0 1 2 3 4]" {
+		t.Fatalf("warm-up bars = %v, want [0 1 2 3 4]", got)
+	}
+
+	// Bar 3 was already warmed up on; bar 5 only closes once bar 6 opens
+	ex.klines <- bar(3, 100)
+	ex.klines <- bar(5, 100)
+	ex.klines <- bar(5, 101)
+	ex.klines <- bar(6, 101)
+	ex.klines <- bar(7, 101)
+	ex.klines <- bar(8, 101)
+	seen := strategy.waitFor(t, at(7))
+	if got := openTimes(seen); fmt.Sprint(got) != "[5 6 7]" {
+		t.Errorf("traded bars = %v, want [5 6 7]", got)
+	}
+	if seen[0].Close != 101 {
+		t.Errorf("bar 5 close = %v, want its last update 101", seen[0].Close)
+	}
+	cancel()
+	r.Wait()
+
+	orders := ex.placedOrders()
+	if len(orders) != 2 {
+		t.Fatalf("placed %d orders, want a buy and a sell", len(orders))
+	}
+	buy, sell := orders[0], orders[1]
+	want := 1000 / (101 * (1 + feeReserve))
+	if buy.Side != domain.OrderSideBuy || buy.Type != domain.OrderTypeMarket || !approx(buy.Quantity, want) {
+		t.Errorf("buy = %s %s %v, want BUY MARKET %v", buy.Side, buy.Type, buy.Quantity, want)
+	}
+	if sell.Side != domain.OrderSideSell || sell.Quantity != buy.FilledQty {
+		t.Errorf("sell = %s %v, want SELL %v", sell.Side, sell.Quantity, buy.FilledQty)
+	}
+	prefix := domain.ClientOrderIDPrefixFor("test")
+	for _, o := range orders {
+		if !strings.HasPrefix(o.ClientOrderID, prefix) {
+			t.Errorf("client order ID %s lacks the instance prefix %s", o.ClientOrderID, prefix)
+		}
+	}
+
+	status := r.Status()[0]
+	if status.Position != 0 || status.Pending != "" || !status.LastBar.Equal(at(7)) {
+		t.Errorf("status = position %v pending %q last bar %s, want flat at %s", status.Position, status.Pending, status.LastBar, at(7))
+	}
+}
+
+func TestRunnerCatchesUpMissedBars(t *testing.T) {
+	ex := newFakeExchange(map[string]float64{"USDT": 1000})
+	strategy := newScriptedStrategy(map[time.Time]*backtest.Signal{
+		at(7): {Action: domain.OrderSideBuy, Quantity: 1}, // Stale by the time it is caught up on
+	})
+	r, cancel := startRunner(t, ex, strategy, 0)
+	strategy.waitFor(t, at(4))
+
+	// The stream skips bars 6-8, which the exchange has by now
+	ex.setCandles(bars(0, 10))
+	ex.klines <- bar(5, 100)
+	ex.klines <- bar(9, 100)
+	ex.klines <- bar(10, 100)
+	if got := openTimes(strategy.waitFor(t, at(9))); fmt.Sprint(got) != "[5 6 7 8 9]" {
+		t.Errorf("bars = %v, want [5 6 7 8 9]", got)
+	}
+	cancel()
+	r.Wait()
+
+	if orders := ex.placedOrders(); len(orders) != 0 {
+		t.Errorf("placed %d orders, want none for caught-up bars", len(orders))
+	}
+	if status := r.Status()[0]; !status.LastBar.Equal(at(9)) {
+		t.Errorf("last bar = %s, want %s", status.LastBar, at(9))
+	}
+}
+
+func TestRunnerTracksPendingOrderAndFlattens(t *testing.T) {
+	ex := newFakeExchange(map[string]float64{"USDT": 1000})
+	strategy := newScriptedStrategy(map[time.Time]*backtest.Signal{
+		at(5): {Action: domain.OrderSideBuy, Quantity: 1, Price: 99},
+	})
+	r, cancel := startRunner(t, ex, strategy, 500)
+	strategy.waitFor(t, at(4))
+
+	ex.klines <- bar(5, 100)
+	ex.klines <- bar(6, 100)
+	var limit *domain.Order
+	select {
+	case limit = <-ex.placed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no order placed for the bar 5 signal")
+	}
+	want := 500 / (99 * (1 + feeReserve))
+	if limit.Type != domain.OrderTypeLimit || limit.Price != 99 || !approx(limit.Quantity, want) {
+		t.Errorf("order = %s %v @ %v, want LIMIT %v @ 99 (the allocation)", limit.Type, limit.Quantity, limit.Price, want)
+	}
+
+	// Fills are picked up before the next bar is traded
+	ex.fill(limit.ID, limit.Quantity/2)
+	ex.klines <- bar(7, 100)
+	strategy.waitFor(t, at(6))
+	status := r.Status()[0]
+	if !approx(status.Position, limit.Quantity/2) || status.Pending != limit.Ref().String() {
+		t.Errorf("status = position %v pending %q, want %v pending %s", status.Position, status.Pending, limit.Quantity/2, limit.Ref())
+	}
+	cancel()
+	r.Wait()
+
+	// Flatten cancels the rest of the order and sells what it filled
+	if err := r.Flatten(context.Background()); err != nil {
+		t.Fatalf("Flatten: %v", err)
+	}
+	orders := ex.placedOrders()
+	if len(orders) != 2 {
+		t.Fatalf("placed %d orders, want the limit buy and a flattening sell", len(orders))
+	}
+	if orders[0].Status != domain.OrderStatusCancelled {
+		t.Errorf("limit order status = %s, want CANCELLED", orders[0].Status)
+	}
+	if sell := orders[1]; sell.Side != domain.OrderSideSell || sell.Type != domain.OrderTypeMarket || !approx(sell.Quantity, limit.Quantity/2) {
+		t.Errorf("flatten order = %s %s %v, want SELL MARKET %v", sell.Side, sell.Type, sell.Quantity, limit.Quantity/2)
+	}
+	if status := r.Status()[0]; status.Position != 0 || status.Pending != "" {
+		t.Errorf("status after flatten = position %v pending %q, want flat", status.Position, status.Pending)
+	}
+}
+
+func TestRunnerRestoresPosition(t *testing.T) {
+	order := func(source, key string, side domain.OrderSide, quantity, filled float64, status domain.OrderStatus) *domain.Order {
+		o := &domain.Order{Symbol: "BTCUSDT", Side: side, Type: domain.OrderTypeLimit, Quantity: quantity, Price: 100}
+		o.ClientOrderID = domain.NewSourceClientOrderID(source, key, o)
+		o.ID = key
+		o.FilledQty = filled
+		o.Status = status
+		return o
+	}
+	open := order("test", "4", domain.OrderSideBuy, 0.5, 0, domain.OrderStatusNew)
+	sold := order("test", "3", domain.OrderSideSell, 0.1, 0.1, domain.OrderStatusFilled)
+	sold.ClientOrderID = strings.ReplaceAll(sold.ClientOrderID, "-", "") // As OKX stores it
+
+	ex := newFakeExchange(map[string]float64{"USDT": 1000, "BTC": 0.15})
+	ex.history = []*domain.Order{
+		order("test", "1", domain.OrderSideBuy, 0.3, 0.3, domain.OrderStatusFilled),
+		order("other", "2", domain.OrderSideBuy, 1, 1, domain.OrderStatusFilled),
+		sold,
+		open,
+	}
+	strategy := newScriptedStrategy(nil)
+	r, _ := startRunner(t, ex, strategy, 0)
+
+	// 0.3 bought less 0.1 sold, capped by the 0.15 the account still holds
+	status := r.Status()[0]
+	if !approx(status.Position, 0.15) {
+		t.Errorf("position = %v, want 0.15", status.Position)
+	}
+	if status.Pending != open.Ref().String() {
+		t.Errorf("pending = %q, want the open order %s", status.Pending, open.Ref())
+	}
+}
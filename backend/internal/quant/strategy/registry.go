@@ -0,0 +1,125 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lavumi/crypto-quant/internal/quant/backtest"
+)
+
+// Names lists the strategies New can create
+var Names = []string{"ma_cross", "rsi", "bb_rsi", "dca", "golden_rsi_bb"}
+
+// New creates a strategy by name from string parameters (e.g. from the config
+// file). Missing parameters use the same defaults as the backtest API; unknown
+// ones are rejected so typos don't silently fall back to defaults.
+func New(name string, params map[string]string) (backtest.Strategy, error) {
+	p := &paramReader{params: params, used: make(map[string]bool)}
+
+	var strat backtest.Strategy
+	switch name {
+	case "ma_cross":
+		strat = NewMACrossStrategy(p.int("fast_period", 10), p.int("slow_period", 30))
+
+	case "rsi":
+		strat = NewRSIStrategy(p.int("period", 14), p.float("oversold", 30), p.float("overbought", 70),
+			p.float("position_size", 0.01))
+
+	case "bb_rsi":
+		strat = NewBBRSIStrategy(p.int("bb_period", 20), p.float("bb_multiplier", 2), p.int("rsi_period", 14),
+			p.float("rsi_oversold", 30), p.float("rsi_overbought", 70), p.float("position_size", 0.01))
+
+	case "dca":
+		strat = NewDCAStrategy(p.duration("period", 24*time.Hour), p.float("amount_usdt", 100))
+
+	case "golden_rsi_bb":
+		strat = NewCustomGoldenRSIBBStrategy(
+			p.int("fast_period", 5), p.int("slow_period", 20), p.int("rsi_period", 14), p.int("bb_period", 20),
+			p.float("rsi_lower_bound", 40), p.float("rsi_upper_bound", 70), p.float("bb_multiplier", 2),
+			p.float("volume_threshold", 1.3), p.float("take_profit_pct", 0.06), p.float("stop_loss_pct", 0.03),
+			p.float("position_size", 0.01),
+		)
+
+	default:
+		return nil, fmt.Errorf("unknown strategy %q (available: %s)", name, strings.Join(Names, ", "))
+	}
+
+	if p.err != nil {
+		return nil, fmt.Errorf("invalid %s parameters: %w", name, p.err)
+	}
+	if unknown := p.unknown(); len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown %s parameters: %s", name, strings.Join(unknown, ", "))
+	}
+	return strat, nil
+}
+
+// paramReader reads typed parameters, keeping the first parse error
+type paramReader struct {
+	params map[string]string
+	used   map[string]bool
+	err    error
+}
+
+// lookup returns a parameter's value and marks it as used
+func (p *paramReader) lookup(key string) (string, bool) {
+	p.used[key] = true
+	value, ok := p.params[key]
+	return strings.TrimSpace(value), ok && strings.TrimSpace(value) != ""
+}
+
+func (p *paramReader) int(key string, def int) int {
+	value, ok := p.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s: %q is not an integer", key, value)
+	}
+	return n
+}
+
+func (p *paramReader) float(key string, def float64) float64 {
+	value, ok := p.lookup(key)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s: %q is not a number", key, value)
+	}
+	return f
+}
+
+// duration also accepts days (e.g. 7d)
+func (p *paramReader) duration(key string, def time.Duration) time.Duration {
+	value, ok := p.lookup(key)
+	if !ok {
+		return def
+	}
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.ParseFloat(days, 64); err == nil {
+			return time.Duration(n * float64(24*time.Hour))
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s: %q is not a duration (e.g. 24h, 7d)", key, value)
+	}
+	return d
+}
+
+// unknown returns the parameters no strategy option read, sorted
+func (p *paramReader) unknown() []string {
+	var keys []string
+	for key := range p.params {
+		if !p.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}